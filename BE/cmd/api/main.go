@@ -81,13 +81,6 @@ func main() {
 	waWorker := worker.NewWACampaignWorker(waCampaignRepo, waGatewayClient, tenantLimiter, waLogService)
 	waWorker.Register(asynqMux)
 
-	go func() {
-		log.Info().Msg("Asynq worker starting")
-		if err := asynqServer.Run(asynqMux); err != nil {
-			log.Error().Err(err).Msg("Asynq worker stopped")
-		}
-	}()
-
 	log.Info().Msg("Infrastructure initialized successfully")
 
 	// Step 4: Setup HTTP router with dependency injection
@@ -98,24 +91,120 @@ func main() {
 		Asynq:  asynqClient,
 	})
 
-	// Step 4b: Start lightweight daily invoice scheduler (H-1 before due date)
+	// Step 4b: Invoice generation (H-1 before due date) runs as a scheduled Asynq job
 	tenantRepo := repository.NewTenantRepository(db)
 	clientRepo := repository.NewClientRepository(db)
 	invoiceRepo := repository.NewInvoiceRepository(db)
 	paymentRepo := repository.NewPaymentRepository(db)
 	servicePackageRepo := repository.NewServicePackageRepository(db)
+	isolirService := service.NewIsolirService(
+		repository.NewIsolirLogRepository(db),
+		clientRepo,
+		invoiceRepo,
+		repository.NewRouterRepository(db),
+		repository.NewPPPoERepository(db),
+		repository.NewNetworkProfileRepository(db),
+		servicePackageRepo,
+		tenantRepo,
+	)
 	billingService := service.NewBillingService(invoiceRepo, paymentRepo, clientRepo, servicePackageRepo)
-	invoiceScheduler := service.NewInvoiceScheduler(tenantRepo, clientRepo, invoiceRepo, billingService)
-	invoiceScheduler.StartDailyScheduler(context.Background())
+	billingService.SetDiscountRepository(repository.NewDiscountRepository(db))
+	billingService.SetTenantRepository(tenantRepo)
+	billingService.SetAdjustmentRepository(repository.NewAdjustmentRepository(db))
+	billingService.SetBalanceRepository(repository.NewBalanceRepository(db))
+	billingService.SetSequenceRepository(repository.NewDocumentSequenceRepository(db))
+	billingService.SetRecurringChargeRepository(repository.NewRecurringChargeRepository(db))
+	billingService.SetIsolirService(isolirService)
+	// Invoices generated, charged and cancelled by the schedulers and workers post to the tenant's books
+	billingService.SetLedger(service.NewLedgerService(repository.NewLedgerRepository(db)))
+	invoiceScheduler := service.NewInvoiceScheduler(clientRepo, invoiceRepo, billingService)
+
+	// Step 4b1: Bulk invoice runs queued from the API are executed by this worker
+	bulkInvoiceService := service.NewBulkInvoiceService(repository.NewBulkInvoiceRunRepository(db), clientRepo, invoiceRepo, billingService, asynqClient)
+	worker.NewBulkInvoiceWorker(bulkInvoiceService).Register(asynqMux)
+
+	// Step 4b1a: WhatsApp billing notifications, raised by billing and isolir actions and the daily
+	// reminder job, are delivered by this worker
+	featureResolver := service.NewFeatureResolver(
+		repository.NewPlanRepository(db),
+		repository.NewAddonRepository(db),
+		repository.NewFeatureRepository(db),
+	)
+	waTemplateRenderer := service.NewWATemplateRenderer(invoiceRepo, repository.NewPaymentRequestRepository(db))
+	notificationService := service.NewNotificationService(
+		repository.NewNotificationRepository(db),
+		tenantRepo,
+		clientRepo,
+		invoiceRepo,
+		repository.NewWATemplateRepository(db),
+		waTemplateRenderer,
+		featureResolver,
+		waGatewayClient,
+		waLogService,
+		asynqClient,
+	)
+	billingService.SetNotifier(notificationService)
+	isolirService.SetNotifier(notificationService)
+	worker.NewBillingNotificationWorker(notificationService, tenantLimiter).Register(asynqMux)
+
+	// Step 4b2: Daily auto-isolir (overdue invoices past grace period) runs as a scheduled Asynq job
+	isolirScheduler := service.NewIsolirScheduler(clientRepo, invoiceRepo, isolirService, featureResolver)
 
-	// Step 4c: Start weekly client cleanup scheduler (hard delete after 28 days)
-	cleanupScheduler := service.NewClientCleanupScheduler(
+	// Step 4b3: Daily dunning (per-tenant reminder/throttle/isolir/terminate timeline) runs as a
+	// scheduled Asynq job
+	dunningService := service.NewDunningService(
+		tenantRepo,
 		clientRepo,
-		28,          // retentionDays
-		time.Monday, // runDay
-		"00:10",     // runTime
+		invoiceRepo,
+		repository.NewDunningRepository(db),
+		isolirService,
+		billingService,
+		featureResolver,
+		waGatewayClient,
+		waLogService,
+		waTemplateRenderer,
+	)
+	dunningScheduler := service.NewDunningScheduler(dunningService)
+
+	// Step 4b4: Daily late fees (per-tenant late fee policy on overdue invoices) run as a scheduled
+	// Asynq job
+	lateFeeService := service.NewLateFeeService(tenantRepo, clientRepo, invoiceRepo, repository.NewLateFeeRepository(db), billingService)
+	lateFeeScheduler := service.NewLateFeeScheduler(lateFeeService)
+
+	// Step 4c: Weekly client cleanup (hard delete after 28 days) runs as a scheduled Asynq job
+	cleanupScheduler := service.NewClientCleanupScheduler(clientRepo, 28)
+
+	// Step 4c2: Daily expiry of prepaid clients whose validity ended runs as a scheduled Asynq job
+	prepaidExpiryScheduler := service.NewPrepaidExpiryScheduler(clientRepo, isolirService)
+
+	// Step 4d: Scheduled jobs run as Asynq tasks; one replica (Redis leader lock) enqueues them
+	schedulerWorker := worker.NewSchedulerWorker(
+		repository.NewJobRunRepository(db),
+		tenantRepo,
+		asynqClient,
+		redisClient,
+		func() *hibasynq.Scheduler {
+			return asynqInfra.NewScheduler(cfg.Redis.Addr, cfg.Redis.Password, cfg.Redis.DB)
+		},
+		map[string]service.JobRunner{
+			service.JobInvoiceGeneration: invoiceScheduler.Runner(),
+			service.JobClientCleanup:     cleanupScheduler.Runner(),
+			service.JobPrepaidExpiry:     prepaidExpiryScheduler.Runner(),
+			service.JobBillingReminders:  notificationService.Runner(),
+			service.JobIsolir:            isolirScheduler.Runner(),
+			service.JobLateFees:          lateFeeScheduler.Runner(),
+			service.JobDunning:           dunningScheduler.Runner(),
+		},
 	)
-	cleanupScheduler.StartWeeklyScheduler(context.Background())
+	schedulerWorker.Register(asynqMux)
+	schedulerWorker.StartLeaderScheduler(context.Background())
+
+	go func() {
+		log.Info().Msg("Asynq worker starting")
+		if err := asynqServer.Run(asynqMux); err != nil {
+			log.Error().Err(err).Msg("Asynq worker stopped")
+		}
+	}()
 
 	// Step 5: Create and run HTTP server
 	srv := server.New(server.Config{
@@ -3,6 +3,7 @@ module rrnet
 go 1.23.0
 
 require (
+	github.com/alicebob/miniredis/v2 v2.31.1
 	github.com/go-redis/redis/v8 v8.11.5
 	github.com/google/uuid v1.5.0
 	github.com/hibiken/asynq v0.24.1
@@ -13,6 +14,11 @@ require (
 	github.com/stretchr/testify v1.11.1
 )
 
+require (
+	github.com/alicebob/gopher-json v0.0.0-20200520072559-a9ecdc9d1d3a // indirect
+	github.com/yuin/gopher-lua v1.1.0 // indirect
+)
+
 require (
 	github.com/beorn7/perks v1.0.1 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
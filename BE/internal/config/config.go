@@ -16,6 +16,7 @@ type Config struct {
 	Auth     AuthConfig
 	Server   ServerConfig
 	WAGateway WAGatewayConfig
+	PaymentGateway PaymentGatewayConfig
 }
 
 // AppConfig holds application-level settings
@@ -62,6 +63,13 @@ type WAGatewayConfig struct {
 	AdminToken string
 }
 
+// PaymentGatewayConfig holds payment gateway integration settings (optional).
+// Merchant credentials are configured per tenant.
+type PaymentGatewayConfig struct {
+	CallbackBaseURL string // public base URL gateways send webhooks to
+	AllowMock       bool   // offer the offline mock provider; never in production
+}
+
 // Load reads and validates configuration from environment variables.
 // Fails fast if required variables are missing or invalid.
 func Load() (*Config, error) {
@@ -191,6 +199,10 @@ func Load() (*Config, error) {
 		cfg.WAGateway.AdminToken = "dev-wa-admin-token"
 	}
 
+	// Payment gateway webhooks (optional)
+	cfg.PaymentGateway.CallbackBaseURL = getEnvOrDefault("PAYMENT_CALLBACK_BASE_URL", "")
+	cfg.PaymentGateway.AllowMock = cfg.App.Env != "production"
+
 	return cfg, nil
 }
 
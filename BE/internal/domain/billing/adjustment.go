@@ -0,0 +1,36 @@
+package billing
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AdjustmentKind defines why a prorated adjustment was created
+type AdjustmentKind string
+
+const (
+	AdjustmentPackageChange AdjustmentKind = "package_change"
+	AdjustmentTermination   AdjustmentKind = "termination"
+)
+
+// Adjustment is a prorated charge (positive) or credit (negative) that is billed as a
+// separate line on the client's next invoice. InvoiceID is nil while it is still pending.
+type Adjustment struct {
+	ID              uuid.UUID      `json:"id"`
+	TenantID        uuid.UUID      `json:"tenant_id"`
+	ClientID        uuid.UUID      `json:"client_id"`
+	Kind            AdjustmentKind `json:"kind"`
+	Description     string         `json:"description"`
+	Amount          int64          `json:"amount"`
+	EffectiveDate   time.Time      `json:"effective_date"`
+	SourceInvoiceID *uuid.UUID     `json:"source_invoice_id,omitempty"`
+	InvoiceID       *uuid.UUID     `json:"invoice_id,omitempty"`
+	AppliedAt       *time.Time     `json:"applied_at,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+}
+
+// IsPending returns true if the adjustment has not been billed yet
+func (a *Adjustment) IsPending() bool {
+	return a.InvoiceID == nil
+}
@@ -0,0 +1,47 @@
+package billing
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BalanceEntryType defines the kind of movement on a client's balance
+type BalanceEntryType string
+
+const (
+	BalanceOverpayment   BalanceEntryType = "overpayment"    // payment above the invoice total (+)
+	BalanceCreditNote    BalanceEntryType = "credit_note"    // credit note above the unpaid amount (+)
+	BalanceCreditApplied BalanceEntryType = "credit_applied" // credit used to pay an invoice (-)
+	BalanceRefund        BalanceEntryType = "refund"         // credit paid back to the client (-)
+	BalanceAdjustment    BalanceEntryType = "adjustment"     // prorated credit, e.g. termination (+)
+)
+
+// BalanceEntry is one movement of a client's balance. Positive amounts are credit owed to the client.
+type BalanceEntry struct {
+	ID           uuid.UUID        `json:"id"`
+	TenantID     uuid.UUID        `json:"tenant_id"`
+	ClientID     uuid.UUID        `json:"client_id"`
+	Type         BalanceEntryType `json:"type"`
+	Amount       int64            `json:"amount"`
+	BalanceAfter int64            `json:"balance_after"`
+	InvoiceID    *uuid.UUID       `json:"invoice_id,omitempty"`
+	PaymentID    *uuid.UUID       `json:"payment_id,omitempty"`
+	CreditNoteID *uuid.UUID       `json:"credit_note_id,omitempty"`
+	Description  string           `json:"description,omitempty"`
+	CreatedBy    *uuid.UUID       `json:"created_by,omitempty"`
+	CreatedAt    time.Time        `json:"created_at"`
+}
+
+// CreditNote reduces an issued invoice
+type CreditNote struct {
+	ID               uuid.UUID  `json:"id"`
+	TenantID         uuid.UUID  `json:"tenant_id"`
+	ClientID         uuid.UUID  `json:"client_id"`
+	InvoiceID        uuid.UUID  `json:"invoice_id"`
+	CreditNoteNumber string     `json:"credit_note_number"`
+	Amount           int64      `json:"amount"`
+	Reason           string     `json:"reason"`
+	ApprovedBy       *uuid.UUID `json:"approved_by,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
@@ -0,0 +1,97 @@
+package billing
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BankLineStatus is the reconciliation state of a bank statement credit
+type BankLineStatus string
+
+const (
+	BankLineAutoMatched BankLineStatus = "auto_matched" // one invoice matches with high confidence
+	BankLineSuggested   BankLineStatus = "suggested"    // plausible invoices, needs a person to pick
+	BankLineUnmatched   BankLineStatus = "unmatched"
+	BankLineConfirmed   BankLineStatus = "confirmed" // payment recorded
+	BankLineIgnored     BankLineStatus = "ignored"   // not a client payment (interest, internal transfer)
+)
+
+// BankStatement is one imported mutation file
+type BankStatement struct {
+	ID             uuid.UUID  `json:"id"`
+	TenantID       uuid.UUID  `json:"tenant_id"`
+	Bank           string     `json:"bank"`
+	AccountNumber  string     `json:"account_number,omitempty"`
+	FileName       string     `json:"file_name"`
+	PeriodStart    *time.Time `json:"period_start,omitempty"`
+	PeriodEnd      *time.Time `json:"period_end,omitempty"`
+	CreditCount    int        `json:"credit_count"`    // new credits stored for reconciliation
+	DebitCount     int        `json:"debit_count"`     // debits in the file, not stored
+	DuplicateCount int        `json:"duplicate_count"` // credits already imported by an earlier statement
+	ImportedBy     uuid.UUID  `json:"imported_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+
+	// Current number of lines per status
+	AutoMatched int `json:"auto_matched"`
+	Suggested   int `json:"suggested"`
+	Unmatched   int `json:"unmatched"`
+	Confirmed   int `json:"confirmed"`
+	Ignored     int `json:"ignored"`
+}
+
+// BankMatchCandidate is an invoice that may have been paid by a bank credit
+type BankMatchCandidate struct {
+	InvoiceID     uuid.UUID `json:"invoice_id"`
+	ClientID      uuid.UUID `json:"client_id"`
+	InvoiceNumber string    `json:"invoice_number"`
+	ClientName    string    `json:"client_name"`
+	Outstanding   int64     `json:"outstanding"`
+	Score         int       `json:"score"`
+	Reasons       []string  `json:"reasons"` // amount, unique_code, invoice_number, client_code, phone, name, name_partial, date_window
+}
+
+// BankStatementLine is a credit (money received) of an imported statement
+type BankStatementLine struct {
+	ID          uuid.UUID            `json:"id"`
+	TenantID    uuid.UUID            `json:"tenant_id"`
+	StatementID uuid.UUID            `json:"statement_id"`
+	Row         int                  `json:"row"`
+	TxnDate     time.Time            `json:"txn_date"`
+	Description string               `json:"description"`
+	Reference   string               `json:"reference,omitempty"`
+	Amount      int64                `json:"amount"`
+	Balance     *int64               `json:"balance,omitempty"`
+	Fingerprint string               `json:"-"` // identifies the mutation across overlapping imports
+	Status      BankLineStatus       `json:"status"`
+	InvoiceID   *uuid.UUID           `json:"invoice_id,omitempty"` // best or confirmed invoice
+	ClientID    *uuid.UUID           `json:"client_id,omitempty"`
+	Score       int                  `json:"score"`
+	Candidates  []BankMatchCandidate `json:"candidates"`
+	PaymentID   *uuid.UUID           `json:"payment_id,omitempty"`
+	ConfirmedBy *uuid.UUID           `json:"confirmed_by,omitempty"`
+	ConfirmedAt *time.Time           `json:"confirmed_at,omitempty"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+}
+
+// IsOpen reports whether the line still waits for a decision
+func (l *BankStatementLine) IsOpen() bool {
+	return l.Status == BankLineAutoMatched || l.Status == BankLineSuggested || l.Status == BankLineUnmatched
+}
+
+// ReconcileInvoice is an open invoice with the client details used to match bank credits
+type ReconcileInvoice struct {
+	InvoiceID     uuid.UUID
+	ClientID      uuid.UUID
+	InvoiceNumber string
+	ClientCode    string
+	ClientName    string
+	ClientPhone   string
+	Outstanding   int64
+	UniqueCode    int   // 0 when the invoice has no code or is partly paid
+	Payable       int64 // amount to transfer, including a unique code that is not in the total
+	PeriodStart   time.Time
+	DueDate       time.Time
+	CreatedAt     time.Time
+}
@@ -0,0 +1,83 @@
+package billing
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BulkRunStatus is the state of a bulk invoice generation run
+type BulkRunStatus string
+
+const (
+	BulkRunQueued    BulkRunStatus = "queued"
+	BulkRunRunning   BulkRunStatus = "running"
+	BulkRunCompleted BulkRunStatus = "completed" // every client processed (some may have failed)
+	BulkRunFailed    BulkRunStatus = "failed"    // stopped by an error, retried runs resume
+	BulkRunUndone    BulkRunStatus = "undone"    // invoices of the run cancelled
+)
+
+// BulkItemStatus is the outcome of one client in a bulk run
+type BulkItemStatus string
+
+const (
+	BulkItemPending   BulkItemStatus = "pending"
+	BulkItemCreated   BulkItemStatus = "created"
+	BulkItemSkipped   BulkItemStatus = "skipped"
+	BulkItemFailed    BulkItemStatus = "failed"
+	BulkItemCancelled BulkItemStatus = "cancelled" // invoice cancelled by an undo
+)
+
+// Reasons a client is left out of a bulk run
+const (
+	BulkSkipPrepaid       = "prepaid"         // renewed on its own validity dates
+	BulkSkipNotCycleStart = "not_cycle_start" // multi-month cycle does not start in the period
+	BulkSkipInvoiceExists = "invoice_exists"
+)
+
+// BulkRunFilter selects the active clients of a bulk run
+type BulkRunFilter struct {
+	GroupID          *uuid.UUID `json:"group_id,omitempty"`
+	ServicePackageID *uuid.UUID `json:"service_package_id,omitempty"`
+	RouterID         *uuid.UUID `json:"router_id,omitempty"`
+}
+
+// BulkRun generates the invoices of a period for many clients at once, as a background job
+type BulkRun struct {
+	ID          uuid.UUID     `json:"id"`
+	TenantID    uuid.UUID     `json:"tenant_id"`
+	PeriodStart time.Time     `json:"period_start"` // first day of the month billed
+	Filter      BulkRunFilter `json:"filter"`
+	Status      BulkRunStatus `json:"status"`
+	Total       int           `json:"total"` // clients selected
+	Processed   int           `json:"processed"`
+	Created     int           `json:"created"`
+	Skipped     int           `json:"skipped"`
+	Failed      int           `json:"failed"`
+	Cancelled   int           `json:"cancelled"`
+	TotalAmount int64         `json:"total_amount"` // of the invoices created
+	Error       *string       `json:"error,omitempty"`
+	CreatedBy   uuid.UUID     `json:"created_by"`
+	StartedAt   *time.Time    `json:"started_at,omitempty"`
+	FinishedAt  *time.Time    `json:"finished_at,omitempty"`
+	UndoneBy    *uuid.UUID    `json:"undone_by,omitempty"`
+	UndoneAt    *time.Time    `json:"undone_at,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+// BulkRunItem is the outcome of one client in a bulk run (or of a dry run)
+type BulkRunItem struct {
+	ID          uuid.UUID      `json:"id,omitempty"`
+	RunID       uuid.UUID      `json:"run_id,omitempty"`
+	ClientID    uuid.UUID      `json:"client_id"`
+	ClientCode  string         `json:"client_code"`
+	ClientName  string         `json:"client_name"`
+	Status      BulkItemStatus `json:"status"`
+	PeriodStart *time.Time     `json:"period_start,omitempty"`
+	PeriodEnd   *time.Time     `json:"period_end,omitempty"`
+	DueDate     *time.Time     `json:"due_date,omitempty"`
+	Amount      int64          `json:"amount"`
+	InvoiceID   *uuid.UUID     `json:"invoice_id,omitempty"`
+	Reason      string         `json:"reason,omitempty"` // skip reason, error or why an undo kept the invoice
+	UpdatedAt   *time.Time     `json:"updated_at,omitempty"`
+}
@@ -0,0 +1,38 @@
+package billing
+
+import "time"
+
+// CycleMonths are the supported billing cycle lengths: monthly, quarterly, semi-annual and annual
+var CycleMonths = []int{1, 3, 6, 12}
+
+// ValidCycleMonths checks if months is a supported billing cycle length
+func ValidCycleMonths(months int) bool {
+	for _, m := range CycleMonths {
+		if m == months {
+			return true
+		}
+	}
+	return false
+}
+
+// CyclePeriodOf returns the billing cycle containing t. Cycles are months-long runs of calendar
+// months aligned to the month of anchor, so a quarterly client anchored in February is billed for
+// Feb-Apr, May-Jul, and so on.
+func CyclePeriodOf(t time.Time, months int, anchor time.Time) (start, end time.Time) {
+	if months < 1 {
+		months = 1
+	}
+	offset := (t.Year()*12 + int(t.Month())) - (anchor.Year()*12 + int(anchor.Month()))
+	offset %= months
+	if offset < 0 {
+		offset += months
+	}
+	start = time.Date(t.Year(), t.Month()-time.Month(offset), 1, 0, 0, 0, 0, time.Local)
+	return start, start.AddDate(0, months, -1)
+}
+
+// IsCycleStart reports whether t falls in the first month of its billing cycle
+func IsCycleStart(t time.Time, months int, anchor time.Time) bool {
+	start, _ := CyclePeriodOf(t, months, anchor)
+	return start.Year() == t.Year() && start.Month() == t.Month()
+}
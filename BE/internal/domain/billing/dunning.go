@@ -0,0 +1,57 @@
+package billing
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DunningAction defines what a dunning step does
+type DunningAction string
+
+const (
+	DunningActionReminder  DunningAction = "reminder"
+	DunningActionThrottle  DunningAction = "throttle"
+	DunningActionIsolir    DunningAction = "isolir"
+	DunningActionTerminate DunningAction = "terminate"
+)
+
+// DunningStepStatus defines the outcome of a dunning step run
+type DunningStepStatus string
+
+const (
+	DunningStepRunning  DunningStepStatus = "running" // reserved, the outcome was not recorded (yet)
+	DunningStepExecuted DunningStepStatus = "executed"
+	DunningStepFailed   DunningStepStatus = "failed"
+	DunningStepSkipped  DunningStepStatus = "skipped"
+)
+
+// DunningStep is one entry of a tenant's dunning policy.
+// OffsetDays is relative to Invoice.DueDate: -3 = H-3 (before due), 7 = D+7 (after due).
+type DunningStep struct {
+	OffsetDays int           `json:"offset_days"`
+	Action     DunningAction `json:"action"`
+	Profile    string        `json:"profile,omitempty"` // throttle: PPPoE profile with reduced speed
+	Message    string        `json:"message,omitempty"` // reminder: WA text (optional)
+}
+
+// DunningPolicy is stored in tenant settings under "dunning"
+type DunningPolicy struct {
+	Enabled bool          `json:"enabled"`
+	Steps   []DunningStep `json:"steps"`
+}
+
+// DunningStepRun records that a step was evaluated for an invoice (one row per invoice/step)
+type DunningStepRun struct {
+	ID         uuid.UUID         `json:"id"`
+	TenantID   uuid.UUID         `json:"tenant_id"`
+	ClientID   uuid.UUID         `json:"client_id"`
+	InvoiceID  uuid.UUID         `json:"invoice_id"`
+	OffsetDays int               `json:"offset_days"`
+	Action     DunningAction     `json:"action"`
+	Status     DunningStepStatus `json:"status"`
+	ErrorMsg   string            `json:"error_msg,omitempty"`
+	RunDate    time.Time         `json:"run_date"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
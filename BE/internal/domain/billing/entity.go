@@ -1,6 +1,7 @@
 package billing
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,6 +18,15 @@ const (
 	InvoiceStatusCancelled InvoiceStatus = "cancelled"
 )
 
+// InvoiceSource tells how an invoice was created
+type InvoiceSource string
+
+const (
+	InvoiceSourceManual  InvoiceSource = "manual"
+	InvoiceSourceMonthly InvoiceSource = "monthly" // generated for the client's billing period; one per period
+	InvoiceSourcePrepaid InvoiceSource = "prepaid" // buys prepaid service validity for its period; one unpaid per client
+)
+
 // PaymentMethod defines payment method types
 type PaymentMethod string
 
@@ -44,11 +54,17 @@ type Invoice struct {
 	DueDate           time.Time     `json:"due_date"`
 	Subtotal          int64         `json:"subtotal"`        // in cents/smallest unit
 	TaxAmount         int64         `json:"tax_amount"`
+	TaxRate           float64       `json:"tax_rate"`      // percent applied when the invoice was issued
+	TaxInclusive      bool          `json:"tax_inclusive"` // item prices include the tax
+	TaxBase           int64         `json:"tax_base"`      // DPP: taxable amount after discount, excluding tax
 	DiscountAmount    int64         `json:"discount_amount"`
 	TotalAmount       int64         `json:"total_amount"`
 	PaidAmount        int64         `json:"paid_amount"`
+	UniqueCode        *int          `json:"unique_code,omitempty"`          // "kode unik" identifying the client's bank transfer
+	UniqueCodeInTotal bool          `json:"unique_code_in_total,omitempty"` // the code is part of TotalAmount
 	Currency          string        `json:"currency"`
 	Status            InvoiceStatus `json:"status"`
+	Source            InvoiceSource `json:"source"`
 	Notes             string        `json:"notes,omitempty"`
 	Items             []InvoiceItem `json:"items,omitempty"`
 	CreatedAt         time.Time     `json:"created_at"`
@@ -64,25 +80,49 @@ type InvoiceItem struct {
 	Quantity    int       `json:"quantity"`
 	UnitPrice   int64     `json:"unit_price"`
 	Amount      int64     `json:"amount"`
+	DiscountID  *uuid.UUID `json:"discount_id,omitempty"` // set on discount lines (negative amount)
 	CreatedAt   time.Time `json:"created_at"`
 }
 
 // Payment represents a payment transaction
 type Payment struct {
-	ID              uuid.UUID     `json:"id"`
-	TenantID        uuid.UUID     `json:"tenant_id"`
-	InvoiceID       uuid.UUID     `json:"invoice_id"`
-	ClientID        uuid.UUID     `json:"client_id"`
-	ClientName      *string       `json:"client_name,omitempty"`
-	Amount          int64         `json:"amount"`
-	Currency        string        `json:"currency"`
-	Method          PaymentMethod `json:"method"`
-	Reference       *string       `json:"reference,omitempty"`
-	CollectorID     *uuid.UUID    `json:"collector_id,omitempty"`
-	Notes           *string       `json:"notes,omitempty"`
-	ReceivedAt      time.Time     `json:"received_at"`
-	CreatedAt       time.Time     `json:"created_at"`
-	CreatedByUserID uuid.UUID     `json:"created_by_user_id"`
+	ID               uuid.UUID     `json:"id"`
+	TenantID         uuid.UUID     `json:"tenant_id"`
+	InvoiceID        uuid.UUID     `json:"invoice_id"`
+	ClientID         uuid.UUID     `json:"client_id"`
+	ClientName       *string       `json:"client_name,omitempty"`
+	Amount           int64         `json:"amount"`
+	Currency         string        `json:"currency"`
+	Method           PaymentMethod `json:"method"`
+	Reference        *string       `json:"reference,omitempty"`
+	CollectorID      *uuid.UUID    `json:"collector_id,omitempty"`
+	Notes            *string       `json:"notes,omitempty"`
+	ReceivedAt       time.Time     `json:"received_at"`
+	CreatedAt        time.Time     `json:"created_at"`
+	CreatedByUserID  uuid.UUID     `json:"created_by_user_id"`
+	ClientPaymentID  *uuid.UUID    `json:"client_payment_id,omitempty"`  // set when allocated from a client-level payment
+	ReceiptNumber    *string       `json:"receipt_number,omitempty"`     // drawn when the payment is recorded
+	PaymentRequestID *uuid.UUID    `json:"payment_request_id,omitempty"` // set when paid through the payment gateway
+}
+
+// ClientPayment is one amount received from a client and allocated across its invoices.
+// Each allocation is stored as a Payment linked by ClientPaymentID.
+type ClientPayment struct {
+	ID               uuid.UUID     `json:"id"`
+	TenantID         uuid.UUID     `json:"tenant_id"`
+	ClientID         uuid.UUID     `json:"client_id"`
+	Amount           int64         `json:"amount"`
+	Currency         string        `json:"currency"`
+	Method           PaymentMethod `json:"method"`
+	Reference        *string       `json:"reference,omitempty"`
+	CollectorID      *uuid.UUID    `json:"collector_id,omitempty"`
+	Notes            *string       `json:"notes,omitempty"`
+	CreditedAmount   int64         `json:"credited_amount"` // unallocated remainder added to the client balance
+	ReceivedAt       time.Time     `json:"received_at"`
+	CreatedAt        time.Time     `json:"created_at"`
+	CreatedByUserID  uuid.UUID     `json:"created_by_user_id"`
+	PaymentRequestID *uuid.UUID    `json:"payment_request_id,omitempty"` // set when paid through the payment gateway
+	Allocations      []*Payment    `json:"allocations"`
 }
 
 // IsolirStatus defines isolir action status
@@ -101,6 +141,8 @@ type IsolirAction string
 const (
 	IsolirActionIsolate   IsolirAction = "isolate"
 	IsolirActionReactivate IsolirAction = "reactivate"
+	IsolirActionThrottle   IsolirAction = "throttle"
+	IsolirActionUnthrottle IsolirAction = "unthrottle"
 )
 
 // IsolirLog represents an isolir action log
@@ -129,6 +171,8 @@ type BillingSummary struct {
 	PendingAmount     int64 `json:"pending_amount"`
 	OverdueAmount     int64 `json:"overdue_amount"`
 	CollectedThisMonth int64 `json:"collected_this_month"`
+	TaxInvoiced       int64 `json:"tax_invoiced"`  // tax on non-cancelled invoices
+	TaxCollected      int64 `json:"tax_collected"` // tax on paid invoices
 }
 
 // ClientBillingSummary provides billing summary for a client
@@ -140,6 +184,8 @@ type ClientBillingSummary struct {
 	OverdueAmount   int64     `json:"overdue_amount"`
 	LastPaymentDate *time.Time `json:"last_payment_date,omitempty"`
 	LastPaymentAmount int64   `json:"last_payment_amount"`
+	Balance         int64     `json:"balance"` // client credit (overpayments, credit notes) not yet used
+	TotalTax        int64     `json:"total_tax"` // tax on non-cancelled invoices
 }
 
 // IsDue checks if invoice is past due date
@@ -152,6 +198,28 @@ func (i *Invoice) RemainingAmount() int64 {
 	return i.TotalAmount - i.PaidAmount
 }
 
+// PayableAmount returns what the client should transfer: the unpaid amount, plus the unique code
+// when the code is not already part of the total
+func (i *Invoice) PayableAmount() int64 {
+	rem := i.RemainingAmount()
+	if rem <= 0 {
+		return 0
+	}
+	if i.UniqueCode != nil && !i.UniqueCodeInTotal {
+		rem += int64(*i.UniqueCode)
+	}
+	return rem
+}
+
+// MarshalJSON adds the payable amount to the invoice
+func (i Invoice) MarshalJSON() ([]byte, error) {
+	type invoice Invoice
+	return json.Marshal(struct {
+		invoice
+		PayableAmount int64 `json:"payable_amount"`
+	}{invoice(i), i.PayableAmount()})
+}
+
 // IsPaid checks if invoice is fully paid
 func (i *Invoice) IsPaid() bool {
 	return i.PaidAmount >= i.TotalAmount
@@ -0,0 +1,68 @@
+package billing
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LateFeeType defines how a late fee amount is computed
+type LateFeeType string
+
+const (
+	LateFeeFlat    LateFeeType = "flat"    // fixed amount per charge
+	LateFeePercent LateFeeType = "percent" // percent of the outstanding amount of the overdue invoice
+)
+
+// LateFeeMode defines where late fees are charged
+type LateFeeMode string
+
+const (
+	LateFeeModeItem    LateFeeMode = "item"    // appended as a line to the overdue invoice
+	LateFeeModeInvoice LateFeeMode = "invoice" // billed on a separate penalty invoice
+)
+
+// LateFeeRule describes when and how much late fee is charged.
+// The first fee is charged GraceDays after the due date; with RepeatDays > 0 another fee is charged
+// every RepeatDays after that until MaxTotal (0 = no cap) is reached.
+type LateFeeRule struct {
+	Type       LateFeeType `json:"type"`
+	Amount     int64       `json:"amount,omitempty"`  // flat
+	Percent    float64     `json:"percent,omitempty"` // percent
+	GraceDays  int         `json:"grace_days"`
+	RepeatDays int         `json:"repeat_days"`
+	MaxTotal   int64       `json:"max_total"`
+}
+
+// LateFeePolicy is stored in tenant settings under "late_fee".
+// PackageRules override the default rule for clients on the given service package. Penalty invoices
+// (invoice mode) are due DueDays after they are created.
+type LateFeePolicy struct {
+	Enabled      bool                      `json:"enabled"`
+	Mode         LateFeeMode               `json:"mode"`
+	Rule         LateFeeRule               `json:"rule"`
+	PackageRules map[uuid.UUID]LateFeeRule `json:"package_rules"`
+	DueDays      int                       `json:"due_days"`
+}
+
+// LateFee is one late fee charged for an overdue invoice. Amount includes tax.
+type LateFee struct {
+	ID               uuid.UUID  `json:"id"`
+	TenantID         uuid.UUID  `json:"tenant_id"`
+	ClientID         uuid.UUID  `json:"client_id"`
+	InvoiceID        uuid.UUID  `json:"invoice_id"`         // the overdue invoice
+	ChargedInvoiceID uuid.UUID  `json:"charged_invoice_id"` // invoice the fee was billed on (same or penalty invoice)
+	Sequence         int        `json:"sequence"`
+	Amount           int64      `json:"amount"`
+	AssessedAt       time.Time  `json:"assessed_at"`
+	WaivedAt         *time.Time `json:"waived_at,omitempty"`
+	WaivedBy         *uuid.UUID `json:"waived_by,omitempty"`
+	WaiveReason      string     `json:"waive_reason,omitempty"`
+	CreditNoteID     *uuid.UUID `json:"credit_note_id,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// IsWaived reports whether the fee has been waived
+func (f *LateFee) IsWaived() bool {
+	return f.WaivedAt != nil
+}
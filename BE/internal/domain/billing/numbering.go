@@ -0,0 +1,186 @@
+package billing
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DocumentKind identifies a numbered billing document; each kind has its own sequence
+type DocumentKind string
+
+const (
+	DocumentInvoice    DocumentKind = "invoice"
+	DocumentCreditNote DocumentKind = "credit_note"
+	DocumentReceipt    DocumentKind = "receipt"
+)
+
+// NumberingReset defines when the sequence of a numbering scheme starts again at 1
+type NumberingReset string
+
+const (
+	NumberingResetNever   NumberingReset = "never"
+	NumberingResetYearly  NumberingReset = "yearly"
+	NumberingResetMonthly NumberingReset = "monthly"
+)
+
+// MaxDocumentNumberLength is the column size of invoice and credit note numbers
+const MaxDocumentNumberLength = 50
+
+var (
+	ErrNumberPatternEmpty     = errors.New("number pattern is required")
+	ErrNumberPatternSeq       = errors.New("number pattern must contain exactly one {SEQ} or {SEQ:n} token (n from 1 to 10)")
+	ErrNumberPatternToken     = errors.New("number pattern contains an unknown token; use {YYYY}, {YY}, {MM}, {DD}, {SEQ:n} or {BRANCH}")
+	ErrNumberPatternChars     = errors.New("number pattern may only contain letters, digits and - / . _ outside tokens")
+	ErrNumberPatternLength    = errors.New("numbers of this pattern exceed 50 characters")
+	ErrNumberPatternReset     = errors.New("reset must be never, yearly or monthly")
+	ErrNumberPatternResetDate = errors.New("a yearly reset needs {YYYY} or {YY} in the pattern, a monthly reset also {MM}")
+	ErrNumberBranchInvalid    = errors.New("branch codes may only contain letters and digits (at most 10)")
+)
+
+var (
+	numberToken   = regexp.MustCompile(`\{[^{}]*\}`)
+	numberLiteral = regexp.MustCompile(`^[A-Za-z0-9\-/._]*$`)
+	branchCode    = regexp.MustCompile(`^[A-Za-z0-9]{0,10}$`)
+)
+
+// NumberingScheme formats the numbers of one document kind, e.g. "INV/{YYYY}/{MM}/{SEQ:5}".
+//
+// Tokens: {YYYY} and {YY} year, {MM} month, {DD} day, {SEQ} or {SEQ:n} the sequence (zero padded
+// to n digits) and {BRANCH} the branch code of the client. Every branch code has its own sequence.
+type NumberingScheme struct {
+	Pattern string         `json:"pattern"`
+	Reset   NumberingReset `json:"reset"`
+}
+
+// DefaultNumberingScheme returns the format used before numbering was configurable
+func DefaultNumberingScheme(kind DocumentKind) NumberingScheme {
+	prefix := "INV"
+	switch kind {
+	case DocumentCreditNote:
+		prefix = "CN"
+	case DocumentReceipt:
+		prefix = "KW"
+	}
+	return NumberingScheme{Pattern: prefix + "-{YYYY}{MM}-{SEQ:4}", Reset: NumberingResetMonthly}
+}
+
+// Validate checks the pattern and reset of the scheme
+func (s NumberingScheme) Validate() error {
+	if strings.TrimSpace(s.Pattern) == "" {
+		return ErrNumberPatternEmpty
+	}
+	switch s.Reset {
+	case NumberingResetNever, NumberingResetYearly, NumberingResetMonthly:
+	default:
+		return ErrNumberPatternReset
+	}
+	seqs := 0
+	width := 0
+	hasYear, hasMonth := false, false
+	for _, tok := range numberToken.FindAllString(s.Pattern, -1) {
+		switch name := tok[1 : len(tok)-1]; {
+		case name == "YYYY", name == "YY":
+			hasYear = true
+		case name == "MM":
+			hasMonth = true
+		case name == "DD", name == "BRANCH":
+		case name == "SEQ":
+			seqs++
+		case strings.HasPrefix(name, "SEQ:"):
+			n, err := strconv.Atoi(name[4:])
+			if err != nil || n < 1 || n > 10 {
+				return ErrNumberPatternSeq
+			}
+			seqs++
+			width = n
+		default:
+			return ErrNumberPatternToken
+		}
+	}
+	if seqs != 1 {
+		return ErrNumberPatternSeq
+	}
+	if !numberLiteral.MatchString(numberToken.ReplaceAllString(s.Pattern, "")) {
+		return ErrNumberPatternChars
+	}
+	if (s.Reset == NumberingResetYearly && !hasYear) || (s.Reset == NumberingResetMonthly && (!hasYear || !hasMonth)) {
+		return ErrNumberPatternResetDate
+	}
+	// Longest possible number: a 10-letter branch and a sequence of at least 6 digits
+	if width < 6 {
+		width = 6
+	}
+	sample := s.Format(time.Date(2000, 12, 31, 0, 0, 0, 0, time.UTC), "XXXXXXXXXX", 0)
+	if len(sample)+width > MaxDocumentNumberLength {
+		return ErrNumberPatternLength
+	}
+	return nil
+}
+
+// ValidateBranchCode checks a branch code used for {BRANCH}
+func ValidateBranchCode(code string) error {
+	if !branchCode.MatchString(code) {
+		return ErrNumberBranchInvalid
+	}
+	return nil
+}
+
+// Scope returns the sequence a number dated at belongs to: the reset period and, when the pattern
+// uses it, the branch code
+func (s NumberingScheme) Scope(at time.Time, branch string) string {
+	var scope string
+	switch s.Reset {
+	case NumberingResetYearly:
+		scope = at.Format("2006")
+	case NumberingResetMonthly:
+		scope = at.Format("2006-01")
+	default:
+		scope = "all"
+	}
+	if strings.Contains(s.Pattern, "{BRANCH}") {
+		scope += "|" + strings.ToUpper(branch)
+	}
+	return scope
+}
+
+// Format renders the number with sequence value seq (seq 0 leaves the sequence out)
+func (s NumberingScheme) Format(at time.Time, branch string, seq int64) string {
+	return numberToken.ReplaceAllStringFunc(s.Pattern, func(tok string) string {
+		switch name := tok[1 : len(tok)-1]; {
+		case name == "YYYY":
+			return at.Format("2006")
+		case name == "YY":
+			return at.Format("06")
+		case name == "MM":
+			return at.Format("01")
+		case name == "DD":
+			return at.Format("02")
+		case name == "BRANCH":
+			return strings.ToUpper(branch)
+		case name == "SEQ":
+			if seq == 0 {
+				return ""
+			}
+			return strconv.FormatInt(seq, 10)
+		case strings.HasPrefix(name, "SEQ:"):
+			if seq == 0 {
+				return ""
+			}
+			n, _ := strconv.Atoi(name[4:])
+			return fmt.Sprintf("%0*d", n, seq)
+		}
+		return tok
+	})
+}
+
+// DocumentNumbering is what is needed to number one document
+type DocumentNumbering struct {
+	Kind   DocumentKind
+	Scheme NumberingScheme
+	Branch string
+	Date   time.Time
+}
@@ -0,0 +1,61 @@
+package billing
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PaymentRequestStatus defines the state of a payment gateway request
+type PaymentRequestStatus string
+
+const (
+	PaymentRequestPending PaymentRequestStatus = "pending"
+	PaymentRequestPaid    PaymentRequestStatus = "paid"
+	PaymentRequestExpired PaymentRequestStatus = "expired"
+	PaymentRequestFailed  PaymentRequestStatus = "failed"
+)
+
+// PaymentRequest is a payment created at a payment gateway for one invoice (a VA number, QRIS code
+// or e-wallet checkout). It is settled by a verified webhook of the gateway.
+type PaymentRequest struct {
+	ID         uuid.UUID            `json:"id"`
+	TenantID   uuid.UUID            `json:"tenant_id"`
+	InvoiceID  uuid.UUID            `json:"invoice_id"`
+	ClientID   uuid.UUID            `json:"client_id"`
+	Provider   string               `json:"provider"`
+	Method     PaymentMethod        `json:"method"`
+	Channel    string               `json:"channel,omitempty"`
+	OrderID    string               `json:"order_id"`            // our reference sent to the gateway
+	Reference  string               `json:"reference,omitempty"` // gateway transaction ID
+	Amount     int64                `json:"amount"`
+	Status     PaymentRequestStatus `json:"status"`
+	VANumber   string               `json:"va_number,omitempty"`
+	QRString   string               `json:"qr_string,omitempty"`
+	PaymentURL string               `json:"payment_url,omitempty"`
+	ExpiresAt  *time.Time           `json:"expires_at,omitempty"`
+	PaymentID  *uuid.UUID           `json:"payment_id,omitempty"` // payment recorded when settled
+	PaidAt     *time.Time           `json:"paid_at,omitempty"`
+	CreatedBy  uuid.UUID            `json:"created_by"`
+	CreatedAt  time.Time            `json:"created_at"`
+	UpdatedAt  time.Time            `json:"updated_at"`
+}
+
+// IsOpen reports whether the request can still be paid
+func (p *PaymentRequest) IsOpen(now time.Time) bool {
+	return p.Status == PaymentRequestPending && (p.ExpiresAt == nil || p.ExpiresAt.After(now))
+}
+
+// PaymentWebhookEvent is a webhook received from a payment gateway
+type PaymentWebhookEvent struct {
+	ID        uuid.UUID `json:"id"`
+	TenantID  uuid.UUID `json:"tenant_id"`
+	Provider  string    `json:"provider"`
+	OrderID   string    `json:"order_id,omitempty"`
+	Reference string    `json:"reference,omitempty"`
+	Status    string    `json:"status,omitempty"`
+	Verified  bool      `json:"verified"`
+	Error     string    `json:"error,omitempty"`
+	Payload   string    `json:"payload"`
+	CreatedAt time.Time `json:"created_at"`
+}
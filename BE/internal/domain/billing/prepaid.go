@@ -0,0 +1,51 @@
+package billing
+
+import "time"
+
+// BillingMode tells whether a client pays after (postpaid) or before (prepaid) the service period
+type BillingMode string
+
+const (
+	BillingModePostpaid BillingMode = "postpaid"
+	BillingModePrepaid  BillingMode = "prepaid"
+)
+
+// IsValid checks if the billing mode is known
+func (m BillingMode) IsValid() bool {
+	return m == BillingModePostpaid || m == BillingModePrepaid
+}
+
+// ResolveBillingMode returns the client override if set, else the package mode, else postpaid
+func ResolveBillingMode(clientMode *BillingMode, packageMode BillingMode) BillingMode {
+	if clientMode != nil && clientMode.IsValid() {
+		return *clientMode
+	}
+	if packageMode.IsValid() {
+		return packageMode
+	}
+	return BillingModePostpaid
+}
+
+// PrepaidWindow returns the validity period bought with periods months of prepaid service. It
+// continues the day after activeUntil while the client is still covered, or starts today when the
+// validity lapsed (or was never set), so lapsed days are not billed.
+func PrepaidWindow(activeUntil *time.Time, today time.Time, periods int) (start, end time.Time) {
+	start = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.Local)
+	if activeUntil != nil {
+		next := time.Date(activeUntil.Year(), activeUntil.Month(), activeUntil.Day()+1, 0, 0, 0, 0, time.Local)
+		if !next.Before(start) {
+			start = next
+		}
+	}
+	end = start.AddDate(0, periods, -1)
+	return start, end
+}
+
+// PrepaidExpired reports whether the validity ended before today
+func PrepaidExpired(activeUntil *time.Time, today time.Time) bool {
+	if activeUntil == nil {
+		return false
+	}
+	until := time.Date(activeUntil.Year(), activeUntil.Month(), activeUntil.Day(), 0, 0, 0, 0, time.Local)
+	return until.Before(time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.Local))
+}
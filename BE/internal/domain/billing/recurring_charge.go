@@ -0,0 +1,22 @@
+package billing
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RecurringCharge is an extra billed on every monthly invoice of a client between StartDate and
+// EndDate (inclusive), e.g. a static public IP or ONT rental. Amount is the monthly price per unit.
+type RecurringCharge struct {
+	ID          uuid.UUID  `json:"id"`
+	TenantID    uuid.UUID  `json:"tenant_id"`
+	ClientID    uuid.UUID  `json:"client_id"`
+	Description string     `json:"description"`
+	Amount      int64      `json:"amount"`
+	Quantity    int        `json:"quantity"`
+	StartDate   time.Time  `json:"start_date"`
+	EndDate     *time.Time `json:"end_date,omitempty"` // nil = until removed
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
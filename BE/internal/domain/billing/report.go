@@ -0,0 +1,56 @@
+package billing
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RevenueDimension is what revenue is grouped by in reports
+type RevenueDimension string
+
+const (
+	RevenueByPackage   RevenueDimension = "package"
+	RevenueByGroup     RevenueDimension = "group"
+	RevenueByRouter    RevenueDimension = "router"
+	RevenueByCollector RevenueDimension = "collector"
+)
+
+// RevenueBasis tells when revenue is counted: cash when a payment is received, accrual when the
+// service period of an invoice starts
+type RevenueBasis string
+
+const (
+	RevenueBasisCash    RevenueBasis = "cash"
+	RevenueBasisAccrual RevenueBasis = "accrual"
+)
+
+// OpenReceivable is the unpaid part of an issued invoice
+type OpenReceivable struct {
+	InvoiceID       uuid.UUID `json:"invoice_id"`
+	InvoiceNumber   string    `json:"invoice_number"`
+	ClientID        uuid.UUID `json:"client_id"`
+	ClientName      string    `json:"client_name"`
+	ClientGroupName *string   `json:"client_group_name,omitempty"`
+	DueDate         time.Time `json:"due_date"`
+	Outstanding     int64     `json:"outstanding"`
+}
+
+// RevenueGroup is the revenue of one package, client group, router or collector. ID is nil for
+// revenue without one (e.g. payments not taken by a collector).
+type RevenueGroup struct {
+	ID      *uuid.UUID `json:"id,omitempty"`
+	Name    string     `json:"name"`
+	Amount  int64      `json:"amount"`
+	Tax     int64      `json:"tax"` // accrual basis only
+	Count   int        `json:"count"`
+	Clients int        `json:"clients"`
+}
+
+// MonthlyAmount is an amount counted in one calendar month
+type MonthlyAmount struct {
+	Month  time.Time `json:"month"` // first day of the month
+	Amount int64     `json:"amount"`
+	Tax    int64     `json:"tax"`
+	Count  int       `json:"count"`
+}
@@ -0,0 +1,19 @@
+package billing
+
+import "math"
+
+// ComputeTax splits an invoice into tax base (DPP), tax and total. The discount is taken off before tax.
+// With tax-inclusive pricing the lines already contain the tax, which is extracted from the discounted
+// amount; otherwise the tax is added on top. Nothing is taxed when the discounted amount is not positive.
+func ComputeTax(subtotal, discount int64, rate float64, inclusive bool) (base, tax, total int64) {
+	net := subtotal - discount
+	if rate <= 0 || net <= 0 {
+		return net, 0, net
+	}
+	if inclusive {
+		tax = int64(math.Round(float64(net) * rate / (100 + rate)))
+		return net - tax, tax, net
+	}
+	tax = int64(math.Round(float64(net) * rate / 100))
+	return net, tax, net + tax
+}
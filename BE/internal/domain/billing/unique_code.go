@@ -0,0 +1,27 @@
+package billing
+
+// UniqueCodeMode defines how the "kode unik" is applied to an invoice
+type UniqueCodeMode string
+
+const (
+	UniqueCodeAddToTotal UniqueCodeMode = "total"   // the code is added to TotalAmount
+	UniqueCodePayable    UniqueCodeMode = "payable" // only the amount to transfer includes the code; the extra becomes client credit
+)
+
+// MaxUniqueCode is the largest code (three digits)
+const MaxUniqueCode = 999
+
+// NextUniqueCode returns the lowest code in 1..max not used by another open invoice. Codes of paid
+// and cancelled invoices are not in used, so they are recycled.
+func NextUniqueCode(used []int, max int) (int, bool) {
+	taken := make(map[int]bool, len(used))
+	for _, c := range used {
+		taken[c] = true
+	}
+	for c := 1; c <= max; c++ {
+		if !taken[c] {
+			return c, true
+		}
+	}
+	return 0, false
+}
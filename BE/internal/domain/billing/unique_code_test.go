@@ -0,0 +1,36 @@
+package billing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextUniqueCode(t *testing.T) {
+	// The lowest free code is handed out, whatever the order of the used codes
+	code, ok := NextUniqueCode(nil, MaxUniqueCode)
+	assert.True(t, ok)
+	assert.Equal(t, 1, code)
+	code, ok = NextUniqueCode([]int{3, 1, 2, 5}, MaxUniqueCode)
+	assert.True(t, ok)
+	assert.Equal(t, 4, code)
+
+	// A code released by a paid or cancelled invoice is no longer used and is handed out again
+	code, ok = NextUniqueCode([]int{1, 3, 4}, MaxUniqueCode)
+	assert.True(t, ok)
+	assert.Equal(t, 2, code)
+
+	// Codes above the maximum do not block the range
+	code, ok = NextUniqueCode([]int{1, 2, 500}, 9)
+	assert.True(t, ok)
+	assert.Equal(t, 3, code)
+
+	// Every code of the range is used
+	used := []int{}
+	for c := 1; c <= 9; c++ {
+		used = append(used, c)
+	}
+	code, ok = NextUniqueCode(used, 9)
+	assert.False(t, ok)
+	assert.Equal(t, 0, code)
+}
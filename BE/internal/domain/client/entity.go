@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"rrnet/internal/domain/billing"
 )
 
 type Category string
@@ -69,6 +71,14 @@ type Client struct {
 	PaymentDueDay          int        `json:"payment_due_day"`      // 1-31
 	PaymentTempoTemplateID *uuid.UUID `json:"payment_tempo_template_id,omitempty"`
 
+	// Prepaid billing
+	BillingMode *billing.BillingMode `json:"billing_mode,omitempty"` // nil follows the service package
+	ActiveUntil *time.Time           `json:"active_until,omitempty"` // last day of paid service (prepaid)
+
+	// Billing cycle override in months (nil follows the package); cycles align to the anchor's month
+	BillingCycleMonths *int       `json:"billing_cycle_months,omitempty"`
+	BillingCycleAnchor *time.Time `json:"billing_cycle_anchor,omitempty"` // nil = month the client was created
+
 	Status             Status          `json:"status"`
 	IsolirReason       *string         `json:"isolir_reason,omitempty"`
 	IsolirAt           *time.Time      `json:"isolir_at,omitempty"`
@@ -130,4 +140,7 @@ type ClientListFilter struct {
 	GroupID  *uuid.UUID `json:"group_id,omitempty"` // Filter by client group
 	Page     int        `json:"page,omitempty"`
 	PageSize int        `json:"page_size,omitempty"`
+
+	ServicePackageID *uuid.UUID `json:"service_package_id,omitempty"`
+	RouterID         *uuid.UUID `json:"router_id,omitempty"`
 }
@@ -0,0 +1,113 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AssignmentStatus follows an invoice through the collector's hands
+type AssignmentStatus string
+
+const (
+	AssignmentAssigned  AssignmentStatus = "assigned"  // to be visited
+	AssignmentCollected AssignmentStatus = "collected" // cash taken at a successful visit
+	AssignmentDeposited AssignmentStatus = "deposited" // cash handed over in a deposit report
+	AssignmentConfirmed AssignmentStatus = "confirmed" // deposit confirmed by finance, payment recorded
+	AssignmentCancelled AssignmentStatus = "cancelled"
+)
+
+// VisitResult is the outcome of a visit to the client
+type VisitResult string
+
+const (
+	VisitSuccess VisitResult = "success"
+	VisitFailed  VisitResult = "failed"
+)
+
+// DepositStatus is the finance review state of a deposit report
+type DepositStatus string
+
+const (
+	DepositReported  DepositStatus = "reported"
+	DepositConfirmed DepositStatus = "confirmed"
+	DepositRejected  DepositStatus = "rejected" // collected invoices go back to the collector
+)
+
+// Assignment hands an invoice to a collector
+type Assignment struct {
+	ID          uuid.UUID        `json:"id"`
+	TenantID    uuid.UUID        `json:"tenant_id"`
+	CollectorID uuid.UUID        `json:"collector_id"`
+	InvoiceID   uuid.UUID        `json:"invoice_id"`
+	ClientID    uuid.UUID        `json:"client_id"`
+	Status      AssignmentStatus `json:"status"`
+	Amount      int64            `json:"amount"` // collected amount, taken from the invoice at the visit
+	Notes       *string          `json:"notes,omitempty"`
+	AssignedBy  uuid.UUID        `json:"assigned_by"`
+	AssignedAt  time.Time        `json:"assigned_at"`
+	CollectedAt *time.Time       `json:"collected_at,omitempty"`
+	DepositID   *uuid.UUID       `json:"deposit_id,omitempty"`
+	PaymentID   *uuid.UUID       `json:"payment_id,omitempty"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+
+	// Joined fields
+	CollectorName    string     `json:"collector_name,omitempty"`
+	InvoiceNumber    string     `json:"invoice_number,omitempty"`
+	InvoiceDueDate   time.Time  `json:"invoice_due_date"`
+	InvoiceTotal     int64      `json:"invoice_total"`
+	InvoiceRemaining int64      `json:"invoice_remaining"`
+	ClientCode       string     `json:"client_code,omitempty"`
+	ClientName       string     `json:"client_name,omitempty"`
+	ClientPhone      *string    `json:"client_phone,omitempty"`
+	ClientAddress    *string    `json:"client_address,omitempty"`
+	Latitude         *float64   `json:"latitude,omitempty"`
+	Longitude        *float64   `json:"longitude,omitempty"`
+	ODPID            *uuid.UUID `json:"odp_id,omitempty"`
+	LastVisitAt      *time.Time `json:"last_visit_at,omitempty"`
+}
+
+// CanVisit reports whether the collector can still log a visit
+func (a *Assignment) CanVisit() bool {
+	return a.Status == AssignmentAssigned
+}
+
+// CanCancel reports whether the office can take the invoice back; collected cash must be
+// deposited first
+func (a *Assignment) CanCancel() bool {
+	return a.Status == AssignmentAssigned
+}
+
+// Visit is one attempt to collect an assigned invoice, logged with the collector's GPS position
+type Visit struct {
+	ID           uuid.UUID   `json:"id"`
+	TenantID     uuid.UUID   `json:"tenant_id"`
+	AssignmentID uuid.UUID   `json:"assignment_id"`
+	CollectorID  uuid.UUID   `json:"collector_id"`
+	Result       VisitResult `json:"result"`
+	Amount       int64       `json:"amount"`
+	Latitude     float64     `json:"latitude"`
+	Longitude    float64     `json:"longitude"`
+	Reason       *string     `json:"reason,omitempty"` // why a visit failed
+	Notes        *string     `json:"notes,omitempty"`
+	VisitedAt    time.Time   `json:"visited_at"`
+}
+
+// Deposit bundles the collected invoices a collector hands over to finance
+type Deposit struct {
+	ID           uuid.UUID     `json:"id"`
+	TenantID     uuid.UUID     `json:"tenant_id"`
+	CollectorID  uuid.UUID     `json:"collector_id"`
+	Status       DepositStatus `json:"status"`
+	TotalAmount  int64         `json:"total_amount"`
+	Notes        *string       `json:"notes,omitempty"`
+	ReportedAt   time.Time     `json:"reported_at"`
+	ReviewedBy   *uuid.UUID    `json:"reviewed_by,omitempty"`
+	ReviewedAt   *time.Time    `json:"reviewed_at,omitempty"`
+	RejectReason *string       `json:"reject_reason,omitempty"`
+
+	// Joined fields
+	CollectorName   string        `json:"collector_name,omitempty"`
+	AssignmentCount int           `json:"assignment_count"`
+	Assignments     []*Assignment `json:"assignments,omitempty"`
+}
@@ -0,0 +1,58 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Point is a WGS84 position
+type Point struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// RouteStop is one client to visit, in route order
+type RouteStop struct {
+	Order         int        `json:"order"` // 1-based
+	Cluster       int        `json:"cluster"`
+	AssignmentID  uuid.UUID  `json:"assignment_id"`
+	InvoiceID     uuid.UUID  `json:"invoice_id"`
+	InvoiceNumber string     `json:"invoice_number"`
+	ClientID      uuid.UUID  `json:"client_id"`
+	ClientCode    string     `json:"client_code"`
+	ClientName    string     `json:"client_name"`
+	ClientPhone   *string    `json:"client_phone,omitempty"`
+	ClientAddress *string    `json:"client_address,omitempty"`
+	ODPID         *uuid.UUID `json:"odp_id,omitempty"`
+	Amount        int64      `json:"amount"` // remaining invoice amount
+	Point
+	LegKm        float64 `json:"leg_km"`        // estimated road distance from the previous stop
+	CumulativeKm float64 `json:"cumulative_km"` // from the start of the route
+}
+
+// RouteSource tells where the route starts
+type RouteSource string
+
+const (
+	RouteStartRequest   RouteSource = "request"    // position sent by the app
+	RouteStartLastVisit RouteSource = "last_visit" // position of the last visit logged that day
+	RouteStartFirstStop RouteSource = "first_stop" // no position known: starts at the most overdue client
+)
+
+// Route is the planned visiting order of a collector's open assignments for one day. It is
+// planned again on every request, so visits logged during the day drop out of it and move the
+// start to the collector's last position.
+type Route struct {
+	CollectorID      uuid.UUID      `json:"collector_id"`
+	Date             time.Time      `json:"date"`
+	Start            *Point         `json:"start,omitempty"`
+	StartSource      RouteSource    `json:"start_source"`
+	Stops            []*RouteStop   `json:"stops"`
+	Clusters         int            `json:"clusters"`
+	TotalDistanceKm  float64        `json:"total_distance_km"`
+	EstimatedMinutes int            `json:"estimated_minutes"` // travel plus time at each stop
+	Unlocated        []*Assignment  `json:"unlocated"`         // clients without coordinates
+	VisitedToday     []*Assignment  `json:"visited_today"`     // failed visit that day, not planned again
+	GeoJSON          map[string]any `json:"geojson"`           // FeatureCollection for the map
+}
@@ -0,0 +1,119 @@
+package commission
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RuleType defines how a commission is computed from the payments a user collected
+type RuleType string
+
+const (
+	RulePercent RuleType = "percent"          // percent of the collected amount
+	RuleFlat    RuleType = "flat_per_invoice" // fixed amount per invoice paid
+	RuleTiered  RuleType = "tiered"           // percent of the tier reached by the monthly volume
+)
+
+// RuleScope tells who a rule pays; a user rule wins over the rule of the user's role
+type RuleScope string
+
+const (
+	ScopeRole RuleScope = "role"
+	ScopeUser RuleScope = "user"
+)
+
+// Tier applies Percent to the whole monthly volume once it reaches MinAmount
+type Tier struct {
+	MinAmount int64   `json:"min_amount"`
+	Percent   float64 `json:"percent"`
+}
+
+// Rule is one version of a commission rule. Versions are never changed: an update stores a new
+// version with the same RuleKey, so statements keep pointing at the version they were computed
+// with. A retired rule is a last version with Active false.
+type Rule struct {
+	ID            uuid.UUID  `json:"id"` // of this version
+	TenantID      uuid.UUID  `json:"tenant_id"`
+	RuleKey       uuid.UUID  `json:"rule_key"` // shared by every version
+	Version       int        `json:"version"`
+	Name          string     `json:"name"`
+	Scope         RuleScope  `json:"scope"`
+	RoleCode      *string    `json:"role_code,omitempty"`
+	UserID        *uuid.UUID `json:"user_id,omitempty"`
+	Type          RuleType   `json:"type"`
+	Percent       float64    `json:"percent,omitempty"`     // percent
+	FlatAmount    int64      `json:"flat_amount,omitempty"` // flat_per_invoice
+	Tiers         []Tier     `json:"tiers,omitempty"`       // tiered, ascending MinAmount
+	Active        bool       `json:"active"`
+	EffectiveFrom time.Time  `json:"effective_from"` // first day the version applies
+	CreatedBy     uuid.UUID  `json:"created_by"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// Compute returns the commission for a month in which the user collected amount over invoices
+func (r *Rule) Compute(amount int64, invoices int) int64 {
+	switch r.Type {
+	case RulePercent:
+		return int64(math.Round(float64(amount) * r.Percent / 100))
+	case RuleFlat:
+		return r.FlatAmount * int64(invoices)
+	case RuleTiered:
+		var percent float64
+		for _, t := range r.Tiers {
+			if amount >= t.MinAmount {
+				percent = t.Percent
+			}
+		}
+		return int64(math.Round(float64(amount) * percent / 100))
+	}
+	return 0
+}
+
+// StatementStatus is the approval and payout state of a monthly statement
+type StatementStatus string
+
+const (
+	StatementDraft    StatementStatus = "draft" // recomputed when the month is generated again
+	StatementApproved StatementStatus = "approved"
+	StatementPaid     StatementStatus = "paid"
+)
+
+// Statement is the commission of one user for one month
+type Statement struct {
+	ID               uuid.UUID       `json:"id"`
+	TenantID         uuid.UUID       `json:"tenant_id"`
+	UserID           uuid.UUID       `json:"user_id"`
+	PeriodStart      time.Time       `json:"period_start"`
+	RuleID           uuid.UUID       `json:"rule_id"` // rule version used
+	Rule             Rule            `json:"rule"`    // snapshot of that version
+	CollectedAmount  int64           `json:"collected_amount"`
+	PaymentCount     int             `json:"payment_count"`
+	InvoiceCount     int             `json:"invoice_count"`
+	CommissionAmount int64           `json:"commission_amount"`
+	Status           StatementStatus `json:"status"`
+	ApprovedBy       *uuid.UUID      `json:"approved_by,omitempty"`
+	ApprovedAt       *time.Time      `json:"approved_at,omitempty"`
+	PaidBy           *uuid.UUID      `json:"paid_by,omitempty"`
+	PaidAt           *time.Time      `json:"paid_at,omitempty"`
+	PayoutReference  *string         `json:"payout_reference,omitempty"`
+	GeneratedAt      time.Time       `json:"generated_at"`
+
+	// Joined fields
+	UserName string           `json:"user_name,omitempty"`
+	RoleCode string           `json:"role_code,omitempty"`
+	Lines    []*StatementLine `json:"lines,omitempty"`
+}
+
+// StatementLine is one payment counted in a statement
+type StatementLine struct {
+	PaymentID     uuid.UUID `json:"payment_id"`
+	InvoiceID     uuid.UUID `json:"invoice_id"`
+	InvoiceNumber string    `json:"invoice_number"`
+	ClientName    string    `json:"client_name"`
+	Amount        int64     `json:"amount"`
+	Method        string    `json:"method"`
+	ReceivedAt    time.Time `json:"received_at"`
+	ConfirmedAt   time.Time `json:"confirmed_at"` // when the payment was recorded
+}
@@ -23,6 +23,7 @@ type Discount struct {
 	Type      Type       `json:"type"`
 	Value     float64    `json:"value"` // Percentage (0-100) or nominal amount
 	ExpiresAt *time.Time `json:"expires_at,omitempty"` // NULL = never expires
+	MaxCycles *int       `json:"max_cycles,omitempty"` // NULL = every cycle; N = first N billing cycles (invoices) per client, whatever the cycle length
 	IsActive  bool       `json:"is_active"`
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
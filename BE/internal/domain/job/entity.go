@@ -0,0 +1,52 @@
+package job
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Trigger tells what started a run
+type Trigger string
+
+const (
+	TriggerSchedule Trigger = "schedule"
+	TriggerManual   Trigger = "manual"
+)
+
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusSkipped   Status = "skipped" // another run of the job was still in progress
+)
+
+// Stats holds the counters reported by a run (e.g. invoices_created, errors)
+type Stats map[string]int64
+
+// Definition describes a scheduled background job. Per-tenant jobs run as one sub-task per active
+// tenant, so a failing tenant is retried on its own.
+type Definition struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Cronspec    string `json:"cronspec"` // server local time
+	PerTenant   bool   `json:"per_tenant"`
+}
+
+// Run is one execution of a job. Runs of per-tenant jobs have a child run per tenant and attempt.
+type Run struct {
+	ID          uuid.UUID  `json:"id"`
+	Job         string     `json:"job"`
+	ParentID    *uuid.UUID `json:"parent_id,omitempty"`
+	TenantID    *uuid.UUID `json:"tenant_id,omitempty"`
+	Trigger     Trigger    `json:"trigger"`
+	Status      Status     `json:"status"`
+	Attempt     int        `json:"attempt"`
+	Stats       Stats      `json:"stats"`
+	Error       *string    `json:"error,omitempty"`
+	TriggeredBy *uuid.UUID `json:"triggered_by,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,184 @@
+package ledger
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AccountType decides on which side an account grows
+type AccountType string
+
+const (
+	AccountAsset         AccountType = "asset"
+	AccountLiability     AccountType = "liability"
+	AccountEquity        AccountType = "equity"
+	AccountRevenue       AccountType = "revenue"
+	AccountContraRevenue AccountType = "contra_revenue" // reduces revenue, e.g. discounts
+	AccountExpense       AccountType = "expense"
+)
+
+// Valid reports whether t is a known account type
+func (t AccountType) Valid() bool {
+	switch t {
+	case AccountAsset, AccountLiability, AccountEquity, AccountRevenue, AccountContraRevenue, AccountExpense:
+		return true
+	}
+	return false
+}
+
+// DebitNormal reports whether the account's balance is debit minus credit
+func (t AccountType) DebitNormal() bool {
+	return t == AccountAsset || t == AccountContraRevenue || t == AccountExpense
+}
+
+// SystemKey identifies an account that receives automatic postings
+type SystemKey string
+
+const (
+	KeyCash           SystemKey = "cash"
+	KeyBank           SystemKey = "bank"
+	KeyCollectorFloat SystemKey = "collector_float" // cash collected in the field, not yet deposited
+	KeyReceivable     SystemKey = "receivable"
+	KeyClientCredit   SystemKey = "client_credit" // balance owed to clients (overpayments, credits)
+	KeyTaxPayable     SystemKey = "tax_payable"
+	KeyOpeningEquity  SystemKey = "opening_equity"
+	KeyRevenue        SystemKey = "revenue"
+	KeyDiscounts      SystemKey = "discounts"
+)
+
+// SystemAccount is the chart entry of a system account
+type SystemAccount struct {
+	Key  SystemKey
+	Code string
+	Name string
+	Type AccountType
+}
+
+// SystemAccounts are created for every tenant on first posting
+var SystemAccounts = []SystemAccount{
+	{KeyCash, "1101", "Kas", AccountAsset},
+	{KeyBank, "1102", "Bank", AccountAsset},
+	{KeyCollectorFloat, "1103", "Kas di Kolektor", AccountAsset},
+	{KeyReceivable, "1201", "Piutang Pelanggan", AccountAsset},
+	{KeyClientCredit, "2101", "Saldo Pelanggan", AccountLiability},
+	{KeyTaxPayable, "2102", "PPN Keluaran", AccountLiability},
+	{KeyOpeningEquity, "3101", "Ekuitas Saldo Awal", AccountEquity},
+	{KeyRevenue, "4101", "Pendapatan Layanan", AccountRevenue},
+	{KeyDiscounts, "4102", "Potongan Penjualan", AccountContraRevenue},
+}
+
+// Account is a ledger account of a tenant
+type Account struct {
+	ID        uuid.UUID   `json:"id"`
+	TenantID  uuid.UUID   `json:"tenant_id"`
+	Code      string      `json:"code"`
+	Name      string      `json:"name"`
+	Type      AccountType `json:"type"`
+	SystemKey *SystemKey  `json:"system_key,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// Source is the kind of document a journal entry was posted for
+type Source string
+
+const (
+	SourceInvoice       Source = "invoice"
+	SourceInvoiceChange Source = "invoice_change" // items appended to an issued invoice
+	SourceInvoiceCancel Source = "invoice_cancel"
+	SourceCreditNote    Source = "credit_note"
+	SourcePayment       Source = "payment"
+	SourceClientPayment Source = "client_payment" // one payment allocated over several invoices
+	SourceClientBalance Source = "client_balance" // client credit applied, refunded or granted
+	SourceCollection    Source = "collection"     // cash taken by a collector at a visit
+	SourceManual        Source = "manual"
+)
+
+// Entry is a balanced journal entry
+type Entry struct {
+	ID          uuid.UUID  `json:"id"`
+	TenantID    uuid.UUID  `json:"tenant_id"`
+	Date        time.Time  `json:"date"`
+	Source      Source     `json:"source"`
+	SourceID    uuid.UUID  `json:"source_id"`
+	Description string     `json:"description"`
+	ClientID    *uuid.UUID `json:"client_id,omitempty"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Lines       []*Line    `json:"lines"`
+}
+
+// Balanced reports whether debits equal credits and every line has exactly one side
+func (e *Entry) Balanced() bool {
+	if len(e.Lines) < 2 {
+		return false
+	}
+	var debit, credit int64
+	for _, l := range e.Lines {
+		if l.Debit < 0 || l.Credit < 0 || (l.Debit == 0) == (l.Credit == 0) {
+			return false
+		}
+		debit += l.Debit
+		credit += l.Credit
+	}
+	return debit == credit
+}
+
+// Line is one debit or credit of an entry
+type Line struct {
+	ID        uuid.UUID `json:"id"`
+	EntryID   uuid.UUID `json:"entry_id"`
+	AccountID uuid.UUID `json:"account_id"`
+	Debit     int64     `json:"debit"`
+	Credit    int64     `json:"credit"`
+	Memo      *string   `json:"memo,omitempty"`
+
+	// Joined fields
+	AccountCode string `json:"account_code,omitempty"`
+	AccountName string `json:"account_name,omitempty"`
+}
+
+// TrialBalanceRow is the debit and credit total of one account up to a date
+type TrialBalanceRow struct {
+	AccountID   uuid.UUID   `json:"account_id"`
+	AccountCode string      `json:"account_code"`
+	AccountName string      `json:"account_name"`
+	AccountType AccountType `json:"account_type"`
+	Debit       int64       `json:"debit"`
+	Credit      int64       `json:"credit"`
+	Balance     int64       `json:"balance"` // on the account's normal side
+}
+
+// TrialBalance lists every account with postings; debits equal credits when the books are sound
+type TrialBalance struct {
+	AsOf        string             `json:"as_of"`
+	Rows        []*TrialBalanceRow `json:"rows"`
+	TotalDebit  int64              `json:"total_debit"`
+	TotalCredit int64              `json:"total_credit"`
+	Balanced    bool               `json:"balanced"`
+}
+
+// LedgerLine is a posting of the general ledger of one account, with the running balance
+type LedgerLine struct {
+	EntryID     uuid.UUID `json:"entry_id"`
+	Date        time.Time `json:"date"`
+	Source      Source    `json:"source"`
+	SourceID    uuid.UUID `json:"source_id"`
+	Description string    `json:"description"`
+	Memo        *string   `json:"memo,omitempty"`
+	Debit       int64     `json:"debit"`
+	Credit      int64     `json:"credit"`
+	Balance     int64     `json:"balance"`
+}
+
+// GeneralLedger is the postings of one account in a period
+type GeneralLedger struct {
+	Account        *Account      `json:"account"`
+	From           string        `json:"from"`
+	To             string        `json:"to"`
+	OpeningBalance int64         `json:"opening_balance"`
+	Lines          []*LedgerLine `json:"lines"`
+	TotalDebit     int64         `json:"total_debit"`
+	TotalCredit    int64         `json:"total_credit"`
+	ClosingBalance int64         `json:"closing_balance"`
+}
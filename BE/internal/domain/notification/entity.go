@@ -0,0 +1,105 @@
+package notification
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event is a billing event that sends the client a WhatsApp message
+type Event string
+
+const (
+	EventInvoiceCreated    Event = "invoice_created"
+	EventInvoiceDueSoon    Event = "invoice_due_soon" // Days before the due date
+	EventInvoiceOverdue    Event = "invoice_overdue"  // Days after the due date
+	EventPaymentReceived   Event = "payment_received"
+	EventClientIsolated    Event = "client_isolated"
+	EventClientReactivated Event = "client_reactivated"
+)
+
+// Events lists every event in the order they are shown in the settings
+var Events = []Event{
+	EventInvoiceCreated,
+	EventInvoiceDueSoon,
+	EventInvoiceOverdue,
+	EventPaymentReceived,
+	EventClientIsolated,
+	EventClientReactivated,
+}
+
+// Scheduled tells whether the event is found by the daily job rather than raised by an action
+func (e Event) Scheduled() bool {
+	return e == EventInvoiceDueSoon || e == EventInvoiceOverdue
+}
+
+// EventSetting configures one event. Without a template the built-in text is sent.
+type EventSetting struct {
+	Event      Event      `json:"event"`
+	Enabled    bool       `json:"enabled"`
+	TemplateID *uuid.UUID `json:"template_id,omitempty"`
+	Days       int        `json:"days,omitempty"` // due_soon and overdue only
+}
+
+// Settings is stored in tenant settings under "wa_notifications". Messages raised outside the send
+// window (HH:MM in Timezone, an IANA zone such as Asia/Jakarta) are held until the window opens.
+type Settings struct {
+	Enabled     bool           `json:"enabled"`
+	WindowStart string         `json:"window_start"`
+	WindowEnd   string         `json:"window_end"`
+	Timezone    string         `json:"timezone"`
+	Events      []EventSetting `json:"events"`
+}
+
+// Event returns the setting of an event
+func (s *Settings) Event(e Event) (EventSetting, bool) {
+	for _, es := range s.Events {
+		if es.Event == e {
+			return es, true
+		}
+	}
+	return EventSetting{}, false
+}
+
+// Status is the delivery state of a notification
+type Status string
+
+const (
+	StatusQueued  Status = "queued"
+	StatusSent    Status = "sent"
+	StatusSkipped Status = "skipped" // opted out, disabled, no phone, invoice already paid
+	StatusFailed  Status = "failed"
+)
+
+// Notification is one message raised for a client. DedupKey is unique per tenant, so an event
+// raised twice (retried task, job run again) is only sent once.
+type Notification struct {
+	ID          uuid.UUID         `json:"id"`
+	TenantID    uuid.UUID         `json:"tenant_id"`
+	Event       Event             `json:"event"`
+	ClientID    uuid.UUID         `json:"client_id"`
+	InvoiceID   *uuid.UUID        `json:"invoice_id,omitempty"`
+	DedupKey    string            `json:"dedup_key"`
+	Data        map[string]string `json:"data,omitempty"` // facts known when the event was raised
+	Status      Status            `json:"status"`
+	ScheduledAt time.Time         `json:"scheduled_at"`
+	SentAt      *time.Time        `json:"sent_at,omitempty"`
+	WALogID     *uuid.UUID        `json:"wa_log_id,omitempty"`
+	Error       *string           `json:"error,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+
+	// Joined fields
+	ClientName string `json:"client_name,omitempty"`
+}
+
+// OptOut stops every billing notification to a client
+type OptOut struct {
+	TenantID  uuid.UUID  `json:"tenant_id"`
+	ClientID  uuid.UUID  `json:"client_id"`
+	Reason    *string    `json:"reason,omitempty"`
+	CreatedBy *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	// Joined fields
+	ClientName string `json:"client_name,omitempty"`
+}
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"rrnet/internal/domain/billing"
 )
 
 type Category string
@@ -35,6 +37,9 @@ type ServicePackage struct {
 	PriceMonthly      float64         `json:"price_monthly"`
 	PricePerDevice    float64         `json:"price_per_device"`
 	BillingDayDefault *int            `json:"billing_day_default,omitempty"`
+	BillingMode       billing.BillingMode `json:"billing_mode"` // default for clients of the package
+	BillingCycles     []CycleOption   `json:"billing_cycles"`       // multi-month cycles offered, with their price
+	DefaultCycleMonths int            `json:"default_cycle_months"` // cycle of clients without an override (1 = monthly)
 	NetworkProfileID  uuid.UUID       `json:"network_profile_id"`
 	IsActive          bool            `json:"is_active"`
 	Metadata          json.RawMessage `json:"metadata,omitempty"`
@@ -44,3 +49,24 @@ type ServicePackage struct {
 }
 
 
+
+// CycleOption is the price of a multi-month billing cycle, usually discounted against PriceMonthly
+// (or PricePerDevice, per device) times Months
+type CycleOption struct {
+	Months int     `json:"months"`
+	Price  float64 `json:"price"`
+}
+
+// CyclePrice returns the price of a months-long cycle: the offered cycle price, or the monthly
+// price times months. For per-device packages the price is per device.
+func (p *ServicePackage) CyclePrice(months int) float64 {
+	for _, o := range p.BillingCycles {
+		if o.Months == months {
+			return o.Price
+		}
+	}
+	if p.PricingModel == PricingModelPerDevice {
+		return p.PricePerDevice * float64(months)
+	}
+	return p.PriceMonthly * float64(months)
+}
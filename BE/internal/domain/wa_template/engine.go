@@ -0,0 +1,386 @@
+package wa_template
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"rrnet/pkg/utils"
+)
+
+// VarType is the kind of value a template variable holds; filters are checked against it
+type VarType string
+
+const (
+	VarText  VarType = "text"
+	VarMoney VarType = "money" // int64 rupiah
+	VarDate  VarType = "date"  // time.Time
+)
+
+// Variable is a placeholder a template may use, e.g. {{client.name}}
+type Variable struct {
+	Name        string  `json:"name"`
+	Type        VarType `json:"type"`
+	Description string  `json:"description"`
+}
+
+// Variables are all placeholders known to the template engine. Values that do not apply to a
+// recipient (e.g. invoice variables of a client without an open invoice) render empty.
+var Variables = []Variable{
+	{"client.name", VarText, "Client name"},
+	{"client.code", VarText, "Client code"},
+	{"client.phone", VarText, "Client phone number"},
+	{"client.address", VarText, "Client address"},
+	{"tenant.name", VarText, "Name of the ISP"},
+	{"invoice.number", VarText, "Invoice number"},
+	{"invoice.period_start", VarDate, "First day of the invoiced period"},
+	{"invoice.period_end", VarDate, "Last day of the invoiced period"},
+	{"invoice.due_date", VarDate, "Due date of the invoice"},
+	{"invoice.total", VarMoney, "Invoice total"},
+	{"invoice.payable", VarMoney, "Amount to transfer, including an unpaid unique code"},
+	{"invoice.paid", VarMoney, "Amount paid so far"},
+	{"invoice.remaining", VarMoney, "Amount still to pay"},
+	{"invoice.unique_code", VarText, "Unique transfer code of the invoice"},
+	{"payment.amount", VarMoney, "Amount of the received payment"},
+	{"payment.method", VarText, "Method of the received payment"},
+	{"payment.date", VarDate, "Date the payment was received"},
+	{"payment.invoices", VarText, "Invoice numbers the payment was allocated to"},
+	{"isolir.reason", VarText, "Reason the service was isolated or reactivated"},
+	{"payment_link", VarText, "Checkout URL of the open payment gateway request of the invoice"},
+}
+
+// Filters: rupiah (money), date with an optional Go layout (date), upper, lower and default
+// with a fallback text for empty values
+var templateFilters = map[string]struct {
+	in      VarType // "" accepts any type
+	minArgs int
+	maxArgs int
+}{
+	"rupiah":  {in: VarMoney},
+	"date":    {in: VarDate, maxArgs: 1},
+	"upper":   {},
+	"lower":   {},
+	"default": {minArgs: 1, maxArgs: 1},
+}
+
+// defaultDateLayout formats dates without a date filter
+const defaultDateLayout = "02-01-2006"
+
+// ErrTemplateInvalid is wrapped by every parse error, with the offending placeholder in the message
+var ErrTemplateInvalid = errors.New("invalid template")
+
+// legacyPlaceholders map the single-brace placeholders of templates written before the engine to
+// their expression, so stored texts keep rendering
+var legacyPlaceholders = map[string]string{
+	"client_name":      "client.name",
+	"client_code":      "client.code",
+	"invoice_number":   "invoice.number",
+	"period":           `invoice.period_start | date "01-2006"`,
+	"due_date":         "invoice.due_date",
+	"amount":           "invoice.total | rupiah",
+	"payable_amount":   "invoice.payable | rupiah",
+	"remaining_amount": "invoice.remaining | rupiah",
+	"unique_code":      "invoice.unique_code",
+	"paid_amount":      "payment.amount | rupiah",
+	"payment_method":   "payment.method",
+	"payment_date":     "payment.date",
+	"reason":           "isolir.reason",
+}
+
+var legacyPlaceholder = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// Values are the per-recipient values of variables: string for text, int64 for money and
+// time.Time for dates. Missing variables render empty.
+type Values map[string]any
+
+// Message is a parsed template, rendered once per recipient
+type Message struct {
+	parts []messagePart
+}
+
+type messagePart struct {
+	text string
+	expr *expression
+}
+
+type expression struct {
+	name    string
+	filters []filterCall
+}
+
+type filterCall struct {
+	name string
+	args []string
+}
+
+// Parse parses a template text. Placeholders are {{variable | filter arg ...}}, e.g.
+// {{invoice.total | rupiah}} or {{invoice.due_date | date "02 Jan 2006"}}. Unknown variables and
+// filters, and filters that do not fit the type of the variable, are rejected.
+func Parse(content string) (*Message, error) {
+	m := &Message{}
+	rest := content
+	for {
+		start := strings.Index(rest, "{{")
+		if start < 0 {
+			break
+		}
+		if err := m.addText(rest[:start]); err != nil {
+			return nil, err
+		}
+		end := strings.Index(rest[start+2:], "}}")
+		if end < 0 {
+			return nil, fmt.Errorf("%w: unclosed placeholder %q", ErrTemplateInvalid, rest[start:])
+		}
+		raw := rest[start+2 : start+2+end]
+		expr, err := parseExpression(raw)
+		if err != nil {
+			return nil, err
+		}
+		m.parts = append(m.parts, messagePart{expr: expr})
+		rest = rest[start+2+end+2:]
+	}
+	if err := m.addText(rest); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Validate checks that content parses
+func Validate(content string) error {
+	_, err := Parse(content)
+	return err
+}
+
+// Render parses content and renders it with values
+func Render(content string, values Values) (string, error) {
+	m, err := Parse(content)
+	if err != nil {
+		return "", err
+	}
+	return m.Render(values), nil
+}
+
+// addText appends literal text, turning known legacy {name} placeholders into expressions
+func (m *Message) addText(text string) error {
+	last := 0
+	for _, loc := range legacyPlaceholder.FindAllStringSubmatchIndex(text, -1) {
+		src, ok := legacyPlaceholders[text[loc[2]:loc[3]]]
+		if !ok {
+			continue
+		}
+		expr, err := parseExpression(src)
+		if err != nil {
+			return err
+		}
+		if loc[0] > last {
+			m.parts = append(m.parts, messagePart{text: text[last:loc[0]]})
+		}
+		m.parts = append(m.parts, messagePart{expr: expr})
+		last = loc[1]
+	}
+	if last < len(text) {
+		m.parts = append(m.parts, messagePart{text: text[last:]})
+	}
+	return nil
+}
+
+// Uses reports whether the message uses a variable whose name starts with prefix
+func (m *Message) Uses(prefix string) bool {
+	for _, p := range m.parts {
+		if p.expr != nil && strings.HasPrefix(p.expr.name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Render renders the message for one recipient
+func (m *Message) Render(values Values) string {
+	var b strings.Builder
+	for _, p := range m.parts {
+		if p.expr == nil {
+			b.WriteString(p.text)
+			continue
+		}
+		b.WriteString(p.expr.eval(values))
+	}
+	return b.String()
+}
+
+func parseExpression(raw string) (*expression, error) {
+	tokens, err := tokenize(raw)
+	if err != nil {
+		return nil, err
+	}
+	// Segments separated by |: the variable, then one filter call each
+	var segments [][]string
+	cur := []string{}
+	for _, tok := range tokens {
+		if tok == "|" {
+			segments = append(segments, cur)
+			cur = []string{}
+			continue
+		}
+		cur = append(cur, tok)
+	}
+	segments = append(segments, cur)
+
+	if len(segments[0]) != 1 {
+		return nil, fmt.Errorf("%w: placeholder {{%s}} must start with one variable", ErrTemplateInvalid, strings.TrimSpace(raw))
+	}
+	e := &expression{name: unquote(segments[0][0])}
+	typ, ok := variableType(e.name)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown variable %q", ErrTemplateInvalid, e.name)
+	}
+	for _, seg := range segments[1:] {
+		if len(seg) == 0 {
+			return nil, fmt.Errorf("%w: empty filter in {{%s}}", ErrTemplateInvalid, strings.TrimSpace(raw))
+		}
+		name := seg[0]
+		spec, ok := templateFilters[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown filter %q", ErrTemplateInvalid, name)
+		}
+		if spec.in != "" && spec.in != typ {
+			return nil, fmt.Errorf("%w: filter %q needs a %s variable, %s is %s", ErrTemplateInvalid, name, spec.in, e.name, typ)
+		}
+		args := seg[1:]
+		if len(args) < spec.minArgs || len(args) > spec.maxArgs {
+			return nil, fmt.Errorf("%w: wrong number of arguments for filter %q", ErrTemplateInvalid, name)
+		}
+		call := filterCall{name: name}
+		for _, a := range args {
+			call.args = append(call.args, unquote(a))
+		}
+		e.filters = append(e.filters, call)
+		typ = VarText
+	}
+	return e, nil
+}
+
+// tokenize splits an expression into words, "quoted strings" and | separators
+func tokenize(raw string) ([]string, error) {
+	var out []string
+	i := 0
+	for i < len(raw) {
+		switch c := raw[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '|':
+			out = append(out, "|")
+			i++
+		case c == '"':
+			end := strings.IndexByte(raw[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated string in {{%s}}", ErrTemplateInvalid, strings.TrimSpace(raw))
+			}
+			out = append(out, raw[i:i+end+2])
+			i += end + 2
+		default:
+			j := i
+			for j < len(raw) && !strings.ContainsRune(" \t\n\r|\"", rune(raw[j])) {
+				j++
+			}
+			out = append(out, raw[i:j])
+			i = j
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%w: empty placeholder {{}}", ErrTemplateInvalid)
+	}
+	return out, nil
+}
+
+func unquote(tok string) string {
+	if len(tok) >= 2 && tok[0] == '"' && tok[len(tok)-1] == '"' {
+		return tok[1 : len(tok)-1]
+	}
+	return tok
+}
+
+func variableType(name string) (VarType, bool) {
+	for _, v := range Variables {
+		if v.Name == name {
+			return v.Type, true
+		}
+	}
+	return "", false
+}
+
+func (e *expression) eval(values Values) string {
+	v := values[e.name]
+	for _, f := range e.filters {
+		switch f.name {
+		case "rupiah":
+			if amount, ok := v.(int64); ok {
+				v = utils.FormatRupiah(amount)
+			} else {
+				v = ""
+			}
+		case "date":
+			layout := defaultDateLayout
+			if len(f.args) > 0 {
+				layout = f.args[0]
+			}
+			if t, ok := v.(time.Time); ok && !t.IsZero() {
+				v = t.Format(layout)
+			} else {
+				v = ""
+			}
+		case "upper":
+			v = strings.ToUpper(formatValue(v))
+		case "lower":
+			v = strings.ToLower(formatValue(v))
+		case "default":
+			if formatValue(v) == "" {
+				v = f.args[0]
+			}
+		}
+	}
+	return formatValue(v)
+}
+
+// formatValue is the text of a value without filters
+func formatValue(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case time.Time:
+		if x.IsZero() {
+			return ""
+		}
+		return x.Format(defaultDateLayout)
+	}
+	return ""
+}
+
+// ParseValues converts values stored as text (money as digits, dates as RFC 3339) to typed values.
+// Unknown names and unparsable values are dropped.
+func ParseValues(data map[string]string) Values {
+	out := make(Values, len(data))
+	for name, s := range data {
+		typ, ok := variableType(name)
+		if !ok {
+			continue
+		}
+		switch typ {
+		case VarMoney:
+			if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+				out[name] = n
+			}
+		case VarDate:
+			if t, err := time.Parse(time.RFC3339, s); err == nil {
+				out[name] = t
+			}
+		default:
+			out[name] = s
+		}
+	}
+	return out
+}
@@ -6,7 +6,8 @@ import (
 	"github.com/google/uuid"
 )
 
-// Template is a tenant-scoped plain-text WhatsApp template.
+// Template is a tenant-scoped WhatsApp template. Content may use {{variable}} placeholders,
+// resolved per recipient (see Parse).
 type Template struct {
 	ID        uuid.UUID `json:"id"`
 	TenantID  uuid.UUID `json:"tenant_id"`
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/auth"
+	"rrnet/internal/repository"
+	"rrnet/internal/service"
+)
+
+const maxBankStatementFile = 10 << 20
+
+type BankReconciliationHandler struct {
+	reconciliationService *service.BankReconciliationService
+}
+
+func NewBankReconciliationHandler(reconciliationService *service.BankReconciliationService) *BankReconciliationHandler {
+	return &BankReconciliationHandler{reconciliationService: reconciliationService}
+}
+
+func (h *BankReconciliationHandler) sendServiceError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, repository.ErrBankStatementNotFound), errors.Is(err, repository.ErrBankStatementLineNotFound),
+		errors.Is(err, service.ErrInvoiceNotFound), errors.Is(err, repository.ErrClientNotFound):
+		sendError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrBankStatementUnreadable), errors.Is(err, service.ErrBankStatementBank),
+		errors.Is(err, service.ErrBankLineNoTarget):
+		sendError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrBankLineConfirmed), errors.Is(err, service.ErrBankLineInvoiceInvalid):
+		sendError(w, http.StatusConflict, err.Error())
+	default:
+		log.Error().Err(err).Msg(msg)
+		sendError(w, http.StatusInternalServerError, msg)
+	}
+}
+
+// Import uploads a mutation CSV as multipart field "file", with optional field "bank"
+// (POST /api/v1/billing/bank-statements)
+func (h *BankReconciliationHandler) Import(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		sendError(w, http.StatusBadRequest, "No user context")
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBankStatementFile+1<<20)
+	if err := r.ParseMultipartForm(maxBankStatementFile); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid upload: send the statement as multipart field \"file\"")
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Missing statement file")
+		return
+	}
+	defer file.Close()
+
+	out, err := h.reconciliationService.ImportStatement(r.Context(), tenantID, userID, service.ImportBankStatementRequest{
+		FileName: header.Filename,
+		Bank:     r.FormValue("bank"),
+	}, file)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to import bank statement")
+		return
+	}
+	sendJSON(w, http.StatusCreated, out)
+}
+
+func (h *BankReconciliationHandler) List(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	out, total, err := h.reconciliationService.ListStatements(r.Context(), tenantID, page, pageSize)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to list bank statements")
+		return
+	}
+	sendJSON(w, http.StatusOK, map[string]interface{}{"data": out, "total": total})
+}
+
+// Get returns a statement with its credits per bucket (GET /api/v1/billing/bank-statements/{id})
+func (h *BankReconciliationHandler) Get(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	statementID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid statement ID")
+		return
+	}
+	out, err := h.reconciliationService.GetStatement(r.Context(), tenantID, statementID)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to get bank statement")
+		return
+	}
+	sendJSON(w, http.StatusOK, out)
+}
+
+// Rematch matches the open credits again (POST /api/v1/billing/bank-statements/{id}/rematch)
+func (h *BankReconciliationHandler) Rematch(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	statementID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid statement ID")
+		return
+	}
+	out, err := h.reconciliationService.Rematch(r.Context(), tenantID, statementID)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to match bank statement")
+		return
+	}
+	sendJSON(w, http.StatusOK, out)
+}
+
+// ConfirmAutoMatched records payments for all auto-matched credits
+// (POST /api/v1/billing/bank-statements/{id}/confirm-auto)
+func (h *BankReconciliationHandler) ConfirmAutoMatched(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		sendError(w, http.StatusBadRequest, "No user context")
+		return
+	}
+	statementID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid statement ID")
+		return
+	}
+	out, err := h.reconciliationService.ConfirmAutoMatched(r.Context(), tenantID, userID, statementID)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to confirm bank statement")
+		return
+	}
+	sendJSON(w, http.StatusOK, out)
+}
+
+// ConfirmLine records a credit as bank transfer payment; the body may pick another invoice or a
+// client (POST /api/v1/billing/bank-statement-lines/{id}/confirm)
+func (h *BankReconciliationHandler) ConfirmLine(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		sendError(w, http.StatusBadRequest, "No user context")
+		return
+	}
+	lineID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid line ID")
+		return
+	}
+	var req service.ConfirmBankLineRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	out, err := h.reconciliationService.ConfirmLine(r.Context(), tenantID, userID, lineID, req)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to confirm bank transfer")
+		return
+	}
+	sendJSON(w, http.StatusOK, out)
+}
+
+// IgnoreLine marks a credit as not a client payment (POST) or reopens it (DELETE)
+// (/api/v1/billing/bank-statement-lines/{id}/ignore)
+func (h *BankReconciliationHandler) IgnoreLine(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	lineID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid line ID")
+		return
+	}
+	out, err := h.reconciliationService.IgnoreLine(r.Context(), tenantID, lineID, r.Method != http.MethodDelete)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to update bank statement line")
+		return
+	}
+	sendJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"rrnet/internal/auth"
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/repository"
+	"rrnet/internal/service"
+)
+
+// ========== Client Balance, Credit Note & Client Payment Handlers ==========
+
+// GetClientBalance returns the balance and ledger of a client (GET /api/v1/clients/{id}/balance)
+func (h *BillingHandler) GetClientBalance(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No tenant context"}`, http.StatusBadRequest)
+		return
+	}
+	clientID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error":"Invalid client ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	filter := repository.BalanceEntryFilter{TenantID: tenantID, ClientID: clientID}
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil {
+		filter.Page = p
+	}
+	if ps, err := strconv.Atoi(r.URL.Query().Get("page_size")); err == nil {
+		filter.PageSize = ps
+	}
+
+	out, err := h.billingService.GetClientBalance(r.Context(), filter)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// RefundClientBalance pays client credit back (POST /api/v1/clients/{id}/balance/refund)
+func (h *BillingHandler) RefundClientBalance(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No tenant context"}`, http.StatusBadRequest)
+		return
+	}
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No user context"}`, http.StatusBadRequest)
+		return
+	}
+	clientID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error":"Invalid client ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req service.RefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.billingService.RefundBalance(r.Context(), tenantID, userID, clientID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrRefundAmountInvalid), errors.Is(err, repository.ErrInsufficientBalance):
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+		case errors.Is(err, repository.ErrClientNotFound):
+			http.Error(w, `{"error":"Client not found"}`, http.StatusNotFound)
+		default:
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// GetClientBillingSummary returns invoice totals and the running balance (GET /api/v1/clients/{id}/billing-summary)
+func (h *BillingHandler) GetClientBillingSummary(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No tenant context"}`, http.StatusBadRequest)
+		return
+	}
+	clientID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error":"Invalid client ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	summary, err := h.billingService.GetClientBillingSummary(r.Context(), tenantID, clientID)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// IssueCreditNote reduces an invoice; the current user is the approver (POST /api/v1/billing/invoices/{id}/credit-notes)
+func (h *BillingHandler) IssueCreditNote(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No tenant context"}`, http.StatusBadRequest)
+		return
+	}
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No user context"}`, http.StatusBadRequest)
+		return
+	}
+	invoiceID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error":"Invalid invoice ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req service.CreditNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	cn, err := h.billingService.IssueCreditNote(r.Context(), tenantID, userID, invoiceID, req)
+	if err != nil {
+		switch err {
+		case service.ErrInvoiceNotFound:
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusNotFound)
+		case service.ErrCreditNoteAmountInvalid, service.ErrCreditNoteReasonRequired, service.ErrCreditNoteInvoiceInvalid:
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+		default:
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(cn)
+}
+
+// ListCreditNotes returns the credit notes of an invoice (GET /api/v1/billing/invoices/{id}/credit-notes)
+func (h *BillingHandler) ListCreditNotes(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No tenant context"}`, http.StatusBadRequest)
+		return
+	}
+	invoiceID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error":"Invalid invoice ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	notes, err := h.billingService.ListCreditNotes(r.Context(), tenantID, invoiceID)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data":  notes,
+		"total": len(notes),
+	})
+}
+
+// RecordClientPayment allocates one received amount across the client's invoices (POST /api/v1/clients/{id}/payments)
+func (h *BillingHandler) RecordClientPayment(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No tenant context"}`, http.StatusBadRequest)
+		return
+	}
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No user context"}`, http.StatusBadRequest)
+		return
+	}
+	clientID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error":"Invalid client ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req service.RecordClientPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if req.Method == billing.PaymentMethodCollector {
+		http.Error(w, `{"error":"`+errCollectorPaymentDirect+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	cp, err := h.billingService.RecordClientPayment(r.Context(), tenantID, userID, clientID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrClientNotFound):
+			http.Error(w, `{"error":"Client not found"}`, http.StatusNotFound)
+		case errors.Is(err, service.ErrPaymentAmountInvalid), errors.Is(err, service.ErrAllocationInvalid),
+			errors.Is(err, service.ErrAllocationExceedsPayment), errors.Is(err, service.ErrPaymentExceedsOutstanding):
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+		case errors.Is(err, repository.ErrInvoiceNotPayable):
+			// An invoice changed since allocation (e.g. paid concurrently); nothing was stored
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusConflict)
+		default:
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(cp)
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"rrnet/internal/auth"
+	"rrnet/internal/service"
+)
+
+// ========== Printable Document Handlers ==========
+
+// GetInvoicePDF renders an invoice as PDF (GET /api/v1/billing/invoices/{id}/pdf)
+func (h *BillingHandler) GetInvoicePDF(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No tenant context"}`, http.StatusBadRequest)
+		return
+	}
+	invoiceID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error":"Invalid invoice ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	out, name, err := h.billingService.RenderInvoicePDF(r.Context(), tenantID, invoiceID)
+	if err != nil {
+		if err == service.ErrInvoiceNotFound {
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+	writePDF(w, r, name, out)
+}
+
+// GetPaymentReceiptPDF renders a payment receipt as PDF (GET /api/v1/billing/payments/{id}/receipt.pdf)
+func (h *BillingHandler) GetPaymentReceiptPDF(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No tenant context"}`, http.StatusBadRequest)
+		return
+	}
+	paymentID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error":"Invalid payment ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	out, name, err := h.billingService.RenderPaymentReceiptPDF(r.Context(), tenantID, paymentID)
+	if err != nil {
+		if err == service.ErrPaymentNotFound {
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+	writePDF(w, r, name, out)
+}
+
+// writePDF sends a PDF inline; ?download=1 asks the browser to save it instead
+func writePDF(w http.ResponseWriter, r *http.Request, name string, out []byte) {
+	disposition := "inline"
+	if r.URL.Query().Get("download") == "1" {
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", disposition+`; filename="`+name+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(out)))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Write(out)
+}
+
+// GetInvoiceTemplate returns the tenant's invoice/receipt template (GET /api/v1/billing/invoice-template)
+func (h *BillingHandler) GetInvoiceTemplate(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No tenant context"}`, http.StatusBadRequest)
+		return
+	}
+	tpl, err := h.billingService.GetInvoiceTemplate(r.Context(), tenantID)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tpl)
+}
+
+// UpdateInvoiceTemplate replaces the tenant's invoice/receipt template (PUT /api/v1/billing/invoice-template)
+func (h *BillingHandler) UpdateInvoiceTemplate(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No tenant context"}`, http.StatusBadRequest)
+		return
+	}
+	var req service.InvoiceTemplate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	tpl, err := h.billingService.UpdateInvoiceTemplate(r.Context(), tenantID, req)
+	if err != nil {
+		switch err {
+		case service.ErrInvoiceLogoInvalid, service.ErrInvoiceLogoTooLarge, service.ErrInvoiceLogoDimensions, service.ErrInvoiceBankAccountInvalid, service.ErrInvoiceFooterTooLong:
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+		default:
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tpl)
+}
@@ -172,6 +172,34 @@ func (h *BillingHandler) GetClientPendingInvoices(w http.ResponseWriter, r *http
 	})
 }
 
+// ListClientAdjustments returns the prorated charges/credits of a client (GET /api/v1/clients/{id}/adjustments)
+func (h *BillingHandler) ListClientAdjustments(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No tenant context"}`, http.StatusBadRequest)
+		return
+	}
+
+	idStr := getPathParam(r, "id")
+	clientID, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid client ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	adjustments, err := h.billingService.ListClientAdjustments(r.Context(), tenantID, clientID)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data":  adjustments,
+		"total": len(adjustments),
+	})
+}
+
 func (h *BillingHandler) GetOverdueInvoices(w http.ResponseWriter, r *http.Request) {
 	tenantID, ok := auth.GetTenantID(r.Context())
 	if !ok {
@@ -366,6 +394,10 @@ func (h *BillingHandler) RecordPayment(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, `{"error":"amount must be positive"}`, http.StatusBadRequest)
 		return
 	}
+	if req.Method == billing.PaymentMethodCollector {
+		http.Error(w, `{"error":"`+errCollectorPaymentDirect+`"}`, http.StatusBadRequest)
+		return
+	}
 
 	payment, err := h.billingService.RecordPayment(r.Context(), tenantID, userID, req)
 	if err != nil {
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"rrnet/internal/auth"
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/service"
+)
+
+// ========== Document Numbering Handlers ==========
+
+func isNumberingValidationError(err error) bool {
+	for _, target := range []error{
+		billing.ErrNumberPatternEmpty, billing.ErrNumberPatternSeq, billing.ErrNumberPatternToken,
+		billing.ErrNumberPatternChars, billing.ErrNumberPatternLength, billing.ErrNumberPatternReset,
+		billing.ErrNumberPatternResetDate, billing.ErrNumberBranchInvalid, service.ErrNumberingKindInvalid,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetNumberingSettings returns the document number schemes (GET /api/v1/billing/numbering-settings)
+func (h *BillingHandler) GetNumberingSettings(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No tenant context"}`, http.StatusBadRequest)
+		return
+	}
+	out, err := h.billingService.GetNumberingSettings(r.Context(), tenantID)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// UpdateNumberingSettings replaces the document number schemes; applies to documents created
+// afterwards (PUT /api/v1/billing/numbering-settings)
+func (h *BillingHandler) UpdateNumberingSettings(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No tenant context"}`, http.StatusBadRequest)
+		return
+	}
+	var req service.NumberingSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	out, err := h.billingService.UpdateNumberingSettings(r.Context(), tenantID, req)
+	if err != nil {
+		if isNumberingValidationError(err) {
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+			return
+		}
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// PreviewNumbering shows the next numbers of a saved or proposed scheme without using them
+// (POST /api/v1/billing/numbering-settings/preview)
+func (h *BillingHandler) PreviewNumbering(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No tenant context"}`, http.StatusBadRequest)
+		return
+	}
+	var req service.NumberingPreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	out, err := h.billingService.PreviewNumbering(r.Context(), tenantID, req)
+	if err != nil {
+		if isNumberingValidationError(err) {
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+			return
+		}
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"rrnet/internal/auth"
+	"rrnet/internal/repository"
+	"rrnet/internal/service"
+)
+
+// ========== Prepaid Billing Handlers ==========
+
+func writePrepaidError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrPrepaidPeriodsInvalid), errors.Is(err, service.ErrClientNotPrepaid):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+	case errors.Is(err, repository.ErrPrepaidInvoiceOpen):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusConflict)
+	case errors.Is(err, repository.ErrClientNotFound):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusNotFound)
+	default:
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusInternalServerError)
+	}
+}
+
+type prepaidInvoiceRequest struct {
+	Periods int `json:"periods"` // months paid in advance, 1-12 (default 1)
+}
+
+// CreatePrepaidInvoice bills a prepaid client for one or more months in advance
+// (POST /api/v1/clients/{id}/prepaid/invoice)
+func (h *BillingHandler) CreatePrepaidInvoice(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No tenant context"}`, http.StatusBadRequest)
+		return
+	}
+	clientID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error":"Invalid client ID"}`, http.StatusBadRequest)
+		return
+	}
+	var req prepaidInvoiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if req.Periods == 0 {
+		req.Periods = 1
+	}
+
+	invoice, err := h.billingService.CreatePrepaidInvoice(r.Context(), tenantID, clientID, req.Periods)
+	if err != nil {
+		writePrepaidError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(invoice)
+}
+
+type activeUntilRequest struct {
+	ActiveUntil *string `json:"active_until"` // YYYY-MM-DD, null clears it
+}
+
+// SetClientActiveUntil sets the validity of a prepaid client by hand
+// (PUT /api/v1/clients/{id}/prepaid/active-until)
+func (h *BillingHandler) SetClientActiveUntil(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No tenant context"}`, http.StatusBadRequest)
+		return
+	}
+	clientID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error":"Invalid client ID"}`, http.StatusBadRequest)
+		return
+	}
+	var req activeUntilRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	var activeUntil *time.Time
+	if req.ActiveUntil != nil {
+		d, err := time.ParseInLocation("2006-01-02", *req.ActiveUntil, time.Local)
+		if err != nil {
+			http.Error(w, `{"error":"Invalid active_until (use YYYY-MM-DD)"}`, http.StatusBadRequest)
+			return
+		}
+		activeUntil = &d
+	}
+
+	c, err := h.billingService.SetClientActiveUntil(r.Context(), tenantID, clientID, activeUntil)
+	if err != nil {
+		writePrepaidError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"client_id":    c.ID,
+		"active_until": c.ActiveUntil,
+	})
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"rrnet/internal/auth"
+	"rrnet/internal/repository"
+	"rrnet/internal/service"
+)
+
+// ========== Recurring Charge Handlers ==========
+
+func writeRecurringChargeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrRecurringChargeDescription), errors.Is(err, service.ErrRecurringChargeAmount),
+		errors.Is(err, service.ErrRecurringChargeQuantity), errors.Is(err, service.ErrRecurringChargeDates):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+	case errors.Is(err, repository.ErrRecurringChargeNotFound), errors.Is(err, repository.ErrClientNotFound):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusNotFound)
+	default:
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusInternalServerError)
+	}
+}
+
+// ListClientCharges returns the recurring charges of a client (GET /api/v1/clients/{id}/charges)
+func (h *BillingHandler) ListClientCharges(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No tenant context"}`, http.StatusBadRequest)
+		return
+	}
+	clientID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error":"Invalid client ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	charges, err := h.billingService.ListClientCharges(r.Context(), tenantID, clientID)
+	if err != nil {
+		writeRecurringChargeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data":  charges,
+		"total": len(charges),
+	})
+}
+
+// CreateClientCharge adds a recurring charge to a client (POST /api/v1/clients/{id}/charges)
+func (h *BillingHandler) CreateClientCharge(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No tenant context"}`, http.StatusBadRequest)
+		return
+	}
+	clientID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error":"Invalid client ID"}`, http.StatusBadRequest)
+		return
+	}
+	var req service.RecurringChargeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	charge, err := h.billingService.CreateClientCharge(r.Context(), tenantID, clientID, req)
+	if err != nil {
+		writeRecurringChargeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(charge)
+}
+
+// UpdateClientCharge replaces a recurring charge (PUT /api/v1/clients/{id}/charges/{charge_id})
+func (h *BillingHandler) UpdateClientCharge(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No tenant context"}`, http.StatusBadRequest)
+		return
+	}
+	clientID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error":"Invalid client ID"}`, http.StatusBadRequest)
+		return
+	}
+	chargeID, err := uuid.Parse(getPathParam(r, "charge_id"))
+	if err != nil {
+		http.Error(w, `{"error":"Invalid charge ID"}`, http.StatusBadRequest)
+		return
+	}
+	var req service.RecurringChargeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	charge, err := h.billingService.UpdateClientCharge(r.Context(), tenantID, clientID, chargeID, req)
+	if err != nil {
+		writeRecurringChargeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(charge)
+}
+
+// DeleteClientCharge removes a recurring charge (DELETE /api/v1/clients/{id}/charges/{charge_id})
+func (h *BillingHandler) DeleteClientCharge(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No tenant context"}`, http.StatusBadRequest)
+		return
+	}
+	clientID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error":"Invalid client ID"}`, http.StatusBadRequest)
+		return
+	}
+	chargeID, err := uuid.Parse(getPathParam(r, "charge_id"))
+	if err != nil {
+		http.Error(w, `{"error":"Invalid charge ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.billingService.DeleteClientCharge(r.Context(), tenantID, clientID, chargeID); err != nil {
+		writeRecurringChargeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
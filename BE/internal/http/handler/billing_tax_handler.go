@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"rrnet/internal/auth"
+	"rrnet/internal/service"
+)
+
+// ========== Tax Profile Handlers ==========
+
+// GetTaxSettings returns the tenant tax profile (GET /api/v1/billing/tax-settings)
+func (h *BillingHandler) GetTaxSettings(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No tenant context"}`, http.StatusBadRequest)
+		return
+	}
+	out, err := h.billingService.GetTaxSettings(r.Context(), tenantID)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// UpdateTaxSettings replaces the tenant tax profile; applies to invoices created afterwards (PUT /api/v1/billing/tax-settings)
+func (h *BillingHandler) UpdateTaxSettings(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No tenant context"}`, http.StatusBadRequest)
+		return
+	}
+	var req service.TaxSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	out, err := h.billingService.UpdateTaxSettings(r.Context(), tenantID, req)
+	if err != nil {
+		switch err {
+		case service.ErrTaxRateInvalid, service.ErrTaxNameInvalid:
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+		default:
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// ========== Unique Code Handlers ==========
+
+// GetUniqueCodeSettings returns the tenant "kode unik" settings (GET /api/v1/billing/unique-code-settings)
+func (h *BillingHandler) GetUniqueCodeSettings(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No tenant context"}`, http.StatusBadRequest)
+		return
+	}
+	out, err := h.billingService.GetUniqueCodeSettings(r.Context(), tenantID)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// UpdateUniqueCodeSettings replaces the "kode unik" settings; applies to invoices created afterwards (PUT /api/v1/billing/unique-code-settings)
+func (h *BillingHandler) UpdateUniqueCodeSettings(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"No tenant context"}`, http.StatusBadRequest)
+		return
+	}
+	var req service.UniqueCodeSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	out, err := h.billingService.UpdateUniqueCodeSettings(r.Context(), tenantID, req)
+	if err != nil {
+		switch err {
+		case service.ErrUniqueCodeModeInvalid, service.ErrUniqueCodeMaxInvalid:
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+		default:
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/auth"
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/repository"
+	"rrnet/internal/service"
+)
+
+// BulkInvoiceHandler generates the invoices of a period for many clients at once
+type BulkInvoiceHandler struct {
+	bulkService *service.BulkInvoiceService
+}
+
+func NewBulkInvoiceHandler(bulkService *service.BulkInvoiceService) *BulkInvoiceHandler {
+	return &BulkInvoiceHandler{bulkService: bulkService}
+}
+
+func (h *BulkInvoiceHandler) sendServiceError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, repository.ErrBulkRunNotFound):
+		sendError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrBulkPeriodInvalid):
+		sendError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repository.ErrBulkRunInProgress), errors.Is(err, service.ErrBulkRunNotUndoable):
+		sendError(w, http.StatusConflict, err.Error())
+	default:
+		log.Error().Err(err).Msg(msg)
+		sendError(w, http.StatusInternalServerError, msg)
+	}
+}
+
+func (h *BulkInvoiceHandler) decodeRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, *service.BulkInvoiceRequest, bool) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return uuid.Nil, nil, false
+	}
+	var req service.BulkInvoiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return uuid.Nil, nil, false
+	}
+	return tenantID, &req, true
+}
+
+// Preview returns the invoices a run would create, with amounts and skipped clients
+// (POST /api/v1/billing/bulk-invoices/preview)
+func (h *BulkInvoiceHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	tenantID, req, ok := h.decodeRequest(w, r)
+	if !ok {
+		return
+	}
+	preview, err := h.bulkService.Preview(r.Context(), tenantID, *req)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to preview bulk invoices")
+		return
+	}
+	sendJSON(w, http.StatusOK, preview)
+}
+
+// Start queues a run (POST /api/v1/billing/bulk-invoices)
+func (h *BulkInvoiceHandler) Start(w http.ResponseWriter, r *http.Request) {
+	tenantID, req, ok := h.decodeRequest(w, r)
+	if !ok {
+		return
+	}
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		sendError(w, http.StatusBadRequest, "No user context")
+		return
+	}
+	run, err := h.bulkService.Start(r.Context(), tenantID, userID, *req)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to start bulk invoice run")
+		return
+	}
+	sendJSON(w, http.StatusAccepted, run)
+}
+
+// List returns the runs of the tenant, newest first (GET /api/v1/billing/bulk-invoices)
+func (h *BulkInvoiceHandler) List(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	runs, total, err := h.bulkService.ListRuns(r.Context(), tenantID, page, pageSize)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to list bulk invoice runs")
+		return
+	}
+	sendJSON(w, http.StatusOK, map[string]interface{}{
+		"data":  runs,
+		"total": total,
+	})
+}
+
+func (h *BulkInvoiceHandler) runParams(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return uuid.Nil, uuid.Nil, false
+	}
+	runID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid run ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return tenantID, runID, true
+}
+
+// Get returns a run with its progress (GET /api/v1/billing/bulk-invoices/{id})
+func (h *BulkInvoiceHandler) Get(w http.ResponseWriter, r *http.Request) {
+	tenantID, runID, ok := h.runParams(w, r)
+	if !ok {
+		return
+	}
+	run, err := h.bulkService.GetRun(r.Context(), tenantID, runID)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to get bulk invoice run")
+		return
+	}
+	sendJSON(w, http.StatusOK, run)
+}
+
+// ListItems returns the outcome per client; status=failed lists the errors
+// (GET /api/v1/billing/bulk-invoices/{id}/items)
+func (h *BulkInvoiceHandler) ListItems(w http.ResponseWriter, r *http.Request) {
+	tenantID, runID, ok := h.runParams(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	filter := repository.BulkRunItemFilter{}
+	filter.Page, _ = strconv.Atoi(q.Get("page"))
+	filter.PageSize, _ = strconv.Atoi(q.Get("page_size"))
+	if filter.PageSize < 1 || filter.PageSize > 500 {
+		filter.PageSize = 100
+	}
+	if v := q.Get("status"); v != "" {
+		status := billing.BulkItemStatus(v)
+		filter.Status = &status
+	}
+	items, total, err := h.bulkService.ListItems(r.Context(), tenantID, runID, filter)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to list bulk invoice run items")
+		return
+	}
+	sendJSON(w, http.StatusOK, map[string]interface{}{
+		"data":  items,
+		"total": total,
+	})
+}
+
+// Undo cancels the unpaid invoices created by a run (POST /api/v1/billing/bulk-invoices/{id}/undo)
+func (h *BulkInvoiceHandler) Undo(w http.ResponseWriter, r *http.Request) {
+	tenantID, runID, ok := h.runParams(w, r)
+	if !ok {
+		return
+	}
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		sendError(w, http.StatusBadRequest, "No user context")
+		return
+	}
+	run, err := h.bulkService.Undo(r.Context(), tenantID, userID, runID)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to undo bulk invoice run")
+		return
+	}
+	sendJSON(w, http.StatusOK, run)
+}
@@ -138,6 +138,8 @@ func (h *ClientHandler) Create(w http.ResponseWriter, r *http.Request) {
 			sendError(w, http.StatusForbidden, "Client limit exceeded for your plan")
 		case repository.ErrClientCodeTaken:
 			sendError(w, http.StatusConflict, "Client code already exists")
+		case service.ErrBillingModeInvalid, service.ErrBillingCycleInvalid:
+			sendError(w, http.StatusBadRequest, err.Error())
 		default:
 			sendError(w, http.StatusInternalServerError, "Failed to create client")
 		}
@@ -179,6 +181,10 @@ func (h *ClientHandler) Update(w http.ResponseWriter, r *http.Request) {
 			sendError(w, http.StatusNotFound, "Client not found")
 			return
 		}
+		if err == service.ErrBillingModeInvalid || err == service.ErrBillingCycleInvalid {
+			sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		sendError(w, http.StatusInternalServerError, "Failed to update client")
 		return
 	}
@@ -212,12 +218,16 @@ func (h *ClientHandler) ChangeStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if userID, ok := auth.GetUserID(r.Context()); ok {
+		req.ExecutedBy = &userID
+	}
+
 	c, err := h.clientService.ChangeStatus(r.Context(), tenantID, clientID, &req)
 	if err != nil {
 		switch err {
 		case repository.ErrClientNotFound:
 			sendError(w, http.StatusNotFound, "Client not found")
-		case service.ErrInvalidStatusChange:
+		case service.ErrInvalidStatusChange, service.ErrClientNotActive, service.ErrClientNotIsolated:
 			sendError(w, http.StatusBadRequest, "Invalid status transition")
 		default:
 			sendError(w, http.StatusInternalServerError, "Failed to change status")
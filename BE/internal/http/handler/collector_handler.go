@@ -0,0 +1,473 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/auth"
+	"rrnet/internal/domain/collector"
+	"rrnet/internal/repository"
+	"rrnet/internal/service"
+)
+
+// errCollectorPaymentDirect rejects collector payments entered by hand; they are only recorded by
+// confirming a collector deposit
+const errCollectorPaymentDirect = "collector payments are recorded by confirming a collector deposit"
+
+// CollectorHandler serves the collector cash workflow: office endpoints over every assignment
+// and deposit of the tenant, and /me endpoints that only expose the calling collector's own
+type CollectorHandler struct {
+	collectorService *service.CollectorService
+}
+
+func NewCollectorHandler(collectorService *service.CollectorService) *CollectorHandler {
+	return &CollectorHandler{collectorService: collectorService}
+}
+
+func (h *CollectorHandler) sendServiceError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, repository.ErrCollectorAssignmentNotFound), errors.Is(err, repository.ErrCollectorDepositNotFound),
+		errors.Is(err, service.ErrInvoiceNotFound):
+		sendError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrCollectorInvalid), errors.Is(err, service.ErrCollectorInvoicesRequired),
+		errors.Is(err, service.ErrCollectorVisitInvalid), errors.Is(err, service.ErrCollectorVisitReason),
+		errors.Is(err, service.ErrCollectorNothingCollected), errors.Is(err, service.ErrCollectorDepositReason):
+		sendError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repository.ErrCollectorAssignmentExists), errors.Is(err, service.ErrCollectorInvoiceNotOpen),
+		errors.Is(err, service.ErrCollectorAssignmentClosed), errors.Is(err, repository.ErrCollectorDepositReviewed):
+		sendError(w, http.StatusConflict, err.Error())
+	default:
+		log.Error().Err(err).Msg(msg)
+		sendError(w, http.StatusInternalServerError, msg)
+	}
+}
+
+// caller returns the tenant and the calling user
+func (h *CollectorHandler) caller(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return uuid.Nil, uuid.Nil, false
+	}
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		sendError(w, http.StatusBadRequest, "No user context")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return tenantID, userID, true
+}
+
+func collectorPathID(w http.ResponseWriter, r *http.Request, what string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid "+what+" ID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func collectorAssignmentFilter(r *http.Request, tenantID uuid.UUID) repository.CollectorAssignmentFilter {
+	q := r.URL.Query()
+	filter := repository.CollectorAssignmentFilter{TenantID: tenantID}
+	filter.Page, _ = strconv.Atoi(q.Get("page"))
+	filter.PageSize, _ = strconv.Atoi(q.Get("page_size"))
+	if filter.PageSize < 1 || filter.PageSize > 200 {
+		filter.PageSize = 50
+	}
+	if v := q.Get("status"); v != "" {
+		status := collector.AssignmentStatus(v)
+		filter.Status = &status
+	}
+	return filter
+}
+
+func collectorDepositFilter(r *http.Request, tenantID uuid.UUID) repository.CollectorDepositFilter {
+	q := r.URL.Query()
+	filter := repository.CollectorDepositFilter{TenantID: tenantID}
+	filter.Page, _ = strconv.Atoi(q.Get("page"))
+	filter.PageSize, _ = strconv.Atoi(q.Get("page_size"))
+	if v := q.Get("status"); v != "" {
+		status := collector.DepositStatus(v)
+		filter.Status = &status
+	}
+	return filter
+}
+
+// Assign hands invoices to a collector (POST /api/v1/collector/assignments)
+func (h *CollectorHandler) Assign(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	var req service.AssignInvoicesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	assignments, err := h.collectorService.AssignInvoices(r.Context(), tenantID, userID, req)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to assign invoices")
+		return
+	}
+	sendJSON(w, http.StatusCreated, map[string]interface{}{"data": assignments})
+}
+
+// ListAssignments lists the assignments of the tenant, optionally of one collector
+// (GET /api/v1/collector/assignments)
+func (h *CollectorHandler) ListAssignments(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	filter := collectorAssignmentFilter(r, tenantID)
+	if v := r.URL.Query().Get("collector_id"); v != "" {
+		collectorID, err := uuid.Parse(v)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, "Invalid collector ID")
+			return
+		}
+		filter.CollectorID = &collectorID
+	}
+	assignments, total, err := h.collectorService.ListAssignments(r.Context(), filter)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to list collector assignments")
+		return
+	}
+	sendJSON(w, http.StatusOK, map[string]interface{}{"data": assignments, "total": total})
+}
+
+// GetAssignment (GET /api/v1/collector/assignments/{id})
+func (h *CollectorHandler) GetAssignment(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	id, ok := collectorPathID(w, r, "assignment")
+	if !ok {
+		return
+	}
+	a, err := h.collectorService.GetAssignment(r.Context(), tenantID, id)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to get collector assignment")
+		return
+	}
+	sendJSON(w, http.StatusOK, a)
+}
+
+// CancelAssignment takes an invoice back before it is collected
+// (POST /api/v1/collector/assignments/{id}/cancel)
+func (h *CollectorHandler) CancelAssignment(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	id, ok := collectorPathID(w, r, "assignment")
+	if !ok {
+		return
+	}
+	if err := h.collectorService.CancelAssignment(r.Context(), tenantID, id); err != nil {
+		h.sendServiceError(w, err, "Failed to cancel collector assignment")
+		return
+	}
+	sendJSON(w, http.StatusOK, map[string]string{"message": "Assignment cancelled"})
+}
+
+// ListVisits (GET /api/v1/collector/assignments/{id}/visits)
+func (h *CollectorHandler) ListVisits(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	id, ok := collectorPathID(w, r, "assignment")
+	if !ok {
+		return
+	}
+	visits, err := h.collectorService.ListVisits(r.Context(), tenantID, nil, id)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to list collector visits")
+		return
+	}
+	sendJSON(w, http.StatusOK, map[string]interface{}{"data": visits})
+}
+
+// ListDeposits lists the deposit reports of the tenant (GET /api/v1/collector/deposits)
+func (h *CollectorHandler) ListDeposits(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	filter := collectorDepositFilter(r, tenantID)
+	if v := r.URL.Query().Get("collector_id"); v != "" {
+		collectorID, err := uuid.Parse(v)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, "Invalid collector ID")
+			return
+		}
+		filter.CollectorID = &collectorID
+	}
+	deposits, total, err := h.collectorService.ListDeposits(r.Context(), filter)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to list collector deposits")
+		return
+	}
+	sendJSON(w, http.StatusOK, map[string]interface{}{"data": deposits, "total": total})
+}
+
+// GetDeposit returns a deposit with its assignments (GET /api/v1/collector/deposits/{id})
+func (h *CollectorHandler) GetDeposit(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	id, ok := collectorPathID(w, r, "deposit")
+	if !ok {
+		return
+	}
+	d, err := h.collectorService.GetDeposit(r.Context(), tenantID, id)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to get collector deposit")
+		return
+	}
+	sendJSON(w, http.StatusOK, d)
+}
+
+// ConfirmDeposit records the payments of a deposit (POST /api/v1/collector/deposits/{id}/confirm)
+func (h *CollectorHandler) ConfirmDeposit(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	id, ok := collectorPathID(w, r, "deposit")
+	if !ok {
+		return
+	}
+	d, err := h.collectorService.ConfirmDeposit(r.Context(), tenantID, userID, id)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to confirm collector deposit")
+		return
+	}
+	sendJSON(w, http.StatusOK, d)
+}
+
+// RejectDeposit sends a deposit back to the collector (POST /api/v1/collector/deposits/{id}/reject)
+func (h *CollectorHandler) RejectDeposit(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	id, ok := collectorPathID(w, r, "deposit")
+	if !ok {
+		return
+	}
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	d, err := h.collectorService.RejectDeposit(r.Context(), tenantID, userID, id, req.Reason)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to reject collector deposit")
+		return
+	}
+	sendJSON(w, http.StatusOK, d)
+}
+
+// MyAssignments lists the caller's assignments (GET /api/v1/collector/me/assignments)
+func (h *CollectorHandler) MyAssignments(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	assignments, total, err := h.collectorService.ListCollectorAssignments(r.Context(), userID, collectorAssignmentFilter(r, tenantID))
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to list collector assignments")
+		return
+	}
+	sendJSON(w, http.StatusOK, map[string]interface{}{"data": assignments, "total": total})
+}
+
+// MyAssignment (GET /api/v1/collector/me/assignments/{id})
+func (h *CollectorHandler) MyAssignment(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	id, ok := collectorPathID(w, r, "assignment")
+	if !ok {
+		return
+	}
+	a, err := h.collectorService.GetCollectorAssignment(r.Context(), tenantID, userID, id)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to get collector assignment")
+		return
+	}
+	sendJSON(w, http.StatusOK, a)
+}
+
+// MyVisits (GET /api/v1/collector/me/assignments/{id}/visits)
+func (h *CollectorHandler) MyVisits(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	id, ok := collectorPathID(w, r, "assignment")
+	if !ok {
+		return
+	}
+	visits, err := h.collectorService.ListVisits(r.Context(), tenantID, &userID, id)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to list collector visits")
+		return
+	}
+	sendJSON(w, http.StatusOK, map[string]interface{}{"data": visits})
+}
+
+// LogVisit records a visit with its GPS position (POST /api/v1/collector/me/assignments/{id}/visits)
+func (h *CollectorHandler) LogVisit(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	id, ok := collectorPathID(w, r, "assignment")
+	if !ok {
+		return
+	}
+	var req service.LogVisitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	v, err := h.collectorService.LogVisit(r.Context(), tenantID, userID, id, req)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to log collector visit")
+		return
+	}
+	sendJSON(w, http.StatusCreated, v)
+}
+
+// MyDeposits lists the caller's deposit reports (GET /api/v1/collector/me/deposits)
+func (h *CollectorHandler) MyDeposits(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	deposits, total, err := h.collectorService.ListCollectorDeposits(r.Context(), userID, collectorDepositFilter(r, tenantID))
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to list collector deposits")
+		return
+	}
+	sendJSON(w, http.StatusOK, map[string]interface{}{"data": deposits, "total": total})
+}
+
+// ReportDeposit bundles collected invoices into a deposit (POST /api/v1/collector/me/deposits)
+func (h *CollectorHandler) ReportDeposit(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	var req service.ReportDepositRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	d, err := h.collectorService.ReportDeposit(r.Context(), tenantID, userID, req)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to report collector deposit")
+		return
+	}
+	sendJSON(w, http.StatusCreated, d)
+}
+
+// MyDeposit (GET /api/v1/collector/me/deposits/{id})
+func (h *CollectorHandler) MyDeposit(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	id, ok := collectorPathID(w, r, "deposit")
+	if !ok {
+		return
+	}
+	d, err := h.collectorService.GetCollectorDeposit(r.Context(), tenantID, userID, id)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to get collector deposit")
+		return
+	}
+	sendJSON(w, http.StatusOK, d)
+}
+
+// collectorRouteParams reads the day (date=YYYY-MM-DD, default today) and the optional start position
+// (start_lat and start_lng) of a route request
+func collectorRouteParams(w http.ResponseWriter, r *http.Request) (time.Time, *collector.Point, bool) {
+	q := r.URL.Query()
+	day := time.Now()
+	if v := q.Get("date"); v != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, "date must be formatted as YYYY-MM-DD")
+			return time.Time{}, nil, false
+		}
+		day = parsed
+	}
+	latStr, lngStr := q.Get("start_lat"), q.Get("start_lng")
+	if latStr == "" && lngStr == "" {
+		return day, nil, true
+	}
+	lat, errLat := strconv.ParseFloat(latStr, 64)
+	lng, errLng := strconv.ParseFloat(lngStr, 64)
+	if errLat != nil || errLng != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		sendError(w, http.StatusBadRequest, "start_lat and start_lng must be a valid position")
+		return time.Time{}, nil, false
+	}
+	return day, &collector.Point{Latitude: lat, Longitude: lng}, true
+}
+
+// MyRoute plans the caller's visits of the day (GET /api/v1/collector/me/route)
+func (h *CollectorHandler) MyRoute(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	day, start, ok := collectorRouteParams(w, r)
+	if !ok {
+		return
+	}
+	route, err := h.collectorService.PlanRoute(r.Context(), tenantID, userID, day, start)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to plan collector route")
+		return
+	}
+	sendJSON(w, http.StatusOK, route)
+}
+
+// CollectorRoute plans the visits of the day of a collector (GET /api/v1/collector/routes/{id})
+func (h *CollectorHandler) CollectorRoute(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	collectorID, ok := collectorPathID(w, r, "collector")
+	if !ok {
+		return
+	}
+	day, start, ok := collectorRouteParams(w, r)
+	if !ok {
+		return
+	}
+	route, err := h.collectorService.PlanRoute(r.Context(), tenantID, collectorID, day, start)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to plan collector route")
+		return
+	}
+	sendJSON(w, http.StatusOK, route)
+}
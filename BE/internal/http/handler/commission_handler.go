@@ -0,0 +1,372 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/auth"
+	"rrnet/internal/domain/commission"
+	"rrnet/internal/reporting"
+	"rrnet/internal/repository"
+	"rrnet/internal/service"
+)
+
+// CommissionHandler serves commission rules and the monthly statements of collecting staff
+type CommissionHandler struct {
+	commissionService *service.CommissionService
+}
+
+func NewCommissionHandler(commissionService *service.CommissionService) *CommissionHandler {
+	return &CommissionHandler{commissionService: commissionService}
+}
+
+func (h *CommissionHandler) sendServiceError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, repository.ErrCommissionRuleNotFound), errors.Is(err, repository.ErrCommissionStatementNotFound):
+		sendError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrCommissionRuleInvalid), errors.Is(err, service.ErrCommissionRuleTarget),
+		errors.Is(err, service.ErrCommissionRuleDate), errors.Is(err, service.ErrBulkPeriodInvalid):
+		sendError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrCommissionRuleRetired), errors.Is(err, repository.ErrCommissionRuleConflict),
+		errors.Is(err, repository.ErrCommissionStatementState):
+		sendError(w, http.StatusConflict, err.Error())
+	default:
+		log.Error().Err(err).Msg(msg)
+		sendError(w, http.StatusInternalServerError, msg)
+	}
+}
+
+func (h *CommissionHandler) caller(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return uuid.Nil, uuid.Nil, false
+	}
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		sendError(w, http.StatusBadRequest, "No user context")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return tenantID, userID, true
+}
+
+func (h *CommissionHandler) pathID(w http.ResponseWriter, r *http.Request, what string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid "+what+" ID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// ListRules returns the latest version of every rule (GET /api/v1/commissions/rules)
+func (h *CommissionHandler) ListRules(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	rules, err := h.commissionService.ListRules(r.Context(), tenantID)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to list commission rules")
+		return
+	}
+	sendJSON(w, http.StatusOK, map[string]interface{}{"data": rules})
+}
+
+// CreateRule (POST /api/v1/commissions/rules)
+func (h *CommissionHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	var req service.CommissionRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	rule, err := h.commissionService.CreateRule(r.Context(), tenantID, userID, req)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to create commission rule")
+		return
+	}
+	sendJSON(w, http.StatusCreated, rule)
+}
+
+// RuleHistory returns every version of a rule (GET /api/v1/commissions/rules/{key})
+func (h *CommissionHandler) RuleHistory(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	key, ok := h.pathID(w, r, "rule")
+	if !ok {
+		return
+	}
+	versions, err := h.commissionService.RuleHistory(r.Context(), tenantID, key)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to get commission rule")
+		return
+	}
+	sendJSON(w, http.StatusOK, map[string]interface{}{"data": versions})
+}
+
+// UpdateRule stores a new version of a rule (PUT /api/v1/commissions/rules/{key})
+func (h *CommissionHandler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	key, ok := h.pathID(w, r, "rule")
+	if !ok {
+		return
+	}
+	var req service.CommissionRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	rule, err := h.commissionService.UpdateRule(r.Context(), tenantID, userID, key, req)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to update commission rule")
+		return
+	}
+	sendJSON(w, http.StatusOK, rule)
+}
+
+// RetireRule stops a rule from ?effective_from (default today) (DELETE /api/v1/commissions/rules/{key})
+func (h *CommissionHandler) RetireRule(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	key, ok := h.pathID(w, r, "rule")
+	if !ok {
+		return
+	}
+	rule, err := h.commissionService.RetireRule(r.Context(), tenantID, userID, key, r.URL.Query().Get("effective_from"))
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to retire commission rule")
+		return
+	}
+	sendJSON(w, http.StatusOK, rule)
+}
+
+// Generate computes the statements of a month (POST /api/v1/commissions/statements/generate)
+func (h *CommissionHandler) Generate(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Period string `json:"period"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	result, err := h.commissionService.Generate(r.Context(), tenantID, req.Period)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to generate commission statements")
+		return
+	}
+	sendJSON(w, http.StatusOK, result)
+}
+
+// statementFilter reads period (YYYY-MM), status and paging
+func (h *CommissionHandler) statementFilter(w http.ResponseWriter, r *http.Request, tenantID uuid.UUID) (repository.CommissionStatementFilter, bool) {
+	q := r.URL.Query()
+	filter := repository.CommissionStatementFilter{TenantID: tenantID}
+	filter.Page, _ = strconv.Atoi(q.Get("page"))
+	filter.PageSize, _ = strconv.Atoi(q.Get("page_size"))
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 20
+	}
+	if v := q.Get("period"); v != "" {
+		month, err := time.ParseInLocation("2006-01", v, time.Local)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, service.ErrBulkPeriodInvalid.Error())
+			return filter, false
+		}
+		filter.PeriodStart = &month
+	}
+	if v := q.Get("status"); v != "" {
+		status := commission.StatementStatus(v)
+		filter.Status = &status
+	}
+	return filter, true
+}
+
+// ListStatements (GET /api/v1/commissions/statements)
+func (h *CommissionHandler) ListStatements(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	filter, ok := h.statementFilter(w, r, tenantID)
+	if !ok {
+		return
+	}
+	if v := r.URL.Query().Get("user_id"); v != "" {
+		userID, err := uuid.Parse(v)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+		filter.UserID = &userID
+	}
+	statements, total, err := h.commissionService.ListStatements(r.Context(), filter)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to list commission statements")
+		return
+	}
+	sendJSON(w, http.StatusOK, map[string]interface{}{"data": statements, "total": total})
+}
+
+// ExportStatements exports the statements of a month as CSV or XLSX
+// (GET /api/v1/commissions/statements/export?period=YYYY-MM&format=csv|xlsx)
+func (h *CommissionHandler) ExportStatements(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	filter, ok := h.statementFilter(w, r, tenantID)
+	if !ok {
+		return
+	}
+	if filter.PeriodStart == nil {
+		sendError(w, http.StatusBadRequest, "period is required")
+		return
+	}
+	format := reporting.Format(r.URL.Query().Get("format"))
+	if format == "" {
+		format = reporting.FormatCSV
+	}
+	if format != reporting.FormatCSV && format != reporting.FormatXLSX {
+		sendError(w, http.StatusBadRequest, reporting.ErrFormatInvalid.Error())
+		return
+	}
+	filter.PageSize = 0
+	statements, _, err := h.commissionService.ListStatements(r.Context(), filter)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to export commission statements")
+		return
+	}
+	var buf bytes.Buffer
+	if err := service.StatementsTable(*filter.PeriodStart, statements).Write(&buf, format); err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to export commission statements")
+		return
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="commission-%s.%s"`, filter.PeriodStart.Format("2006-01"), format))
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// GetStatement returns a statement with its payments (GET /api/v1/commissions/statements/{id})
+func (h *CommissionHandler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	id, ok := h.pathID(w, r, "statement")
+	if !ok {
+		return
+	}
+	st, err := h.commissionService.GetStatement(r.Context(), tenantID, nil, id)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to get commission statement")
+		return
+	}
+	sendJSON(w, http.StatusOK, st)
+}
+
+// Approve (POST /api/v1/commissions/statements/{id}/approve)
+func (h *CommissionHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	id, ok := h.pathID(w, r, "statement")
+	if !ok {
+		return
+	}
+	st, err := h.commissionService.Approve(r.Context(), tenantID, userID, id)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to approve commission statement")
+		return
+	}
+	sendJSON(w, http.StatusOK, st)
+}
+
+// MarkPaid records the payout (POST /api/v1/commissions/statements/{id}/pay)
+func (h *CommissionHandler) MarkPaid(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	id, ok := h.pathID(w, r, "statement")
+	if !ok {
+		return
+	}
+	var req struct {
+		Reference *string `json:"reference,omitempty"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	st, err := h.commissionService.MarkPaid(r.Context(), tenantID, userID, id, req.Reference)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to mark commission statement paid")
+		return
+	}
+	sendJSON(w, http.StatusOK, st)
+}
+
+// MyStatements lists the caller's own statements (GET /api/v1/commissions/me/statements)
+func (h *CommissionHandler) MyStatements(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	filter, ok := h.statementFilter(w, r, tenantID)
+	if !ok {
+		return
+	}
+	filter.UserID = &userID
+	statements, total, err := h.commissionService.ListStatements(r.Context(), filter)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to list commission statements")
+		return
+	}
+	sendJSON(w, http.StatusOK, map[string]interface{}{"data": statements, "total": total})
+}
+
+// MyStatement (GET /api/v1/commissions/me/statements/{id})
+func (h *CommissionHandler) MyStatement(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	id, ok := h.pathID(w, r, "statement")
+	if !ok {
+		return
+	}
+	st, err := h.commissionService.GetStatement(r.Context(), tenantID, &userID, id)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to get commission statement")
+		return
+	}
+	sendJSON(w, http.StatusOK, st)
+}
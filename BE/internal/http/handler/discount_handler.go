@@ -114,6 +114,8 @@ func (h *DiscountHandler) Create(w http.ResponseWriter, r *http.Request) {
 			sendError(w, http.StatusBadRequest, "Invalid discount value")
 		case service.ErrDiscountExpired:
 			sendError(w, http.StatusBadRequest, "Discount expiry date must be in the future")
+		case service.ErrDiscountCyclesInvalid:
+			sendError(w, http.StatusBadRequest, "Max cycles must be greater than 0")
 		case repository.ErrDiscountNameTaken:
 			sendError(w, http.StatusConflict, "Discount name already exists")
 		default:
@@ -165,6 +167,8 @@ func (h *DiscountHandler) Update(w http.ResponseWriter, r *http.Request) {
 			sendError(w, http.StatusBadRequest, "Invalid discount value")
 		case service.ErrDiscountExpired:
 			sendError(w, http.StatusBadRequest, "Discount expiry date must be in the future")
+		case service.ErrDiscountCyclesInvalid:
+			sendError(w, http.StatusBadRequest, "Max cycles must be greater than 0")
 		case repository.ErrDiscountNameTaken:
 			sendError(w, http.StatusConflict, "Discount name already exists")
 		default:
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/auth"
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/wa_template"
+	"rrnet/internal/repository"
+	"rrnet/internal/service"
+)
+
+type DunningHandler struct {
+	dunningService *service.DunningService
+}
+
+func NewDunningHandler(dunningService *service.DunningService) *DunningHandler {
+	return &DunningHandler{dunningService: dunningService}
+}
+
+func (h *DunningHandler) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	out, err := h.dunningService.GetPolicy(r.Context(), tenantID)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get dunning policy")
+		return
+	}
+	sendJSON(w, http.StatusOK, out)
+}
+
+func (h *DunningHandler) UpdatePolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	var req billing.DunningPolicy
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	out, err := h.dunningService.UpdatePolicy(r.Context(), tenantID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrDunningStepInvalid), errors.Is(err, service.ErrDunningStepDuplicate),
+			errors.Is(err, service.ErrDunningTooManySteps), errors.Is(err, service.ErrDunningProfileRequired),
+			errors.Is(err, wa_template.ErrTemplateInvalid):
+			sendError(w, http.StatusBadRequest, err.Error())
+		default:
+			sendError(w, http.StatusInternalServerError, "Failed to update dunning policy")
+		}
+		return
+	}
+	sendJSON(w, http.StatusOK, out)
+}
+
+// Preview is a dry run listing the steps that would run on ?date=YYYY-MM-DD (default: tomorrow)
+func (h *DunningHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+
+	date := time.Now().AddDate(0, 0, 1)
+	if v := r.URL.Query().Get("date"); v != "" {
+		d, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, "Invalid date (use YYYY-MM-DD)")
+			return
+		}
+		date = d
+	}
+
+	out, err := h.dunningService.Preview(r.Context(), tenantID, date)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to preview dunning")
+		sendError(w, http.StatusInternalServerError, "Failed to preview dunning")
+		return
+	}
+	sendJSON(w, http.StatusOK, out)
+}
+
+// ListRuns returns the recorded dunning steps
+func (h *DunningHandler) ListRuns(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+
+	q := r.URL.Query()
+	filter := repository.DunningRunFilter{TenantID: tenantID}
+	if v := q.Get("client_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, "Invalid client_id")
+			return
+		}
+		filter.ClientID = &id
+	}
+	if v := q.Get("invoice_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, "Invalid invoice_id")
+			return
+		}
+		filter.InvoiceID = &id
+	}
+	if v := q.Get("action"); v != "" {
+		a := billing.DunningAction(v)
+		filter.Action = &a
+	}
+	if v := q.Get("status"); v != "" {
+		s := billing.DunningStepStatus(v)
+		filter.Status = &s
+	}
+	if v := q.Get("page"); v != "" {
+		if p, err := strconv.Atoi(v); err == nil {
+			filter.Page = p
+		}
+	}
+	if v := q.Get("page_size"); v != "" {
+		if ps, err := strconv.Atoi(v); err == nil {
+			filter.PageSize = ps
+		}
+	}
+
+	runs, total, err := h.dunningService.ListRuns(r.Context(), filter)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list dunning runs")
+		sendError(w, http.StatusInternalServerError, "Failed to list dunning runs")
+		return
+	}
+	sendJSON(w, http.StatusOK, map[string]interface{}{
+		"data":  runs,
+		"total": total,
+		"page":  filter.Page,
+	})
+}
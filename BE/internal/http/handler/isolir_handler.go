@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/auth"
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/repository"
+	"rrnet/internal/service"
+)
+
+type IsolirHandler struct {
+	isolirService *service.IsolirService
+}
+
+func NewIsolirHandler(isolirService *service.IsolirService) *IsolirHandler {
+	return &IsolirHandler{isolirService: isolirService}
+}
+
+// ListLogs returns the isolir history of a tenant
+func (h *IsolirHandler) ListLogs(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+
+	q := r.URL.Query()
+	filter := repository.IsolirLogFilter{TenantID: tenantID}
+
+	if v := q.Get("client_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, "Invalid client_id")
+			return
+		}
+		filter.ClientID = &id
+	}
+	if v := q.Get("action"); v != "" {
+		a := billing.IsolirAction(v)
+		filter.Action = &a
+	}
+	if v := q.Get("status"); v != "" {
+		s := billing.IsolirStatus(v)
+		filter.Status = &s
+	}
+	if v := q.Get("is_automatic"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, "Invalid is_automatic")
+			return
+		}
+		filter.IsAutomatic = &b
+	}
+	if v := q.Get("start_date"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, "Invalid start_date (use YYYY-MM-DD)")
+			return
+		}
+		filter.StartDate = &t
+	}
+	if v := q.Get("end_date"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, "Invalid end_date (use YYYY-MM-DD)")
+			return
+		}
+		end := t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		filter.EndDate = &end
+	}
+	if v := q.Get("page"); v != "" {
+		if p, err := strconv.Atoi(v); err == nil {
+			filter.Page = p
+		}
+	}
+	if v := q.Get("page_size"); v != "" {
+		if ps, err := strconv.Atoi(v); err == nil {
+			filter.PageSize = ps
+		}
+	}
+
+	logs, total, err := h.isolirService.ListLogs(r.Context(), filter)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list isolir logs")
+		sendError(w, http.StatusInternalServerError, "Failed to list isolir logs")
+		return
+	}
+
+	sendJSON(w, http.StatusOK, map[string]interface{}{
+		"data":  logs,
+		"total": total,
+		"page":  filter.Page,
+	})
+}
+
+func (h *IsolirHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	out, err := h.isolirService.GetSettings(r.Context(), tenantID)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get isolir settings")
+		return
+	}
+	sendJSON(w, http.StatusOK, out)
+}
+
+func (h *IsolirHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	var req service.IsolirSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	out, err := h.isolirService.UpdateSettings(r.Context(), tenantID, req)
+	if err != nil {
+		switch err {
+		case service.ErrIsolirModeInvalid, service.ErrIsolirProfileMissing, service.ErrIsolirGraceInvalid:
+			sendError(w, http.StatusBadRequest, err.Error())
+		default:
+			sendError(w, http.StatusInternalServerError, "Failed to update isolir settings")
+		}
+		return
+	}
+	sendJSON(w, http.StatusOK, out)
+}
+
+type isolirActionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// Isolate manually isolates a client (POST /api/v1/clients/{id}/isolate)
+func (h *IsolirHandler) Isolate(w http.ResponseWriter, r *http.Request) {
+	h.runAction(w, r, h.isolirService.Isolate)
+}
+
+// Reactivate manually reactivates an isolated client (POST /api/v1/clients/{id}/reactivate)
+func (h *IsolirHandler) Reactivate(w http.ResponseWriter, r *http.Request) {
+	h.runAction(w, r, h.isolirService.Reactivate)
+}
+
+func (h *IsolirHandler) runAction(
+	w http.ResponseWriter,
+	r *http.Request,
+	action func(ctx context.Context, tenantID, clientID uuid.UUID, req service.IsolateRequest) (*billing.IsolirLog, error),
+) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	clientID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid client ID")
+		return
+	}
+
+	var body isolirActionRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			sendError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	req := service.IsolateRequest{Reason: body.Reason}
+	if userID, ok := auth.GetUserID(r.Context()); ok {
+		req.ExecutedBy = &userID
+	}
+
+	entry, err := action(r.Context(), tenantID, clientID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrClientNotFound):
+			sendError(w, http.StatusNotFound, "Client not found")
+		case errors.Is(err, service.ErrClientNotActive), errors.Is(err, service.ErrClientNotIsolated):
+			sendError(w, http.StatusBadRequest, err.Error())
+		case entry != nil:
+			// Router enforcement failed; the failed log entry is still returned
+			sendJSON(w, http.StatusBadGateway, map[string]interface{}{
+				"error": err.Error(),
+				"log":   entry,
+			})
+		default:
+			log.Error().Err(err).Str("client_id", clientID.String()).Msg("Isolir action failed")
+			sendError(w, http.StatusInternalServerError, "Isolir action failed")
+		}
+		return
+	}
+	sendJSON(w, http.StatusOK, entry)
+}
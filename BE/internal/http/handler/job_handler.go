@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"rrnet/internal/auth"
+	"rrnet/internal/domain/job"
+	"rrnet/internal/repository"
+	"rrnet/internal/service"
+)
+
+// JobHandler exposes the scheduled background jobs to super admins
+type JobHandler struct {
+	jobService *service.JobService
+}
+
+func NewJobHandler(jobService *service.JobService) *JobHandler {
+	return &JobHandler{jobService: jobService}
+}
+
+// ListJobs returns the scheduled jobs (GET /api/v1/superadmin/jobs)
+func (h *JobHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	jobs := h.jobService.ListJobs()
+	sendJSON(w, http.StatusOK, map[string]interface{}{
+		"data":  jobs,
+		"total": len(jobs),
+	})
+}
+
+// ListRuns returns the run history, newest first (GET /api/v1/superadmin/jobs/runs). Filters: job,
+// status, tenant_id; parent_id lists the per-tenant runs of a run.
+func (h *JobHandler) ListRuns(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := repository.JobRunFilter{Job: q.Get("job")}
+	filter.Page, _ = strconv.Atoi(q.Get("page"))
+	filter.PageSize, _ = strconv.Atoi(q.Get("page_size"))
+	if v := q.Get("status"); v != "" {
+		status := job.Status(v)
+		filter.Status = &status
+	}
+	for key, target := range map[string]**uuid.UUID{"tenant_id": &filter.TenantID, "parent_id": &filter.ParentID} {
+		v := q.Get(key)
+		if v == "" {
+			continue
+		}
+		id, err := uuid.Parse(v)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, "Invalid "+key)
+			return
+		}
+		*target = &id
+	}
+
+	runs, total, err := h.jobService.ListRuns(r.Context(), filter)
+	if err != nil {
+		if errors.Is(err, service.ErrJobNotFound) {
+			sendError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sendJSON(w, http.StatusOK, map[string]interface{}{
+		"data":  runs,
+		"total": total,
+	})
+}
+
+// TriggerJob enqueues a run of a job now (POST /api/v1/superadmin/jobs/{name}/run)
+func (h *JobHandler) TriggerJob(w http.ResponseWriter, r *http.Request) {
+	name := getPathParam(r, "name")
+	userID, _ := auth.GetUserID(r.Context())
+	runID, err := h.jobService.Trigger(r.Context(), name, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrJobNotFound) {
+			sendError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sendJSON(w, http.StatusAccepted, map[string]interface{}{
+		"job":    name,
+		"run_id": runID,
+		"status": "queued",
+	})
+}
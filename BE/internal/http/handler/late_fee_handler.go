@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/auth"
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/repository"
+	"rrnet/internal/service"
+)
+
+type LateFeeHandler struct {
+	lateFeeService *service.LateFeeService
+}
+
+func NewLateFeeHandler(lateFeeService *service.LateFeeService) *LateFeeHandler {
+	return &LateFeeHandler{lateFeeService: lateFeeService}
+}
+
+func (h *LateFeeHandler) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	out, err := h.lateFeeService.GetPolicy(r.Context(), tenantID)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get late fee policy")
+		return
+	}
+	sendJSON(w, http.StatusOK, out)
+}
+
+func (h *LateFeeHandler) UpdatePolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	var req billing.LateFeePolicy
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	out, err := h.lateFeeService.UpdatePolicy(r.Context(), tenantID, req)
+	if err != nil {
+		switch err {
+		case service.ErrLateFeeRuleInvalid, service.ErrLateFeeModeInvalid:
+			sendError(w, http.StatusBadRequest, err.Error())
+		default:
+			sendError(w, http.StatusInternalServerError, "Failed to update late fee policy")
+		}
+		return
+	}
+	sendJSON(w, http.StatusOK, out)
+}
+
+// ListInvoiceLateFees returns the late fees of an invoice (GET /api/v1/billing/invoices/{id}/late-fees)
+func (h *LateFeeHandler) ListInvoiceLateFees(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	invoiceID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid invoice ID")
+		return
+	}
+	fees, err := h.lateFeeService.ListInvoiceLateFees(r.Context(), tenantID, invoiceID)
+	if err != nil {
+		if err == service.ErrInvoiceNotFound {
+			sendError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		log.Error().Err(err).Msg("Failed to list late fees")
+		sendError(w, http.StatusInternalServerError, "Failed to list late fees")
+		return
+	}
+	sendJSON(w, http.StatusOK, map[string]interface{}{"data": fees})
+}
+
+// Waive takes a late fee off its invoice (POST /api/v1/billing/late-fees/{id}/waive)
+func (h *LateFeeHandler) Waive(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		sendError(w, http.StatusBadRequest, "No user context")
+		return
+	}
+	feeID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid late fee ID")
+		return
+	}
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	fee, err := h.lateFeeService.Waive(r.Context(), tenantID, userID, feeID, req.Reason)
+	if err != nil {
+		switch err {
+		case repository.ErrLateFeeNotFound:
+			sendError(w, http.StatusNotFound, err.Error())
+		case repository.ErrLateFeeAlreadyWaived:
+			sendError(w, http.StatusConflict, err.Error())
+		case service.ErrLateFeeWaiveReasonRequired, service.ErrCreditNoteAmountInvalid, service.ErrCreditNoteInvoiceInvalid:
+			sendError(w, http.StatusBadRequest, err.Error())
+		default:
+			log.Error().Err(err).Str("late_fee_id", feeID.String()).Msg("Failed to waive late fee")
+			sendError(w, http.StatusInternalServerError, "Failed to waive late fee")
+		}
+		return
+	}
+	sendJSON(w, http.StatusOK, fee)
+}
@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/auth"
+	"rrnet/internal/domain/ledger"
+	"rrnet/internal/repository"
+	"rrnet/internal/service"
+)
+
+// LedgerHandler serves the tenant's books: chart of accounts, journal, trial balance and general ledger
+type LedgerHandler struct {
+	ledgerService *service.LedgerService
+}
+
+func NewLedgerHandler(ledgerService *service.LedgerService) *LedgerHandler {
+	return &LedgerHandler{ledgerService: ledgerService}
+}
+
+func (h *LedgerHandler) sendServiceError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, repository.ErrLedgerAccountNotFound), errors.Is(err, repository.ErrLedgerEntryNotFound):
+		sendError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrLedgerAccountInvalid), errors.Is(err, service.ErrLedgerEntryInvalid),
+		errors.Is(err, service.ErrLedgerEntryUnbalanced), errors.Is(err, service.ErrLedgerDateInvalid),
+		errors.Is(err, service.ErrLedgerPeriodInvalid):
+		sendError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repository.ErrLedgerAccountCodeTaken):
+		sendError(w, http.StatusConflict, err.Error())
+	default:
+		log.Error().Err(err).Msg(msg)
+		sendError(w, http.StatusInternalServerError, msg)
+	}
+}
+
+func (h *LedgerHandler) caller(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return uuid.Nil, uuid.Nil, false
+	}
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		sendError(w, http.StatusBadRequest, "No user context")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return tenantID, userID, true
+}
+
+// queryDate parses an optional YYYY-MM-DD query parameter
+func (h *LedgerHandler) queryDate(w http.ResponseWriter, r *http.Request, name string) (*time.Time, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return nil, true
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, service.ErrLedgerDateInvalid.Error())
+		return nil, false
+	}
+	return &t, true
+}
+
+// ListAccounts returns the chart of accounts (GET /api/v1/ledger/accounts)
+func (h *LedgerHandler) ListAccounts(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	accounts, err := h.ledgerService.ListAccounts(r.Context(), tenantID)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to list ledger accounts")
+		return
+	}
+	sendJSON(w, http.StatusOK, map[string]interface{}{"data": accounts})
+}
+
+// CreateAccount (POST /api/v1/ledger/accounts)
+func (h *LedgerHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	var req service.LedgerAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	account, err := h.ledgerService.CreateAccount(r.Context(), tenantID, req)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to create ledger account")
+		return
+	}
+	sendJSON(w, http.StatusCreated, account)
+}
+
+// ListEntries returns the journal, newest first
+// (GET /api/v1/ledger/entries?source=&source_id=&client_id=&from=&to=&page=&page_size=)
+func (h *LedgerHandler) ListEntries(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	filter := repository.LedgerEntryFilter{TenantID: tenantID}
+	filter.Page, _ = strconv.Atoi(q.Get("page"))
+	filter.PageSize, _ = strconv.Atoi(q.Get("page_size"))
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 20
+	}
+	if v := q.Get("source"); v != "" {
+		source := ledger.Source(v)
+		filter.Source = &source
+	}
+	if v := q.Get("source_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, "Invalid source ID")
+			return
+		}
+		filter.SourceID = &id
+	}
+	if v := q.Get("client_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, "Invalid client ID")
+			return
+		}
+		filter.ClientID = &id
+	}
+	if filter.From, ok = h.queryDate(w, r, "from"); !ok {
+		return
+	}
+	if filter.To, ok = h.queryDate(w, r, "to"); !ok {
+		return
+	}
+	entries, total, err := h.ledgerService.ListEntries(r.Context(), filter)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to list journal entries")
+		return
+	}
+	sendJSON(w, http.StatusOK, map[string]interface{}{"data": entries, "total": total})
+}
+
+// GetEntry (GET /api/v1/ledger/entries/{id})
+func (h *LedgerHandler) GetEntry(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	id, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid entry ID")
+		return
+	}
+	entry, err := h.ledgerService.GetEntry(r.Context(), tenantID, id)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to get journal entry")
+		return
+	}
+	sendJSON(w, http.StatusOK, entry)
+}
+
+// CreateEntry posts a manual journal entry (POST /api/v1/ledger/entries)
+func (h *LedgerHandler) CreateEntry(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	var req service.ManualEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	entry, err := h.ledgerService.CreateManualEntry(r.Context(), tenantID, userID, req)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to post journal entry")
+		return
+	}
+	sendJSON(w, http.StatusCreated, entry)
+}
+
+// TrialBalance (GET /api/v1/ledger/trial-balance?as_of=YYYY-MM-DD, default today)
+func (h *LedgerHandler) TrialBalance(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	asOf, ok := h.queryDate(w, r, "as_of")
+	if !ok {
+		return
+	}
+	if asOf == nil {
+		now := time.Now()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		asOf = &today
+	}
+	tb, err := h.ledgerService.TrialBalance(r.Context(), tenantID, *asOf)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to compute trial balance")
+		return
+	}
+	sendJSON(w, http.StatusOK, tb)
+}
+
+// GeneralLedger returns the postings of one account with running balance
+// (GET /api/v1/ledger/general-ledger?account_id=&from=YYYY-MM-DD&to=YYYY-MM-DD, default this month)
+func (h *LedgerHandler) GeneralLedger(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	accountID, err := uuid.Parse(r.URL.Query().Get("account_id"))
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+	from, ok := h.queryDate(w, r, "from")
+	if !ok {
+		return
+	}
+	to, ok := h.queryDate(w, r, "to")
+	if !ok {
+		return
+	}
+	now := time.Now()
+	if from == nil {
+		first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		from = &first
+	}
+	if to == nil {
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		to = &today
+	}
+	gl, err := h.ledgerService.GeneralLedger(r.Context(), tenantID, accountID, *from, *to)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to get general ledger")
+		return
+	}
+	sendJSON(w, http.StatusOK, gl)
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/auth"
+	"rrnet/internal/domain/notification"
+	"rrnet/internal/repository"
+	"rrnet/internal/service"
+)
+
+// NotificationHandler serves the settings, history and client opt-outs of WhatsApp billing
+// notifications
+type NotificationHandler struct {
+	notificationService *service.NotificationService
+}
+
+func NewNotificationHandler(notificationService *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notificationService: notificationService}
+}
+
+func (h *NotificationHandler) sendServiceError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, service.ErrNotificationWindowInvalid), errors.Is(err, service.ErrNotificationEventInvalid),
+		errors.Is(err, service.ErrNotificationTemplateInvalid), errors.Is(err, service.ErrNotificationTimezoneInvalid):
+		sendError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repository.ErrClientNotFound), errors.Is(err, repository.ErrOptOutNotFound):
+		sendError(w, http.StatusNotFound, err.Error())
+	default:
+		log.Error().Err(err).Msg(msg)
+		sendError(w, http.StatusInternalServerError, msg)
+	}
+}
+
+// GetSettings (GET /api/v1/wa-notifications/settings)
+func (h *NotificationHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	out, err := h.notificationService.GetSettings(r.Context(), tenantID)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to get notification settings")
+		return
+	}
+	sendJSON(w, http.StatusOK, out)
+}
+
+// UpdateSettings (PUT /api/v1/wa-notifications/settings)
+func (h *NotificationHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	var req notification.Settings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	out, err := h.notificationService.UpdateSettings(r.Context(), tenantID, req)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to update notification settings")
+		return
+	}
+	sendJSON(w, http.StatusOK, out)
+}
+
+// List returns sent, queued and skipped notifications (GET /api/v1/wa-notifications)
+func (h *NotificationHandler) List(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	q := r.URL.Query()
+	filter := repository.NotificationFilter{TenantID: tenantID}
+	filter.Page, _ = strconv.Atoi(q.Get("page"))
+	filter.PageSize, _ = strconv.Atoi(q.Get("page_size"))
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 20
+	}
+	if v := q.Get("client_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, "Invalid client ID")
+			return
+		}
+		filter.ClientID = &id
+	}
+	if v := q.Get("event"); v != "" {
+		event := notification.Event(v)
+		filter.Event = &event
+	}
+	if v := q.Get("status"); v != "" {
+		status := notification.Status(v)
+		filter.Status = &status
+	}
+	out, total, err := h.notificationService.List(r.Context(), filter)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to list notifications")
+		return
+	}
+	sendJSON(w, http.StatusOK, map[string]interface{}{"data": out, "total": total})
+}
+
+// ListOptOuts (GET /api/v1/wa-notifications/opt-outs)
+func (h *NotificationHandler) ListOptOuts(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	out, err := h.notificationService.ListOptOuts(r.Context(), tenantID)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to list notification opt-outs")
+		return
+	}
+	sendJSON(w, http.StatusOK, map[string]interface{}{"data": out})
+}
+
+// OptOut stops notifications to a client (POST /api/v1/wa-notifications/opt-outs)
+func (h *NotificationHandler) OptOut(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		sendError(w, http.StatusBadRequest, "No user context")
+		return
+	}
+	var req struct {
+		ClientID uuid.UUID `json:"client_id"`
+		Reason   *string   `json:"reason,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ClientID == uuid.Nil {
+		sendError(w, http.StatusBadRequest, "client_id is required")
+		return
+	}
+	out, err := h.notificationService.OptOut(r.Context(), tenantID, userID, req.ClientID, req.Reason)
+	if err != nil {
+		h.sendServiceError(w, err, "Failed to opt client out of notifications")
+		return
+	}
+	sendJSON(w, http.StatusCreated, out)
+}
+
+// RemoveOptOut resumes notifications to a client (DELETE /api/v1/wa-notifications/opt-outs/{client_id})
+func (h *NotificationHandler) RemoveOptOut(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	clientID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid client ID")
+		return
+	}
+	if err := h.notificationService.RemoveOptOut(r.Context(), tenantID, clientID); err != nil {
+		h.sendServiceError(w, err, "Failed to remove notification opt-out")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/auth"
+	pgw "rrnet/internal/infra/payment_gateway"
+	"rrnet/internal/repository"
+	"rrnet/internal/service"
+)
+
+const maxWebhookBody = 1 << 20
+
+type PaymentGatewayHandler struct {
+	gatewayService *service.PaymentGatewayService
+}
+
+func NewPaymentGatewayHandler(gatewayService *service.PaymentGatewayService) *PaymentGatewayHandler {
+	return &PaymentGatewayHandler{gatewayService: gatewayService}
+}
+
+func (h *PaymentGatewayHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	out, err := h.gatewayService.GetSettings(r.Context(), tenantID)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get payment gateway settings")
+		return
+	}
+	sendJSON(w, http.StatusOK, out)
+}
+
+func (h *PaymentGatewayHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	var req service.PaymentGatewaySettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	out, err := h.gatewayService.UpdateSettings(r.Context(), tenantID, req)
+	if err != nil {
+		switch err {
+		case service.ErrPaymentGatewayProviderInvalid, pgw.ErrMissingCredentials:
+			sendError(w, http.StatusBadRequest, err.Error())
+		default:
+			sendError(w, http.StatusInternalServerError, "Failed to update payment gateway settings")
+		}
+		return
+	}
+	sendJSON(w, http.StatusOK, out)
+}
+
+// CreatePaymentRequest creates a VA/QRIS/e-wallet payment for an invoice
+// (POST /api/v1/billing/invoices/{id}/payment-requests)
+func (h *PaymentGatewayHandler) CreatePaymentRequest(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		sendError(w, http.StatusBadRequest, "No user context")
+		return
+	}
+	invoiceID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid invoice ID")
+		return
+	}
+	var req service.CreatePaymentRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	pr, err := h.gatewayService.CreatePaymentRequest(r.Context(), tenantID, userID, invoiceID, req)
+	if err != nil {
+		switch err {
+		case service.ErrInvoiceNotFound:
+			sendError(w, http.StatusNotFound, err.Error())
+		case service.ErrPaymentRequestMethodInvalid, pgw.ErrUnsupportedMethod:
+			sendError(w, http.StatusBadRequest, err.Error())
+		case service.ErrPaymentGatewayDisabled, service.ErrPaymentRequestInvoiceInvalid, pgw.ErrMissingCredentials, pgw.ErrUnknownProvider:
+			sendError(w, http.StatusConflict, err.Error())
+		default:
+			log.Error().Err(err).Str("invoice_id", invoiceID.String()).Msg("Failed to create payment request")
+			sendError(w, http.StatusBadGateway, "Failed to create payment at the payment gateway")
+		}
+		return
+	}
+	sendJSON(w, http.StatusCreated, pr)
+}
+
+// ListPaymentRequests returns the gateway payments of an invoice (GET /api/v1/billing/invoices/{id}/payment-requests)
+func (h *PaymentGatewayHandler) ListPaymentRequests(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	invoiceID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid invoice ID")
+		return
+	}
+	out, err := h.gatewayService.ListPaymentRequests(r.Context(), tenantID, invoiceID)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to list payment requests")
+		return
+	}
+	sendJSON(w, http.StatusOK, map[string]interface{}{"data": out})
+}
+
+// SimulatePayment pays a mock provider request (POST /api/v1/billing/payment-requests/{id}/simulate)
+func (h *PaymentGatewayHandler) SimulatePayment(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	requestID, err := uuid.Parse(getPathParam(r, "id"))
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid payment request ID")
+		return
+	}
+	pr, err := h.gatewayService.SimulatePayment(r.Context(), tenantID, requestID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrPaymentRequestNotFound):
+			sendError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, service.ErrPaymentGatewayNotMock):
+			sendError(w, http.StatusConflict, err.Error())
+		default:
+			log.Error().Err(err).Str("payment_request_id", requestID.String()).Msg("Failed to simulate payment")
+			sendError(w, http.StatusInternalServerError, "Failed to simulate payment")
+		}
+		return
+	}
+	sendJSON(w, http.StatusOK, pr)
+}
+
+// Webhook receives a payment gateway notification (POST /api/v1/webhooks/payments/{provider}/{tenant_id}).
+// Public endpoint: authenticity is established by the provider's signature.
+func (h *PaymentGatewayHandler) Webhook(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(getPathParam(r, "tenant_id"))
+	if err != nil {
+		sendError(w, http.StatusNotFound, "Unknown tenant")
+		return
+	}
+	provider := getPathParam(r, "provider")
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	err = h.gatewayService.HandleWebhook(r.Context(), provider, tenantID, r.Header, body)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgw.ErrInvalidSignature), errors.Is(err, pgw.ErrMissingCredentials), errors.Is(err, pgw.ErrUnknownProvider):
+			sendError(w, http.StatusUnauthorized, "Invalid signature")
+		case errors.Is(err, repository.ErrPaymentRequestNotFound):
+			sendError(w, http.StatusNotFound, "Unknown payment")
+		case errors.Is(err, service.ErrPaymentGatewayAmountMismatch):
+			sendError(w, http.StatusUnprocessableEntity, "Amount mismatch")
+		default:
+			log.Error().Err(err).Str("provider", provider).Str("tenant_id", tenantID.String()).Msg("Failed to process payment webhook")
+			sendError(w, http.StatusInternalServerError, "Failed to process webhook")
+		}
+		return
+	}
+	sendJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"rrnet/internal/auth"
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/reporting"
+)
+
+// ReportHandler serves the billing reports as JSON, or as a file with ?format=csv|xlsx
+type ReportHandler struct {
+	reportService *reporting.Service
+}
+
+func NewReportHandler(reportService *reporting.Service) *ReportHandler {
+	return &ReportHandler{reportService: reportService}
+}
+
+// ARAging returns outstanding receivables per client by age (GET /api/v1/reports/ar-aging)
+func (h *ReportHandler) ARAging(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	report, err := h.reportService.ARAging(r.Context(), tenantID)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.send(w, r, report, report.Table, "ar-aging-"+report.AsOf.Format("20060102"))
+}
+
+// Revenue groups revenue by package, group, router or collector
+// (GET /api/v1/reports/revenue?by=package&basis=cash&from=2026-01-01&to=2026-02-01, to exclusive;
+// defaults to the current month)
+func (h *ReportHandler) Revenue(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	q := r.URL.Query()
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	to := from.AddDate(0, 1, 0)
+	for key, target := range map[string]*time.Time{"from": &from, "to": &to} {
+		if v := q.Get(key); v != "" {
+			d, err := time.ParseInLocation("2006-01-02", v, time.Local)
+			if err != nil {
+				sendError(w, http.StatusBadRequest, "Invalid "+key+" (use YYYY-MM-DD)")
+				return
+			}
+			*target = d
+		}
+	}
+
+	by := billing.RevenueDimension(q.Get("by"))
+	if by == "" {
+		by = billing.RevenueByPackage
+	}
+	report, err := h.reportService.Revenue(r.Context(), tenantID, by, billing.RevenueBasis(q.Get("basis")), from, to)
+	if err != nil {
+		if errors.Is(err, reporting.ErrDimensionInvalid) || errors.Is(err, reporting.ErrBasisInvalid) || errors.Is(err, reporting.ErrPeriodInvalid) {
+			sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	name := fmt.Sprintf("revenue-%s-%s-%s", report.By, report.Basis, from.Format("20060102"))
+	h.send(w, r, report, report.Table, name)
+}
+
+// MonthlyRevenue compares accrual and cash revenue per month
+// (GET /api/v1/reports/monthly-revenue?year=2026, defaults to the current year)
+func (h *ReportHandler) MonthlyRevenue(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+	year := time.Now().Year()
+	if v := r.URL.Query().Get("year"); v != "" {
+		y, err := strconv.Atoi(v)
+		if err != nil || y < 2000 || y > 2100 {
+			sendError(w, http.StatusBadRequest, "Invalid year")
+			return
+		}
+		year = y
+	}
+	report, err := h.reportService.MonthlyRevenue(r.Context(), tenantID, year)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.send(w, r, report, report.Table, fmt.Sprintf("monthly-revenue-%d", year))
+}
+
+// send writes the report as JSON, or as an attachment when ?format is set
+func (h *ReportHandler) send(w http.ResponseWriter, r *http.Request, report interface{}, table func() *reporting.Table, filename string) {
+	format := reporting.Format(r.URL.Query().Get("format"))
+	if format == "" || format == "json" {
+		sendJSON(w, http.StatusOK, report)
+		return
+	}
+	if format != reporting.FormatCSV && format != reporting.FormatXLSX {
+		sendError(w, http.StatusBadRequest, reporting.ErrFormatInvalid.Error())
+		return
+	}
+	var buf bytes.Buffer
+	if err := table().Write(&buf, format); err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to export report")
+		return
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format))
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
@@ -102,6 +102,10 @@ func (h *ServicePackageHandler) Create(w http.ResponseWriter, r *http.Request) {
 			sendError(w, http.StatusBadRequest, "Network profile is required")
 		case service.ErrServicePackagePriceInvalid:
 			sendError(w, http.StatusBadRequest, "Invalid price")
+		case service.ErrBillingModeInvalid:
+			sendError(w, http.StatusBadRequest, "Billing mode must be postpaid or prepaid")
+		case service.ErrServicePackageCycleInvalid:
+			sendError(w, http.StatusBadRequest, err.Error())
 		case repository.ErrServicePackageNameTaken:
 			sendError(w, http.StatusConflict, "Package name already exists")
 		default:
@@ -149,6 +153,10 @@ func (h *ServicePackageHandler) Update(w http.ResponseWriter, r *http.Request) {
 			sendError(w, http.StatusBadRequest, "Network profile is required")
 		case service.ErrServicePackagePriceInvalid:
 			sendError(w, http.StatusBadRequest, "Invalid price")
+		case service.ErrBillingModeInvalid:
+			sendError(w, http.StatusBadRequest, "Billing mode must be postpaid or prepaid")
+		case service.ErrServicePackageCycleInvalid:
+			sendError(w, http.StatusBadRequest, err.Error())
 		case repository.ErrServicePackageNameTaken:
 			sendError(w, http.StatusConflict, "Package name already exists")
 		default:
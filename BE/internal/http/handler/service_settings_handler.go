@@ -46,7 +46,7 @@ func (h *ServiceSettingsHandler) UpdateDiscount(w http.ResponseWriter, r *http.R
 	out, err := h.svc.UpdateDiscount(r.Context(), tenantID, req)
 	if err != nil {
 		switch err {
-		case service.ErrDiscountTypeInvalid, service.ErrDiscountValueInvalid:
+		case service.ErrDiscountTypeInvalid, service.ErrDiscountValueInvalid, service.ErrDiscountStackingInvalid:
 			sendError(w, http.StatusBadRequest, err.Error())
 		default:
 			sendError(w, http.StatusInternalServerError, "Failed to update discount")
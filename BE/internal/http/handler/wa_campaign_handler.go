@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"rrnet/internal/auth"
+	"rrnet/internal/domain/wa_template"
 	"rrnet/internal/repository"
 	"rrnet/internal/service"
 )
@@ -45,6 +47,10 @@ func (h *WACampaignHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	c, err := h.svc.CreateAndEnqueue(r.Context(), tenantID, req.Name, req.Message, groupID)
 	if err != nil {
+		if errors.Is(err, wa_template.ErrTemplateInvalid) {
+			sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		switch err {
 		case service.ErrWACampaignNameRequired:
 			sendError(w, http.StatusBadRequest, "Name is required")
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"rrnet/internal/auth"
+	"rrnet/internal/domain/wa_template"
 	"rrnet/internal/repository"
 	"rrnet/internal/service"
 )
@@ -55,6 +57,10 @@ func (h *WATemplateHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	tpl, err := h.svc.Create(r.Context(), tenantID, req.Name, req.Content)
 	if err != nil {
+		if errors.Is(err, wa_template.ErrTemplateInvalid) {
+			sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		switch err {
 		case service.ErrWATemplateNameRequired:
 			sendError(w, http.StatusBadRequest, "Name is required")
@@ -97,6 +103,10 @@ func (h *WATemplateHandler) Update(w http.ResponseWriter, r *http.Request) {
 
 	tpl, err := h.svc.Update(r.Context(), tenantID, id, req.Name, req.Content)
 	if err != nil {
+		if errors.Is(err, wa_template.ErrTemplateInvalid) {
+			sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		switch err {
 		case service.ErrWATemplateNameRequired:
 			sendError(w, http.StatusBadRequest, "Name is required")
@@ -144,3 +154,50 @@ func (h *WATemplateHandler) Delete(w http.ResponseWriter, r *http.Request) {
 }
 
 
+
+// Variables lists the placeholders templates may use (GET /api/v1/wa-templates/variables)
+func (h *WATemplateHandler) Variables(w http.ResponseWriter, r *http.Request) {
+	sendJSON(w, http.StatusOK, map[string]any{
+		"data":    wa_template.Variables,
+		"filters": []string{"rupiah", "date", "upper", "lower", "default"},
+	})
+}
+
+// Preview renders a template for a client (POST /api/v1/wa-templates/preview)
+func (h *WATemplateHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := auth.GetTenantID(r.Context())
+	if !ok || tenantID == (uuid.UUID{}) {
+		sendError(w, http.StatusBadRequest, "No tenant context")
+		return
+	}
+
+	var req service.WATemplatePreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	out, err := h.svc.Preview(r.Context(), tenantID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, wa_template.ErrTemplateInvalid):
+			sendError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrWATemplateClientRequired):
+			sendError(w, http.StatusBadRequest, "Client is required")
+		case errors.Is(err, service.ErrWATemplateContentRequired):
+			sendError(w, http.StatusBadRequest, "Content is required")
+		case errors.Is(err, repository.ErrWATemplateNotFound):
+			sendError(w, http.StatusNotFound, "Template not found")
+		case errors.Is(err, repository.ErrClientNotFound):
+			sendError(w, http.StatusNotFound, "Client not found")
+		case errors.Is(err, service.ErrInvoiceNotFound):
+			sendError(w, http.StatusNotFound, "Invoice not found")
+		default:
+			log.Error().Err(err).Msg("Failed to preview wa template")
+			sendError(w, http.StatusInternalServerError, "Failed to preview template")
+		}
+		return
+	}
+
+	sendJSON(w, http.StatusOK, out)
+}
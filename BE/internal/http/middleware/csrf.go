@@ -194,6 +194,7 @@ func DefaultCSRFProtection() *CSRFProtection {
 		"/api/v1/network/routers/*/disconnect",
 		// Public (non-JWT) endpoints authenticated by shared-secret headers
 		"/api/v1/radius/*",
+		"/api/v1/webhooks/*",
 		// Voucher package operations (Sync requires POST)
 		"/api/v1/voucher-packages/*",
 	}
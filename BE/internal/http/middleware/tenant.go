@@ -33,6 +33,7 @@ func TenantContext(repo *repository.TenantRepository) func(http.Handler) http.Ha
 			publicPaths := []string{
 				"/health", "/version", "/metrics",
 				"/api/v1/radius/",
+				"/api/v1/webhooks/",
 				"/api/v1/auth/",
 				"/api/v1/superadmin/",
 				"/api/v1/plans",
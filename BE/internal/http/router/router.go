@@ -22,6 +22,7 @@ import (
 	wagw "rrnet/internal/infra/wa_gateway"
 	"rrnet/internal/metrics"
 	"rrnet/internal/rbac"
+	"rrnet/internal/reporting"
 	"rrnet/internal/repository"
 	"rrnet/internal/service"
 	"rrnet/internal/version"
@@ -89,17 +90,32 @@ func New(deps Dependencies) http.Handler {
 	voucherService := service.NewVoucherService(voucherRepo, radiusRepo, routerRepo)
 
 	pppoeService := service.NewPPPoEService(pppoeRepo, routerRepo, profileRepo, clientRepo, deps.Config.Auth.JWTSecret)
-	clientService := service.NewClientService(clientRepo, servicePackageRepo, pppoeService, voucherService, featureResolver, limitResolver, deps.Config.Auth.JWTSecret)
+	invoiceRepo := repository.NewInvoiceRepository(deps.DB)
+	isolirLogRepo := repository.NewIsolirLogRepository(deps.DB)
+	isolirService := service.NewIsolirService(isolirLogRepo, clientRepo, invoiceRepo, routerRepo, pppoeRepo, profileRepo, servicePackageRepo, tenantRepo)
+	paymentRepo := repository.NewPaymentRepository(deps.DB)
+	billingService := service.NewBillingService(invoiceRepo, paymentRepo, clientRepo, servicePackageRepo)
+	billingService.SetDiscountRepository(discountRepo)
+	billingService.SetTenantRepository(tenantRepo)
+	billingService.SetAdjustmentRepository(repository.NewAdjustmentRepository(deps.DB))
+	billingService.SetBalanceRepository(repository.NewBalanceRepository(deps.DB))
+	billingService.SetSequenceRepository(repository.NewDocumentSequenceRepository(deps.DB))
+	billingService.SetRecurringChargeRepository(repository.NewRecurringChargeRepository(deps.DB))
+	billingService.SetIsolirService(isolirService)
+	clientService := service.NewClientService(clientRepo, servicePackageRepo, pppoeService, voucherService, featureResolver, limitResolver, isolirService, billingService, deps.Config.Auth.JWTSecret)
 	servicePackageService := service.NewServicePackageService(servicePackageRepo)
 	serviceSettingsService := service.NewServiceSettingsService(tenantRepo)
 	clientGroupService := service.NewClientGroupService(clientGroupRepo)
 	discountService := service.NewDiscountService(discountRepo)
 
 	// WhatsApp campaigns (async)
+	// Templates are rendered per recipient; the renderer resolves the open invoice and payment link
+	paymentRequestRepo := repository.NewPaymentRequestRepository(deps.DB)
+	waTemplateRenderer := service.NewWATemplateRenderer(invoiceRepo, paymentRequestRepo)
 	waCampaignRepo := repository.NewWACampaignRepository(deps.DB)
-	waCampaignService := service.NewWACampaignService(waCampaignRepo, clientRepo, asynqClient)
+	waCampaignService := service.NewWACampaignService(waCampaignRepo, clientRepo, tenantRepo, waTemplateRenderer, asynqClient)
 	waTemplateRepo := repository.NewWATemplateRepository(deps.DB)
-	waTemplateService := service.NewWATemplateService(waTemplateRepo)
+	waTemplateService := service.NewWATemplateService(waTemplateRepo, tenantRepo, clientRepo, invoiceRepo, waTemplateRenderer)
 	waLogRepo := repository.NewWALogRepository(deps.DB)
 	waLogService := service.NewWALogService(waLogRepo)
 
@@ -110,6 +126,7 @@ func New(deps Dependencies) http.Handler {
 	clientHandler := handler.NewClientHandler(clientService)
 	featureHandler := handler.NewFeatureHandler()
 	superAdminHandler := handler.NewSuperAdminHandler(tenantRepo, planRepo, addonRepo, planService, addonService)
+	jobHandler := handler.NewJobHandler(service.NewJobService(repository.NewJobRunRepository(deps.DB), asynqClient))
 	employeeHandler := handler.NewEmployeeHandler(authService, userRepo)
 	servicePackageHandler := handler.NewServicePackageHandler(servicePackageService)
 	serviceSettingsHandler := handler.NewServiceSettingsHandler(serviceSettingsService)
@@ -124,6 +141,46 @@ func New(deps Dependencies) http.Handler {
 	waGatewayClient := wagw.NewClient(deps.Config.WAGateway.URL, deps.Config.WAGateway.AdminToken)
 	waGatewayHandler := handler.NewWAGatewayHandler(waGatewayClient, waLogService)
 
+	// WhatsApp billing notifications raised by billing and isolir actions (sent by the worker)
+	notificationService := service.NewNotificationService(repository.NewNotificationRepository(deps.DB), tenantRepo, clientRepo, invoiceRepo, waTemplateRepo, waTemplateRenderer, featureResolver, waGatewayClient, waLogService, asynqClient)
+	billingService.SetNotifier(notificationService)
+	isolirService.SetNotifier(notificationService)
+	notificationHandler := handler.NewNotificationHandler(notificationService)
+
+	// Dunning timeline (per-tenant policy; executed by DunningScheduler)
+	dunningService := service.NewDunningService(tenantRepo, clientRepo, invoiceRepo, repository.NewDunningRepository(deps.DB), isolirService, billingService, featureResolver, waGatewayClient, waLogService, waTemplateRenderer)
+	dunningHandler := handler.NewDunningHandler(dunningService)
+
+	// Late fees on overdue invoices (per-tenant policy; charged by LateFeeScheduler)
+	lateFeeService := service.NewLateFeeService(tenantRepo, clientRepo, invoiceRepo, repository.NewLateFeeRepository(deps.DB), billingService)
+	lateFeeHandler := handler.NewLateFeeHandler(lateFeeService)
+
+	// Payment gateways (per-tenant merchant account; payments settled by signed webhooks)
+	paymentGatewayService := service.NewPaymentGatewayService(tenantRepo, clientRepo, invoiceRepo, paymentRequestRepo, billingService, deps.Config.PaymentGateway)
+	paymentGatewayHandler := handler.NewPaymentGatewayHandler(paymentGatewayService)
+
+	// Bank statement import and reconciliation of transfers with open invoices
+	bankReconciliationService := service.NewBankReconciliationService(repository.NewBankStatementRepository(deps.DB), invoiceRepo, billingService)
+	bankReconciliationHandler := handler.NewBankReconciliationHandler(bankReconciliationService)
+
+	// Bulk invoice generation for a period (dry-run preview; runs execute as Asynq tasks)
+	bulkInvoiceService := service.NewBulkInvoiceService(repository.NewBulkInvoiceRunRepository(deps.DB), clientRepo, invoiceRepo, billingService, asynqClient)
+	bulkInvoiceHandler := handler.NewBulkInvoiceHandler(bulkInvoiceService)
+
+	// Collector module: assignments, field visits, deposits confirmed by finance into payments
+	collectorService := service.NewCollectorService(repository.NewCollectorRepository(deps.DB), invoiceRepo, userRepo, billingService)
+	collectorHandler := handler.NewCollectorHandler(collectorService)
+
+	// Commissions of collecting staff: versioned rules and monthly statements
+	commissionService := service.NewCommissionService(repository.NewCommissionRepository(deps.DB), userRepo)
+	commissionHandler := handler.NewCommissionHandler(commissionService)
+
+	// Double-entry books: billing and collector actions post automatically
+	ledgerService := service.NewLedgerService(repository.NewLedgerRepository(deps.DB))
+	billingService.SetLedger(ledgerService)
+	collectorService.SetLedger(ledgerService)
+	ledgerHandler := handler.NewLedgerHandler(ledgerService)
+
 	// Technician module (repositories, service, handler)
 	taskRepo := repository.NewTaskRepository(deps.DB)
 	activityLogRepo := repository.NewActivityLogRepository(deps.DB)
@@ -150,6 +207,10 @@ func New(deps Dependencies) http.Handler {
 	requireMapsFeature := middleware.RequireAnyFeature(featureResolver, "odp_maps", "client_maps")
 	requireServicePackagesFeature := middleware.RequireFeature(featureResolver, "service_packages")
 	requireWAGatewayFeature := middleware.RequireFeature(featureResolver, "wa_gateway")
+	requireIsolirManualFeature := middleware.RequireFeature(featureResolver, "isolir_manual")
+	requirePaymentGatewayFeature := middleware.RequireFeature(featureResolver, "payment_gateway")
+	requirePaymentReportingFeature := middleware.RequireFeature(featureResolver, "payment_reporting_advanced")
+	requireRevenueDashboardFeature := middleware.RequireAnyFeature(featureResolver, "dashboard_pendapatan", "payment_reporting_advanced")
 
 	// Initialize Prometheus metrics
 	metrics.Init()
@@ -250,6 +311,8 @@ func New(deps Dependencies) http.Handler {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 		}
 	})))))
+	mux.Handle("/api/v1/wa-templates/variables", requireAuth(requireWAGatewayFeature(requireCapability(rbac.CapWAView)(methodHandler("GET", waTemplateHandler.Variables)))))
+	mux.Handle("/api/v1/wa-templates/preview", requireAuth(requireWAGatewayFeature(requireCapability(rbac.CapWAView)(methodHandler("POST", waTemplateHandler.Preview)))))
 	mux.Handle("/api/v1/wa-templates/", requireAuth(requireWAGatewayFeature(requireCapability(rbac.CapWAView)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		path := strings.TrimPrefix(r.URL.Path, "/api/v1/wa-templates/")
 		if path == "" {
@@ -273,6 +336,43 @@ func New(deps Dependencies) http.Handler {
 	mux.Handle("/api/v1/wa-logs", requireAuth(requireWAGatewayFeature(requireCapability(rbac.CapWAView)(methodHandler("GET", waLogHandler.List)))))
 	mux.Handle("/api/v1/wa-logs/", requireAuth(requireWAGatewayFeature(requireCapability(rbac.CapWAView)(methodHandler("GET", waLogHandler.List)))))
 
+	// ============================================
+	// WhatsApp billing notifications (settings, history, client opt-outs)
+	// ============================================
+	mux.Handle("/api/v1/wa-notifications", requireAuth(requireWAGatewayFeature(requireCapability(rbac.CapWAView)(methodHandler("GET", notificationHandler.List)))))
+	mux.Handle("/api/v1/wa-notifications/settings", requireAuth(requireWAGatewayFeature(requireCapability(rbac.CapWAView)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			notificationHandler.GetSettings(w, r)
+		case http.MethodPut:
+			requireCapability(rbac.CapTenantUpdate)(http.HandlerFunc(notificationHandler.UpdateSettings)).ServeHTTP(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})))))
+	mux.Handle("/api/v1/wa-notifications/opt-outs", requireAuth(requireWAGatewayFeature(requireCapability(rbac.CapWAView)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			notificationHandler.ListOptOuts(w, r)
+		case http.MethodPost:
+			requireCapability(rbac.CapClientUpdate)(http.HandlerFunc(notificationHandler.OptOut)).ServeHTTP(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})))))
+	mux.Handle("/api/v1/wa-notifications/opt-outs/", requireAuth(requireWAGatewayFeature(requireCapability(rbac.CapClientUpdate)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/api/v1/wa-notifications/opt-outs/")
+		if id == "" || strings.Contains(id, "/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method != http.MethodDelete {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		notificationHandler.RemoveOptOut(w, setPathParam(r, "id", id))
+	})))))
+
 	// ============================================
 	// Plan routes (Super Admin only for CRUD, tenant admin can view via /api/v1/my/plan)
 	// ============================================
@@ -382,12 +482,10 @@ func New(deps Dependencies) http.Handler {
 	})
 
 	// ============================================
-	// Billing service initialization (needed for client routes)
+	// Billing handler initialization (needed for client routes)
 	// ============================================
-	invoiceRepo := repository.NewInvoiceRepository(deps.DB)
-	paymentRepo := repository.NewPaymentRepository(deps.DB)
-	billingService := service.NewBillingService(invoiceRepo, paymentRepo, clientRepo, servicePackageRepo)
 	billingHandler := handler.NewBillingHandler(billingService)
+	isolirHandler := handler.NewIsolirHandler(isolirService)
 
 	tempoTemplateRepo := repository.NewBillingTempoTemplateRepository(deps.DB)
 	tempoTemplateService := service.NewBillingTempoTemplateService(tempoTemplateRepo)
@@ -430,6 +528,93 @@ func New(deps Dependencies) http.Handler {
 			return
 		}
 
+		// Client-level payment allocated across invoices: /api/v1/clients/{id}/payments
+		if len(parts) == 2 && parts[1] == "payments" {
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			requireCapability(rbac.CapBillingCollect)(http.HandlerFunc(billingHandler.RecordClientPayment)).ServeHTTP(w, r)
+			return
+		}
+
+		// Client balance: /api/v1/clients/{id}/balance, /api/v1/clients/{id}/balance/refund
+		if len(parts) >= 2 && parts[1] == "balance" {
+			switch {
+			case len(parts) == 2 && r.Method == http.MethodGet:
+				requireCapability(rbac.CapBillingView)(http.HandlerFunc(billingHandler.GetClientBalance)).ServeHTTP(w, r)
+			case len(parts) == 3 && parts[2] == "refund" && r.Method == http.MethodPost:
+				requireCapability(rbac.CapBillingConfirm)(http.HandlerFunc(billingHandler.RefundClientBalance)).ServeHTTP(w, r)
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+			return
+		}
+		if len(parts) == 2 && parts[1] == "billing-summary" {
+			if r.Method != http.MethodGet {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			requireCapability(rbac.CapBillingView)(http.HandlerFunc(billingHandler.GetClientBillingSummary)).ServeHTTP(w, r)
+			return
+		}
+
+		// Prorated adjustments: /api/v1/clients/{id}/adjustments
+		if len(parts) == 2 && parts[1] == "adjustments" {
+			if r.Method != http.MethodGet {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			requireCapability(rbac.CapBillingView)(http.HandlerFunc(billingHandler.ListClientAdjustments)).ServeHTTP(w, r)
+			return
+		}
+
+		// Recurring extras: /api/v1/clients/{id}/charges, /api/v1/clients/{id}/charges/{charge_id}
+		if len(parts) >= 2 && parts[1] == "charges" {
+			switch {
+			case len(parts) == 2 && r.Method == http.MethodGet:
+				requireCapability(rbac.CapBillingView)(http.HandlerFunc(billingHandler.ListClientCharges)).ServeHTTP(w, r)
+			case len(parts) == 2 && r.Method == http.MethodPost:
+				requireCapability(rbac.CapBillingUpdate)(http.HandlerFunc(billingHandler.CreateClientCharge)).ServeHTTP(w, r)
+			case len(parts) == 3 && r.Method == http.MethodPut:
+				r = setPathParam(r, "charge_id", parts[2])
+				requireCapability(rbac.CapBillingUpdate)(http.HandlerFunc(billingHandler.UpdateClientCharge)).ServeHTTP(w, r)
+			case len(parts) == 3 && r.Method == http.MethodDelete:
+				r = setPathParam(r, "charge_id", parts[2])
+				requireCapability(rbac.CapBillingUpdate)(http.HandlerFunc(billingHandler.DeleteClientCharge)).ServeHTTP(w, r)
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		// Prepaid billing: /api/v1/clients/{id}/prepaid/invoice, /api/v1/clients/{id}/prepaid/active-until
+		if len(parts) == 3 && parts[1] == "prepaid" {
+			switch {
+			case parts[2] == "invoice" && r.Method == http.MethodPost:
+				requireCapability(rbac.CapBillingCreate)(http.HandlerFunc(billingHandler.CreatePrepaidInvoice)).ServeHTTP(w, r)
+			case parts[2] == "active-until" && r.Method == http.MethodPut:
+				requireCapability(rbac.CapBillingUpdate)(http.HandlerFunc(billingHandler.SetClientActiveUntil)).ServeHTTP(w, r)
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		// Manual isolir: /api/v1/clients/{id}/isolate, /api/v1/clients/{id}/reactivate
+		if len(parts) == 2 && (parts[1] == "isolate" || parts[1] == "reactivate") {
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			action := isolirHandler.Isolate
+			if parts[1] == "reactivate" {
+				action = isolirHandler.Reactivate
+			}
+			requireCapability(rbac.CapClientSuspend)(requireIsolirManualFeature(http.HandlerFunc(action))).ServeHTTP(w, r)
+			return
+		}
+
 		// Check for status change: /api/v1/clients/{id}/status
 		if len(parts) == 2 && parts[1] == "status" {
 			if r.Method == http.MethodPatch {
@@ -453,6 +638,37 @@ func New(deps Dependencies) http.Handler {
 		}
 	})))
 
+	// ============================================
+	// Isolir routes (history + auto-isolir settings)
+	// ============================================
+	mux.Handle("/api/v1/isolir/logs", requireAuth(requireCapability(rbac.CapClientView)(methodHandler("GET", isolirHandler.ListLogs))))
+	mux.Handle("/api/v1/isolir/settings", requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			requireCapability(rbac.CapClientView)(http.HandlerFunc(isolirHandler.GetSettings)).ServeHTTP(w, r)
+		case http.MethodPut:
+			requireCapability(rbac.CapTenantUpdate)(http.HandlerFunc(isolirHandler.UpdateSettings)).ServeHTTP(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// ============================================
+	// Dunning routes (policy, dry-run preview, history)
+	// ============================================
+	mux.Handle("/api/v1/dunning/policy", requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			requireCapability(rbac.CapBillingView)(http.HandlerFunc(dunningHandler.GetPolicy)).ServeHTTP(w, r)
+		case http.MethodPut:
+			requireCapability(rbac.CapTenantUpdate)(http.HandlerFunc(dunningHandler.UpdatePolicy)).ServeHTTP(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})))
+	mux.Handle("/api/v1/dunning/preview", requireAuth(requireCapability(rbac.CapBillingView)(methodHandler("GET", dunningHandler.Preview))))
+	mux.Handle("/api/v1/dunning/runs", requireAuth(requireCapability(rbac.CapBillingView)(methodHandler("GET", dunningHandler.ListRuns))))
+
 	// ============================================
 	// Dashboard routes (Consolidated)
 	// ============================================
@@ -680,6 +896,34 @@ func New(deps Dependencies) http.Handler {
 	// ============================================
 	// Super Admin routes (Protected, super admin only)
 	// ============================================
+	mux.Handle("/api/v1/superadmin/jobs", requireSuperAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		jobHandler.ListJobs(w, r)
+	})))
+	mux.Handle("/api/v1/superadmin/jobs/", requireSuperAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/superadmin/jobs/"), "/")
+		switch {
+		// /api/v1/superadmin/jobs/runs
+		case len(parts) == 1 && parts[0] == "runs":
+			if r.Method != http.MethodGet {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			jobHandler.ListRuns(w, r)
+		// /api/v1/superadmin/jobs/{name}/run
+		case len(parts) == 2 && parts[0] != "" && parts[1] == "run":
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			jobHandler.TriggerJob(w, setPathParam(r, "name", parts[0]))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})))
 	mux.Handle("/api/v1/superadmin/tenants", requireSuperAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
@@ -1332,6 +1576,44 @@ func New(deps Dependencies) http.Handler {
 			}
 			return
 		}
+		if len(parts) == 2 && parts[1] == "pdf" {
+			if r.Method != http.MethodGet {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			requireCapability(rbac.CapBillingView)(http.HandlerFunc(billingHandler.GetInvoicePDF)).ServeHTTP(w, r)
+			return
+		}
+		if len(parts) == 2 && parts[1] == "credit-notes" {
+			switch r.Method {
+			case http.MethodGet:
+				requireCapability(rbac.CapBillingView)(http.HandlerFunc(billingHandler.ListCreditNotes)).ServeHTTP(w, r)
+			case http.MethodPost:
+				requireCapability(rbac.CapBillingConfirm)(http.HandlerFunc(billingHandler.IssueCreditNote)).ServeHTTP(w, r)
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+			return
+		}
+		if len(parts) == 2 && parts[1] == "payment-requests" {
+			switch r.Method {
+			case http.MethodGet:
+				requirePaymentGatewayFeature(requireCapability(rbac.CapBillingView)(http.HandlerFunc(paymentGatewayHandler.ListPaymentRequests))).ServeHTTP(w, r)
+			case http.MethodPost:
+				requirePaymentGatewayFeature(requireCapability(rbac.CapBillingCollect)(http.HandlerFunc(paymentGatewayHandler.CreatePaymentRequest))).ServeHTTP(w, r)
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+			return
+		}
+		if len(parts) == 2 && parts[1] == "late-fees" {
+			if r.Method != http.MethodGet {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			requireCapability(rbac.CapBillingView)(http.HandlerFunc(lateFeeHandler.ListInvoiceLateFees)).ServeHTTP(w, r)
+			return
+		}
 		if len(parts) == 2 && parts[1] == "cancel" {
 			if r.Method == http.MethodPost {
 				billingHandler.CancelInvoice(w, r)
@@ -1362,11 +1644,26 @@ func New(deps Dependencies) http.Handler {
 	})))
 	mux.Handle("/api/v1/billing/payments/", requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		path := strings.TrimPrefix(r.URL.Path, "/api/v1/billing/payments/")
-		if path == "" {
+		parts := strings.Split(path, "/")
+		if parts[0] == "" {
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
-		r = setPathParam(r, "id", path)
+		r = setPathParam(r, "id", parts[0])
+
+		if len(parts) == 2 && parts[1] == "receipt.pdf" {
+			if r.Method != http.MethodGet {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			requireCapability(rbac.CapBillingView)(http.HandlerFunc(billingHandler.GetPaymentReceiptPDF)).ServeHTTP(w, r)
+			return
+		}
+		if len(parts) > 1 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
 		switch r.Method {
 		case http.MethodGet:
 			billingHandler.GetPayment(w, r)
@@ -1375,12 +1672,452 @@ func New(deps Dependencies) http.Handler {
 		}
 	})))
 
+	// Invoice/receipt print template (logo, footer, bank accounts)
+	mux.Handle("/api/v1/billing/invoice-template", requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			requireCapability(rbac.CapBillingView)(http.HandlerFunc(billingHandler.GetInvoiceTemplate)).ServeHTTP(w, r)
+		case http.MethodPut:
+			requireCapability(rbac.CapTenantUpdate)(http.HandlerFunc(billingHandler.UpdateInvoiceTemplate)).ServeHTTP(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Tenant tax profile (PPN rate, inclusive pricing, NPWP, exemptions)
+	mux.Handle("/api/v1/billing/tax-settings", requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			requireCapability(rbac.CapBillingView)(http.HandlerFunc(billingHandler.GetTaxSettings)).ServeHTTP(w, r)
+		case http.MethodPut:
+			requireCapability(rbac.CapTenantUpdate)(http.HandlerFunc(billingHandler.UpdateTaxSettings)).ServeHTTP(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Invoice, credit note and receipt numbering schemes
+	mux.Handle("/api/v1/billing/numbering-settings", requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			requireCapability(rbac.CapBillingView)(http.HandlerFunc(billingHandler.GetNumberingSettings)).ServeHTTP(w, r)
+		case http.MethodPut:
+			requireCapability(rbac.CapTenantUpdate)(http.HandlerFunc(billingHandler.UpdateNumberingSettings)).ServeHTTP(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})))
+	mux.Handle("/api/v1/billing/numbering-settings/preview", requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		requireCapability(rbac.CapBillingView)(http.HandlerFunc(billingHandler.PreviewNumbering)).ServeHTTP(w, r)
+	})))
+
+	// Unique transfer code ("kode unik") settings
+	mux.Handle("/api/v1/billing/unique-code-settings", requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			requireCapability(rbac.CapBillingView)(http.HandlerFunc(billingHandler.GetUniqueCodeSettings)).ServeHTTP(w, r)
+		case http.MethodPut:
+			requireCapability(rbac.CapTenantUpdate)(http.HandlerFunc(billingHandler.UpdateUniqueCodeSettings)).ServeHTTP(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Payment gateway settings, offline payment simulation (mock provider) and public webhooks
+	mux.Handle("/api/v1/billing/payment-gateway", requireAuth(requirePaymentGatewayFeature(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			requireCapability(rbac.CapBillingView)(http.HandlerFunc(paymentGatewayHandler.GetSettings)).ServeHTTP(w, r)
+		case http.MethodPut:
+			requireCapability(rbac.CapTenantUpdate)(http.HandlerFunc(paymentGatewayHandler.UpdateSettings)).ServeHTTP(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/v1/billing/payment-requests/", requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/billing/payment-requests/"), "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] != "simulate" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		r = setPathParam(r, "id", parts[0])
+		requirePaymentGatewayFeature(requireCapability(rbac.CapBillingCollect)(http.HandlerFunc(paymentGatewayHandler.SimulatePayment))).ServeHTTP(w, r)
+	})))
+	mux.HandleFunc("/api/v1/webhooks/payments/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/webhooks/payments/"), "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		r = setPathParam(r, "provider", parts[0])
+		r = setPathParam(r, "tenant_id", parts[1])
+		paymentGatewayHandler.Webhook(w, r)
+	})
+
+	// Late fee policy and waivers
+	mux.Handle("/api/v1/billing/late-fee-policy", requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			requireCapability(rbac.CapBillingView)(http.HandlerFunc(lateFeeHandler.GetPolicy)).ServeHTTP(w, r)
+		case http.MethodPut:
+			requireCapability(rbac.CapTenantUpdate)(http.HandlerFunc(lateFeeHandler.UpdatePolicy)).ServeHTTP(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})))
+	mux.Handle("/api/v1/billing/late-fees/", requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/billing/late-fees/"), "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] != "waive" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		r = setPathParam(r, "id", parts[0])
+		requireCapability(rbac.CapBillingUpdate)(http.HandlerFunc(lateFeeHandler.Waive)).ServeHTTP(w, r)
+	})))
+
+	// Bulk invoice runs: preview, start, progress, per-client outcome and undo
+	mux.Handle("/api/v1/billing/bulk-invoices", requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			requireCapability(rbac.CapBillingView)(http.HandlerFunc(bulkInvoiceHandler.List)).ServeHTTP(w, r)
+		case http.MethodPost:
+			requireCapability(rbac.CapBillingCreate)(http.HandlerFunc(bulkInvoiceHandler.Start)).ServeHTTP(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})))
+	mux.Handle("/api/v1/billing/bulk-invoices/preview", requireAuth(requireCapability(rbac.CapBillingView)(methodHandler("POST", bulkInvoiceHandler.Preview))))
+	mux.Handle("/api/v1/billing/bulk-invoices/", requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/billing/bulk-invoices/"), "/")
+		if parts[0] == "" || len(parts) > 2 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		r = setPathParam(r, "id", parts[0])
+		switch {
+		case len(parts) == 1 && r.Method == http.MethodGet:
+			requireCapability(rbac.CapBillingView)(http.HandlerFunc(bulkInvoiceHandler.Get)).ServeHTTP(w, r)
+		case len(parts) == 2 && parts[1] == "items" && r.Method == http.MethodGet:
+			requireCapability(rbac.CapBillingView)(http.HandlerFunc(bulkInvoiceHandler.ListItems)).ServeHTTP(w, r)
+		case len(parts) == 2 && parts[1] == "undo" && r.Method == http.MethodPost:
+			requireCapability(rbac.CapBillingConfirm)(http.HandlerFunc(bulkInvoiceHandler.Undo)).ServeHTTP(w, r)
+		case len(parts) == 1 || parts[1] == "items" || parts[1] == "undo":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})))
+
+	// Collector module. Office endpoints see every assignment of the tenant and are closed to the
+	// collector role; /me endpoints only expose the caller's own assignments and deposits.
+	requireCollectorOffice := middleware.RequireRole(rbac.RoleOwner, rbac.RoleAdmin, rbac.RoleFinance)
+	mux.Handle("/api/v1/collector/assignments", requireAuth(requireCollectorOffice(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			requireCapability(rbac.CapCollectorView)(http.HandlerFunc(collectorHandler.ListAssignments)).ServeHTTP(w, r)
+		case http.MethodPost:
+			requireCapability(rbac.CapCollectorManage)(http.HandlerFunc(collectorHandler.Assign)).ServeHTTP(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/v1/collector/assignments/", requireAuth(requireCollectorOffice(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/collector/assignments/"), "/")
+		if parts[0] == "" || len(parts) > 2 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		r = setPathParam(r, "id", parts[0])
+		switch {
+		case len(parts) == 1 && r.Method == http.MethodGet:
+			requireCapability(rbac.CapCollectorView)(http.HandlerFunc(collectorHandler.GetAssignment)).ServeHTTP(w, r)
+		case len(parts) == 2 && parts[1] == "visits" && r.Method == http.MethodGet:
+			requireCapability(rbac.CapCollectorView)(http.HandlerFunc(collectorHandler.ListVisits)).ServeHTTP(w, r)
+		case len(parts) == 2 && parts[1] == "cancel" && r.Method == http.MethodPost:
+			requireCapability(rbac.CapCollectorManage)(http.HandlerFunc(collectorHandler.CancelAssignment)).ServeHTTP(w, r)
+		case len(parts) == 1 || parts[1] == "visits" || parts[1] == "cancel":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))))
+	mux.Handle("/api/v1/collector/deposits", requireAuth(requireCollectorOffice(requireCapability(rbac.CapCollectorView)(methodHandler("GET", collectorHandler.ListDeposits)))))
+	mux.Handle("/api/v1/collector/deposits/", requireAuth(requireCollectorOffice(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/collector/deposits/"), "/")
+		if parts[0] == "" || len(parts) > 2 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		r = setPathParam(r, "id", parts[0])
+		switch {
+		case len(parts) == 1 && r.Method == http.MethodGet:
+			requireCapability(rbac.CapCollectorView)(http.HandlerFunc(collectorHandler.GetDeposit)).ServeHTTP(w, r)
+		case len(parts) == 2 && parts[1] == "confirm" && r.Method == http.MethodPost:
+			requireCapability(rbac.CapBillingConfirm)(http.HandlerFunc(collectorHandler.ConfirmDeposit)).ServeHTTP(w, r)
+		case len(parts) == 2 && parts[1] == "reject" && r.Method == http.MethodPost:
+			requireCapability(rbac.CapBillingConfirm)(http.HandlerFunc(collectorHandler.RejectDeposit)).ServeHTTP(w, r)
+		case len(parts) == 1 || parts[1] == "confirm" || parts[1] == "reject":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))))
+	mux.Handle("/api/v1/collector/routes/", requireAuth(requireCollectorOffice(requireCapability(rbac.CapCollectorView)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/api/v1/collector/routes/")
+		if id == "" || strings.Contains(id, "/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		collectorHandler.CollectorRoute(w, setPathParam(r, "id", id))
+	})))))
+	// Route of the day, planned again on each request from the collector's last logged visit
+	mux.Handle("/api/v1/collector/me/route", requireAuth(requireCapability(rbac.CapCollectorManage)(methodHandler("GET", collectorHandler.MyRoute))))
+	mux.Handle("/api/v1/collector/me/assignments", requireAuth(requireCapability(rbac.CapCollectorManage)(methodHandler("GET", collectorHandler.MyAssignments))))
+	mux.Handle("/api/v1/collector/me/assignments/", requireAuth(requireCapability(rbac.CapCollectorManage)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/collector/me/assignments/"), "/")
+		if parts[0] == "" || len(parts) > 2 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		r = setPathParam(r, "id", parts[0])
+		switch {
+		case len(parts) == 1 && r.Method == http.MethodGet:
+			collectorHandler.MyAssignment(w, r)
+		case len(parts) == 2 && parts[1] == "visits" && r.Method == http.MethodGet:
+			collectorHandler.MyVisits(w, r)
+		case len(parts) == 2 && parts[1] == "visits" && r.Method == http.MethodPost:
+			collectorHandler.LogVisit(w, r)
+		case len(parts) == 1 || parts[1] == "visits":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))))
+	mux.Handle("/api/v1/collector/me/deposits", requireAuth(requireCapability(rbac.CapCollectorManage)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			collectorHandler.MyDeposits(w, r)
+		case http.MethodPost:
+			collectorHandler.ReportDeposit(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/v1/collector/me/deposits/", requireAuth(requireCapability(rbac.CapCollectorManage)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/api/v1/collector/me/deposits/")
+		if id == "" || strings.Contains(id, "/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		collectorHandler.MyDeposit(w, setPathParam(r, "id", id))
+	}))))
+
+	// Commissions. Rules and statements of every user are for the office; /me endpoints only return
+	// the caller's own statements.
+	requireCommissionOffice := middleware.RequireRole(rbac.RoleOwner, rbac.RoleAdmin, rbac.RoleFinance)
+	mux.Handle("/api/v1/commissions/rules", requireAuth(requireCommissionOffice(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			requireCapability(rbac.CapBillingView)(http.HandlerFunc(commissionHandler.ListRules)).ServeHTTP(w, r)
+		case http.MethodPost:
+			requireCapability(rbac.CapBillingUpdate)(http.HandlerFunc(commissionHandler.CreateRule)).ServeHTTP(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/v1/commissions/rules/", requireAuth(requireCommissionOffice(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/api/v1/commissions/rules/")
+		if key == "" || strings.Contains(key, "/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		r = setPathParam(r, "id", key)
+		switch r.Method {
+		case http.MethodGet:
+			requireCapability(rbac.CapBillingView)(http.HandlerFunc(commissionHandler.RuleHistory)).ServeHTTP(w, r)
+		case http.MethodPut:
+			requireCapability(rbac.CapBillingUpdate)(http.HandlerFunc(commissionHandler.UpdateRule)).ServeHTTP(w, r)
+		case http.MethodDelete:
+			requireCapability(rbac.CapBillingUpdate)(http.HandlerFunc(commissionHandler.RetireRule)).ServeHTTP(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/v1/commissions/statements", requireAuth(requireCommissionOffice(requireCapability(rbac.CapBillingView)(methodHandler("GET", commissionHandler.ListStatements)))))
+	mux.Handle("/api/v1/commissions/statements/", requireAuth(requireCommissionOffice(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/commissions/statements/"), "/")
+		if parts[0] == "" || len(parts) > 2 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if len(parts) == 1 && parts[0] == "generate" {
+			requireCapability(rbac.CapBillingUpdate)(methodHandler("POST", commissionHandler.Generate)).ServeHTTP(w, r)
+			return
+		}
+		if len(parts) == 1 && parts[0] == "export" {
+			requireCapability(rbac.CapBillingView)(methodHandler("GET", commissionHandler.ExportStatements)).ServeHTTP(w, r)
+			return
+		}
+		r = setPathParam(r, "id", parts[0])
+		switch {
+		case len(parts) == 1 && r.Method == http.MethodGet:
+			requireCapability(rbac.CapBillingView)(http.HandlerFunc(commissionHandler.GetStatement)).ServeHTTP(w, r)
+		case len(parts) == 2 && parts[1] == "approve" && r.Method == http.MethodPost:
+			requireCapability(rbac.CapBillingConfirm)(http.HandlerFunc(commissionHandler.Approve)).ServeHTTP(w, r)
+		case len(parts) == 2 && parts[1] == "pay" && r.Method == http.MethodPost:
+			requireCapability(rbac.CapBillingConfirm)(http.HandlerFunc(commissionHandler.MarkPaid)).ServeHTTP(w, r)
+		case len(parts) == 1 || parts[1] == "approve" || parts[1] == "pay":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))))
+	mux.Handle("/api/v1/commissions/me/statements", requireAuth(methodHandler("GET", commissionHandler.MyStatements)))
+	mux.Handle("/api/v1/commissions/me/statements/", requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/api/v1/commissions/me/statements/")
+		if id == "" || strings.Contains(id, "/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		commissionHandler.MyStatement(w, setPathParam(r, "id", id))
+	})))
+
+	// Ledger: chart of accounts, journal (manual entries for opening balances and corrections),
+	// trial balance and general ledger; finance office only
+	requireFinanceOffice := middleware.RequireRole(rbac.RoleOwner, rbac.RoleAdmin, rbac.RoleFinance)
+	mux.Handle("/api/v1/ledger/accounts", requireAuth(requireFinanceOffice(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			requireCapability(rbac.CapBillingView)(http.HandlerFunc(ledgerHandler.ListAccounts)).ServeHTTP(w, r)
+		case http.MethodPost:
+			requireCapability(rbac.CapBillingUpdate)(http.HandlerFunc(ledgerHandler.CreateAccount)).ServeHTTP(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/v1/ledger/entries", requireAuth(requireFinanceOffice(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			requireCapability(rbac.CapBillingView)(http.HandlerFunc(ledgerHandler.ListEntries)).ServeHTTP(w, r)
+		case http.MethodPost:
+			requireCapability(rbac.CapBillingConfirm)(http.HandlerFunc(ledgerHandler.CreateEntry)).ServeHTTP(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/v1/ledger/entries/", requireAuth(requireFinanceOffice(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/api/v1/ledger/entries/")
+		if id == "" || strings.Contains(id, "/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		r = setPathParam(r, "id", id)
+		requireCapability(rbac.CapBillingView)(methodHandler("GET", ledgerHandler.GetEntry)).ServeHTTP(w, r)
+	}))))
+	mux.Handle("/api/v1/ledger/trial-balance", requireAuth(requireFinanceOffice(requireCapability(rbac.CapBillingView)(methodHandler("GET", ledgerHandler.TrialBalance)))))
+	mux.Handle("/api/v1/ledger/general-ledger", requireAuth(requireFinanceOffice(requireCapability(rbac.CapBillingView)(methodHandler("GET", ledgerHandler.GeneralLedger)))))
+
+	// Bank statements: import, matching buckets and confirmation of transfers
+	mux.Handle("/api/v1/billing/bank-statements", requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			requireCapability(rbac.CapBillingView)(http.HandlerFunc(bankReconciliationHandler.List)).ServeHTTP(w, r)
+		case http.MethodPost:
+			requireCapability(rbac.CapBillingConfirm)(http.HandlerFunc(bankReconciliationHandler.Import)).ServeHTTP(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})))
+	mux.Handle("/api/v1/billing/bank-statements/", requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/billing/bank-statements/"), "/")
+		if parts[0] == "" || len(parts) > 2 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		r = setPathParam(r, "id", parts[0])
+		if len(parts) == 1 {
+			if r.Method != http.MethodGet {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			requireCapability(rbac.CapBillingView)(http.HandlerFunc(bankReconciliationHandler.Get)).ServeHTTP(w, r)
+			return
+		}
+		var h http.HandlerFunc
+		switch parts[1] {
+		case "rematch":
+			h = bankReconciliationHandler.Rematch
+		case "confirm-auto":
+			h = bankReconciliationHandler.ConfirmAutoMatched
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		requireCapability(rbac.CapBillingConfirm)(h).ServeHTTP(w, r)
+	})))
+	mux.Handle("/api/v1/billing/bank-statement-lines/", requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/billing/bank-statement-lines/"), "/")
+		if len(parts) != 2 || parts[0] == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		r = setPathParam(r, "id", parts[0])
+		switch {
+		case parts[1] == "confirm" && r.Method == http.MethodPost:
+			requireCapability(rbac.CapBillingConfirm)(http.HandlerFunc(bankReconciliationHandler.ConfirmLine)).ServeHTTP(w, r)
+		case parts[1] == "ignore" && (r.Method == http.MethodPost || r.Method == http.MethodDelete):
+			requireCapability(rbac.CapBillingConfirm)(http.HandlerFunc(bankReconciliationHandler.IgnoreLine)).ServeHTTP(w, r)
+		case parts[1] == "confirm" || parts[1] == "ignore":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})))
+
 	// Billing Summary
 	mux.Handle("/api/v1/billing/summary", requireAuth(methodHandler("GET", billingHandler.GetBillingSummary)))
 
 	// Payment Matrix (12-month view)
 	mux.Handle("/api/v1/billing/payment-matrix", requireAuth(methodHandler("GET", billingHandler.GetPaymentMatrix)))
 
+	// Billing reports (JSON, or CSV/XLSX export with ?format=)
+	reportHandler := handler.NewReportHandler(reporting.NewService(invoiceRepo, paymentRepo))
+	mux.Handle("/api/v1/reports/ar-aging", requireAuth(requirePaymentReportingFeature(requireCapability(rbac.CapReportBill)(methodHandler("GET", reportHandler.ARAging)))))
+	mux.Handle("/api/v1/reports/revenue", requireAuth(requirePaymentReportingFeature(requireCapability(rbac.CapReportBill)(methodHandler("GET", reportHandler.Revenue)))))
+	mux.Handle("/api/v1/reports/monthly-revenue", requireAuth(requireRevenueDashboardFeature(requireCapability(rbac.CapReportBill)(methodHandler("GET", reportHandler.MonthlyRevenue)))))
+
 	// Tempo Templates (tenant-scoped, RBAC: billing.view/list, billing.update for mutations)
 	mux.Handle("/api/v1/billing/tempo-templates", requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
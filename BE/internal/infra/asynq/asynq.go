@@ -1,6 +1,8 @@
 package asynq
 
 import (
+	"time"
+
 	"github.com/hibiken/asynq"
 	"github.com/rs/zerolog/log"
 )
@@ -52,9 +54,15 @@ func NewServer(redisAddr, redisPassword string, redisDB int) *asynq.Server {
 	return srv
 }
 
-// TODO: Worker handlers will be registered in future modules
-// Example:
-// mux := asynq.NewServeMux()
-// mux.HandleFunc("billing:invoice", handleInvoiceTask)
-// srv.Run(mux)
-
+// NewScheduler creates an Asynq scheduler that enqueues periodic tasks. Cron specs are evaluated
+// in server local time.
+func NewScheduler(redisAddr, redisPassword string, redisDB int) *asynq.Scheduler {
+	return asynq.NewScheduler(
+		asynq.RedisClientOpt{
+			Addr:     redisAddr,
+			Password: redisPassword,
+			DB:       redisDB,
+		},
+		&asynq.SchedulerOpts{Location: time.Local},
+	)
+}
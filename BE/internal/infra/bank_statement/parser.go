@@ -0,0 +1,420 @@
+// Package bank_statement parses account mutation exports (CSV) of Indonesian banks. Columns are
+// found by their header names, so the generic CSV layout and the KlikBCA, Livin' by Mandiri, BRImo /
+// Internet Banking BRI and BNIDirect exports are read by the same parser.
+package bank_statement
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Banks recognised in statement headers
+const (
+	BankBCA     = "bca"
+	BankMandiri = "mandiri"
+	BankBRI     = "bri"
+	BankBNI     = "bni"
+	BankOther   = "other"
+)
+
+var (
+	ErrEmptyStatement = errors.New("statement file is empty")
+	ErrNoHeader       = errors.New("could not find the transaction columns (date, description and amount) in the statement")
+)
+
+// Transaction is one mutation of the statement
+type Transaction struct {
+	Row         int // 1-based line in the file
+	Date        time.Time
+	Description string
+	Reference   string
+	Amount      int64 // always positive
+	Credit      bool  // money received
+	Balance     *int64
+}
+
+// Statement is a parsed mutation export
+type Statement struct {
+	Bank          string
+	AccountNumber string
+	PeriodStart   *time.Time
+	PeriodEnd     *time.Time
+	Transactions  []Transaction
+	SkippedRows   int // rows below the header that are not transactions (totals, pending mutations)
+}
+
+// Column header names per field, lower case without punctuation
+var headerAliases = map[string][]string{
+	"date":        {"tanggal", "tanggal transaksi", "tgl", "tgl transaksi", "tanggal mutasi", "date", "transaction date", "trans date", "post date", "posting date", "tanggal posting"},
+	"description": {"keterangan", "keterangan transaksi", "uraian", "uraian transaksi", "deskripsi", "description", "transaction description", "remark", "remarks", "berita"},
+	"credit":      {"kredit", "credit", "cr", "mutasi kredit", "jumlah kredit", "credit amount", "incoming"},
+	"debit":       {"debit", "debet", "db", "mutasi debet", "mutasi debit", "jumlah debet", "debit amount", "outgoing"},
+	"amount":      {"jumlah", "amount", "nominal", "mutasi", "nilai", "transaction amount"},
+	"type":        {"cr/db", "db/cr", "d/k", "k/d", "dk", "d/c", "c/d", "type", "jenis", "tipe"},
+	"balance":     {"saldo", "balance", "saldo akhir", "ending balance", "running balance"},
+	"reference":   {"reference", "reference no", "ref", "ref no", "referensi", "no referensi", "nomor referensi", "journal no", "no jurnal"},
+}
+
+type columns struct {
+	date, description, credit, debit, amount, kind, balance, reference int
+}
+
+func normalizeHeader(s string) string {
+	s = strings.ToLower(strings.TrimSpace(strings.Trim(s, "'\"\ufeff")))
+	if i := strings.Index(s, "("); i > 0 {
+		s = strings.TrimSpace(s[:i]) // "jumlah (idr)"
+	}
+	s = strings.NewReplacer(".", "", ":", "", "_", " ").Replace(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// findColumns maps header cells to fields. ok is false when the row is not a transaction header.
+func findColumns(record []string) (columns, bool) {
+	c := columns{-1, -1, -1, -1, -1, -1, -1, -1}
+	set := func(field string, idx int) {
+		target := map[string]*int{
+			"date": &c.date, "description": &c.description, "credit": &c.credit, "debit": &c.debit,
+			"amount": &c.amount, "type": &c.kind, "balance": &c.balance, "reference": &c.reference,
+		}[field]
+		if *target < 0 {
+			*target = idx
+		}
+	}
+	for i, cell := range record {
+		h := normalizeHeader(cell)
+		if h == "" {
+			continue
+		}
+		for field, aliases := range headerAliases {
+			for _, a := range aliases {
+				if h == a {
+					set(field, i)
+				}
+			}
+		}
+	}
+	// KlikBCA puts CR/DB in an unnamed column right after "Jumlah"
+	if c.amount >= 0 && c.kind < 0 && c.amount+1 < len(record) && normalizeHeader(record[c.amount+1]) == "" {
+		c.kind = c.amount + 1
+	}
+	ok := c.date >= 0 && c.description >= 0 && (c.credit >= 0 || c.amount >= 0)
+	return c, ok
+}
+
+// detectDelimiter picks the separator used most on the first lines (Excel in Indonesian locale saves
+// CSV with semicolons)
+func detectDelimiter(data []byte) rune {
+	best, bestCount := ',', 0
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	counts := map[rune]int{}
+	for n := 0; n < 20 && sc.Scan(); n++ {
+		line := sc.Text()
+		for _, d := range []rune{',', ';', '\t', '|'} {
+			counts[d] += strings.Count(line, string(d))
+		}
+	}
+	for _, d := range []rune{',', ';', '\t', '|'} {
+		if counts[d] > bestCount {
+			best, bestCount = d, counts[d]
+		}
+	}
+	return best
+}
+
+// Parse reads a statement. bank may be empty to detect it from the file. now is used to complete
+// dates exported without a year (KlikBCA prints "dd/mm").
+func Parse(r io.Reader, bank string, now time.Time) (*Statement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, ErrEmptyStatement
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = detectDelimiter(data)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	st := &Statement{Bank: strings.ToLower(strings.TrimSpace(bank))}
+	header := -1
+	var cols columns
+	var preamble strings.Builder
+	for i, rec := range records {
+		if c, ok := findColumns(rec); ok {
+			header, cols = i, c
+			break
+		}
+		line := strings.Join(rec, " ")
+		preamble.WriteString(line + "\n")
+		readPreambleLine(st, rec)
+	}
+	if header < 0 {
+		return nil, ErrNoHeader
+	}
+	if st.Bank == "" {
+		st.Bank = detectBank(preamble.String() + strings.Join(records[header], " "))
+	}
+
+	yearRef := now
+	if st.PeriodEnd != nil {
+		yearRef = *st.PeriodEnd
+	}
+	for i := header + 1; i < len(records); i++ {
+		tx, ok := parseRow(records[i], cols, yearRef, now)
+		if !ok {
+			if !blankRecord(records[i]) {
+				st.SkippedRows++
+			}
+			continue
+		}
+		tx.Row = i + 1
+		st.Transactions = append(st.Transactions, tx)
+	}
+	return st, nil
+}
+
+func blankRecord(rec []string) bool {
+	for _, c := range rec {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func cell(rec []string, idx int) string {
+	if idx < 0 || idx >= len(rec) {
+		return ""
+	}
+	return strings.TrimSpace(strings.Trim(strings.TrimSpace(rec[idx]), "'"))
+}
+
+func parseRow(rec []string, c columns, yearRef, now time.Time) (Transaction, bool) {
+	date, ok := ParseDate(cell(rec, c.date), yearRef, now)
+	if !ok {
+		return Transaction{}, false
+	}
+	tx := Transaction{
+		Date:        date,
+		Description: strings.Join(strings.Fields(cell(rec, c.description)), " "),
+		Reference:   cell(rec, c.reference),
+	}
+
+	if c.credit >= 0 || c.debit >= 0 {
+		credit, _ := ParseAmount(cell(rec, c.credit))
+		debit, _ := ParseAmount(cell(rec, c.debit))
+		switch {
+		case credit != 0:
+			tx.Amount, tx.Credit = abs(credit), true
+		case debit != 0:
+			tx.Amount = abs(debit)
+		}
+	}
+	if tx.Amount == 0 && c.amount >= 0 {
+		raw := strings.ToUpper(cell(rec, c.amount))
+		kind := strings.ToUpper(cell(rec, c.kind))
+		// Some exports append the direction to the amount ("150,000.00 CR")
+		for _, suffix := range []string{"CR", "DB", "K", "D"} {
+			if strings.HasSuffix(raw, " "+suffix) {
+				raw, kind = strings.TrimSuffix(raw, " "+suffix), suffix
+				break
+			}
+		}
+		amount, ok := ParseAmount(raw)
+		if !ok || amount == 0 {
+			return Transaction{}, false
+		}
+		switch kind {
+		case "CR", "K", "C", "KREDIT", "CREDIT", "KR":
+			tx.Credit = true
+		case "DB", "D", "DEBIT", "DEBET":
+			tx.Credit = false
+		default:
+			tx.Credit = amount > 0
+		}
+		tx.Amount = abs(amount)
+	}
+	if tx.Amount == 0 {
+		return Transaction{}, false
+	}
+	if c.balance >= 0 {
+		if b, ok := ParseAmount(cell(rec, c.balance)); ok {
+			tx.Balance = &b
+		}
+	}
+	return tx, true
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// ParseAmount reads a rupiah amount in either notation ("1.500.000,00" or "1,500,000.00"). Cents
+// are dropped; a leading minus or surrounding parentheses make it negative.
+func ParseAmount(s string) (int64, bool) {
+	s = strings.TrimSpace(strings.ToUpper(s))
+	s = strings.NewReplacer("RP", "", "IDR", "", " ", "", " ", "").Replace(s)
+	if s == "" || s == "-" {
+		return 0, false
+	}
+	neg := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		neg, s = true, s[1:len(s)-1]
+	}
+	if strings.HasPrefix(s, "-") {
+		neg, s = true, s[1:]
+	} else if strings.HasSuffix(s, "-") {
+		neg, s = true, s[:len(s)-1]
+	}
+
+	dot, comma := strings.LastIndex(s, "."), strings.LastIndex(s, ",")
+	intPart := s
+	switch {
+	case dot >= 0 && comma >= 0:
+		// The separator that comes last is the decimal one
+		if dot > comma {
+			intPart = strings.ReplaceAll(s[:dot], ",", "")
+		} else {
+			intPart = strings.ReplaceAll(s[:comma], ".", "")
+		}
+	case comma >= 0:
+		if strings.Count(s, ",") == 1 && len(s)-comma-1 != 3 {
+			intPart = s[:comma]
+		} else {
+			intPart = strings.ReplaceAll(s, ",", "")
+		}
+	case dot >= 0:
+		if strings.Count(s, ".") == 1 && len(s)-dot-1 != 3 {
+			intPart = s[:dot]
+		} else {
+			intPart = strings.ReplaceAll(s, ".", "")
+		}
+	}
+	if intPart == "" {
+		intPart = "0"
+	}
+	v, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	if neg {
+		v = -v
+	}
+	return v, true
+}
+
+var indonesianMonths = strings.NewReplacer(
+	"Januari", "Jan", "Februari", "Feb", "Maret", "Mar", "April", "Apr", "Juni", "Jun", "Juli", "Jul",
+	"Agustus", "Aug", "September", "Sep", "Oktober", "Oct", "Nopember", "Nov", "November", "Nov", "Desember", "Dec",
+	"Mei", "May", "Agu", "Aug", "Agt", "Aug", "Okt", "Oct", "Des", "Dec", "Peb", "Feb",
+)
+
+var dateLayouts = []string{
+	"02/01/2006", "2/1/2006", "02-01-2006", "2-1-2006", "2006-01-02", "2006/01/02", "02/01/06", "02-01-06",
+	"02 Jan 2006", "2 Jan 2006", "02-Jan-2006", "2-Jan-2006", "02 Jan 06", "02-Jan-06", "02 January 2006",
+}
+
+var shortDate = regexp.MustCompile(`^(\d{1,2})[/-](\d{1,2})$`)
+
+// ParseDate reads a transaction date. Times after the date are ignored. A date without year
+// ("dd/mm") takes the year of yearRef, or the year before when that would put it in the future.
+func ParseDate(s string, yearRef, now time.Time) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	if m := shortDate.FindStringSubmatch(s); m != nil {
+		day, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		if month < 1 || month > 12 || day < 1 || day > 31 {
+			return time.Time{}, false
+		}
+		t := time.Date(yearRef.Year(), time.Month(month), day, 0, 0, 0, 0, time.UTC)
+		if t.After(now.AddDate(0, 0, 1)) {
+			t = t.AddDate(-1, 0, 0)
+		}
+		return t, true
+	}
+
+	s = indonesianMonths.Replace(s)
+	candidates := []string{s}
+	if f := strings.Fields(s); len(f) > 1 {
+		// "05/01/2026 10:15:00" or "05 Jan 2026 10:15"
+		candidates = append(candidates, f[0])
+		if len(f) >= 3 {
+			candidates = append(candidates, strings.Join(f[:3], " "))
+		}
+	}
+	for _, c := range candidates {
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, c); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+var (
+	periodPattern  = regexp.MustCompile(`(\d{1,2}[/-]\d{1,2}[/-]\d{2,4}|\d{4}-\d{2}-\d{2})\s*(?:-|s/d|sd|to|sampai)\s*(\d{1,2}[/-]\d{1,2}[/-]\d{2,4}|\d{4}-\d{2}-\d{2})`)
+	accountPattern = regexp.MustCompile(`\d[\d\-. ]{5,}\d`)
+)
+
+// readPreambleLine picks the account number and statement period from the lines above the header
+func readPreambleLine(st *Statement, rec []string) {
+	line := strings.Join(rec, " ")
+	lower := strings.ToLower(line)
+	if st.PeriodEnd == nil && strings.Contains(lower, "period") {
+		if m := periodPattern.FindStringSubmatch(line); m != nil {
+			start, ok1 := ParseDate(m[1], time.Now(), time.Now())
+			end, ok2 := ParseDate(m[2], time.Now(), time.Now())
+			if ok1 && ok2 {
+				st.PeriodStart, st.PeriodEnd = &start, &end
+			}
+		}
+	}
+	if st.AccountNumber == "" && (strings.Contains(lower, "rekening") || strings.Contains(lower, "account")) {
+		if m := accountPattern.FindString(line); m != "" {
+			st.AccountNumber = strings.Map(func(r rune) rune {
+				if r >= '0' && r <= '9' {
+					return r
+				}
+				return -1
+			}, m)
+		}
+	}
+}
+
+func detectBank(text string) string {
+	upper := strings.ToUpper(text)
+	switch {
+	case strings.Contains(upper, "KLIKBCA") || strings.Contains(upper, " BCA") || strings.Contains(upper, "CABANG"):
+		return BankBCA
+	case strings.Contains(upper, "MANDIRI"):
+		return BankMandiri
+	case strings.Contains(upper, "BRI") || strings.Contains(upper, "TELLER"):
+		return BankBRI
+	case strings.Contains(upper, "BNI") || strings.Contains(upper, "JOURNAL NO"):
+		return BankBNI
+	}
+	return BankOther
+}
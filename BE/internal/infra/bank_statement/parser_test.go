@@ -0,0 +1,109 @@
+package bank_statement
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2026, 2, 3, 10, 0, 0, 0, time.UTC)
+
+func TestParseKlikBCA(t *testing.T) {
+	csv := `No. rekening : 1234567890
+Nama : PT RR NET
+Periode : 01/01/2026 - 31/01/2026
+Kode Mata Uang : IDR
+
+Tanggal Transaksi,Keterangan,Cabang,Jumlah,,Saldo
+'05/01,TRSF E-BANKING CR 0501/FTSCY/WS95031 150123.00 BUDI SANTOSO,'0000,150123.00,CR,1650123.00
+'06/01,BIAYA ADM,'0000,"15,000.00",DB,1635123.00
+'PEND,TRSF E-BANKING CR SITI,'0000,200000.00,CR,
+Saldo Awal,,,1500000.00
+Mutasi Kredit,,,150123.00
+`
+	st, err := Parse(strings.NewReader(csv), "", now)
+	require.NoError(t, err)
+	assert.Equal(t, BankBCA, st.Bank)
+	assert.Equal(t, "1234567890", st.AccountNumber)
+	require.NotNil(t, st.PeriodEnd)
+	require.Len(t, st.Transactions, 2)
+
+	tx := st.Transactions[0]
+	assert.Equal(t, time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC), tx.Date)
+	assert.True(t, tx.Credit)
+	assert.Equal(t, int64(150123), tx.Amount)
+	assert.Contains(t, tx.Description, "BUDI SANTOSO")
+	require.NotNil(t, tx.Balance)
+	assert.Equal(t, int64(1650123), *tx.Balance)
+
+	assert.False(t, st.Transactions[1].Credit)
+	assert.Equal(t, int64(15000), st.Transactions[1].Amount)
+	assert.Equal(t, 3, st.SkippedRows)
+}
+
+func TestParseDebitCreditColumnsSemicolon(t *testing.T) {
+	csv := "Tanggal;Keterangan;Debet;Kredit;Saldo\n" +
+		"05/01/2026 10:15:00;TRANSFER DARI SITI AMINAH INV-202601-0007;;1.250.000,00;3.000.000,00\n" +
+		"06/01/2026;PAJAK BUNGA;2.500,00;;2.997.500,00\n"
+	st, err := Parse(strings.NewReader(csv), BankMandiri, now)
+	require.NoError(t, err)
+	assert.Equal(t, BankMandiri, st.Bank)
+	require.Len(t, st.Transactions, 2)
+	assert.True(t, st.Transactions[0].Credit)
+	assert.Equal(t, int64(1250000), st.Transactions[0].Amount)
+	assert.False(t, st.Transactions[1].Credit)
+	assert.Equal(t, int64(2500), st.Transactions[1].Amount)
+}
+
+func TestParseAmountTypeColumn(t *testing.T) {
+	csv := "Post Date,Branch,Journal No.,Description,Amount,D/K,Balance\n" +
+		"07-Jan-2026,0001,123,TRF 081234567890 ANDI,\"175,000.00\",K,\"1,175,000.00\"\n" +
+		"08 Agt 2025,0001,124,TARIK TUNAI,50000,D,1125000\n"
+	st, err := Parse(strings.NewReader(csv), "", now)
+	require.NoError(t, err)
+	assert.Equal(t, BankBNI, st.Bank)
+	require.Len(t, st.Transactions, 2)
+	assert.True(t, st.Transactions[0].Credit)
+	assert.Equal(t, int64(175000), st.Transactions[0].Amount)
+	assert.Equal(t, time.Date(2025, 8, 8, 0, 0, 0, 0, time.UTC), st.Transactions[1].Date)
+	assert.False(t, st.Transactions[1].Credit)
+}
+
+func TestParseNoHeader(t *testing.T) {
+	_, err := Parse(strings.NewReader("a,b,c\n1,2,3\n"), "", now)
+	assert.ErrorIs(t, err, ErrNoHeader)
+	_, err = Parse(strings.NewReader("  \n"), "", now)
+	assert.ErrorIs(t, err, ErrEmptyStatement)
+}
+
+func TestParseAmount(t *testing.T) {
+	cases := map[string]int64{
+		"150000":       150000,
+		"150.000":      150000,
+		"1.500.000,00": 1500000,
+		"1,500,000.00": 1500000,
+		"150000.00":    150000,
+		"Rp 150.000":   150000,
+		"-25.000":      -25000,
+		"(25,000.00)":  -25000,
+		"1,5":          1,
+		"1.234.567,89": 1234567,
+	}
+	for in, want := range cases {
+		got, ok := ParseAmount(in)
+		assert.True(t, ok, in)
+		assert.Equal(t, want, got, in)
+	}
+	_, ok := ParseAmount("")
+	assert.False(t, ok)
+}
+
+func TestParseDateWithoutYear(t *testing.T) {
+	// December mutations exported in early January belong to the previous year
+	got, ok := ParseDate("28/12", now, now)
+	require.True(t, ok)
+	assert.Equal(t, 2025, got.Year())
+}
@@ -329,6 +329,84 @@ func FindPPPoESecretID(ctx context.Context, addr string, useTLS bool, routerUser
 	return secretID, nil
 }
 
+// SetPPPoESecretProfile switches the profile of an existing PPPoE secret (by username).
+// Only the profile is changed; password, addresses and disabled flag are left untouched.
+func SetPPPoESecretProfile(ctx context.Context, addr string, useTLS bool, routerUsername string, routerPassword string, username string, profileName string) error {
+	client, err := connectToRouter(ctx, addr, useTLS, routerUsername, routerPassword)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	reply, err := client.RunArgs([]string{"/ppp/secret/print", "?name=" + username})
+	if err != nil {
+		return fmt.Errorf("failed to find PPPoE secret: %w", err)
+	}
+	if len(reply.Re) == 0 {
+		return fmt.Errorf("PPPoE secret not found: %s", username)
+	}
+
+	secretID := reply.Re[0].Map[".id"]
+	if secretID == "" {
+		return fmt.Errorf("invalid secret ID")
+	}
+
+	_, err = client.RunArgs([]string{"/ppp/secret/set", "=.id=" + secretID, "=profile=" + profileName})
+	if err != nil {
+		return fmt.Errorf("failed to set PPPoE secret profile: %w", err)
+	}
+
+	return nil
+}
+
+// GetPPPoEActiveAddress returns the address assigned to an active PPPoE session by username
+func GetPPPoEActiveAddress(ctx context.Context, addr string, useTLS bool, routerUsername string, routerPassword string, username string) (string, error) {
+	client, err := connectToRouter(ctx, addr, useTLS, routerUsername, routerPassword)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	reply, err := client.RunArgs([]string{"/ppp/active/print", "?name=" + username})
+	if err != nil {
+		return "", fmt.Errorf("failed to find PPPoE session: %w", err)
+	}
+
+	for _, re := range reply.Re {
+		if address := re.Map["address"]; address != "" {
+			return address, nil
+		}
+	}
+
+	return "", fmt.Errorf("PPPoE session not active: %s", username)
+}
+
+// DisconnectPPPoEUser removes every active PPPoE session of a username so the
+// client has to re-dial and pick up the current secret/profile.
+// Returns nil when the user has no active session.
+func DisconnectPPPoEUser(ctx context.Context, addr string, useTLS bool, routerUsername string, routerPassword string, username string) error {
+	client, err := connectToRouter(ctx, addr, useTLS, routerUsername, routerPassword)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	reply, err := client.RunArgs([]string{"/ppp/active/print", "?name=" + username})
+	if err != nil {
+		return fmt.Errorf("failed to find PPPoE session: %w", err)
+	}
+
+	for _, re := range reply.Re {
+		if id, ok := re.Map[".id"]; ok {
+			if _, err := client.RunArgs([]string{"/ppp/active/remove", "=.id=" + id}); err != nil {
+				return fmt.Errorf("failed to disconnect PPPoE session: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
 // ListPPPoEProfiles lists all PPPoE profiles from MikroTik router
 func ListPPPoEProfiles(ctx context.Context, addr string, useTLS bool, routerUsername string, routerPassword string) ([]PPPoEProfile, error) {
 	client, err := connectToRouter(ctx, addr, useTLS, routerUsername, routerPassword)
@@ -570,4 +648,3 @@ func GetPPPoEServerLocalAddress(ctx context.Context, addr string, useTLS bool, u
 
 	return "", fmt.Errorf("no local address found on router")
 }
-
@@ -0,0 +1,187 @@
+// Package payment_gateway integrates Indonesian payment aggregators (VA, QRIS, e-wallet).
+// Every provider creates a payment for one order and verifies the signature of its webhooks.
+package payment_gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Provider names
+const (
+	ProviderMidtrans = "midtrans"
+	ProviderXendit   = "xendit"
+	ProviderTripay   = "tripay"
+	ProviderMock     = "mock"
+)
+
+// Method is the payment instrument offered to the customer
+type Method string
+
+const (
+	MethodVA      Method = "virtual_account"
+	MethodQRIS    Method = "qris"
+	MethodEWallet Method = "e_wallet"
+)
+
+// Status is the state of a payment reported by a provider
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusPaid    Status = "paid"
+	StatusExpired Status = "expired"
+	StatusFailed  Status = "failed"
+)
+
+var (
+	ErrUnknownProvider    = errors.New("unknown payment gateway provider")
+	ErrUnsupportedMethod  = errors.New("payment method or channel not supported by provider")
+	ErrInvalidSignature   = errors.New("invalid webhook signature")
+	ErrMissingCredentials = errors.New("payment gateway credentials are not configured")
+)
+
+// Credentials of a merchant account. Providers use the fields they need:
+// Midtrans: APIKey (server key). Xendit: APIKey (secret key), WebhookToken (callback token).
+// Tripay: APIKey, PrivateKey, MerchantCode. Mock: APIKey (signing secret, optional).
+type Credentials struct {
+	APIKey       string
+	PrivateKey   string
+	MerchantCode string
+	WebhookToken string
+	Sandbox      bool
+}
+
+// ChargeRequest asks a provider for a payment of one order
+type ChargeRequest struct {
+	OrderID       string // unique per merchant account
+	Amount        int64
+	Method        Method
+	Channel       string // bank or e-wallet, e.g. "bca", "bri", "ovo", "dana" (empty for QRIS)
+	Description   string
+	CustomerName  string
+	CustomerPhone string
+	CustomerEmail string
+	ExpiresAt     time.Time
+	CallbackURL   string // webhook URL, for providers that accept it per request
+	ReturnURL     string // where e-wallet checkouts redirect to afterwards
+}
+
+// Charge is a created payment: what the customer needs to pay it
+type Charge struct {
+	Reference  string // provider transaction ID
+	OrderID    string
+	Amount     int64
+	VANumber   string
+	QRString   string
+	PaymentURL string // checkout page or e-wallet deeplink
+	ExpiresAt  *time.Time
+}
+
+// Notification is a verified webhook
+type Notification struct {
+	Reference string
+	OrderID   string
+	Status    Status
+	Amount    int64 // amount paid for the order, without fees charged to the customer
+	PaidAt    *time.Time
+}
+
+// Provider is implemented by every payment gateway integration
+type Provider interface {
+	Name() string
+	CreateCharge(ctx context.Context, req ChargeRequest) (*Charge, error)
+	// ParseWebhook verifies the webhook signature and decodes it. Returns ErrInvalidSignature when
+	// the webhook was not sent by the provider for this merchant account.
+	ParseWebhook(header http.Header, body []byte) (*Notification, error)
+}
+
+// New returns the provider with the given name
+func New(name string, cred Credentials) (Provider, error) {
+	switch name {
+	case ProviderMidtrans:
+		return newMidtrans(cred)
+	case ProviderXendit:
+		return newXendit(cred)
+	case ProviderTripay:
+		return newTripay(cred)
+	case ProviderMock:
+		return newMock(cred)
+	default:
+		return nil, ErrUnknownProvider
+	}
+}
+
+// Providers lists the real provider names. ProviderMock is not among them: it settles payments
+// without money and is only offered outside production.
+func Providers() []string {
+	return []string{ProviderMidtrans, ProviderXendit, ProviderTripay}
+}
+
+var httpClient = &http.Client{Timeout: 20 * time.Second}
+
+// doJSON sends a JSON request and decodes the JSON response into out. Error responses are returned
+// with their body so provider messages end up in the logs.
+func doJSON(ctx context.Context, method, url string, header http.Header, reqBody, out any) error {
+	var body io.Reader
+	if reqBody != nil {
+		b, err := json.Marshal(reqBody)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("%s %s failed: HTTP %d: %s", method, url, resp.StatusCode, bytes.TrimSpace(raw))
+	}
+	if out != nil {
+		return json.Unmarshal(raw, out)
+	}
+	return nil
+}
+
+// equalSecret compares a received signature or token in constant time
+func equalSecret(got, want string) bool {
+	return want != "" && hmac.Equal([]byte(got), []byte(want))
+}
+
+// basicAuth returns the Authorization header for a key used as username with an empty password
+func basicAuth(key string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(key+":"))
+}
+
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max])
+}
@@ -0,0 +1,135 @@
+package payment_gateway
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMidtransWebhook(t *testing.T) {
+	p, err := New(ProviderMidtrans, Credentials{APIKey: "SB-Mid-server-key"})
+	require.NoError(t, err)
+
+	sum := sha512.Sum512([]byte("INV-1-AB12" + "200" + "150000.00" + "SB-Mid-server-key"))
+	body := fmt.Sprintf(`{"transaction_id":"tx-1","order_id":"INV-1-AB12","status_code":"200","gross_amount":"150000.00",
+		"signature_key":"%s","transaction_status":"settlement","settlement_time":"2026-01-05 10:00:00"}`, hex.EncodeToString(sum[:]))
+
+	n, err := p.ParseWebhook(http.Header{}, []byte(body))
+	require.NoError(t, err)
+	assert.Equal(t, StatusPaid, n.Status)
+	assert.Equal(t, int64(150000), n.Amount)
+	assert.Equal(t, "tx-1", n.Reference)
+	require.NotNil(t, n.PaidAt)
+
+	// Tampered amount
+	tampered := []byte(fmt.Sprintf(`{"order_id":"INV-1-AB12","status_code":"200","gross_amount":"1.00","signature_key":"%s","transaction_status":"settlement"}`, hex.EncodeToString(sum[:])))
+	_, err = p.ParseWebhook(http.Header{}, tampered)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestTripayWebhook(t *testing.T) {
+	p, err := New(ProviderTripay, Credentials{APIKey: "k", PrivateKey: "private", MerchantCode: "T0001"})
+	require.NoError(t, err)
+
+	body := []byte(`{"reference":"T1","merchant_ref":"INV-2-CD34","status":"PAID","total_amount":154250,"fee_customer":4250,"paid_at":1767600000}`)
+	mac := hmac.New(sha256.New, []byte("private"))
+	mac.Write(body)
+	header := http.Header{}
+	header.Set("X-Callback-Signature", hex.EncodeToString(mac.Sum(nil)))
+
+	n, err := p.ParseWebhook(header, body)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPaid, n.Status)
+	assert.Equal(t, int64(150000), n.Amount, "customer fee is not part of the invoice payment")
+
+	header.Set("X-Callback-Signature", "00")
+	_, err = p.ParseWebhook(header, body)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestXenditWebhook(t *testing.T) {
+	p, err := New(ProviderXendit, Credentials{APIKey: "xnd_development_x", WebhookToken: "cb-token"})
+	require.NoError(t, err)
+
+	body := []byte(`{"payment_id":"pay-1","callback_virtual_account_id":"va-1","external_id":"INV-3-EF56","amount":100000,"transaction_timestamp":"2026-01-05T03:00:00Z"}`)
+	header := http.Header{}
+	header.Set("X-Callback-Token", "cb-token")
+	n, err := p.ParseWebhook(header, body)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPaid, n.Status)
+	assert.Equal(t, "INV-3-EF56", n.OrderID)
+	assert.Equal(t, int64(100000), n.Amount)
+
+	qr := []byte(`{"event":"qr.payment","data":{"qr_id":"qr-1","reference_id":"INV-4","amount":50000,"status":"SUCCEEDED"}}`)
+	n, err = p.ParseWebhook(header, qr)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPaid, n.Status)
+	assert.Equal(t, "qr-1", n.Reference)
+
+	header.Set("X-Callback-Token", "wrong")
+	_, err = p.ParseWebhook(header, body)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestMockRoundTrip(t *testing.T) {
+	_, err := New(ProviderMock, Credentials{})
+	assert.ErrorIs(t, err, ErrMissingCredentials)
+
+	cred := Credentials{APIKey: "dev-secret"}
+	p, err := New(ProviderMock, cred)
+	require.NoError(t, err)
+
+	charge, err := p.CreateCharge(context.Background(), ChargeRequest{OrderID: "INV-5", Amount: 75000, Method: MethodVA})
+	require.NoError(t, err)
+	assert.NotEmpty(t, charge.VANumber)
+
+	header, body, err := MockWebhook(cred, Notification{Reference: charge.Reference, OrderID: "INV-5", Status: StatusPaid, Amount: 75000})
+	require.NoError(t, err)
+	n, err := p.ParseWebhook(header, body)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPaid, n.Status)
+	assert.Equal(t, int64(75000), n.Amount)
+
+	// Signed with another secret
+	header, body, _ = MockWebhook(Credentials{APIKey: "other"}, Notification{OrderID: "INV-5", Status: StatusPaid})
+	_, err = p.ParseWebhook(header, body)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestMidtransCreateCharge(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/charge", r.URL.Path)
+		assert.Equal(t, basicAuth("server-key"), r.Header.Get("Authorization"))
+		assert.Equal(t, "https://api.example.com/hook", r.Header.Get("X-Override-Notification"))
+		raw, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(raw, &got))
+		_, _ = w.Write([]byte(`{"status_code":"201","transaction_id":"tx-9","order_id":"INV-9",
+			"va_numbers":[{"bank":"bca","va_number":"12345678901"}],"expiry_time":"2026-01-06 10:00:00"}`))
+	}))
+	defer srv.Close()
+
+	m := &midtrans{serverKey: "server-key", baseURL: srv.URL}
+	charge, err := m.CreateCharge(context.Background(), ChargeRequest{
+		OrderID: "INV-9", Amount: 200000, Method: MethodVA, Channel: "bca", CallbackURL: "https://api.example.com/hook",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "bank_transfer", got["payment_type"])
+	assert.Equal(t, "tx-9", charge.Reference)
+	assert.Equal(t, "12345678901", charge.VANumber)
+	require.NotNil(t, charge.ExpiresAt)
+
+	_, err = m.CreateCharge(context.Background(), ChargeRequest{OrderID: "INV-9", Amount: 1, Method: MethodVA, Channel: "unknown"})
+	assert.ErrorIs(t, err, ErrUnsupportedMethod)
+}
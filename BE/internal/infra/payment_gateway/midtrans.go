@@ -0,0 +1,171 @@
+package payment_gateway
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// midtrans uses the Core API (server-to-server charge) and HTTP notifications
+type midtrans struct {
+	serverKey string
+	baseURL   string
+}
+
+func newMidtrans(cred Credentials) (*midtrans, error) {
+	if cred.APIKey == "" {
+		return nil, ErrMissingCredentials
+	}
+	base := "https://api.midtrans.com"
+	if cred.Sandbox {
+		base = "https://api.sandbox.midtrans.com"
+	}
+	return &midtrans{serverKey: cred.APIKey, baseURL: base}, nil
+}
+
+func (m *midtrans) Name() string { return ProviderMidtrans }
+
+// Midtrans reports times in Asia/Jakarta
+var jakarta = time.FixedZone("WIB", 7*3600)
+
+func (m *midtrans) CreateCharge(ctx context.Context, req ChargeRequest) (*Charge, error) {
+	body := map[string]any{
+		"transaction_details": map[string]any{"order_id": req.OrderID, "gross_amount": req.Amount},
+		"customer_details": map[string]any{
+			"first_name": req.CustomerName,
+			"phone":      req.CustomerPhone,
+			"email":      req.CustomerEmail,
+		},
+		"item_details": []map[string]any{{"id": req.OrderID, "price": req.Amount, "quantity": 1, "name": truncate(req.Description, 50)}},
+	}
+	if !req.ExpiresAt.IsZero() {
+		minutes := int(math.Ceil(time.Until(req.ExpiresAt).Minutes()))
+		if minutes < 1 {
+			minutes = 1
+		}
+		body["custom_expiry"] = map[string]any{"expiry_duration": minutes, "unit": "minute"}
+	}
+
+	switch req.Method {
+	case MethodVA:
+		switch req.Channel {
+		case "bca", "bni", "bri", "cimb", "permata":
+			body["payment_type"] = "bank_transfer"
+			body["bank_transfer"] = map[string]any{"bank": req.Channel}
+		default:
+			return nil, ErrUnsupportedMethod
+		}
+	case MethodQRIS:
+		body["payment_type"] = "qris"
+	case MethodEWallet:
+		switch req.Channel {
+		case "gopay", "shopeepay":
+			body["payment_type"] = req.Channel
+			if req.ReturnURL != "" {
+				body[req.Channel] = map[string]any{"enable_callback": true, "callback_url": req.ReturnURL}
+			}
+		default:
+			return nil, ErrUnsupportedMethod
+		}
+	default:
+		return nil, ErrUnsupportedMethod
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", basicAuth(m.serverKey))
+	if req.CallbackURL != "" {
+		header.Set("X-Override-Notification", req.CallbackURL)
+	}
+
+	var resp struct {
+		StatusCode      string `json:"status_code"`
+		StatusMessage   string `json:"status_message"`
+		TransactionID   string `json:"transaction_id"`
+		OrderID         string `json:"order_id"`
+		PermataVANumber string `json:"permata_va_number"`
+		VANumbers       []struct {
+			Bank     string `json:"bank"`
+			VANumber string `json:"va_number"`
+		} `json:"va_numbers"`
+		QRString string `json:"qr_string"`
+		Actions  []struct {
+			Name string `json:"name"`
+			URL  string `json:"url"`
+		} `json:"actions"`
+		ExpiryTime string `json:"expiry_time"`
+	}
+	if err := doJSON(ctx, http.MethodPost, m.baseURL+"/v2/charge", header, body, &resp); err != nil {
+		return nil, err
+	}
+	// Midtrans answers HTTP 200 with the real status in the body
+	if resp.StatusCode != "200" && resp.StatusCode != "201" {
+		return nil, fmt.Errorf("midtrans charge failed: %s %s", resp.StatusCode, resp.StatusMessage)
+	}
+
+	out := &Charge{Reference: resp.TransactionID, OrderID: resp.OrderID, Amount: req.Amount, QRString: resp.QRString}
+	if len(resp.VANumbers) > 0 {
+		out.VANumber = resp.VANumbers[0].VANumber
+	} else if resp.PermataVANumber != "" {
+		out.VANumber = resp.PermataVANumber
+	}
+	for _, a := range resp.Actions {
+		if a.Name == "deeplink-redirect" || (a.Name == "generate-qr-code" && out.PaymentURL == "") {
+			out.PaymentURL = a.URL
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", resp.ExpiryTime, jakarta); err == nil {
+		out.ExpiresAt = &t
+	}
+	return out, nil
+}
+
+// ParseWebhook verifies signature_key = SHA512(order_id + status_code + gross_amount + server key)
+func (m *midtrans) ParseWebhook(_ http.Header, body []byte) (*Notification, error) {
+	var n struct {
+		TransactionID     string `json:"transaction_id"`
+		OrderID           string `json:"order_id"`
+		StatusCode        string `json:"status_code"`
+		GrossAmount       string `json:"gross_amount"`
+		SignatureKey      string `json:"signature_key"`
+		TransactionStatus string `json:"transaction_status"`
+		FraudStatus       string `json:"fraud_status"`
+		SettlementTime    string `json:"settlement_time"`
+	}
+	if err := json.Unmarshal(body, &n); err != nil {
+		return nil, ErrInvalidSignature
+	}
+	sum := sha512.Sum512([]byte(n.OrderID + n.StatusCode + n.GrossAmount + m.serverKey))
+	if !equalSecret(n.SignatureKey, hex.EncodeToString(sum[:])) {
+		return nil, ErrInvalidSignature
+	}
+
+	amount, _ := strconv.ParseFloat(n.GrossAmount, 64)
+	out := &Notification{Reference: n.TransactionID, OrderID: n.OrderID, Amount: int64(math.Round(amount))}
+	switch n.TransactionStatus {
+	case "settlement":
+		out.Status = StatusPaid
+	case "capture":
+		out.Status = StatusPending
+		if n.FraudStatus == "" || n.FraudStatus == "accept" {
+			out.Status = StatusPaid
+		}
+	case "expire":
+		out.Status = StatusExpired
+	case "deny", "cancel", "failure":
+		out.Status = StatusFailed
+	default:
+		out.Status = StatusPending
+	}
+	if out.Status == StatusPaid {
+		if t, err := time.ParseInLocation("2006-01-02 15:04:05", n.SettlementTime, jakarta); err == nil {
+			out.PaidAt = &t
+		}
+	}
+	return out, nil
+}
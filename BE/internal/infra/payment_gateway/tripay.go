@@ -0,0 +1,157 @@
+package payment_gateway
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// tripay uses closed payment transactions and signed callbacks
+type tripay struct {
+	apiKey       string
+	privateKey   string
+	merchantCode string
+	baseURL      string
+}
+
+func newTripay(cred Credentials) (*tripay, error) {
+	if cred.APIKey == "" || cred.PrivateKey == "" || cred.MerchantCode == "" {
+		return nil, ErrMissingCredentials
+	}
+	base := "https://tripay.co.id/api"
+	if cred.Sandbox {
+		base = "https://tripay.co.id/api-sandbox"
+	}
+	return &tripay{apiKey: cred.APIKey, privateKey: cred.PrivateKey, merchantCode: cred.MerchantCode, baseURL: base}, nil
+}
+
+func (t *tripay) Name() string { return ProviderTripay }
+
+// tripayChannel maps a method and channel to a Tripay payment method code
+func tripayChannel(method Method, channel string) (string, bool) {
+	switch method {
+	case MethodVA:
+		codes := map[string]string{
+			"bri": "BRIVA", "bca": "BCAVA", "bni": "BNIVA", "mandiri": "MANDIRIVA",
+			"permata": "PERMATAVA", "cimb": "CIMBVA", "bsi": "BSIVA",
+		}
+		code, ok := codes[channel]
+		return code, ok
+	case MethodQRIS:
+		return "QRIS", true
+	case MethodEWallet:
+		switch channel {
+		case "ovo", "dana", "shopeepay":
+			return strings.ToUpper(channel), true
+		}
+	}
+	return "", false
+}
+
+func (t *tripay) hmacHex(data []byte) string {
+	mac := hmac.New(sha256.New, []byte(t.privateKey))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (t *tripay) CreateCharge(ctx context.Context, req ChargeRequest) (*Charge, error) {
+	code, ok := tripayChannel(req.Method, req.Channel)
+	if !ok {
+		return nil, ErrUnsupportedMethod
+	}
+	body := map[string]any{
+		"method":         code,
+		"merchant_ref":   req.OrderID,
+		"amount":         req.Amount,
+		"customer_name":  req.CustomerName,
+		"customer_email": req.CustomerEmail,
+		"customer_phone": req.CustomerPhone,
+		"order_items":    []map[string]any{{"name": req.Description, "price": req.Amount, "quantity": 1}},
+		"signature":      t.hmacHex([]byte(fmt.Sprintf("%s%s%d", t.merchantCode, req.OrderID, req.Amount))),
+	}
+	if req.CallbackURL != "" {
+		body["callback_url"] = req.CallbackURL
+	}
+	if req.ReturnURL != "" {
+		body["return_url"] = req.ReturnURL
+	}
+	if !req.ExpiresAt.IsZero() {
+		body["expired_time"] = req.ExpiresAt.Unix()
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+t.apiKey)
+	var resp struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+		Data    struct {
+			Reference   string `json:"reference"`
+			MerchantRef string `json:"merchant_ref"`
+			PayCode     string `json:"pay_code"`
+			CheckoutURL string `json:"checkout_url"`
+			QRString    string `json:"qr_string"`
+			ExpiredTime int64  `json:"expired_time"`
+		} `json:"data"`
+	}
+	if err := doJSON(ctx, http.MethodPost, t.baseURL+"/transaction/create", header, body, &resp); err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("tripay transaction failed: %s", resp.Message)
+	}
+	out := &Charge{
+		Reference:  resp.Data.Reference,
+		OrderID:    resp.Data.MerchantRef,
+		Amount:     req.Amount,
+		QRString:   resp.Data.QRString,
+		PaymentURL: resp.Data.CheckoutURL,
+	}
+	if req.Method == MethodVA {
+		out.VANumber = resp.Data.PayCode
+	}
+	if resp.Data.ExpiredTime > 0 {
+		exp := time.Unix(resp.Data.ExpiredTime, 0)
+		out.ExpiresAt = &exp
+	}
+	return out, nil
+}
+
+// ParseWebhook verifies X-Callback-Signature = HMAC-SHA256(private key, raw body)
+func (t *tripay) ParseWebhook(header http.Header, body []byte) (*Notification, error) {
+	if !equalSecret(header.Get("X-Callback-Signature"), t.hmacHex(body)) {
+		return nil, ErrInvalidSignature
+	}
+	var n struct {
+		Reference   string `json:"reference"`
+		MerchantRef string `json:"merchant_ref"`
+		Status      string `json:"status"`
+		TotalAmount int64  `json:"total_amount"`
+		FeeCustomer int64  `json:"fee_customer"`
+		PaidAt      int64  `json:"paid_at"`
+	}
+	if err := json.Unmarshal(body, &n); err != nil {
+		return nil, err
+	}
+	out := &Notification{Reference: n.Reference, OrderID: n.MerchantRef, Amount: n.TotalAmount - n.FeeCustomer}
+	switch n.Status {
+	case "PAID":
+		out.Status = StatusPaid
+		if n.PaidAt > 0 {
+			paidAt := time.Unix(n.PaidAt, 0)
+			out.PaidAt = &paidAt
+		}
+	case "EXPIRED":
+		out.Status = StatusExpired
+	case "FAILED", "REFUND":
+		out.Status = StatusFailed
+	default:
+		out.Status = StatusPending
+	}
+	return out, nil
+}
@@ -0,0 +1,68 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// ErrLockNotAcquired is returned when the lock is held by someone else
+var ErrLockNotAcquired = errors.New("lock is held by another owner")
+
+// The lock is only extended or released by the owner that holds it
+var (
+	refreshScript = redis.NewScript(`
+		if redis.call("GET", KEYS[1]) == ARGV[1] then
+			return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+		end
+		return 0
+	`)
+	releaseScript = redis.NewScript(`
+		if redis.call("GET", KEYS[1]) == ARGV[1] then
+			return redis.call("DEL", KEYS[1])
+		end
+		return 0
+	`)
+)
+
+// Lock is a lease on a Redis key shared by all replicas. It expires after its TTL unless refreshed,
+// so a crashed owner does not hold it forever.
+type Lock struct {
+	client *redis.Client
+	key    string
+	token  string
+	ttl    time.Duration
+}
+
+// AcquireLock takes the lock on key for ttl, or returns ErrLockNotAcquired
+func AcquireLock(ctx context.Context, client *redis.Client, key string, ttl time.Duration) (*Lock, error) {
+	token := uuid.NewString()
+	ok, err := client.SetNX(ctx, key, token, ttl).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrLockNotAcquired
+	}
+	return &Lock{client: client, key: key, token: token, ttl: ttl}, nil
+}
+
+// Refresh extends the lease by the TTL; ErrLockNotAcquired means the lock was lost
+func (l *Lock) Refresh(ctx context.Context) error {
+	n, err := refreshScript.Run(ctx, l.client, []string{l.key}, l.token, l.ttl.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLockNotAcquired
+	}
+	return nil
+}
+
+// Release gives the lock up if it is still held
+func (l *Lock) Release(ctx context.Context) error {
+	return releaseScript.Run(ctx, l.client, []string{l.key}, l.token).Err()
+}
@@ -0,0 +1,45 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockOwnership(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+
+	lock, err := AcquireLock(ctx, client, "job", time.Minute)
+	require.NoError(t, err)
+
+	_, err = AcquireLock(ctx, client, "job", time.Minute)
+	assert.ErrorIs(t, err, ErrLockNotAcquired)
+
+	// The owner extends the lease
+	mr.FastForward(50 * time.Second)
+	require.NoError(t, lock.Refresh(ctx))
+	assert.Equal(t, time.Minute, mr.TTL("job"))
+
+	// Once the lease expired and another replica took the lock, the old owner neither extends nor
+	// releases it
+	mr.FastForward(2 * time.Minute)
+	other, err := AcquireLock(ctx, client, "job", time.Minute)
+	require.NoError(t, err)
+	assert.ErrorIs(t, lock.Refresh(ctx), ErrLockNotAcquired)
+	require.NoError(t, lock.Release(ctx))
+	assert.True(t, mr.Exists("job"))
+
+	// The owner releases it
+	require.NoError(t, other.Release(ctx))
+	assert.False(t, mr.Exists("job"))
+	_, err = AcquireLock(ctx, client, "job", time.Minute)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,204 @@
+package reporting
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Format is an export file format
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+)
+
+var ErrFormatInvalid = errors.New("format must be csv or xlsx")
+
+// ContentType returns the MIME type of the format
+func (f Format) ContentType() string {
+	if f == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Table is a report laid out for export. Cells are strings or int64 numbers (money in IDR, counts).
+type Table struct {
+	Title   string
+	Columns []string
+	Rows    [][]interface{}
+}
+
+func (t *Table) addRow(cells ...interface{}) {
+	t.Rows = append(t.Rows, cells)
+}
+
+// Write exports the table in format f
+func (t *Table) Write(w io.Writer, f Format) error {
+	switch f {
+	case FormatCSV:
+		return t.WriteCSV(w)
+	case FormatXLSX:
+		return t.WriteXLSX(w)
+	}
+	return ErrFormatInvalid
+}
+
+// WriteCSV writes the header and rows as CSV
+func (t *Table) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.Columns); err != nil {
+		return err
+	}
+	record := make([]string, len(t.Columns))
+	for _, row := range t.Rows {
+		for i := range record {
+			record[i] = ""
+			if i < len(row) {
+				record[i] = cellText(row[i])
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func cellText(v interface{}) string {
+	switch c := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return c
+	case int64:
+		return strconv.FormatInt(c, 10)
+	case int:
+		return strconv.Itoa(c)
+	}
+	return fmt.Sprint(v)
+}
+
+// XLSX parts that do not depend on the data. The workbook has one sheet; strings are stored
+// inline and the header row is bold.
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/></Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`
+	xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><numFmts count="1"><numFmt numFmtId="164" formatCode="#,##0"/></numFmts><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="3"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/><xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs></styleSheet>`
+)
+
+// Cell styles of xlsxStyles
+const (
+	xlsxStyleHeader = 1
+	xlsxStyleNumber = 2
+)
+
+// WriteXLSX writes the table as a single-sheet Excel workbook
+func (t *Table) WriteXLSX(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+		{"xl/workbook.xml", t.xlsxWorkbook()},
+		{"xl/worksheets/sheet1.xml", t.xlsxSheet()},
+	}
+	for _, p := range parts {
+		f, err := zw.Create(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, p.body); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func (t *Table) xlsxWorkbook() string {
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="` +
+		xmlEscape(sheetName(t.Title)) + `" sheetId="1" r:id="rId1"/></sheets></workbook>`
+}
+
+func (t *Table) xlsxSheet() string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	header := make([]interface{}, len(t.Columns))
+	for i, c := range t.Columns {
+		header[i] = c
+	}
+	writeXLSXRow(&b, 1, header, xlsxStyleHeader)
+	for i, row := range t.Rows {
+		writeXLSXRow(&b, i+2, row, 0)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+func writeXLSXRow(b *strings.Builder, n int, cells []interface{}, style int) {
+	fmt.Fprintf(b, `<row r="%d">`, n)
+	for i, v := range cells {
+		ref := columnName(i) + strconv.Itoa(n)
+		switch c := v.(type) {
+		case nil:
+			continue
+		case int64, int:
+			fmt.Fprintf(b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, xlsxStyleNumber, cellText(c))
+		default:
+			s := ""
+			if style != 0 {
+				s = fmt.Sprintf(` s="%d"`, style)
+			}
+			fmt.Fprintf(b, `<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, s, xmlEscape(cellText(c)))
+		}
+	}
+	b.WriteString(`</row>`)
+}
+
+// columnName returns the spreadsheet column of a 0-based index (0 = A, 26 = AA)
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// sheetName makes a valid sheet name: at most 31 characters without []:*?/\
+func sheetName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '-'
+		}
+		return r
+	}, title)
+	if name == "" {
+		name = "Report"
+	}
+	if r := []rune(name); len(r) > 31 {
+		name = string(r[:31])
+	}
+	return name
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
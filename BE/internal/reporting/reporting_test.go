@@ -0,0 +1,92 @@
+package reporting
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rrnet/internal/domain/billing"
+)
+
+func TestAgingBucket(t *testing.T) {
+	for days, want := range map[int]int{-5: 0, 0: 0, 1: 1, 30: 1, 31: 2, 60: 2, 61: 3, 90: 3, 91: 4, 400: 4} {
+		assert.Equal(t, want, agingBucket(days), "days %d", days)
+	}
+}
+
+func TestBuildAging(t *testing.T) {
+	asOf := time.Date(2026, 5, 31, 15, 0, 0, 0, time.Local)
+	a, b := uuid.New(), uuid.New()
+	report := buildAging([]billing.OpenReceivable{
+		{ClientID: a, ClientName: "Andi", DueDate: time.Date(2026, 6, 10, 0, 0, 0, 0, time.Local), Outstanding: 150000},
+		{ClientID: a, ClientName: "Andi", DueDate: time.Date(2026, 5, 1, 0, 0, 0, 0, time.Local), Outstanding: 150000},
+		{ClientID: b, ClientName: "Budi", DueDate: time.Date(2026, 2, 1, 0, 0, 0, 0, time.Local), Outstanding: 200000},
+	}, asOf)
+
+	require.Len(t, report.Rows, 2)
+	assert.Equal(t, [5]int64{150000, 150000, 0, 0, 0}, report.Rows[0].Buckets) // due in 10 days; 30 days past due
+	assert.Equal(t, 2, report.Rows[0].Invoices)
+	assert.Equal(t, time.Date(2026, 5, 1, 0, 0, 0, 0, time.Local), report.Rows[0].OldestDueDate)
+	assert.Equal(t, [5]int64{0, 0, 0, 0, 200000}, report.Rows[1].Buckets) // 119 days
+	assert.Equal(t, [5]int64{150000, 150000, 0, 0, 200000}, report.Totals)
+	assert.Equal(t, int64(500000), report.Total)
+}
+
+func TestBuildMonthlyRevenue(t *testing.T) {
+	report := buildMonthlyRevenue(2026,
+		[]billing.MonthlyAmount{{Month: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), Amount: 1110000, Tax: 110000, Count: 6}},
+		[]billing.MonthlyAmount{{Month: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), Amount: 800000, Count: 4}, {Month: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), Amount: 300000, Count: 2}},
+	)
+	require.Len(t, report.Rows, 12)
+	assert.Equal(t, int64(1110000), report.Rows[2].Accrued)
+	assert.Equal(t, int64(800000), report.Rows[2].Collected)
+	assert.Equal(t, int64(300000), report.Rows[3].Collected)
+	assert.Equal(t, int64(1110000), report.Accrued)
+	assert.Equal(t, int64(1100000), report.Collected)
+}
+
+func TestTableExport(t *testing.T) {
+	table := &Table{
+		Title:   "Revenue by package: 2026/05",
+		Columns: []string{"Package", "Amount"},
+		Rows:    [][]interface{}{{"Home 20 Mbps, \"promo\"", int64(1500000)}, {"TOTAL", nil}},
+	}
+
+	var csvOut bytes.Buffer
+	require.NoError(t, table.Write(&csvOut, FormatCSV))
+	assert.Equal(t, "Package,Amount\n\"Home 20 Mbps, \"\"promo\"\"\",1500000\nTOTAL,\n", csvOut.String())
+
+	var xlsxOut bytes.Buffer
+	require.NoError(t, table.Write(&xlsxOut, FormatXLSX))
+	zr, err := zip.NewReader(bytes.NewReader(xlsxOut.Bytes()), int64(xlsxOut.Len()))
+	require.NoError(t, err)
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(b)
+	}
+	assert.Contains(t, parts, "[Content_Types].xml")
+	assert.Contains(t, parts["xl/workbook.xml"], `name="Revenue by package- 2026-05"`)
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `<c r="A2" t="inlineStr"><is><t xml:space="preserve">Home 20 Mbps, &#34;promo&#34;</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="B2" s="2"><v>1500000</v></c>`)
+	assert.False(t, strings.Contains(sheet, `r="B3"`))
+
+	assert.Equal(t, ErrFormatInvalid, table.Write(io.Discard, "pdf"))
+}
+
+func TestColumnName(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		assert.Equal(t, want, columnName(i))
+	}
+}
@@ -0,0 +1,226 @@
+// Package reporting builds the billing reports (receivable aging, revenue breakdowns and monthly
+// revenue) and exports them as CSV or XLSX.
+package reporting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/repository"
+)
+
+var (
+	ErrDimensionInvalid = errors.New("by must be package, group, router or collector")
+	ErrBasisInvalid     = errors.New("basis must be cash or accrual (collector revenue is cash only)")
+	ErrPeriodInvalid    = errors.New("to must be after from and at most 5 years later")
+)
+
+// maxReportPeriod bounds the period of revenue reports
+const maxReportPeriod = 5 * 366 * 24 * time.Hour
+
+// Service builds reports over invoices and payments
+type Service struct {
+	invoiceRepo *repository.InvoiceRepository
+	paymentRepo *repository.PaymentRepository
+}
+
+func NewService(invoiceRepo *repository.InvoiceRepository, paymentRepo *repository.PaymentRepository) *Service {
+	return &Service{invoiceRepo: invoiceRepo, paymentRepo: paymentRepo}
+}
+
+// ========== Receivable aging ==========
+
+// AgingBuckets are the columns of the aging report, by days past the due date
+var AgingBuckets = []string{"current", "1-30", "31-60", "61-90", "90+"}
+
+// agingBucket returns the index in AgingBuckets of a receivable daysPastDue days past its due date
+func agingBucket(daysPastDue int) int {
+	switch {
+	case daysPastDue <= 0:
+		return 0
+	case daysPastDue <= 30:
+		return 1
+	case daysPastDue <= 60:
+		return 2
+	case daysPastDue <= 90:
+		return 3
+	}
+	return 4
+}
+
+// daysPastDue counts calendar days from the due date to asOf
+func daysPastDue(due, asOf time.Time) int {
+	d := time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, time.UTC)
+	a := time.Date(asOf.Year(), asOf.Month(), asOf.Day(), 0, 0, 0, 0, time.UTC)
+	return int(a.Sub(d).Hours() / 24)
+}
+
+// AgingRow is the outstanding amount of one client per aging bucket
+type AgingRow struct {
+	ClientID        uuid.UUID `json:"client_id"`
+	ClientName      string    `json:"client_name"`
+	ClientGroupName *string   `json:"client_group_name,omitempty"`
+	Invoices        int       `json:"invoices"`
+	Buckets         [5]int64  `json:"buckets"` // in AgingBuckets order
+	Total           int64     `json:"total"`
+	OldestDueDate   time.Time `json:"oldest_due_date"`
+}
+
+type AgingReport struct {
+	AsOf    time.Time  `json:"as_of"`
+	Buckets []string   `json:"buckets"`
+	Rows    []AgingRow `json:"rows"`
+	Totals  [5]int64   `json:"totals"`
+	Total   int64      `json:"total"`
+}
+
+// ARAging returns the receivables outstanding today per client, aged by days past due
+func (s *Service) ARAging(ctx context.Context, tenantID uuid.UUID) (*AgingReport, error) {
+	open, err := s.invoiceRepo.ListOpenReceivables(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list receivables: %w", err)
+	}
+	return buildAging(open, time.Now()), nil
+}
+
+func buildAging(open []billing.OpenReceivable, asOf time.Time) *AgingReport {
+	report := &AgingReport{AsOf: asOf, Buckets: AgingBuckets, Rows: []AgingRow{}}
+	index := map[uuid.UUID]int{}
+	for _, o := range open {
+		i, ok := index[o.ClientID]
+		if !ok {
+			i = len(report.Rows)
+			index[o.ClientID] = i
+			report.Rows = append(report.Rows, AgingRow{
+				ClientID:        o.ClientID,
+				ClientName:      o.ClientName,
+				ClientGroupName: o.ClientGroupName,
+				OldestDueDate:   o.DueDate,
+			})
+		}
+		row := &report.Rows[i]
+		b := agingBucket(daysPastDue(o.DueDate, asOf))
+		row.Invoices++
+		row.Buckets[b] += o.Outstanding
+		row.Total += o.Outstanding
+		if o.DueDate.Before(row.OldestDueDate) {
+			row.OldestDueDate = o.DueDate
+		}
+		report.Totals[b] += o.Outstanding
+		report.Total += o.Outstanding
+	}
+	return report
+}
+
+// ========== Revenue ==========
+
+type RevenueReport struct {
+	By     billing.RevenueDimension `json:"by"`
+	Basis  billing.RevenueBasis     `json:"basis"`
+	From   time.Time                `json:"from"`
+	To     time.Time                `json:"to"` // exclusive
+	Rows   []billing.RevenueGroup   `json:"rows"`
+	Amount int64                    `json:"amount"`
+	Tax    int64                    `json:"tax"`
+}
+
+// Revenue groups the revenue of [from, to) by package, client group, router or collector. Cash
+// basis counts payments by date received, accrual basis issued invoices by service period start.
+// Clients are grouped by their current package, group and router.
+func (s *Service) Revenue(ctx context.Context, tenantID uuid.UUID, by billing.RevenueDimension, basis billing.RevenueBasis, from, to time.Time) (*RevenueReport, error) {
+	switch by {
+	case billing.RevenueByPackage, billing.RevenueByGroup, billing.RevenueByRouter, billing.RevenueByCollector:
+	default:
+		return nil, ErrDimensionInvalid
+	}
+	if basis == "" {
+		basis = billing.RevenueBasisCash
+	}
+	if basis != billing.RevenueBasisCash && (basis != billing.RevenueBasisAccrual || by == billing.RevenueByCollector) {
+		return nil, ErrBasisInvalid
+	}
+	if !to.After(from) || to.Sub(from) > maxReportPeriod {
+		return nil, ErrPeriodInvalid
+	}
+
+	var rows []billing.RevenueGroup
+	var err error
+	if basis == billing.RevenueBasisAccrual {
+		rows, err = s.invoiceRepo.AccruedRevenueBy(ctx, tenantID, by, from, to)
+	} else {
+		rows, err = s.paymentRepo.CollectedRevenueBy(ctx, tenantID, by, from, to)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load revenue: %w", err)
+	}
+	report := &RevenueReport{By: by, Basis: basis, From: from, To: to, Rows: rows}
+	if report.Rows == nil {
+		report.Rows = []billing.RevenueGroup{}
+	}
+	for _, r := range rows {
+		report.Amount += r.Amount
+		report.Tax += r.Tax
+	}
+	return report, nil
+}
+
+// MonthlyRevenueRow compares both bases for one month
+type MonthlyRevenueRow struct {
+	Month      time.Time `json:"month"`
+	Accrued    int64     `json:"accrued"` // invoiced for service periods starting this month
+	AccruedTax int64     `json:"accrued_tax"`
+	Invoices   int       `json:"invoices"`
+	Collected  int64     `json:"collected"` // payments received this month
+	Payments   int       `json:"payments"`
+}
+
+type MonthlyRevenueReport struct {
+	Year      int                 `json:"year"`
+	Rows      []MonthlyRevenueRow `json:"rows"`
+	Accrued   int64               `json:"accrued"`
+	Collected int64               `json:"collected"`
+}
+
+// MonthlyRevenue returns accrual-basis and cash-basis revenue for each month of a year
+func (s *Service) MonthlyRevenue(ctx context.Context, tenantID uuid.UUID, year int) (*MonthlyRevenueReport, error) {
+	from := time.Date(year, 1, 1, 0, 0, 0, 0, time.Local)
+	to := from.AddDate(1, 0, 0)
+	accrued, err := s.invoiceRepo.MonthlyAccrued(ctx, tenantID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load invoiced revenue: %w", err)
+	}
+	collected, err := s.paymentRepo.MonthlyCollected(ctx, tenantID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load collected revenue: %w", err)
+	}
+	return buildMonthlyRevenue(year, accrued, collected), nil
+}
+
+func buildMonthlyRevenue(year int, accrued, collected []billing.MonthlyAmount) *MonthlyRevenueReport {
+	report := &MonthlyRevenueReport{Year: year, Rows: make([]MonthlyRevenueRow, 12)}
+	for i := range report.Rows {
+		report.Rows[i].Month = time.Date(year, time.Month(i+1), 1, 0, 0, 0, 0, time.Local)
+	}
+	for _, m := range accrued {
+		if m.Month.Year() != year {
+			continue
+		}
+		row := &report.Rows[m.Month.Month()-1]
+		row.Accrued, row.AccruedTax, row.Invoices = m.Amount, m.Tax, m.Count
+		report.Accrued += m.Amount
+	}
+	for _, m := range collected {
+		if m.Month.Year() != year {
+			continue
+		}
+		row := &report.Rows[m.Month.Month()-1]
+		row.Collected, row.Payments = m.Amount, m.Count
+		report.Collected += m.Amount
+	}
+	return report
+}
@@ -0,0 +1,71 @@
+package reporting
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Table lays the aging report out for export: one row per client and a total row
+func (r *AgingReport) Table() *Table {
+	t := &Table{
+		Title:   "AR Aging " + r.AsOf.Format("2006-01-02"),
+		Columns: []string{"Client", "Group", "Invoices", "Oldest Due Date"},
+	}
+	for _, b := range r.Buckets {
+		if b == "current" {
+			t.Columns = append(t.Columns, "Current")
+			continue
+		}
+		t.Columns = append(t.Columns, b+" days")
+	}
+	t.Columns = append(t.Columns, "Total")
+
+	for _, row := range r.Rows {
+		group := ""
+		if row.ClientGroupName != nil {
+			group = *row.ClientGroupName
+		}
+		cells := []interface{}{row.ClientName, group, row.Invoices, row.OldestDueDate.Format("2006-01-02")}
+		for _, v := range row.Buckets {
+			cells = append(cells, v)
+		}
+		t.addRow(append(cells, row.Total)...)
+	}
+	total := []interface{}{"TOTAL", nil, nil, nil}
+	for _, v := range r.Totals {
+		total = append(total, v)
+	}
+	t.addRow(append(total, r.Total)...)
+	return t
+}
+
+// Table lays the revenue report out for export: one row per group and a total row
+func (r *RevenueReport) Table() *Table {
+	label := strings.ToUpper(string(r.By[:1])) + string(r.By[1:])
+	t := &Table{
+		Title:   fmt.Sprintf("Revenue by %s (%s)", r.By, r.Basis),
+		Columns: []string{label, "Amount", "Tax", "Count", "Clients"},
+	}
+	for _, row := range r.Rows {
+		name := row.Name
+		if row.ID == nil {
+			name = "(none)"
+		}
+		t.addRow(name, row.Amount, row.Tax, row.Count, row.Clients)
+	}
+	t.addRow("TOTAL", r.Amount, r.Tax, nil, nil)
+	return t
+}
+
+// Table lays the monthly revenue report out for export: one row per month and a total row
+func (r *MonthlyRevenueReport) Table() *Table {
+	t := &Table{
+		Title:   fmt.Sprintf("Monthly Revenue %d", r.Year),
+		Columns: []string{"Month", "Accrued (Invoiced)", "Accrued Tax", "Invoices", "Collected (Cash)", "Payments"},
+	}
+	for _, row := range r.Rows {
+		t.addRow(row.Month.Format("2006-01"), row.Accrued, row.AccruedTax, row.Invoices, row.Collected, row.Payments)
+	}
+	t.addRow("TOTAL", r.Accrued, nil, nil, r.Collected, nil)
+	return t
+}
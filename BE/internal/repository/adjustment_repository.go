@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"rrnet/internal/domain/billing"
+)
+
+type AdjustmentRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAdjustmentRepository(db *pgxpool.Pool) *AdjustmentRepository {
+	return &AdjustmentRepository{db: db}
+}
+
+func (r *AdjustmentRepository) Create(ctx context.Context, a *billing.Adjustment) error {
+	query := `
+		INSERT INTO billing_adjustments (
+			id, tenant_id, client_id, kind, description, amount, effective_date,
+			source_invoice_id, invoice_id, applied_at, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+	_, err := r.db.Exec(ctx, query,
+		a.ID, a.TenantID, a.ClientID, a.Kind, a.Description, a.Amount, a.EffectiveDate,
+		a.SourceInvoiceID, a.InvoiceID, a.AppliedAt, a.CreatedAt,
+	)
+	return err
+}
+
+// ListPendingByClient returns the adjustments of a client that are not billed yet, oldest first
+func (r *AdjustmentRepository) ListPendingByClient(ctx context.Context, tenantID, clientID uuid.UUID) ([]*billing.Adjustment, error) {
+	query := `
+		SELECT id, tenant_id, client_id, kind, description, amount, effective_date,
+			source_invoice_id, invoice_id, applied_at, created_at
+		FROM billing_adjustments
+		WHERE tenant_id = $1 AND client_id = $2 AND invoice_id IS NULL
+		ORDER BY effective_date, created_at
+	`
+	return r.query(ctx, query, tenantID, clientID)
+}
+
+// ListByClient returns all adjustments of a client, newest first
+func (r *AdjustmentRepository) ListByClient(ctx context.Context, tenantID, clientID uuid.UUID) ([]*billing.Adjustment, error) {
+	query := `
+		SELECT id, tenant_id, client_id, kind, description, amount, effective_date,
+			source_invoice_id, invoice_id, applied_at, created_at
+		FROM billing_adjustments
+		WHERE tenant_id = $1 AND client_id = $2
+		ORDER BY effective_date DESC, created_at DESC
+	`
+	return r.query(ctx, query, tenantID, clientID)
+}
+
+// ExistsForSourceInvoice checks whether an adjustment of the given kind was already created for an invoice's period
+func (r *AdjustmentRepository) ExistsForSourceInvoice(ctx context.Context, sourceInvoiceID uuid.UUID, kind billing.AdjustmentKind) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM billing_adjustments WHERE source_invoice_id = $1 AND kind = $2)`
+	var exists bool
+	if err := r.db.QueryRow(ctx, query, sourceInvoiceID, kind).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// MarkApplied links pending adjustments to the invoice they were billed on
+func (r *AdjustmentRepository) MarkApplied(ctx context.Context, ids []uuid.UUID, invoiceID uuid.UUID, appliedAt time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	query := `UPDATE billing_adjustments SET invoice_id = $2, applied_at = $3 WHERE id = ANY($1) AND invoice_id IS NULL`
+	_, err := r.db.Exec(ctx, query, ids, invoiceID, appliedAt)
+	return err
+}
+
+// ReleaseFromInvoice returns the adjustments billed on a cancelled invoice to pending, so the next
+// invoice bills them
+func (r *AdjustmentRepository) ReleaseFromInvoice(ctx context.Context, invoiceID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `UPDATE billing_adjustments SET invoice_id = NULL, applied_at = NULL WHERE invoice_id = $1`, invoiceID)
+	return err
+}
+
+func (r *AdjustmentRepository) query(ctx context.Context, query string, args ...interface{}) ([]*billing.Adjustment, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*billing.Adjustment
+	for rows.Next() {
+		a, err := scanAdjustment(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+func scanAdjustment(row pgx.Row) (*billing.Adjustment, error) {
+	var a billing.Adjustment
+	if err := row.Scan(
+		&a.ID, &a.TenantID, &a.ClientID, &a.Kind, &a.Description, &a.Amount, &a.EffectiveDate,
+		&a.SourceInvoiceID, &a.InvoiceID, &a.AppliedAt, &a.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
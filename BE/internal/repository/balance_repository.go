@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"rrnet/internal/domain/billing"
+)
+
+var ErrInsufficientBalance = errors.New("insufficient client balance")
+
+// BalanceRepository stores the client balance ledger and credit notes
+type BalanceRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewBalanceRepository(db *pgxpool.Pool) *BalanceRepository {
+	return &BalanceRepository{db: db}
+}
+
+// Append adds a movement to a client's ledger and sets e.BalanceAfter. Entries of one client are
+// serialized so the running balance stays consistent; a movement that would make the balance
+// negative returns ErrInsufficientBalance.
+func (r *BalanceRepository) Append(ctx context.Context, e *billing.BalanceEntry) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := appendBalanceEntry(ctx, tx, e); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// appendBalanceEntry inserts a ledger entry inside tx (shared with payment allocation)
+func appendBalanceEntry(ctx context.Context, tx pgx.Tx, e *billing.BalanceEntry) error {
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1::text))`, e.ClientID); err != nil {
+		return err
+	}
+
+	var balance int64
+	err := tx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(amount), 0) FROM client_balance_entries WHERE tenant_id = $1 AND client_id = $2
+	`, e.TenantID, e.ClientID).Scan(&balance)
+	if err != nil {
+		return err
+	}
+	if balance+e.Amount < 0 {
+		return ErrInsufficientBalance
+	}
+	e.BalanceAfter = balance + e.Amount
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO client_balance_entries (
+			id, tenant_id, client_id, entry_type, amount, balance_after,
+			invoice_id, payment_id, credit_note_id, description, created_by, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11, $12)
+	`,
+		e.ID, e.TenantID, e.ClientID, e.Type, e.Amount, e.BalanceAfter,
+		e.InvoiceID, e.PaymentID, e.CreditNoteID, e.Description, e.CreatedBy, e.CreatedAt,
+	)
+	return err
+}
+
+// GetBalance returns the current balance of a client (0 when there are no entries)
+func (r *BalanceRepository) GetBalance(ctx context.Context, tenantID, clientID uuid.UUID) (int64, error) {
+	query := `SELECT COALESCE(SUM(amount), 0) FROM client_balance_entries WHERE tenant_id = $1 AND client_id = $2`
+	var balance int64
+	if err := r.db.QueryRow(ctx, query, tenantID, clientID).Scan(&balance); err != nil {
+		return 0, err
+	}
+	return balance, nil
+}
+
+// SumAppliedToInvoice returns how much client credit was used to pay an invoice
+func (r *BalanceRepository) SumAppliedToInvoice(ctx context.Context, invoiceID uuid.UUID) (int64, error) {
+	query := `SELECT COALESCE(-SUM(amount), 0) FROM client_balance_entries WHERE invoice_id = $1 AND entry_type = 'credit_applied'`
+	var sum int64
+	if err := r.db.QueryRow(ctx, query, invoiceID).Scan(&sum); err != nil {
+		return 0, err
+	}
+	return sum, nil
+}
+
+type BalanceEntryFilter struct {
+	TenantID uuid.UUID
+	ClientID uuid.UUID
+	Page     int
+	PageSize int
+}
+
+// List returns the ledger of a client, newest first
+func (r *BalanceRepository) List(ctx context.Context, filter BalanceEntryFilter) ([]*billing.BalanceEntry, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM client_balance_entries WHERE tenant_id = $1 AND client_id = $2`
+	if err := r.db.QueryRow(ctx, countQuery, filter.TenantID, filter.ClientID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+	offset := (filter.Page - 1) * filter.PageSize
+
+	query := `
+		SELECT id, tenant_id, client_id, entry_type, amount, balance_after,
+			invoice_id, payment_id, credit_note_id, COALESCE(description, ''), created_by, created_at
+		FROM client_balance_entries
+		WHERE tenant_id = $1 AND client_id = $2
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.Query(ctx, query, filter.TenantID, filter.ClientID, filter.PageSize, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var entries []*billing.BalanceEntry
+	for rows.Next() {
+		var e billing.BalanceEntry
+		if err := rows.Scan(
+			&e.ID, &e.TenantID, &e.ClientID, &e.Type, &e.Amount, &e.BalanceAfter,
+			&e.InvoiceID, &e.PaymentID, &e.CreditNoteID, &e.Description, &e.CreatedBy, &e.CreatedAt,
+		); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, &e)
+	}
+	return entries, total, nil
+}
+
+// ========== Credit notes ==========
+
+// CreditNoteIssue is a credit note with its effect on the invoice, stored by IssueCreditNote
+type CreditNoteIssue struct {
+	CreditNote *billing.CreditNote
+	Numbering  *billing.DocumentNumbering // draws the credit note number when set
+	Item       billing.InvoiceItem        // negative line that reduces the invoice
+	// Credit (required) moves what the client paid above the reduced total to the client balance. Its
+	// amount is set when stored; it is nil afterwards when nothing was paid above the total.
+	Credit *billing.BalanceEntry
+	// Describe completes the descriptions of Item and Credit with the credit note number, which is
+	// only known inside the transaction
+	Describe func(number string) (item, credit string)
+}
+
+// IssueCreditNote stores a credit note and applies it to its invoice in one transaction: the invoice
+// is reduced by the item, the paid excess is credited to the client and the invoice is marked paid
+// once it is covered.
+func (r *BalanceRepository) IssueCreditNote(ctx context.Context, issue *CreditNoteIssue) error {
+	cn := issue.CreditNote
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if issue.Numbering != nil {
+		number, err := nextDocumentNumber(ctx, tx, cn.TenantID, *issue.Numbering)
+		if err != nil {
+			return err
+		}
+		cn.CreditNoteNumber = number
+	}
+	query := `
+		INSERT INTO credit_notes (id, tenant_id, client_id, invoice_id, credit_note_number, amount, reason, approved_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err = tx.Exec(ctx, query,
+		cn.ID, cn.TenantID, cn.ClientID, cn.InvoiceID, cn.CreditNoteNumber, cn.Amount, cn.Reason, cn.ApprovedBy, cn.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if issue.Describe != nil {
+		issue.Item.Description, issue.Credit.Description = issue.Describe(cn.CreditNoteNumber)
+	}
+	total, err := appendInvoiceItems(ctx, tx, cn.InvoiceID, []billing.InvoiceItem{issue.Item})
+	if err != nil {
+		return err
+	}
+
+	var paid int64
+	if err := tx.QueryRow(ctx, `SELECT paid_amount FROM invoices WHERE id = $1`, cn.InvoiceID).Scan(&paid); err != nil {
+		return err
+	}
+	if excess := paid - total; excess > 0 {
+		issue.Credit.Amount = excess
+		if err := appendBalanceEntry(ctx, tx, issue.Credit); err != nil {
+			return err
+		}
+		paid = total
+	} else {
+		issue.Credit = nil
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE invoices
+		SET paid_amount = $2,
+			status = CASE WHEN $2 >= total_amount THEN 'paid' ELSE status END,
+			paid_at = CASE WHEN $2 >= total_amount AND status <> 'paid' THEN $3 ELSE paid_at END,
+			updated_at = NOW()
+		WHERE id = $1
+	`, cn.InvoiceID, paid, cn.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *BalanceRepository) ListCreditNotesByInvoice(ctx context.Context, tenantID, invoiceID uuid.UUID) ([]*billing.CreditNote, error) {
+	query := `
+		SELECT id, tenant_id, client_id, invoice_id, credit_note_number, amount, reason, approved_by, created_at
+		FROM credit_notes
+		WHERE tenant_id = $1 AND invoice_id = $2
+		ORDER BY created_at
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []*billing.CreditNote
+	for rows.Next() {
+		var cn billing.CreditNote
+		if err := rows.Scan(
+			&cn.ID, &cn.TenantID, &cn.ClientID, &cn.InvoiceID, &cn.CreditNoteNumber, &cn.Amount, &cn.Reason, &cn.ApprovedBy, &cn.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		notes = append(notes, &cn)
+	}
+	return notes, nil
+}
@@ -0,0 +1,348 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"rrnet/internal/domain/billing"
+)
+
+var (
+	ErrBankStatementNotFound     = errors.New("bank statement not found")
+	ErrBankStatementLineNotFound = errors.New("bank statement line not found")
+)
+
+// BankStatementRepository stores imported bank statements and the reconciliation of their credits
+type BankStatementRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewBankStatementRepository(db *pgxpool.Pool) *BankStatementRepository {
+	return &BankStatementRepository{db: db}
+}
+
+const bankStatementColumns = `
+	s.id, s.tenant_id, s.bank, COALESCE(s.account_number, ''), s.file_name, s.period_start, s.period_end,
+	s.credit_count, s.debit_count, s.duplicate_count, s.imported_by, s.created_at,
+	COUNT(l.id) FILTER (WHERE l.status = 'auto_matched'),
+	COUNT(l.id) FILTER (WHERE l.status = 'suggested'),
+	COUNT(l.id) FILTER (WHERE l.status = 'unmatched'),
+	COUNT(l.id) FILTER (WHERE l.status = 'confirmed'),
+	COUNT(l.id) FILTER (WHERE l.status = 'ignored')
+`
+
+func scanBankStatement(row pgx.Row) (*billing.BankStatement, error) {
+	var s billing.BankStatement
+	err := row.Scan(
+		&s.ID, &s.TenantID, &s.Bank, &s.AccountNumber, &s.FileName, &s.PeriodStart, &s.PeriodEnd,
+		&s.CreditCount, &s.DebitCount, &s.DuplicateCount, &s.ImportedBy, &s.CreatedAt,
+		&s.AutoMatched, &s.Suggested, &s.Unmatched, &s.Confirmed, &s.Ignored,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrBankStatementNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+const bankLineColumns = `
+	id, tenant_id, statement_id, row_number, txn_date, description, COALESCE(reference, ''), amount, balance,
+	fingerprint, status, invoice_id, client_id, score, candidates, payment_id, confirmed_by, confirmed_at,
+	created_at, updated_at
+`
+
+func scanBankLine(row pgx.Row) (*billing.BankStatementLine, error) {
+	var l billing.BankStatementLine
+	var candidates []byte
+	err := row.Scan(
+		&l.ID, &l.TenantID, &l.StatementID, &l.Row, &l.TxnDate, &l.Description, &l.Reference, &l.Amount, &l.Balance,
+		&l.Fingerprint, &l.Status, &l.InvoiceID, &l.ClientID, &l.Score, &candidates, &l.PaymentID, &l.ConfirmedBy, &l.ConfirmedAt,
+		&l.CreatedAt, &l.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrBankStatementLineNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(candidates, &l.Candidates); err != nil {
+		return nil, err
+	}
+	if l.Candidates == nil {
+		l.Candidates = []billing.BankMatchCandidate{}
+	}
+	return &l, nil
+}
+
+func marshalCandidates(c []billing.BankMatchCandidate) ([]byte, error) {
+	if c == nil {
+		c = []billing.BankMatchCandidate{}
+	}
+	return json.Marshal(c)
+}
+
+// KnownFingerprints returns the fingerprints that were already imported
+func (r *BankStatementRepository) KnownFingerprints(ctx context.Context, tenantID uuid.UUID, fingerprints []string) (map[string]bool, error) {
+	out := map[string]bool{}
+	if len(fingerprints) == 0 {
+		return out, nil
+	}
+	rows, err := r.db.Query(ctx, `
+		SELECT fingerprint FROM bank_statement_lines WHERE tenant_id = $1 AND fingerprint = ANY($2)
+	`, tenantID, fingerprints)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var f string
+		if err := rows.Scan(&f); err != nil {
+			return nil, err
+		}
+		out[f] = true
+	}
+	return out, rows.Err()
+}
+
+// Create stores a statement with its credits in one transaction. Lines whose fingerprint was stored
+// concurrently are skipped and counted as duplicates.
+func (r *BankStatementRepository) Create(ctx context.Context, s *billing.BankStatement, lines []*billing.BankStatementLine) ([]*billing.BankStatementLine, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO bank_statements (
+			id, tenant_id, bank, account_number, file_name, period_start, period_end,
+			credit_count, debit_count, duplicate_count, imported_by, created_at
+		) VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9, $10, $11, $12)
+	`, s.ID, s.TenantID, s.Bank, s.AccountNumber, s.FileName, s.PeriodStart, s.PeriodEnd,
+		s.CreditCount, s.DebitCount, s.DuplicateCount, s.ImportedBy, s.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bank statement: %w", err)
+	}
+
+	stored := make([]*billing.BankStatementLine, 0, len(lines))
+	for _, l := range lines {
+		candidates, err := marshalCandidates(l.Candidates)
+		if err != nil {
+			return nil, err
+		}
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO bank_statement_lines (
+				id, tenant_id, statement_id, row_number, txn_date, description, reference, amount, balance,
+				fingerprint, status, invoice_id, client_id, score, candidates, created_at, updated_at
+			) VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10, $11, $12, $13, $14, $15, $16, $16)
+			ON CONFLICT (tenant_id, fingerprint) DO NOTHING
+		`, l.ID, l.TenantID, l.StatementID, l.Row, l.TxnDate, l.Description, l.Reference, l.Amount, l.Balance,
+			l.Fingerprint, l.Status, l.InvoiceID, l.ClientID, l.Score, candidates, l.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create bank statement line: %w", err)
+		}
+		if tag.RowsAffected() == 1 {
+			stored = append(stored, l)
+		}
+	}
+	if skipped := len(lines) - len(stored); skipped > 0 {
+		s.CreditCount -= skipped
+		s.DuplicateCount += skipped
+		if _, err := tx.Exec(ctx, `
+			UPDATE bank_statements SET credit_count = $2, duplicate_count = $3 WHERE id = $1
+		`, s.ID, s.CreditCount, s.DuplicateCount); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+func (r *BankStatementRepository) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*billing.BankStatement, error) {
+	query := `
+		SELECT ` + bankStatementColumns + `
+		FROM bank_statements s
+		LEFT JOIN bank_statement_lines l ON l.statement_id = s.id
+		WHERE s.tenant_id = $1 AND s.id = $2
+		GROUP BY s.id
+	`
+	return scanBankStatement(r.db.QueryRow(ctx, query, tenantID, id))
+}
+
+func (r *BankStatementRepository) List(ctx context.Context, tenantID uuid.UUID, page, pageSize int) ([]*billing.BankStatement, int, error) {
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM bank_statements WHERE tenant_id = $1`, tenantID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+	query := `
+		SELECT ` + bankStatementColumns + `
+		FROM bank_statements s
+		LEFT JOIN bank_statement_lines l ON l.statement_id = s.id
+		WHERE s.tenant_id = $1
+		GROUP BY s.id
+		ORDER BY s.created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	out := []*billing.BankStatement{}
+	for rows.Next() {
+		s, err := scanBankStatement(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, s)
+	}
+	return out, total, rows.Err()
+}
+
+// ListLines returns the credits of a statement, optionally only those with the given status
+func (r *BankStatementRepository) ListLines(ctx context.Context, tenantID, statementID uuid.UUID, status *billing.BankLineStatus) ([]*billing.BankStatementLine, error) {
+	query := `SELECT ` + bankLineColumns + ` FROM bank_statement_lines WHERE tenant_id = $1 AND statement_id = $2`
+	args := []interface{}{tenantID, statementID}
+	if status != nil {
+		query += ` AND status = $3`
+		args = append(args, *status)
+	}
+	query += ` ORDER BY txn_date, row_number`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*billing.BankStatementLine{}
+	for rows.Next() {
+		l, err := scanBankLine(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
+
+func (r *BankStatementRepository) GetLine(ctx context.Context, tenantID, id uuid.UUID) (*billing.BankStatementLine, error) {
+	query := `SELECT ` + bankLineColumns + ` FROM bank_statement_lines WHERE tenant_id = $1 AND id = $2`
+	return scanBankLine(r.db.QueryRow(ctx, query, tenantID, id))
+}
+
+// UpdateMatch stores a new matching result for a line that is still open
+func (r *BankStatementRepository) UpdateMatch(ctx context.Context, l *billing.BankStatementLine) error {
+	candidates, err := marshalCandidates(l.Candidates)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(ctx, `
+		UPDATE bank_statement_lines
+		SET status = $2, invoice_id = $3, client_id = $4, score = $5, candidates = $6, updated_at = NOW()
+		WHERE id = $1 AND status IN ('auto_matched', 'suggested', 'unmatched')
+	`, l.ID, l.Status, l.InvoiceID, l.ClientID, l.Score, candidates)
+	return err
+}
+
+// ClaimConfirm marks a line confirmed for the given invoice/client. Returns false when it was
+// confirmed already, so a payment is recorded once per bank credit.
+func (r *BankStatementRepository) ClaimConfirm(ctx context.Context, id, userID uuid.UUID, invoiceID *uuid.UUID, clientID uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE bank_statement_lines
+		SET status = 'confirmed', invoice_id = $3, client_id = $4, confirmed_by = $2, confirmed_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status <> 'confirmed'
+	`, id, userID, invoiceID, clientID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ReleaseConfirm restores a line claimed by ClaimConfirm when the payment could not be recorded
+func (r *BankStatementRepository) ReleaseConfirm(ctx context.Context, l *billing.BankStatementLine) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE bank_statement_lines
+		SET status = $2, invoice_id = $3, client_id = $4, confirmed_by = NULL, confirmed_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND payment_id IS NULL
+	`, l.ID, l.Status, l.InvoiceID, l.ClientID)
+	return err
+}
+
+func (r *BankStatementRepository) SetPayment(ctx context.Context, id, paymentID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `UPDATE bank_statement_lines SET payment_id = $2, updated_at = NOW() WHERE id = $1`, id, paymentID)
+	return err
+}
+
+// SetIgnored marks an open line as not being a client payment (or reopens an ignored one)
+func (r *BankStatementRepository) SetIgnored(ctx context.Context, tenantID, id uuid.UUID, ignored bool) error {
+	query := `
+		UPDATE bank_statement_lines SET status = 'ignored', updated_at = NOW()
+		WHERE tenant_id = $1 AND id = $2 AND status IN ('auto_matched', 'suggested', 'unmatched')
+	`
+	if !ignored {
+		query = `
+			UPDATE bank_statement_lines SET status = 'unmatched', updated_at = NOW()
+			WHERE tenant_id = $1 AND id = $2 AND status = 'ignored'
+		`
+	}
+	tag, err := r.db.Exec(ctx, query, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrBankStatementLineNotFound
+	}
+	return nil
+}
+
+// ListOpenInvoices returns the pending and overdue invoices of a tenant with what is left to pay
+// and the client details bank descriptions are matched against. The unique code only identifies a
+// transfer of the full amount, so it is left out once an invoice is partly paid.
+func (r *BankStatementRepository) ListOpenInvoices(ctx context.Context, tenantID uuid.UUID) ([]billing.ReconcileInvoice, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT i.id, i.client_id, i.invoice_number, c.client_code, c.name, COALESCE(c.phone, ''),
+			i.total_amount - i.paid_amount,
+			CASE WHEN i.paid_amount = 0 THEN COALESCE(i.unique_code, 0) ELSE 0 END,
+			i.total_amount - i.paid_amount + CASE WHEN i.unique_code_in_total THEN 0 ELSE COALESCE(i.unique_code, 0) END,
+			i.period_start, i.due_date, i.created_at
+		FROM invoices i
+		INNER JOIN clients c ON c.id = i.client_id
+		WHERE i.tenant_id = $1 AND i.status IN ('pending', 'overdue') AND i.total_amount > i.paid_amount
+		ORDER BY i.due_date, i.created_at
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []billing.ReconcileInvoice
+	for rows.Next() {
+		var inv billing.ReconcileInvoice
+		if err := rows.Scan(
+			&inv.InvoiceID, &inv.ClientID, &inv.InvoiceNumber, &inv.ClientCode, &inv.ClientName, &inv.ClientPhone,
+			&inv.Outstanding, &inv.UniqueCode, &inv.Payable, &inv.PeriodStart, &inv.DueDate, &inv.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, inv)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"rrnet/internal/domain/billing"
+)
+
+var (
+	ErrBulkRunNotFound = errors.New("bulk invoice run not found")
+	// ErrBulkRunInProgress is returned when the tenant already has a queued or running bulk run
+	ErrBulkRunInProgress = errors.New("another bulk invoice run is in progress")
+)
+
+// BulkInvoiceRunRepository stores bulk invoice generation runs and the outcome per client
+type BulkInvoiceRunRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewBulkInvoiceRunRepository(db *pgxpool.Pool) *BulkInvoiceRunRepository {
+	return &BulkInvoiceRunRepository{db: db}
+}
+
+// Run counters are derived from the items
+const bulkRunColumns = `
+	r.id, r.tenant_id, r.period_start, r.filter, r.status, r.error, r.created_by,
+	r.started_at, r.finished_at, r.undone_by, r.undone_at, r.created_at,
+	COUNT(i.id),
+	COUNT(i.id) FILTER (WHERE i.status <> 'pending'),
+	COUNT(i.id) FILTER (WHERE i.status = 'created'),
+	COUNT(i.id) FILTER (WHERE i.status = 'skipped'),
+	COUNT(i.id) FILTER (WHERE i.status = 'failed'),
+	COUNT(i.id) FILTER (WHERE i.status = 'cancelled'),
+	COALESCE(SUM(i.amount) FILTER (WHERE i.status = 'created'), 0)
+`
+
+func scanBulkRun(row pgx.Row) (*billing.BulkRun, error) {
+	var run billing.BulkRun
+	err := row.Scan(
+		&run.ID, &run.TenantID, &run.PeriodStart, &run.Filter, &run.Status, &run.Error, &run.CreatedBy,
+		&run.StartedAt, &run.FinishedAt, &run.UndoneBy, &run.UndoneAt, &run.CreatedAt,
+		&run.Total, &run.Processed, &run.Created, &run.Skipped, &run.Failed, &run.Cancelled, &run.TotalAmount,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrBulkRunNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// Create stores a queued run; it fails with ErrBulkRunInProgress while another run of the tenant
+// is queued or running
+func (r *BulkInvoiceRunRepository) Create(ctx context.Context, run *billing.BulkRun) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO bulk_invoice_runs (id, tenant_id, period_start, filter, status, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, run.ID, run.TenantID, run.PeriodStart, run.Filter, run.Status, run.CreatedBy, run.CreatedAt)
+	if isUniqueViolation(err, "idx_bulk_invoice_runs_active") {
+		return ErrBulkRunInProgress
+	}
+	return err
+}
+
+func (r *BulkInvoiceRunRepository) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*billing.BulkRun, error) {
+	return scanBulkRun(r.db.QueryRow(ctx, `
+		SELECT `+bulkRunColumns+`
+		FROM bulk_invoice_runs r
+		LEFT JOIN bulk_invoice_run_items i ON i.run_id = r.id
+		WHERE r.id = $1 AND r.tenant_id = $2
+		GROUP BY r.id
+	`, id, tenantID))
+}
+
+// List returns the runs of a tenant, newest first
+func (r *BulkInvoiceRunRepository) List(ctx context.Context, tenantID uuid.UUID, page, pageSize int) ([]*billing.BulkRun, int, error) {
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM bulk_invoice_runs WHERE tenant_id = $1`, tenantID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	rows, err := r.db.Query(ctx, `
+		SELECT `+bulkRunColumns+`
+		FROM bulk_invoice_runs r
+		LEFT JOIN bulk_invoice_run_items i ON i.run_id = r.id
+		WHERE r.tenant_id = $1
+		GROUP BY r.id
+		ORDER BY r.created_at DESC
+		LIMIT $2 OFFSET $3
+	`, tenantID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	runs := []*billing.BulkRun{}
+	for rows.Next() {
+		run, err := scanBulkRun(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, total, rows.Err()
+}
+
+// SetStatus moves a run to status; starting a run sets started_at, finishing it sets finished_at
+func (r *BulkInvoiceRunRepository) SetStatus(ctx context.Context, id uuid.UUID, status billing.BulkRunStatus, runErr *string) error {
+	now := time.Now()
+	var startedAt, finishedAt *time.Time
+	switch status {
+	case billing.BulkRunRunning:
+		startedAt = &now
+	case billing.BulkRunCompleted, billing.BulkRunFailed:
+		finishedAt = &now
+	}
+	_, err := r.db.Exec(ctx, `
+		UPDATE bulk_invoice_runs
+		SET status = $2, error = $3, started_at = COALESCE(started_at, $4), finished_at = $5
+		WHERE id = $1
+	`, id, status, runErr, startedAt, finishedAt)
+	return err
+}
+
+// MarkUndone records the undo of a run
+func (r *BulkInvoiceRunRepository) MarkUndone(ctx context.Context, id, undoneBy uuid.UUID, at time.Time) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE bulk_invoice_runs SET status = 'undone', undone_by = $2, undone_at = $3 WHERE id = $1
+	`, id, undoneBy, at)
+	return err
+}
+
+// AddItems stores the selected clients of a run as pending items; clients already in the run (a
+// retried job) are kept as they are
+func (r *BulkInvoiceRunRepository) AddItems(ctx context.Context, runID uuid.UUID, items []*billing.BulkRunItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	batch := &pgx.Batch{}
+	for _, it := range items {
+		batch.Queue(`
+			INSERT INTO bulk_invoice_run_items (id, run_id, client_id, client_code, client_name, status)
+			VALUES ($1, $2, $3, $4, $5, 'pending')
+			ON CONFLICT (run_id, client_id) DO NOTHING
+		`, uuid.New(), runID, it.ClientID, it.ClientCode, it.ClientName)
+	}
+	return r.db.SendBatch(ctx, batch).Close()
+}
+
+// UpdateItem stores the outcome of a client
+func (r *BulkInvoiceRunRepository) UpdateItem(ctx context.Context, it *billing.BulkRunItem) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE bulk_invoice_run_items
+		SET status = $2, period_start = $3, period_end = $4, due_date = $5, amount = $6, invoice_id = $7,
+			reason = NULLIF($8, ''), updated_at = NOW()
+		WHERE id = $1
+	`, it.ID, it.Status, it.PeriodStart, it.PeriodEnd, it.DueDate, it.Amount, it.InvoiceID, it.Reason)
+	return err
+}
+
+// BulkRunItemFilter narrows the items of a run
+type BulkRunItemFilter struct {
+	Status   *billing.BulkItemStatus
+	Page     int
+	PageSize int // 0 lists every item
+}
+
+// ListItems returns the items of a run ordered by client code
+func (r *BulkInvoiceRunRepository) ListItems(ctx context.Context, runID uuid.UUID, filter BulkRunItemFilter) ([]*billing.BulkRunItem, int, error) {
+	where := `WHERE run_id = $1`
+	args := []any{runID}
+	argN := 2
+	if filter.Status != nil {
+		where += fmt.Sprintf(` AND status = $%d`, argN)
+		args = append(args, string(*filter.Status))
+		argN++
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM bulk_invoice_run_items `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	q := `
+		SELECT id, run_id, client_id, client_code, client_name, status, period_start, period_end, due_date,
+			amount, invoice_id, COALESCE(reason, ''), updated_at
+		FROM bulk_invoice_run_items
+		` + where + ` ORDER BY client_code, id`
+	if filter.PageSize > 0 {
+		if filter.Page < 1 {
+			filter.Page = 1
+		}
+		q += fmt.Sprintf(` LIMIT $%d OFFSET $%d`, argN, argN+1)
+		args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	}
+
+	rows, err := r.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	items := []*billing.BulkRunItem{}
+	for rows.Next() {
+		var it billing.BulkRunItem
+		if err := rows.Scan(
+			&it.ID, &it.RunID, &it.ClientID, &it.ClientCode, &it.ClientName, &it.Status, &it.PeriodStart, &it.PeriodEnd, &it.DueDate,
+			&it.Amount, &it.InvoiceID, &it.Reason, &it.UpdatedAt,
+		); err != nil {
+			return nil, 0, err
+		}
+		items = append(items, &it)
+	}
+	return items, total, rows.Err()
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -38,11 +39,12 @@ func (r *ClientRepository) Create(ctx context.Context, c *client.Client) error {
 			category, connection_type, router_id, pppoe_username, pppoe_local_address, pppoe_remote_address, pppoe_comment,
 			service_package_id, voucher_package_id, device_count, pppoe_password_enc, pppoe_password_updated_at,
 			service_plan, speed_profile, monthly_fee, billing_date,
-			payment_tempo_option, payment_due_day, payment_tempo_template_id,
+			payment_tempo_option, payment_due_day, payment_tempo_template_id, billing_mode,
+			billing_cycle_months, billing_cycle_anchor,
 			status, ip_address, mac_address, metadata,
 			created_at, updated_at
 		)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23,$24,$25,$26,$27,$28,$29,$30,$31,$32,$33,$34,$35,$36,$37)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23,$24,$25,$26,$27,$28,$29,$30,$31,$32,$33,$34,$35,$36,$37,$38,$39,$40)
 	`
 	_, err := r.db.Exec(ctx, query,
 		c.ID, c.TenantID, c.UserID, c.ClientCode, c.Name, c.Email, c.Phone, c.Address,
@@ -50,7 +52,8 @@ func (r *ClientRepository) Create(ctx context.Context, c *client.Client) error {
 		c.Category, c.ConnectionType, c.RouterID, c.PPPoEUsername, c.PPPoELocalAddress, c.PPPoERemoteAddress, c.PPPoEComment,
 		c.ServicePackageID, c.VoucherPackageID, c.DeviceCount, c.PPPoEPasswordEnc, c.PPPoEPasswordUpdatedAt,
 		c.ServicePlan, c.SpeedProfile, c.MonthlyFee, c.BillingDate,
-		c.PaymentTempoOption, c.PaymentDueDay, c.PaymentTempoTemplateID,
+		c.PaymentTempoOption, c.PaymentDueDay, c.PaymentTempoTemplateID, c.BillingMode,
+		c.BillingCycleMonths, c.BillingCycleAnchor,
 		c.Status, c.IPAddress, c.MACAddress, c.Metadata,
 		c.CreatedAt, c.UpdatedAt,
 	)
@@ -65,7 +68,8 @@ func (r *ClientRepository) GetByID(ctx context.Context, tenantID, clientID uuid.
 			   category, connection_type, router_id, pppoe_username, pppoe_local_address, pppoe_remote_address, pppoe_comment,
 			   service_package_id, voucher_package_id, device_count, pppoe_password_enc, pppoe_password_updated_at,
 			   service_plan, speed_profile, monthly_fee, billing_date,
-			   payment_tempo_option, payment_due_day, payment_tempo_template_id,
+			   payment_tempo_option, payment_due_day, payment_tempo_template_id, billing_mode, active_until,
+			   billing_cycle_months, billing_cycle_anchor,
 			   status, isolir_reason, isolir_at,
 			   ip_address, mac_address, metadata, created_at, updated_at, deleted_at
 		FROM clients
@@ -82,7 +86,8 @@ func (r *ClientRepository) GetByCode(ctx context.Context, tenantID uuid.UUID, cl
 			   category, connection_type, router_id, pppoe_username, pppoe_local_address, pppoe_remote_address, pppoe_comment,
 			   service_package_id, voucher_package_id, device_count, pppoe_password_enc, pppoe_password_updated_at,
 			   service_plan, speed_profile, monthly_fee, billing_date,
-			   payment_tempo_option, payment_due_day, payment_tempo_template_id,
+			   payment_tempo_option, payment_due_day, payment_tempo_template_id, billing_mode, active_until,
+			   billing_cycle_months, billing_cycle_anchor,
 			   status, isolir_reason, isolir_at,
 			   ip_address, mac_address, metadata, created_at, updated_at, deleted_at
 		FROM clients
@@ -118,6 +123,16 @@ func (r *ClientRepository) List(ctx context.Context, tenantID uuid.UUID, filter
 		args = append(args, *filter.GroupID)
 		argNum++
 	}
+	if filter.ServicePackageID != nil {
+		baseQuery += fmt.Sprintf(` AND service_package_id = $%d`, argNum)
+		args = append(args, *filter.ServicePackageID)
+		argNum++
+	}
+	if filter.RouterID != nil {
+		baseQuery += fmt.Sprintf(` AND router_id = $%d`, argNum)
+		args = append(args, *filter.RouterID)
+		argNum++
+	}
 
 	// Count total
 	countQuery := `SELECT COUNT(*) ` + baseQuery
@@ -144,7 +159,8 @@ func (r *ClientRepository) List(ctx context.Context, tenantID uuid.UUID, filter
 			   category, connection_type, router_id, pppoe_username, pppoe_local_address, pppoe_remote_address, pppoe_comment,
 			   service_package_id, voucher_package_id, device_count, pppoe_password_enc, pppoe_password_updated_at,
 			   service_plan, speed_profile, monthly_fee, billing_date,
-			   payment_tempo_option, payment_due_day, payment_tempo_template_id,
+			   payment_tempo_option, payment_due_day, payment_tempo_template_id, billing_mode, active_until,
+			   billing_cycle_months, billing_cycle_anchor,
 			   status, isolir_reason, isolir_at,
 			   ip_address, mac_address, metadata, created_at, updated_at, deleted_at
 		` + baseQuery + fmt.Sprintf(` ORDER BY created_at DESC LIMIT $%d OFFSET $%d`, argNum, argNum+1)
@@ -177,7 +193,8 @@ func (r *ClientRepository) ListByGroupID(ctx context.Context, tenantID, groupID
 			   category, connection_type, router_id, pppoe_username, pppoe_local_address, pppoe_remote_address, pppoe_comment,
 			   service_package_id, voucher_package_id, device_count, pppoe_password_enc, pppoe_password_updated_at,
 			   service_plan, speed_profile, monthly_fee, billing_date,
-			   payment_tempo_option, payment_due_day, payment_tempo_template_id,
+			   payment_tempo_option, payment_due_day, payment_tempo_template_id, billing_mode, active_until,
+			   billing_cycle_months, billing_cycle_anchor,
 			   status, isolir_reason, isolir_at,
 			   ip_address, mac_address, metadata, created_at, updated_at, deleted_at
 		FROM clients
@@ -201,6 +218,44 @@ func (r *ClientRepository) ListByGroupID(ctx context.Context, tenantID, groupID
 	return out, nil
 }
 
+// ListPrepaidActiveUntil returns active prepaid clients (by their own or their package's billing
+// mode) whose service validity ends on or before until
+func (r *ClientRepository) ListPrepaidActiveUntil(ctx context.Context, tenantID uuid.UUID, until time.Time) ([]*client.Client, error) {
+	query := `
+		SELECT id, tenant_id, user_id, client_code, name, email, phone, address,
+			   latitude, longitude, odp_id, group_id, discount_id,
+			   category, connection_type, router_id, pppoe_username, pppoe_local_address, pppoe_remote_address, pppoe_comment,
+			   service_package_id, voucher_package_id, device_count, pppoe_password_enc, pppoe_password_updated_at,
+			   service_plan, speed_profile, monthly_fee, billing_date,
+			   payment_tempo_option, payment_due_day, payment_tempo_template_id, billing_mode, active_until,
+			   billing_cycle_months, billing_cycle_anchor,
+			   status, isolir_reason, isolir_at,
+			   ip_address, mac_address, metadata, created_at, updated_at, deleted_at
+		FROM clients
+		WHERE tenant_id = $1 AND status = 'active' AND deleted_at IS NULL
+		  AND active_until IS NOT NULL AND active_until <= $2
+		  AND COALESCE(billing_mode,
+		               (SELECT p.billing_mode FROM service_packages p WHERE p.id = clients.service_package_id),
+		               'postpaid') = 'prepaid'
+		ORDER BY active_until, client_code
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*client.Client
+	for rows.Next() {
+		c, err := r.scanClientFromRows(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
 // Update updates a client
 func (r *ClientRepository) Update(ctx context.Context, c *client.Client) error {
 	query := `
@@ -214,7 +269,8 @@ func (r *ClientRepository) Update(ctx context.Context, c *client.Client) error {
 			service_plan = $25, speed_profile = $26, monthly_fee = $27, billing_date = $28,
 			payment_tempo_option = $29, payment_due_day = $30, payment_tempo_template_id = $31,
 			ip_address = $32, mac_address = $33,
-			metadata = $34, updated_at = NOW()
+			metadata = $34, billing_mode = $35,
+			billing_cycle_months = $36, billing_cycle_anchor = $37, updated_at = NOW()
 		WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
 	`
 	result, err := r.db.Exec(ctx, query,
@@ -226,7 +282,8 @@ func (r *ClientRepository) Update(ctx context.Context, c *client.Client) error {
 		c.PPPoEPasswordEnc, c.PPPoEPasswordUpdatedAt,
 		c.ServicePlan, c.SpeedProfile, c.MonthlyFee, c.BillingDate,
 		c.PaymentTempoOption, c.PaymentDueDay, c.PaymentTempoTemplateID,
-		c.IPAddress, c.MACAddress, c.Metadata,
+		c.IPAddress, c.MACAddress, c.Metadata, c.BillingMode,
+		c.BillingCycleMonths, c.BillingCycleAnchor,
 	)
 	if err != nil {
 		return err
@@ -268,6 +325,37 @@ func (r *ClientRepository) UpdateStatus(ctx context.Context, tenantID, clientID
 	return nil
 }
 
+// SetActiveUntil sets the end of a client's prepaid service validity (nil clears it)
+func (r *ClientRepository) SetActiveUntil(ctx context.Context, tenantID, clientID uuid.UUID, activeUntil *time.Time) error {
+	query := `UPDATE clients SET active_until = $3, updated_at = NOW() WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL`
+	result, err := r.db.Exec(ctx, query, clientID, tenantID, activeUntil)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrClientNotFound
+	}
+	return nil
+}
+
+// ExtendActiveUntil moves a client's validity to until unless it already runs longer, so applying
+// the same paid period twice has no effect
+func (r *ClientRepository) ExtendActiveUntil(ctx context.Context, tenantID, clientID uuid.UUID, until time.Time) error {
+	query := `
+		UPDATE clients
+		SET active_until = GREATEST(COALESCE(active_until, $3::date), $3::date), updated_at = NOW()
+		WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
+	`
+	result, err := r.db.Exec(ctx, query, clientID, tenantID, until)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrClientNotFound
+	}
+	return nil
+}
+
 // SoftDelete soft deletes a client
 func (r *ClientRepository) SoftDelete(ctx context.Context, tenantID, clientID uuid.UUID) error {
 	query := `UPDATE clients SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL`
@@ -327,7 +415,8 @@ func (r *ClientRepository) scanClient(row pgx.Row) (*client.Client, error) {
 		&c.Category, &c.ConnectionType, &c.RouterID, &c.PPPoEUsername, &c.PPPoELocalAddress, &c.PPPoERemoteAddress, &c.PPPoEComment,
 		&c.ServicePackageID, &c.VoucherPackageID, &c.DeviceCount, &c.PPPoEPasswordEnc, &c.PPPoEPasswordUpdatedAt,
 		&c.ServicePlan, &c.SpeedProfile, &c.MonthlyFee, &c.BillingDate,
-		&c.PaymentTempoOption, &c.PaymentDueDay, &c.PaymentTempoTemplateID,
+		&c.PaymentTempoOption, &c.PaymentDueDay, &c.PaymentTempoTemplateID, &c.BillingMode, &c.ActiveUntil,
+		&c.BillingCycleMonths, &c.BillingCycleAnchor,
 		&c.Status, &c.IsolirReason, &c.IsolirAt,
 		&ipAddress, &macAddress, &c.Metadata, &c.CreatedAt, &c.UpdatedAt, &c.DeletedAt,
 	)
@@ -359,7 +448,8 @@ func (r *ClientRepository) scanClientFromRows(rows pgx.Rows) (*client.Client, er
 		&c.Category, &c.ConnectionType, &c.RouterID, &c.PPPoEUsername, &c.PPPoELocalAddress, &c.PPPoERemoteAddress, &c.PPPoEComment,
 		&c.ServicePackageID, &c.VoucherPackageID, &c.DeviceCount, &c.PPPoEPasswordEnc, &c.PPPoEPasswordUpdatedAt,
 		&c.ServicePlan, &c.SpeedProfile, &c.MonthlyFee, &c.BillingDate,
-		&c.PaymentTempoOption, &c.PaymentDueDay, &c.PaymentTempoTemplateID,
+		&c.PaymentTempoOption, &c.PaymentDueDay, &c.PaymentTempoTemplateID, &c.BillingMode, &c.ActiveUntil,
+		&c.BillingCycleMonths, &c.BillingCycleAnchor,
 		&c.Status, &c.IsolirReason, &c.IsolirAt,
 		&ipAddress, &macAddress, &c.Metadata, &c.CreatedAt, &c.UpdatedAt, &c.DeletedAt,
 	)
@@ -0,0 +1,456 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"rrnet/internal/domain/collector"
+)
+
+var (
+	ErrCollectorAssignmentNotFound = errors.New("collector assignment not found")
+	// ErrCollectorAssignmentExists is returned when the invoice is already in the hands of a collector
+	ErrCollectorAssignmentExists = errors.New("invoice is already assigned to a collector")
+	// ErrCollectorAssignmentChanged is returned when an assignment left the state an update expects
+	// (e.g. visited or cancelled concurrently)
+	ErrCollectorAssignmentChanged = errors.New("collector assignment was changed by another request")
+	ErrCollectorDepositNotFound   = errors.New("collector deposit not found")
+	ErrCollectorDepositReviewed   = errors.New("collector deposit was already confirmed or rejected")
+)
+
+// CollectorRepository stores invoice assignments, visits and deposit reports of collectors
+type CollectorRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCollectorRepository(db *pgxpool.Pool) *CollectorRepository {
+	return &CollectorRepository{db: db}
+}
+
+const collectorAssignmentColumns = `
+	a.id, a.tenant_id, a.collector_id, a.invoice_id, a.client_id, a.status, a.amount, a.notes,
+	a.assigned_by, a.assigned_at, a.collected_at, a.deposit_id, a.payment_id, a.updated_at,
+	u.name, i.invoice_number, i.due_date, i.total_amount, i.total_amount - i.paid_amount,
+	c.client_code, c.name, c.phone, c.address, c.latitude, c.longitude, c.odp_id,
+	(SELECT MAX(v.visited_at) FROM collector_visits v WHERE v.assignment_id = a.id)
+`
+
+const collectorAssignmentFrom = `
+	FROM collector_assignments a
+	JOIN users u ON u.id = a.collector_id
+	JOIN invoices i ON i.id = a.invoice_id
+	JOIN clients c ON c.id = a.client_id
+`
+
+func scanCollectorAssignment(row pgx.Row) (*collector.Assignment, error) {
+	var a collector.Assignment
+	err := row.Scan(
+		&a.ID, &a.TenantID, &a.CollectorID, &a.InvoiceID, &a.ClientID, &a.Status, &a.Amount, &a.Notes,
+		&a.AssignedBy, &a.AssignedAt, &a.CollectedAt, &a.DepositID, &a.PaymentID, &a.UpdatedAt,
+		&a.CollectorName, &a.InvoiceNumber, &a.InvoiceDueDate, &a.InvoiceTotal, &a.InvoiceRemaining,
+		&a.ClientCode, &a.ClientName, &a.ClientPhone, &a.ClientAddress, &a.Latitude, &a.Longitude, &a.ODPID,
+		&a.LastVisitAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrCollectorAssignmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// CreateAssignment stores an assignment; it fails with ErrCollectorAssignmentExists while the
+// invoice has an open assignment
+func (r *CollectorRepository) CreateAssignment(ctx context.Context, a *collector.Assignment) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO collector_assignments (
+			id, tenant_id, collector_id, invoice_id, client_id, status, notes, assigned_by, assigned_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+	`, a.ID, a.TenantID, a.CollectorID, a.InvoiceID, a.ClientID, a.Status, a.Notes, a.AssignedBy, a.AssignedAt)
+	if isUniqueViolation(err, "idx_collector_assignments_open_invoice") {
+		return ErrCollectorAssignmentExists
+	}
+	return err
+}
+
+func (r *CollectorRepository) GetAssignment(ctx context.Context, tenantID, id uuid.UUID) (*collector.Assignment, error) {
+	return scanCollectorAssignment(r.db.QueryRow(ctx, `
+		SELECT `+collectorAssignmentColumns+collectorAssignmentFrom+`
+		WHERE a.id = $1 AND a.tenant_id = $2
+	`, id, tenantID))
+}
+
+// CollectorAssignmentFilter narrows the assignments of a tenant
+type CollectorAssignmentFilter struct {
+	TenantID    uuid.UUID
+	CollectorID *uuid.UUID
+	Status      *collector.AssignmentStatus
+	DepositID   *uuid.UUID
+	Page        int
+	PageSize    int // 0 lists every assignment
+}
+
+// ListAssignments returns assignments ordered by invoice due date, oldest first
+func (r *CollectorRepository) ListAssignments(ctx context.Context, filter CollectorAssignmentFilter) ([]*collector.Assignment, int, error) {
+	where := `WHERE a.tenant_id = $1`
+	args := []any{filter.TenantID}
+	argN := 2
+	if filter.CollectorID != nil {
+		where += fmt.Sprintf(` AND a.collector_id = $%d`, argN)
+		args = append(args, *filter.CollectorID)
+		argN++
+	}
+	if filter.Status != nil {
+		where += fmt.Sprintf(` AND a.status = $%d`, argN)
+		args = append(args, string(*filter.Status))
+		argN++
+	}
+	if filter.DepositID != nil {
+		where += fmt.Sprintf(` AND a.deposit_id = $%d`, argN)
+		args = append(args, *filter.DepositID)
+		argN++
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM collector_assignments a `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	q := `SELECT ` + collectorAssignmentColumns + collectorAssignmentFrom + where + ` ORDER BY i.due_date, c.client_code, a.id`
+	if filter.PageSize > 0 {
+		if filter.Page < 1 {
+			filter.Page = 1
+		}
+		q += fmt.Sprintf(` LIMIT $%d OFFSET $%d`, argN, argN+1)
+		args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	}
+
+	rows, err := r.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	assignments := []*collector.Assignment{}
+	for rows.Next() {
+		a, err := scanCollectorAssignment(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		assignments = append(assignments, a)
+	}
+	return assignments, total, rows.Err()
+}
+
+// CancelAssignment takes back an invoice that was not collected yet
+func (r *CollectorRepository) CancelAssignment(ctx context.Context, tenantID, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE collector_assignments SET status = 'cancelled', updated_at = NOW()
+		WHERE id = $1 AND tenant_id = $2 AND status = 'assigned'
+	`, id, tenantID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCollectorAssignmentChanged
+	}
+	return nil
+}
+
+// RecordVisit stores a visit; a successful visit marks the assignment collected with the visit amount
+func (r *CollectorRepository) RecordVisit(ctx context.Context, v *collector.Visit) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var tag pgconn.CommandTag
+	if v.Result == collector.VisitSuccess {
+		tag, err = tx.Exec(ctx, `
+			UPDATE collector_assignments
+			SET status = 'collected', amount = $3, collected_at = $4, updated_at = NOW()
+			WHERE id = $1 AND collector_id = $2 AND status = 'assigned'
+		`, v.AssignmentID, v.CollectorID, v.Amount, v.VisitedAt)
+	} else {
+		tag, err = tx.Exec(ctx, `
+			UPDATE collector_assignments SET updated_at = NOW()
+			WHERE id = $1 AND collector_id = $2 AND status = 'assigned'
+		`, v.AssignmentID, v.CollectorID)
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCollectorAssignmentChanged
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO collector_visits (
+			id, tenant_id, assignment_id, collector_id, result, amount, latitude, longitude, reason, notes, visited_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, v.ID, v.TenantID, v.AssignmentID, v.CollectorID, v.Result, v.Amount, v.Latitude, v.Longitude, v.Reason, v.Notes, v.VisitedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ListVisits returns the visits of an assignment in the order they happened
+func (r *CollectorRepository) ListVisits(ctx context.Context, tenantID, assignmentID uuid.UUID) ([]*collector.Visit, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, tenant_id, assignment_id, collector_id, result, amount, latitude, longitude, reason, notes, visited_at
+		FROM collector_visits
+		WHERE assignment_id = $1 AND tenant_id = $2
+		ORDER BY visited_at
+	`, assignmentID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	visits := []*collector.Visit{}
+	for rows.Next() {
+		var v collector.Visit
+		if err := rows.Scan(
+			&v.ID, &v.TenantID, &v.AssignmentID, &v.CollectorID, &v.Result, &v.Amount, &v.Latitude, &v.Longitude,
+			&v.Reason, &v.Notes, &v.VisitedAt,
+		); err != nil {
+			return nil, err
+		}
+		visits = append(visits, &v)
+	}
+	return visits, rows.Err()
+}
+
+// LatestVisit returns the last visit the collector logged in [from, to), or nil
+func (r *CollectorRepository) LatestVisit(ctx context.Context, tenantID, collectorID uuid.UUID, from, to time.Time) (*collector.Visit, error) {
+	var v collector.Visit
+	err := r.db.QueryRow(ctx, `
+		SELECT id, tenant_id, assignment_id, collector_id, result, amount, latitude, longitude, reason, notes, visited_at
+		FROM collector_visits
+		WHERE tenant_id = $1 AND collector_id = $2 AND visited_at >= $3 AND visited_at < $4
+		ORDER BY visited_at DESC
+		LIMIT 1
+	`, tenantID, collectorID, from, to).Scan(
+		&v.ID, &v.TenantID, &v.AssignmentID, &v.CollectorID, &v.Result, &v.Amount, &v.Latitude, &v.Longitude,
+		&v.Reason, &v.Notes, &v.VisitedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// CreateDeposit stores a deposit report and moves the bundled assignments from collected to
+// deposited; it fails with ErrCollectorAssignmentChanged when one of them is no longer collected
+func (r *CollectorRepository) CreateDeposit(ctx context.Context, d *collector.Deposit, assignmentIDs []uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO collector_deposits (id, tenant_id, collector_id, status, total_amount, notes, reported_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, d.ID, d.TenantID, d.CollectorID, d.Status, d.TotalAmount, d.Notes, d.ReportedAt)
+	if err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE collector_assignments SET status = 'deposited', deposit_id = $3, updated_at = NOW()
+		WHERE id = ANY($1) AND collector_id = $2 AND status = 'collected'
+	`, assignmentIDs, d.CollectorID, d.ID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() != int64(len(assignmentIDs)) {
+		return ErrCollectorAssignmentChanged
+	}
+	return tx.Commit(ctx)
+}
+
+const collectorDepositColumns = `
+	d.id, d.tenant_id, d.collector_id, d.status, d.total_amount, d.notes, d.reported_at,
+	d.reviewed_by, d.reviewed_at, d.reject_reason, u.name,
+	(SELECT COUNT(*) FROM collector_assignments a WHERE a.deposit_id = d.id)
+`
+
+func scanCollectorDeposit(row pgx.Row) (*collector.Deposit, error) {
+	var d collector.Deposit
+	err := row.Scan(
+		&d.ID, &d.TenantID, &d.CollectorID, &d.Status, &d.TotalAmount, &d.Notes, &d.ReportedAt,
+		&d.ReviewedBy, &d.ReviewedAt, &d.RejectReason, &d.CollectorName, &d.AssignmentCount,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrCollectorDepositNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *CollectorRepository) GetDeposit(ctx context.Context, tenantID, id uuid.UUID) (*collector.Deposit, error) {
+	return scanCollectorDeposit(r.db.QueryRow(ctx, `
+		SELECT `+collectorDepositColumns+`
+		FROM collector_deposits d
+		JOIN users u ON u.id = d.collector_id
+		WHERE d.id = $1 AND d.tenant_id = $2
+	`, id, tenantID))
+}
+
+// CollectorDepositFilter narrows the deposit reports of a tenant
+type CollectorDepositFilter struct {
+	TenantID    uuid.UUID
+	CollectorID *uuid.UUID
+	Status      *collector.DepositStatus
+	Page        int
+	PageSize    int
+}
+
+// ListDeposits returns deposit reports, newest first
+func (r *CollectorRepository) ListDeposits(ctx context.Context, filter CollectorDepositFilter) ([]*collector.Deposit, int, error) {
+	where := `WHERE d.tenant_id = $1`
+	args := []any{filter.TenantID}
+	argN := 2
+	if filter.CollectorID != nil {
+		where += fmt.Sprintf(` AND d.collector_id = $%d`, argN)
+		args = append(args, *filter.CollectorID)
+		argN++
+	}
+	if filter.Status != nil {
+		where += fmt.Sprintf(` AND d.status = $%d`, argN)
+		args = append(args, string(*filter.Status))
+		argN++
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM collector_deposits d `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 20
+	}
+	args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	rows, err := r.db.Query(ctx, `
+		SELECT `+collectorDepositColumns+`
+		FROM collector_deposits d
+		JOIN users u ON u.id = d.collector_id
+		`+where+fmt.Sprintf(` ORDER BY d.reported_at DESC LIMIT $%d OFFSET $%d`, argN, argN+1), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	deposits := []*collector.Deposit{}
+	for rows.Next() {
+		d, err := scanCollectorDeposit(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		deposits = append(deposits, d)
+	}
+	return deposits, total, rows.Err()
+}
+
+// ClaimForConfirmation moves a deposited assignment to confirmed so that only one request records
+// its payment; ReleaseConfirmation undoes the claim when the payment fails
+func (r *CollectorRepository) ClaimForConfirmation(ctx context.Context, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE collector_assignments SET status = 'confirmed', updated_at = NOW()
+		WHERE id = $1 AND status = 'deposited'
+	`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *CollectorRepository) ReleaseConfirmation(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE collector_assignments SET status = 'deposited', updated_at = NOW()
+		WHERE id = $1 AND status = 'confirmed' AND payment_id IS NULL
+	`, id)
+	return err
+}
+
+// SetPayment links a confirmed assignment to the payment it produced
+func (r *CollectorRepository) SetPayment(ctx context.Context, id, paymentID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE collector_assignments SET payment_id = $2, updated_at = NOW() WHERE id = $1
+	`, id, paymentID)
+	return err
+}
+
+// MarkDepositConfirmed closes a deposit once every bundled assignment has its payment
+func (r *CollectorRepository) MarkDepositConfirmed(ctx context.Context, id, reviewedBy uuid.UUID, at time.Time) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE collector_deposits SET status = 'confirmed', reviewed_by = $2, reviewed_at = $3
+		WHERE id = $1 AND status = 'reported'
+	`, id, reviewedBy, at)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCollectorDepositReviewed
+	}
+	return nil
+}
+
+// RejectDeposit closes a deposit without payments; its assignments go back to collected so the
+// collector can report them again
+func (r *CollectorRepository) RejectDeposit(ctx context.Context, id, reviewedBy uuid.UUID, reason string, at time.Time) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE collector_deposits SET status = 'rejected', reviewed_by = $2, reviewed_at = $3, reject_reason = $4
+		WHERE id = $1 AND status = 'reported'
+	`, id, reviewedBy, at, reason)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCollectorDepositReviewed
+	}
+
+	// Assignments already confirmed by an interrupted confirmation keep their payment
+	var confirmed int
+	if err := tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM collector_assignments WHERE deposit_id = $1 AND status = 'confirmed'
+	`, id).Scan(&confirmed); err != nil {
+		return err
+	}
+	if confirmed > 0 {
+		return ErrCollectorDepositReviewed
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE collector_assignments SET status = 'collected', deposit_id = NULL, updated_at = NOW()
+		WHERE deposit_id = $1 AND status = 'deposited'
+	`, id); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
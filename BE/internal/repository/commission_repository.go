@@ -0,0 +1,382 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"rrnet/internal/domain/commission"
+)
+
+var (
+	ErrCommissionRuleNotFound = errors.New("commission rule not found")
+	// ErrCommissionRuleConflict is returned when another request added a version of the rule first
+	ErrCommissionRuleConflict      = errors.New("commission rule was changed by another request")
+	ErrCommissionStatementNotFound = errors.New("commission statement not found")
+	// ErrCommissionStatementState is returned when a statement is not in the state an action needs
+	ErrCommissionStatementState = errors.New("commission statement is not in the required status")
+)
+
+// CommissionRepository stores versioned commission rules and monthly statements
+type CommissionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCommissionRepository(db *pgxpool.Pool) *CommissionRepository {
+	return &CommissionRepository{db: db}
+}
+
+const commissionRuleColumns = `
+	id, tenant_id, rule_key, version, name, scope, role_code, user_id, type, percent::float8, flat_amount,
+	tiers, active, effective_from, created_by, created_at
+`
+
+func scanCommissionRule(row pgx.Row) (*commission.Rule, error) {
+	var r commission.Rule
+	err := row.Scan(
+		&r.ID, &r.TenantID, &r.RuleKey, &r.Version, &r.Name, &r.Scope, &r.RoleCode, &r.UserID, &r.Type, &r.Percent, &r.FlatAmount,
+		&r.Tiers, &r.Active, &r.EffectiveFrom, &r.CreatedBy, &r.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrCommissionRuleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (r *CommissionRepository) queryRules(ctx context.Context, query string, args ...any) ([]*commission.Rule, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []*commission.Rule{}
+	for rows.Next() {
+		rule, err := scanCommissionRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// CreateRuleVersion stores a rule version; it fails with ErrCommissionRuleConflict when the
+// version number is taken
+func (r *CommissionRepository) CreateRuleVersion(ctx context.Context, rule *commission.Rule) error {
+	tiers := rule.Tiers
+	if tiers == nil {
+		tiers = []commission.Tier{}
+	}
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO commission_rules (
+			id, tenant_id, rule_key, version, name, scope, role_code, user_id, type, percent, flat_amount,
+			tiers, active, effective_from, created_by, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	`,
+		rule.ID, rule.TenantID, rule.RuleKey, rule.Version, rule.Name, rule.Scope, rule.RoleCode, rule.UserID, rule.Type, rule.Percent, rule.FlatAmount,
+		tiers, rule.Active, rule.EffectiveFrom, rule.CreatedBy, rule.CreatedAt,
+	)
+	if isUniqueViolation(err, "unique_commission_rule_version") {
+		return ErrCommissionRuleConflict
+	}
+	return err
+}
+
+// ListLatestRules returns the latest version of every rule of the tenant, retired ones included
+func (r *CommissionRepository) ListLatestRules(ctx context.Context, tenantID uuid.UUID) ([]*commission.Rule, error) {
+	return r.queryRules(ctx, `
+		SELECT * FROM (
+			SELECT DISTINCT ON (rule_key) `+commissionRuleColumns+`
+			FROM commission_rules
+			WHERE tenant_id = $1
+			ORDER BY rule_key, version DESC
+		) latest
+		ORDER BY active DESC, name
+	`, tenantID)
+}
+
+// ListRuleVersions returns every version of a rule, oldest first
+func (r *CommissionRepository) ListRuleVersions(ctx context.Context, tenantID, ruleKey uuid.UUID) ([]*commission.Rule, error) {
+	rules, err := r.queryRules(ctx, `
+		SELECT `+commissionRuleColumns+`
+		FROM commission_rules
+		WHERE tenant_id = $1 AND rule_key = $2
+		ORDER BY version
+	`, tenantID, ruleKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, ErrCommissionRuleNotFound
+	}
+	return rules, nil
+}
+
+// RulesInForce returns, per rule, the latest version effective on day; retired rules are left out
+func (r *CommissionRepository) RulesInForce(ctx context.Context, tenantID uuid.UUID, day time.Time) ([]*commission.Rule, error) {
+	return r.queryRules(ctx, `
+		SELECT `+commissionRuleColumns+` FROM (
+			SELECT DISTINCT ON (rule_key) *
+			FROM commission_rules
+			WHERE tenant_id = $1 AND effective_from <= $2
+			ORDER BY rule_key, version DESC
+		) v
+		WHERE active
+	`, tenantID, day)
+}
+
+// CollectedPayment is a payment with a collector, as counted for commissions
+type CollectedPayment struct {
+	CollectorID uuid.UUID
+	commission.StatementLine
+}
+
+// ListCollectedPayments returns the payments with a collector recorded in [from, to)
+func (r *CommissionRepository) ListCollectedPayments(ctx context.Context, tenantID uuid.UUID, from, to time.Time) ([]*CollectedPayment, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT p.collector_id, p.id, p.invoice_id, i.invoice_number, c.name, p.amount, p.method, p.received_at, p.created_at
+		FROM payments p
+		JOIN invoices i ON i.id = p.invoice_id
+		JOIN clients c ON c.id = p.client_id
+		WHERE p.tenant_id = $1 AND p.collector_id IS NOT NULL AND p.created_at >= $2 AND p.created_at < $3
+		ORDER BY p.created_at, p.id
+	`, tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := []*CollectedPayment{}
+	for rows.Next() {
+		var p CollectedPayment
+		if err := rows.Scan(
+			&p.CollectorID, &p.PaymentID, &p.InvoiceID, &p.InvoiceNumber, &p.ClientName, &p.Amount, &p.Method, &p.ReceivedAt, &p.ConfirmedAt,
+		); err != nil {
+			return nil, err
+		}
+		payments = append(payments, &p)
+	}
+	return payments, rows.Err()
+}
+
+// ReplaceDrafts regenerates the draft statements of a month: existing drafts are removed and the
+// given statements stored, except for users whose statement is already approved or paid. Returns
+// the number of statements stored.
+func (r *CommissionRepository) ReplaceDrafts(ctx context.Context, tenantID uuid.UUID, periodStart time.Time, statements []*commission.Statement) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM commission_statements WHERE tenant_id = $1 AND period_start = $2 AND status = 'draft'
+	`, tenantID, periodStart); err != nil {
+		return 0, err
+	}
+
+	stored := 0
+	for _, st := range statements {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO commission_statements (
+				id, tenant_id, user_id, period_start, rule_id, collected_amount, payment_count, invoice_count,
+				commission_amount, status, generated_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			ON CONFLICT (tenant_id, user_id, period_start) DO NOTHING
+		`,
+			st.ID, st.TenantID, st.UserID, st.PeriodStart, st.RuleID, st.CollectedAmount, st.PaymentCount, st.InvoiceCount,
+			st.CommissionAmount, st.Status, st.GeneratedAt,
+		)
+		if err != nil {
+			return 0, err
+		}
+		if tag.RowsAffected() == 0 {
+			continue // approved or paid: left as it is
+		}
+		stored++
+
+		batch := &pgx.Batch{}
+		for _, l := range st.Lines {
+			batch.Queue(`
+				INSERT INTO commission_statement_lines (
+					statement_id, payment_id, invoice_id, invoice_number, client_name, amount, method, received_at, confirmed_at
+				) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			`, st.ID, l.PaymentID, l.InvoiceID, l.InvoiceNumber, l.ClientName, l.Amount, l.Method, l.ReceivedAt, l.ConfirmedAt)
+		}
+		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return stored, nil
+}
+
+// The statement's rule is the version row it references; rule rows are never updated
+const commissionStatementColumns = `
+	s.id, s.tenant_id, s.user_id, s.period_start, s.rule_id, s.collected_amount, s.payment_count, s.invoice_count,
+	s.commission_amount, s.status, s.approved_by, s.approved_at, s.paid_by, s.paid_at, s.payout_reference, s.generated_at,
+	u.name, ro.code,
+	cr.id, cr.tenant_id, cr.rule_key, cr.version, cr.name, cr.scope, cr.role_code, cr.user_id, cr.type, cr.percent::float8,
+	cr.flat_amount, cr.tiers, cr.active, cr.effective_from, cr.created_by, cr.created_at
+`
+
+const commissionStatementFrom = `
+	FROM commission_statements s
+	JOIN users u ON u.id = s.user_id
+	JOIN roles ro ON ro.id = u.role_id
+	JOIN commission_rules cr ON cr.id = s.rule_id
+`
+
+func scanCommissionStatement(row pgx.Row) (*commission.Statement, error) {
+	var s commission.Statement
+	cr := &s.Rule
+	err := row.Scan(
+		&s.ID, &s.TenantID, &s.UserID, &s.PeriodStart, &s.RuleID, &s.CollectedAmount, &s.PaymentCount, &s.InvoiceCount,
+		&s.CommissionAmount, &s.Status, &s.ApprovedBy, &s.ApprovedAt, &s.PaidBy, &s.PaidAt, &s.PayoutReference, &s.GeneratedAt,
+		&s.UserName, &s.RoleCode,
+		&cr.ID, &cr.TenantID, &cr.RuleKey, &cr.Version, &cr.Name, &cr.Scope, &cr.RoleCode, &cr.UserID, &cr.Type, &cr.Percent,
+		&cr.FlatAmount, &cr.Tiers, &cr.Active, &cr.EffectiveFrom, &cr.CreatedBy, &cr.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrCommissionStatementNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *CommissionRepository) GetStatement(ctx context.Context, tenantID, id uuid.UUID) (*commission.Statement, error) {
+	return scanCommissionStatement(r.db.QueryRow(ctx, `
+		SELECT `+commissionStatementColumns+commissionStatementFrom+`
+		WHERE s.id = $1 AND s.tenant_id = $2
+	`, id, tenantID))
+}
+
+// CommissionStatementFilter narrows the statements of a tenant
+type CommissionStatementFilter struct {
+	TenantID    uuid.UUID
+	PeriodStart *time.Time
+	UserID      *uuid.UUID
+	Status      *commission.StatementStatus
+	Page        int
+	PageSize    int // 0 lists every statement
+}
+
+// ListStatements returns statements, newest month first, then by user name
+func (r *CommissionRepository) ListStatements(ctx context.Context, filter CommissionStatementFilter) ([]*commission.Statement, int, error) {
+	where := `WHERE s.tenant_id = $1`
+	args := []any{filter.TenantID}
+	argN := 2
+	if filter.PeriodStart != nil {
+		where += fmt.Sprintf(` AND s.period_start = $%d`, argN)
+		args = append(args, *filter.PeriodStart)
+		argN++
+	}
+	if filter.UserID != nil {
+		where += fmt.Sprintf(` AND s.user_id = $%d`, argN)
+		args = append(args, *filter.UserID)
+		argN++
+	}
+	if filter.Status != nil {
+		where += fmt.Sprintf(` AND s.status = $%d`, argN)
+		args = append(args, string(*filter.Status))
+		argN++
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM commission_statements s `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	q := `SELECT ` + commissionStatementColumns + commissionStatementFrom + where + ` ORDER BY s.period_start DESC, u.name, s.id`
+	if filter.PageSize > 0 {
+		if filter.Page < 1 {
+			filter.Page = 1
+		}
+		q += fmt.Sprintf(` LIMIT $%d OFFSET $%d`, argN, argN+1)
+		args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	}
+
+	rows, err := r.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	statements := []*commission.Statement{}
+	for rows.Next() {
+		s, err := scanCommissionStatement(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		statements = append(statements, s)
+	}
+	return statements, total, rows.Err()
+}
+
+// ListStatementLines returns the payments counted in a statement
+func (r *CommissionRepository) ListStatementLines(ctx context.Context, statementID uuid.UUID) ([]*commission.StatementLine, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT payment_id, invoice_id, invoice_number, client_name, amount, method, received_at, confirmed_at
+		FROM commission_statement_lines
+		WHERE statement_id = $1
+		ORDER BY confirmed_at, payment_id
+	`, statementID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []*commission.StatementLine{}
+	for rows.Next() {
+		var l commission.StatementLine
+		if err := rows.Scan(&l.PaymentID, &l.InvoiceID, &l.InvoiceNumber, &l.ClientName, &l.Amount, &l.Method, &l.ReceivedAt, &l.ConfirmedAt); err != nil {
+			return nil, err
+		}
+		lines = append(lines, &l)
+	}
+	return lines, rows.Err()
+}
+
+// Approve locks a draft statement; later generations of the month leave it as it is
+func (r *CommissionRepository) Approve(ctx context.Context, tenantID, id, by uuid.UUID, at time.Time) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE commission_statements SET status = 'approved', approved_by = $3, approved_at = $4
+		WHERE id = $1 AND tenant_id = $2 AND status = 'draft'
+	`, id, tenantID, by, at)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCommissionStatementState
+	}
+	return nil
+}
+
+// MarkPaid records the payout of an approved statement
+func (r *CommissionRepository) MarkPaid(ctx context.Context, tenantID, id, by uuid.UUID, reference *string, at time.Time) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE commission_statements SET status = 'paid', paid_by = $3, paid_at = $4, payout_reference = $5
+		WHERE id = $1 AND tenant_id = $2 AND status = 'approved'
+	`, id, tenantID, by, at, reference)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCommissionStatementState
+	}
+	return nil
+}
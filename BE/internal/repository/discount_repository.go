@@ -31,13 +31,13 @@ func (r *DiscountRepository) Create(ctx context.Context, d *discount.Discount) e
 	query := `
 		INSERT INTO discounts (
 			id, tenant_id, name, description, type, value, expires_at, is_active,
-			created_at, updated_at
+			created_at, updated_at, max_cycles
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 	`
 	_, err := r.db.Exec(ctx, query,
 		d.ID, d.TenantID, d.Name, d.Description, d.Type, d.Value, d.ExpiresAt, d.IsActive,
-		d.CreatedAt, d.UpdatedAt,
+		d.CreatedAt, d.UpdatedAt, d.MaxCycles,
 	)
 	return err
 }
@@ -46,7 +46,7 @@ func (r *DiscountRepository) Create(ctx context.Context, d *discount.Discount) e
 func (r *DiscountRepository) GetByID(ctx context.Context, discountID, tenantID uuid.UUID) (*discount.Discount, error) {
 	query := `
 		SELECT id, tenant_id, name, description, type, value, expires_at, is_active,
-			   created_at, updated_at, deleted_at
+			   created_at, updated_at, deleted_at, max_cycles
 		FROM discounts
 		WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
 	`
@@ -57,7 +57,7 @@ func (r *DiscountRepository) GetByID(ctx context.Context, discountID, tenantID u
 func (r *DiscountRepository) List(ctx context.Context, tenantID uuid.UUID, includeInactive bool) ([]*discount.Discount, error) {
 	query := `
 		SELECT id, tenant_id, name, description, type, value, expires_at, is_active,
-			   created_at, updated_at, deleted_at
+			   created_at, updated_at, deleted_at, max_cycles
 		FROM discounts
 		WHERE tenant_id = $1 AND deleted_at IS NULL
 	`
@@ -91,7 +91,7 @@ func (r *DiscountRepository) List(ctx context.Context, tenantID uuid.UUID, inclu
 func (r *DiscountRepository) ListValid(ctx context.Context, tenantID uuid.UUID) ([]*discount.Discount, error) {
 	query := `
 		SELECT id, tenant_id, name, description, type, value, expires_at, is_active,
-			   created_at, updated_at, deleted_at
+			   created_at, updated_at, deleted_at, max_cycles
 		FROM discounts
 		WHERE tenant_id = $1 
 		  AND deleted_at IS NULL
@@ -122,12 +122,12 @@ func (r *DiscountRepository) Update(ctx context.Context, d *discount.Discount) e
 	query := `
 		UPDATE discounts
 		SET name = $3, description = $4, type = $5, value = $6, expires_at = $7,
-		    is_active = $8, updated_at = $9
+		    is_active = $8, updated_at = $9, max_cycles = $10
 		WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
 	`
 	result, err := r.db.Exec(ctx, query,
 		d.ID, d.TenantID, d.Name, d.Description, d.Type, d.Value, d.ExpiresAt,
-		d.IsActive, d.UpdatedAt,
+		d.IsActive, d.UpdatedAt, d.MaxCycles,
 	)
 	if err != nil {
 		return err
@@ -176,7 +176,7 @@ func (r *DiscountRepository) scanDiscount(row pgx.Row) (*discount.Discount, erro
 	var d discount.Discount
 	err := row.Scan(
 		&d.ID, &d.TenantID, &d.Name, &d.Description, &d.Type, &d.Value, &d.ExpiresAt,
-		&d.IsActive, &d.CreatedAt, &d.UpdatedAt, &d.DeletedAt,
+		&d.IsActive, &d.CreatedAt, &d.UpdatedAt, &d.DeletedAt, &d.MaxCycles,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -192,7 +192,7 @@ func (r *DiscountRepository) scanDiscountFromRows(rows pgx.Rows) (*discount.Disc
 	var d discount.Discount
 	err := rows.Scan(
 		&d.ID, &d.TenantID, &d.Name, &d.Description, &d.Type, &d.Value, &d.ExpiresAt,
-		&d.IsActive, &d.CreatedAt, &d.UpdatedAt, &d.DeletedAt,
+		&d.IsActive, &d.CreatedAt, &d.UpdatedAt, &d.DeletedAt, &d.MaxCycles,
 	)
 	if err != nil {
 		return nil, err
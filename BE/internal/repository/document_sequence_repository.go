@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"rrnet/internal/domain/billing"
+)
+
+// ErrDocumentNumberExhausted is returned when a scheme keeps producing numbers that are already taken
+var ErrDocumentNumberExhausted = errors.New("no free document number for this numbering scheme")
+
+// maxTakenNumbers bounds how many numbers issued under an earlier scheme are skipped
+const maxTakenNumbers = 10000
+
+// documentNumberColumns is where the numbers of each document kind are stored
+var documentNumberColumns = map[billing.DocumentKind]struct{ table, column string }{
+	billing.DocumentInvoice:    {"invoices", "invoice_number"},
+	billing.DocumentCreditNote: {"credit_notes", "credit_note_number"},
+	billing.DocumentReceipt:    {"payments", "receipt_number"},
+}
+
+// DocumentSequenceRepository reads the counters behind document numbers. Numbers themselves are
+// drawn by the repository that stores the document, in the same transaction.
+type DocumentSequenceRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDocumentSequenceRepository(db *pgxpool.Pool) *DocumentSequenceRepository {
+	return &DocumentSequenceRepository{db: db}
+}
+
+// LastValue returns the last sequence value drawn in a scope (0 when none was drawn yet)
+func (r *DocumentSequenceRepository) LastValue(ctx context.Context, tenantID uuid.UUID, kind billing.DocumentKind, scope string) (int64, error) {
+	var last int64
+	err := r.db.QueryRow(ctx, `
+		SELECT last_value FROM document_sequences WHERE tenant_id = $1 AND kind = $2 AND scope = $3
+	`, tenantID, kind, scope).Scan(&last)
+	if err == pgx.ErrNoRows {
+		return 0, nil
+	}
+	return last, err
+}
+
+// nextDocumentNumber draws the next number of n inside tx. The sequence row stays locked until tx
+// ends, so concurrent documents wait for each other and a rolled back document leaves no gap.
+// Numbers that are already taken (issued under an earlier scheme) are skipped.
+func nextDocumentNumber(ctx context.Context, tx pgx.Tx, tenantID uuid.UUID, n billing.DocumentNumbering) (string, error) {
+	target := documentNumberColumns[n.Kind]
+	scope := n.Scheme.Scope(n.Date, n.Branch)
+	for i := 0; i < maxTakenNumbers; i++ {
+		var seq int64
+		err := tx.QueryRow(ctx, `
+			INSERT INTO document_sequences (tenant_id, kind, scope, last_value, updated_at)
+			VALUES ($1, $2, $3, 1, NOW())
+			ON CONFLICT (tenant_id, kind, scope)
+			DO UPDATE SET last_value = document_sequences.last_value + 1, updated_at = NOW()
+			RETURNING last_value
+		`, tenantID, n.Kind, scope).Scan(&seq)
+		if err != nil {
+			return "", err
+		}
+		number := n.Scheme.Format(n.Date, n.Branch, seq)
+
+		var taken bool
+		err = tx.QueryRow(ctx,
+			`SELECT EXISTS(SELECT 1 FROM `+target.table+` WHERE tenant_id = $1 AND `+target.column+` = $2)`,
+			tenantID, number,
+		).Scan(&taken)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return number, nil
+		}
+	}
+	return "", ErrDocumentNumberExhausted
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"rrnet/internal/domain/billing"
+)
+
+type DunningRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDunningRepository(db *pgxpool.Pool) *DunningRepository {
+	return &DunningRepository{db: db}
+}
+
+// RecordRun stores the outcome of a dunning step. A previously failed step may be overwritten
+// (retry); executed/skipped steps are never overwritten.
+func (r *DunningRepository) RecordRun(ctx context.Context, run *billing.DunningStepRun) error {
+	query := `
+		INSERT INTO dunning_step_runs (
+			id, tenant_id, client_id, invoice_id, offset_days, action, status, error_msg, run_date, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, $10, $10)
+		ON CONFLICT (invoice_id, offset_days, action) DO UPDATE
+		SET status = EXCLUDED.status, error_msg = EXCLUDED.error_msg, run_date = EXCLUDED.run_date
+		WHERE dunning_step_runs.status = 'failed'
+	`
+	_, err := r.db.Exec(ctx, query,
+		run.ID, run.TenantID, run.ClientID, run.InvoiceID, run.OffsetDays, run.Action, run.Status,
+		run.ErrorMsg, run.RunDate, run.CreatedAt,
+	)
+	return err
+}
+
+// ReserveRun claims a dunning step before it is performed by storing it as running. It returns false
+// when the step was claimed or finished before; a failed step may be claimed again (retry).
+func (r *DunningRepository) ReserveRun(ctx context.Context, run *billing.DunningStepRun) (bool, error) {
+	query := `
+		INSERT INTO dunning_step_runs (
+			id, tenant_id, client_id, invoice_id, offset_days, action, status, run_date, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, 'running', $7, $8, $8)
+		ON CONFLICT (invoice_id, offset_days, action) DO UPDATE
+		SET status = 'running', error_msg = NULL, run_date = EXCLUDED.run_date
+		WHERE dunning_step_runs.status = 'failed'
+		RETURNING id
+	`
+	err := r.db.QueryRow(ctx, query,
+		run.ID, run.TenantID, run.ClientID, run.InvoiceID, run.OffsetDays, run.Action, run.RunDate, run.CreatedAt,
+	).Scan(&run.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	run.Status = billing.DunningStepRunning
+	return true, nil
+}
+
+// FinishRun stores the outcome of a step reserved with ReserveRun
+func (r *DunningRepository) FinishRun(ctx context.Context, id uuid.UUID, status billing.DunningStepStatus, errorMsg string) error {
+	query := `
+		UPDATE dunning_step_runs SET status = $2, error_msg = NULLIF($3, '')
+		WHERE id = $1 AND status = 'running'
+	`
+	_, err := r.db.Exec(ctx, query, id, status, errorMsg)
+	return err
+}
+
+// ListByInvoices returns all recorded steps for the given invoices
+func (r *DunningRepository) ListByInvoices(ctx context.Context, tenantID uuid.UUID, invoiceIDs []uuid.UUID) ([]*billing.DunningStepRun, error) {
+	if len(invoiceIDs) == 0 {
+		return nil, nil
+	}
+	query := `
+		SELECT id, tenant_id, client_id, invoice_id, offset_days, action, status,
+			COALESCE(error_msg, ''), run_date, created_at, updated_at
+		FROM dunning_step_runs
+		WHERE tenant_id = $1 AND invoice_id = ANY($2)
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, invoiceIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []*billing.DunningStepRun
+	for rows.Next() {
+		run, err := scanDunningStepRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+type DunningRunFilter struct {
+	TenantID  uuid.UUID
+	ClientID  *uuid.UUID
+	InvoiceID *uuid.UUID
+	Action    *billing.DunningAction
+	Status    *billing.DunningStepStatus
+	Page      int
+	PageSize  int
+}
+
+func (r *DunningRepository) List(ctx context.Context, filter DunningRunFilter) ([]*billing.DunningStepRun, int, error) {
+	baseQuery := ` FROM dunning_step_runs WHERE tenant_id = $1`
+	args := []interface{}{filter.TenantID}
+	argIdx := 2
+
+	if filter.ClientID != nil {
+		baseQuery += fmt.Sprintf(" AND client_id = $%d", argIdx)
+		args = append(args, *filter.ClientID)
+		argIdx++
+	}
+	if filter.InvoiceID != nil {
+		baseQuery += fmt.Sprintf(" AND invoice_id = $%d", argIdx)
+		args = append(args, *filter.InvoiceID)
+		argIdx++
+	}
+	if filter.Action != nil {
+		baseQuery += fmt.Sprintf(" AND action = $%d", argIdx)
+		args = append(args, *filter.Action)
+		argIdx++
+	}
+	if filter.Status != nil {
+		baseQuery += fmt.Sprintf(" AND status = $%d", argIdx)
+		args = append(args, *filter.Status)
+		argIdx++
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*)"+baseQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+	offset := (filter.Page - 1) * filter.PageSize
+
+	dataQuery := `
+		SELECT id, tenant_id, client_id, invoice_id, offset_days, action, status,
+			COALESCE(error_msg, ''), run_date, created_at, updated_at
+	` + baseQuery + fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", argIdx, argIdx+1)
+	args = append(args, filter.PageSize, offset)
+
+	rows, err := r.db.Query(ctx, dataQuery, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var runs []*billing.DunningStepRun
+	for rows.Next() {
+		run, err := scanDunningStepRun(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, total, nil
+}
+
+func scanDunningStepRun(row pgx.Row) (*billing.DunningStepRun, error) {
+	var run billing.DunningStepRun
+	if err := row.Scan(
+		&run.ID, &run.TenantID, &run.ClientID, &run.InvoiceID, &run.OffsetDays, &run.Action, &run.Status,
+		&run.ErrorMsg, &run.RunDate, &run.CreatedAt, &run.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
@@ -2,16 +2,33 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"rrnet/internal/domain/billing"
 )
 
+var (
+	// ErrUniqueCodeExhausted is returned when every unique code is held by an open invoice
+	ErrUniqueCodeExhausted = errors.New("no free unique code")
+	// ErrMonthlyInvoiceExists is returned when the client already has a monthly invoice for the period
+	ErrMonthlyInvoiceExists = errors.New("monthly invoice already exists for this period")
+	// ErrPrepaidInvoiceOpen is returned when the client already has an unpaid prepaid invoice
+	ErrPrepaidInvoiceOpen = errors.New("client already has an unpaid prepaid invoice")
+)
+
+// isUniqueViolation reports whether err violates the unique index or constraint named constraint
+func isUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == constraint
+}
+
 type InvoiceRepository struct {
 	db *pgxpool.Pool
 }
@@ -39,27 +56,139 @@ func (r *InvoiceRepository) ExistsForClientPeriod(ctx context.Context, tenantID,
 	return exists, nil
 }
 
-func (r *InvoiceRepository) Create(ctx context.Context, invoice *billing.Invoice) error {
+// GetForClientPeriod returns the non-cancelled invoice of a client for the given period, or nil if there is none
+func (r *InvoiceRepository) GetForClientPeriod(ctx context.Context, tenantID, clientID uuid.UUID, periodStart, periodEnd time.Time) (*billing.Invoice, error) {
+	query := `
+		SELECT id FROM invoices
+		WHERE tenant_id = $1 AND client_id = $2 AND period_start = $3 AND period_end = $4 AND status != 'cancelled'
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	var id uuid.UUID
+	err := r.db.QueryRow(ctx, query, tenantID, clientID, periodStart, periodEnd).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, id)
+}
+
+// GetOpenPrepaid returns the unpaid prepaid invoice of a client, or nil if there is none
+func (r *InvoiceRepository) GetOpenPrepaid(ctx context.Context, tenantID, clientID uuid.UUID) (*billing.Invoice, error) {
+	query := `
+		SELECT id FROM invoices
+		WHERE tenant_id = $1 AND client_id = $2 AND source = 'prepaid' AND status IN ('draft', 'pending', 'overdue')
+	`
+	var id uuid.UUID
+	err := r.db.QueryRow(ctx, query, tenantID, clientID).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, id)
+}
+
+// AppendItems adds items to an existing invoice, moves the subtotal by their sum (negative items are
+// credits) and recomputes tax and total with the invoice's own tax rate.
+func (r *InvoiceRepository) AppendItems(ctx context.Context, invoiceID uuid.UUID, items []billing.InvoiceItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := appendInvoiceItems(ctx, tx, invoiceID, items); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// appendInvoiceItems is AppendItems inside tx (shared with credit notes); it returns the new total.
+// The invoice row stays locked until tx ends.
+func appendInvoiceItems(ctx context.Context, tx pgx.Tx, invoiceID uuid.UUID, items []billing.InvoiceItem) (int64, error) {
+	var delta int64
+	for _, item := range items {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO invoice_items (id, invoice_id, description, quantity, unit_price, amount, discount_id, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, item.ID, invoiceID, item.Description, item.Quantity, item.UnitPrice, item.Amount, item.DiscountID, item.CreatedAt)
+		if err != nil {
+			return 0, err
+		}
+		delta += item.Amount
+	}
+
+	var subtotal, discount, code int64
+	var rate float64
+	var inclusive, codeInTotal bool
+	err := tx.QueryRow(ctx, `
+		SELECT subtotal + $2, discount_amount, tax_rate, tax_inclusive, COALESCE(unique_code, 0), unique_code_in_total
+		FROM invoices WHERE id = $1 FOR UPDATE
+	`, invoiceID, delta).Scan(&subtotal, &discount, &rate, &inclusive, &code, &codeInTotal)
+	if err != nil {
+		return 0, err
+	}
+	base, tax, total := billing.ComputeTax(subtotal, discount, rate, inclusive)
+	if codeInTotal {
+		total += code
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE invoices
+		SET subtotal = $2, tax_base = $3, tax_amount = $4, total_amount = $5, updated_at = NOW()
+		WHERE id = $1
+	`, invoiceID, subtotal, base, tax, total)
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// Create stores an invoice with its items. With numbering, the invoice number is drawn from the
+// tenant's sequence in the same transaction and set on invoice.
+func (r *InvoiceRepository) Create(ctx context.Context, invoice *billing.Invoice, numbering *billing.DocumentNumbering) error {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback(ctx)
 
+	if numbering != nil {
+		number, err := nextDocumentNumber(ctx, tx, invoice.TenantID, *numbering)
+		if err != nil {
+			return err
+		}
+		invoice.InvoiceNumber = number
+	}
+
 	query := `
 		INSERT INTO invoices (
 			id, tenant_id, client_id, invoice_number, period_start, period_end,
-			due_date, subtotal, tax_amount, discount_amount, total_amount,
-			paid_amount, currency, status, notes, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+			due_date, subtotal, tax_amount, discount_amount, total_amount, tax_rate, tax_inclusive, tax_base,
+			paid_amount, currency, status, source, notes, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
 	`
 	_, err = tx.Exec(ctx, query,
 		invoice.ID, invoice.TenantID, invoice.ClientID, invoice.InvoiceNumber,
 		invoice.PeriodStart, invoice.PeriodEnd, invoice.DueDate,
 		invoice.Subtotal, invoice.TaxAmount, invoice.DiscountAmount, invoice.TotalAmount,
-		invoice.PaidAmount, invoice.Currency, invoice.Status, invoice.Notes,
+		invoice.TaxRate, invoice.TaxInclusive, invoice.TaxBase,
+		invoice.PaidAmount, invoice.Currency, invoice.Status, invoice.Source, invoice.Notes,
 		invoice.CreatedAt, invoice.UpdatedAt,
 	)
+	if isUniqueViolation(err, "idx_invoices_monthly_period") {
+		return ErrMonthlyInvoiceExists
+	}
+	if isUniqueViolation(err, "idx_invoices_prepaid_open") {
+		return ErrPrepaidInvoiceOpen
+	}
 	if err != nil {
 		return err
 	}
@@ -67,11 +196,11 @@ func (r *InvoiceRepository) Create(ctx context.Context, invoice *billing.Invoice
 	// Insert invoice items
 	for _, item := range invoice.Items {
 		itemQuery := `
-			INSERT INTO invoice_items (id, invoice_id, description, quantity, unit_price, amount, created_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			INSERT INTO invoice_items (id, invoice_id, description, quantity, unit_price, amount, discount_id, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		`
 		_, err = tx.Exec(ctx, itemQuery,
-			item.ID, invoice.ID, item.Description, item.Quantity, item.UnitPrice, item.Amount, item.CreatedAt,
+			item.ID, invoice.ID, item.Description, item.Quantity, item.UnitPrice, item.Amount, item.DiscountID, item.CreatedAt,
 		)
 		if err != nil {
 			return err
@@ -84,7 +213,7 @@ func (r *InvoiceRepository) Create(ctx context.Context, invoice *billing.Invoice
 func (r *InvoiceRepository) GetByID(ctx context.Context, id uuid.UUID) (*billing.Invoice, error) {
 	query := `
 		SELECT id, tenant_id, client_id, invoice_number, period_start, period_end,
-			due_date, subtotal, tax_amount, discount_amount, total_amount,
+			due_date, subtotal, tax_amount, discount_amount, total_amount, tax_rate, tax_inclusive, tax_base, unique_code, unique_code_in_total, source,
 			paid_amount, currency, status, notes, created_at, updated_at, paid_at
 		FROM invoices
 		WHERE id = $1
@@ -93,7 +222,7 @@ func (r *InvoiceRepository) GetByID(ctx context.Context, id uuid.UUID) (*billing
 	err := r.db.QueryRow(ctx, query, id).Scan(
 		&invoice.ID, &invoice.TenantID, &invoice.ClientID, &invoice.InvoiceNumber,
 		&invoice.PeriodStart, &invoice.PeriodEnd, &invoice.DueDate,
-		&invoice.Subtotal, &invoice.TaxAmount, &invoice.DiscountAmount, &invoice.TotalAmount,
+		&invoice.Subtotal, &invoice.TaxAmount, &invoice.DiscountAmount, &invoice.TotalAmount, &invoice.TaxRate, &invoice.TaxInclusive, &invoice.TaxBase, &invoice.UniqueCode, &invoice.UniqueCodeInTotal, &invoice.Source,
 		&invoice.PaidAmount, &invoice.Currency, &invoice.Status, &invoice.Notes,
 		&invoice.CreatedAt, &invoice.UpdatedAt, &invoice.PaidAt,
 	)
@@ -116,10 +245,10 @@ func (r *InvoiceRepository) GetByID(ctx context.Context, id uuid.UUID) (*billing
 
 func (r *InvoiceRepository) GetInvoiceItems(ctx context.Context, invoiceID uuid.UUID) ([]billing.InvoiceItem, error) {
 	query := `
-		SELECT id, invoice_id, description, quantity, unit_price, amount, created_at
+		SELECT id, invoice_id, description, quantity, unit_price, amount, discount_id, created_at
 		FROM invoice_items
 		WHERE invoice_id = $1
-		ORDER BY created_at
+		ORDER BY created_at, amount DESC
 	`
 	rows, err := r.db.Query(ctx, query, invoiceID)
 	if err != nil {
@@ -132,7 +261,7 @@ func (r *InvoiceRepository) GetInvoiceItems(ctx context.Context, invoiceID uuid.
 		var item billing.InvoiceItem
 		err := rows.Scan(
 			&item.ID, &item.InvoiceID, &item.Description, &item.Quantity,
-			&item.UnitPrice, &item.Amount, &item.CreatedAt,
+			&item.UnitPrice, &item.Amount, &item.DiscountID, &item.CreatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -252,7 +381,7 @@ func (r *InvoiceRepository) List(ctx context.Context, filter InvoiceFilter) ([]*
 			c.name as client_name, c.phone as client_phone, c.address as client_address,
 			g.name as client_group_name,
 			i.invoice_number, i.period_start, i.period_end,
-			i.due_date, i.subtotal, i.tax_amount, i.discount_amount, i.total_amount,
+			i.due_date, i.subtotal, i.tax_amount, i.discount_amount, i.total_amount, i.tax_rate, i.tax_inclusive, i.tax_base, i.unique_code, i.unique_code_in_total, i.source,
 			i.paid_amount, i.currency, i.status, i.notes, i.created_at, i.updated_at, i.paid_at
 	` + baseQuery + fmt.Sprintf(" ORDER BY i.created_at DESC LIMIT $%d OFFSET $%d", argIdx, argIdx+1)
 	args = append(args, filter.PageSize, offset)
@@ -271,7 +400,7 @@ func (r *InvoiceRepository) List(ctx context.Context, filter InvoiceFilter) ([]*
 			&inv.ClientName, &inv.ClientPhone, &inv.ClientAddress,
 			&inv.ClientGroupName,
 			&inv.InvoiceNumber, &inv.PeriodStart, &inv.PeriodEnd,
-			&inv.DueDate, &inv.Subtotal, &inv.TaxAmount, &inv.DiscountAmount, &inv.TotalAmount,
+			&inv.DueDate, &inv.Subtotal, &inv.TaxAmount, &inv.DiscountAmount, &inv.TotalAmount, &inv.TaxRate, &inv.TaxInclusive, &inv.TaxBase, &inv.UniqueCode, &inv.UniqueCodeInTotal, &inv.Source,
 			&inv.PaidAmount, &inv.Currency, &inv.Status, &inv.Notes,
 			&inv.CreatedAt, &inv.UpdatedAt, &inv.PaidAt,
 		)
@@ -299,7 +428,7 @@ func (r *InvoiceRepository) UpdatePaidAmount(ctx context.Context, id uuid.UUID,
 func (r *InvoiceRepository) GetOverdueInvoices(ctx context.Context, tenantID uuid.UUID) ([]*billing.Invoice, error) {
 	query := `
 		SELECT id, tenant_id, client_id, invoice_number, period_start, period_end,
-			due_date, subtotal, tax_amount, discount_amount, total_amount,
+			due_date, subtotal, tax_amount, discount_amount, total_amount, tax_rate, tax_inclusive, tax_base, unique_code, unique_code_in_total, source,
 			paid_amount, currency, status, notes, created_at, updated_at, paid_at
 		FROM invoices
 		WHERE tenant_id = $1 AND status = 'pending' AND due_date < NOW()
@@ -317,7 +446,43 @@ func (r *InvoiceRepository) GetOverdueInvoices(ctx context.Context, tenantID uui
 		err := rows.Scan(
 			&inv.ID, &inv.TenantID, &inv.ClientID, &inv.InvoiceNumber,
 			&inv.PeriodStart, &inv.PeriodEnd, &inv.DueDate,
-			&inv.Subtotal, &inv.TaxAmount, &inv.DiscountAmount, &inv.TotalAmount,
+			&inv.Subtotal, &inv.TaxAmount, &inv.DiscountAmount, &inv.TotalAmount, &inv.TaxRate, &inv.TaxInclusive, &inv.TaxBase, &inv.UniqueCode, &inv.UniqueCodeInTotal, &inv.Source,
+			&inv.PaidAmount, &inv.Currency, &inv.Status, &inv.Notes,
+			&inv.CreatedAt, &inv.UpdatedAt, &inv.PaidAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, &inv)
+	}
+
+	return invoices, nil
+}
+
+// GetUnpaidPastDue returns pending/overdue invoices of a tenant whose due date is before dueBefore,
+// oldest due date first. Used by the isolir engine (dueBefore = now minus grace period).
+func (r *InvoiceRepository) GetUnpaidPastDue(ctx context.Context, tenantID uuid.UUID, dueBefore time.Time) ([]*billing.Invoice, error) {
+	query := `
+		SELECT id, tenant_id, client_id, invoice_number, period_start, period_end,
+			due_date, subtotal, tax_amount, discount_amount, total_amount, tax_rate, tax_inclusive, tax_base, unique_code, unique_code_in_total, source,
+			paid_amount, currency, status, notes, created_at, updated_at, paid_at
+		FROM invoices
+		WHERE tenant_id = $1 AND status IN ('pending', 'overdue') AND due_date < $2
+		ORDER BY due_date ASC
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, dueBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invoices []*billing.Invoice
+	for rows.Next() {
+		var inv billing.Invoice
+		err := rows.Scan(
+			&inv.ID, &inv.TenantID, &inv.ClientID, &inv.InvoiceNumber,
+			&inv.PeriodStart, &inv.PeriodEnd, &inv.DueDate,
+			&inv.Subtotal, &inv.TaxAmount, &inv.DiscountAmount, &inv.TotalAmount, &inv.TaxRate, &inv.TaxInclusive, &inv.TaxBase, &inv.UniqueCode, &inv.UniqueCodeInTotal, &inv.Source,
 			&inv.PaidAmount, &inv.Currency, &inv.Status, &inv.Notes,
 			&inv.CreatedAt, &inv.UpdatedAt, &inv.PaidAt,
 		)
@@ -330,10 +495,78 @@ func (r *InvoiceRepository) GetOverdueInvoices(ctx context.Context, tenantID uui
 	return invoices, nil
 }
 
+// HasUnpaidPastDue checks whether a client still has pending/overdue invoices due before dueBefore
+func (r *InvoiceRepository) HasUnpaidPastDue(ctx context.Context, tenantID, clientID uuid.UUID, dueBefore time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM invoices
+			WHERE tenant_id = $1 AND client_id = $2 AND status IN ('pending', 'overdue') AND due_date < $3
+		)
+	`
+	var exists bool
+	if err := r.db.QueryRow(ctx, query, tenantID, clientID, dueBefore).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// CountDiscountUsage counts the non-cancelled invoices of a client that carry a line for the given discount,
+// i.e. the billing cycles it was applied to (a quarterly invoice is one cycle)
+func (r *InvoiceRepository) CountDiscountUsage(ctx context.Context, tenantID, clientID, discountID uuid.UUID) (int, error) {
+	query := `
+		SELECT COUNT(DISTINCT i.id)
+		FROM invoices i
+		JOIN invoice_items ii ON ii.invoice_id = i.id
+		WHERE i.tenant_id = $1 AND i.client_id = $2 AND ii.discount_id = $3 AND i.status != 'cancelled'
+	`
+	var count int
+	if err := r.db.QueryRow(ctx, query, tenantID, clientID, discountID).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetClientBillingSummary returns invoice totals and the last payment of a client (balance is filled by the service)
+func (r *InvoiceRepository) GetClientBillingSummary(ctx context.Context, tenantID, clientID uuid.UUID) (*billing.ClientBillingSummary, error) {
+	summary := &billing.ClientBillingSummary{ClientID: clientID}
+	query := `
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE status = 'paid'),
+			COALESCE(SUM(total_amount - paid_amount) FILTER (WHERE status = 'pending'), 0),
+			COALESCE(SUM(total_amount - paid_amount) FILTER (WHERE status = 'overdue'), 0),
+			COALESCE(SUM(tax_amount), 0)
+		FROM invoices
+		WHERE tenant_id = $1 AND client_id = $2 AND status != 'cancelled'
+	`
+	err := r.db.QueryRow(ctx, query, tenantID, clientID).Scan(
+		&summary.TotalInvoices, &summary.PaidInvoices, &summary.PendingAmount, &summary.OverdueAmount, &summary.TotalTax,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	lastQuery := `
+		SELECT received_at, amount FROM payments
+		WHERE tenant_id = $1 AND client_id = $2
+		ORDER BY received_at DESC
+		LIMIT 1
+	`
+	var lastAt time.Time
+	err = r.db.QueryRow(ctx, lastQuery, tenantID, clientID).Scan(&lastAt, &summary.LastPaymentAmount)
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, err
+	}
+	if err == nil {
+		summary.LastPaymentDate = &lastAt
+	}
+	return summary, nil
+}
+
 func (r *InvoiceRepository) GetClientPendingInvoices(ctx context.Context, clientID uuid.UUID) ([]*billing.Invoice, error) {
 	query := `
 		SELECT id, tenant_id, client_id, invoice_number, period_start, period_end,
-			due_date, subtotal, tax_amount, discount_amount, total_amount,
+			due_date, subtotal, tax_amount, discount_amount, total_amount, tax_rate, tax_inclusive, tax_base, unique_code, unique_code_in_total, source,
 			paid_amount, currency, status, notes, created_at, updated_at, paid_at
 		FROM invoices
 		WHERE client_id = $1 AND status IN ('pending', 'overdue')
@@ -351,7 +584,7 @@ func (r *InvoiceRepository) GetClientPendingInvoices(ctx context.Context, client
 		err := rows.Scan(
 			&inv.ID, &inv.TenantID, &inv.ClientID, &inv.InvoiceNumber,
 			&inv.PeriodStart, &inv.PeriodEnd, &inv.DueDate,
-			&inv.Subtotal, &inv.TaxAmount, &inv.DiscountAmount, &inv.TotalAmount,
+			&inv.Subtotal, &inv.TaxAmount, &inv.DiscountAmount, &inv.TotalAmount, &inv.TaxRate, &inv.TaxInclusive, &inv.TaxBase, &inv.UniqueCode, &inv.UniqueCodeInTotal, &inv.Source,
 			&inv.PaidAmount, &inv.Currency, &inv.Status, &inv.Notes,
 			&inv.CreatedAt, &inv.UpdatedAt, &inv.PaidAt,
 		)
@@ -364,22 +597,124 @@ func (r *InvoiceRepository) GetClientPendingInvoices(ctx context.Context, client
 	return invoices, nil
 }
 
-func (r *InvoiceRepository) GenerateInvoiceNumber(ctx context.Context, tenantID uuid.UUID) (string, error) {
-	// Format: INV-YYYYMM-XXXX
-	now := time.Now()
-	prefix := fmt.Sprintf("INV-%d%02d-", now.Year(), now.Month())
-	
-	query := `
-		SELECT COUNT(*) + 1 FROM invoices 
-		WHERE tenant_id = $1 AND invoice_number LIKE $2
-	`
-	var seq int
-	err := r.db.QueryRow(ctx, query, tenantID, prefix+"%").Scan(&seq)
+// AssignUniqueCode gives an invoice the lowest code (1..maxCode) not held by another open invoice of
+// the tenant. Codes of paid or cancelled invoices are free again. With inTotal the code is added to
+// the invoice total, otherwise it is only part of the amount to transfer.
+func (r *InvoiceRepository) AssignUniqueCode(ctx context.Context, tenantID, invoiceID uuid.UUID, maxCode int, inTotal bool) (int, error) {
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
-		return "", err
+		return 0, err
 	}
-	
-	return fmt.Sprintf("%s%04d", prefix, seq), nil
+	defer tx.Rollback(ctx)
+
+	// Serialise allocations per tenant so two invoices cannot take the same free code
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('invoice_unique_code:' || $1::text))`, tenantID); err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT unique_code FROM invoices
+		WHERE tenant_id = $1 AND id <> $2 AND unique_code IS NOT NULL AND status IN ('draft', 'pending', 'overdue')
+	`, tenantID, invoiceID)
+	if err != nil {
+		return 0, err
+	}
+	var used []int
+	for rows.Next() {
+		var code int
+		if err := rows.Scan(&code); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		used = append(used, code)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	code, ok := billing.NextUniqueCode(used, maxCode)
+	if !ok {
+		return 0, ErrUniqueCodeExhausted
+	}
+	tag, err := tx.Exec(ctx, `
+		UPDATE invoices
+		SET unique_code = $2, unique_code_in_total = $3,
+			total_amount = total_amount + CASE WHEN $3 THEN $2 ELSE 0 END, updated_at = NOW()
+		WHERE id = $1 AND unique_code IS NULL
+	`, invoiceID, code, inTotal)
+	if err != nil {
+		return 0, err
+	}
+	if tag.RowsAffected() == 0 {
+		return 0, pgx.ErrNoRows
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return code, nil
+}
+
+// ListOpenReceivables returns the unpaid remainder of every pending or overdue invoice
+func (r *InvoiceRepository) ListOpenReceivables(ctx context.Context, tenantID uuid.UUID) ([]billing.OpenReceivable, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT i.id, i.invoice_number, i.client_id, COALESCE(c.name, ''), g.name, i.due_date, i.total_amount - i.paid_amount
+		FROM invoices i
+		LEFT JOIN clients c ON c.id = i.client_id
+		LEFT JOIN client_groups g ON g.id = c.group_id
+		WHERE i.tenant_id = $1 AND i.status IN ('pending', 'overdue') AND i.total_amount > i.paid_amount
+		ORDER BY c.name, i.due_date
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []billing.OpenReceivable
+	for rows.Next() {
+		var o billing.OpenReceivable
+		if err := rows.Scan(&o.InvoiceID, &o.InvoiceNumber, &o.ClientID, &o.ClientName, &o.ClientGroupName, &o.DueDate, &o.Outstanding); err != nil {
+			return nil, err
+		}
+		out = append(out, o)
+	}
+	return out, rows.Err()
 }
 
+// AccruedRevenueBy sums the issued invoices whose service period starts in [from, to) per
+// package, client group or router (of the client today)
+func (r *InvoiceRepository) AccruedRevenueBy(ctx context.Context, tenantID uuid.UUID, dim billing.RevenueDimension, from, to time.Time) ([]billing.RevenueGroup, error) {
+	join, ok := revenueGroupJoins[dim]
+	if !ok || dim == billing.RevenueByCollector {
+		return nil, fmt.Errorf("revenue dimension %q has no accrual basis", dim)
+	}
+	rows, err := r.db.Query(ctx, `
+		SELECT g.id, COALESCE(MAX(g.name), ''), SUM(i.total_amount), SUM(i.tax_amount), COUNT(*), COUNT(DISTINCT i.client_id)
+		FROM invoices i
+		LEFT JOIN clients c ON c.id = i.client_id
+		`+join+`
+		WHERE i.tenant_id = $1 AND i.status NOT IN ('draft', 'cancelled')
+			AND i.period_start >= $2 AND i.period_start < $3
+		GROUP BY g.id
+		ORDER BY 3 DESC
+	`, tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return scanRevenueGroups(rows)
+}
 
+// MonthlyAccrued sums the issued invoices per month of their service period start in [from, to)
+func (r *InvoiceRepository) MonthlyAccrued(ctx context.Context, tenantID uuid.UUID, from, to time.Time) ([]billing.MonthlyAmount, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT date_trunc('month', period_start)::date, SUM(total_amount), SUM(tax_amount), COUNT(*)
+		FROM invoices
+		WHERE tenant_id = $1 AND status NOT IN ('draft', 'cancelled') AND period_start >= $2 AND period_start < $3
+		GROUP BY 1
+		ORDER BY 1
+	`, tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return scanMonthlyAmounts(rows)
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"rrnet/internal/domain/billing"
+)
+
+type IsolirLogRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewIsolirLogRepository(db *pgxpool.Pool) *IsolirLogRepository {
+	return &IsolirLogRepository{db: db}
+}
+
+func (r *IsolirLogRepository) Create(ctx context.Context, l *billing.IsolirLog) error {
+	query := `
+		INSERT INTO isolir_logs (
+			id, tenant_id, client_id, invoice_id, action, reason, status,
+			executed_at, executed_by, is_automatic, error_msg, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''), $12)
+	`
+	_, err := r.db.Exec(ctx, query,
+		l.ID, l.TenantID, l.ClientID, l.InvoiceID, l.Action, l.Reason, l.Status,
+		l.ExecutedAt, l.ExecutedBy, l.IsAutomatic, l.ErrorMsg, l.CreatedAt,
+	)
+	return err
+}
+
+// MarkExecuted marks a log entry as executed. errMsg may carry a non-fatal note (e.g. router step skipped).
+func (r *IsolirLogRepository) MarkExecuted(ctx context.Context, id uuid.UUID, executedAt time.Time, errMsg string) error {
+	query := `UPDATE isolir_logs SET status = 'executed', executed_at = $2, error_msg = NULLIF($3, '') WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, id, executedAt, errMsg)
+	return err
+}
+
+func (r *IsolirLogRepository) MarkFailed(ctx context.Context, id uuid.UUID, errMsg string) error {
+	query := `UPDATE isolir_logs SET status = 'failed', error_msg = $2 WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, id, errMsg)
+	return err
+}
+
+// MarkClientIsolationsReverted flags all executed isolate/throttle actions of a client as reverted
+// (called after a successful reactivation).
+func (r *IsolirLogRepository) MarkClientIsolationsReverted(ctx context.Context, tenantID, clientID uuid.UUID) error {
+	query := `
+		UPDATE isolir_logs SET status = 'reverted'
+		WHERE tenant_id = $1 AND client_id = $2 AND action IN ('isolate', 'throttle') AND status = 'executed'
+	`
+	_, err := r.db.Exec(ctx, query, tenantID, clientID)
+	return err
+}
+
+// HasExecutedAction checks whether a client has an executed (not yet reverted) action, e.g. an active throttle
+func (r *IsolirLogRepository) HasExecutedAction(ctx context.Context, tenantID, clientID uuid.UUID, action billing.IsolirAction) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM isolir_logs
+			WHERE tenant_id = $1 AND client_id = $2 AND action = $3 AND status = 'executed'
+		)
+	`
+	var exists bool
+	if err := r.db.QueryRow(ctx, query, tenantID, clientID, action).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+type IsolirLogFilter struct {
+	TenantID    uuid.UUID
+	ClientID    *uuid.UUID
+	Action      *billing.IsolirAction
+	Status      *billing.IsolirStatus
+	IsAutomatic *bool
+	StartDate   *time.Time
+	EndDate     *time.Time
+	Page        int
+	PageSize    int
+}
+
+func (r *IsolirLogRepository) List(ctx context.Context, filter IsolirLogFilter) ([]*billing.IsolirLog, int, error) {
+	baseQuery := ` FROM isolir_logs l WHERE l.tenant_id = $1`
+	args := []interface{}{filter.TenantID}
+	argIdx := 2
+
+	if filter.ClientID != nil {
+		baseQuery += fmt.Sprintf(" AND l.client_id = $%d", argIdx)
+		args = append(args, *filter.ClientID)
+		argIdx++
+	}
+	if filter.Action != nil {
+		baseQuery += fmt.Sprintf(" AND l.action = $%d", argIdx)
+		args = append(args, *filter.Action)
+		argIdx++
+	}
+	if filter.Status != nil {
+		baseQuery += fmt.Sprintf(" AND l.status = $%d", argIdx)
+		args = append(args, *filter.Status)
+		argIdx++
+	}
+	if filter.IsAutomatic != nil {
+		baseQuery += fmt.Sprintf(" AND l.is_automatic = $%d", argIdx)
+		args = append(args, *filter.IsAutomatic)
+		argIdx++
+	}
+	if filter.StartDate != nil {
+		baseQuery += fmt.Sprintf(" AND l.created_at >= $%d", argIdx)
+		args = append(args, *filter.StartDate)
+		argIdx++
+	}
+	if filter.EndDate != nil {
+		baseQuery += fmt.Sprintf(" AND l.created_at <= $%d", argIdx)
+		args = append(args, *filter.EndDate)
+		argIdx++
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*)"+baseQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+	offset := (filter.Page - 1) * filter.PageSize
+
+	dataQuery := `
+		SELECT l.id, l.tenant_id, l.client_id, l.invoice_id, l.action, l.reason, l.status,
+			l.executed_at, l.executed_by, l.is_automatic, COALESCE(l.error_msg, ''), l.created_at
+	` + baseQuery + fmt.Sprintf(" ORDER BY l.created_at DESC LIMIT $%d OFFSET $%d", argIdx, argIdx+1)
+	args = append(args, filter.PageSize, offset)
+
+	rows, err := r.db.Query(ctx, dataQuery, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var logs []*billing.IsolirLog
+	for rows.Next() {
+		var l billing.IsolirLog
+		if err := rows.Scan(
+			&l.ID, &l.TenantID, &l.ClientID, &l.InvoiceID, &l.Action, &l.Reason, &l.Status,
+			&l.ExecutedAt, &l.ExecutedBy, &l.IsAutomatic, &l.ErrorMsg, &l.CreatedAt,
+		); err != nil {
+			return nil, 0, err
+		}
+		logs = append(logs, &l)
+	}
+
+	return logs, total, nil
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"rrnet/internal/domain/job"
+)
+
+type JobRunRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewJobRunRepository(db *pgxpool.Pool) *JobRunRepository {
+	return &JobRunRepository{db: db}
+}
+
+// JobRunFilter narrows the run history; without ParentID only top-level runs are listed
+type JobRunFilter struct {
+	Job      string
+	ParentID *uuid.UUID
+	TenantID *uuid.UUID
+	Status   *job.Status
+	Page     int
+	PageSize int
+}
+
+// Start records a run that has just started. Starting a run that exists (a retried task) resets
+// it for the new attempt.
+func (r *JobRunRepository) Start(ctx context.Context, run *job.Run) error {
+	if run.Stats == nil {
+		run.Stats = job.Stats{}
+	}
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO job_runs (id, job, parent_id, tenant_id, trigger, status, attempt, stats, triggered_by, started_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (id) DO UPDATE
+		SET status = EXCLUDED.status, attempt = EXCLUDED.attempt, stats = EXCLUDED.stats,
+			error = NULL, started_at = EXCLUDED.started_at, finished_at = NULL
+	`, run.ID, run.Job, run.ParentID, run.TenantID, run.Trigger, run.Status, run.Attempt, run.Stats, run.TriggeredBy, run.StartedAt)
+	return err
+}
+
+// Finish stores the outcome of a run
+func (r *JobRunRepository) Finish(ctx context.Context, run *job.Run) error {
+	if run.Stats == nil {
+		run.Stats = job.Stats{}
+	}
+	now := time.Now()
+	run.FinishedAt = &now
+	_, err := r.db.Exec(ctx, `
+		UPDATE job_runs SET status = $2, stats = $3, error = $4, finished_at = $5 WHERE id = $1
+	`, run.ID, run.Status, run.Stats, run.Error, run.FinishedAt)
+	return err
+}
+
+// List returns runs, newest first
+func (r *JobRunRepository) List(ctx context.Context, filter JobRunFilter) ([]*job.Run, int, error) {
+	where := `WHERE parent_id IS NULL`
+	args := []any{}
+	argN := 1
+	if filter.ParentID != nil {
+		where = fmt.Sprintf(`WHERE parent_id = $%d`, argN)
+		args = append(args, *filter.ParentID)
+		argN++
+	}
+	if filter.Job != "" {
+		where += fmt.Sprintf(` AND job = $%d`, argN)
+		args = append(args, filter.Job)
+		argN++
+	}
+	if filter.TenantID != nil {
+		where += fmt.Sprintf(` AND tenant_id = $%d`, argN)
+		args = append(args, *filter.TenantID)
+		argN++
+	}
+	if filter.Status != nil {
+		where += fmt.Sprintf(` AND status = $%d`, argN)
+		args = append(args, string(*filter.Status))
+		argN++
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM job_runs `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 20
+	}
+	q := `
+		SELECT id, job, parent_id, tenant_id, trigger, status, attempt, stats, error, triggered_by, started_at, finished_at
+		FROM job_runs
+		` + where + fmt.Sprintf(`
+		ORDER BY started_at DESC, id DESC
+		LIMIT $%d OFFSET $%d`, argN, argN+1)
+	args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+
+	rows, err := r.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var runs []*job.Run
+	for rows.Next() {
+		var run job.Run
+		if err := rows.Scan(
+			&run.ID, &run.Job, &run.ParentID, &run.TenantID, &run.Trigger, &run.Status, &run.Attempt,
+			&run.Stats, &run.Error, &run.TriggeredBy, &run.StartedAt, &run.FinishedAt,
+		); err != nil {
+			return nil, 0, err
+		}
+		runs = append(runs, &run)
+	}
+	return runs, total, rows.Err()
+}
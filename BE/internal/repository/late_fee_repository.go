@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"rrnet/internal/domain/billing"
+)
+
+var (
+	ErrLateFeeNotFound      = errors.New("late fee not found")
+	ErrLateFeeAlreadyWaived = errors.New("late fee is already waived")
+)
+
+// LateFeeRepository stores late fees charged on overdue invoices
+type LateFeeRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLateFeeRepository(db *pgxpool.Pool) *LateFeeRepository {
+	return &LateFeeRepository{db: db}
+}
+
+const lateFeeColumns = `
+	id, tenant_id, client_id, invoice_id, charged_invoice_id, sequence, amount, assessed_at,
+	waived_at, waived_by, COALESCE(waive_reason, ''), credit_note_id, created_at
+`
+
+func scanLateFee(row pgx.Row) (*billing.LateFee, error) {
+	var f billing.LateFee
+	err := row.Scan(
+		&f.ID, &f.TenantID, &f.ClientID, &f.InvoiceID, &f.ChargedInvoiceID, &f.Sequence, &f.Amount, &f.AssessedAt,
+		&f.WaivedAt, &f.WaivedBy, &f.WaiveReason, &f.CreditNoteID, &f.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (r *LateFeeRepository) Create(ctx context.Context, f *billing.LateFee) error {
+	query := `
+		INSERT INTO late_fees (id, tenant_id, client_id, invoice_id, charged_invoice_id, sequence, amount, assessed_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err := r.db.Exec(ctx, query,
+		f.ID, f.TenantID, f.ClientID, f.InvoiceID, f.ChargedInvoiceID, f.Sequence, f.Amount, f.AssessedAt, f.CreatedAt,
+	)
+	return err
+}
+
+func (r *LateFeeRepository) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*billing.LateFee, error) {
+	query := `SELECT ` + lateFeeColumns + ` FROM late_fees WHERE tenant_id = $1 AND id = $2`
+	f, err := scanLateFee(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrLateFeeNotFound
+	}
+	return f, err
+}
+
+// ListByInvoices returns the fees charged for, or billed on, any of the given invoices
+func (r *LateFeeRepository) ListByInvoices(ctx context.Context, tenantID uuid.UUID, invoiceIDs []uuid.UUID) ([]*billing.LateFee, error) {
+	if len(invoiceIDs) == 0 {
+		return nil, nil
+	}
+	query := `
+		SELECT ` + lateFeeColumns + `
+		FROM late_fees
+		WHERE tenant_id = $1 AND (invoice_id = ANY($2) OR charged_invoice_id = ANY($2))
+		ORDER BY invoice_id, sequence
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, invoiceIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fees []*billing.LateFee
+	for rows.Next() {
+		f, err := scanLateFee(rows)
+		if err != nil {
+			return nil, err
+		}
+		fees = append(fees, f)
+	}
+	return fees, rows.Err()
+}
+
+// MarkWaived records a waiver. Returns ErrLateFeeAlreadyWaived when the fee was waived before.
+func (r *LateFeeRepository) MarkWaived(ctx context.Context, id, waivedBy uuid.UUID, reason string, at time.Time) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE late_fees SET waived_at = $2, waived_by = $3, waive_reason = $4
+		WHERE id = $1 AND waived_at IS NULL
+	`, id, at, waivedBy, reason)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrLateFeeAlreadyWaived
+	}
+	return nil
+}
+
+// ClearWaiver undoes MarkWaived when the waiver could not be applied to the invoice
+func (r *LateFeeRepository) ClearWaiver(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE late_fees SET waived_at = NULL, waived_by = NULL, waive_reason = NULL
+		WHERE id = $1 AND credit_note_id IS NULL
+	`, id)
+	return err
+}
+
+// SetCreditNote links the credit note that took a waived fee off its invoice
+func (r *LateFeeRepository) SetCreditNote(ctx context.Context, id, creditNoteID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `UPDATE late_fees SET credit_note_id = $2 WHERE id = $1`, id, creditNoteID)
+	return err
+}
+
+// Delete removes a fee that could not be billed
+func (r *LateFeeRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM late_fees WHERE id = $1`, id)
+	return err
+}
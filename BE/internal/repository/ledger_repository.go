@@ -0,0 +1,361 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"rrnet/internal/domain/ledger"
+)
+
+var (
+	ErrLedgerAccountNotFound  = errors.New("ledger account not found")
+	ErrLedgerAccountCodeTaken = errors.New("ledger account code already exists")
+	ErrLedgerEntryNotFound    = errors.New("journal entry not found")
+)
+
+// LedgerRepository stores the accounts and journal entries of the tenants' books
+type LedgerRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLedgerRepository(db *pgxpool.Pool) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+// EnsureSystemAccounts creates the system accounts the tenant does not have yet
+func (r *LedgerRepository) EnsureSystemAccounts(ctx context.Context, tenantID uuid.UUID) error {
+	batch := &pgx.Batch{}
+	for _, a := range ledger.SystemAccounts {
+		batch.Queue(`
+			INSERT INTO ledger_accounts (id, tenant_id, code, name, type, system_key, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, NOW())
+			ON CONFLICT DO NOTHING
+		`, uuid.New(), tenantID, a.Code, a.Name, a.Type, a.Key)
+	}
+	return r.db.SendBatch(ctx, batch).Close()
+}
+
+const ledgerAccountColumns = `id, tenant_id, code, name, type, system_key, created_at`
+
+func scanLedgerAccount(row pgx.Row) (*ledger.Account, error) {
+	var a ledger.Account
+	err := row.Scan(&a.ID, &a.TenantID, &a.Code, &a.Name, &a.Type, &a.SystemKey, &a.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrLedgerAccountNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *LedgerRepository) ListAccounts(ctx context.Context, tenantID uuid.UUID) ([]*ledger.Account, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+ledgerAccountColumns+`
+		FROM ledger_accounts
+		WHERE tenant_id = $1
+		ORDER BY code
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*ledger.Account
+	for rows.Next() {
+		a, err := scanLedgerAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+func (r *LedgerRepository) GetAccount(ctx context.Context, tenantID, id uuid.UUID) (*ledger.Account, error) {
+	return scanLedgerAccount(r.db.QueryRow(ctx, `
+		SELECT `+ledgerAccountColumns+`
+		FROM ledger_accounts
+		WHERE tenant_id = $1 AND id = $2
+	`, tenantID, id))
+}
+
+func (r *LedgerRepository) CreateAccount(ctx context.Context, a *ledger.Account) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO ledger_accounts (id, tenant_id, code, name, type, system_key, created_at)
+		VALUES ($1, $2, $3, $4, $5, NULL, $6)
+	`, a.ID, a.TenantID, a.Code, a.Name, a.Type, a.CreatedAt)
+	if isUniqueViolation(err, "unique_ledger_account_code") {
+		return ErrLedgerAccountCodeTaken
+	}
+	return err
+}
+
+// Post stores a journal entry with its lines. Returns false when an entry for the same source
+// document exists already, so repeated postings are harmless.
+func (r *LedgerRepository) Post(ctx context.Context, e *ledger.Entry) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO ledger_entries (id, tenant_id, entry_date, source, source_id, description, client_id, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (tenant_id, source, source_id) DO NOTHING
+	`, e.ID, e.TenantID, e.Date, e.Source, e.SourceID, e.Description, e.ClientID, e.CreatedBy, e.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	batch := &pgx.Batch{}
+	for _, l := range e.Lines {
+		batch.Queue(`
+			INSERT INTO ledger_lines (id, entry_id, account_id, debit, credit, memo)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, l.ID, e.ID, l.AccountID, l.Debit, l.Credit, l.Memo)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+const ledgerEntryColumns = `id, tenant_id, entry_date, source, source_id, description, client_id, created_by, created_at`
+
+func scanLedgerEntry(row pgx.Row) (*ledger.Entry, error) {
+	var e ledger.Entry
+	err := row.Scan(&e.ID, &e.TenantID, &e.Date, &e.Source, &e.SourceID, &e.Description, &e.ClientID, &e.CreatedBy, &e.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrLedgerEntryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	e.Lines = []*ledger.Line{}
+	return &e, nil
+}
+
+func (r *LedgerRepository) GetEntry(ctx context.Context, tenantID, id uuid.UUID) (*ledger.Entry, error) {
+	e, err := scanLedgerEntry(r.db.QueryRow(ctx, `
+		SELECT `+ledgerEntryColumns+`
+		FROM ledger_entries
+		WHERE tenant_id = $1 AND id = $2
+	`, tenantID, id))
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadLines(ctx, []*ledger.Entry{e}); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// LedgerEntryFilter selects journal entries; dates are inclusive
+type LedgerEntryFilter struct {
+	TenantID uuid.UUID
+	Source   *ledger.Source
+	SourceID *uuid.UUID
+	ClientID *uuid.UUID
+	From     *time.Time
+	To       *time.Time
+	Page     int
+	PageSize int
+}
+
+func (r *LedgerRepository) ListEntries(ctx context.Context, filter LedgerEntryFilter) ([]*ledger.Entry, int, error) {
+	where := "tenant_id = $1"
+	args := []any{filter.TenantID}
+	if filter.Source != nil {
+		args = append(args, *filter.Source)
+		where += fmt.Sprintf(" AND source = $%d", len(args))
+	}
+	if filter.SourceID != nil {
+		args = append(args, *filter.SourceID)
+		where += fmt.Sprintf(" AND source_id = $%d", len(args))
+	}
+	if filter.ClientID != nil {
+		args = append(args, *filter.ClientID)
+		where += fmt.Sprintf(" AND client_id = $%d", len(args))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		where += fmt.Sprintf(" AND entry_date >= $%d", len(args))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		where += fmt.Sprintf(" AND entry_date <= $%d", len(args))
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM ledger_entries WHERE "+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	if filter.PageSize <= 0 {
+		filter.PageSize = 50
+	}
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	rows, err := r.db.Query(ctx, `
+		SELECT `+ledgerEntryColumns+`
+		FROM ledger_entries
+		WHERE `+where+`
+		ORDER BY entry_date DESC, created_at DESC
+		LIMIT $`+fmt.Sprint(len(args)-1)+` OFFSET $`+fmt.Sprint(len(args)),
+		args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []*ledger.Entry{}
+	for rows.Next() {
+		e, err := scanLedgerEntry(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if err := r.loadLines(ctx, entries); err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+// loadLines fills the lines of the given entries, debits first
+func (r *LedgerRepository) loadLines(ctx context.Context, entries []*ledger.Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, 0, len(entries))
+	byID := make(map[uuid.UUID]*ledger.Entry, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.ID)
+		byID[e.ID] = e
+	}
+	rows, err := r.db.Query(ctx, `
+		SELECT l.id, l.entry_id, l.account_id, l.debit, l.credit, l.memo, a.code, a.name
+		FROM ledger_lines l
+		JOIN ledger_accounts a ON a.id = l.account_id
+		WHERE l.entry_id = ANY($1)
+		ORDER BY l.entry_id, l.credit, a.code
+	`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l ledger.Line
+		if err := rows.Scan(&l.ID, &l.EntryID, &l.AccountID, &l.Debit, &l.Credit, &l.Memo, &l.AccountCode, &l.AccountName); err != nil {
+			return err
+		}
+		byID[l.EntryID].Lines = append(byID[l.EntryID].Lines, &l)
+	}
+	return rows.Err()
+}
+
+// TrialBalance returns the debit and credit totals of every account up to and including asOf
+func (r *LedgerRepository) TrialBalance(ctx context.Context, tenantID uuid.UUID, asOf time.Time) ([]*ledger.TrialBalanceRow, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT a.id, a.code, a.name, a.type, COALESCE(SUM(l.debit), 0), COALESCE(SUM(l.credit), 0)
+		FROM ledger_accounts a
+		LEFT JOIN (
+			ledger_lines l JOIN ledger_entries e ON e.id = l.entry_id AND e.entry_date <= $2
+		) ON l.account_id = a.id
+		WHERE a.tenant_id = $1
+		GROUP BY a.id, a.code, a.name, a.type
+		ORDER BY a.code
+	`, tenantID, asOf)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*ledger.TrialBalanceRow{}
+	for rows.Next() {
+		var row ledger.TrialBalanceRow
+		if err := rows.Scan(&row.AccountID, &row.AccountCode, &row.AccountName, &row.AccountType, &row.Debit, &row.Credit); err != nil {
+			return nil, err
+		}
+		out = append(out, &row)
+	}
+	return out, rows.Err()
+}
+
+// AccountTotals returns the debit and credit totals of an account before a date
+func (r *LedgerRepository) AccountTotals(ctx context.Context, accountID uuid.UUID, before time.Time) (debit, credit int64, err error) {
+	err = r.db.QueryRow(ctx, `
+		SELECT COALESCE(SUM(l.debit), 0), COALESCE(SUM(l.credit), 0)
+		FROM ledger_lines l
+		JOIN ledger_entries e ON e.id = l.entry_id
+		WHERE l.account_id = $1 AND e.entry_date < $2
+	`, accountID, before).Scan(&debit, &credit)
+	return debit, credit, err
+}
+
+// AccountLines returns the postings of an account between two dates (inclusive), oldest first
+func (r *LedgerRepository) AccountLines(ctx context.Context, accountID uuid.UUID, from, to time.Time) ([]*ledger.LedgerLine, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT e.id, e.entry_date, e.source, e.source_id, e.description, l.memo, l.debit, l.credit
+		FROM ledger_lines l
+		JOIN ledger_entries e ON e.id = l.entry_id
+		WHERE l.account_id = $1 AND e.entry_date >= $2 AND e.entry_date <= $3
+		ORDER BY e.entry_date, e.created_at, l.id
+	`, accountID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*ledger.LedgerLine{}
+	for rows.Next() {
+		var l ledger.LedgerLine
+		if err := rows.Scan(&l.EntryID, &l.Date, &l.Source, &l.SourceID, &l.Description, &l.Memo, &l.Debit, &l.Credit); err != nil {
+			return nil, err
+		}
+		out = append(out, &l)
+	}
+	return out, rows.Err()
+}
+
+// SystemAccountIDs maps the system keys of a tenant to account IDs
+func (r *LedgerRepository) SystemAccountIDs(ctx context.Context, tenantID uuid.UUID) (map[ledger.SystemKey]uuid.UUID, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT system_key, id FROM ledger_accounts WHERE tenant_id = $1 AND system_key IS NOT NULL
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[ledger.SystemKey]uuid.UUID)
+	for rows.Next() {
+		var key ledger.SystemKey
+		var id uuid.UUID
+		if err := rows.Scan(&key, &id); err != nil {
+			return nil, err
+		}
+		out[key] = id
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"rrnet/internal/domain/notification"
+)
+
+var (
+	ErrNotificationNotFound = errors.New("notification not found")
+	ErrOptOutNotFound       = errors.New("client has not opted out of notifications")
+)
+
+// NotificationRepository stores the billing notifications sent to clients and their opt-outs
+type NotificationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewNotificationRepository(db *pgxpool.Pool) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+const notificationColumns = `
+	n.id, n.tenant_id, n.event, n.client_id, n.invoice_id, n.dedup_key, n.data, n.status,
+	n.scheduled_at, n.sent_at, n.wa_log_id, n.error, n.created_at, COALESCE(c.name, '')
+`
+
+const notificationFrom = `
+	FROM billing_notifications n
+	LEFT JOIN clients c ON c.id = n.client_id
+`
+
+func scanNotification(row pgx.Row) (*notification.Notification, error) {
+	var n notification.Notification
+	err := row.Scan(
+		&n.ID, &n.TenantID, &n.Event, &n.ClientID, &n.InvoiceID, &n.DedupKey, &n.Data, &n.Status,
+		&n.ScheduledAt, &n.SentAt, &n.WALogID, &n.Error, &n.CreatedAt, &n.ClientName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// Create stores a queued notification. It returns false, without error, when a notification with
+// the same dedup key was already raised for the tenant.
+func (r *NotificationRepository) Create(ctx context.Context, n *notification.Notification) (bool, error) {
+	if n.Data == nil {
+		n.Data = map[string]string{}
+	}
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO billing_notifications (
+			id, tenant_id, event, client_id, invoice_id, dedup_key, data, status, scheduled_at, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (tenant_id, dedup_key) DO NOTHING
+	`,
+		n.ID, n.TenantID, n.Event, n.ClientID, n.InvoiceID, n.DedupKey, n.Data, n.Status, n.ScheduledAt, n.CreatedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *NotificationRepository) Get(ctx context.Context, tenantID, id uuid.UUID) (*notification.Notification, error) {
+	n, err := scanNotification(r.db.QueryRow(ctx, `SELECT `+notificationColumns+notificationFrom+` WHERE n.tenant_id = $1 AND n.id = $2`, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotificationNotFound
+	}
+	return n, err
+}
+
+// Finish records the outcome of a queued notification
+func (r *NotificationRepository) Finish(ctx context.Context, id uuid.UUID, status notification.Status, waLogID *uuid.UUID, errMsg *string) error {
+	var sentAt *time.Time
+	if status == notification.StatusSent {
+		now := time.Now()
+		sentAt = &now
+	}
+	_, err := r.db.Exec(ctx, `
+		UPDATE billing_notifications
+		SET status = $2, wa_log_id = COALESCE($3, wa_log_id), error = $4, sent_at = $5
+		WHERE id = $1 AND status = 'queued'
+	`, id, status, waLogID, errMsg, sentAt)
+	return err
+}
+
+type NotificationFilter struct {
+	TenantID uuid.UUID
+	ClientID *uuid.UUID
+	Event    *notification.Event
+	Status   *notification.Status
+	Page     int
+	PageSize int
+}
+
+// List returns notifications, newest first
+func (r *NotificationRepository) List(ctx context.Context, filter NotificationFilter) ([]*notification.Notification, int, error) {
+	where := `WHERE n.tenant_id = $1`
+	args := []any{filter.TenantID}
+	argN := 2
+	if filter.ClientID != nil {
+		where += fmt.Sprintf(` AND n.client_id = $%d`, argN)
+		args = append(args, *filter.ClientID)
+		argN++
+	}
+	if filter.Event != nil {
+		where += fmt.Sprintf(` AND n.event = $%d`, argN)
+		args = append(args, string(*filter.Event))
+		argN++
+	}
+	if filter.Status != nil {
+		where += fmt.Sprintf(` AND n.status = $%d`, argN)
+		args = append(args, string(*filter.Status))
+		argN++
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM billing_notifications n `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	q := `SELECT ` + notificationColumns + notificationFrom + where +
+		fmt.Sprintf(` ORDER BY n.created_at DESC, n.id LIMIT $%d OFFSET $%d`, argN, argN+1)
+	args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+
+	rows, err := r.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	out := []*notification.Notification{}
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, n)
+	}
+	return out, total, rows.Err()
+}
+
+// ========== Opt-outs ==========
+
+func (r *NotificationRepository) IsOptedOut(ctx context.Context, tenantID, clientID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM client_notification_opt_outs WHERE tenant_id = $1 AND client_id = $2)
+	`, tenantID, clientID).Scan(&exists)
+	return exists, err
+}
+
+// OptOut stores or updates the opt-out of a client
+func (r *NotificationRepository) OptOut(ctx context.Context, o *notification.OptOut) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO client_notification_opt_outs (tenant_id, client_id, reason, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (client_id) DO UPDATE SET reason = EXCLUDED.reason
+	`, o.TenantID, o.ClientID, o.Reason, o.CreatedBy, o.CreatedAt)
+	return err
+}
+
+func (r *NotificationRepository) RemoveOptOut(ctx context.Context, tenantID, clientID uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM client_notification_opt_outs WHERE tenant_id = $1 AND client_id = $2`, tenantID, clientID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrOptOutNotFound
+	}
+	return nil
+}
+
+func (r *NotificationRepository) ListOptOuts(ctx context.Context, tenantID uuid.UUID) ([]*notification.OptOut, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT o.tenant_id, o.client_id, o.reason, o.created_by, o.created_at, c.name
+		FROM client_notification_opt_outs o
+		JOIN clients c ON c.id = o.client_id
+		WHERE o.tenant_id = $1
+		ORDER BY c.name
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*notification.OptOut{}
+	for rows.Next() {
+		var o notification.OptOut
+		if err := rows.Scan(&o.TenantID, &o.ClientID, &o.Reason, &o.CreatedBy, &o.CreatedAt, &o.ClientName); err != nil {
+			return nil, err
+		}
+		out = append(out, &o)
+	}
+	return out, rows.Err()
+}
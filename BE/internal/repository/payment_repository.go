@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -12,6 +13,12 @@ import (
 	"rrnet/internal/domain/billing"
 )
 
+var (
+	ErrInvoiceNotPayable = errors.New("invoice is not outstanding or the allocation exceeds its remaining amount")
+	// ErrGatewayPaymentRecorded is returned when the payment of a gateway request is already stored
+	ErrGatewayPaymentRecorded = errors.New("payment of this payment request is already recorded")
+)
+
 type PaymentRepository struct {
 	db *pgxpool.Pool
 }
@@ -20,28 +27,143 @@ func NewPaymentRepository(db *pgxpool.Pool) *PaymentRepository {
 	return &PaymentRepository{db: db}
 }
 
-func (r *PaymentRepository) Create(ctx context.Context, payment *billing.Payment) error {
-	query := `
+// Create stores a payment. With numbering, its receipt number is drawn from the tenant's sequence
+// in the same transaction and set on payment.
+func (r *PaymentRepository) Create(ctx context.Context, payment *billing.Payment, numbering *billing.DocumentNumbering) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := insertPayment(ctx, tx, payment, numbering); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// insertPayment stores a payment inside tx, numbering its receipt when numbering is set
+func insertPayment(ctx context.Context, tx pgx.Tx, payment *billing.Payment, numbering *billing.DocumentNumbering) error {
+	if numbering != nil {
+		number, err := nextDocumentNumber(ctx, tx, payment.TenantID, *numbering)
+		if err != nil {
+			return err
+		}
+		payment.ReceiptNumber = &number
+	}
+	_, err := tx.Exec(ctx, `
 		INSERT INTO payments (
 			id, tenant_id, invoice_id, client_id, amount, currency, method,
-			reference, collector_id, notes, received_at, created_at, created_by_user_id
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
-	`
-	_, err := r.db.Exec(ctx, query,
+			reference, collector_id, notes, received_at, created_at, created_by_user_id, client_payment_id, receipt_number,
+			payment_request_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	`,
 		payment.ID, payment.TenantID, payment.InvoiceID, payment.ClientID,
 		payment.Amount, payment.Currency, payment.Method, payment.Reference,
 		payment.CollectorID, payment.Notes, payment.ReceivedAt, payment.CreatedAt,
-		payment.CreatedByUserID,
+		payment.CreatedByUserID, payment.ClientPaymentID, payment.ReceiptNumber,
+		payment.PaymentRequestID,
 	)
+	if isUniqueViolation(err, "idx_payments_payment_request") {
+		return ErrGatewayPaymentRecorded
+	}
 	return err
 }
 
+// CreateClientPayment stores a client-level payment with its allocations (cp.Allocations) in one
+// transaction: each allocation becomes a payment row and raises the invoice's paid amount, marking it
+// paid once covered. credit (optional) is the unallocated remainder added to the client balance.
+// With numbering, every allocation gets its receipt number. Returns the IDs of the invoices that
+// became paid.
+func (r *PaymentRepository) CreateClientPayment(ctx context.Context, cp *billing.ClientPayment, credit *billing.BalanceEntry, numbering *billing.DocumentNumbering) ([]uuid.UUID, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO client_payments (
+			id, tenant_id, client_id, amount, currency, method, reference, collector_id, notes,
+			credited_amount, received_at, created_at, created_by_user_id, payment_request_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`,
+		cp.ID, cp.TenantID, cp.ClientID, cp.Amount, cp.Currency, cp.Method, cp.Reference, cp.CollectorID, cp.Notes,
+		cp.CreditedAmount, cp.ReceivedAt, cp.CreatedAt, cp.CreatedByUserID, cp.PaymentRequestID,
+	)
+	if isUniqueViolation(err, "idx_client_payments_payment_request") {
+		return nil, ErrGatewayPaymentRecorded
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var paid []uuid.UUID
+	for _, p := range cp.Allocations {
+		var status billing.InvoiceStatus
+		err := tx.QueryRow(ctx, `
+			UPDATE invoices
+			SET paid_amount = paid_amount + $4,
+				status = CASE WHEN paid_amount + $4 >= total_amount THEN 'paid' ELSE status END,
+				paid_at = CASE WHEN paid_amount + $4 >= total_amount THEN $5 ELSE paid_at END,
+				updated_at = NOW()
+			WHERE id = $1 AND tenant_id = $2 AND client_id = $3
+				AND status IN ('pending', 'overdue') AND paid_amount + $4 <= total_amount
+			RETURNING status
+		`, p.InvoiceID, cp.TenantID, cp.ClientID, p.Amount, cp.CreatedAt).Scan(&status)
+		if err == pgx.ErrNoRows {
+			return nil, ErrInvoiceNotPayable
+		}
+		if err != nil {
+			return nil, err
+		}
+		if status == billing.InvoiceStatusPaid {
+			paid = append(paid, p.InvoiceID)
+		}
+
+		if err := insertPayment(ctx, tx, p, numbering); err != nil {
+			return nil, err
+		}
+	}
+
+	if credit != nil {
+		if err := appendBalanceEntry(ctx, tx, credit); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return paid, nil
+}
+
+// GetByPaymentRequest finds what was recorded for a gateway payment request: the payment, or for a
+// client payment its first allocation. recorded is false when nothing is stored yet; paymentID is
+// nil when a client payment went to the balance entirely.
+func (r *PaymentRepository) GetByPaymentRequest(ctx context.Context, requestID uuid.UUID) (recorded bool, paymentID *uuid.UUID, err error) {
+	err = r.db.QueryRow(ctx, `
+		SELECT id FROM payments WHERE payment_request_id = $1
+		UNION ALL
+		SELECT (SELECT p.id FROM payments p WHERE p.client_payment_id = cp.id ORDER BY p.created_at, p.id LIMIT 1)
+		FROM client_payments cp WHERE cp.payment_request_id = $1
+		LIMIT 1
+	`, requestID).Scan(&paymentID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, err
+	}
+	return true, paymentID, nil
+}
+
 func (r *PaymentRepository) GetByID(ctx context.Context, id uuid.UUID) (*billing.Payment, error) {
 	query := `
 		SELECT p.id, p.tenant_id, p.invoice_id, p.client_id,
 			c.name as client_name,
 			p.amount, p.currency, p.method,
-			p.reference, p.collector_id, p.notes, p.received_at, p.created_at, p.created_by_user_id
+			p.reference, p.collector_id, p.notes, p.received_at, p.created_at, p.created_by_user_id, p.client_payment_id, p.receipt_number, p.payment_request_id
 		FROM payments p
 		LEFT JOIN clients c ON c.id = p.client_id
 		WHERE p.id = $1
@@ -52,7 +174,7 @@ func (r *PaymentRepository) GetByID(ctx context.Context, id uuid.UUID) (*billing
 		&payment.ClientName,
 		&payment.Amount, &payment.Currency, &payment.Method, &payment.Reference,
 		&payment.CollectorID, &payment.Notes, &payment.ReceivedAt, &payment.CreatedAt,
-		&payment.CreatedByUserID,
+		&payment.CreatedByUserID, &payment.ClientPaymentID, &payment.ReceiptNumber, &payment.PaymentRequestID,
 	)
 	if err == pgx.ErrNoRows {
 		return nil, fmt.Errorf("payment not found")
@@ -65,7 +187,7 @@ func (r *PaymentRepository) ListByInvoice(ctx context.Context, invoiceID uuid.UU
 		SELECT p.id, p.tenant_id, p.invoice_id, p.client_id,
 			c.name as client_name,
 			p.amount, p.currency, p.method,
-			p.reference, p.collector_id, p.notes, p.received_at, p.created_at, p.created_by_user_id
+			p.reference, p.collector_id, p.notes, p.received_at, p.created_at, p.created_by_user_id, p.client_payment_id, p.receipt_number, p.payment_request_id
 		FROM payments p
 		LEFT JOIN clients c ON c.id = p.client_id
 		WHERE p.invoice_id = $1
@@ -85,7 +207,7 @@ func (r *PaymentRepository) ListByInvoice(ctx context.Context, invoiceID uuid.UU
 			&p.ClientName,
 			&p.Amount, &p.Currency, &p.Method, &p.Reference,
 			&p.CollectorID, &p.Notes, &p.ReceivedAt, &p.CreatedAt,
-			&p.CreatedByUserID,
+			&p.CreatedByUserID, &p.ClientPaymentID, &p.ReceiptNumber, &p.PaymentRequestID,
 		)
 		if err != nil {
 			return nil, err
@@ -158,7 +280,7 @@ func (r *PaymentRepository) List(ctx context.Context, filter PaymentFilter) ([]*
 		SELECT p.id, p.tenant_id, p.invoice_id, p.client_id,
 			c.name as client_name,
 			p.amount, p.currency, p.method,
-			p.reference, p.collector_id, p.notes, p.received_at, p.created_at, p.created_by_user_id
+			p.reference, p.collector_id, p.notes, p.received_at, p.created_at, p.created_by_user_id, p.client_payment_id, p.receipt_number, p.payment_request_id
 	` + baseQuery + fmt.Sprintf(" ORDER BY p.received_at DESC LIMIT $%d OFFSET $%d", argIdx, argIdx+1)
 	args = append(args, filter.PageSize, offset)
 
@@ -176,7 +298,7 @@ func (r *PaymentRepository) List(ctx context.Context, filter PaymentFilter) ([]*
 			&p.ClientName,
 			&p.Amount, &p.Currency, &p.Method, &p.Reference,
 			&p.CollectorID, &p.Notes, &p.ReceivedAt, &p.CreatedAt,
-			&p.CreatedByUserID,
+			&p.CreatedByUserID, &p.ClientPaymentID, &p.ReceiptNumber, &p.PaymentRequestID,
 		)
 		if err != nil {
 			return nil, 0, err
@@ -225,7 +347,9 @@ func (r *PaymentRepository) GetSummary(ctx context.Context, tenantID uuid.UUID,
 			COUNT(*) FILTER (WHERE status = 'paid') as paid_invoices,
 			COALESCE(SUM(paid_amount) FILTER (WHERE status = 'paid'), 0) as total_revenue,
 			COALESCE(SUM(total_amount - paid_amount) FILTER (WHERE status = 'pending'), 0) as pending_amount,
-			COALESCE(SUM(total_amount - paid_amount) FILTER (WHERE status = 'overdue'), 0) as overdue_amount
+			COALESCE(SUM(total_amount - paid_amount) FILTER (WHERE status = 'overdue'), 0) as overdue_amount,
+			COALESCE(SUM(tax_amount) FILTER (WHERE status != 'cancelled'), 0) as tax_invoiced,
+			COALESCE(SUM(tax_amount) FILTER (WHERE status = 'paid'), 0) as tax_collected
 		FROM invoices
 		WHERE tenant_id = $1 AND created_at BETWEEN $2 AND $3
 	`
@@ -233,6 +357,7 @@ func (r *PaymentRepository) GetSummary(ctx context.Context, tenantID uuid.UUID,
 	err := r.db.QueryRow(ctx, query, tenantID, startDate, endDate).Scan(
 		&summary.TotalInvoices, &summary.PendingInvoices, &summary.OverdueInvoices,
 		&summary.PaidInvoices, &summary.TotalRevenue, &summary.PendingAmount, &summary.OverdueAmount,
+		&summary.TaxInvoiced, &summary.TaxCollected,
 	)
 	if err != nil {
 		return nil, err
@@ -250,3 +375,75 @@ func (r *PaymentRepository) GetSummary(ctx context.Context, tenantID uuid.UUID,
 }
 
 
+
+// revenueGroupJoins joins the entity revenue is grouped by (alias g) onto clients (alias c); the
+// collector is taken from the payment (alias p)
+var revenueGroupJoins = map[billing.RevenueDimension]string{
+	billing.RevenueByPackage:   `LEFT JOIN service_packages g ON g.id = c.service_package_id`,
+	billing.RevenueByGroup:     `LEFT JOIN client_groups g ON g.id = c.group_id`,
+	billing.RevenueByRouter:    `LEFT JOIN routers g ON g.id = c.router_id`,
+	billing.RevenueByCollector: `LEFT JOIN users g ON g.id = p.collector_id`,
+}
+
+// CollectedRevenueBy sums the payments received in [from, to) per package, client group, router
+// (of the client today) or collector
+func (r *PaymentRepository) CollectedRevenueBy(ctx context.Context, tenantID uuid.UUID, dim billing.RevenueDimension, from, to time.Time) ([]billing.RevenueGroup, error) {
+	join, ok := revenueGroupJoins[dim]
+	if !ok {
+		return nil, fmt.Errorf("unknown revenue dimension %q", dim)
+	}
+	rows, err := r.db.Query(ctx, `
+		SELECT g.id, COALESCE(MAX(g.name), ''), SUM(p.amount), 0, COUNT(*), COUNT(DISTINCT p.client_id)
+		FROM payments p
+		LEFT JOIN clients c ON c.id = p.client_id
+		`+join+`
+		WHERE p.tenant_id = $1 AND p.received_at >= $2 AND p.received_at < $3
+		GROUP BY g.id
+		ORDER BY 3 DESC
+	`, tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return scanRevenueGroups(rows)
+}
+
+// MonthlyCollected sums the payments received per month in [from, to)
+func (r *PaymentRepository) MonthlyCollected(ctx context.Context, tenantID uuid.UUID, from, to time.Time) ([]billing.MonthlyAmount, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT date_trunc('month', received_at)::date, SUM(amount), 0, COUNT(*)
+		FROM payments
+		WHERE tenant_id = $1 AND received_at >= $2 AND received_at < $3
+		GROUP BY 1
+		ORDER BY 1
+	`, tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return scanMonthlyAmounts(rows)
+}
+
+func scanRevenueGroups(rows pgx.Rows) ([]billing.RevenueGroup, error) {
+	defer rows.Close()
+	var out []billing.RevenueGroup
+	for rows.Next() {
+		var g billing.RevenueGroup
+		if err := rows.Scan(&g.ID, &g.Name, &g.Amount, &g.Tax, &g.Count, &g.Clients); err != nil {
+			return nil, err
+		}
+		out = append(out, g)
+	}
+	return out, rows.Err()
+}
+
+func scanMonthlyAmounts(rows pgx.Rows) ([]billing.MonthlyAmount, error) {
+	defer rows.Close()
+	var out []billing.MonthlyAmount
+	for rows.Next() {
+		var m billing.MonthlyAmount
+		if err := rows.Scan(&m.Month, &m.Amount, &m.Tax, &m.Count); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
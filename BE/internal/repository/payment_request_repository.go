@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"rrnet/internal/domain/billing"
+)
+
+var ErrPaymentRequestNotFound = errors.New("payment request not found")
+
+// PaymentRequestRepository stores payment gateway requests and received webhooks
+type PaymentRequestRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPaymentRequestRepository(db *pgxpool.Pool) *PaymentRequestRepository {
+	return &PaymentRequestRepository{db: db}
+}
+
+const paymentRequestColumns = `
+	id, tenant_id, invoice_id, client_id, provider, method, COALESCE(channel, ''), order_id,
+	COALESCE(reference, ''), amount, status, COALESCE(va_number, ''), COALESCE(qr_string, ''),
+	COALESCE(payment_url, ''), expires_at, payment_id, paid_at, created_by, created_at, updated_at
+`
+
+func scanPaymentRequest(row pgx.Row) (*billing.PaymentRequest, error) {
+	var p billing.PaymentRequest
+	err := row.Scan(
+		&p.ID, &p.TenantID, &p.InvoiceID, &p.ClientID, &p.Provider, &p.Method, &p.Channel, &p.OrderID,
+		&p.Reference, &p.Amount, &p.Status, &p.VANumber, &p.QRString,
+		&p.PaymentURL, &p.ExpiresAt, &p.PaymentID, &p.PaidAt, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPaymentRequestNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *PaymentRequestRepository) Create(ctx context.Context, p *billing.PaymentRequest) error {
+	query := `
+		INSERT INTO payment_requests (
+			id, tenant_id, invoice_id, client_id, provider, method, channel, order_id, reference, amount,
+			status, va_number, qr_string, payment_url, expires_at, created_by, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, NULLIF($9, ''), $10,
+			$11, NULLIF($12, ''), NULLIF($13, ''), NULLIF($14, ''), $15, $16, $17, $17)
+	`
+	_, err := r.db.Exec(ctx, query,
+		p.ID, p.TenantID, p.InvoiceID, p.ClientID, p.Provider, p.Method, p.Channel, p.OrderID, p.Reference, p.Amount,
+		p.Status, p.VANumber, p.QRString, p.PaymentURL, p.ExpiresAt, p.CreatedBy, p.CreatedAt,
+	)
+	return err
+}
+
+func (r *PaymentRequestRepository) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*billing.PaymentRequest, error) {
+	query := `SELECT ` + paymentRequestColumns + ` FROM payment_requests WHERE tenant_id = $1 AND id = $2`
+	return scanPaymentRequest(r.db.QueryRow(ctx, query, tenantID, id))
+}
+
+// GetByOrderID finds a request by the order ID (or, when the webhook has none, the gateway reference)
+func (r *PaymentRequestRepository) GetByOrderID(ctx context.Context, tenantID uuid.UUID, provider, orderID, reference string) (*billing.PaymentRequest, error) {
+	query := `
+		SELECT ` + paymentRequestColumns + `
+		FROM payment_requests
+		WHERE tenant_id = $1 AND provider = $2
+			AND ((order_id = $3 AND $3 <> '') OR (reference = $4 AND $4 <> ''))
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	return scanPaymentRequest(r.db.QueryRow(ctx, query, tenantID, provider, orderID, reference))
+}
+
+func (r *PaymentRequestRepository) ListByInvoice(ctx context.Context, tenantID, invoiceID uuid.UUID) ([]*billing.PaymentRequest, error) {
+	query := `
+		SELECT ` + paymentRequestColumns + `
+		FROM payment_requests
+		WHERE tenant_id = $1 AND invoice_id = $2
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*billing.PaymentRequest
+	for rows.Next() {
+		p, err := scanPaymentRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// ClaimPaid marks a request paid. Returns false when it was already paid (a repeated webhook), so the
+// payment is recorded only once.
+func (r *PaymentRequestRepository) ClaimPaid(ctx context.Context, id uuid.UUID, paidAt time.Time) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE payment_requests SET status = 'paid', paid_at = $2, updated_at = NOW()
+		WHERE id = $1 AND status <> 'paid'
+	`, id, paidAt)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ReleaseClaim undoes ClaimPaid when the payment could not be recorded, so the webhook retry can
+// claim the request again. Payments are keyed by their request (see
+// PaymentRepository.GetByPaymentRequest), so a retry never records the same payment twice.
+func (r *PaymentRequestRepository) ReleaseClaim(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE payment_requests SET status = 'pending', paid_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND payment_id IS NULL
+	`, id)
+	return err
+}
+
+func (r *PaymentRequestRepository) SetPayment(ctx context.Context, id, paymentID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `UPDATE payment_requests SET payment_id = $2, updated_at = NOW() WHERE id = $1`, id, paymentID)
+	return err
+}
+
+// UpdateStatus moves a pending request to expired or failed
+func (r *PaymentRequestRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status billing.PaymentRequestStatus) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE payment_requests SET status = $2, updated_at = NOW()
+		WHERE id = $1 AND status = 'pending'
+	`, id, status)
+	return err
+}
+
+func (r *PaymentRequestRepository) LogWebhook(ctx context.Context, e *billing.PaymentWebhookEvent) error {
+	query := `
+		INSERT INTO payment_webhook_events (id, tenant_id, provider, order_id, reference, status, verified, error, payload, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), $7, NULLIF($8, ''), $9, $10)
+	`
+	_, err := r.db.Exec(ctx, query,
+		e.ID, e.TenantID, e.Provider, e.OrderID, e.Reference, e.Status, e.Verified, e.Error, e.Payload, e.CreatedAt,
+	)
+	return err
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"rrnet/internal/domain/billing"
+)
+
+var ErrRecurringChargeNotFound = errors.New("recurring charge not found")
+
+type RecurringChargeRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRecurringChargeRepository(db *pgxpool.Pool) *RecurringChargeRepository {
+	return &RecurringChargeRepository{db: db}
+}
+
+const recurringChargeColumns = `id, tenant_id, client_id, description, amount, quantity, start_date, end_date, created_at, updated_at`
+
+func (r *RecurringChargeRepository) Create(ctx context.Context, c *billing.RecurringCharge) error {
+	query := `
+		INSERT INTO client_recurring_charges (` + recurringChargeColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	_, err := r.db.Exec(ctx, query,
+		c.ID, c.TenantID, c.ClientID, c.Description, c.Amount, c.Quantity, c.StartDate, c.EndDate, c.CreatedAt, c.UpdatedAt,
+	)
+	return err
+}
+
+func (r *RecurringChargeRepository) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*billing.RecurringCharge, error) {
+	query := `SELECT ` + recurringChargeColumns + ` FROM client_recurring_charges WHERE tenant_id = $1 AND id = $2`
+	charges, err := r.query(ctx, query, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(charges) == 0 {
+		return nil, ErrRecurringChargeNotFound
+	}
+	return charges[0], nil
+}
+
+// ListByClient returns all charges of a client, including ended ones, by start date
+func (r *RecurringChargeRepository) ListByClient(ctx context.Context, tenantID, clientID uuid.UUID) ([]*billing.RecurringCharge, error) {
+	query := `
+		SELECT ` + recurringChargeColumns + ` FROM client_recurring_charges
+		WHERE tenant_id = $1 AND client_id = $2
+		ORDER BY start_date, created_at
+	`
+	return r.query(ctx, query, tenantID, clientID)
+}
+
+// ListActiveInPeriod returns the charges of a client that cover at least one day of [start, end]
+func (r *RecurringChargeRepository) ListActiveInPeriod(ctx context.Context, tenantID, clientID uuid.UUID, start, end time.Time) ([]*billing.RecurringCharge, error) {
+	query := `
+		SELECT ` + recurringChargeColumns + ` FROM client_recurring_charges
+		WHERE tenant_id = $1 AND client_id = $2
+			AND start_date <= $4::date AND (end_date IS NULL OR end_date >= $3::date)
+		ORDER BY start_date, created_at
+	`
+	return r.query(ctx, query, tenantID, clientID, start, end)
+}
+
+func (r *RecurringChargeRepository) Update(ctx context.Context, c *billing.RecurringCharge) error {
+	query := `
+		UPDATE client_recurring_charges
+		SET description = $3, amount = $4, quantity = $5, start_date = $6, end_date = $7, updated_at = $8
+		WHERE tenant_id = $1 AND id = $2
+	`
+	tag, err := r.db.Exec(ctx, query, c.TenantID, c.ID, c.Description, c.Amount, c.Quantity, c.StartDate, c.EndDate, c.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRecurringChargeNotFound
+	}
+	return nil
+}
+
+func (r *RecurringChargeRepository) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM client_recurring_charges WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRecurringChargeNotFound
+	}
+	return nil
+}
+
+func (r *RecurringChargeRepository) query(ctx context.Context, query string, args ...interface{}) ([]*billing.RecurringCharge, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	charges := []*billing.RecurringCharge{}
+	for rows.Next() {
+		var c billing.RecurringCharge
+		if err := rows.Scan(
+			&c.ID, &c.TenantID, &c.ClientID, &c.Description, &c.Amount, &c.Quantity, &c.StartDate, &c.EndDate, &c.CreatedAt, &c.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		charges = append(charges, &c)
+	}
+	return charges, rows.Err()
+}
@@ -21,6 +21,14 @@ type ServicePackageRepository struct {
 	db *pgxpool.Pool
 }
 
+// cycleOptions stores a package without cycle options as an empty list rather than JSON null
+func cycleOptions(options []service_package.CycleOption) []service_package.CycleOption {
+	if options == nil {
+		return []service_package.CycleOption{}
+	}
+	return options
+}
+
 func NewServicePackageRepository(db *pgxpool.Pool) *ServicePackageRepository {
 	return &ServicePackageRepository{db: db}
 }
@@ -29,14 +37,16 @@ func (r *ServicePackageRepository) Create(ctx context.Context, p *service_packag
 	query := `
 		INSERT INTO service_packages (
 			id, tenant_id, name, category, pricing_model,
-			price_monthly, price_per_device, billing_day_default,
+			price_monthly, price_per_device, billing_day_default, billing_mode,
+			billing_cycles, default_cycle_months,
 			network_profile_id, is_active, metadata,
 			created_at, updated_at
-		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16)
 	`
 	_, err := r.db.Exec(ctx, query,
 		p.ID, p.TenantID, p.Name, p.Category, p.PricingModel,
-		p.PriceMonthly, p.PricePerDevice, p.BillingDayDefault,
+		p.PriceMonthly, p.PricePerDevice, p.BillingDayDefault, p.BillingMode,
+		cycleOptions(p.BillingCycles), p.DefaultCycleMonths,
 		p.NetworkProfileID, p.IsActive, p.Metadata,
 		p.CreatedAt, p.UpdatedAt,
 	)
@@ -46,7 +56,8 @@ func (r *ServicePackageRepository) Create(ctx context.Context, p *service_packag
 func (r *ServicePackageRepository) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*service_package.ServicePackage, error) {
 	query := `
 		SELECT id, tenant_id, name, category, pricing_model,
-		       price_monthly, price_per_device, billing_day_default,
+		       price_monthly, price_per_device, billing_day_default, billing_mode,
+		       billing_cycles, default_cycle_months,
 		       network_profile_id, is_active, metadata,
 		       created_at, updated_at, deleted_at
 		FROM service_packages
@@ -55,7 +66,8 @@ func (r *ServicePackageRepository) GetByID(ctx context.Context, tenantID, id uui
 	var p service_package.ServicePackage
 	err := r.db.QueryRow(ctx, query, id, tenantID).Scan(
 		&p.ID, &p.TenantID, &p.Name, &p.Category, &p.PricingModel,
-		&p.PriceMonthly, &p.PricePerDevice, &p.BillingDayDefault,
+		&p.PriceMonthly, &p.PricePerDevice, &p.BillingDayDefault, &p.BillingMode,
+			&p.BillingCycles, &p.DefaultCycleMonths,
 		&p.NetworkProfileID, &p.IsActive, &p.Metadata,
 		&p.CreatedAt, &p.UpdatedAt, &p.DeletedAt,
 	)
@@ -71,7 +83,8 @@ func (r *ServicePackageRepository) GetByID(ctx context.Context, tenantID, id uui
 func (r *ServicePackageRepository) ListByTenant(ctx context.Context, tenantID uuid.UUID, activeOnly bool, category *service_package.Category) ([]*service_package.ServicePackage, error) {
 	query := `
 		SELECT id, tenant_id, name, category, pricing_model,
-		       price_monthly, price_per_device, billing_day_default,
+		       price_monthly, price_per_device, billing_day_default, billing_mode,
+		       billing_cycles, default_cycle_months,
 		       network_profile_id, is_active, metadata,
 		       created_at, updated_at, deleted_at
 		FROM service_packages
@@ -102,7 +115,8 @@ func (r *ServicePackageRepository) ListByTenant(ctx context.Context, tenantID uu
 		var p service_package.ServicePackage
 		if err := rows.Scan(
 			&p.ID, &p.TenantID, &p.Name, &p.Category, &p.PricingModel,
-			&p.PriceMonthly, &p.PricePerDevice, &p.BillingDayDefault,
+			&p.PriceMonthly, &p.PricePerDevice, &p.BillingDayDefault, &p.BillingMode,
+			&p.BillingCycles, &p.DefaultCycleMonths,
 			&p.NetworkProfileID, &p.IsActive, &p.Metadata,
 			&p.CreatedAt, &p.UpdatedAt, &p.DeletedAt,
 		); err != nil {
@@ -125,6 +139,9 @@ func (r *ServicePackageRepository) Update(ctx context.Context, p *service_packag
 		    network_profile_id = $9,
 		    is_active = $10,
 		    metadata = $11,
+		    billing_mode = $12,
+		    billing_cycles = $13,
+		    default_cycle_months = $14,
 		    updated_at = NOW()
 		WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
 	`
@@ -132,7 +149,8 @@ func (r *ServicePackageRepository) Update(ctx context.Context, p *service_packag
 		p.ID, p.TenantID,
 		p.Name, p.Category, p.PricingModel,
 		p.PriceMonthly, p.PricePerDevice, p.BillingDayDefault,
-		p.NetworkProfileID, p.IsActive, p.Metadata,
+		p.NetworkProfileID, p.IsActive, p.Metadata, p.BillingMode,
+		cycleOptions(p.BillingCycles), p.DefaultCycleMonths,
 	)
 	if err != nil {
 		return err
@@ -0,0 +1,626 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/domain/billing"
+	bankstmt "rrnet/internal/infra/bank_statement"
+	"rrnet/internal/repository"
+)
+
+var (
+	ErrBankStatementUnreadable = errors.New("bank statement could not be read")
+	ErrBankStatementBank       = errors.New("bank must be bca, mandiri, bri, bni or other")
+	ErrBankLineConfirmed       = errors.New("bank statement line is already confirmed")
+	ErrBankLineNoTarget        = errors.New("select the invoice or client this transfer belongs to")
+	ErrBankLineInvoiceInvalid  = errors.New("cancelled invoices cannot be paid")
+)
+
+// Matching weights. A credit is auto-matched when one invoice scores reconcileAutoScore with the
+// exact amount (or the amount carrying its unique code) and no other invoice comes within
+// reconcileAutoMargin; invoices scoring at least reconcileSuggestScore are offered as suggestions.
+const (
+	reconcileScoreAmount        = 40
+	reconcileScoreUniqueCode    = 70 // amount including the invoice's unique code
+	reconcileScoreInvoiceNumber = 60
+	reconcileScoreClientCode    = 40
+	reconcileScorePhone         = 35
+	reconcileScoreName          = 30
+	reconcileScoreNamePartial   = 15
+	reconcileScoreDateWindow    = 10
+	reconcileScoreOutsideWindow = -30
+
+	reconcileAutoScore     = 80
+	reconcileAutoMargin    = 20
+	reconcileSuggestScore  = 40
+	reconcileMaxCandidates = 3
+
+	// Transfers are expected from a week before the billing period until two months after the due date
+	reconcileDaysBefore = 7
+	reconcileDaysAfter  = 60
+)
+
+// ImportBankStatementRequest describes an uploaded mutation file
+type ImportBankStatementRequest struct {
+	FileName string
+	Bank     string // bca, mandiri, bri, bni, other; empty to detect
+}
+
+// BankStatementResult is a statement with its credits grouped by reconciliation bucket
+type BankStatementResult struct {
+	Statement   *billing.BankStatement       `json:"statement"`
+	AutoMatched []*billing.BankStatementLine `json:"auto_matched"`
+	Suggested   []*billing.BankStatementLine `json:"suggested"`
+	Unmatched   []*billing.BankStatementLine `json:"unmatched"`
+	Confirmed   []*billing.BankStatementLine `json:"confirmed"`
+	Ignored     []*billing.BankStatementLine `json:"ignored"`
+}
+
+// ConfirmBankLineRequest overrides the matched invoice. With only a client, the amount is allocated
+// to the client's open invoices, oldest first.
+type ConfirmBankLineRequest struct {
+	InvoiceID *uuid.UUID `json:"invoice_id,omitempty"`
+	ClientID  *uuid.UUID `json:"client_id,omitempty"`
+}
+
+// ConfirmAutoMatchedResult reports a bulk confirmation
+type ConfirmAutoMatchedResult struct {
+	Confirmed int                      `json:"confirmed"`
+	Failed    []BankLineConfirmFailure `json:"failed"`
+}
+
+type BankLineConfirmFailure struct {
+	LineID uuid.UUID `json:"line_id"`
+	Error  string    `json:"error"`
+}
+
+type BankReconciliationService struct {
+	statementRepo  *repository.BankStatementRepository
+	invoiceRepo    *repository.InvoiceRepository
+	billingService *BillingService
+}
+
+func NewBankReconciliationService(
+	statementRepo *repository.BankStatementRepository,
+	invoiceRepo *repository.InvoiceRepository,
+	billingService *BillingService,
+) *BankReconciliationService {
+	return &BankReconciliationService{
+		statementRepo:  statementRepo,
+		invoiceRepo:    invoiceRepo,
+		billingService: billingService,
+	}
+}
+
+// ImportStatement parses a mutation file, stores its new credits and matches them with open
+// invoices. Credits already imported with an earlier (overlapping) statement are skipped.
+func (s *BankReconciliationService) ImportStatement(ctx context.Context, tenantID, userID uuid.UUID, req ImportBankStatementRequest, r io.Reader) (*BankStatementResult, error) {
+	bank := strings.ToLower(strings.TrimSpace(req.Bank))
+	switch bank {
+	case "", "auto":
+		bank = ""
+	case bankstmt.BankBCA, bankstmt.BankMandiri, bankstmt.BankBRI, bankstmt.BankBNI, bankstmt.BankOther:
+	default:
+		return nil, ErrBankStatementBank
+	}
+
+	now := time.Now()
+	parsed, err := bankstmt.Parse(r, bank, now)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBankStatementUnreadable, err)
+	}
+	if len(parsed.Transactions) == 0 {
+		return nil, fmt.Errorf("%w: no transactions found", ErrBankStatementUnreadable)
+	}
+
+	st := &billing.BankStatement{
+		ID:            uuid.New(),
+		TenantID:      tenantID,
+		Bank:          parsed.Bank,
+		AccountNumber: parsed.AccountNumber,
+		FileName:      req.FileName,
+		PeriodStart:   parsed.PeriodStart,
+		PeriodEnd:     parsed.PeriodEnd,
+		ImportedBy:    userID,
+		CreatedAt:     now,
+	}
+
+	var lines []*billing.BankStatementLine
+	occurrences := map[string]int{}
+	for _, tx := range parsed.Transactions {
+		if !tx.Credit {
+			st.DebitCount++
+			continue
+		}
+		key := bankLineKey(tx)
+		occurrences[key]++
+		lines = append(lines, &billing.BankStatementLine{
+			ID:          uuid.New(),
+			TenantID:    tenantID,
+			StatementID: st.ID,
+			Row:         tx.Row,
+			TxnDate:     tx.Date,
+			Description: tx.Description,
+			Reference:   tx.Reference,
+			Amount:      tx.Amount,
+			Balance:     tx.Balance,
+			Fingerprint: bankLineFingerprint(key, occurrences[key]),
+			Status:      billing.BankLineUnmatched,
+			CreatedAt:   now,
+		})
+	}
+
+	fingerprints := make([]string, len(lines))
+	for i, l := range lines {
+		fingerprints[i] = l.Fingerprint
+	}
+	known, err := s.statementRepo.KnownFingerprints(ctx, tenantID, fingerprints)
+	if err != nil {
+		return nil, err
+	}
+	fresh := lines[:0]
+	for _, l := range lines {
+		if known[l.Fingerprint] {
+			st.DuplicateCount++
+			continue
+		}
+		fresh = append(fresh, l)
+	}
+	st.CreditCount = len(fresh)
+
+	invoices, err := s.statementRepo.ListOpenInvoices(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	assignBankMatches(fresh, newReconcileMatcher(invoices))
+
+	if _, err := s.statementRepo.Create(ctx, st, fresh); err != nil {
+		return nil, err
+	}
+	return s.GetStatement(ctx, tenantID, st.ID)
+}
+
+// bankLineKey identifies a mutation by everything the bank prints about it
+func bankLineKey(tx bankstmt.Transaction) string {
+	balance := ""
+	if tx.Balance != nil {
+		balance = fmt.Sprint(*tx.Balance)
+	}
+	return strings.Join([]string{
+		tx.Date.Format("2006-01-02"), fmt.Sprint(tx.Amount), strings.ToUpper(tx.Description), tx.Reference, balance,
+	}, "|")
+}
+
+// bankLineFingerprint hashes the key with its occurrence in the file, so two identical transfers on
+// one day are both kept while re-imports are recognised
+func bankLineFingerprint(key string, occurrence int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d", key, occurrence)))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *BankReconciliationService) ListStatements(ctx context.Context, tenantID uuid.UUID, page, pageSize int) ([]*billing.BankStatement, int, error) {
+	return s.statementRepo.List(ctx, tenantID, page, pageSize)
+}
+
+func (s *BankReconciliationService) GetStatement(ctx context.Context, tenantID, statementID uuid.UUID) (*BankStatementResult, error) {
+	st, err := s.statementRepo.GetByID(ctx, tenantID, statementID)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := s.statementRepo.ListLines(ctx, tenantID, statementID, nil)
+	if err != nil {
+		return nil, err
+	}
+	out := &BankStatementResult{
+		Statement:   st,
+		AutoMatched: []*billing.BankStatementLine{},
+		Suggested:   []*billing.BankStatementLine{},
+		Unmatched:   []*billing.BankStatementLine{},
+		Confirmed:   []*billing.BankStatementLine{},
+		Ignored:     []*billing.BankStatementLine{},
+	}
+	for _, l := range lines {
+		switch l.Status {
+		case billing.BankLineAutoMatched:
+			out.AutoMatched = append(out.AutoMatched, l)
+		case billing.BankLineSuggested:
+			out.Suggested = append(out.Suggested, l)
+		case billing.BankLineConfirmed:
+			out.Confirmed = append(out.Confirmed, l)
+		case billing.BankLineIgnored:
+			out.Ignored = append(out.Ignored, l)
+		default:
+			out.Unmatched = append(out.Unmatched, l)
+		}
+	}
+	return out, nil
+}
+
+// Rematch matches the open credits of a statement again, e.g. after invoices were generated or
+// client phone numbers corrected
+func (s *BankReconciliationService) Rematch(ctx context.Context, tenantID, statementID uuid.UUID) (*BankStatementResult, error) {
+	lines, err := s.statementRepo.ListLines(ctx, tenantID, statementID, nil)
+	if err != nil {
+		return nil, err
+	}
+	var open []*billing.BankStatementLine
+	for _, l := range lines {
+		if l.IsOpen() {
+			open = append(open, l)
+		}
+	}
+	invoices, err := s.statementRepo.ListOpenInvoices(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	assignBankMatches(open, newReconcileMatcher(invoices))
+	for _, l := range open {
+		if err := s.statementRepo.UpdateMatch(ctx, l); err != nil {
+			return nil, err
+		}
+	}
+	return s.GetStatement(ctx, tenantID, statementID)
+}
+
+// ConfirmLine records the bank credit as a bank transfer payment of the matched (or chosen) invoice.
+// When the invoice has been settled in the meantime, or only a client is given, the amount is
+// allocated to the client's open invoices and any remainder credited to the client balance.
+func (s *BankReconciliationService) ConfirmLine(ctx context.Context, tenantID, userID, lineID uuid.UUID, req ConfirmBankLineRequest) (*billing.BankStatementLine, error) {
+	l, err := s.statementRepo.GetLine(ctx, tenantID, lineID)
+	if err != nil {
+		return nil, err
+	}
+	if l.Status == billing.BankLineConfirmed {
+		return nil, ErrBankLineConfirmed
+	}
+
+	invoiceID, clientID := req.InvoiceID, req.ClientID
+	if invoiceID == nil && clientID == nil {
+		invoiceID, clientID = l.InvoiceID, l.ClientID
+	}
+	var inv *billing.Invoice
+	if invoiceID != nil {
+		inv, err = s.invoiceRepo.GetByID(ctx, *invoiceID)
+		if err != nil || inv.TenantID != tenantID {
+			return nil, ErrInvoiceNotFound
+		}
+		if inv.Status == billing.InvoiceStatusCancelled {
+			return nil, ErrBankLineInvoiceInvalid
+		}
+		clientID = &inv.ClientID
+	}
+	if clientID == nil {
+		return nil, ErrBankLineNoTarget
+	}
+
+	claimed, err := s.statementRepo.ClaimConfirm(ctx, l.ID, userID, invoiceID, *clientID)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrBankLineConfirmed
+	}
+
+	reference := l.Reference
+	if reference == "" {
+		reference = fmt.Sprintf("MUTASI-%s-%d", l.TxnDate.Format("20060102"), l.Row)
+	}
+	notes := "Transfer bank: " + l.Description
+	receivedAt := l.TxnDate
+
+	var paymentID *uuid.UUID
+	if inv != nil && (inv.Status == billing.InvoiceStatusPending || inv.Status == billing.InvoiceStatusOverdue) {
+		var p *billing.Payment
+		p, err = s.billingService.RecordPayment(ctx, tenantID, userID, RecordPaymentRequest{
+			InvoiceID:  inv.ID,
+			Amount:     l.Amount,
+			Method:     billing.PaymentMethodBankTransfer,
+			Reference:  &reference,
+			Notes:      &notes,
+			ReceivedAt: &receivedAt,
+		})
+		if p != nil {
+			paymentID = &p.ID
+		}
+	} else {
+		var cp *billing.ClientPayment
+		cp, err = s.billingService.RecordClientPayment(ctx, tenantID, userID, *clientID, RecordClientPaymentRequest{
+			Amount:     l.Amount,
+			Method:     billing.PaymentMethodBankTransfer,
+			Reference:  &reference,
+			Notes:      &notes,
+			ReceivedAt: &receivedAt,
+		})
+		if cp != nil && len(cp.Allocations) > 0 {
+			paymentID = &cp.Allocations[0].ID
+		}
+	}
+	if err != nil {
+		if rerr := s.statementRepo.ReleaseConfirm(ctx, l); rerr != nil {
+			log.Error().Err(rerr).Str("line_id", l.ID.String()).Msg("Failed to release bank statement line")
+		}
+		return nil, fmt.Errorf("failed to record bank transfer: %w", err)
+	}
+	if paymentID != nil {
+		if err := s.statementRepo.SetPayment(ctx, l.ID, *paymentID); err != nil {
+			log.Warn().Err(err).Str("line_id", l.ID.String()).Msg("Failed to link payment to bank statement line")
+		}
+	}
+	return s.statementRepo.GetLine(ctx, tenantID, l.ID)
+}
+
+// ConfirmAutoMatched confirms every auto-matched credit of a statement
+func (s *BankReconciliationService) ConfirmAutoMatched(ctx context.Context, tenantID, userID, statementID uuid.UUID) (*ConfirmAutoMatchedResult, error) {
+	if _, err := s.statementRepo.GetByID(ctx, tenantID, statementID); err != nil {
+		return nil, err
+	}
+	status := billing.BankLineAutoMatched
+	lines, err := s.statementRepo.ListLines(ctx, tenantID, statementID, &status)
+	if err != nil {
+		return nil, err
+	}
+	out := &ConfirmAutoMatchedResult{Failed: []BankLineConfirmFailure{}}
+	for _, l := range lines {
+		if _, err := s.ConfirmLine(ctx, tenantID, userID, l.ID, ConfirmBankLineRequest{}); err != nil {
+			out.Failed = append(out.Failed, BankLineConfirmFailure{LineID: l.ID, Error: err.Error()})
+			continue
+		}
+		out.Confirmed++
+	}
+	return out, nil
+}
+
+// IgnoreLine marks a credit as not being a client payment, or reopens it
+func (s *BankReconciliationService) IgnoreLine(ctx context.Context, tenantID, lineID uuid.UUID, ignored bool) (*billing.BankStatementLine, error) {
+	if err := s.statementRepo.SetIgnored(ctx, tenantID, lineID, ignored); err != nil {
+		return nil, err
+	}
+	return s.statementRepo.GetLine(ctx, tenantID, lineID)
+}
+
+// ========== Matching ==========
+
+type reconcileEntry struct {
+	inv    billing.ReconcileInvoice
+	number string   // invoice number, letters and digits only
+	code   string   // client code, letters and digits only
+	phone  string   // last 9 digits of the client phone
+	names  []string // words of the client name
+}
+
+type reconcileMatcher struct {
+	entries []reconcileEntry
+}
+
+var (
+	nonAlnum    = regexp.MustCompile(`[^A-Z0-9]+`)
+	nonLetter   = regexp.MustCompile(`[^A-Z]+`)
+	digitGroups = regexp.MustCompile(`\d[\d\- ]{7,}\d`)
+)
+
+func alnumUpper(s string) string {
+	return nonAlnum.ReplaceAllString(strings.ToUpper(s), "")
+}
+
+// phoneKey normalises an Indonesian phone number (+62 / 62 / 0 prefix) to its last 9 digits
+func phoneKey(s string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+	if len(digits) < 9 {
+		return ""
+	}
+	return digits[len(digits)-9:]
+}
+
+func newReconcileMatcher(invoices []billing.ReconcileInvoice) *reconcileMatcher {
+	m := &reconcileMatcher{entries: make([]reconcileEntry, 0, len(invoices))}
+	for _, inv := range invoices {
+		e := reconcileEntry{
+			inv:    inv,
+			number: alnumUpper(inv.InvoiceNumber),
+			code:   alnumUpper(inv.ClientCode),
+			phone:  phoneKey(inv.ClientPhone),
+		}
+		for _, w := range strings.Fields(nonLetter.ReplaceAllString(strings.ToUpper(inv.ClientName), " ")) {
+			if len(w) >= 3 {
+				e.names = append(e.names, w)
+			}
+		}
+		m.entries = append(m.entries, e)
+	}
+	return m
+}
+
+// bankDescription holds the forms of a transfer description the matcher compares against
+type bankDescription struct {
+	alnum  string
+	tokens map[string]bool
+	words  []string
+	phones []string
+}
+
+func parseBankDescription(desc string) bankDescription {
+	upper := strings.ToUpper(desc)
+	d := bankDescription{alnum: alnumUpper(desc), tokens: map[string]bool{}}
+	for _, t := range strings.Fields(nonAlnum.ReplaceAllString(upper, " ")) {
+		d.tokens[t] = true
+	}
+	d.words = strings.Fields(nonLetter.ReplaceAllString(upper, " "))
+	for _, g := range digitGroups.FindAllString(upper, -1) {
+		if k := phoneKey(g); k != "" {
+			d.phones = append(d.phones, k)
+		}
+	}
+	return d
+}
+
+// nameMatch counts the client name words found in the description. Banks truncate names, so a
+// description word of at least 4 letters that starts a name word also counts.
+func nameMatch(names, words []string) int {
+	n := 0
+	for _, name := range names {
+		for _, w := range words {
+			if w == name || (len(w) >= 4 && strings.HasPrefix(name, w)) {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
+
+func (e *reconcileEntry) score(amount int64, date time.Time, d bankDescription) (int, []string) {
+	score := 0
+	var reasons []string
+	identified := false
+	add := func(points int, reason string) {
+		score += points
+		reasons = append(reasons, reason)
+	}
+
+	if e.inv.UniqueCode > 0 && amount == e.inv.Payable {
+		add(reconcileScoreUniqueCode, "unique_code")
+		identified = true
+	} else if amount == e.inv.Outstanding {
+		add(reconcileScoreAmount, "amount")
+		identified = true
+	}
+	if len(e.number) >= 5 && strings.Contains(d.alnum, e.number) {
+		add(reconcileScoreInvoiceNumber, "invoice_number")
+		identified = true
+	}
+	if len(e.code) >= 4 && d.tokens[e.code] {
+		add(reconcileScoreClientCode, "client_code")
+		identified = true
+	}
+	if e.phone != "" {
+		for _, p := range d.phones {
+			if p == e.phone {
+				add(reconcileScorePhone, "phone")
+				identified = true
+				break
+			}
+		}
+	}
+	if n := nameMatch(e.names, d.words); n > 0 && n == len(e.names) {
+		add(reconcileScoreName, "name")
+		identified = true
+	} else if n > 0 {
+		add(reconcileScoreNamePartial, "name_partial")
+		identified = true
+	}
+	if !identified {
+		return 0, nil
+	}
+
+	from := e.inv.PeriodStart
+	if e.inv.CreatedAt.Before(from) {
+		from = e.inv.CreatedAt
+	}
+	from = from.AddDate(0, 0, -reconcileDaysBefore)
+	until := e.inv.DueDate.AddDate(0, 0, reconcileDaysAfter)
+	if !date.Before(from.Truncate(24*time.Hour)) && !date.After(until) {
+		add(reconcileScoreDateWindow, "date_window")
+	} else {
+		score += reconcileScoreOutsideWindow
+	}
+	return score, reasons
+}
+
+// candidates returns the best scoring invoices for a credit, highest score first
+func (m *reconcileMatcher) candidates(amount int64, date time.Time, description string) []billing.BankMatchCandidate {
+	d := parseBankDescription(description)
+	var out []billing.BankMatchCandidate
+	for i := range m.entries {
+		e := &m.entries[i]
+		score, reasons := e.score(amount, date, d)
+		if score < reconcileSuggestScore {
+			continue
+		}
+		out = append(out, billing.BankMatchCandidate{
+			InvoiceID:     e.inv.InvoiceID,
+			ClientID:      e.inv.ClientID,
+			InvoiceNumber: e.inv.InvoiceNumber,
+			ClientName:    e.inv.ClientName,
+			Outstanding:   e.inv.Outstanding,
+			Score:         score,
+			Reasons:       reasons,
+		})
+	}
+	// Entries are ordered by due date, so equal scores keep the oldest invoice first
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out
+}
+
+func hasReason(c billing.BankMatchCandidate, reason string) bool {
+	for _, r := range c.Reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// classifyBankLine picks the bucket for a credit from its candidates
+func classifyBankLine(cands []billing.BankMatchCandidate) billing.BankLineStatus {
+	if len(cands) == 0 {
+		return billing.BankLineUnmatched
+	}
+	best := cands[0]
+	unambiguous := len(cands) == 1 || best.Score-cands[1].Score >= reconcileAutoMargin
+	exact := hasReason(best, "amount") || hasReason(best, "unique_code")
+	if best.Score >= reconcileAutoScore && exact && unambiguous {
+		return billing.BankLineAutoMatched
+	}
+	return billing.BankLineSuggested
+}
+
+// assignBankMatches sets the bucket, best invoice and candidates of each line. An invoice is
+// auto-matched to one credit only: the weaker claims become suggestions.
+func assignBankMatches(lines []*billing.BankStatementLine, m *reconcileMatcher) {
+	for _, l := range lines {
+		cands := m.candidates(l.Amount, l.TxnDate, l.Description)
+		l.Status = classifyBankLine(cands)
+		l.InvoiceID, l.ClientID, l.Score = nil, nil, 0
+		if len(cands) > reconcileMaxCandidates {
+			cands = cands[:reconcileMaxCandidates]
+		}
+		l.Candidates = cands
+		if len(cands) > 0 {
+			invoiceID, clientID := cands[0].InvoiceID, cands[0].ClientID
+			l.InvoiceID, l.ClientID, l.Score = &invoiceID, &clientID, cands[0].Score
+		}
+	}
+
+	auto := make([]*billing.BankStatementLine, 0, len(lines))
+	for _, l := range lines {
+		if l.Status == billing.BankLineAutoMatched {
+			auto = append(auto, l)
+		}
+	}
+	sort.SliceStable(auto, func(i, j int) bool { return auto[i].Score > auto[j].Score })
+	taken := map[uuid.UUID]bool{}
+	for _, l := range auto {
+		if taken[*l.InvoiceID] {
+			l.Status = billing.BankLineSuggested
+			continue
+		}
+		taken[*l.InvoiceID] = true
+	}
+}
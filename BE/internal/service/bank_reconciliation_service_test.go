@@ -0,0 +1,113 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rrnet/internal/domain/billing"
+)
+
+func reconcileInvoice(number, code, name, phone string, outstanding int64) billing.ReconcileInvoice {
+	period := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	return billing.ReconcileInvoice{
+		InvoiceID:     uuid.New(),
+		ClientID:      uuid.New(),
+		InvoiceNumber: number,
+		ClientCode:    code,
+		ClientName:    name,
+		ClientPhone:   phone,
+		Outstanding:   outstanding,
+		PeriodStart:   period,
+		DueDate:       period.AddDate(0, 0, 10),
+		CreatedAt:     period,
+	}
+}
+
+func bankLine(amount int64, date time.Time, desc string) *billing.BankStatementLine {
+	return &billing.BankStatementLine{ID: uuid.New(), Amount: amount, TxnDate: date, Description: desc}
+}
+
+func TestAssignBankMatches(t *testing.T) {
+	budi := reconcileInvoice("INV-202601-0001", "C0001", "Budi Santoso", "+62 812-3456-7890", 150000)
+	siti := reconcileInvoice("INV-202601-0002", "C0002", "Siti Aminah", "081298765432", 150000)
+	andi := reconcileInvoice("INV-202601-0003", "C0003", "Andi Wijaya", "", 250000)
+	m := newReconcileMatcher([]billing.ReconcileInvoice{budi, siti, andi})
+	jan := time.Date(2026, 1, 6, 0, 0, 0, 0, time.UTC)
+
+	byName := bankLine(150000, jan, "TRSF E-BANKING CR 0601/FTSCY/WS95031 BUDI SANTOS")
+	byPhone := bankLine(150000, jan, "TRANSFER DARI 081298765432")
+	amountOnly := bankLine(150000, jan, "SETORAN TUNAI")
+	partial := bankLine(100000, jan, "PEMBAYARAN INV202601-0003")
+	nameOnly := bankLine(300000, jan, "ANDI WIJAYA")
+	stale := bankLine(300000, jan.AddDate(1, 0, 0), "ANDI WIJAYA")
+	unknown := bankLine(99000, jan, "BUNGA TABUNGAN")
+
+	assignBankMatches([]*billing.BankStatementLine{byName, byPhone, amountOnly, partial, nameOnly, stale, unknown}, m)
+
+	// Exact amount and the (truncated) client name
+	assert.Equal(t, billing.BankLineAutoMatched, byName.Status)
+	require.NotNil(t, byName.InvoiceID)
+	assert.Equal(t, budi.InvoiceID, *byName.InvoiceID)
+	assert.Equal(t, []string{"amount", "name", "date_window"}, byName.Candidates[0].Reasons)
+
+	// Phone numbers match in any prefix notation
+	assert.Equal(t, billing.BankLineAutoMatched, byPhone.Status)
+	assert.Equal(t, siti.InvoiceID, *byPhone.InvoiceID)
+
+	// The amount alone fits two invoices: both suggested, oldest first
+	assert.Equal(t, billing.BankLineSuggested, amountOnly.Status)
+	assert.Len(t, amountOnly.Candidates, 2)
+
+	// Invoice number with a different amount (partial payment) needs a person
+	assert.Equal(t, billing.BankLineSuggested, partial.Status)
+	assert.Equal(t, andi.InvoiceID, *partial.InvoiceID)
+
+	// The name alone is a suggestion inside the date window, a year after the due date it is not
+	assert.Equal(t, billing.BankLineSuggested, nameOnly.Status)
+	assert.Equal(t, billing.BankLineUnmatched, stale.Status)
+	assert.Nil(t, stale.InvoiceID)
+	assert.Equal(t, billing.BankLineUnmatched, unknown.Status)
+}
+
+func TestAssignBankMatchesOneCreditPerInvoice(t *testing.T) {
+	budi := reconcileInvoice("INV-202601-0001", "C0001", "Budi Santoso", "", 150000)
+	m := newReconcileMatcher([]billing.ReconcileInvoice{budi})
+	jan := time.Date(2026, 1, 6, 0, 0, 0, 0, time.UTC)
+
+	first := bankLine(150000, jan, "BUDI SANTOSO")
+	second := bankLine(150000, jan, "BUDI SANTOSO INV-202601-0001")
+	assignBankMatches([]*billing.BankStatementLine{first, second}, m)
+
+	// The stronger match keeps the auto-match, the other becomes a suggestion
+	assert.Equal(t, billing.BankLineAutoMatched, second.Status)
+	assert.Equal(t, billing.BankLineSuggested, first.Status)
+}
+
+func TestAssignBankMatchesUniqueCode(t *testing.T) {
+	budi := reconcileInvoice("INV-202601-0001", "C0001", "Budi Santoso", "", 150000)
+	budi.UniqueCode, budi.Payable = 12, 150012
+	siti := reconcileInvoice("INV-202601-0002", "C0002", "Siti Aminah", "", 150000)
+	siti.UniqueCode, siti.Payable = 37, 150037
+	m := newReconcileMatcher([]billing.ReconcileInvoice{budi, siti})
+	jan := time.Date(2026, 1, 6, 0, 0, 0, 0, time.UTC)
+
+	withCode := bankLine(150037, jan, "SETORAN TUNAI")
+	withoutCode := bankLine(150000, jan, "SETORAN TUNAI")
+	assignBankMatches([]*billing.BankStatementLine{withCode, withoutCode}, m)
+
+	// The code tells invoices of the same amount apart
+	assert.Equal(t, billing.BankLineAutoMatched, withCode.Status)
+	assert.Equal(t, siti.InvoiceID, *withCode.InvoiceID)
+	assert.Equal(t, []string{"unique_code", "date_window"}, withCode.Candidates[0].Reasons)
+	assert.Equal(t, billing.BankLineSuggested, withoutCode.Status)
+}
+
+func TestBankLineFingerprint(t *testing.T) {
+	key := "2026-01-06|150000|BUDI|ref|"
+	assert.Equal(t, bankLineFingerprint(key, 1), bankLineFingerprint(key, 1))
+	assert.NotEqual(t, bankLineFingerprint(key, 1), bankLineFingerprint(key, 2))
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/notification"
+)
+
+var (
+	ErrPaymentAmountInvalid      = errors.New("amount must be positive")
+	ErrAllocationInvalid         = errors.New("allocation must target an outstanding invoice of the client with a positive amount not above its remaining amount")
+	ErrAllocationExceedsPayment  = errors.New("allocations exceed the payment amount")
+	ErrPaymentExceedsOutstanding = errors.New("amount exceeds the client's outstanding invoices")
+)
+
+type PaymentAllocationRequest struct {
+	InvoiceID uuid.UUID `json:"invoice_id"`
+	Amount    int64     `json:"amount"`
+}
+
+// RecordClientPaymentRequest is one amount received from a client. Without Allocations the amount is
+// allocated FIFO (oldest due date first) across the client's pending and overdue invoices.
+type RecordClientPaymentRequest struct {
+	Amount      int64                      `json:"amount"`
+	Method      billing.PaymentMethod      `json:"method"`
+	Reference   *string                    `json:"reference,omitempty"`
+	CollectorID *uuid.UUID                 `json:"collector_id,omitempty"`
+	Notes       *string                    `json:"notes,omitempty"`
+	ReceivedAt  *time.Time                 `json:"received_at,omitempty"`
+	Allocations []PaymentAllocationRequest `json:"allocations,omitempty"`
+
+	PaymentRequestID *uuid.UUID `json:"-"` // gateway request the payment settles
+}
+
+// allocateFIFO spreads amount over invoices in the given order (oldest due first)
+func allocateFIFO(invoices []*billing.Invoice, amount int64) []PaymentAllocationRequest {
+	var out []PaymentAllocationRequest
+	for _, inv := range invoices {
+		if amount <= 0 {
+			break
+		}
+		part := capDiscount(amount, inv.RemainingAmount())
+		if part <= 0 {
+			continue
+		}
+		out = append(out, PaymentAllocationRequest{InvoiceID: inv.ID, Amount: part})
+		amount -= part
+	}
+	return out
+}
+
+// validateAllocations checks explicit allocations against the client's outstanding invoices
+func validateAllocations(invoices []*billing.Invoice, allocs []PaymentAllocationRequest, amount int64) error {
+	remaining := make(map[uuid.UUID]int64, len(invoices))
+	for _, inv := range invoices {
+		remaining[inv.ID] = inv.RemainingAmount()
+	}
+	var sum int64
+	for _, a := range allocs {
+		left, ok := remaining[a.InvoiceID]
+		if !ok || a.Amount <= 0 || a.Amount > left {
+			return ErrAllocationInvalid
+		}
+		remaining[a.InvoiceID] = left - a.Amount // the same invoice may appear twice
+		sum += a.Amount
+	}
+	if sum > amount {
+		return ErrAllocationExceedsPayment
+	}
+	return nil
+}
+
+// RecordClientPayment allocates one received amount across a client's outstanding invoices. All
+// allocations and invoice updates are written in one transaction; an unallocated remainder is added
+// to the client balance.
+func (s *BillingService) RecordClientPayment(ctx context.Context, tenantID, userID, clientID uuid.UUID, req RecordClientPaymentRequest) (*billing.ClientPayment, error) {
+	if req.Amount <= 0 {
+		return nil, ErrPaymentAmountInvalid
+	}
+	if _, err := s.clientRepo.GetByID(ctx, tenantID, clientID); err != nil {
+		return nil, err
+	}
+	invoices, err := s.invoiceRepo.GetClientPendingInvoices(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	allocs := req.Allocations
+	if len(allocs) == 0 {
+		allocs = allocateFIFO(invoices, req.Amount)
+	} else if err := validateAllocations(invoices, allocs, req.Amount); err != nil {
+		return nil, err
+	}
+
+	var allocated int64
+	for _, a := range allocs {
+		allocated += a.Amount
+	}
+	remainder := req.Amount - allocated
+	if remainder > 0 && s.balanceRepo == nil {
+		return nil, ErrPaymentExceedsOutstanding
+	}
+
+	now := time.Now()
+	receivedAt := now
+	if req.ReceivedAt != nil {
+		receivedAt = *req.ReceivedAt
+	}
+	if req.Method == "" {
+		req.Method = billing.PaymentMethodCash
+	}
+
+	cp := &billing.ClientPayment{
+		ID:               uuid.New(),
+		TenantID:         tenantID,
+		ClientID:         clientID,
+		Amount:           req.Amount,
+		Currency:         "IDR",
+		Method:           req.Method,
+		Reference:        req.Reference,
+		CollectorID:      req.CollectorID,
+		Notes:            req.Notes,
+		CreditedAmount:   remainder,
+		ReceivedAt:       receivedAt,
+		CreatedAt:        now,
+		CreatedByUserID:  userID,
+		PaymentRequestID: req.PaymentRequestID,
+	}
+	for _, a := range allocs {
+		cp.Allocations = append(cp.Allocations, &billing.Payment{
+			ID:              uuid.New(),
+			TenantID:        tenantID,
+			InvoiceID:       a.InvoiceID,
+			ClientID:        clientID,
+			Amount:          a.Amount,
+			Currency:        "IDR",
+			Method:          req.Method,
+			Reference:       req.Reference,
+			CollectorID:     req.CollectorID,
+			Notes:           req.Notes,
+			ReceivedAt:      receivedAt,
+			CreatedAt:       now,
+			CreatedByUserID: userID,
+			ClientPaymentID: &cp.ID,
+		})
+	}
+
+	var credit *billing.BalanceEntry
+	if remainder > 0 {
+		credit = &billing.BalanceEntry{
+			ID:          uuid.New(),
+			TenantID:    tenantID,
+			ClientID:    clientID,
+			Type:        billing.BalanceOverpayment,
+			Amount:      remainder,
+			Description: fmt.Sprintf("Sisa pembayaran %s tidak teralokasi", req.Method),
+			CreatedBy:   &userID,
+			CreatedAt:   now,
+		}
+	}
+
+	numbering := s.documentNumbering(ctx, tenantID, billing.DocumentReceipt, clientID, receivedAt)
+	paid, err := s.paymentRepo.CreateClientPayment(ctx, cp, credit, numbering)
+	if err != nil {
+		return nil, err
+	}
+	if cp.Allocations == nil {
+		cp.Allocations = []*billing.Payment{}
+	}
+	if s.ledger != nil {
+		s.ledger.PostClientPayment(ctx, cp)
+	}
+
+	// Lift isolir once the client has nothing overdue left (and, when prepaid, is covered again)
+	var paidInvoices []*billing.Invoice
+	for _, id := range paid {
+		for _, inv := range invoices {
+			if inv.ID == id {
+				paidInvoices = append(paidInvoices, inv)
+			}
+		}
+	}
+	if len(paid) > 0 && s.applyPrepaidPayment(ctx, tenantID, clientID, paidInvoices...) && s.isolirService != nil {
+		invoiceID := paid[len(paid)-1]
+		if err := s.isolirService.ReactivateIfSettled(ctx, tenantID, clientID, &invoiceID); err != nil {
+			log.Warn().Err(err).Str("client_id", clientID.String()).Msg("Failed to reactivate client after payment")
+		}
+	}
+
+	// One receipt for the whole amount, naming every invoice it was allocated to
+	if s.notifier != nil {
+		var numbers []string
+		var invoiceID *uuid.UUID
+		for _, a := range cp.Allocations {
+			for _, inv := range invoices {
+				if inv.ID == a.InvoiceID {
+					numbers = append(numbers, inv.InvoiceNumber)
+				}
+			}
+		}
+		if len(cp.Allocations) == 1 {
+			invoiceID = &cp.Allocations[0].InvoiceID
+		}
+		s.notifier.Raise(ctx, tenantID, notification.EventPaymentReceived, clientID, invoiceID, cp.ID.String(), paymentNotificationData(cp.Amount, cp.Method, receivedAt, strings.Join(numbers, ", ")))
+	}
+	return cp, nil
+}
+
+// paymentNotificationData are the payment facts of a payment_received notification
+func paymentNotificationData(amount int64, method billing.PaymentMethod, receivedAt time.Time, invoiceNumbers string) map[string]string {
+	return map[string]string{
+		"payment.amount":   strconv.FormatInt(amount, 10),
+		"payment.method":   string(method),
+		"payment.date":     receivedAt.Format(time.RFC3339),
+		"payment.invoices": invoiceNumbers,
+	}
+}
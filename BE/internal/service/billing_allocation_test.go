@@ -0,0 +1,52 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"rrnet/internal/domain/billing"
+)
+
+func TestAllocateFIFO(t *testing.T) {
+	a := &billing.Invoice{ID: uuid.New(), TotalAmount: 100000, PaidAmount: 40000}
+	b := &billing.Invoice{ID: uuid.New(), TotalAmount: 150000}
+	c := &billing.Invoice{ID: uuid.New(), TotalAmount: 150000}
+
+	got := allocateFIFO([]*billing.Invoice{a, b, c}, 250000)
+	assert.Equal(t, []PaymentAllocationRequest{
+		{InvoiceID: a.ID, Amount: 60000},
+		{InvoiceID: b.ID, Amount: 150000},
+		{InvoiceID: c.ID, Amount: 40000},
+	}, got)
+
+	// more than outstanding: remainder is left unallocated
+	got = allocateFIFO([]*billing.Invoice{a}, 100000)
+	assert.Equal(t, []PaymentAllocationRequest{{InvoiceID: a.ID, Amount: 60000}}, got)
+
+	assert.Empty(t, allocateFIFO(nil, 50000))
+}
+
+func TestValidateAllocations(t *testing.T) {
+	a := &billing.Invoice{ID: uuid.New(), TotalAmount: 100000, PaidAmount: 40000}
+	b := &billing.Invoice{ID: uuid.New(), TotalAmount: 150000}
+	invoices := []*billing.Invoice{a, b}
+
+	assert.NoError(t, validateAllocations(invoices, []PaymentAllocationRequest{
+		{InvoiceID: b.ID, Amount: 150000},
+		{InvoiceID: a.ID, Amount: 10000},
+	}, 200000))
+
+	// above the invoice's remaining amount, also when split over two lines
+	assert.ErrorIs(t, validateAllocations(invoices, []PaymentAllocationRequest{{InvoiceID: a.ID, Amount: 60001}}, 100000), ErrAllocationInvalid)
+	assert.ErrorIs(t, validateAllocations(invoices, []PaymentAllocationRequest{
+		{InvoiceID: a.ID, Amount: 40000},
+		{InvoiceID: a.ID, Amount: 40000},
+	}, 100000), ErrAllocationInvalid)
+	// unknown invoice / non-positive amount
+	assert.ErrorIs(t, validateAllocations(invoices, []PaymentAllocationRequest{{InvoiceID: uuid.New(), Amount: 1}}, 100000), ErrAllocationInvalid)
+	assert.ErrorIs(t, validateAllocations(invoices, []PaymentAllocationRequest{{InvoiceID: b.ID, Amount: 0}}, 100000), ErrAllocationInvalid)
+	// allocations above the payment
+	assert.ErrorIs(t, validateAllocations(invoices, []PaymentAllocationRequest{{InvoiceID: b.ID, Amount: 150000}}, 100000), ErrAllocationExceedsPayment)
+}
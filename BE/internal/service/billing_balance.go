@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/repository"
+	"rrnet/pkg/utils"
+)
+
+var (
+	ErrInvoiceNotFound          = errors.New("invoice not found")
+	ErrCreditNoteAmountInvalid  = errors.New("credit note amount must be positive and not exceed the invoice total")
+	ErrCreditNoteReasonRequired = errors.New("credit note reason is required")
+	ErrCreditNoteInvoiceInvalid = errors.New("credit notes can only be issued for pending, overdue or paid invoices")
+	ErrRefundAmountInvalid      = errors.New("refund amount must be positive")
+)
+
+type CreditNoteRequest struct {
+	Amount int64  `json:"amount"`
+	Reason string `json:"reason"`
+}
+
+type RefundRequest struct {
+	Amount    int64                 `json:"amount"`
+	Method    billing.PaymentMethod `json:"method"`
+	Reference *string               `json:"reference,omitempty"`
+	Notes     *string               `json:"notes,omitempty"`
+}
+
+// ClientBalance is a client's current balance with (a page of) its ledger
+type ClientBalance struct {
+	ClientID uuid.UUID               `json:"client_id"`
+	Balance  int64                   `json:"balance"`
+	Entries  []*billing.BalanceEntry `json:"data"`
+	Total    int                     `json:"total"`
+	Page     int                     `json:"page"`
+}
+
+// creditOverpayment moves the part of totalPaid above the invoice total to the client's balance and
+// returns the amount that stays on the invoice.
+func (s *BillingService) creditOverpayment(ctx context.Context, invoice *billing.Invoice, paymentID uuid.UUID, totalPaid int64) int64 {
+	excess := totalPaid - invoice.TotalAmount
+	if excess <= 0 || s.balanceRepo == nil {
+		return totalPaid
+	}
+	err := s.balanceRepo.Append(ctx, &billing.BalanceEntry{
+		ID:          uuid.New(),
+		TenantID:    invoice.TenantID,
+		ClientID:    invoice.ClientID,
+		Type:        billing.BalanceOverpayment,
+		Amount:      excess,
+		InvoiceID:   &invoice.ID,
+		PaymentID:   &paymentID,
+		Description: fmt.Sprintf("Kelebihan bayar %s", invoice.InvoiceNumber),
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		log.Error().Err(err).Str("invoice_id", invoice.ID.String()).Msg("Failed to credit overpayment to client balance")
+		return totalPaid
+	}
+	return invoice.TotalAmount
+}
+
+// applyClientCredit pays a freshly generated invoice from the client's balance as far as it goes
+func (s *BillingService) applyClientCredit(ctx context.Context, tenantID uuid.UUID, invoice *billing.Invoice) {
+	if s.balanceRepo == nil || invoice.TotalAmount <= 0 {
+		return
+	}
+	balance, err := s.balanceRepo.GetBalance(ctx, tenantID, invoice.ClientID)
+	if err != nil {
+		log.Warn().Err(err).Str("client_id", invoice.ClientID.String()).Msg("Failed to read client balance, skipping credit")
+		return
+	}
+	amount := capDiscount(balance, invoice.RemainingAmount())
+	if amount <= 0 {
+		return
+	}
+
+	now := time.Now()
+	entry := &billing.BalanceEntry{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		ClientID:    invoice.ClientID,
+		Type:        billing.BalanceCreditApplied,
+		Amount:      -amount,
+		InvoiceID:   &invoice.ID,
+		Description: fmt.Sprintf("Saldo dipakai untuk %s", invoice.InvoiceNumber),
+		CreatedAt:   now,
+	}
+	if err := s.balanceRepo.Append(ctx, entry); err != nil {
+		// ErrInsufficientBalance: the balance was used concurrently, the invoice simply stays unpaid
+		log.Warn().Err(err).Str("invoice_id", invoice.ID.String()).Msg("Failed to apply client credit")
+		return
+	}
+	if s.ledger != nil {
+		s.ledger.PostBalanceEntry(ctx, entry, "")
+	}
+
+	invoice.PaidAmount += amount
+	if err := s.settleInvoice(ctx, tenantID, invoice, now); err != nil {
+		log.Error().Err(err).Str("invoice_id", invoice.ID.String()).Msg("Failed to update invoice after applying client credit")
+	}
+}
+
+// settleInvoice stores invoice.PaidAmount and marks the invoice paid once it is fully covered
+func (s *BillingService) settleInvoice(ctx context.Context, tenantID uuid.UUID, invoice *billing.Invoice, now time.Time) error {
+	paidAt := invoice.PaidAt
+	becamePaid := invoice.IsPaid() && invoice.Status != billing.InvoiceStatusPaid
+	if becamePaid {
+		paidAt = &now
+		if err := s.invoiceRepo.UpdateStatus(ctx, invoice.ID, billing.InvoiceStatusPaid); err != nil {
+			return err
+		}
+		invoice.Status = billing.InvoiceStatusPaid
+		invoice.PaidAt = paidAt
+	}
+	if err := s.invoiceRepo.UpdatePaidAmount(ctx, invoice.ID, invoice.PaidAmount, paidAt); err != nil {
+		return err
+	}
+	if becamePaid {
+		s.invoiceSettled(ctx, tenantID, invoice)
+	}
+	return nil
+}
+
+// invoiceSettled extends a prepaid client's validity and lifts isolir once an invoice became paid
+func (s *BillingService) invoiceSettled(ctx context.Context, tenantID uuid.UUID, invoice *billing.Invoice) {
+	if s.applyPrepaidPayment(ctx, tenantID, invoice.ClientID, invoice) && s.isolirService != nil {
+		invoiceID := invoice.ID
+		if err := s.isolirService.ReactivateIfSettled(ctx, tenantID, invoice.ClientID, &invoiceID); err != nil {
+			log.Warn().Err(err).Str("invoice_id", invoiceID.String()).Msg("Failed to reactivate client after settlement")
+		}
+	}
+}
+
+// IssueCreditNote reduces an issued invoice. Whatever the client already paid above the reduced total
+// is credited to the client's balance.
+func (s *BillingService) IssueCreditNote(ctx context.Context, tenantID, approvedBy, invoiceID uuid.UUID, req CreditNoteRequest) (*billing.CreditNote, error) {
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return nil, ErrCreditNoteReasonRequired
+	}
+	invoice, err := s.invoiceRepo.GetByID(ctx, invoiceID)
+	if err != nil || invoice.TenantID != tenantID {
+		return nil, ErrInvoiceNotFound
+	}
+	switch invoice.Status {
+	case billing.InvoiceStatusPending, billing.InvoiceStatusOverdue, billing.InvoiceStatusPaid:
+	default:
+		return nil, ErrCreditNoteInvoiceInvalid
+	}
+	if req.Amount <= 0 || req.Amount > invoice.TotalAmount {
+		return nil, ErrCreditNoteAmountInvalid
+	}
+
+	now := time.Now()
+	cn := &billing.CreditNote{
+		ID:         uuid.New(),
+		TenantID:   tenantID,
+		ClientID:   invoice.ClientID,
+		InvoiceID:  invoice.ID,
+		Amount:     req.Amount,
+		Reason:     req.Reason,
+		ApprovedBy: &approvedBy,
+		CreatedAt:  now,
+	}
+	// The credit note amount includes tax; the invoice line and tax are reduced accordingly. The
+	// note, the reduced invoice and the credit of the paid excess are stored together.
+	line := lineAmountFor(invoice, req.Amount)
+	issue := &repository.CreditNoteIssue{
+		CreditNote: cn,
+		Numbering:  s.documentNumbering(ctx, tenantID, billing.DocumentCreditNote, invoice.ClientID, now),
+		Item: billing.InvoiceItem{
+			ID:        uuid.New(),
+			InvoiceID: invoice.ID,
+			Quantity:  1,
+			UnitPrice: -line,
+			Amount:    -line,
+			CreatedAt: now,
+		},
+		Credit: &billing.BalanceEntry{
+			ID:           uuid.New(),
+			TenantID:     tenantID,
+			ClientID:     invoice.ClientID,
+			Type:         billing.BalanceCreditNote,
+			InvoiceID:    &invoice.ID,
+			CreditNoteID: &cn.ID,
+			CreatedBy:    &approvedBy,
+			CreatedAt:    now,
+		},
+		Describe: func(number string) (string, string) {
+			return fmt.Sprintf("Nota kredit %s: %s", number, req.Reason),
+				fmt.Sprintf("Nota kredit %s untuk %s", number, invoice.InvoiceNumber)
+		},
+	}
+	if err := s.balanceRepo.IssueCreditNote(ctx, issue); err != nil {
+		return nil, fmt.Errorf("failed to issue credit note: %w", err)
+	}
+
+	before := invoice
+	if invoice, err = s.invoiceRepo.GetByID(ctx, invoice.ID); err != nil {
+		return nil, fmt.Errorf("failed to reload invoice: %w", err)
+	}
+	var excess int64
+	if issue.Credit != nil {
+		excess = issue.Credit.Amount
+	}
+	if s.ledger != nil {
+		s.ledger.PostCreditNote(ctx, cn, before, invoice, excess)
+	}
+	if before.Status != billing.InvoiceStatusPaid && invoice.Status == billing.InvoiceStatusPaid {
+		s.invoiceSettled(ctx, tenantID, invoice)
+	}
+	return cn, nil
+}
+
+func (s *BillingService) ListCreditNotes(ctx context.Context, tenantID, invoiceID uuid.UUID) ([]*billing.CreditNote, error) {
+	return s.balanceRepo.ListCreditNotesByInvoice(ctx, tenantID, invoiceID)
+}
+
+// RefundBalance pays (part of) a client's credit back; recorded as a negative movement
+func (s *BillingService) RefundBalance(ctx context.Context, tenantID, userID, clientID uuid.UUID, req RefundRequest) (*billing.BalanceEntry, error) {
+	if req.Amount <= 0 {
+		return nil, ErrRefundAmountInvalid
+	}
+	if _, err := s.clientRepo.GetByID(ctx, tenantID, clientID); err != nil {
+		return nil, err
+	}
+	if req.Method == "" {
+		req.Method = billing.PaymentMethodCash
+	}
+
+	desc := fmt.Sprintf("Refund %s (%s)", utils.FormatRupiah(req.Amount), req.Method)
+	if req.Reference != nil && strings.TrimSpace(*req.Reference) != "" {
+		desc += " ref " + strings.TrimSpace(*req.Reference)
+	}
+	if req.Notes != nil && strings.TrimSpace(*req.Notes) != "" {
+		desc += ": " + strings.TrimSpace(*req.Notes)
+	}
+
+	entry := &billing.BalanceEntry{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		ClientID:    clientID,
+		Type:        billing.BalanceRefund,
+		Amount:      -req.Amount,
+		Description: desc,
+		CreatedBy:   &userID,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.balanceRepo.Append(ctx, entry); err != nil {
+		return nil, err
+	}
+	if s.ledger != nil {
+		s.ledger.PostBalanceEntry(ctx, entry, req.Method)
+	}
+	return entry, nil
+}
+
+// GetClientBalance returns the balance of a client with a page of its ledger (newest first)
+func (s *BillingService) GetClientBalance(ctx context.Context, filter repository.BalanceEntryFilter) (*ClientBalance, error) {
+	balance, err := s.balanceRepo.GetBalance(ctx, filter.TenantID, filter.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	entries, total, err := s.balanceRepo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []*billing.BalanceEntry{}
+	}
+	page := filter.Page
+	if page <= 0 {
+		page = 1
+	}
+	return &ClientBalance{ClientID: filter.ClientID, Balance: balance, Entries: entries, Total: total, Page: page}, nil
+}
+
+// GetClientBillingSummary returns invoice totals, last payment and the running balance of a client
+func (s *BillingService) GetClientBillingSummary(ctx context.Context, tenantID, clientID uuid.UUID) (*billing.ClientBillingSummary, error) {
+	summary, err := s.invoiceRepo.GetClientBillingSummary(ctx, tenantID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if s.balanceRepo != nil {
+		if summary.Balance, err = s.balanceRepo.GetBalance(ctx, tenantID, clientID); err != nil {
+			return nil, err
+		}
+	}
+	return summary, nil
+}
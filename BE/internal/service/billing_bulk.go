@@ -0,0 +1,428 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/client"
+	"rrnet/internal/domain/service_package"
+	asynqInfra "rrnet/internal/infra/asynq"
+	"rrnet/internal/repository"
+)
+
+// TaskBulkInvoiceRun executes a bulk invoice run
+const TaskBulkInvoiceRun = "billing:bulk_invoice_run"
+
+// bulkUndoKeptPaid is the reason an undo keeps an invoice that already received money
+const bulkUndoKeptPaid = "kept: invoice has payments"
+
+var (
+	ErrBulkPeriodInvalid  = errors.New("period must be a month formatted as YYYY-MM")
+	ErrBulkRunNotUndoable = errors.New("only completed or failed runs can be undone")
+)
+
+// BulkInvoiceRequest selects the period (YYYY-MM) and the active clients to invoice
+type BulkInvoiceRequest struct {
+	Period string `json:"period"`
+	billing.BulkRunFilter
+}
+
+// BulkInvoicePreview is the dry run of a bulk run: the invoices it would create (status pending)
+// and the clients it would skip or fail on
+type BulkInvoicePreview struct {
+	PeriodStart time.Time              `json:"period_start"`
+	Total       int                    `json:"total"`
+	Billable    int                    `json:"billable"`
+	Skipped     int                    `json:"skipped"`
+	Failed      int                    `json:"failed"`
+	TotalAmount int64                  `json:"total_amount"`
+	Items       []*billing.BulkRunItem `json:"items"`
+}
+
+type BulkInvoiceRunPayload struct {
+	TenantID string `json:"tenant_id"`
+	RunID    string `json:"run_id"`
+}
+
+// BulkInvoiceService generates the invoices of a period for a selection of clients: a dry-run
+// preview, execution as an Asynq task with per-client outcome, and undo of a run
+type BulkInvoiceService struct {
+	runRepo        *repository.BulkInvoiceRunRepository
+	clientRepo     *repository.ClientRepository
+	invoiceRepo    *repository.InvoiceRepository
+	billingService *BillingService
+	asynqClient    *asynq.Client
+}
+
+func NewBulkInvoiceService(
+	runRepo *repository.BulkInvoiceRunRepository,
+	clientRepo *repository.ClientRepository,
+	invoiceRepo *repository.InvoiceRepository,
+	billingService *BillingService,
+	asynqClient *asynq.Client,
+) *BulkInvoiceService {
+	return &BulkInvoiceService{
+		runRepo:        runRepo,
+		clientRepo:     clientRepo,
+		invoiceRepo:    invoiceRepo,
+		billingService: billingService,
+		asynqClient:    asynqClient,
+	}
+}
+
+// parseBulkPeriod returns the first day of a YYYY-MM month
+func parseBulkPeriod(period string) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01", period, time.Local)
+	if err != nil {
+		return time.Time{}, ErrBulkPeriodInvalid
+	}
+	return t, nil
+}
+
+// Preview plans the run without creating anything
+func (s *BulkInvoiceService) Preview(ctx context.Context, tenantID uuid.UUID, req BulkInvoiceRequest) (*BulkInvoicePreview, error) {
+	month, err := parseBulkPeriod(req.Period)
+	if err != nil {
+		return nil, err
+	}
+	clients, err := s.listClients(ctx, tenantID, req.BulkRunFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &BulkInvoicePreview{PeriodStart: month, Total: len(clients), Items: []*billing.BulkRunItem{}}
+	packages := make(map[uuid.UUID]*service_package.ServicePackage)
+	for _, c := range clients {
+		item, _ := s.planClient(ctx, tenantID, c, month, packages)
+		switch item.Status {
+		case billing.BulkItemPending:
+			out.Billable++
+			out.TotalAmount += item.Amount
+		case billing.BulkItemSkipped:
+			out.Skipped++
+		case billing.BulkItemFailed:
+			out.Failed++
+		}
+		out.Items = append(out.Items, item)
+	}
+	return out, nil
+}
+
+// Start queues a run; it is executed by the TaskBulkInvoiceRun worker
+func (s *BulkInvoiceService) Start(ctx context.Context, tenantID, userID uuid.UUID, req BulkInvoiceRequest) (*billing.BulkRun, error) {
+	month, err := parseBulkPeriod(req.Period)
+	if err != nil {
+		return nil, err
+	}
+	run := &billing.BulkRun{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		PeriodStart: month,
+		Filter:      req.BulkRunFilter,
+		Status:      billing.BulkRunQueued,
+		CreatedBy:   userID,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.runRepo.Create(ctx, run); err != nil {
+		return nil, err
+	}
+
+	task, err := NewBulkInvoiceRunTask(tenantID, run.ID)
+	if err == nil {
+		_, err = s.asynqClient.EnqueueContext(ctx, task,
+			asynq.Queue(asynqInfra.QueueBilling),
+			asynq.TaskID(run.ID.String()),
+			asynq.MaxRetry(3),
+			asynq.Timeout(30*time.Minute),
+		)
+	}
+	if err != nil {
+		msg := err.Error()
+		if setErr := s.runRepo.SetStatus(ctx, run.ID, billing.BulkRunFailed, &msg); setErr != nil {
+			log.Error().Err(setErr).Str("run_id", run.ID.String()).Msg("Failed to mark bulk invoice run as failed")
+		}
+		return nil, fmt.Errorf("failed to queue bulk invoice run: %w", err)
+	}
+	return run, nil
+}
+
+func NewBulkInvoiceRunTask(tenantID, runID uuid.UUID) (*asynq.Task, error) {
+	b, err := json.Marshal(BulkInvoiceRunPayload{TenantID: tenantID.String(), RunID: runID.String()})
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TaskBulkInvoiceRun, b), nil
+}
+
+// Execute runs a queued run: the selected clients are stored on the first attempt, then every
+// pending client is invoiced or skipped. A retried task resumes with the clients still pending.
+func (s *BulkInvoiceService) Execute(ctx context.Context, tenantID, runID uuid.UUID) error {
+	run, err := s.runRepo.GetByID(ctx, tenantID, runID)
+	if err != nil {
+		return err
+	}
+	if run.Status == billing.BulkRunCompleted || run.Status == billing.BulkRunUndone {
+		return nil // duplicate delivery
+	}
+	if err := s.runRepo.SetStatus(ctx, runID, billing.BulkRunRunning, nil); err != nil {
+		return err
+	}
+
+	if err := s.execute(ctx, run); err != nil {
+		msg := err.Error()
+		if setErr := s.runRepo.SetStatus(ctx, runID, billing.BulkRunFailed, &msg); setErr != nil {
+			log.Error().Err(setErr).Str("run_id", runID.String()).Msg("Failed to mark bulk invoice run as failed")
+		}
+		return err
+	}
+	return s.runRepo.SetStatus(ctx, runID, billing.BulkRunCompleted, nil)
+}
+
+func (s *BulkInvoiceService) execute(ctx context.Context, run *billing.BulkRun) error {
+	if run.Total == 0 {
+		clients, err := s.listClients(ctx, run.TenantID, run.Filter)
+		if err != nil {
+			return fmt.Errorf("failed to list clients: %w", err)
+		}
+		items := make([]*billing.BulkRunItem, 0, len(clients))
+		for _, c := range clients {
+			items = append(items, &billing.BulkRunItem{ClientID: c.ID, ClientCode: c.ClientCode, ClientName: c.Name})
+		}
+		if err := s.runRepo.AddItems(ctx, run.ID, items); err != nil {
+			return fmt.Errorf("failed to store run clients: %w", err)
+		}
+	}
+
+	pending := billing.BulkItemPending
+	items, _, err := s.runRepo.ListItems(ctx, run.ID, repository.BulkRunItemFilter{Status: &pending})
+	if err != nil {
+		return err
+	}
+	packages := make(map[uuid.UUID]*service_package.ServicePackage)
+	for _, it := range items {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		outcome := s.processItem(ctx, run, it, packages)
+		outcome.ID = it.ID
+		if err := s.runRepo.UpdateItem(ctx, outcome); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processItem invoices one client of a run and returns its outcome
+func (s *BulkInvoiceService) processItem(ctx context.Context, run *billing.BulkRun, it *billing.BulkRunItem, packages map[uuid.UUID]*service_package.ServicePackage) *billing.BulkRunItem {
+	c, err := s.clientRepo.GetByID(ctx, run.TenantID, it.ClientID)
+	if err != nil {
+		it.Status, it.Reason = billing.BulkItemFailed, fmt.Sprintf("client not found: %v", err)
+		return it
+	}
+	item, draft := s.planClient(ctx, run.TenantID, c, run.PeriodStart, packages)
+	if item.Status != billing.BulkItemPending {
+		return item
+	}
+
+	invoice, created, err := s.billingService.createMonthlyInvoice(ctx, run.TenantID, draft)
+	if err != nil {
+		item.Status, item.Reason = billing.BulkItemFailed, err.Error()
+		log.Error().Err(err).Str("run_id", run.ID.String()).Str("client_id", c.ID.String()).Msg("Bulk invoice generation failed for client")
+		return item
+	}
+	if !created {
+		// Created meanwhile by the scheduler or by hand
+		item.Status, item.Reason = billing.BulkItemSkipped, billing.BulkSkipInvoiceExists
+	} else {
+		item.Status = billing.BulkItemCreated
+	}
+	item.InvoiceID, item.Amount = &invoice.ID, invoice.TotalAmount
+	return item
+}
+
+// planClient decides whether a client gets an invoice for the month starting at month. Billable
+// clients come back pending with the amount of the drafted invoice.
+func (s *BulkInvoiceService) planClient(ctx context.Context, tenantID uuid.UUID, c *client.Client, month time.Time, packages map[uuid.UUID]*service_package.ServicePackage) (*billing.BulkRunItem, *monthlyInvoiceDraft) {
+	item := &billing.BulkRunItem{ClientID: c.ID, ClientCode: c.ClientCode, ClientName: c.Name, Status: billing.BulkItemFailed}
+
+	pkg, err := s.clientPackage(ctx, tenantID, c, packages)
+	if err != nil {
+		item.Reason = err.Error()
+		return item, nil
+	}
+	var packageMode billing.BillingMode
+	if pkg != nil {
+		packageMode = pkg.BillingMode
+	}
+	cycle := clientCycle(c, pkg)
+	periodStart, periodEnd := cycle.periodOf(month)
+	item.PeriodStart, item.PeriodEnd = &periodStart, &periodEnd
+	if reason := bulkSkipReason(billing.ResolveBillingMode(c.BillingMode, packageMode), periodStart, month); reason != "" {
+		item.Status, item.Reason = billing.BulkItemSkipped, reason
+		return item, nil
+	}
+
+	existing, err := s.invoiceRepo.GetForClientPeriod(ctx, tenantID, c.ID, periodStart, periodEnd)
+	if err != nil {
+		item.Reason = err.Error()
+		return item, nil
+	}
+	if existing != nil {
+		item.Status, item.Reason = billing.BulkItemSkipped, billing.BulkSkipInvoiceExists
+		item.InvoiceID, item.Amount, item.DueDate = &existing.ID, existing.TotalAmount, &existing.DueDate
+		return item, nil
+	}
+
+	hasAny, err := s.invoiceRepo.HasAnyInvoiceForClient(ctx, tenantID, c.ID)
+	if err != nil {
+		item.Reason = err.Error()
+		return item, nil
+	}
+	// Due on the client's due day within the month (the first of the month without one)
+	dueDate := computeClientDueDate(month, c.CreatedAt, c.PaymentTempoOption, c.PaymentDueDay)
+	draft, err := s.billingService.draftMonthlyInvoice(ctx, tenantID, c, pkg, dueDate, hasAny)
+	if err != nil {
+		item.Reason = err.Error()
+		return item, nil
+	}
+	item.Status, item.DueDate = billing.BulkItemPending, &dueDate
+	item.Amount = s.billingService.buildInvoice(ctx, tenantID, draft.Request, time.Now()).TotalAmount
+	return item, draft
+}
+
+// bulkSkipReason tells why a client with the given billing mode, whose cycle containing month starts
+// at cycleStart, gets no invoice for month ("" when it does)
+func bulkSkipReason(mode billing.BillingMode, cycleStart, month time.Time) string {
+	if mode == billing.BillingModePrepaid {
+		return billing.BulkSkipPrepaid
+	}
+	if !cycleStart.Equal(month) {
+		return billing.BulkSkipNotCycleStart
+	}
+	return ""
+}
+
+// clientPackage loads a client's service package, caching packages per run
+func (s *BulkInvoiceService) clientPackage(ctx context.Context, tenantID uuid.UUID, c *client.Client, packages map[uuid.UUID]*service_package.ServicePackage) (*service_package.ServicePackage, error) {
+	if c.ServicePackageID == nil {
+		return nil, nil
+	}
+	if pkg, ok := packages[*c.ServicePackageID]; ok {
+		return pkg, nil
+	}
+	pkg, err := s.billingService.clientPackage(ctx, tenantID, c)
+	if err != nil {
+		return nil, err
+	}
+	packages[*c.ServicePackageID] = pkg
+	return pkg, nil
+}
+
+// listClients returns the active clients matching filter
+func (s *BulkInvoiceService) listClients(ctx context.Context, tenantID uuid.UUID, filter billing.BulkRunFilter) ([]*client.Client, error) {
+	activeStatus := client.StatusActive
+	var out []*client.Client
+	for page := 1; ; page++ {
+		clients, total, err := s.clientRepo.List(ctx, tenantID, &client.ClientListFilter{
+			Status:           &activeStatus,
+			GroupID:          filter.GroupID,
+			ServicePackageID: filter.ServicePackageID,
+			RouterID:         filter.RouterID,
+			Page:             page,
+			PageSize:         100,
+		})
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, clients...)
+		if len(clients) == 0 || page*100 >= total {
+			return out, nil
+		}
+	}
+}
+
+func (s *BulkInvoiceService) GetRun(ctx context.Context, tenantID, runID uuid.UUID) (*billing.BulkRun, error) {
+	return s.runRepo.GetByID(ctx, tenantID, runID)
+}
+
+func (s *BulkInvoiceService) ListRuns(ctx context.Context, tenantID uuid.UUID, page, pageSize int) ([]*billing.BulkRun, int, error) {
+	return s.runRepo.List(ctx, tenantID, page, pageSize)
+}
+
+// ListItems returns the per-client outcome of a run
+func (s *BulkInvoiceService) ListItems(ctx context.Context, tenantID, runID uuid.UUID, filter repository.BulkRunItemFilter) ([]*billing.BulkRunItem, int, error) {
+	if _, err := s.runRepo.GetByID(ctx, tenantID, runID); err != nil {
+		return nil, 0, err
+	}
+	return s.runRepo.ListItems(ctx, runID, filter)
+}
+
+// Undo cancels the invoices created by a finished run. Invoices that already received a payment
+// (or client credit) are kept and reported on their item.
+func (s *BulkInvoiceService) Undo(ctx context.Context, tenantID, userID, runID uuid.UUID) (*billing.BulkRun, error) {
+	run, err := s.runRepo.GetByID(ctx, tenantID, runID)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status != billing.BulkRunCompleted && run.Status != billing.BulkRunFailed {
+		return nil, ErrBulkRunNotUndoable
+	}
+
+	created := billing.BulkItemCreated
+	items, _, err := s.runRepo.ListItems(ctx, runID, repository.BulkRunItemFilter{Status: &created})
+	if err != nil {
+		return nil, err
+	}
+	for _, it := range items {
+		if it.InvoiceID == nil {
+			continue
+		}
+		invoice, err := s.invoiceRepo.GetByID(ctx, *it.InvoiceID)
+		if err != nil {
+			return nil, err
+		}
+		if invoice.PaidAmount > 0 || invoice.Status == billing.InvoiceStatusPaid {
+			it.Reason = bulkUndoKeptPaid
+		} else {
+			if invoice.Status != billing.InvoiceStatusCancelled {
+				if err := s.billingService.cancelGeneratedInvoice(ctx, invoice); err != nil {
+					return nil, err
+				}
+			}
+			it.Status = billing.BulkItemCancelled
+		}
+		if err := s.runRepo.UpdateItem(ctx, it); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.runRepo.MarkUndone(ctx, runID, userID, time.Now()); err != nil {
+		return nil, err
+	}
+	return s.runRepo.GetByID(ctx, tenantID, runID)
+}
+
+// cancelGeneratedInvoice cancels an unpaid generated invoice and returns the adjustments it billed to
+// pending, so the client's next invoice bills them again
+func (s *BillingService) cancelGeneratedInvoice(ctx context.Context, invoice *billing.Invoice) error {
+	if err := s.invoiceRepo.UpdateStatus(ctx, invoice.ID, billing.InvoiceStatusCancelled); err != nil {
+		return err
+	}
+	if s.ledger != nil {
+		s.ledger.PostInvoiceCancel(ctx, invoice)
+	}
+	if s.adjustmentRepo != nil {
+		if err := s.adjustmentRepo.ReleaseFromInvoice(ctx, invoice.ID); err != nil {
+			return fmt.Errorf("failed to release billing adjustments: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rrnet/internal/domain/billing"
+)
+
+func TestParseBulkPeriod(t *testing.T) {
+	month, err := parseBulkPeriod("2025-06")
+	require.NoError(t, err)
+	assert.Equal(t, ymd(2025, time.June, 1), month)
+
+	for _, v := range []string{"", "2025-13", "06-2025", "2025-06-01"} {
+		_, err := parseBulkPeriod(v)
+		assert.ErrorIs(t, err, ErrBulkPeriodInvalid, v)
+	}
+}
+
+func TestBulkSkipReason(t *testing.T) {
+	june := ymd(2025, time.June, 1)
+	assert.Equal(t, "", bulkSkipReason(billing.BillingModePostpaid, june, june))
+	assert.Equal(t, billing.BulkSkipPrepaid, bulkSkipReason(billing.BillingModePrepaid, june, june))
+	// Quarterly client whose cycle started in May
+	assert.Equal(t, billing.BulkSkipNotCycleStart, bulkSkipReason(billing.BillingModePostpaid, ymd(2025, time.May, 1), june))
+}
+
+func TestComputeClientDueDateInBulkPeriod(t *testing.T) {
+	june := ymd(2025, time.June, 1)
+	created := ymd(2024, time.January, 20)
+	assert.Equal(t, time.Date(2025, time.June, 10, 23, 59, 59, 0, time.Local), computeClientDueDate(june, created, "manual", 10))
+	// Clamped to the end of the month; no due day falls on the first
+	assert.Equal(t, time.Date(2025, time.June, 30, 23, 59, 59, 0, time.Local), computeClientDueDate(june, created, "manual", 31))
+	assert.Equal(t, time.Date(2025, time.June, 1, 23, 59, 59, 0, time.Local), computeClientDueDate(june, created, "manual", 0))
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/client"
+	"rrnet/internal/domain/service_package"
+)
+
+var ErrBillingCycleInvalid = errors.New("billing cycle must be 1, 3, 6 or 12 months")
+
+// billingCycle is how often a client is invoiced: Months-long runs of calendar months aligned to
+// the month of Anchor
+type billingCycle struct {
+	Months int
+	Anchor time.Time
+}
+
+// periodOf returns the cycle period containing t
+func (c billingCycle) periodOf(t time.Time) (time.Time, time.Time) {
+	return billing.CyclePeriodOf(t, c.Months, c.Anchor)
+}
+
+// clientCycle resolves the billing cycle of a client: its own override, else the package default,
+// else monthly. pkg may be nil (legacy clients without a package).
+func clientCycle(c *client.Client, pkg *service_package.ServicePackage) billingCycle {
+	cycle := billingCycle{Months: 1, Anchor: c.CreatedAt}
+	if c.BillingCycleAnchor != nil {
+		cycle.Anchor = *c.BillingCycleAnchor
+	}
+	switch {
+	case c.BillingCycleMonths != nil && billing.ValidCycleMonths(*c.BillingCycleMonths):
+		cycle.Months = *c.BillingCycleMonths
+	case pkg != nil && billing.ValidCycleMonths(pkg.DefaultCycleMonths):
+		cycle.Months = pkg.DefaultCycleMonths
+	}
+	return cycle
+}
+
+// clientPrice returns the price of months of service for a client, using the package's cycle price
+// when it offers that cycle, together with the invoice line description
+func clientPrice(c *client.Client, pkg *service_package.ServicePackage, months int) (int64, string, error) {
+	if pkg != nil {
+		desc := fmt.Sprintf("Layanan Internet - %s", pkg.Name)
+		price := pkg.CyclePrice(months)
+		if pkg.PricingModel == service_package.PricingModelPerDevice {
+			devCount := 1
+			if c.DeviceCount != nil && *c.DeviceCount > 0 {
+				devCount = *c.DeviceCount
+			}
+			price *= float64(devCount)
+		}
+		return int64(math.Round(price)), desc, nil
+	}
+	if c.MonthlyFee == 0 {
+		return 0, "", fmt.Errorf("client has no service package or monthly fee configured")
+	}
+	return int64(c.MonthlyFee*100) * int64(months), "Layanan Internet", nil // legacy cents
+}
+
+// clientPackage loads the service package of a client, or returns nil for clients without one
+func (s *BillingService) clientPackage(ctx context.Context, tenantID uuid.UUID, c *client.Client) (*service_package.ServicePackage, error) {
+	if c.ServicePackageID == nil || *c.ServicePackageID == uuid.Nil || s.servicePackageRepo == nil {
+		return nil, nil
+	}
+	pkg, err := s.servicePackageRepo.GetByID(ctx, tenantID, *c.ServicePackageID)
+	if err != nil {
+		return nil, fmt.Errorf("service package not found: %w", err)
+	}
+	return pkg, nil
+}
+
+// cycleLabel describes a multi-month period on an invoice line
+func cycleLabel(months int, start, end time.Time) string {
+	return fmt.Sprintf("%d bulan, %s - %s", months, start.Format("02-01-2006"), end.Format("02-01-2006"))
+}
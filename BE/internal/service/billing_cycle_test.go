@@ -0,0 +1,56 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"rrnet/internal/domain/client"
+	"rrnet/internal/domain/service_package"
+)
+
+func TestClientCyclePeriod(t *testing.T) {
+	pkg := &service_package.ServicePackage{DefaultCycleMonths: 3}
+	c := &client.Client{CreatedAt: ymd(2025, time.February, 14)}
+
+	// Quarterly cycles anchored in February: Feb-Apr, May-Jul, ...
+	cycle := clientCycle(c, pkg)
+	assert.Equal(t, 3, cycle.Months)
+	start, end := cycle.periodOf(ymd(2025, time.June, 10))
+	assert.Equal(t, ymd(2025, time.May, 1), start)
+	assert.Equal(t, ymd(2025, time.July, 31), end)
+	start, end = cycle.periodOf(ymd(2026, time.January, 5))
+	assert.Equal(t, ymd(2025, time.November, 1), start)
+	assert.Equal(t, ymd(2026, time.January, 31), end)
+
+	// Client override with its own anchor
+	months := 12
+	anchor := ymd(2025, time.July, 1)
+	c.BillingCycleMonths, c.BillingCycleAnchor = &months, &anchor
+	start, end = clientCycle(c, pkg).periodOf(ymd(2025, time.March, 3))
+	assert.Equal(t, ymd(2024, time.July, 1), start)
+	assert.Equal(t, ymd(2025, time.June, 30), end)
+
+	// No package: monthly
+	assert.Equal(t, 1, clientCycle(&client.Client{CreatedAt: anchor}, nil).Months)
+}
+
+func TestClientPriceCycle(t *testing.T) {
+	pkg := &service_package.ServicePackage{
+		Name:          "Bisnis 50",
+		PricingModel:  service_package.PricingModelFlatMonthly,
+		PriceMonthly:  150000,
+		BillingCycles: []service_package.CycleOption{{Months: 12, Price: 1500000}},
+	}
+	c := &client.Client{}
+
+	price, desc, err := clientPrice(c, pkg, 12)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1500000), price)
+	assert.Equal(t, "Layanan Internet - Bisnis 50", desc)
+
+	// Cycle not offered: monthly price times months
+	price, _, _ = clientPrice(c, pkg, 3)
+	assert.Equal(t, int64(450000), price)
+}
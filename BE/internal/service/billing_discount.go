@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/domain/client"
+	"rrnet/internal/domain/discount"
+)
+
+// invoiceDiscountLine is a discount applied to a generated invoice (rendered as a negative item)
+type invoiceDiscountLine struct {
+	Description string
+	Amount      int64 // positive amount to subtract
+	DiscountID  *uuid.UUID
+}
+
+// resolveDiscountLines returns the discount lines for a client's monthly invoice with the given base amount.
+// The client discount is ignored when invalid (inactive/expired/deleted) or when its max cycles are used up.
+// Max cycles count billing cycles, not months: a client on a quarterly cycle gets 3 months per cycle.
+func (s *BillingService) resolveDiscountLines(ctx context.Context, tenantID uuid.UUID, c *client.Client, base int64) []invoiceDiscountLine {
+	var clientDisc *discount.Discount
+	if s.discountRepo != nil && c.DiscountID != nil && *c.DiscountID != uuid.Nil {
+		d, err := s.discountRepo.GetByID(ctx, *c.DiscountID, tenantID)
+		if err != nil {
+			log.Warn().Err(err).Str("client_id", c.ID.String()).Msg("Failed to load client discount, skipping")
+		} else if d.IsValid() {
+			clientDisc = d
+			if d.MaxCycles != nil {
+				used, err := s.invoiceRepo.CountDiscountUsage(ctx, tenantID, c.ID, d.ID)
+				if err != nil {
+					log.Warn().Err(err).Str("client_id", c.ID.String()).Msg("Failed to count discount usage, skipping")
+					clientDisc = nil
+				} else if used >= *d.MaxCycles {
+					clientDisc = nil
+				}
+			}
+		}
+	}
+
+	tenantDisc := ServiceDiscountSetting{}
+	if s.tenantRepo != nil {
+		t, err := s.tenantRepo.GetByID(ctx, tenantID)
+		if err != nil {
+			log.Warn().Err(err).Str("tenant_id", tenantID.String()).Msg("Failed to load tenant discount, skipping")
+		} else {
+			tenantDisc = readServiceDiscount(t.Settings)
+		}
+	}
+
+	return computeDiscountLines(base, clientDisc, tenantDisc)
+}
+
+// computeDiscountLines applies the client discount and the tenant-wide discount to base
+// according to the tenant's stacking rule. The total discount never exceeds base.
+func computeDiscountLines(base int64, clientDisc *discount.Discount, tenantDisc ServiceDiscountSetting) []invoiceDiscountLine {
+	if base <= 0 {
+		return nil
+	}
+
+	clientLine := func(on int64) *invoiceDiscountLine {
+		if clientDisc == nil {
+			return nil
+		}
+		amount := capDiscount(int64(math.Round(clientDisc.CalculateDiscount(float64(on)))), on)
+		if amount <= 0 {
+			return nil
+		}
+		desc := "Diskon " + clientDisc.Name
+		if clientDisc.Type == discount.TypePercent {
+			desc += fmt.Sprintf(" (%s%%)", strconv.FormatFloat(clientDisc.Value, 'f', -1, 64))
+		}
+		id := clientDisc.ID
+		return &invoiceDiscountLine{Description: desc, Amount: amount, DiscountID: &id}
+	}
+
+	tenantLine := func(on int64) *invoiceDiscountLine {
+		if !tenantDisc.Enabled || tenantDisc.Value <= 0 {
+			return nil
+		}
+		var raw float64
+		desc := "Diskon Layanan"
+		if tenantDisc.Type == ServiceDiscountTypeNominal {
+			raw = tenantDisc.Value
+		} else {
+			raw = float64(on) * tenantDisc.Value / 100
+			desc += fmt.Sprintf(" (%s%%)", strconv.FormatFloat(tenantDisc.Value, 'f', -1, 64))
+		}
+		amount := capDiscount(int64(math.Round(raw)), on)
+		if amount <= 0 {
+			return nil
+		}
+		return &invoiceDiscountLine{Description: desc, Amount: amount}
+	}
+
+	var lines []invoiceDiscountLine
+	switch tenantDisc.Stacking {
+	case ServiceDiscountStackCombine:
+		remaining := base
+		if cl := clientLine(remaining); cl != nil {
+			lines = append(lines, *cl)
+			remaining -= cl.Amount
+		}
+		if tl := tenantLine(remaining); tl != nil {
+			lines = append(lines, *tl)
+		}
+	case ServiceDiscountStackClientPriority:
+		if cl := clientLine(base); cl != nil {
+			lines = append(lines, *cl)
+		} else if tl := tenantLine(base); tl != nil {
+			lines = append(lines, *tl)
+		}
+	default: // best
+		cl := clientLine(base)
+		tl := tenantLine(base)
+		switch {
+		case cl != nil && (tl == nil || cl.Amount >= tl.Amount):
+			lines = append(lines, *cl)
+		case tl != nil:
+			lines = append(lines, *tl)
+		}
+	}
+	return lines
+}
+
+func capDiscount(amount, max int64) int64 {
+	if amount > max {
+		return max
+	}
+	return amount
+}
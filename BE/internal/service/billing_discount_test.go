@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rrnet/internal/domain/discount"
+)
+
+func TestComputeDiscountLines(t *testing.T) {
+	half := &discount.Discount{ID: uuid.New(), Name: "Promo", Type: discount.TypePercent, Value: 50, IsActive: true}
+	tenant10 := ServiceDiscountSetting{Enabled: true, Type: ServiceDiscountTypePercent, Value: 10}
+
+	// No discounts at all
+	assert.Empty(t, computeDiscountLines(150000, nil, ServiceDiscountSetting{}))
+
+	// Best (default): the larger discount wins
+	lines := computeDiscountLines(150000, half, tenant10)
+	require.Len(t, lines, 1)
+	assert.Equal(t, int64(75000), lines[0].Amount)
+	assert.Equal(t, "Diskon Promo (50%)", lines[0].Description)
+	require.NotNil(t, lines[0].DiscountID)
+
+	// Combine: client discount first, tenant discount on the remainder
+	combine := tenant10
+	combine.Stacking = ServiceDiscountStackCombine
+	lines = computeDiscountLines(150000, half, combine)
+	require.Len(t, lines, 2)
+	assert.Equal(t, int64(75000), lines[0].Amount)
+	assert.Equal(t, int64(7500), lines[1].Amount)
+	assert.Nil(t, lines[1].DiscountID)
+
+	// Client priority: tenant discount only when the client has none
+	priority := tenant10
+	priority.Stacking = ServiceDiscountStackClientPriority
+	lines = computeDiscountLines(150000, nil, priority)
+	require.Len(t, lines, 1)
+	assert.Equal(t, int64(15000), lines[0].Amount)
+
+	// Nominal discounts never exceed the base amount
+	big := &discount.Discount{ID: uuid.New(), Name: "Gratis", Type: discount.TypeNominal, Value: 500000, IsActive: true}
+	lines = computeDiscountLines(150000, big, ServiceDiscountSetting{})
+	require.Len(t, lines, 1)
+	assert.Equal(t, int64(150000), lines[0].Amount)
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/client"
+	"rrnet/pkg/pdf"
+)
+
+var (
+	ErrPaymentNotFound           = errors.New("payment not found")
+	ErrInvoiceLogoInvalid        = errors.New("logo must be a base64 encoded JPEG or PNG image")
+	ErrInvoiceLogoTooLarge       = errors.New("logo must not exceed 256 KB")
+	ErrInvoiceLogoDimensions     = fmt.Errorf("logo must not exceed %dx%d pixels", pdf.MaxImageSize, pdf.MaxImageSize)
+	ErrInvoiceBankAccountInvalid = errors.New("bank accounts need a bank name and account number (max 5)")
+	ErrInvoiceFooterTooLong      = errors.New("footer must not exceed 500 characters")
+)
+
+const (
+	invoiceLogoMaxBytes    = 256 * 1024
+	invoiceMaxBankAccounts = 5
+	invoiceFooterMaxLen    = 500
+)
+
+// InvoiceBankAccount is a transfer destination printed on invoices
+type InvoiceBankAccount struct {
+	BankName      string `json:"bank_name"`
+	AccountNumber string `json:"account_number"`
+	AccountName   string `json:"account_name"`
+}
+
+// InvoiceTemplate holds the per-tenant options of printed invoices and receipts
+// (stored in tenant settings under "invoice_template")
+type InvoiceTemplate struct {
+	CompanyName  string               `json:"company_name"` // empty = tenant name
+	Address      string               `json:"address"`
+	Phone        string               `json:"phone"`
+	Email        string               `json:"email"`
+	Logo         string               `json:"logo"` // JPEG/PNG as a data URI or plain base64
+	Footer       string               `json:"footer"`
+	BankAccounts []InvoiceBankAccount `json:"bank_accounts"`
+
+	taxName string // from the tenant tax profile, filled when rendering
+	taxID   string
+}
+
+// logoBytes decodes the template logo (nil when there is none)
+func (t InvoiceTemplate) logoBytes() ([]byte, error) {
+	raw := strings.TrimSpace(t.Logo)
+	if raw == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(raw, "data:") {
+		i := strings.Index(raw, ",")
+		if i < 0 || !strings.HasSuffix(raw[:i], ";base64") {
+			return nil, ErrInvoiceLogoInvalid
+		}
+		raw = raw[i+1:]
+	}
+	b, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvoiceLogoInvalid
+	}
+	return b, nil
+}
+
+func readInvoiceTemplate(settings map[string]interface{}) InvoiceTemplate {
+	out := InvoiceTemplate{BankAccounts: []InvoiceBankAccount{}}
+	raw, ok := settings["invoice_template"].(map[string]interface{})
+	if !ok || raw == nil {
+		return out
+	}
+	str := func(m map[string]interface{}, k string) string {
+		v, _ := m[k].(string)
+		return v
+	}
+	out.CompanyName = str(raw, "company_name")
+	out.Address = str(raw, "address")
+	out.Phone = str(raw, "phone")
+	out.Email = str(raw, "email")
+	out.Logo = str(raw, "logo")
+	out.Footer = str(raw, "footer")
+	if accounts, ok := raw["bank_accounts"].([]interface{}); ok {
+		for _, a := range accounts {
+			m, ok := a.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			out.BankAccounts = append(out.BankAccounts, InvoiceBankAccount{
+				BankName:      str(m, "bank_name"),
+				AccountNumber: str(m, "account_number"),
+				AccountName:   str(m, "account_name"),
+			})
+		}
+	}
+	return out
+}
+
+func (s *BillingService) GetInvoiceTemplate(ctx context.Context, tenantID uuid.UUID) (*InvoiceTemplate, error) {
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	out := readInvoiceTemplate(t.Settings)
+	return &out, nil
+}
+
+func (s *BillingService) UpdateInvoiceTemplate(ctx context.Context, tenantID uuid.UUID, in InvoiceTemplate) (*InvoiceTemplate, error) {
+	in.CompanyName = strings.TrimSpace(in.CompanyName)
+	in.Address = strings.TrimSpace(in.Address)
+	in.Phone = strings.TrimSpace(in.Phone)
+	in.Email = strings.TrimSpace(in.Email)
+	in.Logo = strings.TrimSpace(in.Logo)
+	in.Footer = strings.TrimSpace(in.Footer)
+
+	logo, err := in.logoBytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(logo) > invoiceLogoMaxBytes {
+		return nil, ErrInvoiceLogoTooLarge
+	}
+	if logo != nil {
+		if _, err := pdf.New("").AddImage(logo); errors.Is(err, pdf.ErrImageTooLarge) {
+			return nil, ErrInvoiceLogoDimensions
+		} else if err != nil {
+			return nil, ErrInvoiceLogoInvalid
+		}
+	}
+	if len([]rune(in.Footer)) > invoiceFooterMaxLen {
+		return nil, ErrInvoiceFooterTooLong
+	}
+	if len(in.BankAccounts) > invoiceMaxBankAccounts {
+		return nil, ErrInvoiceBankAccountInvalid
+	}
+	accounts := make([]interface{}, 0, len(in.BankAccounts))
+	for i, a := range in.BankAccounts {
+		a.BankName = strings.TrimSpace(a.BankName)
+		a.AccountNumber = strings.TrimSpace(a.AccountNumber)
+		a.AccountName = strings.TrimSpace(a.AccountName)
+		if a.BankName == "" || a.AccountNumber == "" {
+			return nil, ErrInvoiceBankAccountInvalid
+		}
+		in.BankAccounts[i] = a
+		accounts = append(accounts, map[string]interface{}{
+			"bank_name":      a.BankName,
+			"account_number": a.AccountNumber,
+			"account_name":   a.AccountName,
+		})
+	}
+	if in.BankAccounts == nil {
+		in.BankAccounts = []InvoiceBankAccount{}
+	}
+
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if t.Settings == nil {
+		t.Settings = map[string]interface{}{}
+	}
+	t.Settings["invoice_template"] = map[string]interface{}{
+		"company_name":  in.CompanyName,
+		"address":       in.Address,
+		"phone":         in.Phone,
+		"email":         in.Email,
+		"logo":          in.Logo,
+		"footer":        in.Footer,
+		"bank_accounts": accounts,
+	}
+	if err := s.tenantRepo.UpdateSettings(ctx, tenantID, t.Settings); err != nil {
+		return nil, err
+	}
+	return &in, nil
+}
+
+// documentTemplate returns the tenant's template with the company name defaulted to the tenant name
+func (s *BillingService) documentTemplate(ctx context.Context, tenantID uuid.UUID) (InvoiceTemplate, error) {
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return InvoiceTemplate{}, err
+	}
+	tpl := readInvoiceTemplate(t.Settings)
+	if tpl.CompanyName == "" {
+		tpl.CompanyName = t.Name
+	}
+	tax := readTaxSettings(t.Settings)
+	tpl.taxName = tax.Name
+	if tax.Enabled {
+		tpl.taxID = tax.TaxID
+	}
+	return tpl, nil
+}
+
+// documentClient loads the billed client; a missing client falls back to the invoice's denormalized fields
+func (s *BillingService) documentClient(ctx context.Context, tenantID uuid.UUID, inv *billing.Invoice) *client.Client {
+	c, err := s.clientRepo.GetByID(ctx, tenantID, inv.ClientID)
+	if err == nil {
+		return c
+	}
+	fallback := &client.Client{ID: inv.ClientID, Address: inv.ClientAddress, Phone: inv.ClientPhone}
+	if inv.ClientName != nil {
+		fallback.Name = *inv.ClientName
+	}
+	return fallback
+}
+
+// RenderInvoicePDF renders a printable invoice and returns it with a file name
+func (s *BillingService) RenderInvoicePDF(ctx context.Context, tenantID, invoiceID uuid.UUID) ([]byte, string, error) {
+	inv, err := s.invoiceRepo.GetByID(ctx, invoiceID)
+	if err != nil || inv.TenantID != tenantID {
+		return nil, "", ErrInvoiceNotFound
+	}
+	tpl, err := s.documentTemplate(ctx, tenantID)
+	if err != nil {
+		return nil, "", err
+	}
+	out, err := renderInvoicePDF(tpl, inv, s.documentClient(ctx, tenantID, inv))
+	if err != nil {
+		return nil, "", err
+	}
+	return out, documentFileName(inv.InvoiceNumber), nil
+}
+
+// RenderPaymentReceiptPDF renders the receipt of one payment and returns it with a file name.
+// Payments recorded before receipts were numbered show a number derived from the payment ID.
+func (s *BillingService) RenderPaymentReceiptPDF(ctx context.Context, tenantID, paymentID uuid.UUID) ([]byte, string, error) {
+	p, err := s.paymentRepo.GetByID(ctx, paymentID)
+	if err != nil || p.TenantID != tenantID {
+		return nil, "", ErrPaymentNotFound
+	}
+	inv, err := s.invoiceRepo.GetByID(ctx, p.InvoiceID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load invoice of payment: %w", err)
+	}
+	tpl, err := s.documentTemplate(ctx, tenantID)
+	if err != nil {
+		return nil, "", err
+	}
+	out, err := renderReceiptPDF(tpl, p, inv, s.documentClient(ctx, tenantID, inv))
+	if err != nil {
+		return nil, "", err
+	}
+	return out, documentFileName(receiptNumber(p)), nil
+}
+
+// documentFileName turns a document number into a safe file name
+func documentFileName(number string) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, number)
+	return name + ".pdf"
+}
@@ -0,0 +1,405 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/client"
+	"rrnet/pkg/pdf"
+	"rrnet/pkg/utils"
+)
+
+// Page layout of printed billing documents (points, A4)
+const (
+	docMargin     = 40.0
+	docRight      = pdf.A4Width - docMargin
+	docFooterTop  = pdf.A4Height - 60
+	docLineHeight = 13.0
+)
+
+var indonesianMonths = [...]string{
+	"Januari", "Februari", "Maret", "April", "Mei", "Juni",
+	"Juli", "Agustus", "September", "Oktober", "November", "Desember",
+}
+
+// formatDateID formats a date the Indonesian way, e.g. "5 Maret 2026"
+func formatDateID(t time.Time) string {
+	return fmt.Sprintf("%d %s %d", t.Day(), indonesianMonths[t.Month()-1], t.Year())
+}
+
+func paymentMethodLabel(m billing.PaymentMethod) string {
+	switch m {
+	case billing.PaymentMethodCash:
+		return "Tunai"
+	case billing.PaymentMethodBankTransfer:
+		return "Transfer Bank"
+	case billing.PaymentMethodEWallet:
+		return "E-Wallet"
+	case billing.PaymentMethodQRIS:
+		return "QRIS"
+	case billing.PaymentMethodVA:
+		return "Virtual Account"
+	case billing.PaymentMethodCollector:
+		return "Kolektor"
+	}
+	return string(m)
+}
+
+// receiptNumber returns the issued receipt number, or one derived from the payment for receipts
+// issued before numbering
+func receiptNumber(p *billing.Payment) string {
+	if p.ReceiptNumber != nil && *p.ReceiptNumber != "" {
+		return *p.ReceiptNumber
+	}
+	return fmt.Sprintf("KW-%s-%s", p.ReceivedAt.Format("200601"), strings.ToUpper(p.ID.String()[:8]))
+}
+
+// billingDoc draws the shared parts (letterhead, footer, page breaks) of invoices and receipts
+type billingDoc struct {
+	doc  *pdf.Document
+	page *pdf.Page
+	tpl  InvoiceTemplate
+	logo *pdf.Image
+	y    float64
+}
+
+func newBillingDoc(title string, tpl InvoiceTemplate) *billingDoc {
+	d := &billingDoc{doc: pdf.New(title), tpl: tpl}
+	// A logo that no longer decodes is skipped rather than failing the document
+	if b, err := tpl.logoBytes(); err == nil && b != nil {
+		d.logo, _ = d.doc.AddImage(b)
+	}
+	d.newPage()
+	return d
+}
+
+func (d *billingDoc) newPage() {
+	d.page = d.doc.AddPage()
+	d.y = docMargin
+	d.drawFooter()
+}
+
+// ensure starts a new page when h more points do not fit above the footer
+func (d *billingDoc) ensure(h float64) bool {
+	if d.y+h <= docFooterTop-10 {
+		return false
+	}
+	d.newPage()
+	return true
+}
+
+// letterhead draws logo, company details and the document title with its number
+func (d *billingDoc) letterhead(title, number string) {
+	p := d.page
+	x := docMargin
+	top := d.y
+	if d.logo != nil && d.logo.Height() > 0 {
+		h := 50.0
+		w := h * float64(d.logo.Width()) / float64(d.logo.Height())
+		if w > 120 {
+			w, h = 120, 120*float64(d.logo.Height())/float64(d.logo.Width())
+		}
+		p.Image(d.logo, x, top, w, h)
+		x += w + 12
+	}
+
+	y := top + 14
+	p.Text(x, y, 14, pdf.Bold, pdf.Truncate(d.tpl.CompanyName, 14, pdf.Bold, 300))
+	npwp := ""
+	if d.tpl.taxID != "" {
+		npwp = "NPWP: " + d.tpl.taxID
+	}
+	for _, line := range []string{d.tpl.Address, d.tpl.Phone, d.tpl.Email, npwp} {
+		for _, l := range pdf.WrapText(line, 8.5, pdf.Regular, 260) {
+			if l == "" {
+				continue
+			}
+			y += 11
+			p.Text(x, y, 8.5, pdf.Regular, l)
+		}
+	}
+
+	p.TextRight(docRight, top+18, 20, pdf.Bold, title)
+	p.TextRight(docRight, top+34, 10, pdf.Regular, number)
+
+	d.y = maxF(y, top+50) + 14
+	p.SetStrokeColor(0.75, 0.75, 0.75)
+	p.Line(docMargin, d.y, docRight, d.y, 0.8)
+	d.y += 20
+}
+
+// drawFooter prints the tenant footer at the bottom of the current page
+func (d *billingDoc) drawFooter() {
+	if d.tpl.Footer == "" {
+		return
+	}
+	p := d.page
+	p.SetStrokeColor(0.75, 0.75, 0.75)
+	p.Line(docMargin, docFooterTop, docRight, docFooterTop, 0.5)
+	p.SetColor(0.4, 0.4, 0.4)
+	y := docFooterTop + 12
+	for _, l := range pdf.WrapText(d.tpl.Footer, 8, pdf.Regular, docRight-docMargin) {
+		if y > pdf.A4Height-docMargin/2 {
+			break
+		}
+		p.TextCenter(pdf.A4Width/2, y, 8, pdf.Regular, l)
+		y += 10
+	}
+	p.SetColor(0, 0, 0)
+}
+
+// labelValue draws "label  value" rows in a column starting at x
+func (d *billingDoc) labelValue(x, y, labelWidth float64, label, value string, bold bool) {
+	font := pdf.Regular
+	if bold {
+		font = pdf.Bold
+	}
+	d.page.Text(x, y, 9, pdf.Regular, label)
+	d.page.Text(x+labelWidth, y, 9, font, value)
+}
+
+// stamp draws a status stamp (e.g. LUNAS) right-aligned at the current position
+func (d *billingDoc) stamp(text string, r, g, b float64) {
+	w := pdf.TextWidth(text, 16, pdf.Bold) + 24
+	x := docRight - w
+	d.page.SetStrokeColor(r, g, b)
+	d.page.StrokeRect(x, d.y, w, 28, 2)
+	d.page.SetColor(r, g, b)
+	d.page.TextCenter(x+w/2, d.y+20, 16, pdf.Bold, text)
+	d.page.SetColor(0, 0, 0)
+}
+
+// bankAccounts lists the transfer destinations
+func (d *billingDoc) bankAccounts() {
+	if len(d.tpl.BankAccounts) == 0 {
+		return
+	}
+	d.ensure(docLineHeight * float64(len(d.tpl.BankAccounts)+2))
+	d.page.Text(docMargin, d.y, 9, pdf.Bold, "Pembayaran dapat ditransfer ke:")
+	for _, a := range d.tpl.BankAccounts {
+		d.y += docLineHeight
+		line := fmt.Sprintf("%s  %s", a.BankName, a.AccountNumber)
+		if a.AccountName != "" {
+			line += " a.n. " + a.AccountName
+		}
+		d.page.Text(docMargin, d.y, 9, pdf.Regular, line)
+	}
+	d.y += docLineHeight * 2
+}
+
+// notes prints a wrapped free-text block
+func (d *billingDoc) notes(title, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	lines := pdf.WrapText(text, 9, pdf.Regular, docRight-docMargin)
+	d.ensure(docLineHeight * float64(len(lines)+2))
+	d.page.Text(docMargin, d.y, 9, pdf.Bold, title)
+	for _, l := range lines {
+		d.y += docLineHeight
+		d.ensure(docLineHeight)
+		d.page.Text(docMargin, d.y, 9, pdf.Regular, l)
+	}
+	d.y += docLineHeight * 2
+}
+
+// billTo prints the client block at the left; returns the y below it
+func (d *billingDoc) billTo(label string, c *client.Client) float64 {
+	p := d.page
+	y := d.y
+	p.SetColor(0.4, 0.4, 0.4)
+	p.Text(docMargin, y, 8.5, pdf.Bold, label)
+	p.SetColor(0, 0, 0)
+	y += 14
+	p.Text(docMargin, y, 11, pdf.Bold, pdf.Truncate(c.Name, 11, pdf.Bold, 260))
+	if c.ClientCode != "" {
+		y += 12
+		p.Text(docMargin, y, 9, pdf.Regular, "ID Pelanggan: "+c.ClientCode)
+	}
+	for _, l := range pdf.WrapText(utils.Value(c.Address), 9, pdf.Regular, 260) {
+		if l == "" {
+			continue
+		}
+		y += 12
+		p.Text(docMargin, y, 9, pdf.Regular, l)
+	}
+	if phone := utils.Value(c.Phone); phone != "" {
+		y += 12
+		p.Text(docMargin, y, 9, pdf.Regular, "Telp: "+phone)
+	}
+	return y
+}
+
+// renderInvoicePDF lays out an invoice: letterhead, client, dates, items, totals and payment info
+func renderInvoicePDF(tpl InvoiceTemplate, inv *billing.Invoice, c *client.Client) ([]byte, error) {
+	d := newBillingDoc("Invoice "+inv.InvoiceNumber, tpl)
+	d.letterhead("INVOICE", inv.InvoiceNumber)
+
+	bottom := d.billTo("TAGIHAN KEPADA", c)
+	infoX := 340.0
+	y := d.y
+	d.labelValue(infoX, y, 80, "Tanggal", formatDateID(inv.CreatedAt), false)
+	y += docLineHeight
+	d.labelValue(infoX, y, 80, "Periode", formatDateID(inv.PeriodStart)+" - "+formatDateID(inv.PeriodEnd), false)
+	y += docLineHeight
+	d.labelValue(infoX, y, 80, "Jatuh Tempo", formatDateID(inv.DueDate), true)
+	d.y = maxF(bottom, y) + 26
+
+	// Items
+	const (
+		colQty    = 360.0
+		colPrice  = 455.0
+		descWidth = 290.0
+	)
+	header := func() {
+		d.page.SetColor(0.93, 0.93, 0.93)
+		d.page.FillRect(docMargin, d.y-12, docRight-docMargin, 18)
+		d.page.SetColor(0, 0, 0)
+		d.page.Text(docMargin+6, d.y, 9, pdf.Bold, "Deskripsi")
+		d.page.TextRight(colQty, d.y, 9, pdf.Bold, "Qty")
+		d.page.TextRight(colPrice, d.y, 9, pdf.Bold, "Harga")
+		d.page.TextRight(docRight-6, d.y, 9, pdf.Bold, "Jumlah")
+		d.y += 20
+	}
+	header()
+	for _, it := range inv.Items {
+		lines := pdf.WrapText(it.Description, 9, pdf.Regular, descWidth)
+		if d.ensure(docLineHeight * float64(len(lines))) {
+			header()
+		}
+		d.page.TextRight(colQty, d.y, 9, pdf.Regular, fmt.Sprintf("%d", it.Quantity))
+		d.page.TextRight(colPrice, d.y, 9, pdf.Regular, utils.FormatRupiah(it.UnitPrice))
+		d.page.TextRight(docRight-6, d.y, 9, pdf.Regular, utils.FormatRupiah(it.Amount))
+		for i, l := range lines {
+			if i > 0 {
+				d.y += docLineHeight
+			}
+			d.page.Text(docMargin+6, d.y, 9, pdf.Regular, l)
+		}
+		d.y += docLineHeight + 3
+	}
+	d.page.SetStrokeColor(0.75, 0.75, 0.75)
+	d.page.Line(docMargin, d.y-8, docRight, d.y-8, 0.5)
+	d.y += 8
+
+	// Totals
+	type row struct {
+		label  string
+		amount int64
+		bold   bool
+	}
+	taxLabel := tpl.taxName
+	if taxLabel == "" {
+		taxLabel = "Pajak"
+	}
+	if inv.TaxRate > 0 {
+		taxLabel += " " + strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", inv.TaxRate), "0"), ".") + "%"
+	}
+	rows := []row{{"Subtotal", inv.Subtotal, false}}
+	if inv.DiscountAmount > 0 {
+		rows = append(rows, row{"Diskon", -inv.DiscountAmount, false})
+	}
+	if inv.TaxAmount > 0 && !inv.TaxInclusive {
+		rows = append(rows, row{taxLabel, inv.TaxAmount, false})
+	}
+	if inv.UniqueCode != nil && inv.UniqueCodeInTotal {
+		rows = append(rows, row{"Kode Unik", int64(*inv.UniqueCode), false})
+	}
+	rows = append(rows, row{"Total", inv.TotalAmount, true})
+	if inv.TaxAmount > 0 && inv.TaxInclusive {
+		rows = append(rows, row{"DPP", inv.TaxBase, false}, row{taxLabel + " (termasuk)", inv.TaxAmount, false})
+	}
+	if inv.PaidAmount > 0 {
+		rows = append(rows, row{"Dibayar", -inv.PaidAmount, false})
+	}
+	if rem := inv.RemainingAmount(); rem > 0 && inv.Status != billing.InvoiceStatusCancelled {
+		rows = append(rows, row{"Sisa Tagihan", rem, true})
+		if inv.UniqueCode != nil && !inv.UniqueCodeInTotal {
+			rows = append(rows, row{fmt.Sprintf("Jumlah Transfer (kode unik %03d)", *inv.UniqueCode), inv.PayableAmount(), true})
+		}
+	}
+	d.ensure(docLineHeight*float64(len(rows)) + 40)
+	for _, r := range rows {
+		font := pdf.Regular
+		if r.bold {
+			font = pdf.Bold
+		}
+		d.page.Text(colPrice-100, d.y, 9.5, font, r.label)
+		d.page.TextRight(docRight-6, d.y, 9.5, font, utils.FormatRupiah(r.amount))
+		d.y += docLineHeight + 2
+	}
+	d.y += 10
+
+	switch {
+	case inv.Status == billing.InvoiceStatusPaid:
+		d.stamp("LUNAS", 0.1, 0.55, 0.25)
+		d.y += 40
+	case inv.Status == billing.InvoiceStatusCancelled:
+		d.stamp("DIBATALKAN", 0.75, 0.1, 0.1)
+		d.y += 40
+	default:
+		d.bankAccounts()
+	}
+	d.notes("Catatan", inv.Notes)
+
+	return d.doc.Bytes()
+}
+
+// renderReceiptPDF lays out the receipt of one payment against an invoice
+func renderReceiptPDF(tpl InvoiceTemplate, p *billing.Payment, inv *billing.Invoice, c *client.Client) ([]byte, error) {
+	number := receiptNumber(p)
+	d := newBillingDoc("Kwitansi "+number, tpl)
+	d.letterhead("KWITANSI", number)
+
+	bottom := d.billTo("DITERIMA DARI", c)
+	infoX := 340.0
+	y := d.y
+	d.labelValue(infoX, y, 80, "Tanggal", formatDateID(p.ReceivedAt), false)
+	y += docLineHeight
+	d.labelValue(infoX, y, 80, "Metode", paymentMethodLabel(p.Method), false)
+	if ref := utils.Value(p.Reference); ref != "" {
+		y += docLineHeight
+		d.labelValue(infoX, y, 80, "Referensi", pdf.Truncate(ref, 9, pdf.Regular, docRight-infoX-80), false)
+	}
+	d.y = maxF(bottom, y) + 30
+
+	// Amount box
+	d.page.SetColor(0.95, 0.95, 0.95)
+	d.page.FillRect(docMargin, d.y-16, docRight-docMargin, 34)
+	d.page.SetColor(0, 0, 0)
+	d.page.Text(docMargin+10, d.y+5, 10, pdf.Regular, "Jumlah diterima")
+	d.page.TextRight(docRight-10, d.y+6, 16, pdf.Bold, utils.FormatRupiah(p.Amount))
+	d.y += 44
+
+	d.labelValue(docMargin, d.y, 130, "Untuk pembayaran", "Invoice "+inv.InvoiceNumber, true)
+	d.y += docLineHeight
+	d.labelValue(docMargin, d.y, 130, "Periode", formatDateID(inv.PeriodStart)+" - "+formatDateID(inv.PeriodEnd), false)
+	d.y += docLineHeight
+	d.labelValue(docMargin, d.y, 130, "Total tagihan", utils.FormatRupiah(inv.TotalAmount), false)
+	d.y += docLineHeight
+	d.labelValue(docMargin, d.y, 130, "Total dibayar", utils.FormatRupiah(inv.PaidAmount), false)
+	d.y += docLineHeight
+	if rem := inv.RemainingAmount(); rem > 0 {
+		d.labelValue(docMargin, d.y, 130, "Sisa tagihan", utils.FormatRupiah(rem), true)
+		d.y += docLineHeight
+	}
+	d.y += 12
+
+	if inv.Status == billing.InvoiceStatusPaid {
+		d.stamp("LUNAS", 0.1, 0.55, 0.25)
+		d.y += 40
+	}
+	d.notes("Catatan", utils.Value(p.Notes))
+
+	return d.doc.Bytes()
+}
+
+func maxF(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
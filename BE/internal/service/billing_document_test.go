@@ -0,0 +1,120 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/client"
+)
+
+// assertValidPDF checks the header, trailer and that every xref offset points at its object
+func assertValidPDF(t *testing.T, out []byte) {
+	t.Helper()
+	require.True(t, bytes.HasPrefix(out, []byte("%PDF-1.4")))
+	require.True(t, bytes.HasSuffix(out, []byte("%%EOF\n")))
+
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	require.NotNil(t, m)
+	xref, _ := strconv.Atoi(string(m[1]))
+	require.True(t, bytes.HasPrefix(out[xref:], []byte("xref\n0 ")))
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out[xref:], -1)
+	require.NotEmpty(t, entries)
+	for i, e := range entries {
+		off, _ := strconv.Atoi(string(e[1]))
+		assert.True(t, bytes.HasPrefix(out[off:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))), "object %d", i+1)
+	}
+}
+
+func testLogo(t *testing.T) string {
+	return testLogoSized(t, 40, 20)
+}
+
+func testLogoSized(t *testing.T, width, height int) string {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, height/2, color.NRGBA{R: 200, A: 255})
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestRenderInvoicePDF(t *testing.T) {
+	tpl := InvoiceTemplate{
+		CompanyName:  "RR Net (Cabang Utara)",
+		Address:      "Jl. Merdeka No. 1, Bandung",
+		Logo:         testLogo(t),
+		Footer:       "Terima kasih telah berlangganan.",
+		BankAccounts: []InvoiceBankAccount{{BankName: "BCA", AccountNumber: "1234567890", AccountName: "PT RR Net"}},
+	}
+	inv := &billing.Invoice{
+		ID:             uuid.New(),
+		InvoiceNumber:  "INV-202603-0001",
+		PeriodStart:    ymd(2026, 3, 1),
+		PeriodEnd:      ymd(2026, 3, 31),
+		DueDate:        ymd(2026, 3, 10),
+		Subtotal:       150000,
+		DiscountAmount: 15000,
+		TotalAmount:    135000,
+		Status:         billing.InvoiceStatusPending,
+		CreatedAt:      ymd(2026, 3, 1),
+	}
+	// enough lines to need a second page
+	for i := 0; i < 40; i++ {
+		inv.Items = append(inv.Items, billing.InvoiceItem{Description: fmt.Sprintf("Layanan Internet - Paket %d Mbps", i), Quantity: 1, UnitPrice: 1500, Amount: 1500})
+	}
+	addr := "Jl. Sudirman 10"
+	c := &client.Client{Name: "Budi", ClientCode: "CL-001", Address: &addr}
+
+	out, err := renderInvoicePDF(tpl, inv, c)
+	require.NoError(t, err)
+	assertValidPDF(t, out)
+	assert.Equal(t, 2, bytes.Count(out, []byte("/Type /Page /Parent")))
+	assert.Contains(t, string(out), "/Subtype /Image")
+}
+
+func TestRenderReceiptPDF(t *testing.T) {
+	ref := "TRX-(001)"
+	p := &billing.Payment{ID: uuid.New(), Amount: 135000, Method: billing.PaymentMethodBankTransfer, Reference: &ref, ReceivedAt: time.Date(2026, 3, 5, 9, 0, 0, 0, time.Local)}
+	inv := &billing.Invoice{InvoiceNumber: "INV-202603-0001", PeriodStart: ymd(2026, 3, 1), PeriodEnd: ymd(2026, 3, 31), TotalAmount: 135000, PaidAmount: 135000, Status: billing.InvoiceStatusPaid}
+
+	out, err := renderReceiptPDF(InvoiceTemplate{CompanyName: "RR Net"}, p, inv, &client.Client{Name: "Budi"})
+	require.NoError(t, err)
+	assertValidPDF(t, out)
+	assert.Regexp(t, `^KW-202603-[0-9A-F]{8}$`, receiptNumber(p))
+}
+
+func TestFormatDateID(t *testing.T) {
+	assert.Equal(t, "5 Maret 2026", formatDateID(ymd(2026, 3, 5)))
+	assert.Equal(t, "INV-2026-03-0001.pdf", documentFileName("INV/2026/03/0001"))
+}
+
+func TestUpdateInvoiceTemplateLogoSize(t *testing.T) {
+	s := &BillingService{}
+	// A few KB of PNG that would decode into 4097x2 pixels is refused before decoding
+	_, err := s.UpdateInvoiceTemplate(context.Background(), uuid.New(), InvoiceTemplate{Logo: testLogoSized(t, 4097, 2)})
+	assert.ErrorIs(t, err, ErrInvoiceLogoDimensions)
+
+	_, err = s.UpdateInvoiceTemplate(context.Background(), uuid.New(), InvoiceTemplate{Logo: "data:image/png;base64,AAAA"})
+	assert.ErrorIs(t, err, ErrInvoiceLogoInvalid)
+
+	// Rendering skips a stored logo that is too large instead of failing
+	tpl := InvoiceTemplate{CompanyName: "RR Net", Logo: testLogoSized(t, 2, 4097)}
+	assert.Nil(t, newBillingDoc("Invoice", tpl).logo)
+	assert.NotNil(t, newBillingDoc("Invoice", InvoiceTemplate{Logo: testLogo(t)}).logo)
+}
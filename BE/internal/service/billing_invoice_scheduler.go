@@ -2,18 +2,27 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 
+	"rrnet/internal/domain/billing"
 	"rrnet/internal/domain/client"
+	"rrnet/internal/domain/job"
+	"rrnet/internal/domain/service_package"
 	"rrnet/internal/domain/tenant"
 	"rrnet/internal/repository"
 )
 
-// InvoiceScheduler handles automatic invoice generation on a schedule
+// prepaidRenewalLeadDays is how many days before a prepaid client's validity ends the renewal
+// invoice is issued
+const prepaidRenewalLeadDays = 3
+
+// InvoiceScheduler generates the monthly invoices of clients due tomorrow and the renewal invoices
+// of prepaid clients about to expire; it runs as the JobInvoiceGeneration scheduled job
 type InvoiceScheduler struct {
-	tenantRepo     *repository.TenantRepository
 	clientRepo     *repository.ClientRepository
 	invoiceRepo    *repository.InvoiceRepository
 	billingService *BillingService
@@ -21,143 +30,133 @@ type InvoiceScheduler struct {
 
 // NewInvoiceScheduler creates a new invoice scheduler
 func NewInvoiceScheduler(
-	tenantRepo *repository.TenantRepository,
 	clientRepo *repository.ClientRepository,
 	invoiceRepo *repository.InvoiceRepository,
 	billingService *BillingService,
 ) *InvoiceScheduler {
 	return &InvoiceScheduler{
-		tenantRepo:     tenantRepo,
 		clientRepo:     clientRepo,
 		invoiceRepo:    invoiceRepo,
 		billingService: billingService,
 	}
 }
 
-// StartDailyScheduler starts a goroutine that runs the invoice generation job daily at 00:05 local time
-func (s *InvoiceScheduler) StartDailyScheduler(ctx context.Context) {
-	go func() {
-		// Run once on startup (helps recovery if server was down at scheduled time).
-		s.runScheduledJob(ctx)
-
-		for {
-			now := time.Now()
-			nextRun := time.Date(now.Year(), now.Month(), now.Day(), 0, 5, 0, 0, time.Local)
-			if !nextRun.After(now) {
-				nextRun = nextRun.Add(24 * time.Hour)
-			}
-
-			timer := time.NewTimer(time.Until(nextRun))
-			select {
-			case <-ctx.Done():
-				timer.Stop()
-				log.Info().Msg("Invoice scheduler stopped")
-				return
-			case <-timer.C:
-				s.runScheduledJob(ctx)
-			}
-		}
-	}()
-	log.Info().Msg("Invoice scheduler started (runs daily at 00:05 local time)")
+// Runner returns the executor of the invoice generation job (JobInvoiceGeneration)
+func (s *InvoiceScheduler) Runner() JobRunner {
+	return JobRunner{RunTenant: s.RunForTenant}
 }
 
-// runScheduledJob executes the invoice generation for all tenants/clients
-func (s *InvoiceScheduler) runScheduledJob(ctx context.Context) {
-	log.Info().Msg("Starting scheduled invoice generation job")
-
-	// Get all active tenants
-	tenants, err := s.tenantRepo.ListAll(ctx)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to list tenants for invoice generation")
-		return
+// RunForTenant generates the invoices of a tenant's active postpaid clients due the day after now
+// whose billing cycle starts in tomorrow's month, and renews prepaid clients whose validity ends within prepaidRenewalLeadDays. Clients that already
+// have an invoice for the period (or an unpaid renewal) are skipped, so a retried run is safe; it
+// fails when any client failed so the retry picks those up.
+func (s *InvoiceScheduler) RunForTenant(ctx context.Context, t *tenant.Tenant, now time.Time) (job.Stats, error) {
+	stats := job.Stats{"clients_scanned": 0, "invoices_created": 0, "invoices_skipped": 0, "prepaid_renewals": 0, "errors": 0}
+	if t.Status != tenant.StatusActive {
+		return stats, nil
 	}
 
-	totalScanned := 0
-	totalCreated := 0
-	totalSkipped := 0
-	totalErrors := 0
-
-	now := time.Now()
 	tomorrow := now.AddDate(0, 0, 1)
-	periodStart := time.Date(tomorrow.Year(), tomorrow.Month(), 1, 0, 0, 0, 0, time.Local)
-	periodEnd := periodStart.AddDate(0, 1, -1)
 
-	for _, t := range tenants {
-		// Skip non-active tenants
-		if t.Status != tenant.StatusActive {
-			continue
+	// Billing mode and package per package ID, resolved once per run
+	packageModes := make(map[uuid.UUID]billing.BillingMode)
+	packages := make(map[uuid.UUID]*service_package.ServicePackage)
+
+	// Get active clients for this tenant
+	activeStatus := client.StatusActive
+	page := 1
+	pageSize := 100
+	for {
+		clients, total, err := s.clientRepo.List(ctx, t.ID, &client.ClientListFilter{
+			Status:   &activeStatus,
+			Page:     page,
+			PageSize: pageSize,
+		})
+		if err != nil {
+			return stats, fmt.Errorf("failed to list clients: %w", err)
+		}
+		if len(clients) == 0 {
+			break
 		}
 
-		// Get active clients for this tenant
-		activeStatus := client.StatusActive
-		page := 1
-		pageSize := 100
-		for {
-			clients, total, err := s.clientRepo.List(ctx, t.ID, &client.ClientListFilter{
-				Status:   &activeStatus,
-				Page:     page,
-				PageSize: pageSize,
-			})
+		for _, c := range clients {
+			stats["clients_scanned"]++
+			if s.billingMode(ctx, t.ID, c, packageModes) == billing.BillingModePrepaid {
+				continue // renewed below
+			}
+			if !s.isDueTomorrow(tomorrow, c) {
+				continue
+			}
+			// Multi-month cycles are only billed in the first month of the cycle
+			cycle, err := s.clientCycle(ctx, t.ID, c, packages)
 			if err != nil {
-				log.Error().Err(err).Str("tenant_id", t.ID.String()).Msg("Failed to list clients for invoice generation")
-				totalErrors++
-				break
+				log.Error().
+					Err(err).
+					Str("tenant_id", t.ID.String()).
+					Str("client_id", c.ID.String()).
+					Str("client_code", c.ClientCode).
+					Msg("Failed to resolve billing cycle")
+				stats["errors"]++
+				continue
 			}
-			if len(clients) == 0 {
-				break
+			periodStart, periodEnd := cycle.periodOf(tomorrow)
+			if periodStart.Month() != tomorrow.Month() || periodStart.Year() != tomorrow.Year() {
+				continue
 			}
 
-			for _, c := range clients {
-				totalScanned++
-				if !s.isDueTomorrow(tomorrow, c) {
-					continue
-				}
-
-				// Skip if invoice already exists for this upcoming period
-				exists, err := s.invoiceRepo.ExistsForClientPeriod(ctx, t.ID, c.ID, periodStart, periodEnd)
-				if err != nil {
-					log.Error().
-						Err(err).
-						Str("tenant_id", t.ID.String()).
-						Str("client_id", c.ID.String()).
-						Str("client_code", c.ClientCode).
-						Msg("Failed to check existing invoice")
-					totalErrors++
-					continue
-				}
-				if exists {
-					totalSkipped++
-					continue
-				}
-
-				_, err = s.billingService.GenerateMonthlyInvoice(ctx, t.ID, c.ID)
-				if err != nil {
-					log.Error().
-						Err(err).
-						Str("tenant_id", t.ID.String()).
-						Str("client_id", c.ID.String()).
-						Str("client_code", c.ClientCode).
-						Msg("Failed to generate invoice for client")
-					totalErrors++
-				} else {
-					totalCreated++
-				}
+			// Skip if invoice already exists for this upcoming period
+			exists, err := s.invoiceRepo.ExistsForClientPeriod(ctx, t.ID, c.ID, periodStart, periodEnd)
+			if err != nil {
+				log.Error().
+					Err(err).
+					Str("tenant_id", t.ID.String()).
+					Str("client_id", c.ID.String()).
+					Str("client_code", c.ClientCode).
+					Msg("Failed to check existing invoice")
+				stats["errors"]++
+				continue
+			}
+			if exists {
+				stats["invoices_skipped"]++
+				continue
 			}
 
-			if page*pageSize >= total {
-				break
+			// A concurrent run creating the same invoice hits the unique index and gets that invoice back
+			_, err = s.billingService.GenerateMonthlyInvoice(ctx, t.ID, c.ID)
+			if err != nil {
+				log.Error().
+					Err(err).
+					Str("tenant_id", t.ID.String()).
+					Str("client_id", c.ID.String()).
+					Str("client_code", c.ClientCode).
+					Msg("Failed to generate invoice for client")
+				stats["errors"]++
+			} else {
+				stats["invoices_created"]++
 			}
-			page++
 		}
+
+		if page*pageSize >= total {
+			break
+		}
+		page++
 	}
 
+	s.renewPrepaid(ctx, t, now, stats)
+
 	log.Info().
-		Int("tenants_processed", len(tenants)).
-		Int("clients_scanned", totalScanned).
-		Int("invoices_created", totalCreated).
-		Int("invoices_skipped", totalSkipped).
-		Int("errors", totalErrors).
-		Msg("Scheduled invoice generation job completed")
+		Str("tenant_id", t.ID.String()).
+		Int64("clients_scanned", stats["clients_scanned"]).
+		Int64("invoices_created", stats["invoices_created"]).
+		Int64("invoices_skipped", stats["invoices_skipped"]).
+		Int64("prepaid_renewals", stats["prepaid_renewals"]).
+		Int64("errors", stats["errors"]).
+		Msg("Invoice generation completed for tenant")
+
+	if stats["errors"] > 0 {
+		return stats, fmt.Errorf("%d clients failed", stats["errors"])
+	}
+	return stats, nil
 }
 
 // isDueTomorrow checks if tomorrow matches the client's due day (after clamping for end-of-month)
@@ -178,3 +177,66 @@ func (s *InvoiceScheduler) isDueTomorrow(tomorrow time.Time, c *client.Client) b
 	return tomorrow.Day() == clampedDay
 }
 
+// billingMode resolves a client's billing mode, caching the mode of each package in modes
+func (s *InvoiceScheduler) billingMode(ctx context.Context, tenantID uuid.UUID, c *client.Client, modes map[uuid.UUID]billing.BillingMode) billing.BillingMode {
+	if c.BillingMode != nil || c.ServicePackageID == nil {
+		return billing.ResolveBillingMode(c.BillingMode, "")
+	}
+	mode, ok := modes[*c.ServicePackageID]
+	if !ok {
+		mode = s.billingService.ClientBillingMode(ctx, tenantID, c)
+		modes[*c.ServicePackageID] = mode
+	}
+	return mode
+}
+
+// clientCycle resolves a client's billing cycle, caching the service packages in packages
+func (s *InvoiceScheduler) clientCycle(ctx context.Context, tenantID uuid.UUID, c *client.Client, packages map[uuid.UUID]*service_package.ServicePackage) (billingCycle, error) {
+	if c.ServicePackageID == nil {
+		return clientCycle(c, nil), nil
+	}
+	pkg, ok := packages[*c.ServicePackageID]
+	if !ok {
+		var err error
+		if pkg, err = s.billingService.clientPackage(ctx, tenantID, c); err != nil {
+			return billingCycle{}, err
+		}
+		packages[*c.ServicePackageID] = pkg
+	}
+	return clientCycle(c, pkg), nil
+}
+
+// renewPrepaid issues the next period's invoice to prepaid clients whose validity ends within
+// prepaidRenewalLeadDays and that have no unpaid renewal yet
+func (s *InvoiceScheduler) renewPrepaid(ctx context.Context, t *tenant.Tenant, now time.Time, stats job.Stats) {
+	until := time.Date(now.Year(), now.Month(), now.Day()+prepaidRenewalLeadDays, 0, 0, 0, 0, time.Local)
+	clients, err := s.clientRepo.ListPrepaidActiveUntil(ctx, t.ID, until)
+	if err != nil {
+		log.Error().Err(err).Str("tenant_id", t.ID.String()).Msg("Failed to list prepaid clients due for renewal")
+		stats["errors"]++
+		return
+	}
+	for _, c := range clients {
+		open, err := s.invoiceRepo.GetOpenPrepaid(ctx, t.ID, c.ID)
+		if err != nil {
+			log.Error().Err(err).Str("client_id", c.ID.String()).Msg("Failed to check open prepaid invoice")
+			stats["errors"]++
+			continue
+		}
+		if open != nil {
+			stats["invoices_skipped"]++
+			continue
+		}
+		if _, err := s.billingService.GenerateMonthlyInvoice(ctx, t.ID, c.ID); err != nil {
+			log.Error().
+				Err(err).
+				Str("tenant_id", t.ID.String()).
+				Str("client_id", c.ID.String()).
+				Str("client_code", c.ClientCode).
+				Msg("Failed to renew prepaid client")
+			stats["errors"]++
+			continue
+		}
+		stats["prepaid_renewals"]++
+	}
+}
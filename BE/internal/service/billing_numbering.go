@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/domain/billing"
+)
+
+var ErrNumberingKindInvalid = errors.New("kind must be invoice, credit_note or receipt")
+
+// numberingPreviewCount is how many upcoming numbers a preview shows
+const numberingPreviewCount = 3
+
+// NumberingSettings holds the document number schemes of a tenant (stored in tenant settings under
+// "numbering"). Branches maps a client group to the code printed for {BRANCH}; clients outside
+// those groups use DefaultBranch.
+type NumberingSettings struct {
+	Invoice       billing.NumberingScheme `json:"invoice"`
+	CreditNote    billing.NumberingScheme `json:"credit_note"`
+	Receipt       billing.NumberingScheme `json:"receipt"`
+	DefaultBranch string                  `json:"default_branch"`
+	Branches      map[uuid.UUID]string    `json:"branches"`
+}
+
+// Scheme returns the scheme of one document kind
+func (n NumberingSettings) Scheme(kind billing.DocumentKind) billing.NumberingScheme {
+	switch kind {
+	case billing.DocumentCreditNote:
+		return n.CreditNote
+	case billing.DocumentReceipt:
+		return n.Receipt
+	}
+	return n.Invoice
+}
+
+// NumberingPreviewRequest previews a scheme (the saved one when Scheme is empty) for a branch
+type NumberingPreviewRequest struct {
+	Kind   billing.DocumentKind     `json:"kind"`
+	Scheme *billing.NumberingScheme `json:"scheme,omitempty"`
+	Branch string                   `json:"branch,omitempty"`
+	Date   *time.Time               `json:"date,omitempty"`
+}
+
+// NumberingPreview lists the numbers the next documents would get
+type NumberingPreview struct {
+	Kind    billing.DocumentKind    `json:"kind"`
+	Scheme  billing.NumberingScheme `json:"scheme"`
+	Numbers []string                `json:"numbers"`
+}
+
+func readNumberingScheme(raw interface{}, kind billing.DocumentKind) billing.NumberingScheme {
+	out := billing.DefaultNumberingScheme(kind)
+	m, ok := raw.(map[string]interface{})
+	if !ok || m == nil {
+		return out
+	}
+	in := out
+	if v, ok := m["pattern"].(string); ok {
+		in.Pattern = v
+	}
+	if v, ok := m["reset"].(string); ok {
+		in.Reset = billing.NumberingReset(v)
+	}
+	if in.Validate() != nil {
+		return out
+	}
+	return in
+}
+
+func readNumberingSettings(settings map[string]interface{}) NumberingSettings {
+	out := NumberingSettings{Branches: map[uuid.UUID]string{}}
+	raw, _ := settings["numbering"].(map[string]interface{})
+	out.Invoice = readNumberingScheme(raw["invoice"], billing.DocumentInvoice)
+	out.CreditNote = readNumberingScheme(raw["credit_note"], billing.DocumentCreditNote)
+	out.Receipt = readNumberingScheme(raw["receipt"], billing.DocumentReceipt)
+	if v, ok := raw["default_branch"].(string); ok {
+		out.DefaultBranch = v
+	}
+	if branches, ok := raw["branches"].(map[string]interface{}); ok {
+		for k, v := range branches {
+			id, err := uuid.Parse(k)
+			code, _ := v.(string)
+			if err == nil && code != "" {
+				out.Branches[id] = code
+			}
+		}
+	}
+	return out
+}
+
+func (s *BillingService) GetNumberingSettings(ctx context.Context, tenantID uuid.UUID) (*NumberingSettings, error) {
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	out := readNumberingSettings(t.Settings)
+	return &out, nil
+}
+
+// UpdateNumberingSettings replaces the number schemes. Sequences are kept: numbers of the new scheme
+// continue from the counter of their reset period, skipping numbers that are already taken.
+func (s *BillingService) UpdateNumberingSettings(ctx context.Context, tenantID uuid.UUID, in NumberingSettings) (*NumberingSettings, error) {
+	schemes := map[string]billing.NumberingScheme{}
+	for _, kind := range []billing.DocumentKind{billing.DocumentInvoice, billing.DocumentCreditNote, billing.DocumentReceipt} {
+		sc := in.Scheme(kind)
+		sc.Pattern = strings.TrimSpace(sc.Pattern)
+		if sc.Pattern == "" {
+			sc = billing.DefaultNumberingScheme(kind)
+		}
+		if err := sc.Validate(); err != nil {
+			return nil, err
+		}
+		schemes[string(kind)] = sc
+	}
+	in.DefaultBranch = strings.ToUpper(strings.TrimSpace(in.DefaultBranch))
+	if err := billing.ValidateBranchCode(in.DefaultBranch); err != nil {
+		return nil, err
+	}
+	branches := map[string]interface{}{}
+	for groupID, code := range in.Branches {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if err := billing.ValidateBranchCode(code); err != nil {
+			return nil, err
+		}
+		if groupID != uuid.Nil && code != "" {
+			branches[groupID.String()] = code
+		}
+	}
+
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if t.Settings == nil {
+		t.Settings = map[string]interface{}{}
+	}
+	stored := map[string]interface{}{
+		"default_branch": in.DefaultBranch,
+		"branches":       branches,
+	}
+	for kind, sc := range schemes {
+		stored[kind] = map[string]interface{}{"pattern": sc.Pattern, "reset": string(sc.Reset)}
+	}
+	t.Settings["numbering"] = stored
+	if err := s.tenantRepo.UpdateSettings(ctx, tenantID, t.Settings); err != nil {
+		return nil, err
+	}
+	out := readNumberingSettings(t.Settings)
+	return &out, nil
+}
+
+// PreviewNumbering returns the numbers the next documents of a kind would get, without drawing them
+func (s *BillingService) PreviewNumbering(ctx context.Context, tenantID uuid.UUID, req NumberingPreviewRequest) (*NumberingPreview, error) {
+	switch req.Kind {
+	case billing.DocumentInvoice, billing.DocumentCreditNote, billing.DocumentReceipt:
+	default:
+		return nil, ErrNumberingKindInvalid
+	}
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	settings := readNumberingSettings(t.Settings)
+	scheme := settings.Scheme(req.Kind)
+	if req.Scheme != nil {
+		scheme = *req.Scheme
+		scheme.Pattern = strings.TrimSpace(scheme.Pattern)
+		if err := scheme.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	branch := strings.ToUpper(strings.TrimSpace(req.Branch))
+	if branch == "" {
+		branch = settings.DefaultBranch
+	}
+	if err := billing.ValidateBranchCode(branch); err != nil {
+		return nil, err
+	}
+	at := time.Now()
+	if req.Date != nil {
+		at = *req.Date
+	}
+
+	var last int64
+	if s.sequenceRepo != nil {
+		if last, err = s.sequenceRepo.LastValue(ctx, tenantID, req.Kind, scheme.Scope(at, branch)); err != nil {
+			return nil, err
+		}
+	}
+	out := &NumberingPreview{Kind: req.Kind, Scheme: scheme}
+	for i := int64(1); i <= numberingPreviewCount; i++ {
+		out.Numbers = append(out.Numbers, scheme.Format(at, branch, last+i))
+	}
+	return out, nil
+}
+
+// documentNumbering returns how to number a new document of a client. Without tenant settings the
+// default scheme applies.
+func (s *BillingService) documentNumbering(ctx context.Context, tenantID uuid.UUID, kind billing.DocumentKind, clientID uuid.UUID, at time.Time) *billing.DocumentNumbering {
+	settings := readNumberingSettings(nil)
+	if s.tenantRepo != nil {
+		t, err := s.tenantRepo.GetByID(ctx, tenantID)
+		if err != nil {
+			log.Warn().Err(err).Str("tenant_id", tenantID.String()).Msg("Failed to load numbering settings, using the default scheme")
+		} else {
+			settings = readNumberingSettings(t.Settings)
+		}
+	}
+	n := &billing.DocumentNumbering{Kind: kind, Scheme: settings.Scheme(kind), Branch: settings.DefaultBranch, Date: at}
+	if len(settings.Branches) > 0 && s.clientRepo != nil && strings.Contains(n.Scheme.Pattern, "{BRANCH}") {
+		if c, err := s.clientRepo.GetByID(ctx, tenantID, clientID); err == nil && c.GroupID != nil {
+			if code, ok := settings.Branches[*c.GroupID]; ok {
+				n.Branch = code
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"rrnet/internal/domain/billing"
+)
+
+func TestNumberingSchemeValidate(t *testing.T) {
+	cases := []struct {
+		pattern string
+		reset   billing.NumberingReset
+		err     error
+	}{
+		{"INV/{YYYY}/{MM}/{SEQ:5}", billing.NumberingResetMonthly, nil},
+		{"{BRANCH}-{YY}{SEQ:6}", billing.NumberingResetYearly, nil},
+		{"F{SEQ}", billing.NumberingResetNever, nil},
+		{"", billing.NumberingResetNever, billing.ErrNumberPatternEmpty},
+		{"INV-{YYYY}", billing.NumberingResetYearly, billing.ErrNumberPatternSeq},
+		{"{SEQ}-{SEQ:3}", billing.NumberingResetNever, billing.ErrNumberPatternSeq},
+		{"INV-{SEQ:11}", billing.NumberingResetNever, billing.ErrNumberPatternSeq},
+		{"INV-{MONTH}-{SEQ}", billing.NumberingResetNever, billing.ErrNumberPatternToken},
+		{"INV #{SEQ}", billing.NumberingResetNever, billing.ErrNumberPatternChars},
+		{"INV-{YYYY}-{SEQ}", billing.NumberingResetMonthly, billing.ErrNumberPatternResetDate},
+		{"INV-{SEQ}", "daily", billing.ErrNumberPatternReset},
+		{"INVOICE-PELANGGAN-RUMAHAN-{BRANCH}-{YYYY}-{SEQ:10}", billing.NumberingResetYearly, billing.ErrNumberPatternLength},
+	}
+	for _, c := range cases {
+		t.Run(c.pattern, func(t *testing.T) {
+			err := billing.NumberingScheme{Pattern: c.pattern, Reset: c.reset}.Validate()
+			assert.Equal(t, c.err, err)
+		})
+	}
+}
+
+func TestNumberingSchemeFormat(t *testing.T) {
+	at := time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)
+	s := billing.NumberingScheme{Pattern: "INV/{BRANCH}/{YYYY}/{MM}/{SEQ:5}", Reset: billing.NumberingResetYearly}
+	assert.Equal(t, "INV/JKT/2026/03/00042", s.Format(at, "jkt", 42))
+	assert.Equal(t, "2026|JKT", s.Scope(at, "jkt"))
+
+	// The default scheme keeps the historic format
+	def := billing.DefaultNumberingScheme(billing.DocumentInvoice)
+	assert.Equal(t, "INV-202603-0007", def.Format(at, "", 7))
+	assert.Equal(t, "2026-03", def.Scope(at, "JKT"))
+	assert.Equal(t, "F-123456", billing.NumberingScheme{Pattern: "F-{SEQ}", Reset: billing.NumberingResetNever}.Format(at, "", 123456))
+}
+
+func TestReadNumberingSettings(t *testing.T) {
+	group := uuid.New()
+	got := readNumberingSettings(map[string]interface{}{
+		"numbering": map[string]interface{}{
+			"invoice":        map[string]interface{}{"pattern": "INV/{YYYY}/{SEQ:5}", "reset": "yearly"},
+			"credit_note":    map[string]interface{}{"pattern": "broken", "reset": "yearly"},
+			"default_branch": "PST",
+			"branches":       map[string]interface{}{group.String(): "JKT", "not-a-uuid": "BDG"},
+		},
+	})
+	assert.Equal(t, billing.NumberingScheme{Pattern: "INV/{YYYY}/{SEQ:5}", Reset: billing.NumberingResetYearly}, got.Invoice)
+	// Invalid stored schemes fall back to the default
+	assert.Equal(t, billing.DefaultNumberingScheme(billing.DocumentCreditNote), got.CreditNote)
+	assert.Equal(t, billing.DefaultNumberingScheme(billing.DocumentReceipt), got.Receipt)
+	assert.Equal(t, "PST", got.DefaultBranch)
+	assert.Equal(t, map[uuid.UUID]string{group: "JKT"}, got.Branches)
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/client"
+	"rrnet/internal/repository"
+)
+
+var (
+	ErrBillingModeInvalid    = errors.New("billing mode must be postpaid or prepaid")
+	ErrClientNotPrepaid      = errors.New("client is not on prepaid billing")
+	ErrPrepaidPeriodsInvalid = errors.New("prepaid periods must be between 1 and 12")
+)
+
+// maxPrepaidPeriods bounds how many months a client can pay in advance at once
+const maxPrepaidPeriods = 12
+
+// ClientBillingMode returns the effective billing mode of a client: its own override, else the mode
+// of its service package
+func (s *BillingService) ClientBillingMode(ctx context.Context, tenantID uuid.UUID, c *client.Client) billing.BillingMode {
+	var packageMode billing.BillingMode
+	if c.BillingMode == nil && c.ServicePackageID != nil && s.servicePackageRepo != nil {
+		pkg, err := s.servicePackageRepo.GetByID(ctx, tenantID, *c.ServicePackageID)
+		if err != nil {
+			log.Warn().Err(err).Str("client_id", c.ID.String()).Msg("Failed to load service package, assuming postpaid")
+		} else {
+			packageMode = pkg.BillingMode
+		}
+	}
+	return billing.ResolveBillingMode(c.BillingMode, packageMode)
+}
+
+// CreatePrepaidInvoice bills periods months of service for a prepaid client in advance. The invoice
+// covers the validity it buys: from the day after the current validity ends (or today when it
+// lapsed) for periods months, and is due when that period starts. Paying it in full extends the
+// client's validity to the end of the period.
+func (s *BillingService) CreatePrepaidInvoice(ctx context.Context, tenantID, clientID uuid.UUID, periods int) (*billing.Invoice, error) {
+	if periods < 1 || periods > maxPrepaidPeriods {
+		return nil, ErrPrepaidPeriodsInvalid
+	}
+	c, err := s.clientRepo.GetByID(ctx, tenantID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if s.ClientBillingMode(ctx, tenantID, c) != billing.BillingModePrepaid {
+		return nil, ErrClientNotPrepaid
+	}
+	if open, err := s.invoiceRepo.GetOpenPrepaid(ctx, tenantID, clientID); err != nil {
+		return nil, err
+	} else if open != nil {
+		return nil, repository.ErrPrepaidInvoiceOpen
+	}
+	return s.createPrepaidInvoice(ctx, tenantID, c, periods, time.Now())
+}
+
+// renewPrepaid returns the client's unpaid prepaid invoice, or issues one for the next period
+func (s *BillingService) renewPrepaid(ctx context.Context, tenantID uuid.UUID, c *client.Client) (*billing.Invoice, error) {
+	open, err := s.invoiceRepo.GetOpenPrepaid(ctx, tenantID, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	if open != nil {
+		return open, nil
+	}
+	invoice, err := s.createPrepaidInvoice(ctx, tenantID, c, 1, time.Now())
+	if errors.Is(err, repository.ErrPrepaidInvoiceOpen) {
+		// Issued concurrently (another replica or a retried job); return that invoice
+		if open, getErr := s.invoiceRepo.GetOpenPrepaid(ctx, tenantID, c.ID); getErr == nil && open != nil {
+			return open, nil
+		}
+	}
+	return invoice, err
+}
+
+func (s *BillingService) createPrepaidInvoice(ctx context.Context, tenantID uuid.UUID, c *client.Client, periods int, now time.Time) (*billing.Invoice, error) {
+	unitPrice, itemDesc, err := s.clientMonthlyPrice(ctx, tenantID, c)
+	if err != nil {
+		return nil, err
+	}
+	start, end := billing.PrepaidWindow(c.ActiveUntil, now, periods)
+
+	req := CreateInvoiceRequest{
+		ClientID:    c.ID,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		DueDate:     time.Date(start.Year(), start.Month(), start.Day(), 23, 59, 59, 0, time.Local),
+		Source:      billing.InvoiceSourcePrepaid,
+		Items: []InvoiceItemRequest{{
+			Description: fmt.Sprintf("%s (prabayar %s - %s)", itemDesc, start.Format("02-01-2006"), end.Format("02-01-2006")),
+			Quantity:    periods,
+			UnitPrice:   unitPrice,
+		}},
+	}
+
+	// Recurring extras, month by month so charges starting or ending inside the period are prorated
+	for i := 0; i < periods; i++ {
+		from := start.AddDate(0, i, 0)
+		req.Items = mergeItems(req.Items, s.recurringChargeItems(ctx, tenantID, c.ID, from, start.AddDate(0, i+1, -1)))
+	}
+
+	for _, d := range s.resolveDiscountLines(ctx, tenantID, c, unitPrice) {
+		req.Items = append(req.Items, InvoiceItemRequest{
+			Description: d.Description,
+			Quantity:    periods,
+			UnitPrice:   -d.Amount,
+			DiscountID:  d.DiscountID,
+			IsDiscount:  true,
+		})
+	}
+
+	adjItems, adjIDs := s.pendingAdjustmentItems(ctx, tenantID, c.ID)
+	req.Items = append(req.Items, adjItems...)
+
+	invoice, err := s.CreateInvoice(ctx, tenantID, req)
+	if err != nil {
+		return nil, err
+	}
+	s.applyClientCredit(ctx, tenantID, invoice)
+	if len(adjIDs) > 0 {
+		if err := s.adjustmentRepo.MarkApplied(ctx, adjIDs, invoice.ID, invoice.CreatedAt); err != nil {
+			log.Error().Err(err).Str("invoice_id", invoice.ID.String()).Msg("Failed to mark billing adjustments as applied")
+		}
+	}
+	return invoice, nil
+}
+
+// mergeItems appends items, adding the quantity of lines already present with the same description
+// and unit price
+func mergeItems(items, more []InvoiceItemRequest) []InvoiceItemRequest {
+	for _, m := range more {
+		merged := false
+		for i := range items {
+			if items[i].Description == m.Description && items[i].UnitPrice == m.UnitPrice && items[i].DiscountID == nil {
+				items[i].Quantity += m.Quantity
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			items = append(items, m)
+		}
+	}
+	return items
+}
+
+// SetClientActiveUntil sets the validity of a prepaid client by hand, e.g. when moving an existing
+// client to prepaid billing. A date in the past lets the expiry job isolate the client.
+func (s *BillingService) SetClientActiveUntil(ctx context.Context, tenantID, clientID uuid.UUID, activeUntil *time.Time) (*client.Client, error) {
+	c, err := s.clientRepo.GetByID(ctx, tenantID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if s.ClientBillingMode(ctx, tenantID, c) != billing.BillingModePrepaid {
+		return nil, ErrClientNotPrepaid
+	}
+	if activeUntil != nil {
+		d := time.Date(activeUntil.Year(), activeUntil.Month(), activeUntil.Day(), 0, 0, 0, 0, time.Local)
+		activeUntil = &d
+	}
+	if err := s.clientRepo.SetActiveUntil(ctx, tenantID, clientID, activeUntil); err != nil {
+		return nil, err
+	}
+	c.ActiveUntil = activeUntil
+	return c, nil
+}
+
+// applyPrepaidPayment extends the client's validity by the prepaid invoices among paid, and reports
+// whether the client may be reactivated: a prepaid client only while its validity covers today
+func (s *BillingService) applyPrepaidPayment(ctx context.Context, tenantID, clientID uuid.UUID, paid ...*billing.Invoice) bool {
+	if s.clientRepo == nil {
+		return true
+	}
+	for _, inv := range paid {
+		if inv == nil || inv.Source != billing.InvoiceSourcePrepaid {
+			continue
+		}
+		if err := s.clientRepo.ExtendActiveUntil(ctx, tenantID, clientID, inv.PeriodEnd); err != nil {
+			log.Error().Err(err).Str("invoice_id", inv.ID.String()).Msg("Failed to extend prepaid validity")
+		}
+	}
+	c, err := s.clientRepo.GetByID(ctx, tenantID, clientID)
+	if err != nil {
+		return true
+	}
+	if s.ClientBillingMode(ctx, tenantID, c) != billing.BillingModePrepaid {
+		return true
+	}
+	return !billing.PrepaidExpired(c.ActiveUntil, time.Now())
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"rrnet/internal/domain/billing"
+)
+
+func TestPrepaidWindow(t *testing.T) {
+	today := ymd(2025, time.March, 10).Add(14 * time.Hour)
+
+	// Never activated or lapsed: validity starts today, lapsed days are not billed
+	start, end := billing.PrepaidWindow(nil, today, 1)
+	assert.Equal(t, ymd(2025, time.March, 10), start)
+	assert.Equal(t, ymd(2025, time.April, 9), end)
+
+	lapsed := ymd(2025, time.March, 1)
+	start, _ = billing.PrepaidWindow(&lapsed, today, 1)
+	assert.Equal(t, ymd(2025, time.March, 10), start)
+
+	// Still covered (or expiring yesterday): continues the day after, N months long
+	until := ymd(2025, time.March, 14)
+	start, end = billing.PrepaidWindow(&until, today, 3)
+	assert.Equal(t, ymd(2025, time.March, 15), start)
+	assert.Equal(t, ymd(2025, time.June, 14), end)
+
+	yesterday := ymd(2025, time.March, 9)
+	start, _ = billing.PrepaidWindow(&yesterday, today, 1)
+	assert.Equal(t, ymd(2025, time.March, 10), start)
+}
+
+func TestPrepaidExpired(t *testing.T) {
+	today := ymd(2025, time.March, 10).Add(23 * time.Hour)
+	until := ymd(2025, time.March, 10)
+	assert.False(t, billing.PrepaidExpired(&until, today))
+	until = ymd(2025, time.March, 9)
+	assert.True(t, billing.PrepaidExpired(&until, today))
+	assert.False(t, billing.PrepaidExpired(nil, today))
+}
+
+func TestResolveBillingMode(t *testing.T) {
+	prepaid := billing.BillingModePrepaid
+	postpaid := billing.BillingModePostpaid
+	assert.Equal(t, billing.BillingModePrepaid, billing.ResolveBillingMode(nil, billing.BillingModePrepaid))
+	assert.Equal(t, billing.BillingModePostpaid, billing.ResolveBillingMode(&postpaid, billing.BillingModePrepaid))
+	assert.Equal(t, billing.BillingModePrepaid, billing.ResolveBillingMode(&prepaid, ""))
+	assert.Equal(t, billing.BillingModePostpaid, billing.ResolveBillingMode(nil, ""))
+}
+
+func TestMergeItems(t *testing.T) {
+	items := []InvoiceItemRequest{{Description: "Paket", Quantity: 3, UnitPrice: 150000}}
+	items = mergeItems(items, []InvoiceItemRequest{{Description: "IP Publik", Quantity: 1, UnitPrice: 50000}})
+	items = mergeItems(items, []InvoiceItemRequest{{Description: "IP Publik", Quantity: 1, UnitPrice: 50000}})
+	items = mergeItems(items, []InvoiceItemRequest{{Description: "IP Publik (prorata 10/30 hari)", Quantity: 1, UnitPrice: 16667}})
+	assert.Len(t, items, 3)
+	assert.Equal(t, 2, items[1].Quantity)
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/client"
+)
+
+// prorationLine is a prorated invoice line (negative Amount = credit)
+type prorationLine struct {
+	Description string
+	Amount      int64
+}
+
+// prorate returns the share of amount for days out of periodDays, rounded to the nearest rupiah
+func prorate(amount int64, days, periodDays int) int64 {
+	if days <= 0 || periodDays <= 0 {
+		return 0
+	}
+	if days >= periodDays {
+		return amount
+	}
+	return int64(math.Round(float64(amount) * float64(days) / float64(periodDays)))
+}
+
+// billingPeriodOf returns the monthly billing period containing t (invoices cover calendar months)
+func billingPeriodOf(t time.Time) (time.Time, time.Time) {
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.Local)
+	return start, start.AddDate(0, 1, -1)
+}
+
+// periodDays returns the number of days in a period (both ends inclusive)
+func periodDays(start, end time.Time) int {
+	return daysBetween(start, end) + 1
+}
+
+// activeDays returns the number of days of [start, end] on which a client activated at activatedAt
+// was in service (0 when activated after the period)
+func activeDays(activatedAt, start, end time.Time) int {
+	from := start
+	if daysBetween(start, activatedAt) > 0 {
+		from = activatedAt
+	}
+	if daysBetween(from, end) < 0 {
+		return 0
+	}
+	return daysBetween(from, end) + 1
+}
+
+// packageChangeLines computes the prorated lines for a price change effective on `at` (the change day
+// is billed at the new price). When the period was already invoiced at the old price, the remaining days
+// are credited at the old price and charged at the new one. Otherwise the upcoming invoice bills the new
+// price for the whole period, so the days before the change (counted from `from`) are charged at the old
+// price and credited at the new one.
+func packageChangeLines(oldDesc string, oldPrice int64, newDesc string, newPrice int64, at, from, periodStart, periodEnd time.Time, billed bool) []prorationLine {
+	total := periodDays(periodStart, periodEnd)
+	var lines []prorationLine
+	if billed {
+		remaining := daysBetween(at, periodEnd) + 1
+		lines = []prorationLine{
+			{Description: fmt.Sprintf("Kredit %s (sisa %d/%d hari)", oldDesc, remaining, total), Amount: -prorate(oldPrice, remaining, total)},
+			{Description: fmt.Sprintf("%s (prorata %d/%d hari)", newDesc, remaining, total), Amount: prorate(newPrice, remaining, total)},
+		}
+	} else {
+		if daysBetween(from, periodStart) > 0 {
+			from = periodStart
+		}
+		elapsed := daysBetween(from, at)
+		lines = []prorationLine{
+			{Description: fmt.Sprintf("%s (prorata %d/%d hari sebelum perubahan paket)", oldDesc, elapsed, total), Amount: prorate(oldPrice, elapsed, total)},
+			{Description: fmt.Sprintf("Kredit %s (%d/%d hari sebelum perubahan paket)", newDesc, elapsed, total), Amount: -prorate(newPrice, elapsed, total)},
+		}
+	}
+
+	out := lines[:0]
+	for _, l := range lines {
+		if l.Amount != 0 {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// clientMonthlyPrice returns the full monthly price of a client from its service package (preferred)
+// or the legacy MonthlyFee, together with the invoice line description.
+func (s *BillingService) clientMonthlyPrice(ctx context.Context, tenantID uuid.UUID, c *client.Client) (int64, string, error) {
+	pkg, err := s.clientPackage(ctx, tenantID, c)
+	if err != nil {
+		return 0, "", err
+	}
+	return clientPrice(c, pkg, 1)
+}
+
+// ProratePackageChange records the prorated credit/charge lines for a client whose package, billed
+// device count, monthly fee or billing cycle changed on `at`, over the billing cycle containing `at`. The lines are billed on the
+// client's next invoice.
+func (s *BillingService) ProratePackageChange(ctx context.Context, tenantID uuid.UUID, before, after *client.Client, at time.Time) error {
+	if s.adjustmentRepo == nil {
+		return nil
+	}
+	oldPkg, err := s.clientPackage(ctx, tenantID, before)
+	if err != nil {
+		return err
+	}
+	newPkg, err := s.clientPackage(ctx, tenantID, after)
+	if err != nil {
+		return err
+	}
+	// Both prices are compared over the client's (new) cycle, so the days are prorated over its length.
+	// The old price is taken from the old cycle, as a package may price cycles differently per month.
+	cycle := clientCycle(after, newPkg)
+	oldCycle := clientCycle(before, oldPkg)
+	oldPrice, oldDesc, err := clientPrice(before, oldPkg, oldCycle.Months)
+	if err != nil {
+		return err
+	}
+	oldPrice = int64(math.Round(float64(oldPrice) * float64(cycle.Months) / float64(oldCycle.Months)))
+	newPrice, newDesc, err := clientPrice(after, newPkg, cycle.Months)
+	if err != nil {
+		return err
+	}
+	if oldPrice == newPrice {
+		return nil
+	}
+
+	periodStart, periodEnd := cycle.periodOf(at)
+	inv, err := s.invoiceRepo.GetForClientPeriod(ctx, tenantID, after.ID, periodStart, periodEnd)
+	if err != nil {
+		return err
+	}
+
+	var sourceInvoiceID *uuid.UUID
+	if inv != nil {
+		sourceInvoiceID = &inv.ID
+	}
+	lines := packageChangeLines(oldDesc, oldPrice, newDesc, newPrice, at, before.CreatedAt, periodStart, periodEnd, inv != nil)
+	return s.createAdjustments(ctx, tenantID, after.ID, billing.AdjustmentPackageChange, lines, at, sourceInvoiceID)
+}
+
+// ProrateTermination credits the unused days of the current billing cycle when a client is terminated on `at`
+// (the termination day itself is billed). The credit is put on the period's invoice right away while it
+// is still unpaid; otherwise it is added to the client's balance.
+func (s *BillingService) ProrateTermination(ctx context.Context, tenantID uuid.UUID, c *client.Client, at time.Time) error {
+	if s.adjustmentRepo == nil {
+		return nil
+	}
+	pkg, err := s.clientPackage(ctx, tenantID, c)
+	if err != nil {
+		return err
+	}
+	cycle := clientCycle(c, pkg)
+	periodStart, periodEnd := cycle.periodOf(at)
+	unused := daysBetween(at, periodEnd)
+	if unused <= 0 {
+		return nil
+	}
+	inv, err := s.invoiceRepo.GetForClientPeriod(ctx, tenantID, c.ID, periodStart, periodEnd)
+	if err != nil || inv == nil {
+		return err // nothing billed for the unused days
+	}
+	credited, err := s.adjustmentRepo.ExistsForSourceInvoice(ctx, inv.ID, billing.AdjustmentTermination)
+	if err != nil || credited {
+		return err
+	}
+
+	price, desc, err := clientPrice(c, pkg, cycle.Months)
+	if err != nil {
+		return err
+	}
+	total := periodDays(periodStart, periodEnd)
+	amount := capDiscount(prorate(price, unused, total), lineAmountFor(inv, inv.TotalAmount))
+	if amount <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	adj := &billing.Adjustment{
+		ID:              uuid.New(),
+		TenantID:        tenantID,
+		ClientID:        c.ID,
+		Kind:            billing.AdjustmentTermination,
+		Description:     fmt.Sprintf("Kredit terminasi %s (sisa %d/%d hari)", desc, unused, total),
+		Amount:          -amount,
+		EffectiveDate:   at,
+		SourceInvoiceID: &inv.ID,
+		CreatedAt:       now,
+	}
+
+	unpaid := (inv.Status == billing.InvoiceStatusPending || inv.Status == billing.InvoiceStatusOverdue) && inv.PaidAmount == 0
+	if unpaid {
+		item := billing.InvoiceItem{
+			ID:          uuid.New(),
+			InvoiceID:   inv.ID,
+			Description: adj.Description,
+			Quantity:    1,
+			UnitPrice:   adj.Amount,
+			Amount:      adj.Amount,
+			CreatedAt:   now,
+		}
+		if err := s.invoiceRepo.AppendItems(ctx, inv.ID, []billing.InvoiceItem{item}); err != nil {
+			return err
+		}
+		s.postInvoiceChange(ctx, inv, item)
+	} else if s.balanceRepo != nil {
+		entry := &billing.BalanceEntry{
+			ID:          uuid.New(),
+			TenantID:    tenantID,
+			ClientID:    c.ID,
+			Type:        billing.BalanceAdjustment,
+			Amount:      grossAmountFor(inv, amount), // the paid invoice included tax on these days
+			InvoiceID:   &inv.ID,
+			Description: adj.Description,
+			CreatedAt:   now,
+		}
+		if err := s.balanceRepo.Append(ctx, entry); err != nil {
+			return err
+		}
+		if s.ledger != nil {
+			s.ledger.PostBalanceEntry(ctx, entry, "")
+		}
+	} else {
+		return s.adjustmentRepo.Create(ctx, adj) // stays pending for the next invoice
+	}
+	adj.InvoiceID = &inv.ID
+	adj.AppliedAt = &now
+	return s.adjustmentRepo.Create(ctx, adj)
+}
+
+func (s *BillingService) createAdjustments(ctx context.Context, tenantID, clientID uuid.UUID, kind billing.AdjustmentKind, lines []prorationLine, at time.Time, sourceInvoiceID *uuid.UUID) error {
+	now := time.Now()
+	for _, l := range lines {
+		err := s.adjustmentRepo.Create(ctx, &billing.Adjustment{
+			ID:              uuid.New(),
+			TenantID:        tenantID,
+			ClientID:        clientID,
+			Kind:            kind,
+			Description:     l.Description,
+			Amount:          l.Amount,
+			EffectiveDate:   at,
+			SourceInvoiceID: sourceInvoiceID,
+			CreatedAt:       now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pendingAdjustmentItems returns the pending adjustments of a client as invoice item requests
+func (s *BillingService) pendingAdjustmentItems(ctx context.Context, tenantID, clientID uuid.UUID) ([]InvoiceItemRequest, []uuid.UUID) {
+	if s.adjustmentRepo == nil {
+		return nil, nil
+	}
+	pending, err := s.adjustmentRepo.ListPendingByClient(ctx, tenantID, clientID)
+	if err != nil {
+		log.Warn().Err(err).Str("client_id", clientID.String()).Msg("Failed to load pending billing adjustments, skipping")
+		return nil, nil
+	}
+	items := make([]InvoiceItemRequest, 0, len(pending))
+	ids := make([]uuid.UUID, 0, len(pending))
+	for _, a := range pending {
+		items = append(items, InvoiceItemRequest{Description: a.Description, Quantity: 1, UnitPrice: a.Amount})
+		ids = append(ids, a.ID)
+	}
+	return items, ids
+}
+
+// ListClientAdjustments returns the prorated adjustments (pending and billed) of a client
+func (s *BillingService) ListClientAdjustments(ctx context.Context, tenantID, clientID uuid.UUID) ([]*billing.Adjustment, error) {
+	if s.adjustmentRepo == nil {
+		return nil, nil
+	}
+	return s.adjustmentRepo.ListByClient(ctx, tenantID, clientID)
+}
+
+// postInvoiceChange posts the difference an appended item made to an invoice; before is the
+// invoice as loaded before the item was appended
+func (s *BillingService) postInvoiceChange(ctx context.Context, before *billing.Invoice, item billing.InvoiceItem) {
+	if s.ledger == nil {
+		return
+	}
+	after, err := s.invoiceRepo.GetByID(ctx, before.ID)
+	if err != nil {
+		log.Error().Err(err).Str("invoice_id", before.ID.String()).Msg("Failed to reload invoice for ledger posting")
+		return
+	}
+	s.ledger.PostInvoiceChange(ctx, before, after, item.ID, item.Description)
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/client"
+)
+
+func ymd(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+func TestProrate(t *testing.T) {
+	assert.Equal(t, int64(150000), prorate(150000, 31, 31))
+	assert.Equal(t, int64(150000), prorate(150000, 40, 31))
+	assert.Equal(t, int64(58065), prorate(150000, 12, 31))
+	assert.Equal(t, int64(0), prorate(150000, 0, 31))
+}
+
+func TestActiveDays(t *testing.T) {
+	start, end := billingPeriodOf(ymd(2025, time.May, 17))
+	assert.Equal(t, ymd(2025, time.May, 1), start)
+	assert.Equal(t, ymd(2025, time.May, 31), end)
+
+	assert.Equal(t, 31, activeDays(ymd(2025, time.April, 3), start, end))
+	assert.Equal(t, 31, activeDays(ymd(2025, time.May, 1), start, end))
+	assert.Equal(t, 12, activeDays(ymd(2025, time.May, 20).Add(15*time.Hour), start, end))
+	assert.Equal(t, 0, activeDays(ymd(2025, time.June, 2), start, end))
+}
+
+func TestPackageChangeLines(t *testing.T) {
+	start, end := billingPeriodOf(ymd(2025, time.June, 1))
+	at := ymd(2025, time.June, 21)
+
+	// Period already invoiced at the old price: credit old, charge new for the remaining 10 days
+	lines := packageChangeLines("Paket A", 150000, "Paket B", 300000, at, ymd(2024, time.January, 1), start, end, true)
+	require.Len(t, lines, 2)
+	assert.Equal(t, int64(-50000), lines[0].Amount)
+	assert.Equal(t, "Kredit Paket A (sisa 10/30 hari)", lines[0].Description)
+	assert.Equal(t, int64(100000), lines[1].Amount)
+
+	// Not invoiced yet (next invoice bills the new price): charge old, credit new for the 20 days before
+	lines = packageChangeLines("Paket A", 150000, "Paket B", 300000, at, ymd(2024, time.January, 1), start, end, false)
+	require.Len(t, lines, 2)
+	assert.Equal(t, int64(100000), lines[0].Amount)
+	assert.Equal(t, int64(-200000), lines[1].Amount)
+
+	// Activated mid-period: only the days since activation count
+	lines = packageChangeLines("Paket A", 150000, "Paket B", 300000, at, ymd(2025, time.June, 11), start, end, false)
+	require.Len(t, lines, 2)
+	assert.Equal(t, int64(50000), lines[0].Amount)
+
+	// Change on the first day of an uninvoiced period needs no adjustment
+	assert.Empty(t, packageChangeLines("Paket A", 150000, "Paket B", 300000, start, start, start, end, false))
+}
+
+func TestChargeLines(t *testing.T) {
+	start, end := billingPeriodOf(ymd(2025, time.May, 1))
+	ended := ymd(2025, time.May, 10)
+	charges := []*billing.RecurringCharge{
+		{Description: "IP publik statis", Amount: 50000, Quantity: 1, StartDate: ymd(2025, time.January, 1)},
+		{Description: "Sewa ONT", Amount: 31000, Quantity: 2, StartDate: ymd(2025, time.May, 21)},
+		{Description: "Perangkat tambahan", Amount: 31000, Quantity: 1, StartDate: ymd(2025, time.April, 1), EndDate: &ended},
+	}
+
+	lines := chargeLines(charges, start, end)
+	require.Len(t, lines, 3)
+	assert.Equal(t, InvoiceItemRequest{Description: "IP publik statis", Quantity: 1, UnitPrice: 50000}, lines[0])
+	assert.Equal(t, InvoiceItemRequest{Description: "Sewa ONT (prorata 11/31 hari)", Quantity: 2, UnitPrice: 11000}, lines[1])
+	assert.Equal(t, InvoiceItemRequest{Description: "Perangkat tambahan (prorata 10/31 hari)", Quantity: 1, UnitPrice: 10000}, lines[2])
+}
+
+func TestBilledPriceChanged(t *testing.T) {
+	pkgA, pkgB := uuid.New(), uuid.New()
+	ptr := func(n int) *int { return &n }
+	base := client.Client{ServicePackageID: &pkgA, DeviceCount: ptr(2), MonthlyFee: 150000}
+
+	tests := []struct {
+		name   string
+		update func(c *client.Client)
+		want   bool
+	}{
+		{"nothing billed changed", func(c *client.Client) { c.Name = "Renamed" }, false},
+		{"same package pointer value", func(c *client.Client) { id := pkgA; c.ServicePackageID = &id }, false},
+		{"package changed", func(c *client.Client) { c.ServicePackageID = &pkgB }, true},
+		{"package removed", func(c *client.Client) { c.ServicePackageID = nil }, true},
+		{"device count changed", func(c *client.Client) { c.DeviceCount = ptr(3) }, true},
+		{"device count cleared", func(c *client.Client) { c.DeviceCount = nil }, true},
+		{"monthly fee changed", func(c *client.Client) { c.MonthlyFee = 175000 }, true},
+		{"cycle override set", func(c *client.Client) { c.BillingCycleMonths = ptr(3) }, true},
+		{"cycle override zero is no override", func(c *client.Client) { c.BillingCycleMonths = ptr(0) }, false},
+		{"cycle anchor only", func(c *client.Client) { at := ymd(2026, 4, 1); c.BillingCycleAnchor = &at }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after := base
+			tt.update(&after)
+			assert.Equal(t, tt.want, billedPriceChanged(&base, &after))
+		})
+	}
+
+	// Clients without a package compare equal too
+	legacy := client.Client{MonthlyFee: 100000}
+	same := legacy
+	assert.False(t, billedPriceChanged(&legacy, &same))
+}
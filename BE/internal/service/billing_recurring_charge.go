@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/repository"
+)
+
+var (
+	ErrRecurringChargeDescription = errors.New("charge description is required (at most 255 characters)")
+	ErrRecurringChargeAmount      = errors.New("charge amount must be greater than 0")
+	ErrRecurringChargeQuantity    = errors.New("charge quantity must be at least 1")
+	ErrRecurringChargeDates       = errors.New("charge end date must not be before its start date")
+)
+
+// RecurringChargeRequest creates or replaces a recurring charge of a client. StartDate defaults to
+// today and Quantity to 1.
+type RecurringChargeRequest struct {
+	Description string     `json:"description"`
+	Amount      int64      `json:"amount"`
+	Quantity    int        `json:"quantity"`
+	StartDate   *time.Time `json:"start_date,omitempty"`
+	EndDate     *time.Time `json:"end_date,omitempty"`
+}
+
+func (req *RecurringChargeRequest) apply(c *billing.RecurringCharge, now time.Time) error {
+	req.Description = strings.TrimSpace(req.Description)
+	if req.Description == "" || len(req.Description) > 255 {
+		return ErrRecurringChargeDescription
+	}
+	if req.Amount <= 0 {
+		return ErrRecurringChargeAmount
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+	if req.Quantity < 0 {
+		return ErrRecurringChargeQuantity
+	}
+	start := now
+	if req.StartDate != nil {
+		start = *req.StartDate
+	}
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.Local)
+	var end *time.Time
+	if req.EndDate != nil {
+		e := time.Date(req.EndDate.Year(), req.EndDate.Month(), req.EndDate.Day(), 0, 0, 0, 0, time.Local)
+		if e.Before(start) {
+			return ErrRecurringChargeDates
+		}
+		end = &e
+	}
+	c.Description, c.Amount, c.Quantity = req.Description, req.Amount, req.Quantity
+	c.StartDate, c.EndDate = start, end
+	c.UpdatedAt = now
+	return nil
+}
+
+// ListClientCharges returns the recurring charges of a client, including ended ones
+func (s *BillingService) ListClientCharges(ctx context.Context, tenantID, clientID uuid.UUID) ([]*billing.RecurringCharge, error) {
+	return s.chargeRepo.ListByClient(ctx, tenantID, clientID)
+}
+
+func (s *BillingService) CreateClientCharge(ctx context.Context, tenantID, clientID uuid.UUID, req RecurringChargeRequest) (*billing.RecurringCharge, error) {
+	if _, err := s.clientRepo.GetByID(ctx, tenantID, clientID); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	c := &billing.RecurringCharge{
+		ID:        uuid.New(),
+		TenantID:  tenantID,
+		ClientID:  clientID,
+		CreatedAt: now,
+	}
+	if err := req.apply(c, now); err != nil {
+		return nil, err
+	}
+	if err := s.chargeRepo.Create(ctx, c); err != nil {
+		return nil, fmt.Errorf("failed to create recurring charge: %w", err)
+	}
+	return c, nil
+}
+
+// UpdateClientCharge replaces a charge; invoices already issued keep their lines. Set EndDate to
+// stop billing a charge from a date on.
+func (s *BillingService) UpdateClientCharge(ctx context.Context, tenantID, clientID, chargeID uuid.UUID, req RecurringChargeRequest) (*billing.RecurringCharge, error) {
+	c, err := s.clientCharge(ctx, tenantID, clientID, chargeID)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.apply(c, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.chargeRepo.Update(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *BillingService) DeleteClientCharge(ctx context.Context, tenantID, clientID, chargeID uuid.UUID) error {
+	if _, err := s.clientCharge(ctx, tenantID, clientID, chargeID); err != nil {
+		return err
+	}
+	return s.chargeRepo.Delete(ctx, tenantID, chargeID)
+}
+
+func (s *BillingService) clientCharge(ctx context.Context, tenantID, clientID, chargeID uuid.UUID) (*billing.RecurringCharge, error) {
+	c, err := s.chargeRepo.GetByID(ctx, tenantID, chargeID)
+	if err != nil {
+		return nil, err
+	}
+	if c.ClientID != clientID {
+		return nil, repository.ErrRecurringChargeNotFound
+	}
+	return c, nil
+}
+
+// recurringChargeItems returns the invoice lines of a client's recurring charges for a period
+func (s *BillingService) recurringChargeItems(ctx context.Context, tenantID, clientID uuid.UUID, periodStart, periodEnd time.Time) []InvoiceItemRequest {
+	if s.chargeRepo == nil {
+		return nil
+	}
+	charges, err := s.chargeRepo.ListActiveInPeriod(ctx, tenantID, clientID, periodStart, periodEnd)
+	if err != nil {
+		log.Warn().Err(err).Str("client_id", clientID.String()).Msg("Failed to load recurring charges, invoicing without them")
+		return nil
+	}
+	return chargeLines(charges, periodStart, periodEnd)
+}
+
+// chargeLines bills each charge for the days of [periodStart, periodEnd] it covers; charges that
+// start or end inside the period are prorated per unit
+func chargeLines(charges []*billing.RecurringCharge, periodStart, periodEnd time.Time) []InvoiceItemRequest {
+	var items []InvoiceItemRequest
+	total := periodDays(periodStart, periodEnd)
+	for _, c := range charges {
+		from, to := periodStart, periodEnd
+		if daysBetween(from, c.StartDate) > 0 {
+			from = c.StartDate
+		}
+		if c.EndDate != nil && daysBetween(*c.EndDate, to) > 0 {
+			to = *c.EndDate
+		}
+		days := daysBetween(from, to) + 1
+		unit := prorate(c.Amount, days, total)
+		if unit <= 0 {
+			continue
+		}
+		desc := c.Description
+		if days < total {
+			desc = fmt.Sprintf("%s (prorata %d/%d hari)", desc, days, total)
+		}
+		items = append(items, InvoiceItemRequest{Description: desc, Quantity: c.Quantity, UnitPrice: unit})
+	}
+	return items
+}
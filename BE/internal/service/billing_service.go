@@ -2,12 +2,17 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 
 	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/client"
+	"rrnet/internal/domain/notification"
+	"rrnet/internal/domain/service_package"
 	"rrnet/internal/repository"
 )
 
@@ -16,6 +21,15 @@ type BillingService struct {
 	paymentRepo *repository.PaymentRepository
 	clientRepo  *repository.ClientRepository
 	servicePackageRepo *repository.ServicePackageRepository
+	discountRepo *repository.DiscountRepository
+	tenantRepo *repository.TenantRepository
+	adjustmentRepo *repository.AdjustmentRepository
+	balanceRepo *repository.BalanceRepository
+	sequenceRepo *repository.DocumentSequenceRepository
+	chargeRepo *repository.RecurringChargeRepository
+	isolirService *IsolirService
+	notifier *NotificationService
+	ledger *LedgerService
 }
 
 func NewBillingService(
@@ -32,48 +46,117 @@ func NewBillingService(
 	}
 }
 
+// SetDiscountRepository applies client and package discounts to generated invoices
+func (s *BillingService) SetDiscountRepository(discountRepo *repository.DiscountRepository) {
+	s.discountRepo = discountRepo
+}
+
+// SetTenantRepository reads the tenant's billing settings (tax, numbering, document template);
+// without it the defaults apply
+func (s *BillingService) SetTenantRepository(tenantRepo *repository.TenantRepository) {
+	s.tenantRepo = tenantRepo
+}
+
+// SetAdjustmentRepository bills prorated package changes and terminations on the next invoice
+func (s *BillingService) SetAdjustmentRepository(adjustmentRepo *repository.AdjustmentRepository) {
+	s.adjustmentRepo = adjustmentRepo
+}
+
+// SetBalanceRepository keeps client balances: overpayments, credit notes, refunds and credit
+// applied to new invoices
+func (s *BillingService) SetBalanceRepository(balanceRepo *repository.BalanceRepository) {
+	s.balanceRepo = balanceRepo
+}
+
+// SetSequenceRepository previews the next document numbers of the tenant's numbering schemes
+func (s *BillingService) SetSequenceRepository(sequenceRepo *repository.DocumentSequenceRepository) {
+	s.sequenceRepo = sequenceRepo
+}
+
+// SetRecurringChargeRepository bills recurring extras (static IP, equipment rental) on monthly invoices
+func (s *BillingService) SetRecurringChargeRepository(chargeRepo *repository.RecurringChargeRepository) {
+	s.chargeRepo = chargeRepo
+}
+
+// SetIsolirService lifts isolir once a client's overdue invoices are settled
+func (s *BillingService) SetIsolirService(isolirService *IsolirService) {
+	s.isolirService = isolirService
+}
+
+// SetNotifier sends clients a WhatsApp message when invoices are issued and payments received
+func (s *BillingService) SetNotifier(notifier *NotificationService) {
+	s.notifier = notifier
+}
+
+// SetLedger posts invoices, payments, credit notes and balance movements to the tenant's books
+func (s *BillingService) SetLedger(ledger *LedgerService) {
+	s.ledger = ledger
+}
+
 // ========== Invoice Operations ==========
 
 type CreateInvoiceRequest struct {
-	ClientID       uuid.UUID            `json:"client_id"`
-	PeriodStart    time.Time            `json:"period_start"`
-	PeriodEnd      time.Time            `json:"period_end"`
-	DueDate        time.Time            `json:"due_date"`
-	Items          []InvoiceItemRequest `json:"items"`
-	TaxPercent     float64              `json:"tax_percent,omitempty"`
-	DiscountAmount int64                `json:"discount_amount,omitempty"`
-	Notes          string               `json:"notes,omitempty"`
+	ClientID       uuid.UUID             `json:"client_id"`
+	PeriodStart    time.Time             `json:"period_start"`
+	PeriodEnd      time.Time             `json:"period_end"`
+	DueDate        time.Time             `json:"due_date"`
+	Items          []InvoiceItemRequest  `json:"items"`
+	TaxPercent     float64               `json:"tax_percent,omitempty"` // overrides the tenant tax profile (added on top)
+	DiscountAmount int64                 `json:"discount_amount,omitempty"`
+	Notes          string                `json:"notes,omitempty"`
+	Source         billing.InvoiceSource `json:"-"` // manual unless generated by GenerateMonthlyInvoice
 }
 
 type InvoiceItemRequest struct {
-	Description string `json:"description"`
-	Quantity    int    `json:"quantity"`
-	UnitPrice   int64  `json:"unit_price"`
+	Description string     `json:"description"`
+	Quantity    int        `json:"quantity"`
+	UnitPrice   int64      `json:"unit_price"` // negative = credit line
+	DiscountID  *uuid.UUID `json:"discount_id,omitempty"`
+	IsDiscount  bool       `json:"is_discount,omitempty"` // negative line counted in DiscountAmount instead of the subtotal
 }
 
 func (s *BillingService) CreateInvoice(ctx context.Context, tenantID uuid.UUID, req CreateInvoiceRequest) (*billing.Invoice, error) {
-	// Generate invoice number
-	invoiceNumber, err := s.invoiceRepo.GenerateInvoiceNumber(ctx, tenantID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate invoice number: %w", err)
+	now := time.Now()
+	invoice := s.buildInvoice(ctx, tenantID, req, now)
+
+	// The invoice number is drawn from the tenant's sequence when the invoice is stored
+	numbering := s.documentNumbering(ctx, tenantID, billing.DocumentInvoice, req.ClientID, now)
+	if err := s.invoiceRepo.Create(ctx, invoice, numbering); err != nil {
+		return nil, fmt.Errorf("failed to create invoice: %w", err)
+	}
+	s.assignUniqueCode(ctx, tenantID, invoice)
+	if s.ledger != nil {
+		s.ledger.PostInvoice(ctx, invoice)
 	}
 
-	now := time.Now()
+	if s.notifier != nil && invoice.TotalAmount > 0 {
+		invoiceID := invoice.ID
+		s.notifier.Raise(ctx, tenantID, notification.EventInvoiceCreated, invoice.ClientID, &invoiceID, invoice.ID.String(), nil)
+	}
+
+	return invoice, nil
+}
+
+// buildInvoice computes the items and totals of an invoice without storing it
+func (s *BillingService) buildInvoice(ctx context.Context, tenantID uuid.UUID, req CreateInvoiceRequest, now time.Time) *billing.Invoice {
 	invoice := &billing.Invoice{
 		ID:             uuid.New(),
 		TenantID:       tenantID,
 		ClientID:       req.ClientID,
-		InvoiceNumber:  invoiceNumber,
 		PeriodStart:    req.PeriodStart,
 		PeriodEnd:      req.PeriodEnd,
 		DueDate:        req.DueDate,
 		DiscountAmount: req.DiscountAmount,
 		Currency:       "IDR",
 		Status:         billing.InvoiceStatusPending,
+		Source:         req.Source,
 		Notes:          req.Notes,
 		CreatedAt:      now,
 		UpdatedAt:      now,
 	}
+	if invoice.Source == "" {
+		invoice.Source = billing.InvoiceSourceManual
+	}
 
 	// Process items
 	var subtotal int64
@@ -90,21 +173,28 @@ func (s *BillingService) CreateInvoice(ctx context.Context, tenantID uuid.UUID,
 			Quantity:    qty,
 			UnitPrice:   itemReq.UnitPrice,
 			Amount:      amount,
+			DiscountID:  itemReq.DiscountID,
 			CreatedAt:   now,
 		}
 		invoice.Items = append(invoice.Items, item)
+		// Discount lines are shown on the invoice but counted in DiscountAmount, not the subtotal.
+		// Other negative lines (prorated credits) reduce the subtotal.
+		if amount < 0 && (itemReq.IsDiscount || itemReq.DiscountID != nil) {
+			invoice.DiscountAmount += -amount
+			continue
+		}
 		subtotal += amount
 	}
 
-	invoice.Subtotal = subtotal
-	invoice.TaxAmount = int64(float64(subtotal) * req.TaxPercent / 100)
-	invoice.TotalAmount = subtotal + invoice.TaxAmount - invoice.DiscountAmount
-
-	if err := s.invoiceRepo.Create(ctx, invoice); err != nil {
-		return nil, fmt.Errorf("failed to create invoice: %w", err)
+	// Tax follows the tenant tax profile unless the request sets a rate explicitly
+	if req.TaxPercent > 0 {
+		invoice.TaxRate = req.TaxPercent
+	} else {
+		invoice.TaxRate, invoice.TaxInclusive = s.invoiceTaxRate(ctx, tenantID, req.ClientID)
 	}
-
-	return invoice, nil
+	invoice.Subtotal = subtotal
+	invoice.TaxBase, invoice.TaxAmount, invoice.TotalAmount = billing.ComputeTax(subtotal, invoice.DiscountAmount, invoice.TaxRate, invoice.TaxInclusive)
+	return invoice
 }
 
 func (s *BillingService) GetInvoice(ctx context.Context, id uuid.UUID) (*billing.Invoice, error) {
@@ -128,7 +218,17 @@ func (s *BillingService) MarkInvoiceAsOverdue(ctx context.Context, id uuid.UUID)
 }
 
 func (s *BillingService) CancelInvoice(ctx context.Context, id uuid.UUID) error {
-	return s.invoiceRepo.UpdateStatus(ctx, id, billing.InvoiceStatusCancelled)
+	invoice, err := s.invoiceRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.invoiceRepo.UpdateStatus(ctx, id, billing.InvoiceStatusCancelled); err != nil {
+		return err
+	}
+	if s.ledger != nil && invoice.Status != billing.InvoiceStatusCancelled {
+		s.ledger.PostInvoiceCancel(ctx, invoice)
+	}
+	return nil
 }
 
 // ========== Payment Operations ==========
@@ -141,6 +241,9 @@ type RecordPaymentRequest struct {
 	CollectorID *uuid.UUID            `json:"collector_id,omitempty"`
 	Notes       *string               `json:"notes,omitempty"`
 	ReceivedAt  *time.Time            `json:"received_at,omitempty"`
+	Collected   bool                  `json:"-"` // deposit of cash a collector took at a visit
+
+	PaymentRequestID *uuid.UUID `json:"-"` // gateway request the payment settles
 }
 
 func (s *BillingService) RecordPayment(ctx context.Context, tenantID, userID uuid.UUID, req RecordPaymentRequest) (*billing.Payment, error) {
@@ -164,50 +267,88 @@ func (s *BillingService) RecordPayment(ctx context.Context, tenantID, userID uui
 	}
 
 	payment := &billing.Payment{
-		ID:              uuid.New(),
-		TenantID:        tenantID,
-		InvoiceID:       req.InvoiceID,
-		ClientID:        invoice.ClientID,
-		Amount:          req.Amount,
-		Currency:        "IDR",
-		Method:          req.Method,
-		Reference:       req.Reference,
-		CollectorID:     req.CollectorID,
-		Notes:           req.Notes,
-		ReceivedAt:      receivedAt,
-		CreatedAt:       now,
-		CreatedByUserID: userID,
+		ID:               uuid.New(),
+		TenantID:         tenantID,
+		InvoiceID:        req.InvoiceID,
+		ClientID:         invoice.ClientID,
+		Amount:           req.Amount,
+		Currency:         "IDR",
+		Method:           req.Method,
+		Reference:        req.Reference,
+		CollectorID:      req.CollectorID,
+		Notes:            req.Notes,
+		ReceivedAt:       receivedAt,
+		CreatedAt:        now,
+		CreatedByUserID:  userID,
+		PaymentRequestID: req.PaymentRequestID,
 	}
 
 	if payment.Method == "" {
 		payment.Method = billing.PaymentMethodCash
 	}
 
-	if err := s.paymentRepo.Create(ctx, payment); err != nil {
+	// The receipt number is drawn from the tenant's sequence when the payment is stored
+	numbering := s.documentNumbering(ctx, tenantID, billing.DocumentReceipt, payment.ClientID, receivedAt)
+	if err := s.paymentRepo.Create(ctx, payment, numbering); err != nil {
 		return nil, fmt.Errorf("failed to record payment: %w", err)
 	}
 
-	// Update invoice paid amount
+	// Update invoice paid amount (payments plus client credit applied to it)
 	totalPaid, err := s.paymentRepo.GetTotalByInvoice(ctx, req.InvoiceID)
 	if err != nil {
 		return nil, err
 	}
+	if s.balanceRepo != nil {
+		applied, err := s.balanceRepo.SumAppliedToInvoice(ctx, req.InvoiceID)
+		if err != nil {
+			return nil, err
+		}
+		totalPaid += applied
+	}
 
 	var paidAt *time.Time
+	var excess int64
 	if totalPaid >= invoice.TotalAmount {
 		paidAt = &now
 		if err := s.invoiceRepo.UpdateStatus(ctx, req.InvoiceID, billing.InvoiceStatusPaid); err != nil {
 			return nil, err
 		}
+		// Overpayment is carried forward as client credit
+		kept := s.creditOverpayment(ctx, invoice, payment.ID, totalPaid)
+		excess = totalPaid - kept
+		totalPaid = kept
+	}
+	if s.ledger != nil {
+		s.ledger.PostPayment(ctx, payment, invoice, excess, req.Collected)
 	}
 
 	if err := s.invoiceRepo.UpdatePaidAmount(ctx, req.InvoiceID, totalPaid, paidAt); err != nil {
 		return nil, err
 	}
 
+	// Lift isolir automatically once the client has nothing overdue left (and, when prepaid, is
+	// covered again by the validity the invoice bought)
+	if paidAt != nil && s.applyPrepaidPayment(ctx, tenantID, invoice.ClientID, invoice) && s.isolirService != nil {
+		invoiceID := req.InvoiceID
+		if err := s.isolirService.ReactivateIfSettled(ctx, tenantID, invoice.ClientID, &invoiceID); err != nil {
+			log.Warn().Err(err).Str("invoice_id", invoiceID.String()).Msg("Failed to reactivate client after payment")
+		}
+	}
+
+	if s.notifier != nil {
+		invoiceID := req.InvoiceID
+		s.notifier.Raise(ctx, tenantID, notification.EventPaymentReceived, invoice.ClientID, &invoiceID, payment.ID.String(), paymentNotificationData(payment.Amount, payment.Method, receivedAt, invoice.InvoiceNumber))
+	}
+
 	return payment, nil
 }
 
+// GatewayPayment reports whether the payment of a gateway request is already recorded, and which
+// payment settled it (see PaymentRepository.GetByPaymentRequest)
+func (s *BillingService) GatewayPayment(ctx context.Context, requestID uuid.UUID) (bool, *uuid.UUID, error) {
+	return s.paymentRepo.GetByPaymentRequest(ctx, requestID)
+}
+
 func (s *BillingService) GetPayment(ctx context.Context, id uuid.UUID) (*billing.Payment, error) {
 	return s.paymentRepo.GetByID(ctx, id)
 }
@@ -243,51 +384,31 @@ func (s *BillingService) GenerateMonthlyInvoice(ctx context.Context, tenantID, c
 		return nil, fmt.Errorf("client not found: %w", err)
 	}
 
-	// Determine unit price from service package (preferred) or legacy MonthlyFee.
-	var unitPrice int64
-	var itemDesc string
-	if client.ServicePackageID != nil && *client.ServicePackageID != uuid.Nil {
-		pkg, err := s.servicePackageRepo.GetByID(ctx, tenantID, *client.ServicePackageID)
-		if err != nil {
-			return nil, fmt.Errorf("service package not found: %w", err)
-		}
-		itemDesc = fmt.Sprintf("Layanan Internet - %s", pkg.Name)
-		switch pkg.PricingModel {
-		case "per_device":
-			devCount := 1
-			if client.DeviceCount != nil && *client.DeviceCount > 0 {
-				devCount = *client.DeviceCount
-			}
-			unitPrice = int64(pkg.PricePerDevice * float64(devCount))
-		default:
-			unitPrice = int64(pkg.PriceMonthly)
-		}
-	} else {
-		if client.MonthlyFee == 0 {
-			return nil, fmt.Errorf("client has no service package or monthly fee configured")
-		}
-		itemDesc = "Layanan Internet"
-		unitPrice = int64(client.MonthlyFee * 100) // legacy cents
+	// Prepaid clients are billed for their next validity period instead of a calendar month
+	if s.ClientBillingMode(ctx, tenantID, client) == billing.BillingModePrepaid {
+		return s.renewPrepaid(ctx, tenantID, client)
+	}
+
+	pkg, err := s.clientPackage(ctx, tenantID, client)
+	if err != nil {
+		return nil, err
 	}
 
 	now := time.Now()
 
 	// Compute due date from client tempo fields.
 	dueDate := computeClientDueDate(now, client.CreatedAt, client.PaymentTempoOption, client.PaymentDueDay)
+	hasAny, err := s.invoiceRepo.HasAnyInvoiceForClient(ctx, tenantID, clientID)
+	if err != nil {
+		return nil, err
+	}
 	// Special rule: if option=template and first invoice, due date is client created date.
-	if client.PaymentTempoOption == "template" {
-		hasAny, err := s.invoiceRepo.HasAnyInvoiceForClient(ctx, tenantID, clientID)
-		if err != nil {
-			return nil, err
-		}
-		if !hasAny {
-			dueDate = time.Date(client.CreatedAt.Year(), client.CreatedAt.Month(), client.CreatedAt.Day(), 23, 59, 59, 0, time.Local)
-		}
+	if client.PaymentTempoOption == "template" && !hasAny {
+		dueDate = time.Date(client.CreatedAt.Year(), client.CreatedAt.Month(), client.CreatedAt.Day(), 23, 59, 59, 0, time.Local)
 	}
 
-	// Period should follow the month of due_date, not the current month
-	periodStart := time.Date(dueDate.Year(), dueDate.Month(), 1, 0, 0, 0, 0, time.Local)
-	periodEnd := periodStart.AddDate(0, 1, -1) // Last day of the month
+	// Period is the billing cycle containing due_date, not the current month
+	periodStart, periodEnd := clientCycle(client, pkg).periodOf(dueDate)
 
 	// Check if invoice already exists for this client+period to prevent duplicates
 	exists, err := s.invoiceRepo.ExistsForClientPeriod(ctx, tenantID, clientID, periodStart, periodEnd)
@@ -314,21 +435,120 @@ func (s *BillingService) GenerateMonthlyInvoice(ctx context.Context, tenantID, c
 		return nil, fmt.Errorf("invoice exists but could not be retrieved")
 	}
 
+	draft, err := s.draftMonthlyInvoice(ctx, tenantID, client, pkg, dueDate, hasAny)
+	if err != nil {
+		return nil, err
+	}
+	invoice, _, err := s.createMonthlyInvoice(ctx, tenantID, draft)
+	return invoice, err
+}
+
+// monthlyInvoiceDraft is the invoice of a postpaid client for one billing period, built but not stored
+type monthlyInvoiceDraft struct {
+	Request       CreateInvoiceRequest
+	AdjustmentIDs []uuid.UUID // pending adjustments billed on the invoice
+}
+
+// draftMonthlyInvoice builds the invoice of a postpaid client for the billing cycle containing dueDate.
+// hasInvoices is false for the client's first invoice, which is prorated from activation.
+func (s *BillingService) draftMonthlyInvoice(ctx context.Context, tenantID uuid.UUID, c *client.Client, pkg *service_package.ServicePackage, dueDate time.Time, hasInvoices bool) (*monthlyInvoiceDraft, error) {
+	// Determine the cycle price from service package (preferred) or legacy MonthlyFee.
+	cycle := clientCycle(c, pkg)
+	unitPrice, itemDesc, err := clientPrice(c, pkg, cycle.Months)
+	if err != nil {
+		return nil, err
+	}
+
+	periodStart, periodEnd := cycle.periodOf(dueDate)
+	if cycle.Months > 1 {
+		itemDesc = fmt.Sprintf("%s (%s)", itemDesc, cycleLabel(cycle.Months, periodStart, periodEnd))
+	}
+
 	req := CreateInvoiceRequest{
-		ClientID:    clientID,
+		ClientID:    c.ID,
 		PeriodStart: periodStart,
 		PeriodEnd:   periodEnd,
 		DueDate:     dueDate,
-		Items: []InvoiceItemRequest{
-			{
-				Description: itemDesc,
-				Quantity:    1,
-				UnitPrice:   unitPrice,
-			},
-		},
+		Source:      billing.InvoiceSourceMonthly,
+	}
+
+	// First invoice: bill only the days since activation, plus the partial previous cycle
+	// when the client was activated in the cycle before this period.
+	base := unitPrice
+	if !hasInvoices {
+		prevStart, prevEnd := cycle.periodOf(periodStart.AddDate(0, 0, -1))
+		if daysBetween(prevStart, c.CreatedAt) >= 0 && daysBetween(c.CreatedAt, prevEnd) >= 0 {
+			days := activeDays(c.CreatedAt, prevStart, prevEnd)
+			total := periodDays(prevStart, prevEnd)
+			if amount := prorate(unitPrice, days, total); amount > 0 {
+				req.Items = append(req.Items, InvoiceItemRequest{
+					Description: fmt.Sprintf("%s (prorata %s - %s, %d/%d hari)", itemDesc, c.CreatedAt.Format("02-01-2006"), prevEnd.Format("02-01-2006"), days, total),
+					Quantity:    1,
+					UnitPrice:   amount,
+				})
+				base += amount
+			}
+		}
+
+		total := periodDays(periodStart, periodEnd)
+		if days := activeDays(c.CreatedAt, periodStart, periodEnd); days < total {
+			prorated := prorate(unitPrice, days, total)
+			base += prorated - unitPrice
+			unitPrice = prorated
+			itemDesc = fmt.Sprintf("%s (prorata %d/%d hari)", itemDesc, days, total)
+		}
 	}
+	req.Items = append(req.Items, InvoiceItemRequest{
+		Description: itemDesc,
+		Quantity:    1,
+		UnitPrice:   unitPrice,
+	})
+
+	// Recurring extras (static IP, extra device, equipment rental), month by month over the cycle
+	for i := 0; i < cycle.Months; i++ {
+		req.Items = mergeItems(req.Items, s.recurringChargeItems(ctx, tenantID, c.ID, periodStart.AddDate(0, i, 0), periodStart.AddDate(0, i+1, -1)))
+	}
+
+	for _, d := range s.resolveDiscountLines(ctx, tenantID, c, base) {
+		req.Items = append(req.Items, InvoiceItemRequest{
+			Description: d.Description,
+			Quantity:    1,
+			UnitPrice:   -d.Amount,
+			DiscountID:  d.DiscountID,
+			IsDiscount:  true,
+		})
+	}
+
+	// Prorated package changes/terminations waiting to be billed
+	adjItems, adjIDs := s.pendingAdjustmentItems(ctx, tenantID, c.ID)
+	req.Items = append(req.Items, adjItems...)
+
+	return &monthlyInvoiceDraft{Request: req, AdjustmentIDs: adjIDs}, nil
+}
 
-	return s.CreateInvoice(ctx, tenantID, req)
+// createMonthlyInvoice stores a drafted invoice, applies the client's credit balance and marks its
+// adjustments as billed. created is false when the invoice of the period already existed.
+func (s *BillingService) createMonthlyInvoice(ctx context.Context, tenantID uuid.UUID, draft *monthlyInvoiceDraft) (invoice *billing.Invoice, created bool, err error) {
+	req := draft.Request
+	invoice, err = s.CreateInvoice(ctx, tenantID, req)
+	if errors.Is(err, repository.ErrMonthlyInvoiceExists) {
+		// Generated concurrently (another replica or a retried job); return that invoice
+		existing, getErr := s.invoiceRepo.GetForClientPeriod(ctx, tenantID, req.ClientID, req.PeriodStart, req.PeriodEnd)
+		if getErr != nil || existing == nil {
+			return nil, false, err
+		}
+		return existing, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	s.applyClientCredit(ctx, tenantID, invoice)
+	if len(draft.AdjustmentIDs) > 0 {
+		if err := s.adjustmentRepo.MarkApplied(ctx, draft.AdjustmentIDs, invoice.ID, invoice.CreatedAt); err != nil {
+			log.Error().Err(err).Str("invoice_id", invoice.ID.String()).Msg("Failed to mark billing adjustments as applied")
+		}
+	}
+	return invoice, true, nil
 }
 
 func nowMonthYear() string {
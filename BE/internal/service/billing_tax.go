@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/domain/billing"
+)
+
+var (
+	ErrTaxRateInvalid = errors.New("tax rate must be between 0 and 100 percent")
+	ErrTaxNameInvalid = errors.New("tax name must not exceed 20 characters")
+)
+
+// TaxSettings is the tenant tax profile (stored in tenant settings under "tax")
+type TaxSettings struct {
+	Enabled          bool        `json:"enabled"`
+	Name             string      `json:"name"`      // label on invoices, e.g. "PPN"
+	Rate             float64     `json:"rate"`      // percent, e.g. 11
+	Inclusive        bool        `json:"inclusive"` // package prices already include the tax
+	TaxID            string      `json:"tax_id"`    // NPWP printed on invoices
+	ExemptPackageIDs []uuid.UUID `json:"exempt_package_ids"`
+	ExemptClientIDs  []uuid.UUID `json:"exempt_client_ids"`
+}
+
+func readTaxSettings(settings map[string]interface{}) TaxSettings {
+	out := TaxSettings{Name: "PPN", ExemptPackageIDs: []uuid.UUID{}, ExemptClientIDs: []uuid.UUID{}}
+	raw, ok := settings["tax"].(map[string]interface{})
+	if !ok || raw == nil {
+		return out
+	}
+	if v, ok := raw["enabled"].(bool); ok {
+		out.Enabled = v
+	}
+	if v, ok := raw["name"].(string); ok && v != "" {
+		out.Name = v
+	}
+	if v, ok := raw["rate"].(float64); ok && v >= 0 {
+		out.Rate = v
+	}
+	if v, ok := raw["inclusive"].(bool); ok {
+		out.Inclusive = v
+	}
+	if v, ok := raw["tax_id"].(string); ok {
+		out.TaxID = v
+	}
+	out.ExemptPackageIDs = readUUIDList(raw["exempt_package_ids"])
+	out.ExemptClientIDs = readUUIDList(raw["exempt_client_ids"])
+	return out
+}
+
+func readUUIDList(v interface{}) []uuid.UUID {
+	out := []uuid.UUID{}
+	list, _ := v.([]interface{})
+	for _, item := range list {
+		if str, ok := item.(string); ok {
+			if id, err := uuid.Parse(str); err == nil {
+				out = append(out, id)
+			}
+		}
+	}
+	return out
+}
+
+func uuidStrings(ids []uuid.UUID) []interface{} {
+	out := make([]interface{}, 0, len(ids))
+	seen := map[uuid.UUID]bool{}
+	for _, id := range ids {
+		if id == uuid.Nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		out = append(out, id.String())
+	}
+	return out
+}
+
+func containsUUID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *BillingService) GetTaxSettings(ctx context.Context, tenantID uuid.UUID) (*TaxSettings, error) {
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	out := readTaxSettings(t.Settings)
+	return &out, nil
+}
+
+func (s *BillingService) UpdateTaxSettings(ctx context.Context, tenantID uuid.UUID, in TaxSettings) (*TaxSettings, error) {
+	if in.Rate < 0 || in.Rate > 100 {
+		return nil, ErrTaxRateInvalid
+	}
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Name == "" {
+		in.Name = "PPN"
+	}
+	if len([]rune(in.Name)) > 20 {
+		return nil, ErrTaxNameInvalid
+	}
+	in.TaxID = strings.TrimSpace(in.TaxID)
+
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if t.Settings == nil {
+		t.Settings = map[string]interface{}{}
+	}
+	t.Settings["tax"] = map[string]interface{}{
+		"enabled":            in.Enabled,
+		"name":               in.Name,
+		"rate":               in.Rate,
+		"inclusive":          in.Inclusive,
+		"tax_id":             in.TaxID,
+		"exempt_package_ids": uuidStrings(in.ExemptPackageIDs),
+		"exempt_client_ids":  uuidStrings(in.ExemptClientIDs),
+	}
+	if err := s.tenantRepo.UpdateSettings(ctx, tenantID, t.Settings); err != nil {
+		return nil, err
+	}
+	out := readTaxSettings(t.Settings)
+	return &out, nil
+}
+
+// invoiceTaxRate returns the tax rate and pricing mode for a new invoice of a client. Clients that are
+// exempt themselves or through their service package are not taxed.
+func (s *BillingService) invoiceTaxRate(ctx context.Context, tenantID, clientID uuid.UUID) (float64, bool) {
+	if s.tenantRepo == nil {
+		return 0, false
+	}
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		log.Warn().Err(err).Str("tenant_id", tenantID.String()).Msg("Failed to load tax settings, invoicing without tax")
+		return 0, false
+	}
+	tax := readTaxSettings(t.Settings)
+	if !tax.Enabled || tax.Rate <= 0 || containsUUID(tax.ExemptClientIDs, clientID) {
+		return 0, false
+	}
+	if len(tax.ExemptPackageIDs) > 0 && s.clientRepo != nil {
+		c, err := s.clientRepo.GetByID(ctx, tenantID, clientID)
+		if err == nil && c.ServicePackageID != nil && containsUUID(tax.ExemptPackageIDs, *c.ServicePackageID) {
+			return 0, false
+		}
+	}
+	return tax.Rate, tax.Inclusive
+}
+
+// lineAmountFor converts an amount that includes tax into a line amount of inv (lines of tax-exclusive
+// invoices are net of tax)
+func lineAmountFor(inv *billing.Invoice, gross int64) int64 {
+	if inv.TaxInclusive || inv.TaxRate <= 0 {
+		return gross
+	}
+	return int64(math.Round(float64(gross) * 100 / (100 + inv.TaxRate)))
+}
+
+// grossAmountFor is the inverse of lineAmountFor: a line amount of inv including its tax
+func grossAmountFor(inv *billing.Invoice, line int64) int64 {
+	if inv.TaxInclusive || inv.TaxRate <= 0 {
+		return line
+	}
+	return int64(math.Round(float64(line) * (100 + inv.TaxRate) / 100))
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"rrnet/internal/domain/billing"
+)
+
+func TestComputeTax(t *testing.T) {
+	cases := []struct {
+		name               string
+		subtotal, discount int64
+		rate               float64
+		inclusive          bool
+		base, tax, total   int64
+	}{
+		{"no tax", 150000, 0, 0, false, 150000, 0, 150000},
+		{"exclusive", 150000, 0, 11, false, 150000, 16500, 166500},
+		{"exclusive after discount", 150000, 15000, 11, false, 135000, 14850, 149850},
+		{"inclusive", 111000, 0, 11, true, 100000, 11000, 111000},
+		{"inclusive rounds", 150000, 0, 11, true, 135135, 14865, 150000},
+		{"credit beyond subtotal", 10000, 20000, 11, false, -10000, 0, -10000},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			base, tax, total := billing.ComputeTax(c.subtotal, c.discount, c.rate, c.inclusive)
+			assert.Equal(t, []int64{c.base, c.tax, c.total}, []int64{base, tax, total})
+		})
+	}
+}
+
+func TestLineAmountFor(t *testing.T) {
+	exclusive := &billing.Invoice{TaxRate: 11}
+	assert.Equal(t, int64(100000), lineAmountFor(exclusive, 111000))
+	assert.Equal(t, int64(111000), grossAmountFor(exclusive, 100000))
+
+	inclusive := &billing.Invoice{TaxRate: 11, TaxInclusive: true}
+	assert.Equal(t, int64(111000), lineAmountFor(inclusive, 111000))
+	assert.Equal(t, int64(50000), grossAmountFor(&billing.Invoice{}, 50000))
+}
+
+func TestReadTaxSettings(t *testing.T) {
+	pkg := uuid.New()
+	got := readTaxSettings(map[string]interface{}{
+		"tax": map[string]interface{}{
+			"enabled":            true,
+			"rate":               11.0,
+			"inclusive":          true,
+			"tax_id":             "01.234.567.8-901.000",
+			"exempt_package_ids": []interface{}{pkg.String(), "not-a-uuid"},
+		},
+	})
+	assert.True(t, got.Enabled)
+	assert.Equal(t, "PPN", got.Name)
+	assert.Equal(t, 11.0, got.Rate)
+	assert.True(t, got.Inclusive)
+	assert.Equal(t, []uuid.UUID{pkg}, got.ExemptPackageIDs)
+	assert.Empty(t, got.ExemptClientIDs)
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/domain/billing"
+)
+
+var (
+	ErrUniqueCodeModeInvalid = errors.New("unique code mode must be total or payable")
+	ErrUniqueCodeMaxInvalid  = errors.New("unique code maximum must be between 9 and 999")
+)
+
+// UniqueCodeSettings configures the "kode unik" of new invoices (stored in tenant settings under
+// "unique_code"). With mode "total" the code is added to the invoice total; with mode "payable" it
+// is only added to the amount to transfer and the extra is credited to the client balance.
+type UniqueCodeSettings struct {
+	Enabled bool                   `json:"enabled"`
+	Mode    billing.UniqueCodeMode `json:"mode"`
+	MaxCode int                    `json:"max_code"` // highest code handed out, at most 999
+}
+
+func readUniqueCodeSettings(settings map[string]interface{}) UniqueCodeSettings {
+	out := UniqueCodeSettings{Mode: billing.UniqueCodeAddToTotal, MaxCode: billing.MaxUniqueCode}
+	raw, ok := settings["unique_code"].(map[string]interface{})
+	if !ok || raw == nil {
+		return out
+	}
+	if v, ok := raw["enabled"].(bool); ok {
+		out.Enabled = v
+	}
+	if v, ok := raw["mode"].(string); ok && billing.UniqueCodeMode(v) == billing.UniqueCodePayable {
+		out.Mode = billing.UniqueCodePayable
+	}
+	if v, ok := raw["max_code"].(float64); ok && v >= 9 && v <= billing.MaxUniqueCode {
+		out.MaxCode = int(v)
+	}
+	return out
+}
+
+func (s *BillingService) GetUniqueCodeSettings(ctx context.Context, tenantID uuid.UUID) (*UniqueCodeSettings, error) {
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	out := readUniqueCodeSettings(t.Settings)
+	return &out, nil
+}
+
+func (s *BillingService) UpdateUniqueCodeSettings(ctx context.Context, tenantID uuid.UUID, in UniqueCodeSettings) (*UniqueCodeSettings, error) {
+	if in.Mode == "" {
+		in.Mode = billing.UniqueCodeAddToTotal
+	}
+	if in.Mode != billing.UniqueCodeAddToTotal && in.Mode != billing.UniqueCodePayable {
+		return nil, ErrUniqueCodeModeInvalid
+	}
+	if in.MaxCode == 0 {
+		in.MaxCode = billing.MaxUniqueCode
+	}
+	if in.MaxCode < 9 || in.MaxCode > billing.MaxUniqueCode {
+		return nil, ErrUniqueCodeMaxInvalid
+	}
+
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if t.Settings == nil {
+		t.Settings = map[string]interface{}{}
+	}
+	t.Settings["unique_code"] = map[string]interface{}{
+		"enabled":  in.Enabled,
+		"mode":     string(in.Mode),
+		"max_code": in.MaxCode,
+	}
+	if err := s.tenantRepo.UpdateSettings(ctx, tenantID, t.Settings); err != nil {
+		return nil, err
+	}
+	out := readUniqueCodeSettings(t.Settings)
+	return &out, nil
+}
+
+// assignUniqueCode gives a new invoice its unique code when the tenant enabled them. A missing code
+// only makes bank matching harder, so failures are logged and the invoice is kept.
+func (s *BillingService) assignUniqueCode(ctx context.Context, tenantID uuid.UUID, inv *billing.Invoice) {
+	if s.tenantRepo == nil || inv.TotalAmount <= 0 || inv.UniqueCode != nil {
+		return
+	}
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		log.Warn().Err(err).Str("tenant_id", tenantID.String()).Msg("Failed to load unique code settings")
+		return
+	}
+	cfg := readUniqueCodeSettings(t.Settings)
+	if !cfg.Enabled {
+		return
+	}
+	inTotal := cfg.Mode == billing.UniqueCodeAddToTotal
+	code, err := s.invoiceRepo.AssignUniqueCode(ctx, tenantID, inv.ID, cfg.MaxCode, inTotal)
+	if err != nil {
+		log.Warn().Err(err).Str("invoice_id", inv.ID.String()).Msg("Failed to assign unique code")
+		return
+	}
+	inv.UniqueCode = &code
+	inv.UniqueCodeInTotal = inTotal
+	if inTotal {
+		inv.TotalAmount += int64(code)
+	}
+}
+
+// uniqueCodeText formats a unique code for messages ("" when the invoice has none)
+func uniqueCodeText(code *int) string {
+	if code == nil {
+		return ""
+	}
+	return fmt.Sprintf("%03d", *code)
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"rrnet/internal/domain/billing"
+)
+
+func TestReadUniqueCodeSettings(t *testing.T) {
+	assert.Equal(t, UniqueCodeSettings{Mode: billing.UniqueCodeAddToTotal, MaxCode: billing.MaxUniqueCode}, readUniqueCodeSettings(nil))
+
+	s := readUniqueCodeSettings(map[string]interface{}{
+		"unique_code": map[string]interface{}{"enabled": true, "mode": "payable", "max_code": float64(99)},
+	})
+	assert.Equal(t, UniqueCodeSettings{Enabled: true, Mode: billing.UniqueCodePayable, MaxCode: 99}, s)
+
+	// Unknown modes and a maximum out of 9..999 fall back to the defaults
+	for _, max := range []float64{0, 8, 1000} {
+		s = readUniqueCodeSettings(map[string]interface{}{
+			"unique_code": map[string]interface{}{"enabled": true, "mode": "random", "max_code": max},
+		})
+		assert.Equal(t, billing.UniqueCodeAddToTotal, s.Mode)
+		assert.Equal(t, billing.MaxUniqueCode, s.MaxCode)
+	}
+}
+
+func TestUpdateUniqueCodeSettingsValidation(t *testing.T) {
+	svc := &BillingService{}
+	update := func(in UniqueCodeSettings) error {
+		_, err := svc.UpdateUniqueCodeSettings(context.Background(), uuid.New(), in)
+		return err
+	}
+
+	assert.ErrorIs(t, update(UniqueCodeSettings{Mode: "random"}), ErrUniqueCodeModeInvalid)
+	assert.ErrorIs(t, update(UniqueCodeSettings{Mode: billing.UniqueCodePayable, MaxCode: 8}), ErrUniqueCodeMaxInvalid)
+	assert.ErrorIs(t, update(UniqueCodeSettings{MaxCode: billing.MaxUniqueCode + 1}), ErrUniqueCodeMaxInvalid)
+	assert.ErrorIs(t, update(UniqueCodeSettings{MaxCode: -1}), ErrUniqueCodeMaxInvalid)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 
+	"rrnet/internal/domain/billing"
 	"rrnet/internal/domain/client"
 	"rrnet/internal/repository"
 	"rrnet/pkg/utils"
@@ -33,6 +34,8 @@ type ClientService struct {
 	voucherService     *VoucherService
 	featureResolver    *FeatureResolver
 	limitResolver      *LimitResolver
+	isolirService      *IsolirService
+	billingService     *BillingService
 	encKey32           [32]byte
 }
 
@@ -44,6 +47,8 @@ func NewClientService(
 	voucherService *VoucherService,
 	featureResolver *FeatureResolver,
 	limitResolver *LimitResolver,
+	isolirService *IsolirService,
+	billingService *BillingService,
 	encryptionSecret string,
 ) *ClientService {
 	return &ClientService{
@@ -53,6 +58,8 @@ func NewClientService(
 		voucherService:     voucherService,
 		featureResolver:    featureResolver,
 		limitResolver:      limitResolver,
+		isolirService:      isolirService,
+		billingService:     billingService,
 		encKey32:           utils.DeriveKey32(encryptionSecret),
 	}
 }
@@ -91,6 +98,14 @@ type CreateClientRequest struct {
 	PaymentTempoOption     *string    `json:"payment_tempo_option,omitempty"` // default|template|manual
 	PaymentDueDay          *int       `json:"payment_due_day,omitempty"`      // 1-31
 	PaymentTempoTemplateID *uuid.UUID `json:"payment_tempo_template_id,omitempty"`
+
+	// Billing mode override: postpaid|prepaid; empty follows the service package
+	BillingMode *string `json:"billing_mode,omitempty"`
+
+	// Billing cycle override in months (1, 3, 6, 12); 0 follows the service package. Cycles align to
+	// the anchor's month (default: the month the client is created).
+	BillingCycleMonths *int       `json:"billing_cycle_months,omitempty"`
+	BillingCycleAnchor *time.Time `json:"billing_cycle_anchor,omitempty"`
 }
 
 // ClientDTO represents client data for API responses
@@ -117,6 +132,10 @@ type ClientDTO struct {
 	PaymentTempoOption     string                `json:"payment_tempo_option"`
 	PaymentDueDay          int                   `json:"payment_due_day"`
 	PaymentTempoTemplateID *uuid.UUID            `json:"payment_tempo_template_id,omitempty"`
+	BillingMode            *billing.BillingMode  `json:"billing_mode,omitempty"`
+	ActiveUntil            *time.Time            `json:"active_until,omitempty"`
+	BillingCycleMonths     *int                  `json:"billing_cycle_months,omitempty"`
+	BillingCycleAnchor     *time.Time            `json:"billing_cycle_anchor,omitempty"`
 	Status                 client.Status         `json:"status"`
 	IsolirReason           *string               `json:"isolir_reason,omitempty"`
 	IsolirAt               *time.Time            `json:"isolir_at,omitempty"`
@@ -255,6 +274,15 @@ func (s *ClientService) Create(ctx context.Context, tenantID uuid.UUID, req *Cre
 		}
 	}
 
+	billingMode, err := parseClientBillingMode(req.BillingMode)
+	if err != nil {
+		return nil, err
+	}
+	cycleMonths, err := parseClientBillingCycle(req.BillingCycleMonths)
+	if err != nil {
+		return nil, err
+	}
+
 	c := &client.Client{
 		ID:                     uuid.New(),
 		TenantID:               tenantID,
@@ -285,6 +313,9 @@ func (s *ClientService) Create(ctx context.Context, tenantID uuid.UUID, req *Cre
 		PaymentTempoOption:     paymentOption,
 		PaymentDueDay:          paymentDueDay,
 		PaymentTempoTemplateID: paymentTemplateID,
+		BillingMode:            billingMode,
+		BillingCycleMonths:     cycleMonths,
+		BillingCycleAnchor:     dateOnly(req.BillingCycleAnchor),
 		Status:                 client.StatusActive,
 		Metadata:               metadata,
 		CreatedAt:              now,
@@ -409,6 +440,13 @@ type UpdateClientRequest struct {
 	PaymentTempoOption     *string    `json:"payment_tempo_option,omitempty"`
 	PaymentDueDay          *int       `json:"payment_due_day,omitempty"`
 	PaymentTempoTemplateID *uuid.UUID `json:"payment_tempo_template_id,omitempty"`
+
+	// Billing mode override: postpaid|prepaid; empty follows the service package, nil keeps it
+	BillingMode *string `json:"billing_mode,omitempty"`
+
+	// Billing cycle override in months; 0 follows the service package, nil keeps it
+	BillingCycleMonths *int       `json:"billing_cycle_months,omitempty"`
+	BillingCycleAnchor *time.Time `json:"billing_cycle_anchor,omitempty"`
 }
 
 // Update updates a client
@@ -418,6 +456,7 @@ func (s *ClientService) Update(ctx context.Context, tenantID, clientID uuid.UUID
 	if err != nil {
 		return nil, err
 	}
+	before := *c
 
 	// Update fields
 	if req.Name != "" {
@@ -457,6 +496,20 @@ func (s *ClientService) Update(ctx context.Context, tenantID, clientID uuid.UUID
 		}
 	}
 
+	if req.BillingMode != nil {
+		if c.BillingMode, err = parseClientBillingMode(req.BillingMode); err != nil {
+			return nil, err
+		}
+	}
+	if req.BillingCycleMonths != nil {
+		if c.BillingCycleMonths, err = parseClientBillingCycle(req.BillingCycleMonths); err != nil {
+			return nil, err
+		}
+	}
+	if req.BillingCycleAnchor != nil {
+		c.BillingCycleAnchor = dateOnly(req.BillingCycleAnchor)
+	}
+
 	if req.ServicePackageID == (uuid.UUID{}) {
 		return nil, errors.New("service_package_id is required")
 	}
@@ -520,13 +573,31 @@ func (s *ClientService) Update(ctx context.Context, tenantID, clientID uuid.UUID
 		return nil, err
 	}
 
+	// Mid-period price changes (package, devices, cycle) are prorated on the next invoice
+	if s.billingService != nil && c.Status != client.StatusTerminated && billedPriceChanged(&before, c) {
+		if err := s.billingService.ProratePackageChange(ctx, tenantID, &before, c, time.Now()); err != nil {
+			log.Warn().Err(err).Str("client_id", clientID.String()).Msg("Failed to prorate package change")
+		}
+	}
+
 	return s.toDTO(c), nil
 }
 
+// billedPriceChanged reports whether an update touched the fields the billed price is computed from:
+// the package, the device count, the legacy monthly fee or the billing cycle override (nil and zero
+// mean the same: no package, one device, the package's cycle)
+func billedPriceChanged(before, after *client.Client) bool {
+	return utils.Value(before.ServicePackageID) != utils.Value(after.ServicePackageID) ||
+		utils.Value(before.DeviceCount) != utils.Value(after.DeviceCount) ||
+		utils.Value(before.BillingCycleMonths) != utils.Value(after.BillingCycleMonths) ||
+		before.MonthlyFee != after.MonthlyFee
+}
+
 // ChangeStatusRequest represents request to change client status
 type ChangeStatusRequest struct {
-	Status client.Status `json:"status"`
-	Reason *string       `json:"reason,omitempty"`
+	Status     client.Status `json:"status"`
+	Reason     *string       `json:"reason,omitempty"`
+	ExecutedBy *uuid.UUID    `json:"-"` // set by handler from auth context
 }
 
 // ChangeStatus changes client status
@@ -542,11 +613,35 @@ func (s *ClientService) ChangeStatus(ctx context.Context, tenantID, clientID uui
 		return nil, ErrInvalidStatusChange
 	}
 
+	// Isolir transitions go through the isolir engine so the router is updated and the action is logged
+	if s.isolirService != nil && (req.Status == client.StatusIsolir || (c.Status == client.StatusIsolir && req.Status == client.StatusActive)) {
+		isolirReq := IsolateRequest{ExecutedBy: req.ExecutedBy}
+		if req.Reason != nil {
+			isolirReq.Reason = *req.Reason
+		}
+		if req.Status == client.StatusIsolir {
+			_, err = s.isolirService.Isolate(ctx, tenantID, clientID, isolirReq)
+		} else {
+			_, err = s.isolirService.Reactivate(ctx, tenantID, clientID, isolirReq)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return s.GetByID(ctx, tenantID, clientID)
+	}
+
 	// Update status
 	if err := s.clientRepo.UpdateStatus(ctx, tenantID, clientID, req.Status, req.Reason); err != nil {
 		return nil, err
 	}
 
+	// Credit the unused days of the current period
+	if req.Status == client.StatusTerminated && s.billingService != nil {
+		if err := s.billingService.ProrateTermination(ctx, tenantID, c, time.Now()); err != nil {
+			log.Warn().Err(err).Str("client_id", clientID.String()).Msg("Failed to prorate termination")
+		}
+	}
+
 	// Return updated client
 	return s.GetByID(ctx, tenantID, clientID)
 }
@@ -574,6 +669,39 @@ func (s *ClientService) GetStats(ctx context.Context, tenantID uuid.UUID) (map[s
 	}, nil
 }
 
+// parseClientBillingMode validates a billing mode override; nil or empty follows the package
+func parseClientBillingMode(v *string) (*billing.BillingMode, error) {
+	if v == nil || strings.TrimSpace(*v) == "" {
+		return nil, nil
+	}
+	mode := billing.BillingMode(strings.TrimSpace(*v))
+	if !mode.IsValid() {
+		return nil, ErrBillingModeInvalid
+	}
+	return &mode, nil
+}
+
+// parseClientBillingCycle validates a billing cycle override; nil or 0 follows the package
+func parseClientBillingCycle(v *int) (*int, error) {
+	if v == nil || *v == 0 {
+		return nil, nil
+	}
+	if !billing.ValidCycleMonths(*v) {
+		return nil, ErrBillingCycleInvalid
+	}
+	months := *v
+	return &months, nil
+}
+
+// dateOnly truncates a date to midnight local time
+func dateOnly(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	d := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+	return &d
+}
+
 // toDTO converts client entity to DTO
 func (s *ClientService) toDTO(c *client.Client) *ClientDTO {
 	return &ClientDTO{
@@ -599,6 +727,10 @@ func (s *ClientService) toDTO(c *client.Client) *ClientDTO {
 		PaymentTempoOption:     c.PaymentTempoOption,
 		PaymentDueDay:          c.PaymentDueDay,
 		PaymentTempoTemplateID: c.PaymentTempoTemplateID,
+		BillingMode:            c.BillingMode,
+		ActiveUntil:            c.ActiveUntil,
+		BillingCycleMonths:     c.BillingCycleMonths,
+		BillingCycleAnchor:     c.BillingCycleAnchor,
 		Status:                 c.Status,
 		IsolirReason:           c.IsolirReason,
 		IsolirAt:               c.IsolirAt,
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"rrnet/internal/domain/collector"
+	"rrnet/internal/repository"
+)
+
+const (
+	// routeClusterRadiusKm joins clients closer than this into one area
+	routeClusterRadiusKm = 1.0
+	// routeRoadFactor turns straight-line distance into an estimate of the road distance
+	routeRoadFactor = 1.3
+	// routeSpeedKmh is the average speed of a collector on a motorbike in residential streets
+	routeSpeedKmh = 20.0
+	// routeStopMinutes is the time spent at each client
+	routeStopMinutes = 5
+)
+
+// haversineKm is the great-circle distance between two positions
+func haversineKm(a, b collector.Point) float64 {
+	const earthRadiusKm = 6371.0
+	lat1 := a.Latitude * math.Pi / 180
+	lat2 := b.Latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := (b.Longitude - a.Longitude) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// clusterStops groups stops by area: stops on the same ODP, or within radiusKm of each other
+// (transitively), share a cluster. Clusters are numbered from 0 in order of first stop.
+func clusterStops(points []collector.Point, odps []*uuid.UUID, radiusKm float64) []int {
+	parent := make([]int, len(points))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) {
+		if ri, rj := find(i), find(j); ri != rj {
+			parent[rj] = ri
+		}
+	}
+	for i := range points {
+		for j := i + 1; j < len(points); j++ {
+			sameODP := odps[i] != nil && odps[j] != nil && *odps[i] == *odps[j]
+			if sameODP || haversineKm(points[i], points[j]) <= radiusKm {
+				union(i, j)
+			}
+		}
+	}
+
+	clusters := make([]int, len(points))
+	ids := map[int]int{}
+	for i := range points {
+		root := find(i)
+		id, ok := ids[root]
+		if !ok {
+			id = len(ids)
+			ids[root] = id
+		}
+		clusters[i] = id
+	}
+	return clusters
+}
+
+// nearestNeighbourOrder visits the nearest remaining stop from start, and finishes the area of
+// that stop (again nearest first) before moving to the next area
+func nearestNeighbourOrder(start collector.Point, points []collector.Point, clusters []int) []int {
+	visited := make([]bool, len(points))
+	order := make([]int, 0, len(points))
+	current := start
+	nearest := func(inCluster int) int {
+		best, bestKm := -1, math.MaxFloat64
+		for i, p := range points {
+			if visited[i] || (inCluster >= 0 && clusters[i] != inCluster) {
+				continue
+			}
+			if km := haversineKm(current, p); km < bestKm {
+				best, bestKm = i, km
+			}
+		}
+		return best
+	}
+	for len(order) < len(points) {
+		next := nearest(-1)
+		cluster := clusters[next]
+		for ; next >= 0; next = nearest(cluster) {
+			visited[next] = true
+			order = append(order, next)
+			current = points[next]
+		}
+	}
+	return order
+}
+
+// twoOptSegment improves order[lo..hi] by reversing sub-paths while that shortens the open path
+// from start. Stops outside the segment stay where they are, so areas keep their sequence.
+func twoOptSegment(start collector.Point, points []collector.Point, order []int, lo, hi int) {
+	at := func(pos int) collector.Point {
+		if pos < 0 {
+			return start
+		}
+		return points[order[pos]]
+	}
+	for improved, rounds := true, 0; improved && rounds < 50; rounds++ {
+		improved = false
+		for i := lo; i < hi; i++ {
+			for k := i + 1; k <= hi; k++ {
+				before := haversineKm(at(i-1), at(i))
+				after := haversineKm(at(i-1), at(k))
+				if k+1 < len(order) {
+					before += haversineKm(at(k), at(k+1))
+					after += haversineKm(at(i), at(k+1))
+				}
+				if after < before-1e-9 {
+					for a, b := i, k; a < b; a, b = a+1, b-1 {
+						order[a], order[b] = order[b], order[a]
+					}
+					improved = true
+				}
+			}
+		}
+	}
+}
+
+// planStops orders the stops from start: nearest neighbour by area, then 2-opt within each area
+func planStops(start collector.Point, points []collector.Point, clusters []int) []int {
+	order := nearestNeighbourOrder(start, points, clusters)
+	for lo := 0; lo < len(order); {
+		hi := lo
+		for hi+1 < len(order) && clusters[order[hi+1]] == clusters[order[lo]] {
+			hi++
+		}
+		twoOptSegment(start, points, order, lo, hi)
+		lo = hi + 1
+	}
+	return order
+}
+
+// routeGeoJSON renders the route as a FeatureCollection: the start, one point per stop and the
+// path as a LineString. GeoJSON positions are [longitude, latitude].
+func routeGeoJSON(start *collector.Point, stops []*collector.RouteStop) map[string]any {
+	features := []any{}
+	line := [][]float64{}
+	if start != nil {
+		line = append(line, []float64{start.Longitude, start.Latitude})
+		features = append(features, map[string]any{
+			"type":       "Feature",
+			"geometry":   map[string]any{"type": "Point", "coordinates": []float64{start.Longitude, start.Latitude}},
+			"properties": map[string]any{"kind": "start"},
+		})
+	}
+	for _, s := range stops {
+		line = append(line, []float64{s.Longitude, s.Latitude})
+		features = append(features, map[string]any{
+			"type":     "Feature",
+			"geometry": map[string]any{"type": "Point", "coordinates": []float64{s.Longitude, s.Latitude}},
+			"properties": map[string]any{
+				"kind":          "stop",
+				"order":         s.Order,
+				"cluster":       s.Cluster,
+				"assignment_id": s.AssignmentID,
+				"client_code":   s.ClientCode,
+				"client_name":   s.ClientName,
+				"amount":        s.Amount,
+			},
+		})
+	}
+	if len(line) > 1 {
+		features = append(features, map[string]any{
+			"type":       "Feature",
+			"geometry":   map[string]any{"type": "LineString", "coordinates": line},
+			"properties": map[string]any{"kind": "route"},
+		})
+	}
+	return map[string]any{"type": "FeatureCollection", "features": features}
+}
+
+// buildRoute plans the located assignments from start; without a start the route begins at the
+// client with the oldest due date
+func buildRoute(route *collector.Route, located []*collector.Assignment) {
+	route.Stops = []*collector.RouteStop{}
+	if len(located) > 0 {
+		sort.SliceStable(located, func(i, j int) bool { return located[i].InvoiceDueDate.Before(located[j].InvoiceDueDate) })
+		points := make([]collector.Point, len(located))
+		odps := make([]*uuid.UUID, len(located))
+		for i, a := range located {
+			points[i] = collector.Point{Latitude: *a.Latitude, Longitude: *a.Longitude}
+			odps[i] = a.ODPID
+		}
+		start := points[0]
+		if route.Start != nil {
+			start = *route.Start
+		}
+		clusters := clusterStops(points, odps, routeClusterRadiusKm)
+
+		prev := start
+		var total float64
+		for n, i := range planStops(start, points, clusters) {
+			a := located[i]
+			leg := haversineKm(prev, points[i]) * routeRoadFactor
+			total += leg
+			route.Stops = append(route.Stops, &collector.RouteStop{
+				Order:         n + 1,
+				Cluster:       clusters[i] + 1,
+				AssignmentID:  a.ID,
+				InvoiceID:     a.InvoiceID,
+				InvoiceNumber: a.InvoiceNumber,
+				ClientID:      a.ClientID,
+				ClientCode:    a.ClientCode,
+				ClientName:    a.ClientName,
+				ClientPhone:   a.ClientPhone,
+				ClientAddress: a.ClientAddress,
+				ODPID:         a.ODPID,
+				Amount:        a.InvoiceRemaining,
+				Point:         points[i],
+				LegKm:         math.Round(leg*100) / 100,
+				CumulativeKm:  math.Round(total*100) / 100,
+			})
+			prev = points[i]
+		}
+		for _, c := range clusters {
+			route.Clusters = max(route.Clusters, c+1)
+		}
+		route.TotalDistanceKm = math.Round(total*100) / 100
+		route.EstimatedMinutes = int(math.Round(total/routeSpeedKmh*60)) + routeStopMinutes*len(route.Stops)
+	}
+	route.GeoJSON = routeGeoJSON(route.Start, route.Stops)
+}
+
+// PlanRoute plans the day of a collector over the open assignments. Assignments visited without
+// success that day are left out, and the route starts at start, else at the last visit logged
+// that day, so every logged visit re-plans the rest of the day.
+func (s *CollectorService) PlanRoute(ctx context.Context, tenantID, collectorID uuid.UUID, day time.Time, start *collector.Point) (*collector.Route, error) {
+	dayStart := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	status := collector.AssignmentAssigned
+	open, _, err := s.collectorRepo.ListAssignments(ctx, repository.CollectorAssignmentFilter{
+		TenantID:    tenantID,
+		CollectorID: &collectorID,
+		Status:      &status,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	route := &collector.Route{
+		CollectorID:  collectorID,
+		Date:         dayStart,
+		Start:        start,
+		StartSource:  collector.RouteStartRequest,
+		Unlocated:    []*collector.Assignment{},
+		VisitedToday: []*collector.Assignment{},
+	}
+	var located []*collector.Assignment
+	for _, a := range open {
+		switch {
+		case a.InvoiceRemaining <= 0:
+			// Settled another way; nothing to collect
+		case a.LastVisitAt != nil && !a.LastVisitAt.Before(dayStart) && a.LastVisitAt.Before(dayEnd):
+			route.VisitedToday = append(route.VisitedToday, a)
+		case a.Latitude == nil || a.Longitude == nil:
+			route.Unlocated = append(route.Unlocated, a)
+		default:
+			located = append(located, a)
+		}
+	}
+
+	if route.Start == nil {
+		last, err := s.collectorRepo.LatestVisit(ctx, tenantID, collectorID, dayStart, dayEnd)
+		if err != nil {
+			return nil, err
+		}
+		if last != nil {
+			route.Start = &collector.Point{Latitude: last.Latitude, Longitude: last.Longitude}
+			route.StartSource = collector.RouteStartLastVisit
+		} else {
+			route.StartSource = collector.RouteStartFirstStop
+		}
+	}
+
+	buildRoute(route, located)
+	return route, nil
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rrnet/internal/domain/collector"
+)
+
+func TestHaversineKm(t *testing.T) {
+	// Monas to Bundaran HI, about 2.2 km
+	km := haversineKm(collector.Point{Latitude: -6.1754, Longitude: 106.8272}, collector.Point{Latitude: -6.1950, Longitude: 106.8231})
+	assert.InDelta(t, 2.2, km, 0.1)
+	assert.Zero(t, haversineKm(collector.Point{Latitude: 1, Longitude: 2}, collector.Point{Latitude: 1, Longitude: 2}))
+}
+
+func TestClusterStops(t *testing.T) {
+	odp := uuid.New()
+	points := []collector.Point{
+		{Latitude: -6.2000, Longitude: 106.8000},
+		{Latitude: -6.2030, Longitude: 106.8020}, // ~0.4 km from the first
+		{Latitude: -6.3000, Longitude: 106.9000}, // far away, but on the same ODP as the last
+		{Latitude: -6.5000, Longitude: 107.0000},
+		{Latitude: -6.7000, Longitude: 107.2000},
+	}
+	odps := []*uuid.UUID{nil, nil, &odp, nil, &odp}
+	assert.Equal(t, []int{0, 0, 1, 2, 1}, clusterStops(points, odps, 1.0))
+}
+
+func TestPlanStopsFinishesAreaFirst(t *testing.T) {
+	// A1 and A2 hang on the same ODP, 2 km apart; B is 1.5 km from A1. Plain nearest neighbour
+	// would go A1, B, A2; the route finishes the ODP's area first.
+	odp := uuid.New()
+	points := []collector.Point{
+		{Latitude: 0, Longitude: 0.0145},    // B
+		{Latitude: 0.018, Longitude: 0.001}, // A2
+		{Latitude: 0, Longitude: 0.001},     // A1
+	}
+	clusters := clusterStops(points, []*uuid.UUID{nil, &odp, &odp}, 1.0)
+	order := planStops(collector.Point{Latitude: 0, Longitude: 0}, points, clusters)
+	assert.Equal(t, []int{2, 1, 0}, order)
+}
+
+func TestTwoOptRemovesCrossing(t *testing.T) {
+	// Order 0,2,1,3 goes back and forth along the street; 2-opt straightens it
+	points := []collector.Point{
+		{Latitude: 0, Longitude: 0.001},
+		{Latitude: 0, Longitude: 0.002},
+		{Latitude: 0, Longitude: 0.003},
+		{Latitude: 0, Longitude: 0.004},
+	}
+	order := []int{0, 2, 1, 3}
+	twoOptSegment(collector.Point{}, points, order, 0, 3)
+	assert.Equal(t, []int{0, 1, 2, 3}, order)
+}
+
+func TestBuildRoute(t *testing.T) {
+	lat := func(v float64) *float64 { return &v }
+	due := time.Date(2025, time.June, 10, 0, 0, 0, 0, time.UTC)
+	near := &collector.Assignment{ID: uuid.New(), ClientName: "Near", Latitude: lat(-6.2010), Longitude: lat(106.8000), InvoiceDueDate: due, InvoiceRemaining: 150000}
+	far := &collector.Assignment{ID: uuid.New(), ClientName: "Far", Latitude: lat(-6.2300), Longitude: lat(106.8000), InvoiceDueDate: due.AddDate(0, -1, 0), InvoiceRemaining: 200000}
+
+	// From the collector's position the nearest client comes first
+	route := &collector.Route{Start: &collector.Point{Latitude: -6.2000, Longitude: 106.8000}}
+	buildRoute(route, []*collector.Assignment{far, near})
+	require.Len(t, route.Stops, 2)
+	assert.Equal(t, "Near", route.Stops[0].ClientName)
+	assert.Equal(t, 2, route.Stops[1].Order)
+	assert.Equal(t, 2, route.Clusters)
+	assert.InDelta(t, 0.11*routeRoadFactor, route.Stops[0].LegKm, 0.01)
+	assert.InDelta(t, route.Stops[1].CumulativeKm, route.TotalDistanceKm, 0.001)
+	assert.Equal(t, int64(200000), route.Stops[1].Amount)
+
+	features := route.GeoJSON["features"].([]any)
+	assert.Len(t, features, 4) // start, two stops, the path
+	path := features[3].(map[string]any)["geometry"].(map[string]any)
+	assert.Equal(t, "LineString", path["type"])
+	assert.Equal(t, []float64{106.8000, -6.2000}, path["coordinates"].([][]float64)[0])
+
+	// Without a position the route starts at the oldest invoice
+	route = &collector.Route{}
+	buildRoute(route, []*collector.Assignment{near, far})
+	assert.Equal(t, "Far", route.Stops[0].ClientName)
+	assert.Zero(t, route.Stops[0].LegKm)
+
+	route = &collector.Route{}
+	buildRoute(route, nil)
+	assert.Empty(t, route.Stops)
+	assert.Equal(t, "FeatureCollection", route.GeoJSON["type"])
+}
@@ -0,0 +1,474 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/collector"
+	"rrnet/internal/domain/user"
+	"rrnet/internal/rbac"
+	"rrnet/internal/repository"
+)
+
+var (
+	ErrCollectorInvalid          = errors.New("collector must be an active user with the collector role")
+	ErrCollectorInvoicesRequired = errors.New("invoice_ids is required")
+	ErrCollectorInvoiceNotOpen   = errors.New("only pending or overdue invoices with a remaining amount can be collected")
+	ErrCollectorAssignmentClosed = errors.New("assignment is no longer open for this action")
+	ErrCollectorVisitInvalid     = errors.New("visit needs a result (success or failed) and a GPS position")
+	ErrCollectorVisitReason      = errors.New("reason is required for a failed visit")
+	ErrCollectorNothingCollected = errors.New("deposit needs at least one collected assignment")
+	ErrCollectorDepositReason    = errors.New("reason is required to reject a deposit")
+)
+
+// AssignInvoicesRequest hands invoices to a collector
+type AssignInvoicesRequest struct {
+	CollectorID uuid.UUID   `json:"collector_id"`
+	InvoiceIDs  []uuid.UUID `json:"invoice_ids"`
+	Notes       *string     `json:"notes,omitempty"`
+}
+
+// LogVisitRequest is what the collector reports from the field. The collected amount is not part
+// of it: a successful visit collects the remaining amount of the invoice.
+type LogVisitRequest struct {
+	Result    collector.VisitResult `json:"result"`
+	Latitude  *float64              `json:"latitude"`
+	Longitude *float64              `json:"longitude"`
+	Reason    *string               `json:"reason,omitempty"`
+	Notes     *string               `json:"notes,omitempty"`
+}
+
+// ReportDepositRequest bundles collected assignments; without AssignmentIDs every collected
+// assignment of the collector is included
+type ReportDepositRequest struct {
+	AssignmentIDs []uuid.UUID `json:"assignment_ids,omitempty"`
+	Notes         *string     `json:"notes,omitempty"`
+}
+
+// CollectorService runs the cash workflow of collectors: the office assigns invoices, the
+// collector logs visits and reports deposits, and finance confirms deposits into payments.
+// Methods taking a collectorID only see that collector's assignments and deposits.
+type CollectorService struct {
+	collectorRepo  *repository.CollectorRepository
+	invoiceRepo    *repository.InvoiceRepository
+	userRepo       *repository.UserRepository
+	billingService *BillingService
+	ledger         *LedgerService
+}
+
+func NewCollectorService(
+	collectorRepo *repository.CollectorRepository,
+	invoiceRepo *repository.InvoiceRepository,
+	userRepo *repository.UserRepository,
+	billingService *BillingService,
+) *CollectorService {
+	return &CollectorService{
+		collectorRepo:  collectorRepo,
+		invoiceRepo:    invoiceRepo,
+		userRepo:       userRepo,
+		billingService: billingService,
+	}
+}
+
+// SetLedger posts cash taken at visits to the collector float; the confirmed deposit clears it
+func (s *CollectorService) SetLedger(ledger *LedgerService) {
+	s.ledger = ledger
+}
+
+// invoiceCollectable reports whether a collector can still take money for the invoice
+func invoiceCollectable(inv *billing.Invoice) bool {
+	return (inv.Status == billing.InvoiceStatusPending || inv.Status == billing.InvoiceStatusOverdue) &&
+		inv.RemainingAmount() > 0
+}
+
+func (s *CollectorService) tenantInvoice(ctx context.Context, tenantID, invoiceID uuid.UUID) (*billing.Invoice, error) {
+	inv, err := s.invoiceRepo.GetByID(ctx, invoiceID)
+	if err != nil || inv.TenantID != tenantID {
+		return nil, ErrInvoiceNotFound
+	}
+	return inv, nil
+}
+
+// AssignInvoices gives open invoices to a collector; nothing is assigned when one of them is not
+// collectable or already with a collector
+func (s *CollectorService) AssignInvoices(ctx context.Context, tenantID, userID uuid.UUID, req AssignInvoicesRequest) ([]*collector.Assignment, error) {
+	if len(req.InvoiceIDs) == 0 {
+		return nil, ErrCollectorInvoicesRequired
+	}
+	u, err := s.userRepo.GetByID(ctx, req.CollectorID)
+	if err != nil || u.TenantID == nil || *u.TenantID != tenantID || u.Role == nil ||
+		u.Role.Code != string(rbac.RoleCollector) || u.Status != user.StatusActive {
+		return nil, ErrCollectorInvalid
+	}
+
+	now := time.Now()
+	assignments := make([]*collector.Assignment, 0, len(req.InvoiceIDs))
+	seen := map[uuid.UUID]bool{}
+	for _, id := range req.InvoiceIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		inv, err := s.tenantInvoice(ctx, tenantID, id)
+		if err != nil {
+			return nil, err
+		}
+		if !invoiceCollectable(inv) {
+			return nil, fmt.Errorf("%w: %s", ErrCollectorInvoiceNotOpen, inv.InvoiceNumber)
+		}
+		assignments = append(assignments, &collector.Assignment{
+			ID:          uuid.New(),
+			TenantID:    tenantID,
+			CollectorID: req.CollectorID,
+			InvoiceID:   inv.ID,
+			ClientID:    inv.ClientID,
+			Status:      collector.AssignmentAssigned,
+			Notes:       req.Notes,
+			AssignedBy:  userID,
+			AssignedAt:  now,
+		})
+	}
+
+	created := make([]*collector.Assignment, 0, len(assignments))
+	for _, a := range assignments {
+		if err := s.collectorRepo.CreateAssignment(ctx, a); err != nil {
+			// Take back what this request assigned so the call stays all-or-nothing
+			for _, c := range created {
+				if cerr := s.collectorRepo.CancelAssignment(ctx, tenantID, c.ID); cerr != nil {
+					log.Warn().Err(cerr).Str("assignment_id", c.ID.String()).Msg("Failed to roll back collector assignment")
+				}
+			}
+			return nil, err
+		}
+		created = append(created, a)
+	}
+
+	result := make([]*collector.Assignment, 0, len(created))
+	for _, a := range created {
+		full, err := s.collectorRepo.GetAssignment(ctx, tenantID, a.ID)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, full)
+	}
+	return result, nil
+}
+
+// ListAssignments lists the assignments of the tenant (office view)
+func (s *CollectorService) ListAssignments(ctx context.Context, filter repository.CollectorAssignmentFilter) ([]*collector.Assignment, int, error) {
+	return s.collectorRepo.ListAssignments(ctx, filter)
+}
+
+// ListCollectorAssignments lists only the assignments of the collector
+func (s *CollectorService) ListCollectorAssignments(ctx context.Context, collectorID uuid.UUID, filter repository.CollectorAssignmentFilter) ([]*collector.Assignment, int, error) {
+	filter.CollectorID = &collectorID
+	return s.collectorRepo.ListAssignments(ctx, filter)
+}
+
+func (s *CollectorService) GetAssignment(ctx context.Context, tenantID, id uuid.UUID) (*collector.Assignment, error) {
+	return s.collectorRepo.GetAssignment(ctx, tenantID, id)
+}
+
+// GetCollectorAssignment returns an assignment of the collector; others are reported as not found
+func (s *CollectorService) GetCollectorAssignment(ctx context.Context, tenantID, collectorID, id uuid.UUID) (*collector.Assignment, error) {
+	a, err := s.collectorRepo.GetAssignment(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if a.CollectorID != collectorID {
+		return nil, repository.ErrCollectorAssignmentNotFound
+	}
+	return a, nil
+}
+
+// CancelAssignment takes an invoice back from the collector before it is collected
+func (s *CollectorService) CancelAssignment(ctx context.Context, tenantID, id uuid.UUID) error {
+	a, err := s.collectorRepo.GetAssignment(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if !a.CanCancel() {
+		return ErrCollectorAssignmentClosed
+	}
+	if err := s.collectorRepo.CancelAssignment(ctx, tenantID, id); err != nil {
+		if errors.Is(err, repository.ErrCollectorAssignmentChanged) {
+			return ErrCollectorAssignmentClosed
+		}
+		return err
+	}
+	return nil
+}
+
+// validateVisit checks a visit report; GPS is required for both results
+func validateVisit(req LogVisitRequest) error {
+	if req.Result != collector.VisitSuccess && req.Result != collector.VisitFailed {
+		return ErrCollectorVisitInvalid
+	}
+	if req.Latitude == nil || req.Longitude == nil ||
+		*req.Latitude < -90 || *req.Latitude > 90 || *req.Longitude < -180 || *req.Longitude > 180 {
+		return ErrCollectorVisitInvalid
+	}
+	if req.Result == collector.VisitFailed && (req.Reason == nil || strings.TrimSpace(*req.Reason) == "") {
+		return ErrCollectorVisitReason
+	}
+	return nil
+}
+
+// LogVisit records a visit of the collector. A successful visit collects the invoice's remaining
+// amount; a failed one leaves the assignment open for another visit.
+func (s *CollectorService) LogVisit(ctx context.Context, tenantID, collectorID, assignmentID uuid.UUID, req LogVisitRequest) (*collector.Visit, error) {
+	if err := validateVisit(req); err != nil {
+		return nil, err
+	}
+	a, err := s.GetCollectorAssignment(ctx, tenantID, collectorID, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	if !a.CanVisit() {
+		return nil, ErrCollectorAssignmentClosed
+	}
+
+	v := &collector.Visit{
+		ID:           uuid.New(),
+		TenantID:     tenantID,
+		AssignmentID: a.ID,
+		CollectorID:  collectorID,
+		Result:       req.Result,
+		Latitude:     *req.Latitude,
+		Longitude:    *req.Longitude,
+		Reason:       req.Reason,
+		Notes:        req.Notes,
+		VisitedAt:    time.Now(),
+	}
+	var inv *billing.Invoice
+	if req.Result == collector.VisitSuccess {
+		inv, err = s.tenantInvoice(ctx, tenantID, a.InvoiceID)
+		if err != nil {
+			return nil, err
+		}
+		if !invoiceCollectable(inv) {
+			return nil, ErrCollectorInvoiceNotOpen
+		}
+		v.Amount = inv.RemainingAmount()
+	}
+
+	if err := s.collectorRepo.RecordVisit(ctx, v); err != nil {
+		if errors.Is(err, repository.ErrCollectorAssignmentChanged) {
+			return nil, ErrCollectorAssignmentClosed
+		}
+		return nil, err
+	}
+	if inv != nil && s.ledger != nil {
+		s.ledger.PostCollection(ctx, v, inv)
+	}
+	return v, nil
+}
+
+// ListVisits returns the visits of an assignment; a collectorID restricts it to that collector
+func (s *CollectorService) ListVisits(ctx context.Context, tenantID uuid.UUID, collectorID *uuid.UUID, assignmentID uuid.UUID) ([]*collector.Visit, error) {
+	if collectorID != nil {
+		if _, err := s.GetCollectorAssignment(ctx, tenantID, *collectorID, assignmentID); err != nil {
+			return nil, err
+		}
+	} else if _, err := s.collectorRepo.GetAssignment(ctx, tenantID, assignmentID); err != nil {
+		return nil, err
+	}
+	return s.collectorRepo.ListVisits(ctx, tenantID, assignmentID)
+}
+
+// depositSelection picks the collected assignments a deposit bundles; ids (optional) must all be
+// among them. Returns the assignments and the deposit total.
+func depositSelection(collected []*collector.Assignment, ids []uuid.UUID) ([]*collector.Assignment, int64, error) {
+	selected := collected
+	if len(ids) > 0 {
+		byID := make(map[uuid.UUID]*collector.Assignment, len(collected))
+		for _, a := range collected {
+			byID[a.ID] = a
+		}
+		selected = make([]*collector.Assignment, 0, len(ids))
+		for _, id := range ids {
+			a, ok := byID[id]
+			if !ok {
+				return nil, 0, ErrCollectorAssignmentClosed
+			}
+			delete(byID, id)
+			selected = append(selected, a)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, 0, ErrCollectorNothingCollected
+	}
+	var total int64
+	for _, a := range selected {
+		total += a.Amount
+	}
+	return selected, total, nil
+}
+
+// ReportDeposit hands collected cash over to finance. The total is the sum of the amounts taken
+// at the visits.
+func (s *CollectorService) ReportDeposit(ctx context.Context, tenantID, collectorID uuid.UUID, req ReportDepositRequest) (*collector.Deposit, error) {
+	status := collector.AssignmentCollected
+	collected, _, err := s.collectorRepo.ListAssignments(ctx, repository.CollectorAssignmentFilter{
+		TenantID:    tenantID,
+		CollectorID: &collectorID,
+		Status:      &status,
+	})
+	if err != nil {
+		return nil, err
+	}
+	selected, total, err := depositSelection(collected, req.AssignmentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &collector.Deposit{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		CollectorID: collectorID,
+		Status:      collector.DepositReported,
+		TotalAmount: total,
+		Notes:       req.Notes,
+		ReportedAt:  time.Now(),
+	}
+	ids := make([]uuid.UUID, len(selected))
+	for i, a := range selected {
+		ids[i] = a.ID
+	}
+	if err := s.collectorRepo.CreateDeposit(ctx, d, ids); err != nil {
+		if errors.Is(err, repository.ErrCollectorAssignmentChanged) {
+			return nil, ErrCollectorAssignmentClosed
+		}
+		return nil, err
+	}
+	return s.GetDeposit(ctx, tenantID, d.ID)
+}
+
+// GetDeposit returns a deposit with its assignments
+func (s *CollectorService) GetDeposit(ctx context.Context, tenantID, id uuid.UUID) (*collector.Deposit, error) {
+	d, err := s.collectorRepo.GetDeposit(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	d.Assignments, _, err = s.collectorRepo.ListAssignments(ctx, repository.CollectorAssignmentFilter{
+		TenantID:  tenantID,
+		DepositID: &id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// GetCollectorDeposit returns a deposit of the collector; others are reported as not found
+func (s *CollectorService) GetCollectorDeposit(ctx context.Context, tenantID, collectorID, id uuid.UUID) (*collector.Deposit, error) {
+	d, err := s.GetDeposit(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.CollectorID != collectorID {
+		return nil, repository.ErrCollectorDepositNotFound
+	}
+	return d, nil
+}
+
+func (s *CollectorService) ListDeposits(ctx context.Context, filter repository.CollectorDepositFilter) ([]*collector.Deposit, int, error) {
+	return s.collectorRepo.ListDeposits(ctx, filter)
+}
+
+// ListCollectorDeposits lists only the deposits of the collector
+func (s *CollectorService) ListCollectorDeposits(ctx context.Context, collectorID uuid.UUID, filter repository.CollectorDepositFilter) ([]*collector.Deposit, int, error) {
+	filter.CollectorID = &collectorID
+	return s.collectorRepo.ListDeposits(ctx, filter)
+}
+
+// ConfirmDeposit is the only step that turns collected cash into payments: one collector payment
+// per assignment, for the amount taken at the visit. Every invoice is checked before anything is
+// recorded; a confirmation interrupted halfway can be repeated and skips the assignments that
+// already have their payment.
+func (s *CollectorService) ConfirmDeposit(ctx context.Context, tenantID, userID, id uuid.UUID) (*collector.Deposit, error) {
+	d, err := s.GetDeposit(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.Status != collector.DepositReported {
+		return nil, repository.ErrCollectorDepositReviewed
+	}
+
+	var pending []*collector.Assignment
+	for _, a := range d.Assignments {
+		if a.Status != collector.AssignmentDeposited {
+			continue
+		}
+		inv, err := s.tenantInvoice(ctx, tenantID, a.InvoiceID)
+		if err != nil {
+			return nil, err
+		}
+		if inv.Status != billing.InvoiceStatusPending && inv.Status != billing.InvoiceStatusOverdue {
+			// Settled or cancelled since the visit; finance has to reject the deposit
+			return nil, fmt.Errorf("%w: %s", ErrCollectorInvoiceNotOpen, inv.InvoiceNumber)
+		}
+		pending = append(pending, a)
+	}
+
+	reference := "Setoran " + d.ID.String()[:8]
+	for _, a := range pending {
+		claimed, err := s.collectorRepo.ClaimForConfirmation(ctx, a.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !claimed {
+			continue // confirmed by a concurrent request
+		}
+		collectorID := a.CollectorID
+		receivedAt := a.AssignedAt
+		if a.CollectedAt != nil {
+			receivedAt = *a.CollectedAt
+		}
+		payment, err := s.billingService.RecordPayment(ctx, tenantID, userID, RecordPaymentRequest{
+			InvoiceID:   a.InvoiceID,
+			Amount:      a.Amount,
+			Method:      billing.PaymentMethodCollector,
+			Reference:   &reference,
+			CollectorID: &collectorID,
+			ReceivedAt:  &receivedAt,
+			Collected:   true,
+		})
+		if err != nil {
+			if rerr := s.collectorRepo.ReleaseConfirmation(ctx, a.ID); rerr != nil {
+				log.Warn().Err(rerr).Str("assignment_id", a.ID.String()).Msg("Failed to release collector assignment")
+			}
+			return nil, err
+		}
+		if err := s.collectorRepo.SetPayment(ctx, a.ID, payment.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.collectorRepo.MarkDepositConfirmed(ctx, d.ID, userID, time.Now()); err != nil {
+		return nil, err
+	}
+	return s.GetDeposit(ctx, tenantID, id)
+}
+
+// RejectDeposit sends a deposit back: no payment is recorded and its assignments return to
+// collected, to be reported again
+func (s *CollectorService) RejectDeposit(ctx context.Context, tenantID, userID, id uuid.UUID, reason string) (*collector.Deposit, error) {
+	if strings.TrimSpace(reason) == "" {
+		return nil, ErrCollectorDepositReason
+	}
+	if _, err := s.collectorRepo.GetDeposit(ctx, tenantID, id); err != nil {
+		return nil, err
+	}
+	if err := s.collectorRepo.RejectDeposit(ctx, id, userID, strings.TrimSpace(reason), time.Now()); err != nil {
+		return nil, err
+	}
+	return s.GetDeposit(ctx, tenantID, id)
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/collector"
+)
+
+func TestValidateVisit(t *testing.T) {
+	lat, lng := -6.2, 106.8
+	reason := "rumah kosong"
+	blank := "  "
+
+	assert.NoError(t, validateVisit(LogVisitRequest{Result: collector.VisitSuccess, Latitude: &lat, Longitude: &lng}))
+	assert.NoError(t, validateVisit(LogVisitRequest{Result: collector.VisitFailed, Latitude: &lat, Longitude: &lng, Reason: &reason}))
+
+	assert.ErrorIs(t, validateVisit(LogVisitRequest{Result: "paid", Latitude: &lat, Longitude: &lng}), ErrCollectorVisitInvalid)
+	assert.ErrorIs(t, validateVisit(LogVisitRequest{Result: collector.VisitSuccess, Latitude: &lat}), ErrCollectorVisitInvalid)
+	badLat := 91.0
+	assert.ErrorIs(t, validateVisit(LogVisitRequest{Result: collector.VisitSuccess, Latitude: &badLat, Longitude: &lng}), ErrCollectorVisitInvalid)
+	assert.ErrorIs(t, validateVisit(LogVisitRequest{Result: collector.VisitFailed, Latitude: &lat, Longitude: &lng}), ErrCollectorVisitReason)
+	assert.ErrorIs(t, validateVisit(LogVisitRequest{Result: collector.VisitFailed, Latitude: &lat, Longitude: &lng, Reason: &blank}), ErrCollectorVisitReason)
+}
+
+func TestInvoiceCollectable(t *testing.T) {
+	assert.True(t, invoiceCollectable(&billing.Invoice{Status: billing.InvoiceStatusOverdue, TotalAmount: 150000, PaidAmount: 50000}))
+	assert.False(t, invoiceCollectable(&billing.Invoice{Status: billing.InvoiceStatusPending, TotalAmount: 150000, PaidAmount: 150000}))
+	assert.False(t, invoiceCollectable(&billing.Invoice{Status: billing.InvoiceStatusCancelled, TotalAmount: 150000}))
+	assert.False(t, invoiceCollectable(&billing.Invoice{Status: billing.InvoiceStatusDraft, TotalAmount: 150000}))
+}
+
+func TestDepositSelection(t *testing.T) {
+	a := &collector.Assignment{ID: uuid.New(), Amount: 150000}
+	b := &collector.Assignment{ID: uuid.New(), Amount: 200000}
+	collected := []*collector.Assignment{a, b}
+
+	// Everything collected by default; the total comes from the visits, not the request
+	selected, total, err := depositSelection(collected, nil)
+	require.NoError(t, err)
+	assert.Len(t, selected, 2)
+	assert.Equal(t, int64(350000), total)
+
+	selected, total, err = depositSelection(collected, []uuid.UUID{b.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []*collector.Assignment{b}, selected)
+	assert.Equal(t, int64(200000), total)
+
+	// Unknown (or not collected) and repeated assignments are refused
+	_, _, err = depositSelection(collected, []uuid.UUID{uuid.New()})
+	assert.ErrorIs(t, err, ErrCollectorAssignmentClosed)
+	_, _, err = depositSelection(collected, []uuid.UUID{a.ID, a.ID})
+	assert.ErrorIs(t, err, ErrCollectorAssignmentClosed)
+
+	_, _, err = depositSelection(nil, nil)
+	assert.ErrorIs(t, err, ErrCollectorNothingCollected)
+}
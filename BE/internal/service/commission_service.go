@@ -0,0 +1,409 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"rrnet/internal/domain/commission"
+	"rrnet/internal/rbac"
+	"rrnet/internal/reporting"
+	"rrnet/internal/repository"
+)
+
+var (
+	ErrCommissionRuleInvalid = errors.New("commission rule needs a name and a percent between 0 and 100, a positive flat amount, or ascending tiers")
+	ErrCommissionRuleTarget  = errors.New("commission rule needs role_code (scope role) or user_id (scope user) of the tenant")
+	ErrCommissionRuleDate    = errors.New("effective_from must be formatted as YYYY-MM-DD")
+	ErrCommissionRuleRetired = errors.New("commission rule is retired")
+)
+
+// CommissionRuleRequest defines a rule, or the terms of its next version on update (the scope and
+// target of a rule never change)
+type CommissionRuleRequest struct {
+	Name          string               `json:"name"`
+	Scope         commission.RuleScope `json:"scope"`
+	RoleCode      *string              `json:"role_code,omitempty"`
+	UserID        *uuid.UUID           `json:"user_id,omitempty"`
+	Type          commission.RuleType  `json:"type"`
+	Percent       float64              `json:"percent,omitempty"`
+	FlatAmount    int64                `json:"flat_amount,omitempty"`
+	Tiers         []commission.Tier    `json:"tiers,omitempty"`
+	EffectiveFrom string               `json:"effective_from,omitempty"` // YYYY-MM-DD, default today
+}
+
+// CommissionGenerateResult summarizes the statements generated for a month
+type CommissionGenerateResult struct {
+	PeriodStart time.Time `json:"period_start"`
+	Generated   int       `json:"generated"`
+	// Locked counts users whose statement is already approved or paid and was left as it is
+	Locked int `json:"locked"`
+	// Unruled lists users with collected payments that no rule applies to
+	Unruled []uuid.UUID `json:"unruled"`
+}
+
+// CommissionService computes the commission of staff who collect payments: versioned rules per
+// role or user, evaluated over the payments recorded with a collector, into monthly statements
+// that are approved and then paid out
+type CommissionService struct {
+	commissionRepo *repository.CommissionRepository
+	userRepo       *repository.UserRepository
+}
+
+func NewCommissionService(commissionRepo *repository.CommissionRepository, userRepo *repository.UserRepository) *CommissionService {
+	return &CommissionService{commissionRepo: commissionRepo, userRepo: userRepo}
+}
+
+// validateRuleTerms checks the computation part of a rule
+func validateRuleTerms(r *commission.Rule) error {
+	if strings.TrimSpace(r.Name) == "" {
+		return ErrCommissionRuleInvalid
+	}
+	switch r.Type {
+	case commission.RulePercent:
+		if r.Percent <= 0 || r.Percent > 100 {
+			return ErrCommissionRuleInvalid
+		}
+		r.FlatAmount, r.Tiers = 0, nil
+	case commission.RuleFlat:
+		if r.FlatAmount <= 0 {
+			return ErrCommissionRuleInvalid
+		}
+		r.Percent, r.Tiers = 0, nil
+	case commission.RuleTiered:
+		if len(r.Tiers) == 0 {
+			return ErrCommissionRuleInvalid
+		}
+		for i, t := range r.Tiers {
+			if t.MinAmount < 0 || t.Percent < 0 || t.Percent > 100 || (i > 0 && t.MinAmount <= r.Tiers[i-1].MinAmount) {
+				return ErrCommissionRuleInvalid
+			}
+		}
+		r.Percent, r.FlatAmount = 0, 0
+	default:
+		return ErrCommissionRuleInvalid
+	}
+	return nil
+}
+
+func parseEffectiveFrom(v string, now time.Time) (time.Time, error) {
+	if v == "" {
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local), nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+	if err != nil {
+		return time.Time{}, ErrCommissionRuleDate
+	}
+	return t, nil
+}
+
+// ruleVersion builds the next version of a rule from the request terms
+func ruleVersion(req CommissionRuleRequest, now time.Time) (*commission.Rule, error) {
+	effective, err := parseEffectiveFrom(req.EffectiveFrom, now)
+	if err != nil {
+		return nil, err
+	}
+	r := &commission.Rule{
+		ID:            uuid.New(),
+		Name:          strings.TrimSpace(req.Name),
+		Type:          req.Type,
+		Percent:       req.Percent,
+		FlatAmount:    req.FlatAmount,
+		Tiers:         req.Tiers,
+		Active:        true,
+		EffectiveFrom: effective,
+		CreatedAt:     now,
+	}
+	if err := validateRuleTerms(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// CreateRule stores version 1 of a rule for a role or a user of the tenant
+func (s *CommissionService) CreateRule(ctx context.Context, tenantID, userID uuid.UUID, req CommissionRuleRequest) (*commission.Rule, error) {
+	rule, err := ruleVersion(req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	switch req.Scope {
+	case commission.ScopeRole:
+		if req.RoleCode == nil || !rbac.IsValidRole(*req.RoleCode) || *req.RoleCode == string(rbac.RoleSuperAdmin) {
+			return nil, ErrCommissionRuleTarget
+		}
+		rule.RoleCode = req.RoleCode
+	case commission.ScopeUser:
+		if req.UserID == nil {
+			return nil, ErrCommissionRuleTarget
+		}
+		u, err := s.userRepo.GetByID(ctx, *req.UserID)
+		if err != nil || u.TenantID == nil || *u.TenantID != tenantID {
+			return nil, ErrCommissionRuleTarget
+		}
+		rule.UserID = req.UserID
+	default:
+		return nil, ErrCommissionRuleTarget
+	}
+	rule.TenantID = tenantID
+	rule.RuleKey = uuid.New()
+	rule.Version = 1
+	rule.Scope = req.Scope
+	rule.CreatedBy = userID
+	if err := s.commissionRepo.CreateRuleVersion(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// UpdateRule stores the next version of a rule with new terms; earlier versions stay as they are
+func (s *CommissionService) UpdateRule(ctx context.Context, tenantID, userID, ruleKey uuid.UUID, req CommissionRuleRequest) (*commission.Rule, error) {
+	versions, err := s.commissionRepo.ListRuleVersions(ctx, tenantID, ruleKey)
+	if err != nil {
+		return nil, err
+	}
+	latest := versions[len(versions)-1]
+	if !latest.Active {
+		return nil, ErrCommissionRuleRetired
+	}
+	rule, err := ruleVersion(req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return s.addVersion(ctx, latest, rule, userID)
+}
+
+// RetireRule stores a last, inactive version: the rule stops applying from effectiveFrom
+func (s *CommissionService) RetireRule(ctx context.Context, tenantID, userID, ruleKey uuid.UUID, effectiveFrom string) (*commission.Rule, error) {
+	versions, err := s.commissionRepo.ListRuleVersions(ctx, tenantID, ruleKey)
+	if err != nil {
+		return nil, err
+	}
+	latest := versions[len(versions)-1]
+	if !latest.Active {
+		return nil, ErrCommissionRuleRetired
+	}
+	now := time.Now()
+	effective, err := parseEffectiveFrom(effectiveFrom, now)
+	if err != nil {
+		return nil, err
+	}
+	rule := *latest
+	rule.ID = uuid.New()
+	rule.Active = false
+	rule.EffectiveFrom = effective
+	rule.CreatedAt = now
+	return s.addVersion(ctx, latest, &rule, userID)
+}
+
+func (s *CommissionService) addVersion(ctx context.Context, latest, rule *commission.Rule, userID uuid.UUID) (*commission.Rule, error) {
+	rule.TenantID = latest.TenantID
+	rule.RuleKey = latest.RuleKey
+	rule.Version = latest.Version + 1
+	rule.Scope = latest.Scope
+	rule.RoleCode = latest.RoleCode
+	rule.UserID = latest.UserID
+	rule.CreatedBy = userID
+	if err := s.commissionRepo.CreateRuleVersion(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// ListRules returns the latest version of every rule
+func (s *CommissionService) ListRules(ctx context.Context, tenantID uuid.UUID) ([]*commission.Rule, error) {
+	return s.commissionRepo.ListLatestRules(ctx, tenantID)
+}
+
+// RuleHistory returns every version of a rule
+func (s *CommissionService) RuleHistory(ctx context.Context, tenantID, ruleKey uuid.UUID) ([]*commission.Rule, error) {
+	return s.commissionRepo.ListRuleVersions(ctx, tenantID, ruleKey)
+}
+
+// pickRule returns the rule that pays a user: a rule of the user wins over a rule of the role;
+// among several, the one effective most recently (then created last)
+func pickRule(rules []*commission.Rule, userID uuid.UUID, roleCode string) *commission.Rule {
+	var best *commission.Rule
+	better := func(r *commission.Rule) bool {
+		if best == nil {
+			return true
+		}
+		if (r.Scope == commission.ScopeUser) != (best.Scope == commission.ScopeUser) {
+			return r.Scope == commission.ScopeUser
+		}
+		if !r.EffectiveFrom.Equal(best.EffectiveFrom) {
+			return r.EffectiveFrom.After(best.EffectiveFrom)
+		}
+		return r.CreatedAt.After(best.CreatedAt)
+	}
+	for _, r := range rules {
+		applies := (r.Scope == commission.ScopeUser && r.UserID != nil && *r.UserID == userID) ||
+			(r.Scope == commission.ScopeRole && r.RoleCode != nil && *r.RoleCode == roleCode)
+		if applies && better(r) {
+			best = r
+		}
+	}
+	return best
+}
+
+// buildStatement computes the statement of a user from the payments collected in the month
+func buildStatement(tenantID, userID uuid.UUID, month time.Time, rule *commission.Rule, payments []*repository.CollectedPayment, now time.Time) *commission.Statement {
+	st := &commission.Statement{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		UserID:      userID,
+		PeriodStart: month,
+		RuleID:      rule.ID,
+		Rule:        *rule,
+		Status:      commission.StatementDraft,
+		GeneratedAt: now,
+	}
+	invoices := map[uuid.UUID]bool{}
+	for _, p := range payments {
+		line := p.StatementLine
+		st.Lines = append(st.Lines, &line)
+		st.CollectedAmount += p.Amount
+		invoices[p.InvoiceID] = true
+	}
+	st.PaymentCount = len(payments)
+	st.InvoiceCount = len(invoices)
+	st.CommissionAmount = rule.Compute(st.CollectedAmount, st.InvoiceCount)
+	return st
+}
+
+// Generate computes the statements of a month (YYYY-MM) from the payments with a collector that
+// were recorded in it, with the rule versions in force on the last day of the month. Drafts of the
+// month are replaced; approved and paid statements are kept.
+func (s *CommissionService) Generate(ctx context.Context, tenantID uuid.UUID, period string) (*CommissionGenerateResult, error) {
+	month, err := parseBulkPeriod(period)
+	if err != nil {
+		return nil, err
+	}
+	next := month.AddDate(0, 1, 0)
+
+	rules, err := s.commissionRepo.RulesInForce(ctx, tenantID, next.AddDate(0, 0, -1))
+	if err != nil {
+		return nil, err
+	}
+	payments, err := s.commissionRepo.ListCollectedPayments(ctx, tenantID, month, next)
+	if err != nil {
+		return nil, err
+	}
+	byUser := map[uuid.UUID][]*repository.CollectedPayment{}
+	var users []uuid.UUID
+	for _, p := range payments {
+		if _, ok := byUser[p.CollectorID]; !ok {
+			users = append(users, p.CollectorID)
+		}
+		byUser[p.CollectorID] = append(byUser[p.CollectorID], p)
+	}
+
+	result := &CommissionGenerateResult{PeriodStart: month, Unruled: []uuid.UUID{}}
+	now := time.Now()
+	var statements []*commission.Statement
+	for _, userID := range users {
+		roleCode := ""
+		if u, err := s.userRepo.GetByID(ctx, userID); err == nil && u.Role != nil {
+			roleCode = u.Role.Code
+		}
+		rule := pickRule(rules, userID, roleCode)
+		if rule == nil {
+			result.Unruled = append(result.Unruled, userID)
+			continue
+		}
+		statements = append(statements, buildStatement(tenantID, userID, month, rule, byUser[userID], now))
+	}
+
+	stored, err := s.commissionRepo.ReplaceDrafts(ctx, tenantID, month, statements)
+	if err != nil {
+		return nil, err
+	}
+	result.Generated = stored
+	result.Locked = len(statements) - stored
+	return result, nil
+}
+
+// ListStatements lists statements; filter.UserID restricts them to one user
+func (s *CommissionService) ListStatements(ctx context.Context, filter repository.CommissionStatementFilter) ([]*commission.Statement, int, error) {
+	return s.commissionRepo.ListStatements(ctx, filter)
+}
+
+// GetStatement returns a statement with the payments it counts; a userID restricts it to that
+// user's statements
+func (s *CommissionService) GetStatement(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, id uuid.UUID) (*commission.Statement, error) {
+	st, err := s.commissionRepo.GetStatement(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if userID != nil && st.UserID != *userID {
+		return nil, repository.ErrCommissionStatementNotFound
+	}
+	st.Lines, err = s.commissionRepo.ListStatementLines(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// Approve locks a draft statement for payout
+func (s *CommissionService) Approve(ctx context.Context, tenantID, userID, id uuid.UUID) (*commission.Statement, error) {
+	if err := s.commissionRepo.Approve(ctx, tenantID, id, userID, time.Now()); err != nil {
+		if errors.Is(err, repository.ErrCommissionStatementState) {
+			if _, gerr := s.commissionRepo.GetStatement(ctx, tenantID, id); gerr != nil {
+				return nil, gerr
+			}
+		}
+		return nil, err
+	}
+	return s.GetStatement(ctx, tenantID, nil, id)
+}
+
+// MarkPaid records the payout of an approved statement
+func (s *CommissionService) MarkPaid(ctx context.Context, tenantID, userID, id uuid.UUID, reference *string) (*commission.Statement, error) {
+	if err := s.commissionRepo.MarkPaid(ctx, tenantID, id, userID, reference, time.Now()); err != nil {
+		if errors.Is(err, repository.ErrCommissionStatementState) {
+			if _, gerr := s.commissionRepo.GetStatement(ctx, tenantID, id); gerr != nil {
+				return nil, gerr
+			}
+		}
+		return nil, err
+	}
+	return s.GetStatement(ctx, tenantID, nil, id)
+}
+
+// StatementsTable lays out the statements of a month for export
+func StatementsTable(month time.Time, statements []*commission.Statement) *reporting.Table {
+	t := &reporting.Table{
+		Title: fmt.Sprintf("Komisi %s", month.Format("2006-01")),
+		Columns: []string{
+			"User", "Role", "Rule", "Rule Version", "Collected", "Payments", "Invoices", "Commission",
+			"Status", "Approved At", "Paid At", "Payout Reference",
+		},
+	}
+	sort.SliceStable(statements, func(i, j int) bool { return statements[i].UserName < statements[j].UserName })
+	var collected, total int64
+	for _, st := range statements {
+		approvedAt, paidAt, reference := "", "", ""
+		if st.ApprovedAt != nil {
+			approvedAt = st.ApprovedAt.Format("2006-01-02")
+		}
+		if st.PaidAt != nil {
+			paidAt = st.PaidAt.Format("2006-01-02")
+		}
+		if st.PayoutReference != nil {
+			reference = *st.PayoutReference
+		}
+		t.Rows = append(t.Rows, []interface{}{
+			st.UserName, st.RoleCode, st.Rule.Name, st.Rule.Version, st.CollectedAmount, st.PaymentCount, st.InvoiceCount,
+			st.CommissionAmount, string(st.Status), approvedAt, paidAt, reference,
+		})
+		collected += st.CollectedAmount
+		total += st.CommissionAmount
+	}
+	t.Rows = append(t.Rows, []interface{}{"Total", "", "", "", collected, "", "", total, "", "", "", ""})
+	return t
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rrnet/internal/domain/commission"
+	"rrnet/internal/reporting"
+	"rrnet/internal/repository"
+)
+
+func TestRuleCompute(t *testing.T) {
+	percent := &commission.Rule{Type: commission.RulePercent, Percent: 2.5}
+	assert.Equal(t, int64(25000), percent.Compute(1000000, 4))
+	assert.Equal(t, int64(4), percent.Compute(150, 1)) // 3.75 rounds up
+
+	flat := &commission.Rule{Type: commission.RuleFlat, FlatAmount: 5000}
+	assert.Equal(t, int64(20000), flat.Compute(1000000, 4))
+
+	tiered := &commission.Rule{Type: commission.RuleTiered, Tiers: []commission.Tier{
+		{MinAmount: 0, Percent: 1},
+		{MinAmount: 5000000, Percent: 2},
+		{MinAmount: 10000000, Percent: 3},
+	}}
+	assert.Equal(t, int64(40000), tiered.Compute(4000000, 10))
+	// The tier reached applies to the whole volume
+	assert.Equal(t, int64(100000), tiered.Compute(5000000, 10))
+	assert.Equal(t, int64(360000), tiered.Compute(12000000, 10))
+
+	above := &commission.Rule{Type: commission.RuleTiered, Tiers: []commission.Tier{{MinAmount: 1000000, Percent: 2}}}
+	assert.Zero(t, above.Compute(999999, 3))
+}
+
+func TestValidateRuleTerms(t *testing.T) {
+	valid := []*commission.Rule{
+		{Name: "Kolektor", Type: commission.RulePercent, Percent: 2, FlatAmount: 100},
+		{Name: "Kolektor", Type: commission.RuleFlat, FlatAmount: 2500},
+		{Name: "Kolektor", Type: commission.RuleTiered, Tiers: []commission.Tier{{MinAmount: 0, Percent: 1}, {MinAmount: 100, Percent: 2}}},
+	}
+	for _, r := range valid {
+		assert.NoError(t, validateRuleTerms(r))
+	}
+	// Terms of other types are dropped
+	assert.Zero(t, valid[0].FlatAmount)
+
+	invalid := []*commission.Rule{
+		{Name: " ", Type: commission.RulePercent, Percent: 2},
+		{Name: "x", Type: commission.RulePercent, Percent: 0},
+		{Name: "x", Type: commission.RulePercent, Percent: 101},
+		{Name: "x", Type: commission.RuleFlat},
+		{Name: "x", Type: commission.RuleTiered},
+		{Name: "x", Type: commission.RuleTiered, Tiers: []commission.Tier{{MinAmount: 100, Percent: 1}, {MinAmount: 100, Percent: 2}}},
+		{Name: "x", Type: "bonus", Percent: 2},
+	}
+	for _, r := range invalid {
+		assert.ErrorIs(t, validateRuleTerms(r), ErrCommissionRuleInvalid)
+	}
+}
+
+func TestRuleVersionEffectiveFrom(t *testing.T) {
+	now := time.Date(2025, time.March, 14, 16, 30, 0, 0, time.Local)
+	req := CommissionRuleRequest{Name: "Kolektor", Type: commission.RulePercent, Percent: 2}
+	r, err := ruleVersion(req, now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, time.March, 14, 0, 0, 0, 0, time.Local), r.EffectiveFrom)
+	assert.True(t, r.Active)
+
+	req.EffectiveFrom = "01-04-2025"
+	_, err = ruleVersion(req, now)
+	assert.ErrorIs(t, err, ErrCommissionRuleDate)
+}
+
+func TestPickRule(t *testing.T) {
+	userID := uuid.New()
+	role := "collector"
+	day := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	roleOld := &commission.Rule{Scope: commission.ScopeRole, RoleCode: &role, EffectiveFrom: day}
+	roleNew := &commission.Rule{Scope: commission.ScopeRole, RoleCode: &role, EffectiveFrom: day.AddDate(0, 1, 0)}
+	other := uuid.New()
+	otherUser := &commission.Rule{Scope: commission.ScopeUser, UserID: &other, EffectiveFrom: day}
+
+	assert.Same(t, roleNew, pickRule([]*commission.Rule{roleOld, roleNew, otherUser}, userID, role))
+	assert.Nil(t, pickRule([]*commission.Rule{roleOld, otherUser}, userID, "finance"))
+
+	// A rule of the user wins over a newer rule of the role
+	own := &commission.Rule{Scope: commission.ScopeUser, UserID: &userID, EffectiveFrom: day}
+	assert.Same(t, own, pickRule([]*commission.Rule{roleNew, own, roleOld}, userID, role))
+}
+
+func TestBuildStatement(t *testing.T) {
+	tenantID, userID := uuid.New(), uuid.New()
+	month := time.Date(2025, time.May, 1, 0, 0, 0, 0, time.Local)
+	rule := &commission.Rule{ID: uuid.New(), Version: 3, Type: commission.RuleFlat, FlatAmount: 2000}
+	invoiceA, invoiceB := uuid.New(), uuid.New()
+	payments := []*repository.CollectedPayment{
+		{CollectorID: userID, StatementLine: commission.StatementLine{PaymentID: uuid.New(), InvoiceID: invoiceA, Amount: 100000}},
+		{CollectorID: userID, StatementLine: commission.StatementLine{PaymentID: uuid.New(), InvoiceID: invoiceA, Amount: 50000}},
+		{CollectorID: userID, StatementLine: commission.StatementLine{PaymentID: uuid.New(), InvoiceID: invoiceB, Amount: 200000}},
+	}
+
+	st := buildStatement(tenantID, userID, month, rule, payments, time.Now())
+	assert.Equal(t, rule.ID, st.RuleID)
+	assert.Equal(t, 3, st.Rule.Version)
+	assert.Equal(t, commission.StatementDraft, st.Status)
+	assert.Equal(t, int64(350000), st.CollectedAmount)
+	assert.Equal(t, 3, st.PaymentCount)
+	assert.Equal(t, 2, st.InvoiceCount)
+	// Flat commission is paid per invoice, not per instalment
+	assert.Equal(t, int64(4000), st.CommissionAmount)
+	assert.Len(t, st.Lines, 3)
+}
+
+func TestStatementsTable(t *testing.T) {
+	month := time.Date(2025, time.May, 1, 0, 0, 0, 0, time.Local)
+	ref := "TRF-0525"
+	paidAt := time.Date(2025, time.June, 3, 10, 0, 0, 0, time.Local)
+	statements := []*commission.Statement{
+		{UserName: "Budi", Rule: commission.Rule{Name: "Kolektor", Version: 2}, CollectedAmount: 300000, CommissionAmount: 6000, Status: commission.StatementDraft},
+		{UserName: "Agus", Rule: commission.Rule{Name: "Kolektor", Version: 2}, CollectedAmount: 100000, CommissionAmount: 2000, Status: commission.StatementPaid, PaidAt: &paidAt, PayoutReference: &ref},
+	}
+
+	table := StatementsTable(month, statements)
+	require.Len(t, table.Rows, 3)
+	assert.Equal(t, "Agus", table.Rows[0][0])
+	assert.Equal(t, "2025-06-03", table.Rows[0][10])
+	assert.Equal(t, "TRF-0525", table.Rows[0][11])
+	assert.Equal(t, []interface{}{"Total", "", "", "", int64(400000), "", "", int64(8000), "", "", "", ""}, table.Rows[2])
+
+	var buf bytes.Buffer
+	require.NoError(t, table.Write(&buf, reporting.FormatCSV))
+	assert.Contains(t, buf.String(), "Agus,,Kolektor,2,100000")
+}
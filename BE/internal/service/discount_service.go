@@ -16,6 +16,8 @@ var (
 	ErrDiscountTypeInvalid    = errors.New("invalid discount type")
 	ErrDiscountValueInvalid   = errors.New("invalid discount value")
 	ErrDiscountExpired        = errors.New("discount has expired")
+	ErrDiscountCyclesInvalid  = errors.New("max cycles must be greater than 0")
+	ErrDiscountStackingInvalid = errors.New("invalid discount stacking rule")
 )
 
 // DiscountService handles discount business logic
@@ -35,6 +37,7 @@ type CreateDiscountRequest struct {
 	Type        discount.Type `json:"type"`
 	Value       float64    `json:"value"`
 	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	MaxCycles   *int       `json:"max_cycles,omitempty"` // billing cycles, e.g. 3 = first 3 invoices (9 months on a quarterly cycle)
 	IsActive    bool       `json:"is_active"`
 }
 
@@ -50,6 +53,7 @@ type DiscountDTO struct {
 	Type        discount.Type `json:"type"`
 	Value       float64     `json:"value"`
 	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	MaxCycles   *int        `json:"max_cycles,omitempty"`
 	IsActive    bool        `json:"is_active"`
 	IsValid     bool        `json:"is_valid"` // Computed: active, not expired, not deleted
 	CreatedAt   time.Time   `json:"created_at"`
@@ -82,6 +86,9 @@ func (s *DiscountService) Create(ctx context.Context, tenantID uuid.UUID, req *C
 	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
 		return nil, ErrDiscountExpired
 	}
+	if req.MaxCycles != nil && *req.MaxCycles <= 0 {
+		return nil, ErrDiscountCyclesInvalid
+	}
 
 	// Check if name already exists
 	exists, err := s.repo.NameExists(ctx, tenantID, req.Name, nil)
@@ -101,6 +108,7 @@ func (s *DiscountService) Create(ctx context.Context, tenantID uuid.UUID, req *C
 		Type:      req.Type,
 		Value:     req.Value,
 		ExpiresAt: req.ExpiresAt,
+		MaxCycles: req.MaxCycles,
 		IsActive:  req.IsActive,
 		CreatedAt: now,
 		UpdatedAt: now,
@@ -165,6 +173,9 @@ func (s *DiscountService) Update(ctx context.Context, tenantID, id uuid.UUID, re
 	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
 		return nil, ErrDiscountExpired
 	}
+	if req.MaxCycles != nil && *req.MaxCycles <= 0 {
+		return nil, ErrDiscountCyclesInvalid
+	}
 
 	// Check if name already exists (excluding current discount)
 	excludeID := &id
@@ -188,6 +199,7 @@ func (s *DiscountService) Update(ctx context.Context, tenantID, id uuid.UUID, re
 	d.Type = req.Type
 	d.Value = req.Value
 	d.ExpiresAt = req.ExpiresAt
+	d.MaxCycles = req.MaxCycles
 	d.IsActive = req.IsActive
 	d.UpdatedAt = time.Now()
 
@@ -212,6 +224,7 @@ func toDiscountDTO(d *discount.Discount) *DiscountDTO {
 		Type:        d.Type,
 		Value:       d.Value,
 		ExpiresAt:   d.ExpiresAt,
+		MaxCycles:   d.MaxCycles,
 		IsActive:    d.IsActive,
 		IsValid:     d.IsValid(),
 		CreatedAt:   d.CreatedAt,
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/domain/job"
+	"rrnet/internal/domain/tenant"
+)
+
+// DunningScheduler runs the per-tenant dunning timeline; it runs as the JobDunning scheduled job
+type DunningScheduler struct {
+	dunningService *DunningService
+}
+
+// NewDunningScheduler creates a new dunning scheduler
+func NewDunningScheduler(dunningService *DunningService) *DunningScheduler {
+	return &DunningScheduler{
+		dunningService: dunningService,
+	}
+}
+
+// Runner returns the executor of the dunning job (JobDunning)
+func (s *DunningScheduler) Runner() JobRunner {
+	return JobRunner{RunTenant: s.RunForTenant}
+}
+
+// RunForTenant evaluates the dunning policy of the tenant. Every step outcome is recorded, so a
+// retried run does not repeat executed steps.
+func (s *DunningScheduler) RunForTenant(ctx context.Context, t *tenant.Tenant, now time.Time) (job.Stats, error) {
+	stats := job.Stats{"steps_executed": 0, "steps_skipped": 0, "steps_failed": 0}
+	if t.Status != tenant.StatusActive {
+		return stats, nil
+	}
+	res, err := s.dunningService.RunForTenant(ctx, t, now)
+	if err != nil {
+		return stats, err
+	}
+	stats["steps_executed"] = int64(res.Executed)
+	stats["steps_skipped"] = int64(res.Skipped)
+	stats["steps_failed"] = int64(res.Failed)
+
+	log.Info().
+		Str("tenant_id", t.ID.String()).
+		Int("steps_executed", res.Executed).
+		Int("steps_skipped", res.Skipped).
+		Int("steps_failed", res.Failed).
+		Msg("Dunning completed for tenant")
+	return stats, nil
+}
@@ -0,0 +1,583 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/client"
+	"rrnet/internal/domain/tenant"
+	"rrnet/internal/domain/wa_log"
+	"rrnet/internal/domain/wa_template"
+	wagw "rrnet/internal/infra/wa_gateway"
+	"rrnet/internal/repository"
+)
+
+var (
+	ErrDunningStepInvalid     = errors.New("invalid dunning step")
+	ErrDunningStepDuplicate   = errors.New("duplicate dunning step")
+	ErrDunningTooManySteps    = errors.New("a dunning policy supports at most 10 steps")
+	ErrDunningProfileRequired = errors.New("throttle step requires a profile")
+)
+
+const (
+	dunningMaxSteps     = 10
+	dunningMinOffset    = -30
+	dunningMaxOffset    = 365
+	defaultReminderText = "Halo {{client.name}}, tagihan {{invoice.number}} sebesar {{invoice.payable | rupiah}} jatuh tempo pada {{invoice.due_date}}. Mohon lakukan pembayaran tepat waktu. Terima kasih."
+)
+
+// DunningPlanItem is one step that is due for an invoice on a given date
+type DunningPlanItem struct {
+	InvoiceID     uuid.UUID             `json:"invoice_id"`
+	InvoiceNumber string                `json:"invoice_number"`
+	DueDate       time.Time             `json:"due_date"`
+	TotalAmount   int64                 `json:"total_amount"`
+	PayableAmount int64                 `json:"payable_amount"` // amount to transfer, including an unpaid unique code
+	UniqueCode    *int                  `json:"unique_code,omitempty"`
+	ClientID      uuid.UUID             `json:"client_id"`
+	ClientCode    string                `json:"client_code"`
+	ClientName    string                `json:"client_name"`
+	Step          billing.DunningStep   `json:"step"`
+	Superseded    []billing.DunningStep `json:"superseded,omitempty"` // earlier steps that will be recorded as skipped
+}
+
+// DunningPreview is the dry-run output for one date
+type DunningPreview struct {
+	Date    string             `json:"date"`
+	Enabled bool               `json:"enabled"`
+	Items   []*DunningPlanItem `json:"items"`
+}
+
+// DunningRunResult summarizes one scheduler run for a tenant
+type DunningRunResult struct {
+	Executed int
+	Skipped  int
+	Failed   int
+}
+
+type DunningService struct {
+	tenantRepo      *repository.TenantRepository
+	clientRepo      *repository.ClientRepository
+	invoiceRepo     *repository.InvoiceRepository
+	dunningRepo     *repository.DunningRepository
+	isolirService   *IsolirService
+	billingService  *BillingService
+	featureResolver *FeatureResolver
+	waClient        *wagw.Client
+	waLogService    *WALogService
+	renderer        *WATemplateRenderer
+}
+
+func NewDunningService(
+	tenantRepo *repository.TenantRepository,
+	clientRepo *repository.ClientRepository,
+	invoiceRepo *repository.InvoiceRepository,
+	dunningRepo *repository.DunningRepository,
+	isolirService *IsolirService,
+	billingService *BillingService,
+	featureResolver *FeatureResolver,
+	waClient *wagw.Client,
+	waLogService *WALogService,
+	renderer *WATemplateRenderer,
+) *DunningService {
+	return &DunningService{
+		tenantRepo:      tenantRepo,
+		clientRepo:      clientRepo,
+		invoiceRepo:     invoiceRepo,
+		dunningRepo:     dunningRepo,
+		isolirService:   isolirService,
+		billingService:  billingService,
+		featureResolver: featureResolver,
+		waClient:        waClient,
+		waLogService:    waLogService,
+		renderer:        renderer,
+	}
+}
+
+// ========== Policy ==========
+
+func (s *DunningService) GetPolicy(ctx context.Context, tenantID uuid.UUID) (*billing.DunningPolicy, error) {
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	out := readDunningPolicy(t.Settings)
+	return &out, nil
+}
+
+func (s *DunningService) UpdatePolicy(ctx context.Context, tenantID uuid.UUID, in billing.DunningPolicy) (*billing.DunningPolicy, error) {
+	if err := validateDunningPolicy(in); err != nil {
+		return nil, err
+	}
+	sortDunningSteps(in.Steps)
+
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if t.Settings == nil {
+		t.Settings = map[string]interface{}{}
+	}
+	steps := make([]interface{}, 0, len(in.Steps))
+	for _, st := range in.Steps {
+		steps = append(steps, map[string]interface{}{
+			"offset_days": st.OffsetDays,
+			"action":      string(st.Action),
+			"profile":     st.Profile,
+			"message":     st.Message,
+		})
+	}
+	t.Settings["dunning"] = map[string]interface{}{
+		"enabled": in.Enabled,
+		"steps":   steps,
+	}
+	if err := s.tenantRepo.UpdateSettings(ctx, tenantID, t.Settings); err != nil {
+		return nil, err
+	}
+	return &in, nil
+}
+
+func validateDunningPolicy(p billing.DunningPolicy) error {
+	if len(p.Steps) > dunningMaxSteps {
+		return ErrDunningTooManySteps
+	}
+	seen := make(map[string]bool)
+	for _, st := range p.Steps {
+		switch st.Action {
+		case billing.DunningActionReminder, billing.DunningActionIsolir, billing.DunningActionTerminate:
+		case billing.DunningActionThrottle:
+			if strings.TrimSpace(st.Profile) == "" {
+				return ErrDunningProfileRequired
+			}
+		default:
+			return ErrDunningStepInvalid
+		}
+		if st.OffsetDays < dunningMinOffset || st.OffsetDays > dunningMaxOffset {
+			return ErrDunningStepInvalid
+		}
+		// Enforcement before the due date makes no sense
+		if st.Action != billing.DunningActionReminder && st.OffsetDays <= 0 {
+			return ErrDunningStepInvalid
+		}
+		if st.Message != "" {
+			if err := wa_template.Validate(st.Message); err != nil {
+				return err
+			}
+		}
+		key := fmt.Sprintf("%d:%s", st.OffsetDays, st.Action)
+		if seen[key] {
+			return ErrDunningStepDuplicate
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+func sortDunningSteps(steps []billing.DunningStep) {
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].OffsetDays < steps[j].OffsetDays })
+}
+
+// defaultDunningSteps is the suggested timeline: reminders at H-3/H-1, throttle D+3, isolir D+7, terminate D+60
+func defaultDunningSteps() []billing.DunningStep {
+	return []billing.DunningStep{
+		{OffsetDays: -3, Action: billing.DunningActionReminder},
+		{OffsetDays: -1, Action: billing.DunningActionReminder},
+		{OffsetDays: 3, Action: billing.DunningActionThrottle, Profile: "throttle"},
+		{OffsetDays: 7, Action: billing.DunningActionIsolir},
+		{OffsetDays: 60, Action: billing.DunningActionTerminate},
+	}
+}
+
+func readDunningPolicy(settings map[string]interface{}) billing.DunningPolicy {
+	out := billing.DunningPolicy{Enabled: false, Steps: defaultDunningSteps()}
+	if settings == nil {
+		return out
+	}
+	raw, ok := settings["dunning"].(map[string]interface{})
+	if !ok || raw == nil {
+		return out
+	}
+	if v, ok := raw["enabled"].(bool); ok {
+		out.Enabled = v
+	}
+	rawSteps, ok := raw["steps"].([]interface{})
+	if !ok {
+		return out
+	}
+	out.Steps = make([]billing.DunningStep, 0, len(rawSteps))
+	for _, item := range rawSteps {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var st billing.DunningStep
+		if v, ok := m["offset_days"].(float64); ok {
+			st.OffsetDays = int(v)
+		}
+		if v, ok := m["action"].(string); ok {
+			st.Action = billing.DunningAction(v)
+		}
+		if v, ok := m["profile"].(string); ok {
+			st.Profile = v
+		}
+		if v, ok := m["message"].(string); ok {
+			st.Message = v
+		}
+		out.Steps = append(out.Steps, st)
+	}
+	sortDunningSteps(out.Steps)
+	return out
+}
+
+// ========== Planning ==========
+
+// Preview returns the steps that would run on the given date (dry run, nothing is recorded).
+func (s *DunningService) Preview(ctx context.Context, tenantID uuid.UUID, date time.Time) (*DunningPreview, error) {
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	policy := readDunningPolicy(t.Settings)
+	items, err := s.plan(ctx, tenantID, policy, date)
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []*DunningPlanItem{}
+	}
+	return &DunningPreview{
+		Date:    date.Format("2006-01-02"),
+		Enabled: policy.Enabled,
+		Items:   items,
+	}, nil
+}
+
+// plan evaluates the policy against every unpaid invoice of the tenant for asOf.
+func (s *DunningService) plan(ctx context.Context, tenantID uuid.UUID, policy billing.DunningPolicy, asOf time.Time) ([]*DunningPlanItem, error) {
+	if len(policy.Steps) == 0 {
+		return nil, nil
+	}
+
+	// Reminders may run before the due date, so look ahead by the largest negative offset
+	lead := 0
+	for _, st := range policy.Steps {
+		if -st.OffsetDays > lead {
+			lead = -st.OffsetDays
+		}
+	}
+	day := time.Date(asOf.Year(), asOf.Month(), asOf.Day(), 0, 0, 0, 0, time.Local)
+	invoices, err := s.invoiceRepo.GetUnpaidPastDue(ctx, tenantID, day.AddDate(0, 0, lead+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(invoices) == 0 {
+		return nil, nil
+	}
+
+	invoiceIDs := make([]uuid.UUID, 0, len(invoices))
+	for _, inv := range invoices {
+		invoiceIDs = append(invoiceIDs, inv.ID)
+	}
+	runs, err := s.dunningRepo.ListByInvoices(ctx, tenantID, invoiceIDs)
+	if err != nil {
+		return nil, err
+	}
+	done := make(map[string]bool, len(runs))
+	for _, run := range runs {
+		if run.Status != billing.DunningStepFailed {
+			done[dunningRunKey(run.InvoiceID, run.OffsetDays, run.Action)] = true
+		}
+	}
+
+	clients := make(map[uuid.UUID]*client.Client)
+	var items []*DunningPlanItem
+	for _, inv := range invoices {
+		daysSinceDue := daysBetween(inv.DueDate, day)
+		due, superseded := dueDunningSteps(policy.Steps, daysSinceDue, func(st billing.DunningStep) bool {
+			return done[dunningRunKey(inv.ID, st.OffsetDays, st.Action)]
+		})
+		if due == nil {
+			continue
+		}
+
+		c, ok := clients[inv.ClientID]
+		if !ok {
+			c, err = s.clientRepo.GetByID(ctx, tenantID, inv.ClientID)
+			if err != nil {
+				log.Warn().Err(err).Str("client_id", inv.ClientID.String()).Msg("Dunning: failed to get client")
+				continue
+			}
+			clients[inv.ClientID] = c
+		}
+		if c.Status == client.StatusTerminated {
+			continue
+		}
+
+		items = append(items, &DunningPlanItem{
+			InvoiceID:     inv.ID,
+			InvoiceNumber: inv.InvoiceNumber,
+			DueDate:       inv.DueDate,
+			TotalAmount:   inv.TotalAmount,
+			PayableAmount: inv.PayableAmount(),
+			UniqueCode:    inv.UniqueCode,
+			ClientID:      c.ID,
+			ClientCode:    c.ClientCode,
+			ClientName:    c.Name,
+			Step:          *due,
+			Superseded:    superseded,
+		})
+	}
+	return items, nil
+}
+
+// dueDunningSteps picks the step to run for an invoice that is daysSinceDue days past its due date
+// (negative = before due). Only the latest reached step runs; earlier steps that were never
+// run (e.g. policy enabled late, server down) are returned as superseded so stale reminders
+// or milder actions are not fired after a harsher one is due.
+func dueDunningSteps(steps []billing.DunningStep, daysSinceDue int, isDone func(billing.DunningStep) bool) (*billing.DunningStep, []billing.DunningStep) {
+	var pending []billing.DunningStep
+	for _, st := range steps {
+		if st.OffsetDays > daysSinceDue || isDone(st) {
+			continue
+		}
+		pending = append(pending, st)
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+	sortDunningSteps(pending)
+	last := pending[len(pending)-1]
+	return &last, pending[:len(pending)-1]
+}
+
+func dunningRunKey(invoiceID uuid.UUID, offset int, action billing.DunningAction) string {
+	return fmt.Sprintf("%s:%d:%s", invoiceID, offset, action)
+}
+
+// daysBetween returns the number of calendar days from a to b (b - a)
+func daysBetween(a, b time.Time) int {
+	da := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	db := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(db.Sub(da).Hours() / 24)
+}
+
+// ========== Execution ==========
+
+// RunForTenant executes all due dunning steps of a tenant for asOf and records every outcome.
+// Each step is performed at most once: a step left running (outcome not stored) is not retried.
+func (s *DunningService) RunForTenant(ctx context.Context, t *tenant.Tenant, asOf time.Time) (*DunningRunResult, error) {
+	result := &DunningRunResult{}
+	policy := readDunningPolicy(t.Settings)
+	if !policy.Enabled {
+		return result, nil
+	}
+
+	items, err := s.plan(ctx, t.ID, policy, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	runDate := time.Date(asOf.Year(), asOf.Month(), asOf.Day(), 0, 0, 0, 0, time.Local)
+	// A client with several overdue invoices is only isolated/throttled/terminated once per run
+	handled := make(map[string]bool)
+
+	for _, item := range items {
+		for _, st := range item.Superseded {
+			s.record(ctx, t.ID, item, st, billing.DunningStepSkipped, "superseded by a later step", runDate)
+			result.Skipped++
+		}
+
+		key := item.ClientID.String() + ":" + string(item.Step.Action)
+		if item.Step.Action != billing.DunningActionReminder && handled[key] {
+			s.record(ctx, t.ID, item, item.Step, billing.DunningStepSkipped, "already applied to client in this run", runDate)
+			result.Skipped++
+			continue
+		}
+		handled[key] = true
+
+		// The step is reserved first: if its outcome cannot be stored afterwards, the next run still
+		// sees it and does not send or isolate again
+		run := &billing.DunningStepRun{
+			ID:         uuid.New(),
+			TenantID:   t.ID,
+			ClientID:   item.ClientID,
+			InvoiceID:  item.InvoiceID,
+			OffsetDays: item.Step.OffsetDays,
+			Action:     item.Step.Action,
+			RunDate:    runDate,
+			CreatedAt:  time.Now(),
+		}
+		reserved, err := s.dunningRepo.ReserveRun(ctx, run)
+		if err != nil {
+			log.Error().Err(err).Str("invoice_id", item.InvoiceID.String()).Str("action", string(item.Step.Action)).Msg("Failed to reserve dunning step")
+			result.Failed++
+			continue
+		}
+		if !reserved {
+			continue // taken by a concurrent run
+		}
+
+		status, note := s.executeStep(ctx, t, item)
+		if err := s.dunningRepo.FinishRun(ctx, run.ID, status, note); err != nil {
+			log.Error().Err(err).Str("invoice_id", item.InvoiceID.String()).Str("action", string(item.Step.Action)).
+				Str("status", string(status)).Msg("Failed to record dunning step outcome, step stays running")
+		}
+		switch status {
+		case billing.DunningStepExecuted:
+			result.Executed++
+		case billing.DunningStepSkipped:
+			result.Skipped++
+		default:
+			result.Failed++
+		}
+	}
+	return result, nil
+}
+
+func (s *DunningService) record(ctx context.Context, tenantID uuid.UUID, item *DunningPlanItem, st billing.DunningStep, status billing.DunningStepStatus, note string, runDate time.Time) {
+	err := s.dunningRepo.RecordRun(ctx, &billing.DunningStepRun{
+		ID:         uuid.New(),
+		TenantID:   tenantID,
+		ClientID:   item.ClientID,
+		InvoiceID:  item.InvoiceID,
+		OffsetDays: st.OffsetDays,
+		Action:     st.Action,
+		Status:     status,
+		ErrorMsg:   note,
+		RunDate:    runDate,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		log.Error().Err(err).Str("invoice_id", item.InvoiceID.String()).Str("action", string(st.Action)).Msg("Failed to record dunning step")
+	}
+}
+
+// executeStep performs a single step and returns its status plus an optional note
+func (s *DunningService) executeStep(ctx context.Context, t *tenant.Tenant, item *DunningPlanItem) (billing.DunningStepStatus, string) {
+	tenantID := t.ID
+	invoiceID := item.InvoiceID
+	req := IsolateRequest{
+		InvoiceID:   &invoiceID,
+		Reason:      fmt.Sprintf("Dunning D%+d: invoice %s", item.Step.OffsetDays, item.InvoiceNumber),
+		IsAutomatic: true,
+	}
+
+	switch item.Step.Action {
+	case billing.DunningActionReminder:
+		return s.sendReminder(ctx, t, item)
+
+	case billing.DunningActionThrottle:
+		if !s.featureResolver.Has(ctx, tenantID, "isolir_auto") {
+			return billing.DunningStepSkipped, "feature isolir_auto not available"
+		}
+		if _, err := s.isolirService.Throttle(ctx, tenantID, item.ClientID, item.Step.Profile, req); err != nil {
+			if errors.Is(err, ErrClientNotActive) {
+				return billing.DunningStepSkipped, "client is not active"
+			}
+			return billing.DunningStepFailed, err.Error()
+		}
+
+	case billing.DunningActionIsolir:
+		if !s.featureResolver.Has(ctx, tenantID, "isolir_auto") {
+			return billing.DunningStepSkipped, "feature isolir_auto not available"
+		}
+		if _, err := s.isolirService.Isolate(ctx, tenantID, item.ClientID, req); err != nil {
+			if errors.Is(err, ErrClientNotActive) {
+				return billing.DunningStepSkipped, "client is not active"
+			}
+			return billing.DunningStepFailed, err.Error()
+		}
+
+	case billing.DunningActionTerminate:
+		c, err := s.clientRepo.GetByID(ctx, tenantID, item.ClientID)
+		if err != nil {
+			return billing.DunningStepFailed, err.Error()
+		}
+		if !c.CanTransitionTo(client.StatusTerminated) {
+			return billing.DunningStepSkipped, "client cannot be terminated from status " + string(c.Status)
+		}
+		if err := s.clientRepo.UpdateStatus(ctx, tenantID, item.ClientID, client.StatusTerminated, &req.Reason); err != nil {
+			return billing.DunningStepFailed, err.Error()
+		}
+		if s.billingService != nil {
+			if err := s.billingService.ProrateTermination(ctx, tenantID, c, time.Now()); err != nil {
+				log.Warn().Err(err).Str("client_id", item.ClientID.String()).Msg("Dunning: failed to prorate termination")
+			}
+		}
+
+	default:
+		return billing.DunningStepSkipped, "unknown action"
+	}
+	return billing.DunningStepExecuted, ""
+}
+
+func (s *DunningService) sendReminder(ctx context.Context, t *tenant.Tenant, item *DunningPlanItem) (billing.DunningStepStatus, string) {
+	tenantID := t.ID
+	if s.waClient == nil || !s.featureResolver.Has(ctx, tenantID, "wa_gateway") {
+		return billing.DunningStepSkipped, "WhatsApp gateway not available"
+	}
+	c, err := s.clientRepo.GetByID(ctx, tenantID, item.ClientID)
+	if err != nil {
+		return billing.DunningStepFailed, err.Error()
+	}
+	if c.Phone == nil || strings.TrimSpace(*c.Phone) == "" {
+		return billing.DunningStepSkipped, "client has no phone number"
+	}
+	inv, err := s.invoiceRepo.GetByID(ctx, item.InvoiceID)
+	if err != nil {
+		return billing.DunningStepFailed, err.Error()
+	}
+
+	text := item.Step.Message
+	if strings.TrimSpace(text) == "" {
+		text = defaultReminderText
+	}
+	msg, err := wa_template.Parse(text)
+	if err != nil {
+		return billing.DunningStepFailed, err.Error()
+	}
+	text, err = s.renderer.Render(ctx, t, c, inv, msg, nil)
+	if err != nil {
+		return billing.DunningStepFailed, err.Error()
+	}
+
+	var logID *uuid.UUID
+	if s.waLogService != nil {
+		clientID := c.ID
+		l, err := s.waLogService.CreateQueued(ctx, tenantID, CreateWALogInput{
+			Source:      wa_log.SourceSystem,
+			ClientID:    &clientID,
+			ClientName:  &c.Name,
+			ToPhone:     *c.Phone,
+			MessageText: text,
+		})
+		if err == nil {
+			logID = &l.ID
+		}
+	}
+
+	out, err := s.waClient.Send(ctx, tenantID.String(), *c.Phone, text)
+	if err != nil {
+		if logID != nil {
+			_ = s.waLogService.MarkFailed(ctx, tenantID, *logID, err.Error())
+		}
+		return billing.DunningStepFailed, err.Error()
+	}
+	if logID != nil {
+		_ = s.waLogService.MarkSent(ctx, tenantID, *logID, out.MessageID)
+	}
+	return billing.DunningStepExecuted, ""
+}
+
+// ListRuns returns recorded dunning steps (history)
+func (s *DunningService) ListRuns(ctx context.Context, filter repository.DunningRunFilter) ([]*billing.DunningStepRun, int, error) {
+	return s.dunningRepo.List(ctx, filter)
+}
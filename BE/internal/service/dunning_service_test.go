@@ -0,0 +1,82 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rrnet/internal/domain/billing"
+)
+
+func TestDueDunningSteps(t *testing.T) {
+	steps := defaultDunningSteps()
+	none := func(billing.DunningStep) bool { return false }
+
+	// H-5: nothing due yet
+	due, superseded := dueDunningSteps(steps, -5, none)
+	assert.Nil(t, due)
+	assert.Empty(t, superseded)
+
+	// H-3: first reminder
+	due, superseded = dueDunningSteps(steps, -3, none)
+	require.NotNil(t, due)
+	assert.Equal(t, -3, due.OffsetDays)
+	assert.Empty(t, superseded)
+
+	// D+7 with nothing recorded (policy enabled late): only isolir runs, earlier steps are superseded
+	due, superseded = dueDunningSteps(steps, 7, none)
+	require.NotNil(t, due)
+	assert.Equal(t, billing.DunningActionIsolir, due.Action)
+	assert.Len(t, superseded, 3)
+
+	// D+7 with every step up to isolir done: nothing left
+	doneUpTo7 := func(st billing.DunningStep) bool { return st.OffsetDays <= 7 }
+	due, _ = dueDunningSteps(steps, 7, doneUpTo7)
+	assert.Nil(t, due)
+}
+
+func TestValidateDunningPolicy(t *testing.T) {
+	assert.NoError(t, validateDunningPolicy(billing.DunningPolicy{Enabled: true, Steps: defaultDunningSteps()}))
+
+	assert.ErrorIs(t, validateDunningPolicy(billing.DunningPolicy{Steps: []billing.DunningStep{
+		{OffsetDays: 3, Action: billing.DunningActionThrottle},
+	}}), ErrDunningProfileRequired)
+
+	assert.ErrorIs(t, validateDunningPolicy(billing.DunningPolicy{Steps: []billing.DunningStep{
+		{OffsetDays: -1, Action: billing.DunningActionIsolir},
+	}}), ErrDunningStepInvalid)
+
+	assert.ErrorIs(t, validateDunningPolicy(billing.DunningPolicy{Steps: []billing.DunningStep{
+		{OffsetDays: 7, Action: billing.DunningActionIsolir},
+		{OffsetDays: 7, Action: billing.DunningActionIsolir},
+	}}), ErrDunningStepDuplicate)
+}
+
+func TestReadDunningPolicy(t *testing.T) {
+	p := readDunningPolicy(nil)
+	assert.False(t, p.Enabled)
+	assert.Len(t, p.Steps, 5)
+
+	p = readDunningPolicy(map[string]interface{}{
+		"dunning": map[string]interface{}{
+			"enabled": true,
+			"steps": []interface{}{
+				map[string]interface{}{"offset_days": float64(10), "action": "isolir"},
+				map[string]interface{}{"offset_days": float64(-2), "action": "reminder"},
+			},
+		},
+	})
+	assert.True(t, p.Enabled)
+	require.Len(t, p.Steps, 2)
+	assert.Equal(t, -2, p.Steps[0].OffsetDays)
+	assert.Equal(t, billing.DunningActionIsolir, p.Steps[1].Action)
+}
+
+func TestDaysBetween(t *testing.T) {
+	due := time.Date(2026, 3, 28, 23, 59, 59, 0, time.Local)
+	assert.Equal(t, 0, daysBetween(due, time.Date(2026, 3, 28, 0, 0, 0, 0, time.Local)))
+	assert.Equal(t, 7, daysBetween(due, time.Date(2026, 4, 4, 8, 0, 0, 0, time.Local)))
+	assert.Equal(t, -3, daysBetween(due, time.Date(2026, 3, 25, 8, 0, 0, 0, time.Local)))
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/client"
+	"rrnet/internal/domain/job"
+	"rrnet/internal/domain/tenant"
+	"rrnet/internal/repository"
+)
+
+// IsolirScheduler isolates clients with overdue invoices; it runs as the JobIsolir scheduled job
+type IsolirScheduler struct {
+	clientRepo      *repository.ClientRepository
+	invoiceRepo     *repository.InvoiceRepository
+	isolirService   *IsolirService
+	featureResolver *FeatureResolver
+}
+
+// NewIsolirScheduler creates a new isolir scheduler
+func NewIsolirScheduler(
+	clientRepo *repository.ClientRepository,
+	invoiceRepo *repository.InvoiceRepository,
+	isolirService *IsolirService,
+	featureResolver *FeatureResolver,
+) *IsolirScheduler {
+	return &IsolirScheduler{
+		clientRepo:      clientRepo,
+		invoiceRepo:     invoiceRepo,
+		isolirService:   isolirService,
+		featureResolver: featureResolver,
+	}
+}
+
+// Runner returns the executor of the auto-isolir job (JobIsolir)
+func (s *IsolirScheduler) Runner() JobRunner {
+	return JobRunner{RunTenant: s.RunForTenant}
+}
+
+// RunForTenant marks the tenant's unpaid invoices past due as overdue and isolates every active
+// PPPoE client whose oldest unpaid invoice exceeded the grace period. Isolated clients are no longer
+// active, so a retried run skips them; it fails when any invoice or client failed.
+func (s *IsolirScheduler) RunForTenant(ctx context.Context, t *tenant.Tenant, now time.Time) (job.Stats, error) {
+	stats := job.Stats{"invoices_marked_overdue": 0, "clients_isolated": 0, "errors": 0}
+	if t.Status != tenant.StatusActive {
+		return stats, nil
+	}
+	if !s.featureResolver.Has(ctx, t.ID, "isolir_auto") {
+		return stats, nil
+	}
+
+	settings := readIsolirSettings(t.Settings)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	// Flag everything past due as overdue first (independent of grace period)
+	pastDue, err := s.invoiceRepo.GetUnpaidPastDue(ctx, t.ID, today)
+	if err != nil {
+		return stats, fmt.Errorf("failed to list past-due invoices: %w", err)
+	}
+	for _, inv := range pastDue {
+		if inv.Status != billing.InvoiceStatusPending {
+			continue
+		}
+		if err := s.invoiceRepo.UpdateStatus(ctx, inv.ID, billing.InvoiceStatusOverdue); err != nil {
+			log.Error().Err(err).Str("invoice_id", inv.ID.String()).Msg("Failed to mark invoice overdue")
+			stats["errors"]++
+			continue
+		}
+		stats["invoices_marked_overdue"]++
+	}
+
+	// Tenants with a dunning policy isolate on the policy's timeline instead (see DunningService)
+	if !readDunningPolicy(t.Settings).Enabled {
+		for _, inv := range isolationCandidates(pastDue, isolirCutoff(now, settings.GraceDays)) {
+			c, err := s.clientRepo.GetByID(ctx, t.ID, inv.ClientID)
+			if err != nil {
+				log.Error().Err(err).Str("client_id", inv.ClientID.String()).Msg("Failed to get client for auto-isolir")
+				stats["errors"]++
+				continue
+			}
+			if c.Status != client.StatusActive || c.ConnectionType != client.ConnectionTypePPPoE {
+				continue
+			}
+
+			invoiceID := inv.ID
+			_, err = s.isolirService.Isolate(ctx, t.ID, c.ID, IsolateRequest{
+				InvoiceID:   &invoiceID,
+				Reason:      fmt.Sprintf("Overdue invoice %s", inv.InvoiceNumber),
+				IsAutomatic: true,
+			})
+			if err != nil {
+				log.Error().
+					Err(err).
+					Str("tenant_id", t.ID.String()).
+					Str("client_code", c.ClientCode).
+					Str("invoice_number", inv.InvoiceNumber).
+					Msg("Failed to auto-isolate client")
+				stats["errors"]++
+				continue
+			}
+			stats["clients_isolated"]++
+		}
+	}
+
+	log.Info().
+		Str("tenant_id", t.ID.String()).
+		Int64("invoices_marked_overdue", stats["invoices_marked_overdue"]).
+		Int64("clients_isolated", stats["clients_isolated"]).
+		Int64("errors", stats["errors"]).
+		Msg("Auto-isolir completed for tenant")
+
+	if stats["errors"] > 0 {
+		return stats, fmt.Errorf("%d invoices or clients failed", stats["errors"])
+	}
+	return stats, nil
+}
+
+// isolationCandidates picks, per client, the oldest invoice due before cutoff; pastDue is ordered
+// by due date
+func isolationCandidates(pastDue []*billing.Invoice, cutoff time.Time) []*billing.Invoice {
+	var out []*billing.Invoice
+	seen := make(map[uuid.UUID]bool)
+	for _, inv := range pastDue {
+		if !inv.DueDate.Before(cutoff) || seen[inv.ClientID] {
+			continue
+		}
+		seen[inv.ClientID] = true
+		out = append(out, inv)
+	}
+	return out
+}
@@ -0,0 +1,502 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/client"
+	"rrnet/internal/domain/network"
+	"rrnet/internal/domain/notification"
+	"rrnet/internal/infra/mikrotik"
+	"rrnet/internal/repository"
+)
+
+var (
+	ErrIsolirModeInvalid    = errors.New("invalid isolir mode")
+	ErrIsolirProfileMissing = errors.New("isolir profile is required for profile mode")
+	ErrIsolirGraceInvalid   = errors.New("grace days must be between 0 and 90")
+	ErrClientNotIsolated    = errors.New("client is not isolated")
+	ErrClientNotActive      = errors.New("client is not active")
+)
+
+// IsolirMode defines how isolation is enforced on the MikroTik
+type IsolirMode string
+
+const (
+	// IsolirModeProfile swaps the PPPoE secret to the isolir profile
+	IsolirModeProfile IsolirMode = "profile"
+	// IsolirModeAddressList adds the client's IP to the "isolated" firewall address-list
+	IsolirModeAddressList IsolirMode = "address_list"
+)
+
+// IsolirSettings is stored in tenant settings under "isolir"
+type IsolirSettings struct {
+	Mode      IsolirMode `json:"mode"`
+	Profile   string     `json:"profile"`
+	GraceDays int        `json:"grace_days"`
+}
+
+type IsolirService struct {
+	isolirLogRepo      *repository.IsolirLogRepository
+	clientRepo         *repository.ClientRepository
+	invoiceRepo        *repository.InvoiceRepository
+	routerRepo         *repository.RouterRepository
+	pppoeRepo          *repository.PPPoERepository
+	profileRepo        *repository.NetworkProfileRepository
+	servicePackageRepo *repository.ServicePackageRepository
+	tenantRepo         *repository.TenantRepository
+	notifier           *NotificationService
+}
+
+func NewIsolirService(
+	isolirLogRepo *repository.IsolirLogRepository,
+	clientRepo *repository.ClientRepository,
+	invoiceRepo *repository.InvoiceRepository,
+	routerRepo *repository.RouterRepository,
+	pppoeRepo *repository.PPPoERepository,
+	profileRepo *repository.NetworkProfileRepository,
+	servicePackageRepo *repository.ServicePackageRepository,
+	tenantRepo *repository.TenantRepository,
+) *IsolirService {
+	return &IsolirService{
+		isolirLogRepo:      isolirLogRepo,
+		clientRepo:         clientRepo,
+		invoiceRepo:        invoiceRepo,
+		routerRepo:         routerRepo,
+		pppoeRepo:          pppoeRepo,
+		profileRepo:        profileRepo,
+		servicePackageRepo: servicePackageRepo,
+		tenantRepo:         tenantRepo,
+	}
+}
+
+// SetNotifier sends clients a WhatsApp message when they are isolated or reactivated
+func (s *IsolirService) SetNotifier(notifier *NotificationService) {
+	s.notifier = notifier
+}
+
+// ========== Settings ==========
+
+func (s *IsolirService) GetSettings(ctx context.Context, tenantID uuid.UUID) (*IsolirSettings, error) {
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	out := readIsolirSettings(t.Settings)
+	return &out, nil
+}
+
+func (s *IsolirService) UpdateSettings(ctx context.Context, tenantID uuid.UUID, in IsolirSettings) (*IsolirSettings, error) {
+	if in.Mode != IsolirModeProfile && in.Mode != IsolirModeAddressList {
+		return nil, ErrIsolirModeInvalid
+	}
+	if in.Mode == IsolirModeProfile && in.Profile == "" {
+		return nil, ErrIsolirProfileMissing
+	}
+	if in.GraceDays < 0 || in.GraceDays > 90 {
+		return nil, ErrIsolirGraceInvalid
+	}
+
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if t.Settings == nil {
+		t.Settings = map[string]interface{}{}
+	}
+	t.Settings["isolir"] = map[string]interface{}{
+		"mode":       string(in.Mode),
+		"profile":    in.Profile,
+		"grace_days": in.GraceDays,
+	}
+	if err := s.tenantRepo.UpdateSettings(ctx, tenantID, t.Settings); err != nil {
+		return nil, err
+	}
+	return &in, nil
+}
+
+func readIsolirSettings(settings map[string]interface{}) IsolirSettings {
+	out := IsolirSettings{
+		Mode:      IsolirModeProfile,
+		Profile:   "isolir",
+		GraceDays: 0,
+	}
+	if settings == nil {
+		return out
+	}
+	raw, ok := settings["isolir"].(map[string]interface{})
+	if !ok || raw == nil {
+		return out
+	}
+	if m, ok := raw["mode"].(string); ok && m == string(IsolirModeAddressList) {
+		out.Mode = IsolirModeAddressList
+	}
+	if p, ok := raw["profile"].(string); ok && p != "" {
+		out.Profile = p
+	}
+	if g, ok := raw["grace_days"].(float64); ok && g >= 0 {
+		out.GraceDays = int(g)
+	}
+	return out
+}
+
+// ========== Isolate / Reactivate ==========
+
+// IsolateRequest describes a single isolation action
+type IsolateRequest struct {
+	InvoiceID   *uuid.UUID
+	Reason      string
+	ExecutedBy  *uuid.UUID
+	IsAutomatic bool
+}
+
+// Isolate moves a client to isolir and enforces it on the router.
+// The client status is only changed once the router step succeeded, so a failed
+// automatic isolation is retried by the next scheduler run.
+func (s *IsolirService) Isolate(ctx context.Context, tenantID, clientID uuid.UUID, req IsolateRequest) (*billing.IsolirLog, error) {
+	c, err := s.clientRepo.GetByID(ctx, tenantID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if !c.CanTransitionTo(client.StatusIsolir) {
+		return nil, ErrClientNotActive
+	}
+	settings, err := s.GetSettings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.execute(ctx, c, billing.IsolirActionIsolate, req,
+		func() (string, error) { return s.enforceIsolate(ctx, c, settings) },
+		func() error {
+			reason := req.Reason
+			return s.clientRepo.UpdateStatus(ctx, tenantID, clientID, client.StatusIsolir, &reason)
+		},
+	)
+}
+
+// Reactivate restores an isolated client on the router and sets it back to active.
+func (s *IsolirService) Reactivate(ctx context.Context, tenantID, clientID uuid.UUID, req IsolateRequest) (*billing.IsolirLog, error) {
+	c, err := s.clientRepo.GetByID(ctx, tenantID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if c.Status != client.StatusIsolir {
+		return nil, ErrClientNotIsolated
+	}
+	settings, err := s.GetSettings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	throttled, err := s.isolirLogRepo.HasExecutedAction(ctx, tenantID, clientID, billing.IsolirActionThrottle)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.execute(ctx, c, billing.IsolirActionReactivate, req,
+		func() (string, error) { return s.enforceReactivate(ctx, c, settings, throttled) },
+		func() error {
+			if err := s.clientRepo.UpdateStatus(ctx, tenantID, clientID, client.StatusActive, nil); err != nil {
+				return err
+			}
+			if err := s.isolirLogRepo.MarkClientIsolationsReverted(ctx, tenantID, clientID); err != nil {
+				log.Warn().Err(err).Str("client_id", clientID.String()).Msg("Failed to mark isolations as reverted")
+			}
+			return nil
+		},
+	)
+}
+
+// Throttle swaps an active client's PPPoE secret to a reduced-speed profile without changing its status.
+func (s *IsolirService) Throttle(ctx context.Context, tenantID, clientID uuid.UUID, profileName string, req IsolateRequest) (*billing.IsolirLog, error) {
+	c, err := s.clientRepo.GetByID(ctx, tenantID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if c.Status != client.StatusActive {
+		return nil, ErrClientNotActive
+	}
+
+	return s.execute(ctx, c, billing.IsolirActionThrottle, req,
+		func() (string, error) { return s.enforceProfile(ctx, c, profileName) },
+		nil,
+	)
+}
+
+// Unthrottle restores the original PPPoE profile of a throttled client.
+func (s *IsolirService) Unthrottle(ctx context.Context, tenantID, clientID uuid.UUID, req IsolateRequest) (*billing.IsolirLog, error) {
+	c, err := s.clientRepo.GetByID(ctx, tenantID, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.execute(ctx, c, billing.IsolirActionUnthrottle, req,
+		func() (string, error) { return s.enforceRestoreProfile(ctx, c) },
+		func() error {
+			if err := s.isolirLogRepo.MarkClientIsolationsReverted(ctx, tenantID, clientID); err != nil {
+				log.Warn().Err(err).Str("client_id", clientID.String()).Msg("Failed to mark throttle as reverted")
+			}
+			return nil
+		},
+	)
+}
+
+// ReactivateIfSettled lifts isolir or throttling once no invoice past the grace period remains.
+// Called after a payment fully settles an invoice.
+func (s *IsolirService) ReactivateIfSettled(ctx context.Context, tenantID, clientID uuid.UUID, invoiceID *uuid.UUID) error {
+	c, err := s.clientRepo.GetByID(ctx, tenantID, clientID)
+	if err != nil {
+		return err
+	}
+
+	throttled := false
+	if c.Status == client.StatusActive {
+		throttled, err = s.isolirLogRepo.HasExecutedAction(ctx, tenantID, clientID, billing.IsolirActionThrottle)
+		if err != nil {
+			return err
+		}
+	}
+	if c.Status != client.StatusIsolir && !throttled {
+		return nil
+	}
+
+	settings, err := s.GetSettings(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	outstanding, err := s.invoiceRepo.HasUnpaidPastDue(ctx, tenantID, clientID, isolirCutoff(time.Now(), settings.GraceDays))
+	if err != nil {
+		return err
+	}
+	if outstanding {
+		return nil
+	}
+
+	req := IsolateRequest{
+		InvoiceID:   invoiceID,
+		Reason:      "Invoice paid",
+		IsAutomatic: true,
+	}
+	if throttled {
+		_, err = s.Unthrottle(ctx, tenantID, clientID, req)
+		return err
+	}
+	_, err = s.Reactivate(ctx, tenantID, clientID, req)
+	return err
+}
+
+// isolirCutoff is the due date from which unpaid invoices are still within the grace period;
+// invoices due before it get the client isolated
+func isolirCutoff(now time.Time, graceDays int) time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	return today.AddDate(0, 0, -graceDays)
+}
+
+func (s *IsolirService) ListLogs(ctx context.Context, filter repository.IsolirLogFilter) ([]*billing.IsolirLog, int, error) {
+	return s.isolirLogRepo.List(ctx, filter)
+}
+
+func (s *IsolirService) createLog(ctx context.Context, c *client.Client, action billing.IsolirAction, req IsolateRequest) (*billing.IsolirLog, error) {
+	entry := &billing.IsolirLog{
+		ID:          uuid.New(),
+		TenantID:    c.TenantID,
+		ClientID:    c.ID,
+		InvoiceID:   req.InvoiceID,
+		Action:      action,
+		Reason:      req.Reason,
+		Status:      billing.IsolirStatusPending,
+		ExecutedBy:  req.ExecutedBy,
+		IsAutomatic: req.IsAutomatic,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.isolirLogRepo.Create(ctx, entry); err != nil {
+		return nil, fmt.Errorf("failed to create isolir log: %w", err)
+	}
+	return entry, nil
+}
+
+// execute logs an action as pending, runs the router step and the follow-up DB change,
+// and marks the log executed or failed accordingly.
+func (s *IsolirService) execute(
+	ctx context.Context,
+	c *client.Client,
+	action billing.IsolirAction,
+	req IsolateRequest,
+	enforce func() (string, error),
+	after func() error,
+) (*billing.IsolirLog, error) {
+	entry, err := s.createLog(ctx, c, action, req)
+	if err != nil {
+		return nil, err
+	}
+
+	note, enforceErr := enforce()
+	if enforceErr != nil {
+		if err := s.isolirLogRepo.MarkFailed(ctx, entry.ID, enforceErr.Error()); err != nil {
+			log.Error().Err(err).Str("log_id", entry.ID.String()).Msg("Failed to mark isolir log as failed")
+		}
+		entry.Status = billing.IsolirStatusFailed
+		entry.ErrorMsg = enforceErr.Error()
+		return entry, fmt.Errorf("%s enforcement failed: %w", action, enforceErr)
+	}
+
+	if after != nil {
+		if err := after(); err != nil {
+			_ = s.isolirLogRepo.MarkFailed(ctx, entry.ID, err.Error())
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	if err := s.isolirLogRepo.MarkExecuted(ctx, entry.ID, now, note); err != nil {
+		log.Error().Err(err).Str("log_id", entry.ID.String()).Msg("Failed to mark isolir log as executed")
+	}
+	entry.Status = billing.IsolirStatusExecuted
+	entry.ExecutedAt = &now
+	entry.ErrorMsg = note
+
+	if s.notifier != nil {
+		event := notification.EventClientIsolated
+		if action == billing.IsolirActionReactivate {
+			event = notification.EventClientReactivated
+		}
+		if action == billing.IsolirActionIsolate || action == billing.IsolirActionReactivate {
+			s.notifier.Raise(ctx, c.TenantID, event, c.ID, req.InvoiceID, entry.ID.String(), map[string]string{"isolir.reason": req.Reason})
+		}
+	}
+	return entry, nil
+}
+
+// ========== MikroTik enforcement ==========
+
+// resolveRouter returns the client's router and PPPoE username, or a note explaining why
+// the router step is skipped (e.g. hotspot clients or clients without a router binding).
+func (s *IsolirService) resolveRouter(ctx context.Context, c *client.Client) (*network.Router, string, string, error) {
+	if c.ConnectionType != client.ConnectionTypePPPoE {
+		return nil, "", "router step skipped: client is not a PPPoE client", nil
+	}
+	if c.RouterID == nil || c.PPPoEUsername == nil || *c.PPPoEUsername == "" {
+		return nil, "", "router step skipped: client has no router/PPPoE binding", nil
+	}
+	router, err := s.routerRepo.GetByID(ctx, *c.RouterID)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to get router: %w", err)
+	}
+	return router, *c.PPPoEUsername, "", nil
+}
+
+func (s *IsolirService) enforceIsolate(ctx context.Context, c *client.Client, settings *IsolirSettings) (string, error) {
+	if settings.Mode != IsolirModeAddressList {
+		return s.enforceProfile(ctx, c, settings.Profile)
+	}
+
+	router, username, note, err := s.resolveRouter(ctx, c)
+	if err != nil || router == nil {
+		return note, err
+	}
+	addr := net.JoinHostPort(router.Host, strconv.Itoa(router.APIPort))
+
+	ip, err := mikrotik.GetPPPoEActiveAddress(ctx, addr, router.APIUseTLS, router.Username, router.Password, username)
+	if err != nil {
+		if c.PPPoERemoteAddress == nil || *c.PPPoERemoteAddress == "" {
+			return "", fmt.Errorf("cannot resolve client IP for address-list: %w", err)
+		}
+		ip = *c.PPPoERemoteAddress
+	}
+	if err := mikrotik.AddToIsolatedList(ctx, addr, router.APIUseTLS, router.Username, router.Password, ip, isolirComment(c)); err != nil {
+		return "", err
+	}
+	s.kickSession(ctx, router, addr, username, c)
+	return "", nil
+}
+
+func (s *IsolirService) enforceReactivate(ctx context.Context, c *client.Client, settings *IsolirSettings, throttled bool) (string, error) {
+	if settings.Mode != IsolirModeAddressList {
+		return s.enforceRestoreProfile(ctx, c)
+	}
+
+	router, username, note, err := s.resolveRouter(ctx, c)
+	if err != nil || router == nil {
+		return note, err
+	}
+	addr := net.JoinHostPort(router.Host, strconv.Itoa(router.APIPort))
+
+	if err := mikrotik.RemoveFromIsolatedList(ctx, addr, router.APIUseTLS, router.Username, router.Password, isolirComment(c)); err != nil {
+		return "", err
+	}
+	// Address-list isolation keeps the PPPoE profile, so a prior throttle must be undone as well
+	if throttled {
+		return s.enforceRestoreProfile(ctx, c)
+	}
+	s.kickSession(ctx, router, addr, username, c)
+	return "", nil
+}
+
+// enforceProfile swaps the client's PPPoE secret to profileName and kicks the active session.
+func (s *IsolirService) enforceProfile(ctx context.Context, c *client.Client, profileName string) (string, error) {
+	router, username, note, err := s.resolveRouter(ctx, c)
+	if err != nil || router == nil {
+		return note, err
+	}
+	addr := net.JoinHostPort(router.Host, strconv.Itoa(router.APIPort))
+
+	if err := mikrotik.SetPPPoESecretProfile(ctx, addr, router.APIUseTLS, router.Username, router.Password, username, profileName); err != nil {
+		return "", err
+	}
+	s.kickSession(ctx, router, addr, username, c)
+	return "", nil
+}
+
+// enforceRestoreProfile puts the client's original PPPoE profile back.
+func (s *IsolirService) enforceRestoreProfile(ctx context.Context, c *client.Client) (string, error) {
+	router, username, note, err := s.resolveRouter(ctx, c)
+	if err != nil || router == nil {
+		return note, err
+	}
+	profileName, err := s.originalProfileName(ctx, c, username)
+	if err != nil {
+		return "", err
+	}
+	return s.enforceProfile(ctx, c, profileName)
+}
+
+// kickSession disconnects the active PPPoE session so the new profile / address-list applies immediately
+func (s *IsolirService) kickSession(ctx context.Context, router *network.Router, addr, username string, c *client.Client) {
+	if err := mikrotik.DisconnectPPPoEUser(ctx, addr, router.APIUseTLS, router.Username, router.Password, username); err != nil {
+		log.Warn().Err(err).Str("client_code", c.ClientCode).Msg("Failed to disconnect PPPoE session")
+	}
+}
+
+// originalProfileName resolves the profile to restore: the PPPoE secret's profile, falling back to the service package's profile.
+func (s *IsolirService) originalProfileName(ctx context.Context, c *client.Client, username string) (string, error) {
+	var profileID *uuid.UUID
+	if secret, err := s.pppoeRepo.GetByUsername(ctx, c.TenantID, username); err == nil && secret.ProfileID != uuid.Nil {
+		profileID = &secret.ProfileID
+	}
+	if profileID == nil && c.ServicePackageID != nil {
+		pkg, err := s.servicePackageRepo.GetByID(ctx, c.TenantID, *c.ServicePackageID)
+		if err == nil && pkg.NetworkProfileID != uuid.Nil {
+			profileID = &pkg.NetworkProfileID
+		}
+	}
+	if profileID == nil {
+		return "", errors.New("cannot resolve original PPPoE profile")
+	}
+	profile, err := s.profileRepo.GetByID(ctx, *profileID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get network profile: %w", err)
+	}
+	return profile.Name, nil
+}
+
+func isolirComment(c *client.Client) string {
+	return "client:" + c.ClientCode
+}
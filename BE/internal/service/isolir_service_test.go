@@ -0,0 +1,67 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rrnet/internal/domain/billing"
+)
+
+func TestIsolirCutoff(t *testing.T) {
+	now := time.Date(2026, 4, 10, 8, 30, 0, 0, time.Local)
+	assert.Equal(t, time.Date(2026, 4, 10, 0, 0, 0, 0, time.Local), isolirCutoff(now, 0))
+	assert.Equal(t, time.Date(2026, 4, 3, 0, 0, 0, 0, time.Local), isolirCutoff(now, 7))
+	assert.Equal(t, time.Date(2026, 3, 31, 0, 0, 0, 0, time.Local), isolirCutoff(now, 10))
+}
+
+func TestIsolationCandidates(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	inv := func(client uuid.UUID, number string, due time.Time) *billing.Invoice {
+		return &billing.Invoice{ID: uuid.New(), ClientID: client, InvoiceNumber: number, DueDate: due}
+	}
+	// As returned by GetUnpaidPastDue: oldest due date first
+	pastDue := []*billing.Invoice{
+		inv(a, "INV-A1", time.Date(2026, 2, 10, 0, 0, 0, 0, time.Local)),
+		inv(b, "INV-B1", time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)),
+		inv(a, "INV-A2", time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)),
+		inv(c, "INV-C1", time.Date(2026, 4, 5, 0, 0, 0, 0, time.Local)),
+	}
+
+	// 7 grace days on 10 April: C is overdue but still within the grace period
+	got := isolationCandidates(pastDue, isolirCutoff(time.Date(2026, 4, 10, 1, 0, 0, 0, time.Local), 7))
+	require.Len(t, got, 2)
+	assert.Equal(t, "INV-A1", got[0].InvoiceNumber) // one isolation per client, for its oldest invoice
+	assert.Equal(t, "INV-B1", got[1].InvoiceNumber)
+
+	// Due on the cutoff day itself is not past the grace period yet
+	got = isolationCandidates(pastDue, time.Date(2026, 4, 5, 0, 0, 0, 0, time.Local))
+	assert.Len(t, got, 2)
+	got = isolationCandidates(pastDue, time.Date(2026, 4, 6, 0, 0, 0, 0, time.Local))
+	assert.Len(t, got, 3)
+
+	assert.Empty(t, isolationCandidates(pastDue, time.Date(2026, 2, 10, 0, 0, 0, 0, time.Local)))
+}
+
+func TestReadIsolirSettings(t *testing.T) {
+	s := readIsolirSettings(nil)
+	assert.Equal(t, IsolirSettings{Mode: IsolirModeProfile, Profile: "isolir"}, s)
+
+	s = readIsolirSettings(map[string]interface{}{
+		"isolir": map[string]interface{}{"mode": "address_list", "profile": "", "grace_days": float64(5)},
+	})
+	assert.Equal(t, IsolirModeAddressList, s.Mode)
+	assert.Equal(t, "isolir", s.Profile)
+	assert.Equal(t, 5, s.GraceDays)
+
+	// Unknown modes and negative grace days fall back to the defaults
+	s = readIsolirSettings(map[string]interface{}{
+		"isolir": map[string]interface{}{"mode": "firewall", "profile": "blokir", "grace_days": float64(-3)},
+	})
+	assert.Equal(t, IsolirModeProfile, s.Mode)
+	assert.Equal(t, "blokir", s.Profile)
+	assert.Equal(t, 0, s.GraceDays)
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/domain/job"
+	"rrnet/internal/domain/tenant"
+)
+
+// LateFeeScheduler charges late fees on overdue invoices; it runs as the JobLateFees scheduled job
+type LateFeeScheduler struct {
+	lateFeeService *LateFeeService
+}
+
+// NewLateFeeScheduler creates a new late fee scheduler
+func NewLateFeeScheduler(lateFeeService *LateFeeService) *LateFeeScheduler {
+	return &LateFeeScheduler{
+		lateFeeService: lateFeeService,
+	}
+}
+
+// Runner returns the executor of the late fee job (JobLateFees)
+func (s *LateFeeScheduler) Runner() JobRunner {
+	return JobRunner{RunTenant: s.RunForTenant}
+}
+
+// RunForTenant applies the late fee policy of the tenant. Fees are recorded per invoice and
+// sequence, so a retried run does not charge them twice.
+func (s *LateFeeScheduler) RunForTenant(ctx context.Context, t *tenant.Tenant, now time.Time) (job.Stats, error) {
+	stats := job.Stats{"fees_charged": 0, "amount_charged": 0, "fees_failed": 0}
+	if t.Status != tenant.StatusActive {
+		return stats, nil
+	}
+	res, err := s.lateFeeService.RunForTenant(ctx, t, now)
+	if err != nil {
+		return stats, err
+	}
+	stats["fees_charged"] = int64(res.Charged)
+	stats["amount_charged"] = res.Amount
+	stats["fees_failed"] = int64(res.Failed)
+
+	log.Info().
+		Str("tenant_id", t.ID.String()).
+		Int("fees_charged", res.Charged).
+		Int64("amount_charged", res.Amount).
+		Int("fees_failed", res.Failed).
+		Msg("Late fees completed for tenant")
+	return stats, nil
+}
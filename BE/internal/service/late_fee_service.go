@@ -0,0 +1,525 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/client"
+	"rrnet/internal/domain/tenant"
+	"rrnet/internal/repository"
+)
+
+var (
+	ErrLateFeeRuleInvalid         = errors.New("invalid late fee rule")
+	ErrLateFeeModeInvalid         = errors.New("late fee mode must be item or invoice")
+	ErrLateFeeWaiveReasonRequired = errors.New("waive reason is required")
+)
+
+const (
+	lateFeeMaxDays = 365
+	// lateFeeDueDays is the default payment term of penalty invoices
+	lateFeeDueDays = 7
+)
+
+// LateFeeRunResult summarizes one scheduler run for a tenant
+type LateFeeRunResult struct {
+	Charged int
+	Amount  int64
+	Failed  int
+}
+
+type LateFeeService struct {
+	tenantRepo     *repository.TenantRepository
+	clientRepo     *repository.ClientRepository
+	invoiceRepo    *repository.InvoiceRepository
+	lateFeeRepo    *repository.LateFeeRepository
+	billingService *BillingService
+}
+
+func NewLateFeeService(
+	tenantRepo *repository.TenantRepository,
+	clientRepo *repository.ClientRepository,
+	invoiceRepo *repository.InvoiceRepository,
+	lateFeeRepo *repository.LateFeeRepository,
+	billingService *BillingService,
+) *LateFeeService {
+	return &LateFeeService{
+		tenantRepo:     tenantRepo,
+		clientRepo:     clientRepo,
+		invoiceRepo:    invoiceRepo,
+		lateFeeRepo:    lateFeeRepo,
+		billingService: billingService,
+	}
+}
+
+// ========== Policy ==========
+
+func (s *LateFeeService) GetPolicy(ctx context.Context, tenantID uuid.UUID) (*billing.LateFeePolicy, error) {
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	out := readLateFeePolicy(t.Settings)
+	return &out, nil
+}
+
+func (s *LateFeeService) UpdatePolicy(ctx context.Context, tenantID uuid.UUID, in billing.LateFeePolicy) (*billing.LateFeePolicy, error) {
+	if in.Mode == "" {
+		in.Mode = billing.LateFeeModeItem
+	}
+	if in.Mode != billing.LateFeeModeItem && in.Mode != billing.LateFeeModeInvoice {
+		return nil, ErrLateFeeModeInvalid
+	}
+	if in.DueDays == 0 {
+		in.DueDays = lateFeeDueDays
+	}
+	if in.DueDays < 0 || in.DueDays > lateFeeMaxDays {
+		return nil, ErrLateFeeRuleInvalid
+	}
+	if err := validateLateFeeRule(in.Rule); err != nil {
+		return nil, err
+	}
+	packageRules := make(map[string]interface{}, len(in.PackageRules))
+	for pkgID, rule := range in.PackageRules {
+		if pkgID == uuid.Nil {
+			return nil, ErrLateFeeRuleInvalid
+		}
+		if err := validateLateFeeRule(rule); err != nil {
+			return nil, err
+		}
+		packageRules[pkgID.String()] = lateFeeRuleSettings(rule)
+	}
+	if in.PackageRules == nil {
+		in.PackageRules = map[uuid.UUID]billing.LateFeeRule{}
+	}
+
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if t.Settings == nil {
+		t.Settings = map[string]interface{}{}
+	}
+	t.Settings["late_fee"] = map[string]interface{}{
+		"enabled":       in.Enabled,
+		"mode":          string(in.Mode),
+		"rule":          lateFeeRuleSettings(in.Rule),
+		"package_rules": packageRules,
+		"due_days":      in.DueDays,
+	}
+	if err := s.tenantRepo.UpdateSettings(ctx, tenantID, t.Settings); err != nil {
+		return nil, err
+	}
+	return &in, nil
+}
+
+func validateLateFeeRule(r billing.LateFeeRule) error {
+	switch r.Type {
+	case billing.LateFeeFlat:
+		if r.Amount <= 0 {
+			return ErrLateFeeRuleInvalid
+		}
+	case billing.LateFeePercent:
+		if r.Percent <= 0 || r.Percent > 100 {
+			return ErrLateFeeRuleInvalid
+		}
+	default:
+		return ErrLateFeeRuleInvalid
+	}
+	if r.GraceDays < 0 || r.GraceDays > lateFeeMaxDays || r.RepeatDays < 0 || r.RepeatDays > lateFeeMaxDays || r.MaxTotal < 0 {
+		return ErrLateFeeRuleInvalid
+	}
+	return nil
+}
+
+func lateFeeRuleSettings(r billing.LateFeeRule) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        string(r.Type),
+		"amount":      r.Amount,
+		"percent":     r.Percent,
+		"grace_days":  r.GraceDays,
+		"repeat_days": r.RepeatDays,
+		"max_total":   r.MaxTotal,
+	}
+}
+
+func readLateFeeRule(raw map[string]interface{}) billing.LateFeeRule {
+	out := billing.LateFeeRule{Type: billing.LateFeeFlat}
+	if v, ok := raw["type"].(string); ok && v != "" {
+		out.Type = billing.LateFeeType(v)
+	}
+	if v, ok := raw["amount"].(float64); ok {
+		out.Amount = int64(v)
+	}
+	if v, ok := raw["percent"].(float64); ok {
+		out.Percent = v
+	}
+	if v, ok := raw["grace_days"].(float64); ok {
+		out.GraceDays = int(v)
+	}
+	if v, ok := raw["repeat_days"].(float64); ok {
+		out.RepeatDays = int(v)
+	}
+	if v, ok := raw["max_total"].(float64); ok {
+		out.MaxTotal = int64(v)
+	}
+	return out
+}
+
+func readLateFeePolicy(settings map[string]interface{}) billing.LateFeePolicy {
+	out := billing.LateFeePolicy{
+		Mode:         billing.LateFeeModeItem,
+		Rule:         billing.LateFeeRule{Type: billing.LateFeeFlat},
+		PackageRules: map[uuid.UUID]billing.LateFeeRule{},
+		DueDays:      lateFeeDueDays,
+	}
+	raw, ok := settings["late_fee"].(map[string]interface{})
+	if !ok || raw == nil {
+		return out
+	}
+	if v, ok := raw["enabled"].(bool); ok {
+		out.Enabled = v
+	}
+	if v, ok := raw["mode"].(string); ok && v != "" {
+		out.Mode = billing.LateFeeMode(v)
+	}
+	if v, ok := raw["rule"].(map[string]interface{}); ok {
+		out.Rule = readLateFeeRule(v)
+	}
+	if v, ok := raw["due_days"].(float64); ok && v > 0 {
+		out.DueDays = int(v)
+	}
+	if rules, ok := raw["package_rules"].(map[string]interface{}); ok {
+		for k, v := range rules {
+			id, err := uuid.Parse(k)
+			m, ok := v.(map[string]interface{})
+			if err != nil || !ok {
+				continue
+			}
+			out.PackageRules[id] = readLateFeeRule(m)
+		}
+	}
+	return out
+}
+
+// lateFeeRuleFor returns the rule that applies to a client (package override or the default rule)
+func lateFeeRuleFor(p billing.LateFeePolicy, c *client.Client) billing.LateFeeRule {
+	if c != nil && c.ServicePackageID != nil {
+		if rule, ok := p.PackageRules[*c.ServicePackageID]; ok {
+			return rule
+		}
+	}
+	return p.Rule
+}
+
+// dueLateFees returns the amounts of the fees that are due for an invoice daysOverdue days past its
+// due date, when `assessed` fees were charged before and `charged` of that still counts towards the
+// cap (waived fees do not). Fees missed by earlier runs are caught up; percent fees are computed from
+// the outstanding amount without earlier fees.
+func dueLateFees(rule billing.LateFeeRule, daysOverdue, assessed int, charged, outstanding int64) []int64 {
+	if daysOverdue <= rule.GraceDays {
+		return nil
+	}
+	count := 1
+	if rule.RepeatDays > 0 {
+		count += (daysOverdue - rule.GraceDays - 1) / rule.RepeatDays
+	}
+
+	var out []int64
+	for n := assessed; n < count; n++ {
+		amount := rule.Amount
+		if rule.Type == billing.LateFeePercent {
+			amount = int64(math.Round(float64(outstanding) * rule.Percent / 100))
+		}
+		if rule.MaxTotal > 0 && charged+amount > rule.MaxTotal {
+			amount = rule.MaxTotal - charged
+		}
+		if amount <= 0 {
+			break
+		}
+		out = append(out, amount)
+		charged += amount
+	}
+	return out
+}
+
+// ========== Execution ==========
+
+// lateFeeState is what was charged before for one overdue invoice
+type lateFeeState struct {
+	assessed int
+	charged  int64 // not waived, counts towards the cap
+	onSelf   int64 // not waived and billed on the overdue invoice itself
+	lastFee  *billing.LateFee
+}
+
+// RunForTenant charges all late fees that are due on asOf
+func (s *LateFeeService) RunForTenant(ctx context.Context, t *tenant.Tenant, asOf time.Time) (*LateFeeRunResult, error) {
+	result := &LateFeeRunResult{}
+	policy := readLateFeePolicy(t.Settings)
+	if !policy.Enabled {
+		return result, nil
+	}
+
+	day := time.Date(asOf.Year(), asOf.Month(), asOf.Day(), 0, 0, 0, 0, time.Local)
+	invoices, err := s.invoiceRepo.GetUnpaidPastDue(ctx, t.ID, day)
+	if err != nil {
+		return nil, err
+	}
+	if len(invoices) == 0 {
+		return result, nil
+	}
+	invoiceIDs := make([]uuid.UUID, 0, len(invoices))
+	for _, inv := range invoices {
+		invoiceIDs = append(invoiceIDs, inv.ID)
+	}
+	fees, err := s.lateFeeRepo.ListByInvoices(ctx, t.ID, invoiceIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[uuid.UUID]*lateFeeState)
+	penalty := make(map[uuid.UUID]bool)
+	for _, f := range fees {
+		if f.ChargedInvoiceID != f.InvoiceID {
+			penalty[f.ChargedInvoiceID] = true
+		}
+		st, ok := states[f.InvoiceID]
+		if !ok {
+			st = &lateFeeState{}
+			states[f.InvoiceID] = st
+		}
+		if f.Sequence > st.assessed {
+			st.assessed = f.Sequence
+			st.lastFee = f
+		}
+		if !f.IsWaived() {
+			st.charged += f.Amount
+			if f.ChargedInvoiceID == f.InvoiceID {
+				st.onSelf += f.Amount
+			}
+		}
+	}
+
+	clients := make(map[uuid.UUID]*client.Client)
+	for _, inv := range invoices {
+		// No fees on fees
+		if penalty[inv.ID] {
+			continue
+		}
+		c, ok := clients[inv.ClientID]
+		if !ok {
+			c, err = s.clientRepo.GetByID(ctx, t.ID, inv.ClientID)
+			if err != nil {
+				log.Warn().Err(err).Str("client_id", inv.ClientID.String()).Msg("Late fee: failed to get client")
+				continue
+			}
+			clients[inv.ClientID] = c
+		}
+		if c.Status == client.StatusTerminated {
+			continue
+		}
+
+		st := states[inv.ID]
+		if st == nil {
+			st = &lateFeeState{}
+		}
+		outstanding := inv.RemainingAmount() - st.onSelf
+		amounts := dueLateFees(lateFeeRuleFor(policy, c), daysBetween(inv.DueDate, day), st.assessed, st.charged, outstanding)
+		for _, amount := range amounts {
+			fee, err := s.charge(ctx, policy, inv, st, amount, day)
+			if err != nil {
+				log.Error().Err(err).Str("invoice_id", inv.ID.String()).Int("sequence", st.assessed+1).Msg("Failed to charge late fee")
+				result.Failed++
+				break
+			}
+			st.assessed = fee.Sequence
+			st.lastFee = fee
+			result.Charged++
+			result.Amount += amount
+		}
+	}
+	return result, nil
+}
+
+// charge records the next fee of inv and bills it. The fee row is written first so that the unique
+// (invoice, sequence) constraint prevents double charges; it is removed again if billing fails.
+func (s *LateFeeService) charge(ctx context.Context, policy billing.LateFeePolicy, inv *billing.Invoice, st *lateFeeState, amount int64, day time.Time) (*billing.LateFee, error) {
+	fee := &billing.LateFee{
+		ID:               uuid.New(),
+		TenantID:         inv.TenantID,
+		ClientID:         inv.ClientID,
+		InvoiceID:        inv.ID,
+		ChargedInvoiceID: inv.ID,
+		Sequence:         st.assessed + 1,
+		Amount:           amount,
+		AssessedAt:       day,
+		CreatedAt:        time.Now(),
+	}
+	description := fmt.Sprintf("Denda keterlambatan %s (ke-%d)", inv.InvoiceNumber, fee.Sequence)
+
+	var target *billing.Invoice
+	if policy.Mode == billing.LateFeeModeInvoice {
+		target = s.openPenaltyInvoice(ctx, st.lastFee)
+		if target != nil {
+			fee.ChargedInvoiceID = target.ID
+		}
+	} else {
+		target = inv
+	}
+
+	if target == nil {
+		// New penalty invoice: the fee row references it, so the invoice is created first
+		penaltyInv, err := s.createPenaltyInvoice(ctx, inv, amount, description, day, policy.DueDays)
+		if err != nil {
+			return nil, err
+		}
+		fee.ChargedInvoiceID = penaltyInv.ID
+		if err := s.lateFeeRepo.Create(ctx, fee); err != nil {
+			if cerr := s.billingService.CancelInvoice(ctx, penaltyInv.ID); cerr != nil {
+				log.Error().Err(cerr).Str("invoice_id", penaltyInv.ID.String()).Msg("Failed to cancel orphaned penalty invoice")
+			}
+			return nil, err
+		}
+		return fee, nil
+	}
+
+	if err := s.lateFeeRepo.Create(ctx, fee); err != nil {
+		return nil, err
+	}
+	item := billing.InvoiceItem{
+		ID:          uuid.New(),
+		InvoiceID:   target.ID,
+		Description: description,
+		Quantity:    1,
+		UnitPrice:   lineAmountFor(target, amount),
+		Amount:      lineAmountFor(target, amount),
+		CreatedAt:   fee.CreatedAt,
+	}
+	if err := s.invoiceRepo.AppendItems(ctx, target.ID, []billing.InvoiceItem{item}); err != nil {
+		if derr := s.lateFeeRepo.Delete(ctx, fee.ID); derr != nil {
+			log.Error().Err(derr).Str("late_fee_id", fee.ID.String()).Msg("Failed to remove unbilled late fee")
+		}
+		return nil, err
+	}
+	s.billingService.postInvoiceChange(ctx, target, item)
+	return fee, nil
+}
+
+// openPenaltyInvoice returns the penalty invoice of the previous fee while it can still take more fees
+func (s *LateFeeService) openPenaltyInvoice(ctx context.Context, last *billing.LateFee) *billing.Invoice {
+	if last == nil || last.ChargedInvoiceID == last.InvoiceID {
+		return nil
+	}
+	inv, err := s.invoiceRepo.GetByID(ctx, last.ChargedInvoiceID)
+	if err != nil || inv.PaidAmount > 0 {
+		return nil
+	}
+	if inv.Status != billing.InvoiceStatusPending && inv.Status != billing.InvoiceStatusOverdue {
+		return nil
+	}
+	return inv
+}
+
+// createPenaltyInvoice bills a fee on a new invoice. It gets its own payment term: due on the day it
+// is created, it would be overdue at once and picked up by isolir, dunning and the overdue reminders.
+func (s *LateFeeService) createPenaltyInvoice(ctx context.Context, inv *billing.Invoice, amount int64, description string, day time.Time, dueDays int) (*billing.Invoice, error) {
+	// The fee includes tax; the line is converted with the rate the new invoice will get
+	rate, inclusive := s.billingService.invoiceTaxRate(ctx, inv.TenantID, inv.ClientID)
+	line := lineAmountFor(&billing.Invoice{TaxRate: rate, TaxInclusive: inclusive}, amount)
+	return s.billingService.CreateInvoice(ctx, inv.TenantID, CreateInvoiceRequest{
+		ClientID:    inv.ClientID,
+		PeriodStart: day,
+		PeriodEnd:   day,
+		DueDate:     penaltyDueDate(day, dueDays),
+		Items:       []InvoiceItemRequest{{Description: description, Quantity: 1, UnitPrice: line}},
+		Notes:       fmt.Sprintf("Denda keterlambatan pembayaran %s", inv.InvoiceNumber),
+	})
+}
+
+// penaltyDueDate returns the end of the last day a penalty invoice created on `day` can be paid
+func penaltyDueDate(day time.Time, dueDays int) time.Time {
+	if dueDays < 1 {
+		dueDays = lateFeeDueDays
+	}
+	due := day.AddDate(0, 0, dueDays)
+	return time.Date(due.Year(), due.Month(), due.Day(), 23, 59, 59, 0, due.Location())
+}
+
+// ========== Queries & waivers ==========
+
+// ListInvoiceLateFees returns the fees charged for, or billed on, an invoice
+func (s *LateFeeService) ListInvoiceLateFees(ctx context.Context, tenantID, invoiceID uuid.UUID) ([]*billing.LateFee, error) {
+	inv, err := s.invoiceRepo.GetByID(ctx, invoiceID)
+	if err != nil || inv.TenantID != tenantID {
+		return nil, ErrInvoiceNotFound
+	}
+	fees, err := s.lateFeeRepo.ListByInvoices(ctx, tenantID, []uuid.UUID{invoiceID})
+	if err != nil {
+		return nil, err
+	}
+	if fees == nil {
+		fees = []*billing.LateFee{}
+	}
+	return fees, nil
+}
+
+// Waive takes a fee off the invoice it was billed on with a credit note. A fee that was already paid
+// is credited to the client's balance by the credit note.
+func (s *LateFeeService) Waive(ctx context.Context, tenantID, userID, feeID uuid.UUID, reason string) (*billing.LateFee, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrLateFeeWaiveReasonRequired
+	}
+	fee, err := s.lateFeeRepo.GetByID(ctx, tenantID, feeID)
+	if err != nil {
+		return nil, err
+	}
+	if fee.IsWaived() {
+		return nil, repository.ErrLateFeeAlreadyWaived
+	}
+	now := time.Now()
+	if err := s.lateFeeRepo.MarkWaived(ctx, fee.ID, userID, reason, now); err != nil {
+		return nil, err
+	}
+
+	charged, err := s.invoiceRepo.GetByID(ctx, fee.ChargedInvoiceID)
+	if err != nil {
+		s.clearWaiver(ctx, fee.ID)
+		return nil, fmt.Errorf("failed to load invoice of late fee: %w", err)
+	}
+	// A cancelled invoice is not owed anymore, only the waiver is recorded
+	if charged.Status != billing.InvoiceStatusCancelled {
+		cn, err := s.billingService.IssueCreditNote(ctx, tenantID, userID, charged.ID, CreditNoteRequest{
+			Amount: fee.Amount,
+			Reason: fmt.Sprintf("Pembebasan denda keterlambatan (ke-%d): %s", fee.Sequence, reason),
+		})
+		if err != nil {
+			s.clearWaiver(ctx, fee.ID)
+			return nil, err
+		}
+		if err := s.lateFeeRepo.SetCreditNote(ctx, fee.ID, cn.ID); err != nil {
+			log.Warn().Err(err).Str("late_fee_id", fee.ID.String()).Msg("Failed to link credit note to waived late fee")
+		}
+		fee.CreditNoteID = &cn.ID
+	}
+
+	fee.WaivedAt = &now
+	fee.WaivedBy = &userID
+	fee.WaiveReason = reason
+	return fee, nil
+}
+
+func (s *LateFeeService) clearWaiver(ctx context.Context, feeID uuid.UUID) {
+	if err := s.lateFeeRepo.ClearWaiver(ctx, feeID); err != nil {
+		log.Error().Err(err).Str("late_fee_id", feeID.String()).Msg("Failed to undo late fee waiver")
+	}
+}
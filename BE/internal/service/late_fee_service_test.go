@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rrnet/internal/domain/billing"
+)
+
+func TestDueLateFees(t *testing.T) {
+	flat := billing.LateFeeRule{Type: billing.LateFeeFlat, Amount: 10000, GraceDays: 3}
+
+	// Within the grace period nothing is charged
+	assert.Nil(t, dueLateFees(flat, 3, 0, 0, 200000))
+
+	// One-off fee on the first day after the grace period, never repeated
+	assert.Equal(t, []int64{10000}, dueLateFees(flat, 4, 0, 0, 200000))
+	assert.Nil(t, dueLateFees(flat, 30, 1, 10000, 200000))
+
+	// Repeating every 7 days: D+4, D+11, D+18; missed runs are caught up
+	weekly := flat
+	weekly.RepeatDays = 7
+	assert.Len(t, dueLateFees(weekly, 10, 0, 0, 200000), 1)
+	assert.Len(t, dueLateFees(weekly, 11, 1, 10000, 200000), 1)
+	assert.Equal(t, []int64{10000, 10000, 10000}, dueLateFees(weekly, 18, 0, 0, 200000))
+
+	// The cap cuts the last fee short and stops further fees
+	weekly.MaxTotal = 25000
+	assert.Equal(t, []int64{10000, 10000, 5000}, dueLateFees(weekly, 18, 0, 0, 200000))
+	assert.Nil(t, dueLateFees(weekly, 40, 3, 25000, 200000))
+
+	// A waived fee no longer counts towards the cap
+	assert.Equal(t, []int64{10000}, dueLateFees(weekly, 25, 3, 15000, 200000))
+
+	// Percent of the outstanding amount
+	pct := billing.LateFeeRule{Type: billing.LateFeePercent, Percent: 2.5}
+	assert.Equal(t, []int64{5000}, dueLateFees(pct, 1, 0, 0, 200000))
+	assert.Nil(t, dueLateFees(pct, 1, 0, 0, 0))
+}
+
+func TestValidateLateFeeRule(t *testing.T) {
+	assert.NoError(t, validateLateFeeRule(billing.LateFeeRule{Type: billing.LateFeeFlat, Amount: 5000}))
+	assert.NoError(t, validateLateFeeRule(billing.LateFeeRule{Type: billing.LateFeePercent, Percent: 2}))
+	assert.Error(t, validateLateFeeRule(billing.LateFeeRule{Type: billing.LateFeeFlat}))
+	assert.Error(t, validateLateFeeRule(billing.LateFeeRule{Type: billing.LateFeePercent, Percent: 150}))
+	assert.Error(t, validateLateFeeRule(billing.LateFeeRule{Type: "daily", Amount: 5000}))
+	assert.Error(t, validateLateFeeRule(billing.LateFeeRule{Type: billing.LateFeeFlat, Amount: 5000, GraceDays: -1}))
+}
+
+func TestReadLateFeePolicy(t *testing.T) {
+	pkgID := uuid.New()
+	raw := []byte(`{"late_fee": {"enabled": true, "mode": "invoice",
+		"rule": {"type": "flat", "amount": 15000, "grace_days": 5, "repeat_days": 30, "max_total": 45000},
+		"package_rules": {"` + pkgID.String() + `": {"type": "percent", "percent": 5}, "not-a-uuid": {"type": "flat"}}}}`)
+	var settings map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &settings))
+
+	p := readLateFeePolicy(settings)
+	assert.True(t, p.Enabled)
+	assert.Equal(t, billing.LateFeeModeInvoice, p.Mode)
+	assert.Equal(t, billing.LateFeeRule{Type: billing.LateFeeFlat, Amount: 15000, GraceDays: 5, RepeatDays: 30, MaxTotal: 45000}, p.Rule)
+	require.Len(t, p.PackageRules, 1)
+	assert.Equal(t, 5.0, p.PackageRules[pkgID].Percent)
+
+	assert.Equal(t, lateFeeDueDays, p.DueDays)
+
+	empty := readLateFeePolicy(nil)
+	assert.False(t, empty.Enabled)
+	assert.Equal(t, billing.LateFeeModeItem, empty.Mode)
+
+	settings["late_fee"].(map[string]interface{})["due_days"] = float64(14)
+	assert.Equal(t, 14, readLateFeePolicy(settings).DueDays)
+}
+
+func TestPenaltyDueDate(t *testing.T) {
+	day := time.Date(2026, 4, 28, 0, 0, 0, 0, time.Local)
+
+	// Not due on the day it is created, so isolir, dunning and reminders leave it alone until the term ends
+	due := penaltyDueDate(day, 7)
+	assert.Equal(t, time.Date(2026, 5, 5, 23, 59, 59, 0, time.Local), due)
+	penalty := []*billing.Invoice{{ClientID: uuid.New(), DueDate: due}}
+	assert.Empty(t, isolationCandidates(penalty, isolirCutoff(day.AddDate(0, 0, 1), 0)))
+	assert.Len(t, isolationCandidates(penalty, isolirCutoff(day.AddDate(0, 0, 8), 0)), 1)
+
+	assert.Equal(t, time.Date(2026, 4, 29, 23, 59, 59, 0, time.Local), penaltyDueDate(day, 1))
+	assert.Equal(t, penaltyDueDate(day, lateFeeDueDays), penaltyDueDate(day, 0))
+
+	// The term is validated with the policy
+	_, err := (&LateFeeService{}).UpdatePolicy(context.Background(), uuid.New(), billing.LateFeePolicy{
+		Rule:    billing.LateFeeRule{Type: billing.LateFeeFlat, Amount: 5000},
+		DueDays: -1,
+	})
+	assert.ErrorIs(t, err, ErrLateFeeRuleInvalid)
+}
@@ -0,0 +1,316 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/collector"
+	"rrnet/internal/domain/ledger"
+)
+
+// Automatic postings. Every billing document is posted once (the entry is keyed by source and
+// source ID), and posting never fails the action that triggered it: errors are logged and the
+// books can be corrected with a manual entry.
+
+// posting is a journal entry under construction on the system accounts
+type posting struct {
+	tenantID    uuid.UUID
+	date        time.Time
+	source      ledger.Source
+	sourceID    uuid.UUID
+	description string
+	clientID    *uuid.UUID
+	createdBy   *uuid.UUID
+	lines       []postingLine
+}
+
+type postingLine struct {
+	key    ledger.SystemKey
+	debit  int64
+	credit int64
+}
+
+// debit adds a debit; a negative amount is a credit
+func (p *posting) debit(key ledger.SystemKey, amount int64) {
+	switch {
+	case amount > 0:
+		p.lines = append(p.lines, postingLine{key: key, debit: amount})
+	case amount < 0:
+		p.lines = append(p.lines, postingLine{key: key, credit: -amount})
+	}
+}
+
+// credit adds a credit; a negative amount is a debit
+func (p *posting) credit(key ledger.SystemKey, amount int64) {
+	p.debit(key, -amount)
+}
+
+// invoiceAmounts posts an invoice total (or a change of it): receivable and discounts against tax
+// and revenue
+func (p *posting) invoiceAmounts(total, tax, discount int64) {
+	p.debit(ledger.KeyReceivable, total)
+	p.debit(ledger.KeyDiscounts, discount)
+	p.credit(ledger.KeyTaxPayable, tax)
+	p.credit(ledger.KeyRevenue, total-tax+discount)
+}
+
+// netLines merges the lines per account into one debit or credit, in the order accounts first
+// appear; accounts that net to zero are dropped
+func (p *posting) netLines() []postingLine {
+	var order []ledger.SystemKey
+	net := make(map[ledger.SystemKey]int64)
+	for _, l := range p.lines {
+		if _, ok := net[l.key]; !ok {
+			order = append(order, l.key)
+		}
+		net[l.key] += l.debit - l.credit
+	}
+	var out []postingLine
+	for _, key := range order {
+		switch n := net[key]; {
+		case n > 0:
+			out = append(out, postingLine{key: key, debit: n})
+		case n < 0:
+			out = append(out, postingLine{key: key, credit: -n})
+		}
+	}
+	return out
+}
+
+// moneyAccount is the account a payment method moves money in or out of
+func moneyAccount(method billing.PaymentMethod) ledger.SystemKey {
+	switch method {
+	case billing.PaymentMethodCash, billing.PaymentMethodCollector, "":
+		return ledger.KeyCash
+	}
+	return ledger.KeyBank
+}
+
+// post stores p on the tenant's system accounts
+func (s *LedgerService) post(ctx context.Context, p *posting) {
+	lines := p.netLines()
+	if len(lines) == 0 {
+		return
+	}
+	logger := log.With().Str("source", string(p.source)).Str("source_id", p.sourceID.String()).Logger()
+	ids, err := s.systemAccountIDs(ctx, p.tenantID)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to load ledger accounts")
+		return
+	}
+	e := &ledger.Entry{
+		ID:          uuid.New(),
+		TenantID:    p.tenantID,
+		Date:        ledgerDate(p.date),
+		Source:      p.source,
+		SourceID:    p.sourceID,
+		Description: p.description,
+		ClientID:    p.clientID,
+		CreatedBy:   p.createdBy,
+		CreatedAt:   time.Now(),
+	}
+	for _, l := range lines {
+		accountID, ok := ids[l.key]
+		if !ok {
+			logger.Error().Str("account", string(l.key)).Msg("Ledger system account missing")
+			return
+		}
+		e.Lines = append(e.Lines, &ledger.Line{ID: uuid.New(), EntryID: e.ID, AccountID: accountID, Debit: l.debit, Credit: l.credit})
+	}
+	if !e.Balanced() {
+		logger.Error().Msg("Unbalanced ledger posting skipped")
+		return
+	}
+	if _, err := s.ledgerRepo.Post(ctx, e); err != nil {
+		logger.Error().Err(err).Msg("Failed to post ledger entry")
+	}
+}
+
+func invoicePosting(inv *billing.Invoice) *posting {
+	p := &posting{
+		tenantID:    inv.TenantID,
+		date:        inv.CreatedAt,
+		source:      ledger.SourceInvoice,
+		sourceID:    inv.ID,
+		description: "Tagihan " + inv.InvoiceNumber,
+		clientID:    &inv.ClientID,
+	}
+	p.invoiceAmounts(inv.TotalAmount, inv.TaxAmount, inv.DiscountAmount)
+	return p
+}
+
+// PostInvoice books an issued invoice: Dr receivable (and discounts), Cr revenue (and tax)
+func (s *LedgerService) PostInvoice(ctx context.Context, inv *billing.Invoice) {
+	s.post(ctx, invoicePosting(inv))
+}
+
+func invoiceChangePosting(before, after *billing.Invoice, changeID uuid.UUID, description string, at time.Time) *posting {
+	p := &posting{
+		tenantID:    after.TenantID,
+		date:        at,
+		source:      ledger.SourceInvoiceChange,
+		sourceID:    changeID,
+		description: fmt.Sprintf("%s (%s)", description, after.InvoiceNumber),
+		clientID:    &after.ClientID,
+	}
+	p.invoiceAmounts(after.TotalAmount-before.TotalAmount, after.TaxAmount-before.TaxAmount, after.DiscountAmount-before.DiscountAmount)
+	return p
+}
+
+// PostInvoiceChange books the difference of an invoice after items were appended to it (late fees,
+// termination credits); changeID is the appended item
+func (s *LedgerService) PostInvoiceChange(ctx context.Context, before, after *billing.Invoice, changeID uuid.UUID, description string) {
+	s.post(ctx, invoiceChangePosting(before, after, changeID, description, time.Now()))
+}
+
+func creditNotePosting(cn *billing.CreditNote, before, after *billing.Invoice, excess int64) *posting {
+	p := &posting{
+		tenantID:    cn.TenantID,
+		date:        cn.CreatedAt,
+		source:      ledger.SourceCreditNote,
+		sourceID:    cn.ID,
+		description: fmt.Sprintf("Nota kredit %s untuk %s", cn.CreditNoteNumber, after.InvoiceNumber),
+		clientID:    &cn.ClientID,
+		createdBy:   cn.ApprovedBy,
+	}
+	p.invoiceAmounts(after.TotalAmount-before.TotalAmount, after.TaxAmount-before.TaxAmount, after.DiscountAmount-before.DiscountAmount)
+	// What the client paid above the reduced total is owed back as client credit
+	p.debit(ledger.KeyReceivable, excess)
+	p.credit(ledger.KeyClientCredit, excess)
+	return p
+}
+
+// PostCreditNote books a credit note: revenue and tax are reduced against the receivable, or
+// against client credit for the part already paid
+func (s *LedgerService) PostCreditNote(ctx context.Context, cn *billing.CreditNote, before, after *billing.Invoice, excess int64) {
+	s.post(ctx, creditNotePosting(cn, before, after, excess))
+}
+
+func invoiceCancelPosting(inv *billing.Invoice, at time.Time) *posting {
+	p := &posting{
+		tenantID:    inv.TenantID,
+		date:        at,
+		source:      ledger.SourceInvoiceCancel,
+		sourceID:    inv.ID,
+		description: "Pembatalan " + inv.InvoiceNumber,
+		clientID:    &inv.ClientID,
+	}
+	if inv.PaidAmount == 0 {
+		p.invoiceAmounts(-inv.TotalAmount, -inv.TaxAmount, -inv.DiscountAmount)
+		return p
+	}
+	// Partly paid: only the open rest is written off
+	rest := inv.TotalAmount - inv.PaidAmount
+	p.debit(ledger.KeyRevenue, rest)
+	p.credit(ledger.KeyReceivable, rest)
+	return p
+}
+
+// PostInvoiceCancel reverses what is still open of a cancelled invoice
+func (s *LedgerService) PostInvoiceCancel(ctx context.Context, inv *billing.Invoice) {
+	s.post(ctx, invoiceCancelPosting(inv, time.Now()))
+}
+
+func paymentPosting(payment *billing.Payment, inv *billing.Invoice, excess int64, collected bool) *posting {
+	p := &posting{
+		tenantID:    payment.TenantID,
+		date:        payment.ReceivedAt,
+		source:      ledger.SourcePayment,
+		sourceID:    payment.ID,
+		description: fmt.Sprintf("Pembayaran %s (%s)", inv.InvoiceNumber, payment.Method),
+		clientID:    &payment.ClientID,
+		createdBy:   &payment.CreatedByUserID,
+	}
+	p.debit(moneyAccount(payment.Method), payment.Amount)
+	if collected {
+		// The receivable was settled at the collector's visit; the deposit empties the float
+		p.credit(ledger.KeyCollectorFloat, payment.Amount)
+		p.debit(ledger.KeyReceivable, excess)
+	} else {
+		p.credit(ledger.KeyReceivable, payment.Amount-excess)
+	}
+	p.credit(ledger.KeyClientCredit, excess)
+	return p
+}
+
+// PostPayment books a payment of one invoice. excess is the part credited to the client balance;
+// collected marks the deposit of cash a collector took at a visit (see PostCollection).
+func (s *LedgerService) PostPayment(ctx context.Context, payment *billing.Payment, inv *billing.Invoice, excess int64, collected bool) {
+	s.post(ctx, paymentPosting(payment, inv, excess, collected))
+}
+
+func clientPaymentPosting(cp *billing.ClientPayment) *posting {
+	p := &posting{
+		tenantID:    cp.TenantID,
+		date:        cp.ReceivedAt,
+		source:      ledger.SourceClientPayment,
+		sourceID:    cp.ID,
+		description: fmt.Sprintf("Pembayaran pelanggan (%s)", cp.Method),
+		clientID:    &cp.ClientID,
+		createdBy:   &cp.CreatedByUserID,
+	}
+	p.debit(moneyAccount(cp.Method), cp.Amount)
+	p.credit(ledger.KeyReceivable, cp.Amount-cp.CreditedAmount)
+	p.credit(ledger.KeyClientCredit, cp.CreditedAmount)
+	return p
+}
+
+// PostClientPayment books a payment allocated over several invoices as one entry
+func (s *LedgerService) PostClientPayment(ctx context.Context, cp *billing.ClientPayment) {
+	s.post(ctx, clientPaymentPosting(cp))
+}
+
+func balanceEntryPosting(e *billing.BalanceEntry, method billing.PaymentMethod) *posting {
+	p := &posting{
+		tenantID:    e.TenantID,
+		date:        e.CreatedAt,
+		source:      ledger.SourceClientBalance,
+		sourceID:    e.ID,
+		description: e.Description,
+		clientID:    &e.ClientID,
+		createdBy:   e.CreatedBy,
+	}
+	switch e.Type {
+	case billing.BalanceCreditApplied:
+		p.debit(ledger.KeyClientCredit, -e.Amount)
+		p.credit(ledger.KeyReceivable, -e.Amount)
+	case billing.BalanceRefund:
+		p.debit(ledger.KeyClientCredit, -e.Amount)
+		p.credit(moneyAccount(method), -e.Amount)
+	case billing.BalanceAdjustment:
+		p.debit(ledger.KeyRevenue, e.Amount)
+		p.credit(ledger.KeyClientCredit, e.Amount)
+	}
+	// Overpayments and credit note excesses are part of the payment and credit note entries
+	return p
+}
+
+// PostBalanceEntry books a movement of the client balance; method is the payout method of refunds
+func (s *LedgerService) PostBalanceEntry(ctx context.Context, e *billing.BalanceEntry, method billing.PaymentMethod) {
+	s.post(ctx, balanceEntryPosting(e, method))
+}
+
+func collectionPosting(v *collector.Visit, inv *billing.Invoice) *posting {
+	p := &posting{
+		tenantID:    v.TenantID,
+		date:        v.VisitedAt,
+		source:      ledger.SourceCollection,
+		sourceID:    v.ID,
+		description: "Ditagih kolektor " + inv.InvoiceNumber,
+		clientID:    &inv.ClientID,
+	}
+	p.debit(ledger.KeyCollectorFloat, v.Amount)
+	p.credit(ledger.KeyReceivable, v.Amount)
+	return p
+}
+
+// PostCollection books cash a collector took at a successful visit: the receivable moves to the
+// collector float until finance confirms the deposit
+func (s *LedgerService) PostCollection(ctx context.Context, v *collector.Visit, inv *billing.Invoice) {
+	s.post(ctx, collectionPosting(v, inv))
+}
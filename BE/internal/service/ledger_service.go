@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"rrnet/internal/domain/ledger"
+	"rrnet/internal/repository"
+)
+
+var (
+	ErrLedgerAccountInvalid  = errors.New("ledger account needs a code, a name and a valid type")
+	ErrLedgerEntryInvalid    = errors.New("journal entry needs a description and at least two lines with either a positive debit or a positive credit")
+	ErrLedgerEntryUnbalanced = errors.New("journal entry debits must equal credits")
+	ErrLedgerDateInvalid     = errors.New("dates must be formatted as YYYY-MM-DD")
+	ErrLedgerPeriodInvalid   = errors.New("from must not be after to")
+)
+
+// LedgerService keeps the double-entry books of a tenant. Billing and collector actions post their
+// entries automatically (see ledger_posting.go); finance adds manual entries for opening balances
+// and corrections. Documents from before the ledger existed are not posted and need an opening entry.
+type LedgerService struct {
+	ledgerRepo *repository.LedgerRepository
+
+	// System account IDs per tenant; accounts are never deleted, so they are cached for good
+	systemAccounts sync.Map // uuid.UUID -> map[ledger.SystemKey]uuid.UUID
+}
+
+func NewLedgerService(ledgerRepo *repository.LedgerRepository) *LedgerService {
+	return &LedgerService{ledgerRepo: ledgerRepo}
+}
+
+// systemAccountIDs returns the system accounts of a tenant, creating them on first use
+func (s *LedgerService) systemAccountIDs(ctx context.Context, tenantID uuid.UUID) (map[ledger.SystemKey]uuid.UUID, error) {
+	if ids, ok := s.systemAccounts.Load(tenantID); ok {
+		return ids.(map[ledger.SystemKey]uuid.UUID), nil
+	}
+	if err := s.ledgerRepo.EnsureSystemAccounts(ctx, tenantID); err != nil {
+		return nil, err
+	}
+	ids, err := s.ledgerRepo.SystemAccountIDs(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	s.systemAccounts.Store(tenantID, ids)
+	return ids, nil
+}
+
+// ========== Accounts ==========
+
+func (s *LedgerService) ListAccounts(ctx context.Context, tenantID uuid.UUID) ([]*ledger.Account, error) {
+	if _, err := s.systemAccountIDs(ctx, tenantID); err != nil {
+		return nil, err
+	}
+	return s.ledgerRepo.ListAccounts(ctx, tenantID)
+}
+
+type LedgerAccountRequest struct {
+	Code string             `json:"code"`
+	Name string             `json:"name"`
+	Type ledger.AccountType `json:"type"`
+}
+
+// CreateAccount adds an account for manual entries, e.g. an expense or a second bank account
+func (s *LedgerService) CreateAccount(ctx context.Context, tenantID uuid.UUID, req LedgerAccountRequest) (*ledger.Account, error) {
+	req.Code = strings.TrimSpace(req.Code)
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Code == "" || len(req.Code) > 20 || req.Name == "" || len(req.Name) > 100 || !req.Type.Valid() {
+		return nil, ErrLedgerAccountInvalid
+	}
+	// System accounts first, so they keep their codes
+	if _, err := s.systemAccountIDs(ctx, tenantID); err != nil {
+		return nil, err
+	}
+	a := &ledger.Account{
+		ID:        uuid.New(),
+		TenantID:  tenantID,
+		Code:      req.Code,
+		Name:      req.Name,
+		Type:      req.Type,
+		CreatedAt: time.Now(),
+	}
+	if err := s.ledgerRepo.CreateAccount(ctx, a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// ========== Journal ==========
+
+func (s *LedgerService) ListEntries(ctx context.Context, filter repository.LedgerEntryFilter) ([]*ledger.Entry, int, error) {
+	return s.ledgerRepo.ListEntries(ctx, filter)
+}
+
+func (s *LedgerService) GetEntry(ctx context.Context, tenantID, id uuid.UUID) (*ledger.Entry, error) {
+	return s.ledgerRepo.GetEntry(ctx, tenantID, id)
+}
+
+type ManualEntryLine struct {
+	AccountID uuid.UUID `json:"account_id"`
+	Debit     int64     `json:"debit,omitempty"`
+	Credit    int64     `json:"credit,omitempty"`
+	Memo      *string   `json:"memo,omitempty"`
+}
+
+type ManualEntryRequest struct {
+	Date        string            `json:"date,omitempty"` // YYYY-MM-DD, default today
+	Description string            `json:"description"`
+	ClientID    *uuid.UUID        `json:"client_id,omitempty"`
+	Lines       []ManualEntryLine `json:"lines"`
+}
+
+// CreateManualEntry posts a balanced entry on accounts of the tenant. Entries are never edited; a
+// mistake is corrected by a reversing entry.
+func (s *LedgerService) CreateManualEntry(ctx context.Context, tenantID, userID uuid.UUID, req ManualEntryRequest) (*ledger.Entry, error) {
+	now := time.Now()
+	date := ledgerDate(now)
+	if req.Date != "" {
+		d, err := time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			return nil, ErrLedgerDateInvalid
+		}
+		date = d
+	}
+	req.Description = strings.TrimSpace(req.Description)
+	if req.Description == "" {
+		return nil, ErrLedgerEntryInvalid
+	}
+
+	e := &ledger.Entry{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		Date:        date,
+		Source:      ledger.SourceManual,
+		Description: req.Description,
+		ClientID:    req.ClientID,
+		CreatedBy:   &userID,
+		CreatedAt:   now,
+	}
+	e.SourceID = e.ID
+	for _, l := range req.Lines {
+		if _, err := s.ledgerRepo.GetAccount(ctx, tenantID, l.AccountID); err != nil {
+			return nil, err
+		}
+		e.Lines = append(e.Lines, &ledger.Line{
+			ID:        uuid.New(),
+			EntryID:   e.ID,
+			AccountID: l.AccountID,
+			Debit:     l.Debit,
+			Credit:    l.Credit,
+			Memo:      l.Memo,
+		})
+	}
+	if err := validateManualEntry(e); err != nil {
+		return nil, err
+	}
+	if _, err := s.ledgerRepo.Post(ctx, e); err != nil {
+		return nil, err
+	}
+	return s.ledgerRepo.GetEntry(ctx, tenantID, e.ID)
+}
+
+func validateManualEntry(e *ledger.Entry) error {
+	if len(e.Lines) < 2 {
+		return ErrLedgerEntryInvalid
+	}
+	for _, l := range e.Lines {
+		if l.Debit < 0 || l.Credit < 0 || (l.Debit == 0) == (l.Credit == 0) {
+			return ErrLedgerEntryInvalid
+		}
+	}
+	if !e.Balanced() {
+		return ErrLedgerEntryUnbalanced
+	}
+	return nil
+}
+
+// ========== Reports ==========
+
+// accountBalance is the balance of an account on its normal side
+func accountBalance(t ledger.AccountType, debit, credit int64) int64 {
+	if t.DebitNormal() {
+		return debit - credit
+	}
+	return credit - debit
+}
+
+// TrialBalance lists the totals of every account up to and including asOf
+func (s *LedgerService) TrialBalance(ctx context.Context, tenantID uuid.UUID, asOf time.Time) (*ledger.TrialBalance, error) {
+	if _, err := s.systemAccountIDs(ctx, tenantID); err != nil {
+		return nil, err
+	}
+	rows, err := s.ledgerRepo.TrialBalance(ctx, tenantID, asOf)
+	if err != nil {
+		return nil, err
+	}
+	return buildTrialBalance(rows, asOf), nil
+}
+
+func buildTrialBalance(rows []*ledger.TrialBalanceRow, asOf time.Time) *ledger.TrialBalance {
+	tb := &ledger.TrialBalance{AsOf: asOf.Format("2006-01-02"), Rows: rows}
+	for _, row := range rows {
+		row.Balance = accountBalance(row.AccountType, row.Debit, row.Credit)
+		tb.TotalDebit += row.Debit
+		tb.TotalCredit += row.Credit
+	}
+	tb.Balanced = tb.TotalDebit == tb.TotalCredit
+	return tb
+}
+
+// GeneralLedger lists the postings of an account between from and to (inclusive) with the running
+// balance, starting from the balance before from
+func (s *LedgerService) GeneralLedger(ctx context.Context, tenantID, accountID uuid.UUID, from, to time.Time) (*ledger.GeneralLedger, error) {
+	if to.Before(from) {
+		return nil, ErrLedgerPeriodInvalid
+	}
+	account, err := s.ledgerRepo.GetAccount(ctx, tenantID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	debit, credit, err := s.ledgerRepo.AccountTotals(ctx, account.ID, from)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := s.ledgerRepo.AccountLines(ctx, account.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	gl := buildGeneralLedger(account, accountBalance(account.Type, debit, credit), lines)
+	gl.From = from.Format("2006-01-02")
+	gl.To = to.Format("2006-01-02")
+	return gl, nil
+}
+
+func buildGeneralLedger(account *ledger.Account, opening int64, lines []*ledger.LedgerLine) *ledger.GeneralLedger {
+	gl := &ledger.GeneralLedger{Account: account, OpeningBalance: opening, Lines: lines}
+	balance := opening
+	for _, l := range lines {
+		balance += accountBalance(account.Type, l.Debit, l.Credit)
+		l.Balance = balance
+		gl.TotalDebit += l.Debit
+		gl.TotalCredit += l.Credit
+	}
+	gl.ClosingBalance = balance
+	return gl
+}
+
+// ledgerDate is the calendar day of t, as stored in DATE columns
+func ledgerDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/collector"
+	"rrnet/internal/domain/ledger"
+)
+
+// postingAmounts nets a posting into debit (+) or credit (-) per account
+func postingAmounts(p *posting) map[ledger.SystemKey]int64 {
+	out := make(map[ledger.SystemKey]int64)
+	var debit, credit int64
+	for _, l := range p.netLines() {
+		out[l.key] = l.debit - l.credit
+		debit += l.debit
+		credit += l.credit
+	}
+	if debit != credit {
+		out["unbalanced"] = debit - credit
+	}
+	return out
+}
+
+func TestInvoicePosting(t *testing.T) {
+	inv := &billing.Invoice{ID: uuid.New(), InvoiceNumber: "INV-1", Subtotal: 200000, DiscountAmount: 20000, TaxAmount: 19800, TotalAmount: 199800}
+	assert.Equal(t, map[ledger.SystemKey]int64{
+		ledger.KeyReceivable: 199800,
+		ledger.KeyDiscounts:  20000,
+		ledger.KeyTaxPayable: -19800,
+		ledger.KeyRevenue:    -200000,
+	}, postingAmounts(invoicePosting(inv)))
+
+	// A late fee appended to the invoice posts the difference only
+	after := *inv
+	after.Subtotal += 10000
+	after.TaxAmount += 1100
+	after.TotalAmount += 11100
+	assert.Equal(t, map[ledger.SystemKey]int64{
+		ledger.KeyReceivable: 11100,
+		ledger.KeyTaxPayable: -1100,
+		ledger.KeyRevenue:    -10000,
+	}, postingAmounts(invoiceChangePosting(inv, &after, uuid.New(), "Denda", time.Now())))
+}
+
+func TestCreditNotePosting(t *testing.T) {
+	before := &billing.Invoice{ID: uuid.New(), TotalAmount: 111000, TaxAmount: 11000, PaidAmount: 111000}
+	after := *before
+	after.TotalAmount, after.TaxAmount = 55500, 5500
+	cn := &billing.CreditNote{ID: uuid.New(), Amount: 55500}
+
+	// Fully paid invoice: the whole credit goes to the client balance
+	assert.Equal(t, map[ledger.SystemKey]int64{
+		ledger.KeyRevenue:      50000,
+		ledger.KeyTaxPayable:   5500,
+		ledger.KeyClientCredit: -55500,
+	}, postingAmounts(creditNotePosting(cn, before, &after, 55500)))
+
+	// Unpaid invoice: the receivable is reduced
+	before.PaidAmount, after.PaidAmount = 0, 0
+	assert.Equal(t, map[ledger.SystemKey]int64{
+		ledger.KeyRevenue:    50000,
+		ledger.KeyTaxPayable: 5500,
+		ledger.KeyReceivable: -55500,
+	}, postingAmounts(creditNotePosting(cn, before, &after, 0)))
+}
+
+func TestInvoiceCancelPosting(t *testing.T) {
+	inv := &billing.Invoice{ID: uuid.New(), TotalAmount: 111000, TaxAmount: 11000}
+	assert.Equal(t, map[ledger.SystemKey]int64{
+		ledger.KeyReceivable: -111000,
+		ledger.KeyTaxPayable: 11000,
+		ledger.KeyRevenue:    100000,
+	}, postingAmounts(invoiceCancelPosting(inv, time.Now())))
+
+	inv.PaidAmount = 100000
+	assert.Equal(t, map[ledger.SystemKey]int64{
+		ledger.KeyRevenue:    11000,
+		ledger.KeyReceivable: -11000,
+	}, postingAmounts(invoiceCancelPosting(inv, time.Now())))
+}
+
+func TestPaymentPosting(t *testing.T) {
+	inv := &billing.Invoice{ID: uuid.New(), InvoiceNumber: "INV-1", TotalAmount: 100000}
+	transfer := &billing.Payment{ID: uuid.New(), Amount: 120000, Method: billing.PaymentMethodBankTransfer}
+	assert.Equal(t, map[ledger.SystemKey]int64{
+		ledger.KeyBank:         120000,
+		ledger.KeyReceivable:   -100000,
+		ledger.KeyClientCredit: -20000,
+	}, postingAmounts(paymentPosting(transfer, inv, 20000, false)))
+
+	cash := &billing.Payment{ID: uuid.New(), Amount: 100000, Method: billing.PaymentMethodCash}
+	assert.Equal(t, map[ledger.SystemKey]int64{
+		ledger.KeyCash:       100000,
+		ledger.KeyReceivable: -100000,
+	}, postingAmounts(paymentPosting(cash, inv, 0, false)))
+
+	// A collector visit moves the receivable to the float; the confirmed deposit empties it
+	visit := &collector.Visit{ID: uuid.New(), Amount: 100000, VisitedAt: time.Now()}
+	assert.Equal(t, map[ledger.SystemKey]int64{
+		ledger.KeyCollectorFloat: 100000,
+		ledger.KeyReceivable:     -100000,
+	}, postingAmounts(collectionPosting(visit, inv)))
+
+	deposit := &billing.Payment{ID: uuid.New(), Amount: 100000, Method: billing.PaymentMethodCollector}
+	assert.Equal(t, map[ledger.SystemKey]int64{
+		ledger.KeyCash:           100000,
+		ledger.KeyCollectorFloat: -100000,
+	}, postingAmounts(paymentPosting(deposit, inv, 0, true)))
+
+	// Paid elsewhere in part meanwhile: the surplus of the deposit becomes client credit
+	assert.Equal(t, map[ledger.SystemKey]int64{
+		ledger.KeyCash:           100000,
+		ledger.KeyCollectorFloat: -100000,
+		ledger.KeyReceivable:     30000,
+		ledger.KeyClientCredit:   -30000,
+	}, postingAmounts(paymentPosting(deposit, inv, 30000, true)))
+}
+
+func TestClientPaymentAndBalancePostings(t *testing.T) {
+	cp := &billing.ClientPayment{ID: uuid.New(), Amount: 250000, CreditedAmount: 50000, Method: billing.PaymentMethodQRIS}
+	assert.Equal(t, map[ledger.SystemKey]int64{
+		ledger.KeyBank:         250000,
+		ledger.KeyReceivable:   -200000,
+		ledger.KeyClientCredit: -50000,
+	}, postingAmounts(clientPaymentPosting(cp)))
+
+	applied := &billing.BalanceEntry{ID: uuid.New(), Type: billing.BalanceCreditApplied, Amount: -40000}
+	assert.Equal(t, map[ledger.SystemKey]int64{
+		ledger.KeyClientCredit: 40000,
+		ledger.KeyReceivable:   -40000,
+	}, postingAmounts(balanceEntryPosting(applied, "")))
+
+	refund := &billing.BalanceEntry{ID: uuid.New(), Type: billing.BalanceRefund, Amount: -40000}
+	assert.Equal(t, map[ledger.SystemKey]int64{
+		ledger.KeyClientCredit: 40000,
+		ledger.KeyCash:         -40000,
+	}, postingAmounts(balanceEntryPosting(refund, billing.PaymentMethodCash)))
+
+	// Overpayments are booked with their payment
+	over := &billing.BalanceEntry{ID: uuid.New(), Type: billing.BalanceOverpayment, Amount: 40000}
+	assert.Empty(t, balanceEntryPosting(over, "").netLines())
+}
+
+func TestValidateManualEntry(t *testing.T) {
+	line := func(debit, credit int64) *ledger.Line {
+		return &ledger.Line{AccountID: uuid.New(), Debit: debit, Credit: credit}
+	}
+	assert.NoError(t, validateManualEntry(&ledger.Entry{Lines: []*ledger.Line{line(1000, 0), line(0, 600), line(0, 400)}}))
+	assert.ErrorIs(t, validateManualEntry(&ledger.Entry{Lines: []*ledger.Line{line(1000, 0), line(0, 900)}}), ErrLedgerEntryUnbalanced)
+	assert.ErrorIs(t, validateManualEntry(&ledger.Entry{Lines: []*ledger.Line{line(1000, 0)}}), ErrLedgerEntryInvalid)
+	assert.ErrorIs(t, validateManualEntry(&ledger.Entry{Lines: []*ledger.Line{line(1000, 1000), line(0, 0)}}), ErrLedgerEntryInvalid)
+	assert.ErrorIs(t, validateManualEntry(&ledger.Entry{Lines: []*ledger.Line{line(-1000, 0), line(0, -1000)}}), ErrLedgerEntryInvalid)
+}
+
+func TestBuildTrialBalance(t *testing.T) {
+	asOf := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+	tb := buildTrialBalance([]*ledger.TrialBalanceRow{
+		{AccountCode: "1101", AccountType: ledger.AccountAsset, Debit: 300000, Credit: 50000},
+		{AccountCode: "1201", AccountType: ledger.AccountAsset, Debit: 333000, Credit: 300000},
+		{AccountCode: "2102", AccountType: ledger.AccountLiability, Credit: 33000},
+		{AccountCode: "2101", AccountType: ledger.AccountLiability, Debit: 50000},
+		{AccountCode: "4101", AccountType: ledger.AccountRevenue, Credit: 300000},
+	}, asOf)
+
+	assert.Equal(t, "2025-03-31", tb.AsOf)
+	assert.Equal(t, int64(683000), tb.TotalDebit)
+	assert.Equal(t, int64(683000), tb.TotalCredit)
+	assert.True(t, tb.Balanced)
+	assert.Equal(t, int64(250000), tb.Rows[0].Balance)
+	assert.Equal(t, int64(33000), tb.Rows[2].Balance)
+	assert.Equal(t, int64(-50000), tb.Rows[3].Balance)
+	assert.Equal(t, int64(300000), tb.Rows[4].Balance)
+}
+
+func TestBuildGeneralLedger(t *testing.T) {
+	revenue := &ledger.Account{Code: "4101", Type: ledger.AccountRevenue}
+	gl := buildGeneralLedger(revenue, 500000, []*ledger.LedgerLine{
+		{Credit: 100000},
+		{Credit: 150000},
+		{Debit: 50000}, // credit note
+	})
+	require.Len(t, gl.Lines, 3)
+	assert.Equal(t, int64(600000), gl.Lines[0].Balance)
+	assert.Equal(t, int64(750000), gl.Lines[1].Balance)
+	assert.Equal(t, int64(700000), gl.Lines[2].Balance)
+	assert.Equal(t, int64(50000), gl.TotalDebit)
+	assert.Equal(t, int64(250000), gl.TotalCredit)
+	assert.Equal(t, int64(700000), gl.ClosingBalance)
+
+	cash := &ledger.Account{Code: "1101", Type: ledger.AccountAsset}
+	gl = buildGeneralLedger(cash, 0, []*ledger.LedgerLine{{Debit: 80000}, {Credit: 30000}})
+	assert.Equal(t, int64(50000), gl.ClosingBalance)
+}
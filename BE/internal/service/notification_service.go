@@ -0,0 +1,579 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/client"
+	"rrnet/internal/domain/job"
+	"rrnet/internal/domain/notification"
+	"rrnet/internal/domain/tenant"
+	"rrnet/internal/domain/wa_log"
+	"rrnet/internal/domain/wa_template"
+	asynqInfra "rrnet/internal/infra/asynq"
+	wagw "rrnet/internal/infra/wa_gateway"
+	"rrnet/internal/repository"
+)
+
+var (
+	ErrNotificationWindowInvalid   = errors.New("send window must be formatted as HH:MM")
+	ErrNotificationTimezoneInvalid = errors.New("unknown time zone, use an IANA name such as Asia/Jakarta")
+	ErrNotificationEventInvalid    = errors.New("unknown notification event, or days outside 1-30")
+	ErrNotificationTemplateInvalid = errors.New("notification template not found")
+)
+
+const (
+	notificationMaxDays = 30
+	// The send window of tenants that did not set a time zone is in Western Indonesian Time
+	defaultNotificationTimezone = "Asia/Jakarta"
+	// A scheduled reminder missed by the daily job (server down, notifications enabled late) is
+	// still sent within this many days of its target day
+	notificationCatchUpDays = 3
+)
+
+// defaultNotificationTexts are sent for events without a tenant template
+var defaultNotificationTexts = map[notification.Event]string{
+	notification.EventInvoiceCreated:    `Halo {{client.name}}, tagihan {{invoice.number}} periode {{invoice.period_start | date "01-2006"}} sebesar {{invoice.payable | rupiah}} telah terbit dan jatuh tempo pada {{invoice.due_date}}. Terima kasih.`,
+	notification.EventInvoiceDueSoon:    "Halo {{client.name}}, tagihan {{invoice.number}} sebesar {{invoice.payable | rupiah}} akan jatuh tempo pada {{invoice.due_date}}. Mohon lakukan pembayaran tepat waktu. Terima kasih.",
+	notification.EventInvoiceOverdue:    "Halo {{client.name}}, tagihan {{invoice.number}} sebesar {{invoice.remaining | rupiah}} telah melewati jatuh tempo {{invoice.due_date}}. Mohon segera lakukan pembayaran agar layanan tidak terganggu.",
+	notification.EventPaymentReceived:   "Halo {{client.name}}, pembayaran sebesar {{payment.amount | rupiah}} untuk tagihan {{payment.invoices}} telah kami terima pada {{payment.date}}. Terima kasih.",
+	notification.EventClientIsolated:    "Halo {{client.name}}, layanan internet Anda dinonaktifkan sementara karena tagihan belum dibayar. Silakan lakukan pembayaran untuk mengaktifkan kembali layanan.",
+	notification.EventClientReactivated: "Halo {{client.name}}, layanan internet Anda telah aktif kembali. Terima kasih.",
+}
+
+// NotificationService sends clients WhatsApp messages on billing events. Actions raise events
+// (Raise) and the daily job raises due and overdue reminders; every notification is stored once per
+// dedup key and delivered by a TaskBillingNotificationSend task inside the tenant's send window.
+type NotificationService struct {
+	notificationRepo *repository.NotificationRepository
+	tenantRepo       *repository.TenantRepository
+	clientRepo       *repository.ClientRepository
+	invoiceRepo      *repository.InvoiceRepository
+	templateRepo     *repository.WATemplateRepository
+	renderer         *WATemplateRenderer
+	featureResolver  *FeatureResolver
+	waClient         *wagw.Client
+	waLogService     *WALogService
+	asynqClient      *asynq.Client
+}
+
+func NewNotificationService(
+	notificationRepo *repository.NotificationRepository,
+	tenantRepo *repository.TenantRepository,
+	clientRepo *repository.ClientRepository,
+	invoiceRepo *repository.InvoiceRepository,
+	templateRepo *repository.WATemplateRepository,
+	renderer *WATemplateRenderer,
+	featureResolver *FeatureResolver,
+	waClient *wagw.Client,
+	waLogService *WALogService,
+	asynqClient *asynq.Client,
+) *NotificationService {
+	return &NotificationService{
+		notificationRepo: notificationRepo,
+		tenantRepo:       tenantRepo,
+		clientRepo:       clientRepo,
+		invoiceRepo:      invoiceRepo,
+		templateRepo:     templateRepo,
+		renderer:         renderer,
+		featureResolver:  featureResolver,
+		waClient:         waClient,
+		waLogService:     waLogService,
+		asynqClient:      asynqClient,
+	}
+}
+
+// ========== Settings ==========
+
+func defaultNotificationSettings() notification.Settings {
+	out := notification.Settings{Enabled: false, WindowStart: "08:00", WindowEnd: "20:00", Timezone: defaultNotificationTimezone}
+	for _, e := range notification.Events {
+		es := notification.EventSetting{Event: e, Enabled: true}
+		switch e {
+		case notification.EventInvoiceDueSoon:
+			es.Days = 3
+		case notification.EventInvoiceOverdue:
+			es.Days = 1
+		}
+		out.Events = append(out.Events, es)
+	}
+	return out
+}
+
+func readNotificationSettings(settings map[string]interface{}) notification.Settings {
+	out := defaultNotificationSettings()
+	raw, ok := settings["wa_notifications"].(map[string]interface{})
+	if !ok || raw == nil {
+		return out
+	}
+	if v, ok := raw["enabled"].(bool); ok {
+		out.Enabled = v
+	}
+	if v, ok := raw["window_start"].(string); ok {
+		out.WindowStart = v
+	}
+	if v, ok := raw["window_end"].(string); ok {
+		out.WindowEnd = v
+	}
+	if v, ok := raw["timezone"].(string); ok && v != "" {
+		out.Timezone = v
+	}
+	rawEvents, _ := raw["events"].([]interface{})
+	for _, item := range rawEvents {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := m["event"].(string)
+		for i := range out.Events {
+			es := &out.Events[i]
+			if string(es.Event) != name {
+				continue
+			}
+			if v, ok := m["enabled"].(bool); ok {
+				es.Enabled = v
+			}
+			if v, ok := m["days"].(float64); ok && v > 0 {
+				es.Days = int(v)
+			}
+			if v, ok := m["template_id"].(string); ok {
+				if id, err := uuid.Parse(v); err == nil {
+					es.TemplateID = &id
+				}
+			}
+		}
+	}
+	return out
+}
+
+// parseClock parses HH:MM into minutes after midnight
+func parseClock(v string) (int, error) {
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, ErrNotificationWindowInvalid
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// wib is the fallback of Asia/Jakarta when the zone database is missing (no daylight saving)
+var wib = time.FixedZone("WIB", 7*3600)
+
+// sendWindowLocation returns the zone of a send window, Asia/Jakarta when unset or unknown
+func sendWindowLocation(name string) *time.Location {
+	if name == "" {
+		name = defaultNotificationTimezone
+	}
+	if loc, err := time.LoadLocation(name); err == nil {
+		return loc
+	}
+	return wib
+}
+
+// tenantToday returns the start of the tenant's calendar day at now. A run shortly after midnight in
+// the tenant's zone is already the next day there, whatever the zone of the server.
+func tenantToday(now time.Time, loc *time.Location) time.Time {
+	local := now.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+}
+
+// nextSendTime returns now when it falls inside the send window, otherwise the next opening of the
+// window. The window is wall-clock time in loc, whatever the zone of the server; it may run past
+// midnight (start after end), and start equal to end means no window.
+func nextSendTime(now time.Time, windowStart, windowEnd string, loc *time.Location) time.Time {
+	start, err := parseClock(windowStart)
+	if err != nil {
+		return now
+	}
+	end, err := parseClock(windowEnd)
+	if err != nil || start == end {
+		return now
+	}
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	open := minute >= start && minute < end
+	if start > end {
+		open = minute >= start || minute < end
+	}
+	if open {
+		return now
+	}
+	opening := time.Date(local.Year(), local.Month(), local.Day(), start/60, start%60, 0, 0, loc)
+	if !opening.After(now) {
+		opening = opening.AddDate(0, 0, 1)
+	}
+	return opening
+}
+
+func (s *NotificationService) GetSettings(ctx context.Context, tenantID uuid.UUID) (*notification.Settings, error) {
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	out := readNotificationSettings(t.Settings)
+	return &out, nil
+}
+
+// UpdateSettings stores the settings; events missing from the request are turned off
+func (s *NotificationService) UpdateSettings(ctx context.Context, tenantID uuid.UUID, in notification.Settings) (*notification.Settings, error) {
+	if _, err := parseClock(in.WindowStart); err != nil {
+		return nil, err
+	}
+	if _, err := parseClock(in.WindowEnd); err != nil {
+		return nil, err
+	}
+	if in.Timezone == "" {
+		in.Timezone = defaultNotificationTimezone
+	}
+	if _, err := time.LoadLocation(in.Timezone); err != nil {
+		return nil, ErrNotificationTimezoneInvalid
+	}
+	for _, es := range in.Events {
+		if _, ok := defaultNotificationTexts[es.Event]; !ok {
+			return nil, ErrNotificationEventInvalid
+		}
+	}
+	out := notification.Settings{Enabled: in.Enabled, WindowStart: in.WindowStart, WindowEnd: in.WindowEnd, Timezone: in.Timezone}
+	for _, def := range defaultNotificationSettings().Events {
+		es := notification.EventSetting{Event: def.Event, Days: def.Days}
+		if v, ok := in.Event(def.Event); ok {
+			es.Enabled, es.TemplateID = v.Enabled, v.TemplateID
+			if def.Event.Scheduled() {
+				es.Days = v.Days
+			}
+		}
+		if def.Event.Scheduled() && (es.Days < 1 || es.Days > notificationMaxDays) {
+			return nil, ErrNotificationEventInvalid
+		}
+		if es.TemplateID != nil {
+			if _, err := s.templateRepo.Get(ctx, tenantID, *es.TemplateID); err != nil {
+				if errors.Is(err, repository.ErrWATemplateNotFound) {
+					return nil, ErrNotificationTemplateInvalid
+				}
+				return nil, err
+			}
+		}
+		out.Events = append(out.Events, es)
+	}
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if t.Settings == nil {
+		t.Settings = map[string]interface{}{}
+	}
+	events := make([]interface{}, 0, len(out.Events))
+	for _, es := range out.Events {
+		m := map[string]interface{}{
+			"event":   string(es.Event),
+			"enabled": es.Enabled,
+			"days":    es.Days,
+		}
+		if es.TemplateID != nil {
+			m["template_id"] = es.TemplateID.String()
+		}
+		events = append(events, m)
+	}
+	t.Settings["wa_notifications"] = map[string]interface{}{
+		"enabled":      out.Enabled,
+		"window_start": out.WindowStart,
+		"window_end":   out.WindowEnd,
+		"timezone":     out.Timezone,
+		"events":       events,
+	}
+	if err := s.tenantRepo.UpdateSettings(ctx, tenantID, t.Settings); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ========== Raising events ==========
+
+// Raise queues the notification of an event for a client. ref identifies the occurrence (invoice,
+// payment, isolir log), so raising it again does nothing. Failures are logged and never fail the
+// action that raised the event.
+func (s *NotificationService) Raise(ctx context.Context, tenantID uuid.UUID, event notification.Event, clientID uuid.UUID, invoiceID *uuid.UUID, ref string, data map[string]string) {
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		log.Warn().Err(err).Str("tenant_id", tenantID.String()).Msg("Notification: failed to load tenant")
+		return
+	}
+	settings := readNotificationSettings(t.Settings)
+	if _, err := s.raise(ctx, tenantID, &settings, event, clientID, invoiceID, ref, data, time.Now()); err != nil {
+		log.Warn().Err(err).Str("tenant_id", tenantID.String()).Str("event", string(event)).Msg("Notification: failed to queue")
+	}
+}
+
+// raise stores and enqueues a notification; false when the event is off or was already raised
+func (s *NotificationService) raise(ctx context.Context, tenantID uuid.UUID, settings *notification.Settings, event notification.Event, clientID uuid.UUID, invoiceID *uuid.UUID, ref string, data map[string]string, now time.Time) (bool, error) {
+	if es, ok := settings.Event(event); !settings.Enabled || !ok || !es.Enabled {
+		return false, nil
+	}
+	n := &notification.Notification{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		Event:       event,
+		ClientID:    clientID,
+		InvoiceID:   invoiceID,
+		DedupKey:    string(event) + ":" + ref,
+		Data:        data,
+		Status:      notification.StatusQueued,
+		ScheduledAt: nextSendTime(now, settings.WindowStart, settings.WindowEnd, sendWindowLocation(settings.Timezone)),
+		CreatedAt:   now,
+	}
+	created, err := s.notificationRepo.Create(ctx, n)
+	if err != nil || !created {
+		return false, err
+	}
+	if err := s.enqueue(ctx, tenantID, n.ID, n.ScheduledAt); err != nil {
+		msg := err.Error()
+		if ferr := s.notificationRepo.Finish(ctx, n.ID, notification.StatusFailed, nil, &msg); ferr != nil {
+			log.Error().Err(ferr).Str("notification_id", n.ID.String()).Msg("Failed to mark notification as failed")
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *NotificationService) enqueue(ctx context.Context, tenantID, id uuid.UUID, at time.Time) error {
+	task, err := NewBillingNotificationSendTask(tenantID, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.asynqClient.EnqueueContext(ctx, task,
+		asynq.Queue(asynqInfra.QueueNotification),
+		asynq.ProcessAt(at),
+		asynq.MaxRetry(3),
+		asynq.Timeout(30*time.Second),
+	)
+	return err
+}
+
+// ========== Daily reminders ==========
+
+// Runner returns the scheduled job runner of due and overdue reminders
+func (s *NotificationService) Runner() JobRunner {
+	return JobRunner{RunTenant: s.RunForTenant}
+}
+
+// scheduledEventDue tells whether a reminder that targets days before (due soon) or after
+// (overdue) the due date is due for an invoice daysSinceDue days past its due date
+func scheduledEventDue(event notification.Event, days, daysSinceDue int) bool {
+	switch event {
+	case notification.EventInvoiceDueSoon:
+		return daysSinceDue >= -days && daysSinceDue < 0 && daysSinceDue < -days+notificationCatchUpDays
+	case notification.EventInvoiceOverdue:
+		return daysSinceDue >= days && daysSinceDue < days+notificationCatchUpDays
+	}
+	return false
+}
+
+// RunForTenant raises the due soon and overdue reminders of the tenant's unpaid invoices
+func (s *NotificationService) RunForTenant(ctx context.Context, t *tenant.Tenant, now time.Time) (job.Stats, error) {
+	stats := job.Stats{string(notification.EventInvoiceDueSoon): 0, string(notification.EventInvoiceOverdue): 0}
+	settings := readNotificationSettings(t.Settings)
+	if !settings.Enabled {
+		return stats, nil
+	}
+	dueSoon, _ := settings.Event(notification.EventInvoiceDueSoon)
+	overdue, _ := settings.Event(notification.EventInvoiceOverdue)
+	if !dueSoon.Enabled && !overdue.Enabled {
+		return stats, nil
+	}
+
+	today := tenantToday(now, sendWindowLocation(settings.Timezone))
+	invoices, err := s.invoiceRepo.GetUnpaidPastDue(ctx, t.ID, today.AddDate(0, 0, dueSoon.Days+1))
+	if err != nil {
+		return stats, fmt.Errorf("failed to list unpaid invoices: %w", err)
+	}
+	for _, inv := range invoices {
+		since := daysBetween(inv.DueDate, today)
+		for _, es := range []notification.EventSetting{dueSoon, overdue} {
+			if !es.Enabled || !scheduledEventDue(es.Event, es.Days, since) {
+				continue
+			}
+			invoiceID := inv.ID
+			ref := fmt.Sprintf("%s:%s", inv.ID, inv.DueDate.Format("2006-01-02"))
+			raised, err := s.raise(ctx, t.ID, &settings, es.Event, inv.ClientID, &invoiceID, ref, nil, now)
+			if err != nil {
+				return stats, err
+			}
+			if raised {
+				stats[string(es.Event)]++
+			}
+		}
+	}
+	return stats, nil
+}
+
+// ========== Delivery ==========
+
+// Deliver renders and sends a queued notification. Notifications that may no longer be sent
+// (turned off, opted out, invoice paid meanwhile) are recorded as skipped; a gateway error is
+// recorded as failed and not retried.
+func (s *NotificationService) Deliver(ctx context.Context, tenantID, id uuid.UUID) error {
+	n, err := s.notificationRepo.Get(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if n.Status != notification.StatusQueued {
+		return nil // duplicate delivery
+	}
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	settings := readNotificationSettings(t.Settings)
+
+	// The window may have changed since the notification was queued
+	now := time.Now()
+	if at := nextSendTime(now, settings.WindowStart, settings.WindowEnd, sendWindowLocation(settings.Timezone)); at.After(now) {
+		return s.enqueue(ctx, tenantID, id, at)
+	}
+
+	text, phone, templateID, reason, err := s.compose(ctx, n, t, &settings)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		return s.notificationRepo.Finish(ctx, id, notification.StatusSkipped, nil, &reason)
+	}
+
+	var logID *uuid.UUID
+	if s.waLogService != nil {
+		clientName := n.ClientName
+		l, err := s.waLogService.CreateQueued(ctx, tenantID, CreateWALogInput{
+			Source:      wa_log.SourceSystem,
+			ClientID:    &n.ClientID,
+			ClientName:  &clientName,
+			ToPhone:     phone,
+			MessageText: text,
+			TemplateID:  templateID,
+		})
+		if err == nil {
+			logID = &l.ID
+		}
+	}
+
+	res, err := s.waClient.Send(ctx, tenantID.String(), phone, text)
+	if err == nil && (res == nil || !res.OK) {
+		err = errors.New("wa-gateway reported ok=false")
+	}
+	if err != nil {
+		msg := err.Error()
+		if logID != nil {
+			_ = s.waLogService.MarkFailed(ctx, tenantID, *logID, msg)
+		}
+		log.Warn().Err(err).Str("tenant_id", tenantID.String()).Str("notification_id", id.String()).Msg("Billing notification send failed")
+		return s.notificationRepo.Finish(ctx, id, notification.StatusFailed, logID, &msg)
+	}
+	if logID != nil {
+		_ = s.waLogService.MarkSent(ctx, tenantID, *logID, res.MessageID)
+	}
+	return s.notificationRepo.Finish(ctx, id, notification.StatusSent, logID, nil)
+}
+
+// compose renders the message of a notification, or returns why it is skipped
+func (s *NotificationService) compose(ctx context.Context, n *notification.Notification, t *tenant.Tenant, settings *notification.Settings) (text, phone string, templateID *uuid.UUID, skip string, err error) {
+	es, ok := settings.Event(n.Event)
+	if !settings.Enabled || !ok || !es.Enabled {
+		return "", "", nil, "notifications turned off", nil
+	}
+	if s.waClient == nil || !s.featureResolver.Has(ctx, n.TenantID, "wa_gateway") {
+		return "", "", nil, "WhatsApp gateway not available", nil
+	}
+	optedOut, err := s.notificationRepo.IsOptedOut(ctx, n.TenantID, n.ClientID)
+	if err != nil {
+		return "", "", nil, "", err
+	}
+	if optedOut {
+		return "", "", nil, "client opted out", nil
+	}
+	c, err := s.clientRepo.GetByID(ctx, n.TenantID, n.ClientID)
+	if errors.Is(err, repository.ErrClientNotFound) {
+		return "", "", nil, "client was deleted", nil
+	}
+	if err != nil {
+		return "", "", nil, "", err
+	}
+	if c.Status == client.StatusTerminated {
+		return "", "", nil, "client is terminated", nil
+	}
+	if c.Phone == nil || strings.TrimSpace(*c.Phone) == "" {
+		return "", "", nil, "client has no phone number", nil
+	}
+
+	var inv *billing.Invoice
+	if n.InvoiceID != nil {
+		inv, err = s.invoiceRepo.GetByID(ctx, *n.InvoiceID)
+		if err != nil {
+			return "", "", nil, "", err
+		}
+		if n.Event.Scheduled() && inv.Status != billing.InvoiceStatusPending && inv.Status != billing.InvoiceStatusOverdue {
+			return "", "", nil, "invoice is no longer unpaid", nil
+		}
+	}
+
+	text = defaultNotificationTexts[n.Event]
+	if es.TemplateID != nil {
+		tpl, err := s.templateRepo.Get(ctx, n.TenantID, *es.TemplateID)
+		if err != nil && !errors.Is(err, repository.ErrWATemplateNotFound) {
+			return "", "", nil, "", err
+		}
+		if tpl != nil {
+			text, templateID = tpl.Content, &tpl.ID
+		}
+	}
+	msg, err := wa_template.Parse(text)
+	if err != nil {
+		// Templates are validated on save; this only catches texts stored before the engine
+		return "", "", nil, err.Error(), nil
+	}
+	text, err = s.renderer.Render(ctx, t, c, inv, msg, wa_template.ParseValues(n.Data))
+	if err != nil {
+		return "", "", nil, "", err
+	}
+	n.ClientName = c.Name
+	return text, *c.Phone, templateID, "", nil
+}
+
+// ========== History and opt-outs ==========
+
+func (s *NotificationService) List(ctx context.Context, filter repository.NotificationFilter) ([]*notification.Notification, int, error) {
+	return s.notificationRepo.List(ctx, filter)
+}
+
+// OptOut stops every billing notification to a client of the tenant
+func (s *NotificationService) OptOut(ctx context.Context, tenantID, userID, clientID uuid.UUID, reason *string) (*notification.OptOut, error) {
+	c, err := s.clientRepo.GetByID(ctx, tenantID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	o := &notification.OptOut{
+		TenantID:   tenantID,
+		ClientID:   clientID,
+		Reason:     reason,
+		CreatedBy:  &userID,
+		CreatedAt:  time.Now(),
+		ClientName: c.Name,
+	}
+	if err := s.notificationRepo.OptOut(ctx, o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (s *NotificationService) RemoveOptOut(ctx context.Context, tenantID, clientID uuid.UUID) error {
+	return s.notificationRepo.RemoveOptOut(ctx, tenantID, clientID)
+}
+
+func (s *NotificationService) ListOptOuts(ctx context.Context, tenantID uuid.UUID) ([]*notification.OptOut, error) {
+	return s.notificationRepo.ListOptOuts(ctx, tenantID)
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/client"
+	"rrnet/internal/domain/notification"
+	"rrnet/internal/domain/wa_template"
+)
+
+func TestNextSendTime(t *testing.T) {
+	at := func(h, m int) time.Time { return time.Date(2025, time.March, 10, h, m, 0, 0, time.Local) }
+
+	assert.Equal(t, at(9, 30), nextSendTime(at(9, 30), "08:00", "20:00", time.Local))
+	assert.Equal(t, at(8, 0), nextSendTime(at(6, 15), "08:00", "20:00", time.Local))
+	// After the window closes the message waits for the next morning
+	assert.Equal(t, at(8, 0).AddDate(0, 0, 1), nextSendTime(at(20, 0), "08:00", "20:00", time.Local))
+	assert.Equal(t, at(8, 0).AddDate(0, 0, 1), nextSendTime(at(23, 59), "08:00", "20:00", time.Local))
+
+	// A window past midnight
+	assert.Equal(t, at(1, 0), nextSendTime(at(1, 0), "22:00", "02:00", time.Local))
+	assert.Equal(t, at(22, 0), nextSendTime(at(12, 0), "22:00", "02:00", time.Local))
+
+	// No window
+	assert.Equal(t, at(3, 0), nextSendTime(at(3, 0), "00:00", "00:00", time.Local))
+	assert.Equal(t, at(3, 0), nextSendTime(at(3, 0), "8 pagi", "20:00", time.Local))
+}
+
+func TestNextSendTimeInTenantZone(t *testing.T) {
+	// The server runs in UTC; the window is 08:00-20:00 in Jakarta (UTC+7)
+	jakarta := sendWindowLocation("")
+	utc := func(day, h, m int) time.Time { return time.Date(2025, time.March, day, h, m, 0, 0, time.UTC) }
+
+	// 07:30 WIB: held until 08:00 WIB, which is 01:00 UTC the same day
+	assert.True(t, utc(10, 1, 0).Equal(nextSendTime(utc(10, 0, 30), "08:00", "20:00", jakarta)))
+	// 09:00 UTC is 16:00 WIB, inside the window
+	assert.Equal(t, utc(10, 9, 0), nextSendTime(utc(10, 9, 0), "08:00", "20:00", jakarta))
+	// 13:30 UTC is 20:30 WIB: closed, though still inside the window in UTC
+	assert.True(t, utc(11, 1, 0).Equal(nextSendTime(utc(10, 13, 30), "08:00", "20:00", jakarta)))
+	// 23:30 UTC on the 10th is 06:30 WIB on the 11th: the window opens that morning, not a day later
+	assert.True(t, utc(11, 1, 0).Equal(nextSendTime(utc(10, 23, 30), "08:00", "20:00", jakarta)))
+
+	assert.Equal(t, jakarta, sendWindowLocation("Asia/Jakarta"))
+	assert.Equal(t, "Asia/Makassar", sendWindowLocation("Asia/Makassar").String())
+}
+
+func TestTenantToday(t *testing.T) {
+	jakarta := sendWindowLocation("")
+
+	// 18:30 UTC on the 10th is 01:30 WIB on the 11th: reminders are counted from the 11th
+	today := tenantToday(time.Date(2025, time.March, 10, 18, 30, 0, 0, time.UTC), jakarta)
+	assert.Equal(t, time.Date(2025, time.March, 11, 0, 0, 0, 0, jakarta), today)
+	due := time.Date(2025, time.March, 12, 23, 59, 59, 0, jakarta)
+	assert.Equal(t, -1, daysBetween(due, today))
+
+	// 10:00 UTC is 17:00 WIB, the same day
+	assert.Equal(t, time.Date(2025, time.March, 10, 0, 0, 0, 0, jakarta), tenantToday(time.Date(2025, time.March, 10, 10, 0, 0, 0, time.UTC), jakarta))
+}
+
+func TestScheduledEventDue(t *testing.T) {
+	// Reminder 3 days before the due date, caught up until the day before
+	assert.False(t, scheduledEventDue(notification.EventInvoiceDueSoon, 3, -4))
+	assert.True(t, scheduledEventDue(notification.EventInvoiceDueSoon, 3, -3))
+	assert.True(t, scheduledEventDue(notification.EventInvoiceDueSoon, 3, -1))
+	assert.False(t, scheduledEventDue(notification.EventInvoiceDueSoon, 3, 0))
+	assert.True(t, scheduledEventDue(notification.EventInvoiceDueSoon, 5, -5))
+	assert.False(t, scheduledEventDue(notification.EventInvoiceDueSoon, 5, -2))
+
+	// Overdue notice one day after the due date; long overdue invoices are not messaged
+	assert.False(t, scheduledEventDue(notification.EventInvoiceOverdue, 1, 0))
+	assert.True(t, scheduledEventDue(notification.EventInvoiceOverdue, 1, 1))
+	assert.True(t, scheduledEventDue(notification.EventInvoiceOverdue, 1, 3))
+	assert.False(t, scheduledEventDue(notification.EventInvoiceOverdue, 1, 4))
+
+	assert.False(t, scheduledEventDue(notification.EventInvoiceCreated, 1, 1))
+}
+
+func TestReadNotificationSettings(t *testing.T) {
+	out := readNotificationSettings(nil)
+	assert.False(t, out.Enabled)
+	assert.Equal(t, "08:00", out.WindowStart)
+	assert.Equal(t, "Asia/Jakarta", out.Timezone)
+	assert.Len(t, out.Events, len(notification.Events))
+	dueSoon, ok := out.Event(notification.EventInvoiceDueSoon)
+	assert.True(t, ok)
+	assert.Equal(t, 3, dueSoon.Days)
+
+	templateID := uuid.New()
+	out = readNotificationSettings(map[string]interface{}{
+		"wa_notifications": map[string]interface{}{
+			"enabled":      true,
+			"window_start": "07:30",
+			"window_end":   "21:00",
+			"timezone":     "Asia/Makassar",
+			"events": []interface{}{
+				map[string]interface{}{"event": "invoice_overdue", "enabled": true, "days": float64(5), "template_id": templateID.String()},
+				map[string]interface{}{"event": "client_isolated", "enabled": false},
+				map[string]interface{}{"event": "unknown", "enabled": true},
+			},
+		},
+	})
+	assert.True(t, out.Enabled)
+	assert.Equal(t, "21:00", out.WindowEnd)
+	assert.Equal(t, "Asia/Makassar", out.Timezone)
+	overdue, _ := out.Event(notification.EventInvoiceOverdue)
+	assert.Equal(t, 5, overdue.Days)
+	assert.Equal(t, &templateID, overdue.TemplateID)
+	isolated, _ := out.Event(notification.EventClientIsolated)
+	assert.False(t, isolated.Enabled)
+	created, _ := out.Event(notification.EventInvoiceCreated)
+	assert.True(t, created.Enabled)
+}
+
+func TestDefaultNotificationTexts(t *testing.T) {
+	code := 7
+	c := &client.Client{Name: "Siti", ClientCode: "C-001"}
+	inv := &billing.Invoice{
+		InvoiceNumber: "INV-2025-0001",
+		PeriodStart:   time.Date(2025, time.March, 1, 0, 0, 0, 0, time.Local),
+		DueDate:       time.Date(2025, time.March, 10, 0, 0, 0, 0, time.Local),
+		TotalAmount:   150000,
+		PaidAmount:    50000,
+		UniqueCode:    &code,
+	}
+	for _, e := range notification.Events {
+		msg, err := wa_template.Parse(defaultNotificationTexts[e])
+		assert.NoError(t, err, e)
+		assert.NotContains(t, msg.Render(waTemplateValues(nil, c, inv, "")), "{", e)
+	}
+
+	msg, _ := wa_template.Parse(defaultNotificationTexts[notification.EventInvoiceOverdue])
+	text := msg.Render(waTemplateValues(nil, c, inv, ""))
+	assert.Contains(t, text, "Halo Siti, tagihan INV-2025-0001 sebesar Rp 100.000")
+	assert.Contains(t, text, "jatuh tempo 10-03-2025")
+
+	// Payment facts are stored as text with the notification
+	receivedAt := time.Date(2025, time.March, 12, 9, 30, 0, 0, time.Local)
+	data := wa_template.ParseValues(paymentNotificationData(50000, billing.PaymentMethodCash, receivedAt, "INV-2025-0001, INV-2025-0002"))
+	values := waTemplateValues(nil, c, nil, "")
+	for k, v := range data {
+		values[k] = v
+	}
+	msg, _ = wa_template.Parse(defaultNotificationTexts[notification.EventPaymentReceived])
+	assert.Equal(t, "Halo Siti, pembayaran sebesar Rp 50.000 untuk tagihan INV-2025-0001, INV-2025-0002 telah kami terima pada 12-03-2025. Terima kasih.", msg.Render(values))
+}
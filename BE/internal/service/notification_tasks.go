@@ -0,0 +1,24 @@
+package service
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+// TaskBillingNotificationSend delivers one queued billing notification
+const TaskBillingNotificationSend = "wa:billing_notification_send"
+
+type BillingNotificationSendPayload struct {
+	TenantID       string `json:"tenant_id"`
+	NotificationID string `json:"notification_id"`
+}
+
+func NewBillingNotificationSendTask(tenantID, notificationID uuid.UUID) (*asynq.Task, error) {
+	b, err := json.Marshal(BillingNotificationSendPayload{TenantID: tenantID.String(), NotificationID: notificationID.String()})
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TaskBillingNotificationSend, b), nil
+}
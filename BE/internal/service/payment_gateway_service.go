@@ -0,0 +1,564 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/config"
+	"rrnet/internal/domain/billing"
+	pgw "rrnet/internal/infra/payment_gateway"
+	"rrnet/internal/repository"
+)
+
+var (
+	ErrPaymentGatewayDisabled        = errors.New("payment gateway is not enabled for this tenant")
+	ErrPaymentGatewayProviderInvalid = errors.New("unknown payment gateway provider")
+	ErrPaymentGatewayNotMock         = errors.New("payments can only be simulated with the mock provider")
+	ErrPaymentGatewayAmountMismatch  = errors.New("paid amount does not match the payment request")
+	ErrPaymentRequestMethodInvalid   = errors.New("method must be virtual_account, qris or e_wallet")
+	ErrPaymentRequestInvoiceInvalid  = errors.New("only pending or overdue invoices can be paid online")
+)
+
+const (
+	paymentGatewayDefaultExpiry = 24
+	paymentGatewayMaxExpiry     = 24 * 7
+	secretMask                  = "****"
+)
+
+// PaymentGatewaySettings is the tenant's merchant account (stored in tenant settings under
+// "payment_gateway"). Secrets are masked when read back.
+type PaymentGatewaySettings struct {
+	Enabled      bool   `json:"enabled"`
+	Provider     string `json:"provider"`
+	APIKey       string `json:"api_key"`       // Midtrans server key, Xendit secret key, Tripay API key
+	PrivateKey   string `json:"private_key"`   // Tripay
+	MerchantCode string `json:"merchant_code"` // Tripay
+	WebhookToken string `json:"webhook_token"` // Xendit callback verification token
+	Sandbox      bool   `json:"sandbox"`
+	ExpiryHours  int    `json:"expiry_hours"`
+	ReturnURL    string `json:"return_url"`  // e-wallet checkouts return here
+	WebhookURL   string `json:"webhook_url"` // read-only: URL to register at the provider
+}
+
+func (p PaymentGatewaySettings) credentials() pgw.Credentials {
+	return pgw.Credentials{
+		APIKey:       p.APIKey,
+		PrivateKey:   p.PrivateKey,
+		MerchantCode: p.MerchantCode,
+		WebhookToken: p.WebhookToken,
+		Sandbox:      p.Sandbox,
+	}
+}
+
+func (p PaymentGatewaySettings) masked() PaymentGatewaySettings {
+	p.APIKey = maskSecret(p.APIKey)
+	p.PrivateKey = maskSecret(p.PrivateKey)
+	p.WebhookToken = maskSecret(p.WebhookToken)
+	return p
+}
+
+// maskSecret keeps the last 4 characters of a secret so users can tell which key is configured
+func maskSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	if len(s) <= 8 {
+		return secretMask
+	}
+	return secretMask + s[len(s)-4:]
+}
+
+// keepSecret returns the stored secret when the submitted value is empty or the masked value
+func keepSecret(submitted, stored string) string {
+	submitted = strings.TrimSpace(submitted)
+	if submitted == "" || strings.HasPrefix(submitted, secretMask) {
+		return stored
+	}
+	return submitted
+}
+
+func readPaymentGatewaySettings(settings map[string]interface{}) PaymentGatewaySettings {
+	out := PaymentGatewaySettings{ExpiryHours: paymentGatewayDefaultExpiry}
+	raw, ok := settings["payment_gateway"].(map[string]interface{})
+	if !ok || raw == nil {
+		return out
+	}
+	str := func(k string) string {
+		v, _ := raw[k].(string)
+		return v
+	}
+	if v, ok := raw["enabled"].(bool); ok {
+		out.Enabled = v
+	}
+	if v, ok := raw["sandbox"].(bool); ok {
+		out.Sandbox = v
+	}
+	if v, ok := raw["expiry_hours"].(float64); ok && v > 0 {
+		out.ExpiryHours = int(v)
+	}
+	out.Provider = str("provider")
+	out.APIKey = str("api_key")
+	out.PrivateKey = str("private_key")
+	out.MerchantCode = str("merchant_code")
+	out.WebhookToken = str("webhook_token")
+	out.ReturnURL = str("return_url")
+	return out
+}
+
+// CreatePaymentRequestRequest selects how the client wants to pay an invoice
+type CreatePaymentRequestRequest struct {
+	Method  billing.PaymentMethod `json:"method"`
+	Channel string                `json:"channel,omitempty"` // bank or e-wallet, e.g. "bca", "ovo"
+}
+
+type PaymentGatewayService struct {
+	tenantRepo      *repository.TenantRepository
+	clientRepo      *repository.ClientRepository
+	invoiceRepo     *repository.InvoiceRepository
+	requestRepo     *repository.PaymentRequestRepository
+	billingService  *BillingService
+	callbackBaseURL string
+	allowMock       bool
+}
+
+func NewPaymentGatewayService(
+	tenantRepo *repository.TenantRepository,
+	clientRepo *repository.ClientRepository,
+	invoiceRepo *repository.InvoiceRepository,
+	requestRepo *repository.PaymentRequestRepository,
+	billingService *BillingService,
+	cfg config.PaymentGatewayConfig,
+) *PaymentGatewayService {
+	return &PaymentGatewayService{
+		tenantRepo:      tenantRepo,
+		clientRepo:      clientRepo,
+		invoiceRepo:     invoiceRepo,
+		requestRepo:     requestRepo,
+		billingService:  billingService,
+		callbackBaseURL: strings.TrimRight(cfg.CallbackBaseURL, "/"),
+		allowMock:       cfg.AllowMock,
+	}
+}
+
+// providers lists the providers tenants may choose; the mock provider only outside production
+func (s *PaymentGatewayService) providers() []string {
+	if s.allowMock {
+		return append(pgw.Providers(), pgw.ProviderMock)
+	}
+	return pgw.Providers()
+}
+
+// newProvider is pgw.New, refusing the mock provider where it is not offered, so settings stored
+// while it was allowed cannot settle payments
+func (s *PaymentGatewayService) newProvider(name string, cred pgw.Credentials) (pgw.Provider, error) {
+	if name == pgw.ProviderMock && !s.allowMock {
+		return nil, pgw.ErrUnknownProvider
+	}
+	return pgw.New(name, cred)
+}
+
+// webhookURL is the public URL a provider posts a tenant's webhooks to
+func (s *PaymentGatewayService) webhookURL(provider string, tenantID uuid.UUID) string {
+	if s.callbackBaseURL == "" || provider == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/api/v1/webhooks/payments/%s/%s", s.callbackBaseURL, provider, tenantID)
+}
+
+// ========== Settings ==========
+
+func (s *PaymentGatewayService) GetSettings(ctx context.Context, tenantID uuid.UUID) (*PaymentGatewaySettings, error) {
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	out := readPaymentGatewaySettings(t.Settings).masked()
+	out.WebhookURL = s.webhookURL(out.Provider, tenantID)
+	return &out, nil
+}
+
+func (s *PaymentGatewayService) UpdateSettings(ctx context.Context, tenantID uuid.UUID, in PaymentGatewaySettings) (*PaymentGatewaySettings, error) {
+	in.Provider = strings.ToLower(strings.TrimSpace(in.Provider))
+	if in.Provider != "" {
+		valid := false
+		for _, p := range s.providers() {
+			valid = valid || p == in.Provider
+		}
+		if !valid {
+			return nil, ErrPaymentGatewayProviderInvalid
+		}
+	}
+	if in.ExpiryHours <= 0 {
+		in.ExpiryHours = paymentGatewayDefaultExpiry
+	}
+	if in.ExpiryHours > paymentGatewayMaxExpiry {
+		in.ExpiryHours = paymentGatewayMaxExpiry
+	}
+
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	stored := readPaymentGatewaySettings(t.Settings)
+	in.APIKey = keepSecret(in.APIKey, stored.APIKey)
+	in.PrivateKey = keepSecret(in.PrivateKey, stored.PrivateKey)
+	in.WebhookToken = keepSecret(in.WebhookToken, stored.WebhookToken)
+	in.MerchantCode = strings.TrimSpace(in.MerchantCode)
+	in.ReturnURL = strings.TrimSpace(in.ReturnURL)
+
+	// Enabling requires complete credentials for the chosen provider
+	if in.Enabled {
+		if in.Provider == "" {
+			return nil, ErrPaymentGatewayProviderInvalid
+		}
+		if _, err := s.newProvider(in.Provider, in.credentials()); err != nil {
+			return nil, err
+		}
+	}
+
+	if t.Settings == nil {
+		t.Settings = map[string]interface{}{}
+	}
+	t.Settings["payment_gateway"] = map[string]interface{}{
+		"enabled":       in.Enabled,
+		"provider":      in.Provider,
+		"api_key":       in.APIKey,
+		"private_key":   in.PrivateKey,
+		"merchant_code": in.MerchantCode,
+		"webhook_token": in.WebhookToken,
+		"sandbox":       in.Sandbox,
+		"expiry_hours":  in.ExpiryHours,
+		"return_url":    in.ReturnURL,
+	}
+	if err := s.tenantRepo.UpdateSettings(ctx, tenantID, t.Settings); err != nil {
+		return nil, err
+	}
+	out := in.masked()
+	out.WebhookURL = s.webhookURL(out.Provider, tenantID)
+	return &out, nil
+}
+
+// ========== Payment requests ==========
+
+// CreatePaymentRequest creates a payment for the unpaid amount of an invoice at the tenant's gateway.
+// An open request for the same method, channel and amount is returned instead of creating another one.
+func (s *PaymentGatewayService) CreatePaymentRequest(ctx context.Context, tenantID, userID, invoiceID uuid.UUID, req CreatePaymentRequestRequest) (*billing.PaymentRequest, error) {
+	switch req.Method {
+	case billing.PaymentMethodVA, billing.PaymentMethodQRIS, billing.PaymentMethodEWallet:
+	default:
+		return nil, ErrPaymentRequestMethodInvalid
+	}
+	req.Channel = strings.ToLower(strings.TrimSpace(req.Channel))
+
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	settings := readPaymentGatewaySettings(t.Settings)
+	if !settings.Enabled {
+		return nil, ErrPaymentGatewayDisabled
+	}
+
+	inv, err := s.invoiceRepo.GetByID(ctx, invoiceID)
+	if err != nil || inv.TenantID != tenantID {
+		return nil, ErrInvoiceNotFound
+	}
+	if inv.Status != billing.InvoiceStatusPending && inv.Status != billing.InvoiceStatusOverdue {
+		return nil, ErrPaymentRequestInvoiceInvalid
+	}
+	amount := inv.RemainingAmount()
+	if amount <= 0 {
+		return nil, ErrPaymentRequestInvoiceInvalid
+	}
+
+	now := time.Now()
+	existing, err := s.requestRepo.ListByInvoice(ctx, tenantID, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	for _, pr := range existing {
+		if pr.Provider == settings.Provider && pr.Method == req.Method && pr.Channel == req.Channel &&
+			pr.Amount == amount && pr.IsOpen(now) {
+			return pr, nil
+		}
+	}
+
+	provider, err := s.newProvider(settings.Provider, settings.credentials())
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.clientRepo.GetByID(ctx, tenantID, inv.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	pr := &billing.PaymentRequest{
+		ID:        uuid.New(),
+		TenantID:  tenantID,
+		InvoiceID: inv.ID,
+		ClientID:  inv.ClientID,
+		Provider:  provider.Name(),
+		Method:    req.Method,
+		Channel:   req.Channel,
+		OrderID:   paymentOrderID(inv.InvoiceNumber),
+		Amount:    amount,
+		Status:    billing.PaymentRequestPending,
+		CreatedBy: userID,
+		CreatedAt: now,
+	}
+	charge, err := provider.CreateCharge(ctx, pgw.ChargeRequest{
+		OrderID:       pr.OrderID,
+		Amount:        amount,
+		Method:        pgw.Method(req.Method),
+		Channel:       req.Channel,
+		Description:   fmt.Sprintf("Tagihan %s", inv.InvoiceNumber),
+		CustomerName:  c.Name,
+		CustomerPhone: derefString(c.Phone),
+		CustomerEmail: derefString(c.Email),
+		ExpiresAt:     now.Add(time.Duration(settings.ExpiryHours) * time.Hour),
+		CallbackURL:   s.webhookURL(provider.Name(), tenantID),
+		ReturnURL:     settings.ReturnURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	pr.Reference = charge.Reference
+	pr.VANumber = charge.VANumber
+	pr.QRString = charge.QRString
+	pr.PaymentURL = charge.PaymentURL
+	pr.ExpiresAt = charge.ExpiresAt
+
+	if err := s.requestRepo.Create(ctx, pr); err != nil {
+		return nil, fmt.Errorf("failed to store payment request: %w", err)
+	}
+	return pr, nil
+}
+
+func (s *PaymentGatewayService) ListPaymentRequests(ctx context.Context, tenantID, invoiceID uuid.UUID) ([]*billing.PaymentRequest, error) {
+	out, err := s.requestRepo.ListByInvoice(ctx, tenantID, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if out == nil {
+		out = []*billing.PaymentRequest{}
+	}
+	return out, nil
+}
+
+// paymentOrderID derives a unique gateway order ID from the invoice number (gateways reject repeated
+// order IDs, and an invoice may need several payment requests)
+func paymentOrderID(invoiceNumber string) string {
+	base := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '-'
+	}, invoiceNumber)
+	if len(base) > 40 {
+		base = base[:40]
+	}
+	return base + "-" + strings.ToUpper(uuid.NewString()[:8])
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// ========== Webhooks ==========
+
+// HandleWebhook verifies a webhook of a tenant's gateway and applies it. Repeated webhooks for a
+// paid request are acknowledged without recording the payment again.
+func (s *PaymentGatewayService) HandleWebhook(ctx context.Context, providerName string, tenantID uuid.UUID, header http.Header, body []byte) error {
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return repository.ErrPaymentRequestNotFound
+	}
+	settings := readPaymentGatewaySettings(t.Settings)
+	if settings.Provider != providerName {
+		return pgw.ErrInvalidSignature
+	}
+	provider, err := s.newProvider(providerName, settings.credentials())
+	if err != nil {
+		return err
+	}
+
+	event := &billing.PaymentWebhookEvent{
+		ID:        uuid.New(),
+		TenantID:  tenantID,
+		Provider:  providerName,
+		Payload:   string(body),
+		CreatedAt: time.Now(),
+	}
+	n, err := provider.ParseWebhook(header, body)
+	if err == nil {
+		event.Verified = true
+		event.OrderID, event.Reference, event.Status = n.OrderID, n.Reference, string(n.Status)
+		err = s.applyNotification(ctx, tenantID, providerName, n)
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	if lerr := s.requestRepo.LogWebhook(ctx, event); lerr != nil {
+		log.Warn().Err(lerr).Str("tenant_id", tenantID.String()).Msg("Failed to log payment webhook")
+	}
+	return err
+}
+
+func (s *PaymentGatewayService) applyNotification(ctx context.Context, tenantID uuid.UUID, provider string, n *pgw.Notification) error {
+	if n.Status == pgw.StatusPending {
+		return nil
+	}
+	pr, err := s.requestRepo.GetByOrderID(ctx, tenantID, provider, n.OrderID, n.Reference)
+	if err != nil {
+		return err
+	}
+	switch n.Status {
+	case pgw.StatusExpired:
+		return s.requestRepo.UpdateStatus(ctx, pr.ID, billing.PaymentRequestExpired)
+	case pgw.StatusFailed:
+		return s.requestRepo.UpdateStatus(ctx, pr.ID, billing.PaymentRequestFailed)
+	case pgw.StatusPaid:
+		return s.settle(ctx, pr, n)
+	}
+	return nil
+}
+
+// settle records the payment of a paid request once. If the invoice was settled otherwise in the
+// meantime, the money is allocated to the client's other open invoices or credited to the balance.
+func (s *PaymentGatewayService) settle(ctx context.Context, pr *billing.PaymentRequest, n *pgw.Notification) error {
+	paidAt := time.Now()
+	if n.PaidAt != nil {
+		paidAt = *n.PaidAt
+	}
+	// A paid notification must carry exactly the requested amount; anything else is not settled
+	// automatically and stays in the webhook log for finance
+	if n.Amount != pr.Amount {
+		return fmt.Errorf("%w: paid %d, requested %d", ErrPaymentGatewayAmountMismatch, n.Amount, pr.Amount)
+	}
+	claimed, err := s.requestRepo.ClaimPaid(ctx, pr.ID, paidAt)
+	if err != nil || !claimed {
+		return err
+	}
+
+	amount := pr.Amount
+	reference := n.Reference
+	if reference == "" {
+		reference = pr.Reference
+	}
+	if reference == "" {
+		reference = pr.OrderID
+	}
+	notes := fmt.Sprintf("Dibayar via %s (%s)", pr.Provider, pr.OrderID)
+
+	// A failed attempt may have stored the payment before it failed and released the claim; its
+	// retry links that payment instead of recording the money twice
+	recorded, paymentID, err := s.billingService.GatewayPayment(ctx, pr.ID)
+	if err != nil {
+		s.releaseClaim(ctx, pr)
+		return fmt.Errorf("failed to look up gateway payment: %w", err)
+	}
+	if recorded {
+		s.linkPayment(ctx, pr, paymentID)
+		return nil
+	}
+
+	inv, err := s.invoiceRepo.GetByID(ctx, pr.InvoiceID)
+	if err == nil && (inv.Status == billing.InvoiceStatusPending || inv.Status == billing.InvoiceStatusOverdue) {
+		var p *billing.Payment
+		p, err = s.billingService.RecordPayment(ctx, pr.TenantID, pr.CreatedBy, RecordPaymentRequest{
+			InvoiceID:  pr.InvoiceID,
+			Amount:     amount,
+			Method:     pr.Method,
+			Reference:  &reference,
+			Notes:      &notes,
+			ReceivedAt: &paidAt,
+
+			PaymentRequestID: &pr.ID,
+		})
+		if p != nil {
+			paymentID = &p.ID
+		}
+	} else if err == nil {
+		var cp *billing.ClientPayment
+		cp, err = s.billingService.RecordClientPayment(ctx, pr.TenantID, pr.CreatedBy, pr.ClientID, RecordClientPaymentRequest{
+			Amount:     amount,
+			Method:     pr.Method,
+			Reference:  &reference,
+			Notes:      &notes,
+			ReceivedAt: &paidAt,
+
+			PaymentRequestID: &pr.ID,
+		})
+		if cp != nil && len(cp.Allocations) > 0 {
+			paymentID = &cp.Allocations[0].ID
+		}
+	}
+	if errors.Is(err, repository.ErrGatewayPaymentRecorded) {
+		// Recorded concurrently since the lookup above
+		return nil
+	}
+	if err != nil {
+		s.releaseClaim(ctx, pr)
+		return fmt.Errorf("failed to record gateway payment: %w", err)
+	}
+	s.linkPayment(ctx, pr, paymentID)
+	return nil
+}
+
+// releaseClaim puts a claimed request back to pending so the provider's webhook retry settles it
+func (s *PaymentGatewayService) releaseClaim(ctx context.Context, pr *billing.PaymentRequest) {
+	if err := s.requestRepo.ReleaseClaim(ctx, pr.ID); err != nil {
+		log.Error().Err(err).Str("payment_request_id", pr.ID.String()).Msg("Failed to release payment request claim")
+	}
+}
+
+func (s *PaymentGatewayService) linkPayment(ctx context.Context, pr *billing.PaymentRequest, paymentID *uuid.UUID) {
+	if paymentID == nil {
+		return
+	}
+	if err := s.requestRepo.SetPayment(ctx, pr.ID, *paymentID); err != nil {
+		log.Warn().Err(err).Str("payment_request_id", pr.ID.String()).Msg("Failed to link payment to payment request")
+	}
+}
+
+// SimulatePayment completes a payment request of the mock provider by sending it a signed webhook,
+// exercising the same path as a real gateway
+func (s *PaymentGatewayService) SimulatePayment(ctx context.Context, tenantID, requestID uuid.UUID) (*billing.PaymentRequest, error) {
+	pr, err := s.requestRepo.GetByID(ctx, tenantID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	settings := readPaymentGatewaySettings(t.Settings)
+	if !s.allowMock || pr.Provider != pgw.ProviderMock || settings.Provider != pgw.ProviderMock {
+		return nil, ErrPaymentGatewayNotMock
+	}
+	now := time.Now()
+	header, body, err := pgw.MockWebhook(settings.credentials(), pgw.Notification{
+		Reference: pr.Reference,
+		OrderID:   pr.OrderID,
+		Status:    pgw.StatusPaid,
+		Amount:    pr.Amount,
+		PaidAt:    &now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.HandleWebhook(ctx, pgw.ProviderMock, tenantID, header, body); err != nil {
+		return nil, err
+	}
+	return s.requestRepo.GetByID(ctx, tenantID, requestID)
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rrnet/internal/config"
+	"rrnet/internal/domain/billing"
+	pgw "rrnet/internal/infra/payment_gateway"
+)
+
+func TestPaymentGatewayMockOnlyOutsideProduction(t *testing.T) {
+	cred := pgw.Credentials{APIKey: "dev-secret"}
+
+	prod := NewPaymentGatewayService(nil, nil, nil, nil, nil, config.PaymentGatewayConfig{})
+	assert.NotContains(t, prod.providers(), pgw.ProviderMock)
+	_, err := prod.newProvider(pgw.ProviderMock, cred)
+	assert.ErrorIs(t, err, pgw.ErrUnknownProvider)
+
+	dev := NewPaymentGatewayService(nil, nil, nil, nil, nil, config.PaymentGatewayConfig{AllowMock: true})
+	assert.Contains(t, dev.providers(), pgw.ProviderMock)
+	_, err = dev.newProvider(pgw.ProviderMock, cred)
+	require.NoError(t, err)
+	_, err = dev.newProvider(pgw.ProviderMock, pgw.Credentials{})
+	assert.ErrorIs(t, err, pgw.ErrMissingCredentials)
+}
+
+func TestSettleRejectsAmountMismatch(t *testing.T) {
+	s := NewPaymentGatewayService(nil, nil, nil, nil, nil, config.PaymentGatewayConfig{})
+	pr := &billing.PaymentRequest{ID: uuid.New(), Amount: 150000}
+	// Rejected before the request is claimed, so nothing is recorded
+	err := s.settle(context.Background(), pr, &pgw.Notification{Status: pgw.StatusPaid, Amount: 1500000})
+	assert.ErrorIs(t, err, ErrPaymentGatewayAmountMismatch)
+	err = s.settle(context.Background(), pr, &pgw.Notification{Status: pgw.StatusPaid})
+	assert.ErrorIs(t, err, ErrPaymentGatewayAmountMismatch)
+}
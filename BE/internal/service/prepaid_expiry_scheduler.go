@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/domain/client"
+	"rrnet/internal/domain/job"
+	"rrnet/internal/domain/tenant"
+	"rrnet/internal/repository"
+)
+
+// PrepaidExpiryScheduler isolates prepaid clients whose paid validity has ended; it runs as the
+// JobPrepaidExpiry scheduled job. Paying the renewal reactivates them (see applyPrepaidPayment).
+type PrepaidExpiryScheduler struct {
+	clientRepo    *repository.ClientRepository
+	isolirService *IsolirService
+}
+
+// NewPrepaidExpiryScheduler creates a new prepaid expiry scheduler
+func NewPrepaidExpiryScheduler(clientRepo *repository.ClientRepository, isolirService *IsolirService) *PrepaidExpiryScheduler {
+	return &PrepaidExpiryScheduler{
+		clientRepo:    clientRepo,
+		isolirService: isolirService,
+	}
+}
+
+// Runner returns the executor of the prepaid expiry job (JobPrepaidExpiry)
+func (s *PrepaidExpiryScheduler) Runner() JobRunner {
+	return JobRunner{RunTenant: s.RunForTenant}
+}
+
+// RunForTenant isolates the tenant's active prepaid PPPoE clients whose validity ended before the day
+// of now. Isolated clients are no longer active, so a retried run skips them.
+func (s *PrepaidExpiryScheduler) RunForTenant(ctx context.Context, t *tenant.Tenant, now time.Time) (job.Stats, error) {
+	stats := job.Stats{"clients_expired": 0, "clients_isolated": 0, "errors": 0}
+	if t.Status != tenant.StatusActive {
+		return stats, nil
+	}
+
+	yesterday := time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, time.Local)
+	clients, err := s.clientRepo.ListPrepaidActiveUntil(ctx, t.ID, yesterday)
+	if err != nil {
+		return stats, fmt.Errorf("failed to list expired prepaid clients: %w", err)
+	}
+	for _, c := range clients {
+		stats["clients_expired"]++
+		if c.ConnectionType != client.ConnectionTypePPPoE {
+			continue
+		}
+		_, err := s.isolirService.Isolate(ctx, t.ID, c.ID, IsolateRequest{
+			Reason:      fmt.Sprintf("Prepaid service expired on %s", c.ActiveUntil.Format("2006-01-02")),
+			IsAutomatic: true,
+		})
+		if err != nil {
+			log.Error().
+				Err(err).
+				Str("tenant_id", t.ID.String()).
+				Str("client_code", c.ClientCode).
+				Msg("Failed to isolate expired prepaid client")
+			stats["errors"]++
+			continue
+		}
+		stats["clients_isolated"]++
+	}
+
+	log.Info().
+		Str("tenant_id", t.ID.String()).
+		Int64("clients_expired", stats["clients_expired"]).
+		Int64("clients_isolated", stats["clients_isolated"]).
+		Int64("errors", stats["errors"]).
+		Msg("Prepaid expiry completed for tenant")
+
+	if stats["errors"] > 0 {
+		return stats, fmt.Errorf("%d clients failed", stats["errors"])
+	}
+	return stats, nil
+}
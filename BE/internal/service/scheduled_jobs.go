@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+
+	"rrnet/internal/domain/job"
+	"rrnet/internal/domain/tenant"
+	asynqInfra "rrnet/internal/infra/asynq"
+	"rrnet/internal/repository"
+)
+
+const (
+	// TaskScheduledJobRun starts a run of a scheduled job; per-tenant jobs fan out into
+	// TaskScheduledJobTenant tasks
+	TaskScheduledJobRun    = "scheduler:job_run"
+	TaskScheduledJobTenant = "scheduler:job_tenant"
+)
+
+const (
+	JobInvoiceGeneration = "invoice_generation"
+	JobClientCleanup     = "client_cleanup"
+	JobPrepaidExpiry     = "prepaid_expiry"
+	JobBillingReminders  = "billing_reminders"
+	JobIsolir            = "isolir"
+	JobLateFees          = "late_fees"
+	JobDunning           = "dunning"
+)
+
+var ErrJobNotFound = errors.New("job not found")
+
+// ScheduledJobs are the background jobs run by the Asynq scheduler
+var ScheduledJobs = []job.Definition{
+	{
+		Name:        JobInvoiceGeneration,
+		Description: "Generate monthly invoices for clients due tomorrow (H-1)",
+		Cronspec:    "5 0 * * *",
+		PerTenant:   true,
+	},
+	{
+		Name:        JobClientCleanup,
+		Description: "Hard delete clients soft-deleted more than 28 days ago",
+		Cronspec:    "10 0 * * 1",
+	},
+	{
+		Name:        JobPrepaidExpiry,
+		Description: "Isolate prepaid clients whose service validity has ended",
+		Cronspec:    "15 0 * * *",
+		PerTenant:   true,
+	},
+	{
+		Name:        JobBillingReminders,
+		Description: "Queue WhatsApp reminders for invoices due soon or overdue",
+		Cronspec:    "0 7 * * *",
+		PerTenant:   true,
+	},
+	{
+		Name:        JobIsolir,
+		Description: "Mark past-due invoices overdue and isolate clients past the grace period",
+		Cronspec:    "0 1 * * *",
+		PerTenant:   true,
+	},
+	{
+		// After the isolir job has marked invoices overdue, before dunning reminders go out
+		Name:        JobLateFees,
+		Description: "Charge late fees on overdue invoices per the tenant's late fee policy",
+		Cronspec:    "0 2 * * *",
+		PerTenant:   true,
+	},
+	{
+		// Reminders should not go out in the middle of the night
+		Name:        JobDunning,
+		Description: "Run the tenant's dunning timeline (reminder, throttle, isolir, terminate)",
+		Cronspec:    "0 8 * * *",
+		PerTenant:   true,
+	},
+}
+
+// ScheduledJob returns the definition of a job
+func ScheduledJob(name string) (job.Definition, bool) {
+	for _, d := range ScheduledJobs {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return job.Definition{}, false
+}
+
+// JobRunner executes a scheduled job: RunTenant for per-tenant jobs, Run otherwise. now is the
+// reference time of the run, kept across retries so a retried run works on the same period.
+type JobRunner struct {
+	RunTenant func(ctx context.Context, t *tenant.Tenant, now time.Time) (job.Stats, error)
+	Run       func(ctx context.Context, now time.Time) (job.Stats, error)
+}
+
+type ScheduledJobRunPayload struct {
+	Job         string      `json:"job"`
+	Trigger     job.Trigger `json:"trigger"`
+	RunID       string      `json:"run_id,omitempty"` // set for manual runs; scheduled runs derive it from the task ID
+	TriggeredBy string      `json:"triggered_by,omitempty"`
+}
+
+type ScheduledJobTenantPayload struct {
+	Job      string      `json:"job"`
+	RunID    string      `json:"run_id"`
+	TenantID string      `json:"tenant_id"`
+	Trigger  job.Trigger `json:"trigger"`
+	Now      time.Time   `json:"now"`
+}
+
+func NewScheduledJobRunTask(p ScheduledJobRunPayload) (*asynq.Task, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TaskScheduledJobRun, b), nil
+}
+
+func NewScheduledJobTenantTask(p ScheduledJobTenantPayload) (*asynq.Task, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TaskScheduledJobTenant, b), nil
+}
+
+// JobService lists scheduled jobs and their run history and triggers runs on demand
+type JobService struct {
+	runRepo     *repository.JobRunRepository
+	asynqClient *asynq.Client
+}
+
+func NewJobService(runRepo *repository.JobRunRepository, asynqClient *asynq.Client) *JobService {
+	return &JobService{runRepo: runRepo, asynqClient: asynqClient}
+}
+
+func (s *JobService) ListJobs() []job.Definition {
+	return ScheduledJobs
+}
+
+func (s *JobService) ListRuns(ctx context.Context, filter repository.JobRunFilter) ([]*job.Run, int, error) {
+	if filter.Job != "" {
+		if _, ok := ScheduledJob(filter.Job); !ok {
+			return nil, 0, ErrJobNotFound
+		}
+	}
+	return s.runRepo.List(ctx, filter)
+}
+
+// Trigger enqueues a manual run of a job and returns the ID its run will be recorded under. The
+// run is skipped when another run of the job is still in progress, and so is a tenant whose sub-task
+// of another run is still in progress.
+func (s *JobService) Trigger(ctx context.Context, name string, triggeredBy uuid.UUID) (uuid.UUID, error) {
+	if _, ok := ScheduledJob(name); !ok {
+		return uuid.Nil, ErrJobNotFound
+	}
+	runID := uuid.New()
+	p := ScheduledJobRunPayload{Job: name, Trigger: job.TriggerManual, RunID: runID.String()}
+	if triggeredBy != uuid.Nil {
+		p.TriggeredBy = triggeredBy.String()
+	}
+	task, err := NewScheduledJobRunTask(p)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if _, err := s.asynqClient.EnqueueContext(ctx, task, ScheduledJobRunOptions()...); err != nil {
+		return uuid.Nil, err
+	}
+	return runID, nil
+}
+
+// ScheduledJobRunOptions are the Asynq options of TaskScheduledJobRun tasks
+func ScheduledJobRunOptions() []asynq.Option {
+	return []asynq.Option{
+		asynq.Queue(asynqInfra.QueueBilling),
+		asynq.MaxRetry(3),
+		asynq.Timeout(30 * time.Minute),
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/uuid"
 
+	"rrnet/internal/domain/billing"
 	"rrnet/internal/domain/service_package"
 	"rrnet/internal/repository"
 )
@@ -18,6 +19,7 @@ var (
 	ErrServicePackageInvalidPricing  = errors.New("invalid pricing model for category")
 	ErrServicePackageProfileRequired = errors.New("network profile is required")
 	ErrServicePackagePriceInvalid    = errors.New("invalid price")
+	ErrServicePackageCycleInvalid    = errors.New("billing cycles must be 1, 3, 6 or 12 months, each listed once with a price; the default cycle must be monthly or offered")
 )
 
 type ServicePackageService struct {
@@ -35,6 +37,9 @@ type CreateServicePackageRequest struct {
 	PriceMonthly      float64                     `json:"price_monthly,omitempty"`
 	PricePerDevice    float64                     `json:"price_per_device,omitempty"`
 	BillingDayDefault *int                        `json:"billing_day_default,omitempty"`
+	BillingMode       billing.BillingMode         `json:"billing_mode,omitempty"` // postpaid (default) or prepaid
+	BillingCycles     []service_package.CycleOption `json:"billing_cycles,omitempty"`
+	DefaultCycleMonths int                        `json:"default_cycle_months,omitempty"` // default 1 (monthly)
 	NetworkProfileID  uuid.UUID                   `json:"network_profile_id"`
 	IsActive          bool                        `json:"is_active"`
 	Metadata          map[string]interface{}       `json:"metadata,omitempty"`
@@ -50,6 +55,9 @@ type ServicePackageDTO struct {
 	PriceMonthly      float64                     `json:"price_monthly"`
 	PricePerDevice    float64                     `json:"price_per_device"`
 	BillingDayDefault *int                        `json:"billing_day_default,omitempty"`
+	BillingMode       billing.BillingMode         `json:"billing_mode"`
+	BillingCycles     []service_package.CycleOption `json:"billing_cycles"`
+	DefaultCycleMonths int                        `json:"default_cycle_months"`
 	NetworkProfileID  uuid.UUID                   `json:"network_profile_id"`
 	IsActive          bool                        `json:"is_active"`
 	CreatedAt         time.Time                   `json:"created_at"`
@@ -66,6 +74,15 @@ func (s *ServicePackageService) Create(ctx context.Context, tenantID uuid.UUID,
 	if err := validatePackageReq(req.Category, req.PricingModel, req.PriceMonthly, req.PricePerDevice); err != nil {
 		return nil, err
 	}
+	if req.BillingMode == "" {
+		req.BillingMode = billing.BillingModePostpaid
+	}
+	if !req.BillingMode.IsValid() {
+		return nil, ErrBillingModeInvalid
+	}
+	if err := validatePackageCycles(req); err != nil {
+		return nil, err
+	}
 
 	exists, err := s.repo.NameExists(ctx, tenantID, req.Name, nil)
 	if err != nil {
@@ -87,6 +104,9 @@ func (s *ServicePackageService) Create(ctx context.Context, tenantID uuid.UUID,
 		PriceMonthly:      req.PriceMonthly,
 		PricePerDevice:    req.PricePerDevice,
 		BillingDayDefault: req.BillingDayDefault,
+		BillingMode:       req.BillingMode,
+		BillingCycles:     req.BillingCycles,
+		DefaultCycleMonths: req.DefaultCycleMonths,
 		NetworkProfileID:  req.NetworkProfileID,
 		IsActive:          req.IsActive,
 		Metadata:          metadataJSON,
@@ -130,6 +150,15 @@ func (s *ServicePackageService) Update(ctx context.Context, tenantID, id uuid.UU
 	if err := validatePackageReq(req.Category, req.PricingModel, req.PriceMonthly, req.PricePerDevice); err != nil {
 		return nil, err
 	}
+	if req.BillingMode == "" {
+		req.BillingMode = billing.BillingModePostpaid
+	}
+	if !req.BillingMode.IsValid() {
+		return nil, ErrBillingModeInvalid
+	}
+	if err := validatePackageCycles(req); err != nil {
+		return nil, err
+	}
 
 	exists, err := s.repo.NameExists(ctx, tenantID, req.Name, &id)
 	if err != nil {
@@ -150,6 +179,9 @@ func (s *ServicePackageService) Update(ctx context.Context, tenantID, id uuid.UU
 		PriceMonthly:      req.PriceMonthly,
 		PricePerDevice:    req.PricePerDevice,
 		BillingDayDefault: req.BillingDayDefault,
+		BillingMode:       req.BillingMode,
+		BillingCycles:     req.BillingCycles,
+		DefaultCycleMonths: req.DefaultCycleMonths,
 		NetworkProfileID:  req.NetworkProfileID,
 		IsActive:          req.IsActive,
 		Metadata:          metadataJSON,
@@ -175,6 +207,9 @@ func toServicePackageDTO(p *service_package.ServicePackage) *ServicePackageDTO {
 		PriceMonthly:      p.PriceMonthly,
 		PricePerDevice:    p.PricePerDevice,
 		BillingDayDefault: p.BillingDayDefault,
+		BillingMode:       p.BillingMode,
+		BillingCycles:     p.BillingCycles,
+		DefaultCycleMonths: p.DefaultCycleMonths,
 		NetworkProfileID:  p.NetworkProfileID,
 		IsActive:          p.IsActive,
 		CreatedAt:         p.CreatedAt,
@@ -182,6 +217,27 @@ func toServicePackageDTO(p *service_package.ServicePackage) *ServicePackageDTO {
 	}
 }
 
+// validatePackageCycles checks the offered cycles and defaults the package to monthly billing
+func validatePackageCycles(req *CreateServicePackageRequest) error {
+	if req.BillingCycles == nil {
+		req.BillingCycles = []service_package.CycleOption{}
+	}
+	seen := map[int]bool{}
+	for _, o := range req.BillingCycles {
+		if !billing.ValidCycleMonths(o.Months) || o.Months == 1 || o.Price < 0 || seen[o.Months] {
+			return ErrServicePackageCycleInvalid
+		}
+		seen[o.Months] = true
+	}
+	if req.DefaultCycleMonths == 0 {
+		req.DefaultCycleMonths = 1
+	}
+	if req.DefaultCycleMonths != 1 && !seen[req.DefaultCycleMonths] {
+		return ErrServicePackageCycleInvalid
+	}
+	return nil
+}
+
 func validatePackageReq(category service_package.Category, pricing service_package.PricingModel, priceMonthly, pricePerDevice float64) error {
 	switch category {
 	case service_package.CategoryRegular, service_package.CategoryBusiness, service_package.CategoryEnterprise:
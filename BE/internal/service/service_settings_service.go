@@ -16,10 +16,23 @@ const (
 	ServiceDiscountTypeNominal ServiceDiscountType = "nominal"
 )
 
+// ServiceDiscountStacking decides how the tenant-wide discount combines with a client's own discount
+type ServiceDiscountStacking string
+
+const (
+	// ServiceDiscountStackBest applies only the larger of the two discounts
+	ServiceDiscountStackBest ServiceDiscountStacking = "best"
+	// ServiceDiscountStackCombine applies the client discount, then the tenant discount on the remainder
+	ServiceDiscountStackCombine ServiceDiscountStacking = "combine"
+	// ServiceDiscountStackClientPriority applies the client discount if any, otherwise the tenant discount
+	ServiceDiscountStackClientPriority ServiceDiscountStacking = "client_priority"
+)
+
 type ServiceDiscountSetting struct {
-	Enabled bool               `json:"enabled"`
-	Type    ServiceDiscountType `json:"type"`
-	Value   float64            `json:"value"`
+	Enabled  bool                    `json:"enabled"`
+	Type     ServiceDiscountType     `json:"type"`
+	Value    float64                 `json:"value"`
+	Stacking ServiceDiscountStacking `json:"stacking"`
 }
 
 type ServiceSettingsDTO struct {
@@ -56,6 +69,13 @@ func (s *ServiceSettingsService) UpdateDiscount(ctx context.Context, tenantID uu
 			return nil, ErrDiscountValueInvalid
 		}
 	}
+	switch in.Stacking {
+	case "":
+		in.Stacking = ServiceDiscountStackBest
+	case ServiceDiscountStackBest, ServiceDiscountStackCombine, ServiceDiscountStackClientPriority:
+	default:
+		return nil, ErrDiscountStackingInvalid
+	}
 
 	t, err := s.tenantRepo.GetByID(ctx, tenantID)
 	if err != nil {
@@ -65,9 +85,10 @@ func (s *ServiceSettingsService) UpdateDiscount(ctx context.Context, tenantID uu
 		t.Settings = map[string]interface{}{}
 	}
 	t.Settings["service_discount"] = map[string]interface{}{
-		"enabled": in.Enabled,
-		"type":    string(in.Type),
-		"value":   in.Value,
+		"enabled":  in.Enabled,
+		"type":     string(in.Type),
+		"value":    in.Value,
+		"stacking": string(in.Stacking),
 	}
 	if err := s.tenantRepo.UpdateSettings(ctx, tenantID, t.Settings); err != nil {
 		return nil, err
@@ -77,9 +98,10 @@ func (s *ServiceSettingsService) UpdateDiscount(ctx context.Context, tenantID uu
 
 func readServiceDiscount(settings map[string]interface{}) ServiceDiscountSetting {
 	out := ServiceDiscountSetting{
-		Enabled: false,
-		Type:    ServiceDiscountTypePercent,
-		Value:   0,
+		Enabled:  false,
+		Type:     ServiceDiscountTypePercent,
+		Value:    0,
+		Stacking: ServiceDiscountStackBest,
 	}
 	if settings == nil {
 		return out
@@ -102,6 +124,12 @@ func readServiceDiscount(settings map[string]interface{}) ServiceDiscountSetting
 	if val, ok := raw["value"].(float64); ok {
 		out.Value = val
 	}
+	if st, ok := raw["stacking"].(string); ok {
+		switch ServiceDiscountStacking(st) {
+		case ServiceDiscountStackCombine, ServiceDiscountStackClientPriority:
+			out.Stacking = ServiceDiscountStacking(st)
+		}
+	}
 	return out
 }
 
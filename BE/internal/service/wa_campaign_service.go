@@ -11,7 +11,10 @@ import (
 	"github.com/hibiken/asynq"
 
 	asynqInfra "rrnet/internal/infra/asynq"
+	"rrnet/internal/domain/client"
+	"rrnet/internal/domain/tenant"
 	"rrnet/internal/domain/wa_campaign"
+	"rrnet/internal/domain/wa_template"
 	"rrnet/internal/repository"
 )
 
@@ -22,20 +25,28 @@ var (
 	ErrWACampaignNoRecipients    = errors.New("no recipients found")
 )
 
+// WACampaignService sends one message to every client of a group. The message is a template
+// rendered per recipient when the campaign is queued.
 type WACampaignService struct {
 	campaignRepo *repository.WACampaignRepository
 	clientRepo   *repository.ClientRepository
+	tenantRepo   *repository.TenantRepository
+	renderer     *WATemplateRenderer
 	asynqClient  *asynq.Client
 }
 
 func NewWACampaignService(
 	campaignRepo *repository.WACampaignRepository,
 	clientRepo *repository.ClientRepository,
+	tenantRepo *repository.TenantRepository,
+	renderer *WATemplateRenderer,
 	asynqClient *asynq.Client,
 ) *WACampaignService {
 	return &WACampaignService{
 		campaignRepo: campaignRepo,
 		clientRepo:   clientRepo,
+		tenantRepo:   tenantRepo,
+		renderer:     renderer,
 		asynqClient:  asynqClient,
 	}
 }
@@ -52,6 +63,14 @@ func (s *WACampaignService) CreateAndEnqueue(ctx context.Context, tenantID uuid.
 	if groupID == uuid.Nil {
 		return nil, ErrWACampaignGroupRequired
 	}
+	msg, err := wa_template.Parse(message)
+	if err != nil {
+		return nil, err
+	}
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
 
 	clients, err := s.clientRepo.ListByGroupID(ctx, tenantID, groupID)
 	if err != nil {
@@ -59,6 +78,7 @@ func (s *WACampaignService) CreateAndEnqueue(ctx context.Context, tenantID uuid.
 	}
 
 	var recs []*wa_campaign.Recipient
+	texts := make(map[uuid.UUID]string)
 	for _, c := range clients {
 		phone := ""
 		if c.Phone != nil {
@@ -67,8 +87,13 @@ func (s *WACampaignService) CreateAndEnqueue(ctx context.Context, tenantID uuid.
 		if phone == "" {
 			continue
 		}
+		text, err := s.renderer.Render(ctx, t, c, nil, msg, nil)
+		if err != nil {
+			return nil, err
+		}
 		id := uuid.New()
 		clientID := c.ID
+		texts[id] = text
 		recs = append(recs, &wa_campaign.Recipient{
 			ID:         id,
 			CampaignID: uuid.Nil, // filled after campaign created
@@ -94,7 +119,7 @@ func (s *WACampaignService) CreateAndEnqueue(ctx context.Context, tenantID uuid.
 
 	// Enqueue tasks
 	for _, r := range recs {
-		task, err := NewWACampaignSendTask(tenantID, camp.ID, r.ID, r.Phone, message)
+		task, err := NewWACampaignSendTask(tenantID, camp.ID, r.ID, r.Phone, texts[r.ID])
 		if err != nil {
 			return nil, err
 		}
@@ -145,6 +170,14 @@ func (s *WACampaignService) RetryFailed(ctx context.Context, tenantID, campaignI
 	if len(failedRecs) == 0 {
 		return 0, nil
 	}
+	msg, err := wa_template.Parse(camp.Message)
+	if err != nil {
+		return 0, err
+	}
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return 0, err
+	}
 
 	n, err := s.campaignRepo.ResetFailedRecipients(ctx, tenantID, campaignID)
 	if err != nil {
@@ -152,7 +185,11 @@ func (s *WACampaignService) RetryFailed(ctx context.Context, tenantID, campaignI
 	}
 
 	for _, r := range failedRecs {
-		task, err := NewWACampaignSendTask(tenantID, campaignID, r.ID, r.Phone, camp.Message)
+		text, err := s.recipientText(ctx, t, r, msg)
+		if err != nil {
+			return 0, err
+		}
+		task, err := NewWACampaignSendTask(tenantID, campaignID, r.ID, r.Phone, text)
 		if err != nil {
 			return 0, err
 		}
@@ -166,3 +203,19 @@ func (s *WACampaignService) RetryFailed(ctx context.Context, tenantID, campaignI
 }
 
 
+
+// recipientText renders the message again for a recipient; a deleted client keeps the name and
+// phone stored with the recipient
+func (s *WACampaignService) recipientText(ctx context.Context, t *tenant.Tenant, r *wa_campaign.Recipient, msg *wa_template.Message) (string, error) {
+	c := &client.Client{TenantID: t.ID, Name: r.ClientName, Phone: &r.Phone}
+	if r.ClientID != nil {
+		found, err := s.clientRepo.GetByID(ctx, t.ID, *r.ClientID)
+		if err != nil && !errors.Is(err, repository.ErrClientNotFound) {
+			return "", err
+		}
+		if found != nil {
+			c = found
+		}
+	}
+	return s.renderer.Render(ctx, t, c, nil, msg, nil)
+}
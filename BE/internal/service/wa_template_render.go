@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/client"
+	"rrnet/internal/domain/tenant"
+	"rrnet/internal/domain/wa_template"
+	"rrnet/internal/repository"
+)
+
+// WATemplateRenderer renders WhatsApp templates for one recipient. It loads what the message needs
+// beyond the client: the open invoice of the client and the payment link of the invoice.
+type WATemplateRenderer struct {
+	invoiceRepo        *repository.InvoiceRepository
+	paymentRequestRepo *repository.PaymentRequestRepository
+}
+
+func NewWATemplateRenderer(invoiceRepo *repository.InvoiceRepository, paymentRequestRepo *repository.PaymentRequestRepository) *WATemplateRenderer {
+	return &WATemplateRenderer{invoiceRepo: invoiceRepo, paymentRequestRepo: paymentRequestRepo}
+}
+
+// Render renders msg for client c. Without inv, a message using invoice variables or the payment
+// link is rendered with the oldest unpaid invoice of the client. extra holds event facts such as
+// the received payment.
+func (r *WATemplateRenderer) Render(ctx context.Context, t *tenant.Tenant, c *client.Client, inv *billing.Invoice, msg *wa_template.Message, extra wa_template.Values) (string, error) {
+	if inv == nil && (msg.Uses("invoice.") || msg.Uses("payment_link")) {
+		open, err := r.OpenInvoice(ctx, c)
+		if err != nil {
+			return "", err
+		}
+		inv = open
+	}
+	paymentLink := ""
+	if inv != nil && msg.Uses("payment_link") {
+		link, err := r.paymentLink(ctx, inv)
+		if err != nil {
+			return "", err
+		}
+		paymentLink = link
+	}
+	values := waTemplateValues(t, c, inv, paymentLink)
+	for k, v := range extra {
+		values[k] = v
+	}
+	return msg.Render(values), nil
+}
+
+// OpenInvoice returns the oldest unpaid invoice of a client, or nil
+func (r *WATemplateRenderer) OpenInvoice(ctx context.Context, c *client.Client) (*billing.Invoice, error) {
+	if c.ID == uuid.Nil {
+		return nil, nil
+	}
+	invoices, err := r.invoiceRepo.GetClientPendingInvoices(ctx, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, inv := range invoices {
+		if inv.TenantID == c.TenantID {
+			return inv, nil
+		}
+	}
+	return nil, nil
+}
+
+// paymentLink is the checkout URL of the newest open payment gateway request of the invoice
+func (r *WATemplateRenderer) paymentLink(ctx context.Context, inv *billing.Invoice) (string, error) {
+	if r.paymentRequestRepo == nil {
+		return "", nil
+	}
+	requests, err := r.paymentRequestRepo.ListByInvoice(ctx, inv.TenantID, inv.ID)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	for _, p := range requests {
+		if p.IsOpen(now) && p.PaymentURL != "" {
+			return p.PaymentURL, nil
+		}
+	}
+	return "", nil
+}
+
+// waTemplateValues are the variable values of a client and, when given, an invoice
+func waTemplateValues(t *tenant.Tenant, c *client.Client, inv *billing.Invoice, paymentLink string) wa_template.Values {
+	values := wa_template.Values{
+		"client.name": c.Name,
+		"client.code": c.ClientCode,
+	}
+	if c.Phone != nil {
+		values["client.phone"] = strings.TrimSpace(*c.Phone)
+	}
+	if c.Address != nil {
+		values["client.address"] = *c.Address
+	}
+	if t != nil {
+		values["tenant.name"] = t.Name
+	}
+	if inv != nil {
+		values["invoice.number"] = inv.InvoiceNumber
+		values["invoice.period_start"] = inv.PeriodStart
+		values["invoice.period_end"] = inv.PeriodEnd
+		values["invoice.due_date"] = inv.DueDate
+		values["invoice.total"] = inv.TotalAmount
+		values["invoice.payable"] = inv.PayableAmount()
+		values["invoice.paid"] = inv.PaidAmount
+		values["invoice.remaining"] = inv.RemainingAmount()
+		values["invoice.unique_code"] = uniqueCodeText(inv.UniqueCode)
+	}
+	if paymentLink != "" {
+		values["payment_link"] = paymentLink
+	}
+	return values
+}
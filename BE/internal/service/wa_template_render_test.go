@@ -0,0 +1,75 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/client"
+	"rrnet/internal/domain/tenant"
+	"rrnet/internal/domain/wa_template"
+)
+
+func TestWATemplateParseRejects(t *testing.T) {
+	for _, content := range []string{
+		"Halo {{client.nama}}",                 // unknown variable
+		"Halo {{client.name",                   // unclosed
+		"Halo {{}}",                            // empty
+		"Total {{invoice.total | idr}}",        // unknown filter
+		"Halo {{client.name | rupiah}}",        // filter does not fit the type
+		"Jatuh tempo {{invoice.total | date}}", // filter does not fit the type
+		`Jatuh tempo {{invoice.due_date | date "02" "01"}}`,
+		`Halo {{client.name | default}}`, // missing argument
+		`Jatuh tempo {{invoice.due_date | date "02 Jan}}`,
+		"Halo {{client.name client.code}}",
+	} {
+		assert.ErrorIs(t, wa_template.Validate(content), wa_template.ErrTemplateInvalid, content)
+	}
+	assert.NoError(t, wa_template.Validate("Promo bulan ini, tanpa variabel"))
+	assert.NoError(t, wa_template.Validate("Kode {promo} tetap teks biasa"))
+}
+
+func TestWATemplateRender(t *testing.T) {
+	code := 42
+	phone := "08123"
+	tn := &tenant.Tenant{Name: "RRNet"}
+	c := &client.Client{Name: "Budi Santoso", ClientCode: "C-009", Phone: &phone}
+	inv := &billing.Invoice{
+		InvoiceNumber: "INV-202505-0003",
+		PeriodStart:   time.Date(2025, time.May, 1, 0, 0, 0, 0, time.Local),
+		PeriodEnd:     time.Date(2025, time.May, 31, 0, 0, 0, 0, time.Local),
+		DueDate:       time.Date(2025, time.May, 10, 0, 0, 0, 0, time.Local),
+		TotalAmount:   1250000,
+		UniqueCode:    &code,
+	}
+	values := waTemplateValues(tn, c, inv, "https://pay.example/abc")
+
+	text, err := wa_template.Render(`{{ client.name | upper }} ({{client.code}}) - {{tenant.name}}: {{invoice.number}} {{invoice.total | rupiah}}, `+
+		`bayar {{invoice.payable}} sebelum {{invoice.due_date | date "02 Jan 2006"}} di {{payment_link}}`, values)
+	assert.NoError(t, err)
+	assert.Equal(t, "BUDI SANTOSO (C-009) - RRNet: INV-202505-0003 Rp 1.250.000, bayar 1250042 sebelum 10 May 2025 di https://pay.example/abc", text)
+
+	// Values missing for the recipient render empty unless a default is given
+	noInvoice := waTemplateValues(tn, c, nil, "")
+	text, err = wa_template.Render(`Tagihan: {{invoice.number}}|{{invoice.total | rupiah}}|{{payment_link | default "hubungi admin"}}`, noInvoice)
+	assert.NoError(t, err)
+	assert.Equal(t, "Tagihan: ||hubungi admin", text)
+
+	// Placeholders of templates written before the engine keep working
+	text, err = wa_template.Render("Halo {client_name}, tagihan {invoice_number} periode {period} sebesar {payable_amount} jatuh tempo {due_date}. {tidak_dikenal}", values)
+	assert.NoError(t, err)
+	assert.Equal(t, "Halo Budi Santoso, tagihan INV-202505-0003 periode 05-2025 sebesar Rp 1.250.042 jatuh tempo 10-05-2025. {tidak_dikenal}", text)
+}
+
+func TestWATemplateUses(t *testing.T) {
+	msg, err := wa_template.Parse("Halo {{client.name}}, bayar di {{payment_link}}")
+	assert.NoError(t, err)
+	assert.True(t, msg.Uses("payment_link"))
+	assert.False(t, msg.Uses("invoice."))
+
+	msg, err = wa_template.Parse("Halo {client_name}, tagihan {amount}")
+	assert.NoError(t, err)
+	assert.True(t, msg.Uses("invoice."))
+}
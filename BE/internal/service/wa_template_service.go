@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 
+	"rrnet/internal/domain/billing"
 	"rrnet/internal/domain/wa_template"
 	"rrnet/internal/repository"
 )
@@ -14,14 +15,31 @@ import (
 var (
 	ErrWATemplateNameRequired    = errors.New("template name is required")
 	ErrWATemplateContentRequired = errors.New("template content is required")
+	ErrWATemplateClientRequired  = errors.New("client is required for a preview")
 )
 
 type WATemplateService struct {
-	repo *repository.WATemplateRepository
+	repo        *repository.WATemplateRepository
+	tenantRepo  *repository.TenantRepository
+	clientRepo  *repository.ClientRepository
+	invoiceRepo *repository.InvoiceRepository
+	renderer    *WATemplateRenderer
 }
 
-func NewWATemplateService(repo *repository.WATemplateRepository) *WATemplateService {
-	return &WATemplateService{repo: repo}
+func NewWATemplateService(
+	repo *repository.WATemplateRepository,
+	tenantRepo *repository.TenantRepository,
+	clientRepo *repository.ClientRepository,
+	invoiceRepo *repository.InvoiceRepository,
+	renderer *WATemplateRenderer,
+) *WATemplateService {
+	return &WATemplateService{
+		repo:        repo,
+		tenantRepo:  tenantRepo,
+		clientRepo:  clientRepo,
+		invoiceRepo: invoiceRepo,
+		renderer:    renderer,
+	}
 }
 
 func (s *WATemplateService) List(ctx context.Context, tenantID uuid.UUID) ([]*wa_template.Template, error) {
@@ -37,6 +55,9 @@ func (s *WATemplateService) Create(ctx context.Context, tenantID uuid.UUID, name
 	if content == "" {
 		return nil, ErrWATemplateContentRequired
 	}
+	if err := wa_template.Validate(content); err != nil {
+		return nil, err
+	}
 	return s.repo.Create(ctx, tenantID, name, content)
 }
 
@@ -49,6 +70,9 @@ func (s *WATemplateService) Update(ctx context.Context, tenantID, id uuid.UUID,
 	if content == "" {
 		return nil, ErrWATemplateContentRequired
 	}
+	if err := wa_template.Validate(content); err != nil {
+		return nil, err
+	}
 	return s.repo.Update(ctx, tenantID, id, name, content)
 }
 
@@ -57,3 +81,68 @@ func (s *WATemplateService) Delete(ctx context.Context, tenantID, id uuid.UUID)
 }
 
 
+
+// WATemplatePreviewRequest renders a stored template (TemplateID) or unsaved Content for a client.
+// Without InvoiceID the oldest unpaid invoice of the client is used.
+type WATemplatePreviewRequest struct {
+	TemplateID *uuid.UUID `json:"template_id,omitempty"`
+	Content    string     `json:"content,omitempty"`
+	ClientID   uuid.UUID  `json:"client_id"`
+	InvoiceID  *uuid.UUID `json:"invoice_id,omitempty"`
+}
+
+type WATemplatePreview struct {
+	Text      string     `json:"text"`
+	ClientID  uuid.UUID  `json:"client_id"`
+	InvoiceID *uuid.UUID `json:"invoice_id,omitempty"`
+}
+
+// Preview renders a template as the chosen client would receive it
+func (s *WATemplateService) Preview(ctx context.Context, tenantID uuid.UUID, req WATemplatePreviewRequest) (*WATemplatePreview, error) {
+	if req.ClientID == uuid.Nil {
+		return nil, ErrWATemplateClientRequired
+	}
+	content := req.Content
+	if req.TemplateID != nil {
+		tpl, err := s.repo.Get(ctx, tenantID, *req.TemplateID)
+		if err != nil {
+			return nil, err
+		}
+		content = tpl.Content
+	}
+	if strings.TrimSpace(content) == "" {
+		return nil, ErrWATemplateContentRequired
+	}
+	msg, err := wa_template.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.clientRepo.GetByID(ctx, tenantID, req.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	var inv *billing.Invoice
+	if req.InvoiceID != nil {
+		inv, err = s.invoiceRepo.GetByID(ctx, *req.InvoiceID)
+		if err != nil || inv.TenantID != tenantID || inv.ClientID != c.ID {
+			return nil, ErrInvoiceNotFound
+		}
+	} else if inv, err = s.renderer.OpenInvoice(ctx, c); err != nil {
+		return nil, err
+	}
+
+	text, err := s.renderer.Render(ctx, t, c, inv, msg, nil)
+	if err != nil {
+		return nil, err
+	}
+	out := &WATemplatePreview{Text: text, ClientID: c.ID}
+	if inv != nil {
+		out.InvoiceID = &inv.ID
+	}
+	return out, nil
+}
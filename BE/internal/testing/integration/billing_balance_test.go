@@ -0,0 +1,183 @@
+package integration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/client"
+	"rrnet/internal/domain/tenant"
+	"rrnet/internal/repository"
+	"rrnet/internal/service"
+	"rrnet/internal/testing/fixtures"
+	"rrnet/internal/testing/helpers"
+)
+
+type balanceEnv struct {
+	tc             *helpers.TestConfig
+	invoiceRepo    *repository.InvoiceRepository
+	balanceRepo    *repository.BalanceRepository
+	billingService *service.BillingService
+	tenant         *tenant.Tenant
+	client         *client.Client
+}
+
+func setupBalanceEnv(t *testing.T) *balanceEnv {
+	tc := helpers.SetupTestEnvironment(t)
+	t.Cleanup(func() {
+		tc.TruncateTables(t, "client_balance_entries", "credit_notes", "payments", "invoices", "clients", "tenants")
+		tc.CleanupTestEnvironment(t)
+	})
+
+	tenantRepo := repository.NewTenantRepository(tc.DB)
+	clientRepo := repository.NewClientRepository(tc.DB)
+	env := &balanceEnv{
+		tc:          tc,
+		invoiceRepo: repository.NewInvoiceRepository(tc.DB),
+		balanceRepo: repository.NewBalanceRepository(tc.DB),
+	}
+	env.billingService = service.NewBillingService(env.invoiceRepo, repository.NewPaymentRepository(tc.DB), clientRepo, repository.NewServicePackageRepository(tc.DB))
+	env.billingService.SetBalanceRepository(env.balanceRepo)
+
+	env.tenant = fixtures.CreateTestTenant("Test Tenant", "test-tenant")
+	require.NoError(t, tenantRepo.Create(tc.Ctx, env.tenant))
+	env.client = fixtures.CreateTestClient(env.tenant.ID, "Test Client", "081234567890")
+	env.client.MonthlyFee = 150000
+	require.NoError(t, clientRepo.Create(tc.Ctx, env.client))
+	return env
+}
+
+func (e *balanceEnv) invoice(t *testing.T, amount int64) *billing.Invoice {
+	now := time.Now()
+	inv, err := e.billingService.CreateInvoice(e.tc.Ctx, e.tenant.ID, service.CreateInvoiceRequest{
+		ClientID:    e.client.ID,
+		PeriodStart: now.AddDate(0, -1, 0),
+		PeriodEnd:   now,
+		DueDate:     now.AddDate(0, 0, 7),
+		Items:       []service.InvoiceItemRequest{{Description: "Layanan Internet", Quantity: 1, UnitPrice: amount}},
+	})
+	require.NoError(t, err)
+	return inv
+}
+
+func (e *balanceEnv) pay(t *testing.T, inv *billing.Invoice, amount int64) {
+	_, err := e.billingService.RecordPayment(e.tc.Ctx, e.tenant.ID, uuid.New(), service.RecordPaymentRequest{
+		InvoiceID: inv.ID,
+		Amount:    amount,
+		Method:    billing.PaymentMethodBankTransfer,
+	})
+	require.NoError(t, err)
+}
+
+func (e *balanceEnv) balance(t *testing.T) int64 {
+	balance, err := e.balanceRepo.GetBalance(e.tc.Ctx, e.tenant.ID, e.client.ID)
+	require.NoError(t, err)
+	return balance
+}
+
+func (e *balanceEnv) reload(t *testing.T, inv *billing.Invoice) *billing.Invoice {
+	out, err := e.invoiceRepo.GetByID(e.tc.Ctx, inv.ID)
+	require.NoError(t, err)
+	return out
+}
+
+func TestOverpaymentIsCreditedToClientBalance(t *testing.T) {
+	env := setupBalanceEnv(t)
+
+	inv := env.invoice(t, 100000)
+	env.pay(t, inv, 120000)
+
+	inv = env.reload(t, inv)
+	assert.Equal(t, billing.InvoiceStatusPaid, inv.Status)
+	assert.Equal(t, int64(100000), inv.PaidAmount)
+	assert.Equal(t, int64(20000), env.balance(t))
+
+	entries, _, err := env.balanceRepo.List(env.tc.Ctx, repository.BalanceEntryFilter{TenantID: env.tenant.ID, ClientID: env.client.ID})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, billing.BalanceOverpayment, entries[0].Type)
+	assert.Equal(t, &inv.ID, entries[0].InvoiceID)
+}
+
+func TestClientCreditPaysGeneratedInvoice(t *testing.T) {
+	env := setupBalanceEnv(t)
+
+	// 200.000 of credit covers the 150.000 monthly invoice and leaves 50.000
+	first := env.invoice(t, 100000)
+	env.pay(t, first, 300000)
+	require.Equal(t, int64(200000), env.balance(t))
+
+	inv, err := env.billingService.GenerateMonthlyInvoice(env.tc.Ctx, env.tenant.ID, env.client.ID)
+	require.NoError(t, err)
+	inv = env.reload(t, inv)
+	assert.Equal(t, billing.InvoiceStatusPaid, inv.Status)
+	assert.Equal(t, inv.TotalAmount, inv.PaidAmount)
+	assert.Equal(t, 200000-inv.TotalAmount, env.balance(t))
+}
+
+func TestRefundBalance(t *testing.T) {
+	env := setupBalanceEnv(t)
+
+	inv := env.invoice(t, 100000)
+	env.pay(t, inv, 130000)
+	require.Equal(t, int64(30000), env.balance(t))
+
+	refund := func(amount int64) (*billing.BalanceEntry, error) {
+		return env.billingService.RefundBalance(env.tc.Ctx, env.tenant.ID, uuid.New(), env.client.ID, service.RefundRequest{Amount: amount})
+	}
+
+	_, err := refund(0)
+	assert.ErrorIs(t, err, service.ErrRefundAmountInvalid)
+	_, err = refund(40000)
+	assert.ErrorIs(t, err, repository.ErrInsufficientBalance)
+
+	entry, err := refund(25000)
+	require.NoError(t, err)
+	assert.Equal(t, billing.BalanceRefund, entry.Type)
+	assert.Equal(t, int64(-25000), entry.Amount)
+	assert.Equal(t, int64(5000), entry.BalanceAfter)
+	assert.Equal(t, int64(5000), env.balance(t))
+}
+
+func TestCreditNoteCreditsPaidExcess(t *testing.T) {
+	env := setupBalanceEnv(t)
+	approver := uuid.New()
+
+	// Unpaid invoice: the invoice is reduced, nothing is credited
+	unpaid := env.invoice(t, 150000)
+	cn, err := env.billingService.IssueCreditNote(env.tc.Ctx, env.tenant.ID, approver, unpaid.ID, service.CreditNoteRequest{Amount: 50000, Reason: "Gangguan 10 hari"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, cn.CreditNoteNumber)
+	unpaid = env.reload(t, unpaid)
+	assert.Equal(t, int64(100000), unpaid.TotalAmount)
+	assert.Equal(t, billing.InvoiceStatusPending, unpaid.Status)
+	assert.Equal(t, int64(0), env.balance(t))
+
+	// Partly paid above the reduced total: the excess is credited and the invoice is paid
+	partly := env.invoice(t, 150000)
+	env.pay(t, partly, 120000)
+	cn, err = env.billingService.IssueCreditNote(env.tc.Ctx, env.tenant.ID, approver, partly.ID, service.CreditNoteRequest{Amount: 50000, Reason: "Gangguan 10 hari"})
+	require.NoError(t, err)
+	partly = env.reload(t, partly)
+	assert.Equal(t, int64(100000), partly.TotalAmount)
+	assert.Equal(t, int64(100000), partly.PaidAmount)
+	assert.Equal(t, billing.InvoiceStatusPaid, partly.Status)
+	assert.NotNil(t, partly.PaidAt)
+	assert.Equal(t, int64(20000), env.balance(t))
+
+	entries, _, err := env.balanceRepo.List(env.tc.Ctx, repository.BalanceEntryFilter{TenantID: env.tenant.ID, ClientID: env.client.ID})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, billing.BalanceCreditNote, entries[0].Type)
+	assert.Equal(t, &cn.ID, entries[0].CreditNoteID)
+	assert.Contains(t, entries[0].Description, cn.CreditNoteNumber)
+
+	notes, err := env.billingService.ListCreditNotes(env.tc.Ctx, env.tenant.ID, partly.ID)
+	require.NoError(t, err)
+	require.Len(t, notes, 1)
+	assert.Equal(t, cn.CreditNoteNumber, notes[0].CreditNoteNumber)
+}
@@ -0,0 +1,94 @@
+package integration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rrnet/internal/domain/client"
+	"rrnet/internal/domain/discount"
+	"rrnet/internal/repository"
+	"rrnet/internal/service"
+	"rrnet/internal/testing/fixtures"
+	"rrnet/internal/testing/helpers"
+)
+
+// Discount max cycles count billing cycles: a quarterly client's invoice uses one cycle for 3 months
+func TestDiscountMaxCyclesCountsBillingCycles(t *testing.T) {
+	tc := helpers.SetupTestEnvironment(t)
+	t.Cleanup(func() {
+		tc.TruncateTables(t, "invoices", "clients", "discounts", "tenants")
+		tc.CleanupTestEnvironment(t)
+	})
+
+	tenantRepo := repository.NewTenantRepository(tc.DB)
+	clientRepo := repository.NewClientRepository(tc.DB)
+	invoiceRepo := repository.NewInvoiceRepository(tc.DB)
+	discountRepo := repository.NewDiscountRepository(tc.DB)
+	billingService := service.NewBillingService(invoiceRepo, repository.NewPaymentRepository(tc.DB), clientRepo, repository.NewServicePackageRepository(tc.DB))
+	billingService.SetDiscountRepository(discountRepo)
+
+	tn := fixtures.CreateTestTenant("Test Tenant", "test-tenant")
+	require.NoError(t, tenantRepo.Create(tc.Ctx, tn))
+
+	newDiscount := func(maxCycles int) *discount.Discount {
+		now := time.Now()
+		d := &discount.Discount{
+			ID: uuid.New(), TenantID: tn.ID, Name: "Promo", Type: discount.TypePercent, Value: 10,
+			MaxCycles: &maxCycles, IsActive: true, CreatedAt: now, UpdatedAt: now,
+		}
+		require.NoError(t, discountRepo.Create(tc.Ctx, d))
+		return d
+	}
+	// quarterlyClient has been billed one quarter with the discount already
+	quarterlyClient := func(phone string, d *discount.Discount) *client.Client {
+		months := 3
+		c := fixtures.CreateTestClient(tn.ID, "Client "+phone, phone)
+		c.MonthlyFee = 100000
+		c.BillingCycleMonths = &months
+		c.DiscountID = &d.ID
+		require.NoError(t, clientRepo.Create(tc.Ctx, c))
+
+		now := time.Now()
+		_, err := billingService.CreateInvoice(tc.Ctx, tn.ID, service.CreateInvoiceRequest{
+			ClientID:    c.ID,
+			PeriodStart: now.AddDate(0, -6, 0),
+			PeriodEnd:   now.AddDate(0, -3, -1),
+			DueDate:     now.AddDate(0, -6, 0),
+			Items: []service.InvoiceItemRequest{
+				{Description: "Layanan Internet (3 bulan)", Quantity: 1, UnitPrice: 300000},
+				{Description: "Diskon Promo (10%)", Quantity: 1, UnitPrice: -30000, DiscountID: &d.ID, IsDiscount: true},
+			},
+		})
+		require.NoError(t, err)
+		return c
+	}
+	discounted := func(c *client.Client, d *discount.Discount) bool {
+		generated, err := billingService.GenerateMonthlyInvoice(tc.Ctx, tn.ID, c.ID)
+		require.NoError(t, err)
+		inv, err := invoiceRepo.GetByID(tc.Ctx, generated.ID)
+		require.NoError(t, err)
+		for _, item := range inv.Items {
+			if item.DiscountID != nil && *item.DiscountID == d.ID {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Two cycles: the second quarter is still discounted although 3 months were discounted before
+	twoCycles := newDiscount(2)
+	c := quarterlyClient("081200000001", twoCycles)
+	assert.True(t, discounted(c, twoCycles))
+	used, err := invoiceRepo.CountDiscountUsage(tc.Ctx, tn.ID, c.ID, twoCycles.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, used)
+
+	// One cycle: used up by the first quarter
+	oneCycle := newDiscount(1)
+	c = quarterlyClient("081200000002", oneCycle)
+	assert.False(t, discounted(c, oneCycle))
+}
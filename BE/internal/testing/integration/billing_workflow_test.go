@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"sync"
 	"testing"
 	"time"
 
@@ -305,3 +306,54 @@ func TestCollector3PhaseFlow(t *testing.T) {
 	assert.Equal(t, "collector", string(paymentList2[0].Method))
 }
 
+func TestGenerateMonthlyInvoiceConcurrent(t *testing.T) {
+	tc := helpers.SetupTestEnvironment(t)
+	defer tc.CleanupTestEnvironment(t)
+	defer tc.TruncateTables(t, "invoices", "clients", "tenants")
+
+	tenantRepo := repository.NewTenantRepository(tc.DB)
+	clientRepo := repository.NewClientRepository(tc.DB)
+	invoiceRepo := repository.NewInvoiceRepository(tc.DB)
+	paymentRepo := repository.NewPaymentRepository(tc.DB)
+	servicePackageRepo := repository.NewServicePackageRepository(tc.DB)
+
+	billingService := service.NewBillingService(invoiceRepo, paymentRepo, clientRepo, servicePackageRepo)
+
+	tenant := fixtures.CreateTestTenant("Test Tenant", "test-tenant")
+	require.NoError(t, tenantRepo.Create(tc.Ctx, tenant))
+	client := fixtures.CreateTestClient(tenant.ID, "Test Client", "081234567890")
+	client.MonthlyFee = 150000
+	require.NoError(t, clientRepo.Create(tc.Ctx, client))
+
+	// Replicas generating the same invoice race past the existence check; the losers of the insert
+	// (ErrMonthlyInvoiceExists) return the winner's invoice
+	const replicas = 5
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	ids := make([]uuid.UUID, replicas)
+	errs := make([]error, replicas)
+	for i := 0; i < replicas; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			inv, err := billingService.GenerateMonthlyInvoice(tc.Ctx, tenant.ID, client.ID)
+			errs[i] = err
+			if inv != nil {
+				ids[i] = inv.ID
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	for i := 0; i < replicas; i++ {
+		require.NoError(t, errs[i])
+		assert.Equal(t, ids[0], ids[i])
+	}
+	invoices, total, err := invoiceRepo.List(tc.Ctx, repository.InvoiceFilter{TenantID: tenant.ID, ClientID: &client.ID, Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	require.Len(t, invoices, 1)
+	assert.Equal(t, ids[0], invoices[0].ID)
+}
@@ -0,0 +1,66 @@
+package integration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/repository"
+)
+
+func TestDunningStepReservedOnce(t *testing.T) {
+	env := setupIsolirEnv(t)
+	dunningRepo := repository.NewDunningRepository(env.tc.DB)
+
+	c := env.pppoeClient(t, "CL-001", nil)
+	inv := env.invoiceDue(t, c, time.Now().AddDate(0, 0, -7))
+	run := func() *billing.DunningStepRun {
+		return &billing.DunningStepRun{
+			ID:         uuid.New(),
+			TenantID:   env.tenant.ID,
+			ClientID:   c.ID,
+			InvoiceID:  inv.ID,
+			OffsetDays: 7,
+			Action:     billing.DunningActionIsolir,
+			RunDate:    time.Now(),
+			CreatedAt:  time.Now(),
+		}
+	}
+	status := func() billing.DunningStepStatus {
+		runs, err := dunningRepo.ListByInvoices(env.tc.Ctx, env.tenant.ID, []uuid.UUID{inv.ID})
+		require.NoError(t, err)
+		require.Len(t, runs, 1)
+		return runs[0].Status
+	}
+
+	first := run()
+	ok, err := dunningRepo.ReserveRun(env.tc.Ctx, first)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, billing.DunningStepRunning, status())
+
+	// A step whose outcome was never stored is not claimed again
+	ok, err = dunningRepo.ReserveRun(env.tc.Ctx, run())
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// A failed step is retried on the same row
+	require.NoError(t, dunningRepo.FinishRun(env.tc.Ctx, first.ID, billing.DunningStepFailed, "router unreachable"))
+	assert.Equal(t, billing.DunningStepFailed, status())
+	retry := run()
+	ok, err = dunningRepo.ReserveRun(env.tc.Ctx, retry)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, first.ID, retry.ID)
+
+	// An executed step stays executed
+	require.NoError(t, dunningRepo.FinishRun(env.tc.Ctx, retry.ID, billing.DunningStepExecuted, ""))
+	ok, err = dunningRepo.ReserveRun(env.tc.Ctx, run())
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, billing.DunningStepExecuted, status())
+}
@@ -0,0 +1,218 @@
+package integration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/domain/client"
+	"rrnet/internal/domain/tenant"
+	"rrnet/internal/repository"
+	"rrnet/internal/service"
+	"rrnet/internal/testing/fixtures"
+	"rrnet/internal/testing/helpers"
+)
+
+type isolirEnv struct {
+	tc             *helpers.TestConfig
+	tenantRepo     *repository.TenantRepository
+	clientRepo     *repository.ClientRepository
+	invoiceRepo    *repository.InvoiceRepository
+	isolirLogRepo  *repository.IsolirLogRepository
+	featureRepo    *repository.FeatureRepository
+	billingService *service.BillingService
+	isolirService  *service.IsolirService
+	tenant         *tenant.Tenant
+}
+
+func setupIsolirEnv(t *testing.T) *isolirEnv {
+	tc := helpers.SetupTestEnvironment(t)
+	t.Cleanup(func() {
+		tc.TruncateTables(t, "isolir_logs", "feature_toggles", "payments", "invoices", "clients", "tenants")
+		tc.CleanupTestEnvironment(t)
+	})
+
+	env := &isolirEnv{
+		tc:            tc,
+		tenantRepo:    repository.NewTenantRepository(tc.DB),
+		clientRepo:    repository.NewClientRepository(tc.DB),
+		invoiceRepo:   repository.NewInvoiceRepository(tc.DB),
+		isolirLogRepo: repository.NewIsolirLogRepository(tc.DB),
+		featureRepo:   repository.NewFeatureRepository(tc.DB),
+	}
+	servicePackageRepo := repository.NewServicePackageRepository(tc.DB)
+	env.billingService = service.NewBillingService(env.invoiceRepo, repository.NewPaymentRepository(tc.DB), env.clientRepo, servicePackageRepo)
+	env.isolirService = service.NewIsolirService(
+		env.isolirLogRepo,
+		env.clientRepo,
+		env.invoiceRepo,
+		repository.NewRouterRepository(tc.DB),
+		repository.NewPPPoERepository(tc.DB),
+		repository.NewNetworkProfileRepository(tc.DB),
+		servicePackageRepo,
+		env.tenantRepo,
+	)
+
+	env.tenant = fixtures.CreateTestTenant("Test Tenant", "test-tenant")
+	require.NoError(t, env.tenantRepo.Create(tc.Ctx, env.tenant))
+	return env
+}
+
+// pppoeClient creates an active PPPoE client; without a router binding the router step is skipped
+func (e *isolirEnv) pppoeClient(t *testing.T, code string, routerID *uuid.UUID) *client.Client {
+	c := fixtures.CreateTestClient(e.tenant.ID, "Client "+code, "0812"+code)
+	c.ClientCode = code
+	c.ConnectionType = client.ConnectionTypePPPoE
+	if routerID != nil {
+		username := "pppoe-" + code
+		c.RouterID = routerID
+		c.PPPoEUsername = &username
+	}
+	require.NoError(t, e.clientRepo.Create(e.tc.Ctx, c))
+	return c
+}
+
+func (e *isolirEnv) invoiceDue(t *testing.T, c *client.Client, due time.Time) *billing.Invoice {
+	inv, err := e.billingService.CreateInvoice(e.tc.Ctx, e.tenant.ID, service.CreateInvoiceRequest{
+		ClientID:    c.ID,
+		PeriodStart: due.AddDate(0, -1, 0),
+		PeriodEnd:   due,
+		DueDate:     due,
+		Items:       []service.InvoiceItemRequest{{Description: "Layanan Internet", Quantity: 1, UnitPrice: 150000}},
+	})
+	require.NoError(t, err)
+	return inv
+}
+
+func (e *isolirEnv) status(t *testing.T, c *client.Client) client.Status {
+	out, err := e.clientRepo.GetByID(e.tc.Ctx, e.tenant.ID, c.ID)
+	require.NoError(t, err)
+	return out.Status
+}
+
+func (e *isolirEnv) logs(t *testing.T, c *client.Client) []*billing.IsolirLog {
+	logs, _, err := e.isolirLogRepo.List(e.tc.Ctx, repository.IsolirLogFilter{TenantID: e.tenant.ID, ClientID: &c.ID, PageSize: 50})
+	require.NoError(t, err)
+	return logs
+}
+
+func TestIsolateEnforcesBeforeStatusChange(t *testing.T) {
+	env := setupIsolirEnv(t)
+	req := service.IsolateRequest{Reason: "Tunggakan", IsAutomatic: true}
+
+	// The router cannot be loaded: the log fails and the client stays active for the next run
+	missingRouter := uuid.New()
+	failing := env.pppoeClient(t, "CL-001", &missingRouter)
+	entry, err := env.isolirService.Isolate(env.tc.Ctx, env.tenant.ID, failing.ID, req)
+	require.Error(t, err)
+	require.NotNil(t, entry)
+	assert.Equal(t, billing.IsolirStatusFailed, entry.Status)
+	assert.Equal(t, client.StatusActive, env.status(t, failing))
+	logs := env.logs(t, failing)
+	require.Len(t, logs, 1)
+	assert.Equal(t, billing.IsolirStatusFailed, logs[0].Status)
+	assert.NotEmpty(t, logs[0].ErrorMsg)
+
+	// The router step is skipped: the status changes and the log is executed with the note
+	unbound := env.pppoeClient(t, "CL-002", nil)
+	entry, err = env.isolirService.Isolate(env.tc.Ctx, env.tenant.ID, unbound.ID, req)
+	require.NoError(t, err)
+	assert.Equal(t, billing.IsolirStatusExecuted, entry.Status)
+	assert.Contains(t, entry.ErrorMsg, "router step skipped")
+	assert.Equal(t, client.StatusIsolir, env.status(t, unbound))
+	logs = env.logs(t, unbound)
+	require.Len(t, logs, 1)
+	assert.Equal(t, billing.IsolirStatusExecuted, logs[0].Status)
+	assert.NotNil(t, logs[0].ExecutedAt)
+
+	// An isolated client cannot be isolated again, and only isolated clients are reactivated
+	_, err = env.isolirService.Isolate(env.tc.Ctx, env.tenant.ID, unbound.ID, req)
+	assert.ErrorIs(t, err, service.ErrClientNotActive)
+	_, err = env.isolirService.Reactivate(env.tc.Ctx, env.tenant.ID, failing.ID, req)
+	assert.ErrorIs(t, err, service.ErrClientNotIsolated)
+}
+
+func TestReactivateIfSettled(t *testing.T) {
+	env := setupIsolirEnv(t)
+	_, err := env.isolirService.UpdateSettings(env.tc.Ctx, env.tenant.ID, service.IsolirSettings{Mode: service.IsolirModeProfile, Profile: "isolir", GraceDays: 3})
+	require.NoError(t, err)
+
+	today := time.Now()
+	c := env.pppoeClient(t, "CL-001", nil)
+	old := env.invoiceDue(t, c, today.AddDate(0, 0, -10))
+	recent := env.invoiceDue(t, c, today.AddDate(0, 0, -1))
+	_, err = env.isolirService.Isolate(env.tc.Ctx, env.tenant.ID, c.ID, service.IsolateRequest{InvoiceID: &old.ID, Reason: "Tunggakan"})
+	require.NoError(t, err)
+
+	// Active clients without a throttle are left alone
+	active := env.pppoeClient(t, "CL-002", nil)
+	require.NoError(t, env.isolirService.ReactivateIfSettled(env.tc.Ctx, env.tenant.ID, active.ID, nil))
+	assert.Empty(t, env.logs(t, active))
+
+	// The old invoice is still past the grace period
+	require.NoError(t, env.isolirService.ReactivateIfSettled(env.tc.Ctx, env.tenant.ID, c.ID, &recent.ID))
+	assert.Equal(t, client.StatusIsolir, env.status(t, c))
+
+	// Once it is paid only the invoice within the grace period is open, which does not keep isolir
+	require.NoError(t, env.invoiceRepo.UpdateStatus(env.tc.Ctx, old.ID, billing.InvoiceStatusPaid))
+	require.NoError(t, env.isolirService.ReactivateIfSettled(env.tc.Ctx, env.tenant.ID, c.ID, &old.ID))
+	assert.Equal(t, client.StatusActive, env.status(t, c))
+
+	logs := env.logs(t, c)
+	require.Len(t, logs, 2)
+	actions := map[billing.IsolirAction]*billing.IsolirLog{}
+	for _, l := range logs {
+		actions[l.Action] = l
+	}
+	require.Contains(t, actions, billing.IsolirActionReactivate)
+	assert.Equal(t, billing.IsolirStatusExecuted, actions[billing.IsolirActionReactivate].Status)
+	assert.True(t, actions[billing.IsolirActionReactivate].IsAutomatic)
+	assert.Equal(t, &old.ID, actions[billing.IsolirActionReactivate].InvoiceID)
+}
+
+func TestIsolirSchedulerRunForTenant(t *testing.T) {
+	env := setupIsolirEnv(t)
+	require.NoError(t, env.featureRepo.UpsertTenantToggle(env.tc.Ctx, env.tenant.ID, "isolir_auto", true))
+	_, err := env.isolirService.UpdateSettings(env.tc.Ctx, env.tenant.ID, service.IsolirSettings{Mode: service.IsolirModeProfile, Profile: "isolir", GraceDays: 5})
+	require.NoError(t, err)
+	tn, err := env.tenantRepo.GetByID(env.tc.Ctx, env.tenant.ID)
+	require.NoError(t, err)
+
+	features := service.NewFeatureResolver(repository.NewPlanRepository(env.tc.DB), repository.NewAddonRepository(env.tc.DB), env.featureRepo)
+	scheduler := service.NewIsolirScheduler(env.clientRepo, env.invoiceRepo, env.isolirService, features)
+
+	now := time.Now()
+	overdue := env.pppoeClient(t, "CL-001", nil)
+	env.invoiceDue(t, overdue, now.AddDate(0, 0, -20))
+	env.invoiceDue(t, overdue, now.AddDate(0, 0, -10))
+	inGrace := env.pppoeClient(t, "CL-002", nil)
+	graceInvoice := env.invoiceDue(t, inGrace, now.AddDate(0, 0, -2))
+	hotspot := fixtures.CreateTestClient(env.tenant.ID, "Client CL-003", "0812CL-003")
+	hotspot.ClientCode = "CL-003"
+	hotspot.ConnectionType = client.ConnectionTypeHotspot
+	require.NoError(t, env.clientRepo.Create(env.tc.Ctx, hotspot))
+	env.invoiceDue(t, hotspot, now.AddDate(0, 0, -10))
+
+	stats, err := scheduler.RunForTenant(env.tc.Ctx, tn, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), stats["invoices_marked_overdue"])
+	assert.Equal(t, int64(1), stats["clients_isolated"])
+	assert.Equal(t, client.StatusIsolir, env.status(t, overdue))
+	assert.Len(t, env.logs(t, overdue), 1) // one isolation for the oldest invoice
+	assert.Equal(t, client.StatusActive, env.status(t, inGrace))
+	assert.Equal(t, client.StatusActive, env.status(t, hotspot))
+
+	inv, err := env.invoiceRepo.GetByID(env.tc.Ctx, graceInvoice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, billing.InvoiceStatusOverdue, inv.Status)
+
+	// A second run finds nothing left to do
+	stats, err = scheduler.RunForTenant(env.tc.Ctx, tn, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), stats["invoices_marked_overdue"])
+	assert.Equal(t, int64(0), stats["clients_isolated"])
+}
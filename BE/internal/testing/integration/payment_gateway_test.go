@@ -0,0 +1,81 @@
+package integration
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rrnet/internal/domain/billing"
+	"rrnet/internal/repository"
+	"rrnet/internal/service"
+	"rrnet/internal/testing/fixtures"
+)
+
+func TestGatewayPaymentRecordedOnce(t *testing.T) {
+	env := setupBalanceEnv(t)
+	userRepo := repository.NewUserRepository(env.tc.DB)
+	requestRepo := repository.NewPaymentRequestRepository(env.tc.DB)
+
+	role, err := userRepo.GetRoleByCode(env.tc.Ctx, "owner")
+	require.NoError(t, err)
+	owner, err := fixtures.CreateTestUser(&env.tenant.ID, "owner@example.com", "Owner", "secret123", role.ID)
+	require.NoError(t, err)
+	require.NoError(t, userRepo.Create(env.tc.Ctx, owner))
+
+	inv := env.invoice(t, 100000)
+	request := func(amount int64) *billing.PaymentRequest {
+		pr := &billing.PaymentRequest{
+			ID:        uuid.New(),
+			TenantID:  env.tenant.ID,
+			InvoiceID: inv.ID,
+			ClientID:  env.client.ID,
+			Provider:  "mock",
+			Method:    billing.PaymentMethodQRIS,
+			OrderID:   fmt.Sprintf("ORD-%s", uuid.NewString()[:8]),
+			Amount:    amount,
+			Status:    billing.PaymentRequestPending,
+			CreatedBy: owner.ID,
+			CreatedAt: time.Now(),
+		}
+		require.NoError(t, requestRepo.Create(env.tc.Ctx, pr))
+		return pr
+	}
+
+	// Nothing recorded yet
+	first := request(40000)
+	recorded, _, err := env.billingService.GatewayPayment(env.tc.Ctx, first.ID)
+	require.NoError(t, err)
+	assert.False(t, recorded)
+
+	pay := service.RecordPaymentRequest{InvoiceID: inv.ID, Amount: 40000, Method: billing.PaymentMethodQRIS, PaymentRequestID: &first.ID}
+	p, err := env.billingService.RecordPayment(env.tc.Ctx, env.tenant.ID, owner.ID, pay)
+	require.NoError(t, err)
+	recorded, paymentID, err := env.billingService.GatewayPayment(env.tc.Ctx, first.ID)
+	require.NoError(t, err)
+	assert.True(t, recorded)
+	assert.Equal(t, &p.ID, paymentID)
+
+	// A retry of the same request cannot store the money a second time
+	_, err = env.billingService.RecordPayment(env.tc.Ctx, env.tenant.ID, owner.ID, pay)
+	assert.ErrorIs(t, err, repository.ErrGatewayPaymentRecorded)
+	assert.Equal(t, int64(40000), env.reload(t, inv).PaidAmount)
+
+	// A request settled as a client payment is found through its allocation
+	second := request(30000)
+	clientPay := service.RecordClientPaymentRequest{Amount: 30000, Method: billing.PaymentMethodQRIS, PaymentRequestID: &second.ID}
+	cp, err := env.billingService.RecordClientPayment(env.tc.Ctx, env.tenant.ID, owner.ID, env.client.ID, clientPay)
+	require.NoError(t, err)
+	require.Len(t, cp.Allocations, 1)
+	recorded, paymentID, err = env.billingService.GatewayPayment(env.tc.Ctx, second.ID)
+	require.NoError(t, err)
+	assert.True(t, recorded)
+	assert.Equal(t, &cp.Allocations[0].ID, paymentID)
+
+	_, err = env.billingService.RecordClientPayment(env.tc.Ctx, env.tenant.ID, owner.ID, env.client.ID, clientPay)
+	assert.ErrorIs(t, err, repository.ErrGatewayPaymentRecorded)
+	assert.Equal(t, int64(70000), env.reload(t, inv).PaidAmount)
+}
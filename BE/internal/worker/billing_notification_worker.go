@@ -0,0 +1,54 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/service"
+)
+
+// BillingNotificationWorker delivers the billing notifications queued by NotificationService.
+// Sends share the tenant limiter with campaigns, so one tenant's gateway is never hit in parallel.
+type BillingNotificationWorker struct {
+	notificationService *service.NotificationService
+	limiter             *TenantLimiter
+}
+
+func NewBillingNotificationWorker(notificationService *service.NotificationService, limiter *TenantLimiter) *BillingNotificationWorker {
+	if limiter == nil {
+		limiter = NewTenantLimiter(1)
+	}
+	return &BillingNotificationWorker{notificationService: notificationService, limiter: limiter}
+}
+
+func (w *BillingNotificationWorker) Register(mux *asynq.ServeMux) {
+	mux.HandleFunc(service.TaskBillingNotificationSend, w.handleSend)
+}
+
+func (w *BillingNotificationWorker) handleSend(ctx context.Context, t *asynq.Task) error {
+	var p service.BillingNotificationSendPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return err
+	}
+	tenantID, err := uuid.Parse(p.TenantID)
+	if err != nil {
+		return err
+	}
+	id, err := uuid.Parse(p.NotificationID)
+	if err != nil {
+		return err
+	}
+
+	release := w.limiter.acquire(tenantID.String())
+	defer release()
+
+	if err := w.notificationService.Deliver(ctx, tenantID, id); err != nil {
+		log.Error().Err(err).Str("tenant_id", p.TenantID).Str("notification_id", p.NotificationID).Msg("Billing notification delivery failed")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/service"
+)
+
+// BulkInvoiceWorker executes bulk invoice runs queued by BulkInvoiceService.Start
+type BulkInvoiceWorker struct {
+	bulkService *service.BulkInvoiceService
+}
+
+func NewBulkInvoiceWorker(bulkService *service.BulkInvoiceService) *BulkInvoiceWorker {
+	return &BulkInvoiceWorker{bulkService: bulkService}
+}
+
+func (w *BulkInvoiceWorker) Register(mux *asynq.ServeMux) {
+	mux.HandleFunc(service.TaskBulkInvoiceRun, w.handleRun)
+}
+
+func (w *BulkInvoiceWorker) handleRun(ctx context.Context, t *asynq.Task) error {
+	var p service.BulkInvoiceRunPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return err
+	}
+	tenantID, err := uuid.Parse(p.TenantID)
+	if err != nil {
+		return err
+	}
+	runID, err := uuid.Parse(p.RunID)
+	if err != nil {
+		return err
+	}
+
+	if err := w.bulkService.Execute(ctx, tenantID, runID); err != nil {
+		log.Error().Err(err).Str("tenant_id", p.TenantID).Str("run_id", p.RunID).Msg("Bulk invoice run failed")
+		return err
+	}
+	log.Info().Str("tenant_id", p.TenantID).Str("run_id", p.RunID).Msg("Bulk invoice run completed")
+	return nil
+}
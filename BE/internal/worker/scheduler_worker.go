@@ -0,0 +1,335 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+
+	"rrnet/internal/domain/job"
+	"rrnet/internal/domain/tenant"
+	asynqInfra "rrnet/internal/infra/asynq"
+	redisInfra "rrnet/internal/infra/redis"
+	"rrnet/internal/repository"
+	"rrnet/internal/service"
+)
+
+const (
+	// Only the replica holding the leader lock runs the Asynq scheduler
+	schedulerLeaderKey   = "rrnet:scheduler:leader"
+	schedulerLeaderTTL   = 30 * time.Second
+	schedulerLeaderRetry = 10 * time.Second
+
+	// A job runs at most once at a time across replicas, and so does its sub-task of a tenant: the
+	// run lock is released once the sub-tasks are enqueued, the tenant lock when a sub-task ends
+	jobLockPrefix = "rrnet:scheduler:job:"
+	jobLockTTL    = 30 * time.Minute
+
+	// Scheduled runs enqueued twice (e.g. around a leader change) are dropped within this window
+	scheduledRunUniqueTTL = time.Hour
+
+	tenantTaskMaxRetry = 5
+	tenantTaskTimeout  = 20 * time.Minute
+)
+
+// runIDNamespace derives the run ID of a scheduled run from its task ID, so a retried task
+// updates the same run
+var runIDNamespace = uuid.MustParse("6f1c2a8e-4b7d-4e0a-9c35-2d8f1b7e5a10")
+
+// SchedulerWorker runs the scheduled jobs (service.ScheduledJobs) as Asynq tasks: a run task that
+// per-tenant jobs fan out into one retried sub-task per active tenant. Every run is recorded in
+// job_runs.
+type SchedulerWorker struct {
+	runRepo      *repository.JobRunRepository
+	tenantRepo   *repository.TenantRepository
+	asynqClient  *asynq.Client
+	redis        *goredis.Client
+	newScheduler func() *asynq.Scheduler
+	runners      map[string]service.JobRunner
+}
+
+func NewSchedulerWorker(
+	runRepo *repository.JobRunRepository,
+	tenantRepo *repository.TenantRepository,
+	asynqClient *asynq.Client,
+	redis *goredis.Client,
+	newScheduler func() *asynq.Scheduler,
+	runners map[string]service.JobRunner,
+) *SchedulerWorker {
+	return &SchedulerWorker{
+		runRepo:      runRepo,
+		tenantRepo:   tenantRepo,
+		asynqClient:  asynqClient,
+		redis:        redis,
+		newScheduler: newScheduler,
+		runners:      runners,
+	}
+}
+
+func (w *SchedulerWorker) Register(mux *asynq.ServeMux) {
+	mux.HandleFunc(service.TaskScheduledJobRun, w.handleRun)
+	mux.HandleFunc(service.TaskScheduledJobTenant, w.handleTenant)
+}
+
+// StartLeaderScheduler competes for the scheduler leader lock until ctx is done. The replica that
+// holds it enqueues the periodic run tasks; the others take over when its lease expires.
+func (w *SchedulerWorker) StartLeaderScheduler(ctx context.Context) {
+	go func() {
+		for {
+			lock, err := redisInfra.AcquireLock(ctx, w.redis, schedulerLeaderKey, schedulerLeaderTTL)
+			if err == nil {
+				w.lead(ctx, lock)
+			} else if !errors.Is(err, redisInfra.ErrLockNotAcquired) {
+				log.Warn().Err(err).Msg("Failed to acquire scheduler leader lock")
+			}
+
+			timer := time.NewTimer(schedulerLeaderRetry)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				log.Info().Msg("Job scheduler stopped")
+				return
+			case <-timer.C:
+			}
+		}
+	}()
+	log.Info().Int("jobs", len(service.ScheduledJobs)).Msg("Job scheduler started (leader elected via Redis)")
+}
+
+// lead runs the Asynq scheduler while the leader lock can be refreshed
+func (w *SchedulerWorker) lead(ctx context.Context, lock *redisInfra.Lock) {
+	defer lock.Release(context.Background())
+
+	scheduler := w.newScheduler()
+	for _, d := range service.ScheduledJobs {
+		task, err := service.NewScheduledJobRunTask(service.ScheduledJobRunPayload{Job: d.Name, Trigger: job.TriggerSchedule})
+		if err != nil {
+			log.Error().Err(err).Str("job", d.Name).Msg("Failed to build scheduled job task")
+			continue
+		}
+		opts := append(service.ScheduledJobRunOptions(), asynq.Unique(scheduledRunUniqueTTL))
+		if _, err := scheduler.Register(d.Cronspec, task, opts...); err != nil {
+			log.Error().Err(err).Str("job", d.Name).Str("cronspec", d.Cronspec).Msg("Failed to register scheduled job")
+		}
+	}
+	if err := scheduler.Start(); err != nil {
+		log.Error().Err(err).Msg("Failed to start job scheduler")
+		return
+	}
+	defer scheduler.Shutdown()
+	log.Info().Msg("Acquired scheduler leadership")
+
+	ticker := time.NewTicker(schedulerLeaderTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := lock.Refresh(ctx); err != nil {
+				log.Warn().Err(err).Msg("Lost scheduler leadership")
+				return
+			}
+		}
+	}
+}
+
+func (w *SchedulerWorker) handleRun(ctx context.Context, t *asynq.Task) error {
+	var p service.ScheduledJobRunPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return err
+	}
+	def, ok := service.ScheduledJob(p.Job)
+	runner, hasRunner := w.runners[p.Job]
+	if !ok || !hasRunner {
+		return fmt.Errorf("%w: %s: %v", asynq.SkipRetry, p.Job, service.ErrJobNotFound)
+	}
+
+	run := &job.Run{
+		ID:        runIDFromTask(ctx, p.RunID),
+		Job:       p.Job,
+		Trigger:   p.Trigger,
+		Status:    job.StatusRunning,
+		Attempt:   attempt(ctx),
+		StartedAt: time.Now(),
+	}
+	if id, err := uuid.Parse(p.TriggeredBy); err == nil {
+		run.TriggeredBy = &id
+	}
+	if err := w.runRepo.Start(ctx, run); err != nil {
+		return fmt.Errorf("failed to record job run: %w", err)
+	}
+
+	lock, err := redisInfra.AcquireLock(ctx, w.redis, jobLockPrefix+p.Job, jobLockTTL)
+	if errors.Is(err, redisInfra.ErrLockNotAcquired) {
+		log.Info().Str("job", p.Job).Msg("Job is already running, skipping")
+		msg := "another run of this job is in progress"
+		run.Status, run.Error = job.StatusSkipped, &msg
+		return w.finish(ctx, run)
+	}
+	if err != nil {
+		return w.fail(ctx, run, fmt.Errorf("failed to acquire job lock: %w", err))
+	}
+	defer lock.Release(context.Background())
+
+	log.Info().Str("job", p.Job).Str("run_id", run.ID.String()).Str("trigger", string(p.Trigger)).Msg("Starting job run")
+	if def.PerTenant {
+		run.Stats, err = w.fanOut(ctx, run)
+	} else {
+		run.Stats, err = runner.Run(ctx, run.StartedAt)
+	}
+	if err != nil {
+		return w.fail(ctx, run, err)
+	}
+	run.Status = job.StatusSucceeded
+	return w.finish(ctx, run)
+}
+
+// fanOut enqueues one sub-task per active tenant
+func (w *SchedulerWorker) fanOut(ctx context.Context, run *job.Run) (job.Stats, error) {
+	tenants, err := w.tenantRepo.ListAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+	return w.enqueueTenants(ctx, run, tenants)
+}
+
+// enqueueTenants enqueues the sub-tasks of run for the active tenants. Sub-task IDs are derived from
+// the run, so a retried run task does not enqueue a tenant twice.
+func (w *SchedulerWorker) enqueueTenants(ctx context.Context, run *job.Run, tenants []*tenant.Tenant) (job.Stats, error) {
+	stats := job.Stats{"tenants": 0}
+	for _, t := range tenants {
+		if t.Status != tenant.StatusActive {
+			continue
+		}
+		task, err := service.NewScheduledJobTenantTask(service.ScheduledJobTenantPayload{
+			Job:      run.Job,
+			RunID:    run.ID.String(),
+			TenantID: t.ID.String(),
+			Trigger:  run.Trigger,
+			Now:      run.StartedAt,
+		})
+		if err != nil {
+			return stats, err
+		}
+		_, err = w.asynqClient.EnqueueContext(ctx, task,
+			asynq.Queue(asynqInfra.QueueBilling),
+			asynq.TaskID(tenantTaskID(run.ID, t.ID)),
+			asynq.MaxRetry(tenantTaskMaxRetry),
+			asynq.Timeout(tenantTaskTimeout),
+		)
+		if err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+			return stats, fmt.Errorf("failed to enqueue tenant %s: %w", t.ID, err)
+		}
+		stats["tenants"]++
+	}
+	return stats, nil
+}
+
+func (w *SchedulerWorker) handleTenant(ctx context.Context, t *asynq.Task) error {
+	var p service.ScheduledJobTenantPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return err
+	}
+	runner, ok := w.runners[p.Job]
+	if !ok || runner.RunTenant == nil {
+		return fmt.Errorf("%w: %s: %v", asynq.SkipRetry, p.Job, service.ErrJobNotFound)
+	}
+	parentID, err := uuid.Parse(p.RunID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", asynq.SkipRetry, err)
+	}
+	tenantID, err := uuid.Parse(p.TenantID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", asynq.SkipRetry, err)
+	}
+
+	run := &job.Run{
+		ID:        uuid.New(),
+		Job:       p.Job,
+		ParentID:  &parentID,
+		TenantID:  &tenantID,
+		Trigger:   p.Trigger,
+		Status:    job.StatusRunning,
+		Attempt:   attempt(ctx),
+		StartedAt: time.Now(),
+	}
+	if err := w.runRepo.Start(ctx, run); err != nil {
+		return fmt.Errorf("failed to record job run: %w", err)
+	}
+
+	lock, err := redisInfra.AcquireLock(ctx, w.redis, tenantLockKey(p.Job, tenantID), jobLockTTL)
+	if errors.Is(err, redisInfra.ErrLockNotAcquired) {
+		log.Info().Str("job", p.Job).Str("tenant_id", p.TenantID).Msg("Job is already running for tenant, skipping")
+		msg := "another run of this job is in progress for the tenant"
+		run.Status, run.Error = job.StatusSkipped, &msg
+		return w.finish(ctx, run)
+	}
+	if err != nil {
+		return w.fail(ctx, run, fmt.Errorf("failed to acquire job lock: %w", err))
+	}
+	defer lock.Release(context.Background())
+
+	tn, err := w.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return w.fail(ctx, run, fmt.Errorf("failed to load tenant: %w", err))
+	}
+	run.Stats, err = runner.RunTenant(ctx, tn, p.Now)
+	if err != nil {
+		return w.fail(ctx, run, err)
+	}
+	run.Status = job.StatusSucceeded
+	return w.finish(ctx, run)
+}
+
+// fail records err on the run and returns it so Asynq retries the task
+func (w *SchedulerWorker) fail(ctx context.Context, run *job.Run, err error) error {
+	log.Error().Err(err).Str("job", run.Job).Str("run_id", run.ID.String()).Int("attempt", run.Attempt).Msg("Job run failed")
+	msg := err.Error()
+	run.Status, run.Error = job.StatusFailed, &msg
+	if ferr := w.runRepo.Finish(ctx, run); ferr != nil {
+		log.Warn().Err(ferr).Str("run_id", run.ID.String()).Msg("Failed to record job run result")
+	}
+	return err
+}
+
+func (w *SchedulerWorker) finish(ctx context.Context, run *job.Run) error {
+	if err := w.runRepo.Finish(ctx, run); err != nil {
+		log.Warn().Err(err).Str("run_id", run.ID.String()).Msg("Failed to record job run result")
+	}
+	log.Info().Str("job", run.Job).Str("run_id", run.ID.String()).Str("status", string(run.Status)).Interface("stats", run.Stats).Msg("Job run finished")
+	return nil
+}
+
+// tenantTaskID is the Asynq task ID of the sub-task of a run for a tenant
+func tenantTaskID(runID, tenantID uuid.UUID) string {
+	return runID.String() + ":" + tenantID.String()
+}
+
+// tenantLockKey is the lock held while a sub-task of job runs for a tenant
+func tenantLockKey(jobName string, tenantID uuid.UUID) string {
+	return jobLockPrefix + jobName + ":" + tenantID.String()
+}
+
+// runIDFromTask returns the run ID of a manual run, or derives a stable one from the task ID
+func runIDFromTask(ctx context.Context, runID string) uuid.UUID {
+	if id, err := uuid.Parse(runID); err == nil {
+		return id
+	}
+	if taskID, ok := asynq.GetTaskID(ctx); ok {
+		return uuid.NewSHA1(runIDNamespace, []byte(taskID))
+	}
+	return uuid.New()
+}
+
+// attempt returns the 1-based attempt of the task being processed
+func attempt(ctx context.Context) int {
+	n, _ := asynq.GetRetryCount(ctx)
+	return n + 1
+}
@@ -0,0 +1,114 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rrnet/internal/domain/job"
+	"rrnet/internal/domain/tenant"
+	asynqInfra "rrnet/internal/infra/asynq"
+	"rrnet/internal/service"
+)
+
+func TestRunIDFromTask(t *testing.T) {
+	// Manual runs carry their run ID
+	runID := uuid.New()
+	assert.Equal(t, runID, runIDFromTask(context.Background(), runID.String()))
+
+	// Scheduled runs derive it from the task ID, which asynq keeps across retries
+	mr := miniredis.RunT(t)
+	redisOpt := asynq.RedisClientOpt{Addr: mr.Addr()}
+	srv := asynq.NewServer(redisOpt, asynq.Config{
+		Concurrency:              1,
+		DelayedTaskCheckInterval: 100 * time.Millisecond,
+		RetryDelayFunc:           func(int, error, *asynq.Task) time.Duration { return 0 },
+		Logger:                   nopLogger{},
+	})
+	ids := make(chan uuid.UUID, 3)
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("test:run", func(ctx context.Context, _ *asynq.Task) error {
+		ids <- runIDFromTask(ctx, "")
+		if attempt(ctx) < 2 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	require.NoError(t, srv.Start(mux))
+	defer srv.Shutdown()
+
+	client := asynq.NewClient(redisOpt)
+	defer client.Close()
+	_, err := client.Enqueue(asynq.NewTask("test:run", nil), asynq.MaxRetry(1))
+	require.NoError(t, err)
+
+	var got []uuid.UUID
+	for len(got) < 2 {
+		select {
+		case id := <-ids:
+			got = append(got, id)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("task attempts not processed, got %d", len(got))
+		}
+	}
+	assert.NotEqual(t, uuid.Nil, got[0])
+	assert.Equal(t, got[0], got[1])
+}
+
+func TestEnqueueTenantsDedup(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redisOpt := asynq.RedisClientOpt{Addr: mr.Addr()}
+	client := asynq.NewClient(redisOpt)
+	defer client.Close()
+	w := &SchedulerWorker{asynqClient: client}
+
+	run := &job.Run{ID: uuid.New(), Job: service.JobInvoiceGeneration, Trigger: job.TriggerSchedule, StartedAt: time.Now()}
+	tenants := []*tenant.Tenant{
+		{ID: uuid.New(), Status: tenant.StatusActive},
+		{ID: uuid.New(), Status: tenant.StatusActive},
+		{ID: uuid.New(), Status: tenant.StatusSuspended},
+	}
+
+	stats, err := w.enqueueTenants(context.Background(), run, tenants)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats["tenants"])
+
+	// A retried run task enqueues the same sub-tasks, which asynq drops
+	stats, err = w.enqueueTenants(context.Background(), run, tenants)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats["tenants"])
+
+	inspector := asynq.NewInspector(redisOpt)
+	defer inspector.Close()
+	pending, err := inspector.ListPendingTasks(asynqInfra.QueueBilling)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.ElementsMatch(t,
+		[]string{tenantTaskID(run.ID, tenants[0].ID), tenantTaskID(run.ID, tenants[1].ID)},
+		[]string{pending[0].ID, pending[1].ID},
+	)
+
+	// Another run enqueues its own sub-tasks
+	other := *run
+	other.ID = uuid.New()
+	_, err = w.enqueueTenants(context.Background(), &other, tenants)
+	require.NoError(t, err)
+	pending, err = inspector.ListPendingTasks(asynqInfra.QueueBilling)
+	require.NoError(t, err)
+	assert.Len(t, pending, 4)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(...interface{}) {}
+func (nopLogger) Info(...interface{})  {}
+func (nopLogger) Warn(...interface{})  {}
+func (nopLogger) Error(...interface{}) {}
+func (nopLogger) Fatal(...interface{}) {}
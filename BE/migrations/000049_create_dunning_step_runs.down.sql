@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS dunning_step_runs;
+
+DELETE FROM isolir_logs WHERE action IN ('throttle', 'unthrottle');
+ALTER TABLE isolir_logs DROP CONSTRAINT IF EXISTS valid_isolir_action;
+ALTER TABLE isolir_logs ADD CONSTRAINT valid_isolir_action CHECK (action IN ('isolate', 'reactivate'));
@@ -0,0 +1,34 @@
+-- Dunning timeline: throttle actions are logged alongside isolir actions
+ALTER TABLE isolir_logs DROP CONSTRAINT IF EXISTS valid_isolir_action;
+ALTER TABLE isolir_logs ADD CONSTRAINT valid_isolir_action
+    CHECK (action IN ('isolate', 'reactivate', 'throttle', 'unthrottle'));
+
+-- One row per invoice/step so a dunning step never runs twice
+CREATE TABLE IF NOT EXISTS dunning_step_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+    invoice_id UUID NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    offset_days INTEGER NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    error_msg TEXT,
+    run_date DATE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT valid_dunning_action CHECK (action IN ('reminder', 'throttle', 'isolir', 'terminate')),
+    CONSTRAINT valid_dunning_status CHECK (status IN ('executed', 'failed', 'skipped')),
+    CONSTRAINT unique_dunning_step_per_invoice UNIQUE (invoice_id, offset_days, action)
+);
+
+CREATE INDEX idx_dunning_step_runs_tenant_id ON dunning_step_runs(tenant_id);
+CREATE INDEX idx_dunning_step_runs_client_id ON dunning_step_runs(client_id);
+CREATE INDEX idx_dunning_step_runs_run_date ON dunning_step_runs(run_date);
+
+CREATE TRIGGER update_dunning_step_runs_updated_at
+    BEFORE UPDATE ON dunning_step_runs
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE dunning_step_runs IS 'Executed dunning steps per invoice (reminder, throttle, isolir, terminate)';
+COMMENT ON COLUMN dunning_step_runs.offset_days IS 'Day offset relative to invoice due date (negative = before due)';
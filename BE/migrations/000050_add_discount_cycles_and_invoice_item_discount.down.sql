@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_invoice_items_discount_id;
+ALTER TABLE invoice_items DROP COLUMN IF EXISTS discount_id;
+ALTER TABLE discounts DROP COLUMN IF EXISTS max_cycles;
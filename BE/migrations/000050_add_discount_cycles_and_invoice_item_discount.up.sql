@@ -0,0 +1,9 @@
+-- Discounts limited to the first N invoices of a client (e.g. 50% for the first 3 months)
+ALTER TABLE discounts ADD COLUMN IF NOT EXISTS max_cycles INTEGER CHECK (max_cycles IS NULL OR max_cycles > 0);
+
+COMMENT ON COLUMN discounts.max_cycles IS 'Number of invoices per client the discount applies to (NULL = every invoice)';
+
+-- Discount lines on invoices (negative amount) reference the applied discount
+ALTER TABLE invoice_items ADD COLUMN IF NOT EXISTS discount_id UUID REFERENCES discounts(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_invoice_items_discount_id ON invoice_items(discount_id) WHERE discount_id IS NOT NULL;
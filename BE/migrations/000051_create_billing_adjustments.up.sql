@@ -0,0 +1,22 @@
+-- Prorated charges/credits (package change, termination) waiting to be billed on a client's next invoice
+CREATE TABLE IF NOT EXISTS billing_adjustments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    amount BIGINT NOT NULL,
+    effective_date DATE NOT NULL,
+    source_invoice_id UUID REFERENCES invoices(id) ON DELETE SET NULL,
+    invoice_id UUID REFERENCES invoices(id) ON DELETE SET NULL,
+    applied_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT valid_billing_adjustment_kind CHECK (kind IN ('package_change', 'termination'))
+);
+
+CREATE INDEX idx_billing_adjustments_tenant_id ON billing_adjustments(tenant_id);
+CREATE INDEX idx_billing_adjustments_pending ON billing_adjustments(client_id) WHERE invoice_id IS NULL;
+
+COMMENT ON TABLE billing_adjustments IS 'Prorated invoice lines (negative = credit) created by package changes and terminations';
+COMMENT ON COLUMN billing_adjustments.source_invoice_id IS 'Invoice of the period the adjustment relates to';
+COMMENT ON COLUMN billing_adjustments.invoice_id IS 'Invoice the adjustment was billed on (NULL = pending)';
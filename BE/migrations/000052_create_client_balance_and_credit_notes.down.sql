@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS client_balance_entries;
+DROP TABLE IF EXISTS credit_notes;
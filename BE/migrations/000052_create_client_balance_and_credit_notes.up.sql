@@ -0,0 +1,42 @@
+-- Credit notes reduce an issued invoice (with a reason and the approving user)
+CREATE TABLE IF NOT EXISTS credit_notes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+    invoice_id UUID NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    credit_note_number VARCHAR(50) NOT NULL,
+    amount BIGINT NOT NULL,
+    reason TEXT NOT NULL,
+    approved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT positive_credit_note_amount CHECK (amount > 0),
+    CONSTRAINT unique_credit_note_number UNIQUE (tenant_id, credit_note_number)
+);
+
+CREATE INDEX idx_credit_notes_invoice_id ON credit_notes(invoice_id);
+CREATE INDEX idx_credit_notes_client_id ON credit_notes(client_id);
+
+-- Per-client balance ledger: positive = credit owed to the client, negative = credit used or refunded
+CREATE TABLE IF NOT EXISTS client_balance_entries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+    entry_type VARCHAR(20) NOT NULL,
+    amount BIGINT NOT NULL,
+    balance_after BIGINT NOT NULL,
+    invoice_id UUID REFERENCES invoices(id) ON DELETE SET NULL,
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    credit_note_id UUID REFERENCES credit_notes(id) ON DELETE SET NULL,
+    description TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT valid_balance_entry_type CHECK (entry_type IN ('overpayment', 'credit_note', 'credit_applied', 'refund', 'adjustment')),
+    CONSTRAINT nonzero_balance_entry_amount CHECK (amount <> 0),
+    CONSTRAINT non_negative_balance CHECK (balance_after >= 0)
+);
+
+CREATE INDEX idx_client_balance_entries_client ON client_balance_entries(client_id, created_at);
+CREATE INDEX idx_client_balance_entries_invoice_id ON client_balance_entries(invoice_id);
+
+COMMENT ON TABLE client_balance_entries IS 'Client credit ledger (overpayments, credit notes, credit applied to invoices, refunds)';
+COMMENT ON COLUMN client_balance_entries.balance_after IS 'Running client balance after this entry';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_payments_client_payment_id;
+ALTER TABLE payments DROP COLUMN IF EXISTS client_payment_id;
+DROP TABLE IF EXISTS client_payments;
@@ -0,0 +1,25 @@
+-- One received amount allocated across several invoices; each allocation is a row in payments
+CREATE TABLE IF NOT EXISTS client_payments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+    amount BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'IDR',
+    method VARCHAR(30) NOT NULL,
+    reference VARCHAR(255),
+    collector_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    notes TEXT,
+    credited_amount BIGINT NOT NULL DEFAULT 0,
+    received_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_by_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT positive_client_payment_amount CHECK (amount > 0)
+);
+
+CREATE INDEX idx_client_payments_tenant_id ON client_payments(tenant_id);
+CREATE INDEX idx_client_payments_client_id ON client_payments(client_id);
+
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS client_payment_id UUID REFERENCES client_payments(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_payments_client_payment_id ON payments(client_payment_id) WHERE client_payment_id IS NOT NULL;
+
+COMMENT ON COLUMN client_payments.credited_amount IS 'Part of the amount not allocated to invoices, added to the client balance';
@@ -0,0 +1,4 @@
+ALTER TABLE invoices
+    DROP COLUMN IF EXISTS tax_base,
+    DROP COLUMN IF EXISTS tax_inclusive,
+    DROP COLUMN IF EXISTS tax_rate;
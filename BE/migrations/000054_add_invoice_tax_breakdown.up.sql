@@ -0,0 +1,10 @@
+-- Tax breakdown per invoice (tenant tax profile lives in tenants.settings->'tax')
+ALTER TABLE invoices
+    ADD COLUMN IF NOT EXISTS tax_rate NUMERIC(5,2) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tax_inclusive BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS tax_base BIGINT NOT NULL DEFAULT 0;
+
+-- Existing invoices: tax was added on top of the subtotal
+UPDATE invoices
+SET tax_base = subtotal - discount_amount,
+    tax_rate = CASE WHEN tax_amount > 0 AND subtotal > 0 THEN ROUND(tax_amount * 100.0 / subtotal, 2) ELSE 0 END;
@@ -0,0 +1,25 @@
+-- Late fees charged on overdue invoices, either as a line of the overdue invoice itself or on a
+-- separate penalty invoice (charged_invoice_id). Waived fees are kept with who waived them and why.
+CREATE TABLE IF NOT EXISTS late_fees (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+    invoice_id UUID NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    charged_invoice_id UUID NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    sequence INT NOT NULL,
+    amount BIGINT NOT NULL,
+    assessed_at DATE NOT NULL,
+    waived_at TIMESTAMPTZ,
+    waived_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    waive_reason TEXT,
+    credit_note_id UUID REFERENCES credit_notes(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT positive_late_fee_amount CHECK (amount > 0),
+    CONSTRAINT unique_late_fee_sequence UNIQUE (invoice_id, sequence)
+);
+
+CREATE INDEX idx_late_fees_tenant_id ON late_fees(tenant_id);
+CREATE INDEX idx_late_fees_charged_invoice_id ON late_fees(charged_invoice_id);
+
+COMMENT ON COLUMN late_fees.amount IS 'Fee including tax of the charged invoice';
+COMMENT ON COLUMN late_fees.sequence IS '1 = first fee after the grace period, 2.. = repeated fees';
@@ -0,0 +1,47 @@
+-- Payments created at a payment gateway (VA, QRIS, e-wallet) for an invoice, settled by webhooks
+CREATE TABLE IF NOT EXISTS payment_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    invoice_id UUID NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+    provider VARCHAR(20) NOT NULL,
+    method VARCHAR(30) NOT NULL,
+    channel VARCHAR(30),
+    order_id VARCHAR(64) NOT NULL,
+    reference VARCHAR(100),
+    amount BIGINT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    va_number VARCHAR(50),
+    qr_string TEXT,
+    payment_url TEXT,
+    expires_at TIMESTAMPTZ,
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    paid_at TIMESTAMPTZ,
+    created_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT positive_payment_request_amount CHECK (amount > 0),
+    CONSTRAINT valid_payment_request_status CHECK (status IN ('pending', 'paid', 'expired', 'failed')),
+    CONSTRAINT unique_payment_request_order UNIQUE (provider, order_id)
+);
+
+CREATE INDEX idx_payment_requests_tenant_id ON payment_requests(tenant_id);
+CREATE INDEX idx_payment_requests_invoice_id ON payment_requests(invoice_id);
+
+-- Every webhook received from a gateway, verified or not, for auditing
+CREATE TABLE IF NOT EXISTS payment_webhook_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    provider VARCHAR(20) NOT NULL,
+    order_id VARCHAR(64),
+    reference VARCHAR(100),
+    status VARCHAR(20),
+    verified BOOLEAN NOT NULL DEFAULT FALSE,
+    error TEXT,
+    payload TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_payment_webhook_events_tenant ON payment_webhook_events(tenant_id, created_at);
+
+COMMENT ON COLUMN payment_requests.created_by IS 'User who requested the payment; webhook payments are recorded on their behalf';
@@ -0,0 +1,48 @@
+-- Imported bank mutation files
+CREATE TABLE IF NOT EXISTS bank_statements (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    bank VARCHAR(20) NOT NULL,
+    account_number VARCHAR(50),
+    file_name VARCHAR(255) NOT NULL,
+    period_start DATE,
+    period_end DATE,
+    credit_count INTEGER NOT NULL DEFAULT 0,
+    debit_count INTEGER NOT NULL DEFAULT 0,
+    duplicate_count INTEGER NOT NULL DEFAULT 0,
+    imported_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_bank_statements_tenant ON bank_statements(tenant_id, created_at);
+
+-- Credits of imported statements and their reconciliation with invoices
+CREATE TABLE IF NOT EXISTS bank_statement_lines (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    statement_id UUID NOT NULL REFERENCES bank_statements(id) ON DELETE CASCADE,
+    row_number INTEGER NOT NULL,
+    txn_date DATE NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    reference VARCHAR(100),
+    amount BIGINT NOT NULL,
+    balance BIGINT,
+    fingerprint VARCHAR(64) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'unmatched',
+    invoice_id UUID REFERENCES invoices(id) ON DELETE SET NULL,
+    client_id UUID REFERENCES clients(id) ON DELETE SET NULL,
+    score INTEGER NOT NULL DEFAULT 0,
+    candidates JSONB NOT NULL DEFAULT '[]',
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    confirmed_by UUID REFERENCES users(id),
+    confirmed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT positive_bank_line_amount CHECK (amount > 0),
+    CONSTRAINT valid_bank_line_status CHECK (status IN ('auto_matched', 'suggested', 'unmatched', 'confirmed', 'ignored')),
+    CONSTRAINT unique_bank_line_fingerprint UNIQUE (tenant_id, fingerprint)
+);
+
+CREATE INDEX idx_bank_statement_lines_statement ON bank_statement_lines(statement_id, status);
+
+COMMENT ON COLUMN bank_statement_lines.fingerprint IS 'Hash of date, amount, description, reference and balance; re-importing an overlapping period skips known mutations';
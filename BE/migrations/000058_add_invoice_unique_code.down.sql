@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_invoices_open_unique_code;
+ALTER TABLE invoices
+    DROP COLUMN IF EXISTS unique_code_in_total,
+    DROP COLUMN IF EXISTS unique_code;
@@ -0,0 +1,8 @@
+-- "Kode unik": 3-digit code identifying the bank transfer of an invoice (settings in tenants.settings->'unique_code')
+ALTER TABLE invoices
+    ADD COLUMN IF NOT EXISTS unique_code INTEGER,
+    ADD COLUMN IF NOT EXISTS unique_code_in_total BOOLEAN NOT NULL DEFAULT false;
+
+-- Codes are unique among the open invoices of a tenant
+CREATE INDEX IF NOT EXISTS idx_invoices_open_unique_code ON invoices(tenant_id, unique_code)
+    WHERE unique_code IS NOT NULL AND status IN ('draft', 'pending', 'overdue');
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_payments_receipt_number;
+ALTER TABLE payments DROP COLUMN IF EXISTS receipt_number;
+DROP TABLE IF EXISTS document_sequences;
@@ -0,0 +1,18 @@
+-- Gap-free numbering of invoices, credit notes and receipts (schemes in tenants.settings->'numbering')
+CREATE TABLE IF NOT EXISTS document_sequences (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,
+    scope VARCHAR(50) NOT NULL,
+    last_value BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, kind, scope),
+    CONSTRAINT valid_document_sequence_kind CHECK (kind IN ('invoice', 'credit_note', 'receipt'))
+);
+
+COMMENT ON COLUMN document_sequences.scope IS 'Reset period (all, YYYY or YYYY-MM) and branch code the sequence counts in';
+
+-- Receipts are numbered when the payment is recorded
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS receipt_number VARCHAR(50);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_receipt_number ON payments(tenant_id, receipt_number)
+    WHERE receipt_number IS NOT NULL;
@@ -0,0 +1,20 @@
+-- Recurring extras billed with a client's monthly invoice (static IP, extra device, ONT rental)
+CREATE TABLE IF NOT EXISTS client_recurring_charges (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+    description VARCHAR(255) NOT NULL,
+    amount BIGINT NOT NULL,
+    quantity INTEGER NOT NULL DEFAULT 1,
+    start_date DATE NOT NULL,
+    end_date DATE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT positive_recurring_charge_amount CHECK (amount > 0),
+    CONSTRAINT positive_recurring_charge_quantity CHECK (quantity > 0),
+    CONSTRAINT valid_recurring_charge_dates CHECK (end_date IS NULL OR end_date >= start_date)
+);
+
+CREATE INDEX idx_client_recurring_charges_client ON client_recurring_charges(tenant_id, client_id);
+
+COMMENT ON COLUMN client_recurring_charges.amount IS 'Monthly price per unit; periods the charge only partly covers are prorated';
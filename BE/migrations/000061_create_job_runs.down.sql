@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_invoices_monthly_period;
+ALTER TABLE invoices DROP COLUMN IF EXISTS source;
+DROP TABLE IF EXISTS job_runs;
@@ -0,0 +1,31 @@
+-- History of scheduled background jobs. A run of a per-tenant job has one child run per tenant
+-- (one row per attempt, so retries stay visible).
+CREATE TABLE IF NOT EXISTS job_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    job VARCHAR(50) NOT NULL,
+    parent_id UUID REFERENCES job_runs(id) ON DELETE CASCADE,
+    tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE,
+    trigger VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    attempt INTEGER NOT NULL DEFAULT 1,
+    stats JSONB NOT NULL DEFAULT '{}'::jsonb,
+    error TEXT,
+    triggered_by UUID,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ,
+    CONSTRAINT valid_job_run_trigger CHECK (trigger IN ('schedule', 'manual')),
+    CONSTRAINT valid_job_run_status CHECK (status IN ('running', 'succeeded', 'failed', 'skipped'))
+);
+
+CREATE INDEX idx_job_runs_job ON job_runs(job, started_at DESC);
+CREATE INDEX idx_job_runs_parent ON job_runs(parent_id) WHERE parent_id IS NOT NULL;
+
+-- Invoices generated by the monthly run can exist only once per client and period, so concurrent
+-- generation attempts (several replicas, retries) cannot create duplicates.
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS source VARCHAR(20) NOT NULL DEFAULT 'manual';
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invoices_monthly_period
+    ON invoices(tenant_id, client_id, period_start, period_end)
+    WHERE source = 'monthly' AND status <> 'cancelled';
+
+COMMENT ON COLUMN invoices.source IS 'manual or monthly (generated for the client''s billing period)';
@@ -0,0 +1,8 @@
+COMMENT ON COLUMN invoices.source IS 'manual or monthly (generated for the client''s billing period)';
+DROP INDEX IF EXISTS idx_invoices_prepaid_open;
+DROP INDEX IF EXISTS idx_clients_active_until;
+ALTER TABLE clients DROP CONSTRAINT IF EXISTS valid_client_billing_mode;
+ALTER TABLE clients DROP COLUMN IF EXISTS active_until;
+ALTER TABLE clients DROP COLUMN IF EXISTS billing_mode;
+ALTER TABLE service_packages DROP CONSTRAINT IF EXISTS valid_service_package_billing_mode;
+ALTER TABLE service_packages DROP COLUMN IF EXISTS billing_mode;
@@ -0,0 +1,23 @@
+-- Prepaid billing: the client pays before the service period and the service stays active until
+-- active_until. Packages set the default mode, a client may override it (NULL = follow the package).
+ALTER TABLE service_packages ADD COLUMN IF NOT EXISTS billing_mode VARCHAR(20) NOT NULL DEFAULT 'postpaid';
+ALTER TABLE service_packages ADD CONSTRAINT valid_service_package_billing_mode
+    CHECK (billing_mode IN ('postpaid', 'prepaid'));
+
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS billing_mode VARCHAR(20);
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS active_until DATE;
+ALTER TABLE clients ADD CONSTRAINT valid_client_billing_mode
+    CHECK (billing_mode IS NULL OR billing_mode IN ('postpaid', 'prepaid'));
+
+CREATE INDEX IF NOT EXISTS idx_clients_active_until
+    ON clients(tenant_id, active_until) WHERE active_until IS NOT NULL AND deleted_at IS NULL;
+
+-- A prepaid invoice buys the validity period between period_start and period_end. A client has at
+-- most one unpaid prepaid invoice, so renewals cannot be issued twice.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invoices_prepaid_open
+    ON invoices(tenant_id, client_id)
+    WHERE source = 'prepaid' AND status IN ('draft', 'pending', 'overdue');
+
+COMMENT ON COLUMN clients.billing_mode IS 'postpaid or prepaid; NULL follows the service package';
+COMMENT ON COLUMN clients.active_until IS 'Last day of the paid service period (prepaid clients)';
+COMMENT ON COLUMN invoices.source IS 'manual, monthly (generated for the client''s billing period) or prepaid (buys service validity)';
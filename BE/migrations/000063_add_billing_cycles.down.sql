@@ -0,0 +1,6 @@
+ALTER TABLE clients DROP CONSTRAINT IF EXISTS valid_client_billing_cycle;
+ALTER TABLE clients DROP COLUMN IF EXISTS billing_cycle_anchor;
+ALTER TABLE clients DROP COLUMN IF EXISTS billing_cycle_months;
+ALTER TABLE service_packages DROP CONSTRAINT IF EXISTS valid_service_package_cycle;
+ALTER TABLE service_packages DROP COLUMN IF EXISTS default_cycle_months;
+ALTER TABLE service_packages DROP COLUMN IF EXISTS billing_cycles;
@@ -0,0 +1,16 @@
+-- Multi-month billing cycles. A package offers cycle lengths with their (usually discounted) price
+-- and a default cycle; a client may override the cycle. Cycles run over calendar months aligned to
+-- the month of billing_cycle_anchor (or of the client's creation).
+ALTER TABLE service_packages ADD COLUMN IF NOT EXISTS billing_cycles JSONB NOT NULL DEFAULT '[]'::jsonb;
+ALTER TABLE service_packages ADD COLUMN IF NOT EXISTS default_cycle_months INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE service_packages ADD CONSTRAINT valid_service_package_cycle
+    CHECK (default_cycle_months IN (1, 3, 6, 12));
+
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS billing_cycle_months INTEGER;
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS billing_cycle_anchor DATE;
+ALTER TABLE clients ADD CONSTRAINT valid_client_billing_cycle
+    CHECK (billing_cycle_months IS NULL OR billing_cycle_months IN (1, 3, 6, 12));
+
+COMMENT ON COLUMN service_packages.billing_cycles IS 'Offered cycles: [{"months": 3, "price": 420000}, ...]';
+COMMENT ON COLUMN clients.billing_cycle_months IS 'Billing cycle in months; NULL follows the service package';
+COMMENT ON COLUMN clients.billing_cycle_anchor IS 'Cycles align to this month; NULL uses the month the client was created';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS bulk_invoice_run_items;
+DROP TABLE IF EXISTS bulk_invoice_runs;
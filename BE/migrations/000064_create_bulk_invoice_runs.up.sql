@@ -0,0 +1,47 @@
+-- Bulk invoice generation for a period, executed as a background job. Progress and outcome are
+-- counted from the run items; an undo cancels the invoices the run created.
+CREATE TABLE IF NOT EXISTS bulk_invoice_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    period_start DATE NOT NULL,
+    filter JSONB NOT NULL DEFAULT '{}'::jsonb,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued',
+    error TEXT,
+    created_by UUID NOT NULL REFERENCES users(id),
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ,
+    undone_by UUID REFERENCES users(id),
+    undone_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT valid_bulk_invoice_run_status CHECK (status IN ('queued', 'running', 'completed', 'failed', 'undone'))
+);
+
+CREATE INDEX idx_bulk_invoice_runs_tenant ON bulk_invoice_runs(tenant_id, created_at DESC);
+
+-- A tenant has at most one run in progress
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bulk_invoice_runs_active
+    ON bulk_invoice_runs(tenant_id)
+    WHERE status IN ('queued', 'running');
+
+CREATE TABLE IF NOT EXISTS bulk_invoice_run_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    run_id UUID NOT NULL REFERENCES bulk_invoice_runs(id) ON DELETE CASCADE,
+    client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+    client_code VARCHAR(50) NOT NULL,
+    client_name VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    period_start DATE,
+    period_end DATE,
+    due_date TIMESTAMPTZ,
+    amount BIGINT NOT NULL DEFAULT 0,
+    invoice_id UUID REFERENCES invoices(id) ON DELETE SET NULL,
+    reason TEXT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT valid_bulk_invoice_item_status CHECK (status IN ('pending', 'created', 'skipped', 'failed', 'cancelled')),
+    CONSTRAINT unique_bulk_invoice_run_client UNIQUE (run_id, client_id)
+);
+
+CREATE INDEX idx_bulk_invoice_run_items_run ON bulk_invoice_run_items(run_id, status);
+
+COMMENT ON COLUMN bulk_invoice_runs.filter IS 'Client selection: {"group_id", "service_package_id", "router_id"}';
+COMMENT ON COLUMN bulk_invoice_run_items.reason IS 'Skip reason (prepaid, not_cycle_start, invoice_exists), error, or why an undo kept the invoice';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS collector_visits;
+DROP TABLE IF EXISTS collector_assignments;
+DROP TABLE IF EXISTS collector_deposits;
@@ -0,0 +1,66 @@
+-- Collector module: invoices assigned to collectors, field visits, and deposit reports that finance
+-- confirms into payments (visit -> deposit -> confirm)
+CREATE TABLE IF NOT EXISTS collector_deposits (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    collector_id UUID NOT NULL REFERENCES users(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'reported',
+    total_amount BIGINT NOT NULL,
+    notes TEXT,
+    reported_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    reviewed_by UUID REFERENCES users(id),
+    reviewed_at TIMESTAMPTZ,
+    reject_reason TEXT,
+    CONSTRAINT valid_collector_deposit_status CHECK (status IN ('reported', 'confirmed', 'rejected'))
+);
+
+CREATE INDEX idx_collector_deposits_tenant ON collector_deposits(tenant_id, status, reported_at DESC);
+CREATE INDEX idx_collector_deposits_collector ON collector_deposits(collector_id, reported_at DESC);
+
+CREATE TABLE IF NOT EXISTS collector_assignments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    collector_id UUID NOT NULL REFERENCES users(id),
+    invoice_id UUID NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'assigned',
+    amount BIGINT NOT NULL DEFAULT 0,
+    notes TEXT,
+    assigned_by UUID NOT NULL REFERENCES users(id),
+    assigned_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    collected_at TIMESTAMPTZ,
+    deposit_id UUID REFERENCES collector_deposits(id) ON DELETE SET NULL,
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT valid_collector_assignment_status CHECK (status IN ('assigned', 'collected', 'deposited', 'confirmed', 'cancelled'))
+);
+
+CREATE INDEX idx_collector_assignments_collector ON collector_assignments(collector_id, status);
+CREATE INDEX idx_collector_assignments_tenant ON collector_assignments(tenant_id, status);
+CREATE INDEX idx_collector_assignments_deposit ON collector_assignments(deposit_id);
+
+-- An invoice is in the hands of at most one collector at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_collector_assignments_open_invoice
+    ON collector_assignments(invoice_id)
+    WHERE status IN ('assigned', 'collected', 'deposited');
+
+CREATE TABLE IF NOT EXISTS collector_visits (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    assignment_id UUID NOT NULL REFERENCES collector_assignments(id) ON DELETE CASCADE,
+    collector_id UUID NOT NULL REFERENCES users(id),
+    result VARCHAR(20) NOT NULL,
+    amount BIGINT NOT NULL DEFAULT 0,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    reason TEXT,
+    notes TEXT,
+    visited_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT valid_collector_visit_result CHECK (result IN ('success', 'failed'))
+);
+
+CREATE INDEX idx_collector_visits_assignment ON collector_visits(assignment_id, visited_at);
+CREATE INDEX idx_collector_visits_collector ON collector_visits(collector_id, visited_at DESC);
+
+COMMENT ON COLUMN collector_assignments.amount IS 'Remaining invoice amount taken at the successful visit; becomes the payment on confirmation';
+COMMENT ON COLUMN collector_visits.amount IS 'Amount collected (success only), taken from the invoice';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS commission_statement_lines;
+DROP TABLE IF EXISTS commission_statements;
+DROP TABLE IF EXISTS commission_rules;
@@ -0,0 +1,68 @@
+-- Commission rules for staff who collect payments. Rule versions are immutable: an update adds a
+-- version with the same rule_key, so statements stay reproducible.
+CREATE TABLE IF NOT EXISTS commission_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    rule_key UUID NOT NULL,
+    version INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    scope VARCHAR(10) NOT NULL,
+    role_code VARCHAR(50),
+    user_id UUID REFERENCES users(id),
+    type VARCHAR(20) NOT NULL,
+    percent NUMERIC(5,2) NOT NULL DEFAULT 0,
+    flat_amount BIGINT NOT NULL DEFAULT 0,
+    tiers JSONB NOT NULL DEFAULT '[]'::jsonb,
+    active BOOLEAN NOT NULL DEFAULT true,
+    effective_from DATE NOT NULL,
+    created_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT valid_commission_rule_scope CHECK (
+        (scope = 'role' AND role_code IS NOT NULL AND user_id IS NULL) OR
+        (scope = 'user' AND user_id IS NOT NULL AND role_code IS NULL)
+    ),
+    CONSTRAINT valid_commission_rule_type CHECK (type IN ('percent', 'flat_per_invoice', 'tiered')),
+    CONSTRAINT unique_commission_rule_version UNIQUE (rule_key, version)
+);
+
+CREATE INDEX idx_commission_rules_tenant ON commission_rules(tenant_id, rule_key, version DESC);
+
+-- Monthly commission per user, with the rule version it was computed with
+CREATE TABLE IF NOT EXISTS commission_statements (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id),
+    period_start DATE NOT NULL,
+    rule_id UUID NOT NULL REFERENCES commission_rules(id),
+    collected_amount BIGINT NOT NULL,
+    payment_count INT NOT NULL,
+    invoice_count INT NOT NULL,
+    commission_amount BIGINT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'draft',
+    approved_by UUID REFERENCES users(id),
+    approved_at TIMESTAMPTZ,
+    paid_by UUID REFERENCES users(id),
+    paid_at TIMESTAMPTZ,
+    payout_reference VARCHAR(100),
+    generated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT valid_commission_statement_status CHECK (status IN ('draft', 'approved', 'paid')),
+    CONSTRAINT unique_commission_statement_period UNIQUE (tenant_id, user_id, period_start)
+);
+
+CREATE INDEX idx_commission_statements_period ON commission_statements(tenant_id, period_start, status);
+
+CREATE TABLE IF NOT EXISTS commission_statement_lines (
+    statement_id UUID NOT NULL REFERENCES commission_statements(id) ON DELETE CASCADE,
+    payment_id UUID NOT NULL REFERENCES payments(id),
+    invoice_id UUID NOT NULL,
+    invoice_number VARCHAR(50) NOT NULL,
+    client_name VARCHAR(255) NOT NULL,
+    amount BIGINT NOT NULL,
+    method VARCHAR(30) NOT NULL,
+    received_at TIMESTAMPTZ NOT NULL,
+    confirmed_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (statement_id, payment_id)
+);
+
+COMMENT ON COLUMN commission_rules.tiers IS 'tiered: [{"min_amount", "percent"}]; the highest tier reached applies to the whole monthly volume';
+COMMENT ON COLUMN commission_statements.period_start IS 'Month of the payments, by the time they were recorded (confirmed)';
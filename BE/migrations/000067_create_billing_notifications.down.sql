@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS client_notification_opt_outs;
+DROP TABLE IF EXISTS billing_notifications;
@@ -0,0 +1,36 @@
+-- WhatsApp billing notifications: one row per message raised by a billing event, deduplicated per
+-- tenant, and the clients that opted out of them
+CREATE TABLE IF NOT EXISTS billing_notifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    event VARCHAR(30) NOT NULL,
+    client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+    invoice_id UUID REFERENCES invoices(id) ON DELETE CASCADE,
+    dedup_key VARCHAR(200) NOT NULL,
+    data JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'queued',
+    scheduled_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMPTZ,
+    wa_log_id UUID,
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT unique_billing_notification UNIQUE (tenant_id, dedup_key),
+    CONSTRAINT valid_billing_notification_event CHECK (event IN (
+        'invoice_created', 'invoice_due_soon', 'invoice_overdue',
+        'payment_received', 'client_isolated', 'client_reactivated'
+    )),
+    CONSTRAINT valid_billing_notification_status CHECK (status IN ('queued', 'sent', 'skipped', 'failed'))
+);
+
+CREATE INDEX idx_billing_notifications_tenant ON billing_notifications(tenant_id, created_at DESC);
+CREATE INDEX idx_billing_notifications_client ON billing_notifications(client_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS client_notification_opt_outs (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    client_id UUID PRIMARY KEY REFERENCES clients(id) ON DELETE CASCADE,
+    reason TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_client_notification_opt_outs_tenant ON client_notification_opt_outs(tenant_id);
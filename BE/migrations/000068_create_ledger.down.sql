@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS ledger_lines;
+DROP TABLE IF EXISTS ledger_entries;
+DROP TABLE IF EXISTS ledger_accounts;
@@ -0,0 +1,51 @@
+-- Double-entry books per tenant. System accounts (system_key set) are created on first use and
+-- receive the postings of billing and collector actions; finance may add accounts for manual entries.
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    code VARCHAR(20) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    system_key VARCHAR(30),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT valid_ledger_account_type CHECK (type IN ('asset', 'liability', 'equity', 'revenue', 'contra_revenue', 'expense')),
+    CONSTRAINT unique_ledger_account_code UNIQUE (tenant_id, code),
+    CONSTRAINT unique_ledger_account_system_key UNIQUE (tenant_id, system_key)
+);
+
+-- A journal entry is posted once per source document; entries are never changed, a correction
+-- is a new (manual) entry
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    entry_date DATE NOT NULL,
+    source VARCHAR(30) NOT NULL,
+    source_id UUID NOT NULL,
+    description TEXT NOT NULL,
+    client_id UUID,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT valid_ledger_entry_source CHECK (source IN (
+        'invoice', 'invoice_change', 'invoice_cancel', 'credit_note', 'payment', 'client_payment',
+        'client_balance', 'collection', 'manual'
+    )),
+    CONSTRAINT unique_ledger_entry_source UNIQUE (tenant_id, source, source_id)
+);
+
+CREATE INDEX idx_ledger_entries_date ON ledger_entries(tenant_id, entry_date, created_at);
+
+CREATE TABLE IF NOT EXISTS ledger_lines (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    entry_id UUID NOT NULL REFERENCES ledger_entries(id) ON DELETE CASCADE,
+    account_id UUID NOT NULL REFERENCES ledger_accounts(id),
+    debit BIGINT NOT NULL DEFAULT 0,
+    credit BIGINT NOT NULL DEFAULT 0,
+    memo TEXT,
+    CONSTRAINT valid_ledger_line_amount CHECK (debit >= 0 AND credit >= 0 AND (debit = 0) <> (credit = 0))
+);
+
+CREATE INDEX idx_ledger_lines_entry ON ledger_lines(entry_id);
+CREATE INDEX idx_ledger_lines_account ON ledger_lines(account_id);
+
+COMMENT ON COLUMN ledger_entries.source_id IS 'Document the entry was posted for (invoice, payment, credit note, balance entry, collector visit); a random ID for manual entries';
+COMMENT ON COLUMN ledger_entries.entry_date IS 'Accounting date: issue date of invoices, received date of payments, visit date of collections';
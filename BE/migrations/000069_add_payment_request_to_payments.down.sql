@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_client_payments_payment_request;
+DROP INDEX IF EXISTS idx_payments_payment_request;
+ALTER TABLE client_payments DROP COLUMN IF EXISTS payment_request_id;
+ALTER TABLE payments DROP COLUMN IF EXISTS payment_request_id;
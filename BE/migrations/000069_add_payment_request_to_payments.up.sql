@@ -0,0 +1,12 @@
+-- A payment received through the payment gateway keeps the request it settles. The unique indexes
+-- make a retried webhook unable to record the same gateway payment twice.
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS payment_request_id UUID REFERENCES payment_requests(id) ON DELETE SET NULL;
+ALTER TABLE client_payments ADD COLUMN IF NOT EXISTS payment_request_id UUID REFERENCES payment_requests(id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_payment_request
+    ON payments(payment_request_id) WHERE payment_request_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_client_payments_payment_request
+    ON client_payments(payment_request_id) WHERE payment_request_id IS NOT NULL;
+
+COMMENT ON COLUMN payments.payment_request_id IS 'Payment gateway request settled by this payment';
+COMMENT ON COLUMN client_payments.payment_request_id IS 'Payment gateway request settled by this payment';
@@ -0,0 +1,5 @@
+UPDATE dunning_step_runs SET status = 'failed', error_msg = 'outcome not recorded' WHERE status = 'running';
+ALTER TABLE dunning_step_runs DROP CONSTRAINT IF EXISTS valid_dunning_status;
+ALTER TABLE dunning_step_runs ADD CONSTRAINT valid_dunning_status
+    CHECK (status IN ('executed', 'failed', 'skipped'));
+COMMENT ON COLUMN dunning_step_runs.status IS NULL;
@@ -0,0 +1,7 @@
+-- A dunning step is reserved as 'running' before it is performed and updated with its outcome
+-- afterwards, so a step whose outcome could not be stored is never performed twice
+ALTER TABLE dunning_step_runs DROP CONSTRAINT IF EXISTS valid_dunning_status;
+ALTER TABLE dunning_step_runs ADD CONSTRAINT valid_dunning_status
+    CHECK (status IN ('running', 'executed', 'failed', 'skipped'));
+
+COMMENT ON COLUMN dunning_step_runs.status IS 'running (reserved, outcome not stored yet), executed, failed (retried) or skipped';
@@ -0,0 +1,86 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg" // register decoders
+	_ "image/png"
+)
+
+// MaxImageSize is the largest width and height AddImage accepts; larger images would be decoded
+// into a huge pixel buffer from a few KB of compressed data
+const MaxImageSize = 4096
+
+var (
+	ErrUnsupportedImage = errors.New("unsupported image (JPEG or PNG expected)")
+	ErrImageTooLarge    = fmt.Errorf("image exceeds %dx%d pixels", MaxImageSize, MaxImageSize)
+)
+
+// Image is an image embedded in a Document
+type Image struct {
+	name       string
+	obj        int
+	width      int
+	height     int
+	colorSpace string
+	filter     string
+	data       []byte
+}
+
+// Width returns the width in pixels
+func (i *Image) Width() int { return i.width }
+
+// Height returns the height in pixels
+func (i *Image) Height() int { return i.height }
+
+// AddImage embeds a JPEG or PNG image. Baseline RGB/gray JPEGs are embedded as-is; anything else is
+// decoded and stored as RGB (transparency is flattened onto white). Images larger than
+// MaxImageSize on either side are rejected before decoding.
+func (d *Document) AddImage(data []byte) (*Image, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if cfg.Width > MaxImageSize || cfg.Height > MaxImageSize {
+		return nil, ErrImageTooLarge
+	}
+	img := &Image{name: fmt.Sprintf("Im%d", len(d.images)+1), width: cfg.Width, height: cfg.Height}
+
+	switch {
+	case format == "jpeg" && cfg.ColorModel == color.YCbCrModel:
+		img.colorSpace, img.filter, img.data = "DeviceRGB", "DCTDecode", data
+	case format == "jpeg" && cfg.ColorModel == color.GrayModel:
+		img.colorSpace, img.filter, img.data = "DeviceGray", "DCTDecode", data
+	case format == "jpeg" || format == "png":
+		src, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrUnsupportedImage
+		}
+		if img.data, err = deflate(flattenRGB(src)); err != nil {
+			return nil, err
+		}
+		img.colorSpace, img.filter = "DeviceRGB", "FlateDecode"
+	default:
+		return nil, ErrUnsupportedImage
+	}
+
+	d.images = append(d.images, img)
+	return img, nil
+}
+
+// flattenRGB returns the 8-bit RGB samples of src composited onto a white background
+func flattenRGB(src image.Image) []byte {
+	b := src.Bounds()
+	out := make([]byte, 0, b.Dx()*b.Dy()*3)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, a := src.At(x, y).RGBA() // alpha-premultiplied, 16 bit
+			white := 0xffff - a
+			out = append(out, byte((r+white)>>8), byte((g+white)>>8), byte((bl+white)>>8))
+		}
+	}
+	return out
+}
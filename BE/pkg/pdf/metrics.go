@@ -0,0 +1,75 @@
+package pdf
+
+import "strings"
+
+// Glyph widths (1/1000 em) of the printable ASCII range 32..126, from the Adobe AFM files
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
+
+// TextWidth returns the width of s in points
+func TextWidth(s string, size float64, font Font) float64 {
+	widths := &helveticaWidths
+	if font == Bold {
+		widths = &helveticaBoldWidths
+	}
+	total := 0
+	for _, c := range []byte(encode(s)) {
+		if c >= 32 && c <= 126 {
+			total += widths[c-32]
+		} else {
+			total += 556 // accented Latin-1 letters are close to this
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// WrapText splits s into lines no wider than maxWidth, breaking on spaces (and on existing newlines).
+// A single word wider than maxWidth is put on its own line.
+func WrapText(s string, size float64, font Font, maxWidth float64) []string {
+	var lines []string
+	for _, para := range strings.Split(s, "\n") {
+		words := strings.Fields(para)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+		line := words[0]
+		for _, w := range words[1:] {
+			if TextWidth(line+" "+w, size, font) <= maxWidth {
+				line += " " + w
+				continue
+			}
+			lines = append(lines, line)
+			line = w
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Truncate shortens s with an ellipsis so that it fits maxWidth
+func Truncate(s string, size float64, font Font, maxWidth float64) string {
+	if TextWidth(s, size, font) <= maxWidth {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 && TextWidth(string(r)+"...", size, font) > maxWidth {
+		r = r[:len(r)-1]
+	}
+	return string(r) + "..."
+}
@@ -0,0 +1,258 @@
+// Package pdf is a small PDF 1.4 writer for printable documents such as invoices and receipts.
+// It supports text in the standard Helvetica fonts (WinAnsi encoding), lines, filled rectangles
+// and JPEG/PNG images. Coordinates are in points with the origin at the top-left of the page.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A4 page size in points
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+// Font selects one of the built-in fonts
+type Font int
+
+const (
+	Regular Font = iota
+	Bold
+)
+
+func (f Font) resource() string {
+	if f == Bold {
+		return "F2"
+	}
+	return "F1"
+}
+
+// Document is a PDF under construction
+type Document struct {
+	title  string
+	width  float64
+	height float64
+	pages  []*Page
+	images []*Image
+}
+
+// New returns an empty A4 portrait document
+func New(title string) *Document {
+	return &Document{title: title, width: A4Width, height: A4Height}
+}
+
+// Width returns the page width in points
+func (d *Document) Width() float64 { return d.width }
+
+// Height returns the page height in points
+func (d *Document) Height() float64 { return d.height }
+
+// Page is one page of a Document
+type Page struct {
+	doc     *Document
+	content bytes.Buffer
+	images  map[string]*Image
+}
+
+// AddPage appends a new blank page
+func (d *Document) AddPage() *Page {
+	p := &Page{doc: d, images: map[string]*Image{}}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// Text draws s with its baseline at (x, y)
+func (p *Page) Text(x, y, size float64, font Font, s string) {
+	fmt.Fprintf(&p.content, "BT /%s %s Tf %s %s Td (%s) Tj ET\n",
+		font.resource(), num(size), num(x), num(p.doc.height-y), escape(encode(s)))
+}
+
+// TextRight draws s right-aligned to x
+func (p *Page) TextRight(x, y, size float64, font Font, s string) {
+	p.Text(x-TextWidth(s, size, font), y, size, font, s)
+}
+
+// TextCenter draws s centered on x
+func (p *Page) TextCenter(x, y, size float64, font Font, s string) {
+	p.Text(x-TextWidth(s, size, font)/2, y, size, font, s)
+}
+
+// SetColor sets the fill (text) color, components in 0..1
+func (p *Page) SetColor(r, g, b float64) {
+	fmt.Fprintf(&p.content, "%s %s %s rg\n", num(r), num(g), num(b))
+}
+
+// SetStrokeColor sets the line color, components in 0..1
+func (p *Page) SetStrokeColor(r, g, b float64) {
+	fmt.Fprintf(&p.content, "%s %s %s RG\n", num(r), num(g), num(b))
+}
+
+// Line draws a line from (x1, y1) to (x2, y2)
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n",
+		num(width), num(x1), num(p.doc.height-y1), num(x2), num(p.doc.height-y2))
+}
+
+// FillRect fills the rectangle with its top-left corner at (x, y) in the current fill color
+func (p *Page) FillRect(x, y, w, h float64) {
+	fmt.Fprintf(&p.content, "%s %s %s %s re f\n", num(x), num(p.doc.height-y-h), num(w), num(h))
+}
+
+// StrokeRect outlines the rectangle with its top-left corner at (x, y)
+func (p *Page) StrokeRect(x, y, w, h, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s %s %s re S\n", num(width), num(x), num(p.doc.height-y-h), num(w), num(h))
+}
+
+// Image draws img with its top-left corner at (x, y), scaled to w x h
+func (p *Page) Image(img *Image, x, y, w, h float64) {
+	p.images[img.name] = img
+	fmt.Fprintf(&p.content, "q %s 0 0 %s %s %s cm /%s Do Q\n", num(w), num(h), num(x), num(p.doc.height-y-h), img.name)
+}
+
+// Bytes renders the document
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := d.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteTo renders the document to w
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	// Object numbers: 1 catalog, 2 pages, 3-4 fonts, 5 info, then images, then page + content pairs
+	const firstImage = 6
+	firstPage := firstImage + len(d.images)
+	pageObj := func(i int) int { return firstPage + 2*i }
+
+	var out bytes.Buffer
+	offsets := []int{0}
+	begin := func() int {
+		offsets = append(offsets, out.Len())
+		n := len(offsets) - 1
+		fmt.Fprintf(&out, "%d 0 obj\n", n)
+		return n
+	}
+	end := func() { out.WriteString("endobj\n") }
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	begin()
+	out.WriteString("<< /Type /Catalog /Pages 2 0 R >>\n")
+	end()
+
+	begin()
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", pageObj(i))
+	}
+	fmt.Fprintf(&out, "<< /Type /Pages /Kids [%s] /Count %d >>\n", strings.Join(kids, " "), len(d.pages))
+	end()
+
+	for _, base := range []string{"Helvetica", "Helvetica-Bold"} {
+		begin()
+		fmt.Fprintf(&out, "<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>\n", base)
+		end()
+	}
+
+	begin()
+	fmt.Fprintf(&out, "<< /Title (%s) /Producer (rrnet) >>\n", escape(encode(d.title)))
+	end()
+
+	for i, img := range d.images {
+		img.obj = firstImage + i
+		begin()
+		fmt.Fprintf(&out, "<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8 /Filter /%s /Length %d >>\nstream\n",
+			img.width, img.height, img.colorSpace, img.filter, len(img.data))
+		out.Write(img.data)
+		out.WriteString("\nendstream\n")
+		end()
+	}
+
+	for i, p := range d.pages {
+		var xobjects strings.Builder
+		for name, img := range p.images {
+			fmt.Fprintf(&xobjects, " /%s %d 0 R", name, img.obj)
+		}
+		begin()
+		fmt.Fprintf(&out, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> /XObject <<%s >> >> /Contents %d 0 R >>\n",
+			num(d.width), num(d.height), xobjects.String(), pageObj(i)+1)
+		end()
+
+		stream, err := deflate(p.content.Bytes())
+		if err != nil {
+			return 0, err
+		}
+		begin()
+		fmt.Fprintf(&out, "<< /Filter /FlateDecode /Length %d >>\nstream\n", len(stream))
+		out.Write(stream)
+		out.WriteString("\nendstream\n")
+		end()
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets))
+	for _, off := range offsets[1:] {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets), xref)
+
+	n, err := w.Write(out.Bytes())
+	return int64(n), err
+}
+
+func deflate(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// num formats a coordinate without trailing zeros
+func num(f float64) string {
+	s := strings.TrimRight(fmt.Sprintf("%.2f", f), "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// encode maps s to WinAnsi bytes; characters outside Latin-1 become '?'
+func encode(s string) string {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '\t':
+			b = append(b, ' ')
+		case r < 32:
+			// control characters are dropped
+		case r < 127, r >= 160 && r <= 255:
+			b = append(b, byte(r))
+		case r == '–' || r == '—':
+			b = append(b, '-')
+		case r == '‘' || r == '’':
+			b = append(b, '\'')
+		case r == '“' || r == '”':
+			b = append(b, '"')
+		default:
+			b = append(b, '?')
+		}
+	}
+	return string(b)
+}
+
+func escape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`)
+	return r.Replace(s)
+}
@@ -0,0 +1,21 @@
+package utils
+
+import "strconv"
+
+// FormatRupiah formats an IDR amount with dot thousand separators, e.g. 150000 -> "Rp 150.000".
+func FormatRupiah(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	digits := strconv.FormatInt(amount, 10)
+	out := make([]byte, 0, len(digits)+len(digits)/3)
+	for i := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			out = append(out, '.')
+		}
+		out = append(out, digits[i])
+	}
+	return sign + "Rp " + string(out)
+}